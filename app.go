@@ -0,0 +1,1136 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// RunMethod is the name of the method that is called to execute a
+// command.  The method is called through the injector, so it may
+// declare any arguments available from the injector; it may return
+// nothing, an error, or a result followed by an error, in which case
+// the result is rendered in the selected output format; see Output.
+// If a command has no RunMethod, its ExecuteMethod is called
+// instead.
+const (
+	RunMethod     = "Run"
+	ExecuteMethod = "Execute"
+)
+
+// Errors produced by the dispatcher.
+var (
+	ErrMissingCommand = errors.New("missing command")
+	ErrUnknownCommand = errors.New("unknown command")
+)
+
+// Args is the list of positional arguments passed to a command.  It
+// is available from the injector.
+type Args []string
+
+// Passthrough is the list of arguments following "--" for commands
+// that accept passthrough arguments; see IPassthrough.  Unknown flags
+// collected under UnknownFlagsCollect precede them.  It is available
+// from the injector.
+type Passthrough []string
+
+// IOStreams contains the standard input, output, and error streams
+// for a command, along with the stream to which help is written.  It
+// is available from the injector, so that commands need not use the
+// process's streams directly; see App.Streams and NewTestIOStreams.
+type IOStreams struct {
+	In     io.Reader // Standard input
+	Out    io.Writer // Standard output
+	ErrOut io.Writer // Standard error
+	Help   io.Writer // Help output; if nil, help is written to Out
+
+	theme   *Theme // The application's theme; see OutStyler
+	noColor bool   // True if the NoColorFlag was given
+}
+
+// help returns the stream to which help is written.
+func (s *IOStreams) help() io.Writer {
+	if s.Help != nil {
+		return s.Help
+	}
+
+	return s.Out
+}
+
+// Invocation describes a resolved command invocation.  It is
+// available from the injector.
+type Invocation struct {
+	Path     []string    // Names of the commands, beginning with the application
+	Commands []ICommand  // The commands along the path, beginning with the root
+	Command  ICommand    // The resolved command
+	Args     []string    // The positional arguments remaining after resolution
+	Options  interface{} // Pointer to the populated defaults of the command, if any
+
+	// Passthrough contains the arguments following "--" for
+	// commands that accept passthrough arguments, preceded by any
+	// unknown flags collected under UnknownFlagsCollect.
+	Passthrough []string
+
+	options []*options             // Populated defaults along the path
+	globals []*options             // Populated application-global flags
+	closers []io.Closer            // Files to close once the command has run
+	noColor bool                   // True if the NoColorFlag was given
+	config  string                 // Configuration file named by the ConfigFlag, if any
+	cfg     map[string]interface{} // The configuration loaded
+	helpAll bool                   // True if help including hidden items was requested
+	inj     *Injector              // Injector used to compute dynamic subcommands for help
+}
+
+// App describes an application.  It contains the root of the command
+// tree and the settings that control how commands are dispatched.
+type App struct {
+	Name              string                  // Name of the application; defaults to the executable name
+	Version           string                  // Version of the application
+	Root              ICommand                // The root command
+	Stdin             io.Reader               // Standard input; defaults to os.Stdin
+	Stdout            io.Writer               // Standard output; defaults to os.Stdout
+	Stderr            io.Writer               // Standard error; defaults to os.Stderr
+	HelpOut           io.Writer               // Help output; defaults to Stdout
+	StrictDeprecation bool                    // If true, deprecated commands past their removal date fail
+	Injector          *Injector               // Optional injector containing values available to all commands
+	ConfigFile        string                  // Optional path to the application's configuration file
+	ConfigFiles       []string                // Optional paths of configuration files loaded before ConfigFile; see WithConfigFiles
+	ConfigSearch      []string                // Paths searched for configuration files; defaults to DefaultConfigSearch
+	NoConfigSearch    bool                    // If true, configuration files are not searched for; see WithNoConfigSearch
+	NoDebugFlags      bool                    // If true, the hidden diagnostic flags are not recognized; see CPUProfileFlag
+	ConfigFormat      string                  // Format of the configuration file; see WithConfigFormat
+	ConfigProviders   []ConfigProvider        // Sources of configuration merged over the configuration files; see WithConfigProviders
+	EnvPrefix         string                  // Prefix of the environment variables bound to flags
+	Exiter            Exiter                  // Used to exit the program; defaults to os.Exit
+	AllowDryRun       bool                    // If true, the global DryRunFlag is recognized
+	AllowWatch        bool                    // If true, the global WatchFlag is recognized
+	AllowConfigFlag   bool                    // If true, the global ConfigFlag and its environment variable are recognized
+	AllowVerbosity    bool                    // If true, the global verbosity flags are recognized; see VerboseFlag
+	AllowOutput       bool                    // If true, the global OutputFlag is recognized
+	AllowLogFormat    bool                    // If true, the global LogFormatFlag is recognized
+	AllowCommands     bool                    // If true, the CommandsCommand is added to the root command
+	AllowShellAliases bool                    // If true, the ShellAliasCommand is added to the root command
+	AllowConfirmFlags bool                    // If true, the global YesFlag and ForceFlag are recognized; see Destructive
+	AllowAudit        bool                    // If true, invocations are recorded to the sinks configured under AuditKey
+	AllowDetach       bool                    // If true, the global DetachFlag is recognized; see Daemonize
+	LogHandler        LogHandler              // Optional handler receiving the messages of the Logger; see WithLogHandler
+	Clock             Clock                   // Source of time; defaults to RealClock
+	RandSource        rand.Source             // Source of the random numbers available from the injector; see WithRandSource
+	DefaultOutput     string                  // Output format used if none is selected; see WithDefaultOutput
+	OutputFormats     map[string]OutputFormat // Output formats registered with the application; see WithOutputFormat
+	UnknownFlags      UnknownFlags            // Default handling of unknown flags; see Command.UnknownFlags
+	WindowsFlags      bool                    // If true, Windows-style flags are recognized; see WithWindowsFlags
+	SecretPrompter    SecretPrompter          // Prompts for secrets; see KindSecret
+	Confirmer         Confirmer               // Confirms the operations of destructive commands; see Destructive
+	Globals           []interface{}           // Structs declaring application-global flags; see WithGlobals
+	InsensitiveFlags  bool                    // If true, long flags match regardless of case and of dashes versus underscores
+	UsageTemplate     string                  // Template for usage messages; see WithTemplates
+	HelpTemplate      string                  // Template for full help; see WithTemplates
+	Width             int                     // Width to which help is wrapped; see WithWidth
+	Theme             *Theme                  // Optional theme used to color help and errors; see WithTheme
+	Locale            string                  // Locale selecting the language of help; see WithLocale
+	LocaleValues      bool                    // If true, numbers and dates are parsed in the locale's format; see WithLocaleValues
+	LocaleFormats     map[string]LocaleFormat // Formats of numbers and dates, by locale; see WithLocaleFormat
+	Catalogs          map[string]Catalog      // Catalogs translating help, by language; see WithCatalog
+	Topics            map[string]*Topic       // Standalone help topics, by name; see WithTopic
+	UsageCode         int                     // Exit code for usage errors; defaults to UsageExitCode
+	RemoteToken       string                  // Bearer token required by RemoteHandler, if set; see WithRemoteToken
+
+	// NotFound is an optional handler called when a command name
+	// does not match any subcommand.
+	NotFound CommandNotFoundHandler
+
+	// WarningHandler is an optional function used to report
+	// warnings; see Warn.
+	WarningHandler WarningHandler
+
+	middleware []Middleware  // Middleware wrapping command execution
+	onStart    []interface{} // Functions called when dispatching begins
+	onResolved []interface{} // Functions called when the command is resolved
+	onExit     []interface{} // Functions called when dispatching completes
+
+	preprocessors []Preprocessor // Transformations applied to the arguments
+}
+
+// CommandNotFoundHandler is a function called when a command name
+// does not match any subcommand.  It receives the invocation, the
+// attempted name, and the remaining arguments.  It may, for
+// instance, suggest the installation of a plugin, delegate to an
+// external resolver, or return a custom CommandError; its return
+// value becomes the result of the invocation.  Handlers that merely
+// wish to add to the usual error may return UnknownCommand(name).
+type CommandNotFoundHandler func(ctx context.Context, inv *Invocation, name string, args []string) error
+
+// UnknownCommand returns the usage error reported when a command
+// name does not match any subcommand.
+func UnknownCommand(name string) error {
+	return usageError(fmt.Errorf("%w %q", ErrUnknownCommand, name))
+}
+
+// Exiter is an interface for exiting the program.  Applications
+// may provide an alternate Exiter, typically so that tests can
+// assert on the exit code without the process exiting.
+type Exiter interface {
+	// Exit exits the program with the specified exit code.
+	Exit(code int)
+}
+
+// ExiterFunc is an adapter allowing an ordinary function to be used
+// as an Exiter.
+type ExiterFunc func(code int)
+
+// Exit exits the program with the specified exit code.
+func (f ExiterFunc) Exit(code int) {
+	f(code)
+}
+
+// New constructs a new App with the specified name.  The App may be
+// configured using its chainable With* methods, and then run with
+// Run; for instance:
+//
+//	func main() {
+//		nelson.New("tool").
+//			WithVersion("1.0").
+//			WithRoot(root).
+//			Run(context.Background(), os.Args[1:])
+//	}
+func New(name string) *App {
+	return &App{
+		Name: name,
+	}
+}
+
+// WithVersion sets the version of the application.  Returns the App,
+// to allow chaining.
+func (a *App) WithVersion(version string) *App {
+	a.Version = version
+	return a
+}
+
+// WithRoot sets the root command of the application.  Returns the
+// App, to allow chaining.
+func (a *App) WithRoot(root ICommand) *App {
+	a.Root = root
+	return a
+}
+
+// WithInjector sets the injector containing values that will be
+// available to all commands.  The injector is copied for each
+// invocation, so values added during an invocation are not retained.
+// Returns the App, to allow chaining.
+func (a *App) WithInjector(inj *Injector) *App {
+	a.Injector = inj
+	return a
+}
+
+// WithIO sets the standard input, output, and error streams of the
+// application.  A nil stream leaves the corresponding stream
+// unchanged.  Returns the App, to allow chaining.
+func (a *App) WithIO(stdin io.Reader, stdout, stderr io.Writer) *App {
+	if stdin != nil {
+		a.Stdin = stdin
+	}
+	if stdout != nil {
+		a.Stdout = stdout
+	}
+	if stderr != nil {
+		a.Stderr = stderr
+	}
+	return a
+}
+
+// WithStreams sets the standard input, output, and error streams of
+// the application, along with the stream to which help requested with
+// HelpFlag or the HelpCommand is written.  Usage messages for usage
+// errors are written to the standard error stream.  A nil stream
+// leaves the corresponding stream unchanged.  Returns the App, to
+// allow chaining.
+func (a *App) WithStreams(streams *IOStreams) *App {
+	a.WithIO(streams.In, streams.Out, streams.ErrOut)
+	if streams.Help != nil {
+		a.HelpOut = streams.Help
+	}
+	return a
+}
+
+// WithUsageCode sets the exit code for usage errors, such as unknown
+// flags or missing arguments, which are reported to standard error
+// along with a brief usage message.  By default, UsageExitCode is
+// used.  Returns the App, to allow chaining.
+func (a *App) WithUsageCode(code int) *App {
+	a.UsageCode = code
+	return a
+}
+
+// WithExiter sets the Exiter used to exit the program.  Returns the
+// App, to allow chaining.
+func (a *App) WithExiter(exiter Exiter) *App {
+	a.Exiter = exiter
+	return a
+}
+
+// WithDryRun sets whether the global DryRunFlag is recognized.
+// Returns the App, to allow chaining.
+func (a *App) WithDryRun(allow bool) *App {
+	a.AllowDryRun = allow
+	return a
+}
+
+// WithWatch sets whether the global WatchFlag is recognized.
+// Returns the App, to allow chaining.
+func (a *App) WithWatch(allow bool) *App {
+	a.AllowWatch = allow
+	return a
+}
+
+// WithConfigFlag sets whether the global ConfigFlag is recognized,
+// along with the environment variable named by ConfigEnv.  Either
+// names the configuration file explicitly, in which case it is the
+// only configuration file loaded: the search for configuration files
+// and the files set by WithConfigFiles and WithConfigFile are
+// bypassed.  The flag takes precedence over the environment
+// variable.  Unlike other configuration files, a file named
+// explicitly must exist.  Returns the App, to allow chaining.
+func (a *App) WithConfigFlag(allow bool) *App {
+	a.AllowConfigFlag = allow
+	return a
+}
+
+// WithNotFound sets the handler called when a command name does not
+// match any subcommand.  Returns the App, to allow chaining.
+func (a *App) WithNotFound(handler CommandNotFoundHandler) *App {
+	a.NotFound = handler
+	return a
+}
+
+// WithUnknownFlags sets the default policy for handling unknown
+// flags, used for commands that do not select their own.  Returns
+// the App, to allow chaining.
+func (a *App) WithUnknownFlags(policy UnknownFlags) *App {
+	a.UnknownFlags = policy
+	return a
+}
+
+// WithInsensitiveFlags sets whether long flags are matched
+// insensitively, to ease migration from tools that accepted variant
+// spellings: when set, "--log_level" and "--Log-Level" both select
+// the flag "--log-level".  Short flags remain case sensitive.
+// Returns the App, to allow chaining.
+func (a *App) WithInsensitiveFlags(insensitive bool) *App {
+	a.InsensitiveFlags = insensitive
+	return a
+}
+
+// WithConfigFile sets the path to the application's configuration
+// file, a YAML, JSON, or TOML file from which flags are populated;
+// see ConfigTag and WithConfigFormat.  Values from the file take
+// precedence over the defaults, but are overridden by the
+// environment and the command line.  A missing file is ignored.
+// Returns the App, to allow chaining.
+func (a *App) WithConfigFile(path string) *App {
+	a.ConfigFile = path
+	return a
+}
+
+// WithConfigFiles sets the paths of several configuration files,
+// such as system, user, and project files, loaded in order after the
+// files found by searching (see WithConfigSearch) and before the
+// ConfigFile, if any.  Each file overrides the files before it
+// key by key: a key set in a later file replaces the value from an
+// earlier file, while sections present in several files are merged.
+// Missing files are ignored.  The merged configuration is available
+// from Config.  Returns the App, to allow chaining.
+func (a *App) WithConfigFiles(paths ...string) *App {
+	a.ConfigFiles = paths
+	return a
+}
+
+// configFiles returns the paths of the configuration files, in the
+// order in which they are loaded: the paths searched for
+// configuration files, followed by the ConfigFiles and the
+// ConfigFile.
+func (a *App) configFiles() []string {
+	paths := append(a.configSearch(), a.ConfigFiles...)
+	if a.ConfigFile != "" {
+		paths = append(paths, a.ConfigFile)
+	}
+
+	return paths
+}
+
+// Config loads and merges the application's configuration files,
+// returning the configuration from which flags are populated; see
+// WithConfigFiles.  Sections of the configuration are
+// map[string]interface{}.  Returns nil if there are no configuration
+// files.  If the application allows the ConfigFlag, the environment
+// variable named by ConfigEnv is honored, but the flag, which is
+// only available while dispatching, is not.  The configuration
+// providers are then loaded, using the context; see
+// WithConfigProviders.
+func (a *App) Config(ctx context.Context) (map[string]interface{}, error) {
+	return a.config(ctx, "", nil)
+}
+
+// ConfigEnv returns the name of the environment variable naming the
+// configuration file, if the application allows the ConfigFlag:
+// "<PREFIX>_CONFIG", where the prefix is the application's
+// environment prefix, or its name if there is none; see EnvName.
+func (a *App) ConfigEnv() string {
+	prefix := a.EnvPrefix
+	if prefix == "" {
+		prefix = a.name()
+	}
+
+	return EnvName(prefix, nil, "config")
+}
+
+// config implements Config, given the path named by the ConfigFlag,
+// if any.  The files and providers loaded are reported to the
+// logger, if any.
+func (a *App) config(ctx context.Context, path string, log *Logger) (map[string]interface{}, error) {
+	cfg, err := a.configFromFiles(path, log)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.loadProviders(ctx, cfg, log)
+}
+
+// configFromFiles is a helper that loads the configuration files,
+// given the path named by the ConfigFlag, if any.
+func (a *App) configFromFiles(path string, log *Logger) (map[string]interface{}, error) {
+	if a.AllowConfigFlag && path == "" {
+		path = os.Getenv(a.ConfigEnv())
+	}
+	if !a.AllowConfigFlag || path == "" {
+		return loadConfigs(a.configFiles(), a.ConfigFormat, log)
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrMissingConfig, path)
+	}
+	log.Debugf("loading configuration file %s", path)
+	cfg, err := loadConfig(path, a.ConfigFormat)
+	if err == nil && cfg == nil {
+		cfg = map[string]interface{}{}
+	}
+
+	return cfg, err
+}
+
+// WithConfigFormat sets the format of the application's
+// configuration files, ConfigYAML, ConfigTOML, or ConfigJSON.  By
+// default, the format of each file is selected by its extension:
+// ".toml" files contain TOML, ".json" files contain JSON, and other
+// files contain YAML or JSON.  The same keys are used
+// regardless of the format.  Returns the App, to allow chaining.
+func (a *App) WithConfigFormat(format string) *App {
+	a.ConfigFormat = format
+	return a
+}
+
+// Run executes the application with the specified arguments, which
+// should not include the program name, and exits the program with
+// the resulting exit code, using the application's Exiter.  It is
+// intended to be the final call in the program's main function.
+func (a *App) Run(ctx context.Context, args []string) {
+	a.exiter().Exit(a.Execute(ctx, args))
+}
+
+// Runner is an interface for running a resolved command invocation.
+// The injector contains the values available to the command.
+type Runner interface {
+	// Run runs the command invocation.
+	Run(ctx context.Context, inv *Invocation, inj *Injector) error
+}
+
+// RunnerFunc is an adapter allowing an ordinary function to be used
+// as a Runner.
+type RunnerFunc func(ctx context.Context, inv *Invocation, inj *Injector) error
+
+// Run runs the command invocation.
+func (f RunnerFunc) Run(ctx context.Context, inv *Invocation, inj *Injector) error {
+	return f(ctx, inv, inj)
+}
+
+// Middleware wraps a Runner, returning a Runner.  Middleware may be
+// used to implement cross-cutting concerns, such as authorization
+// checks, locking, or metrics, without per-command code.  Middleware
+// may run code before and after calling the next Runner, may decline
+// to call it, and may pass a derived context to it; the context
+// passed to the next Runner is made available to the command through
+// the injector.
+type Middleware func(next Runner) Runner
+
+// Use adds middleware to the application.  Middleware is applied in
+// the order added, with the first middleware added being the
+// outermost.  Returns the App, to allow chaining.
+func (a *App) Use(mw ...Middleware) *App {
+	a.middleware = append(a.middleware, mw...)
+	return a
+}
+
+// Execute is a convenience function that executes the command tree
+// rooted at root with the specified arguments, which should not
+// include the program name.  Errors are reported to standard error,
+// and the exit code for the program is returned.
+func Execute(root ICommand, args []string) int {
+	app := &App{
+		Root: root,
+	}
+
+	return app.Execute(context.Background(), args)
+}
+
+// Execute resolves the command from the arguments, which should not
+// include the program name, and executes it.  The context is
+// available from the injector, and should be used by commands for
+// cancellation and timeouts; if nil, context.Background() is used.
+// Errors are reported to the application's standard error, along
+// with usage if the error requests it, and the exit code for the
+// program is returned, as determined by ExitControl.  Usage errors
+// with UsageExitCode exit with the application's usage code instead,
+// if one is set; see WithUsageCode.
+func (a *App) Execute(ctx context.Context, args []string) int {
+	inv, err := a.dispatch(ctx, args)
+	return a.report(inv, err)
+}
+
+// report reports the error from dispatching the invocation, as
+// described for Execute, returning the exit code.  The invocation
+// may be nil if the command was not resolved.
+func (a *App) report(inv *Invocation, err error) int {
+	if err == nil {
+		return 0
+	}
+
+	// Report the error
+	code, usage := ExitControl(err)
+	if usage && code == UsageExitCode && a.UsageCode != 0 {
+		code = a.UsageCode
+	}
+	prefix := a.name() + ":"
+	if theme := a.theme(a.stderr(), inv != nil && inv.noColor); theme != nil {
+		prefix = theme.paint(theme.Error, prefix)
+	}
+	if msg := err.Error(); msg != "" {
+		fmt.Fprintf(a.stderr(), "%s %s\n", prefix, msg)
+	}
+	if usage && inv != nil {
+		if err := a.usage(a.stderr(), inv); err != nil {
+			fmt.Fprintf(a.stderr(), "%s %s\n", prefix, err)
+		}
+	}
+
+	return code
+}
+
+// Dispatch resolves the command from the arguments, which should not
+// include the program name, and executes it with the specified
+// context.  Unlike Execute, errors are not reported, but are
+// returned to the caller.
+func (a *App) Dispatch(ctx context.Context, args []string) error {
+	_, err := a.dispatch(ctx, args)
+	return err
+}
+
+// newInjector constructs the injector for dispatching a command,
+// containing the App and the context, along with the values in the
+// App's injector.  If the context is nil, context.Background() is
+// used.
+func (a *App) newInjector(ctx context.Context) *Injector {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	inj := NewInjector()
+	if a.Injector != nil {
+		inj = a.Injector.Clone()
+	}
+	log := NewLogger(a.stderr(), a.name(), VerbosityNormal)
+	log.Handler = a.LogHandler
+	log.clock = a.clock()
+	inj.Provide(a, VerbosityNormal, log, log.StdLogger(VerbosityVerbose), a.rand())
+	inj.ProvideAs((*context.Context)(nil), ctx)
+	inj.ProvideAs((*Clock)(nil), a.clock())
+
+	return inj
+}
+
+// dispatch implements Dispatch, returning the resolved invocation
+// for use in reporting errors.
+func (a *App) dispatch(ctx context.Context, args []string) (inv *Invocation, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	inj := a.newInjector(ctx)
+
+	// Call the lifecycle hooks
+	defer func() {
+		err = a.exitHooks(inj, err)
+	}()
+	defer func() {
+		if inv != nil {
+			inv.inj = inj // Retained for rendering usage
+			if closeErr := inv.closeFiles(); err == nil {
+				err = closeErr
+			}
+		}
+	}()
+	if err = callHooks(inj, onStartName, a.onStart); err != nil {
+		return nil, err
+	}
+	raw := append([]string(nil), args...)
+
+	// Preprocess the arguments
+	if args, err = a.preprocess(args); err != nil {
+		return nil, err
+	}
+
+	// Handle the hidden diagnostic flags
+	if !a.NoDebugFlags {
+		var debug *debugFlags
+		if debug, args, err = extractDebug(args); err != nil {
+			return nil, usageError(err)
+		}
+		stop, startErr := debug.start()
+		if startErr != nil {
+			return nil, startErr
+		}
+		defer func() {
+			if stopErr := stop(); err == nil {
+				err = stopErr
+			}
+		}()
+		if debug.injector {
+			defer func() {
+				if err != nil {
+					dumpInjector(a.stderr(), inj, inv)
+				}
+			}()
+		}
+	}
+
+	// Hide the flags the command declares itself, and their values,
+	// from the global flags of the same names
+	args, unmask := a.maskDeclared(inj, args)
+
+	// Handle the global dry run flag
+	dryRun := DryRun(false)
+	if a.AllowDryRun {
+		dryRun, args = extractDryRun(args)
+	}
+	inj.Provide(dryRun)
+
+	// Handle the global confirmation flags
+	assumeYes := AssumeYes(false)
+	if a.AllowConfirmFlags {
+		assumeYes, args = extractAssumeYes(args)
+	}
+	inj.Provide(assumeYes)
+
+	// Handle the global detach flag
+	detach := false
+	if a.AllowDetach {
+		detach, args = extractDetach(args)
+	}
+
+	// Handle the global log format flag
+	var log *Logger
+	inj.Get(&log)
+	if a.AllowLogFormat {
+		if log.Format, args, err = extractLogFormat(args); err != nil {
+			return nil, usageError(err)
+		}
+	}
+
+	// Handle the global no-color flag
+	noColor := false
+	if a.Theme != nil {
+		noColor, args = extractNoColor(args)
+	}
+
+	// Handle the global watch flag
+	var interval time.Duration
+	var watchErr error
+	if a.AllowWatch {
+		interval, args, watchErr = extractWatch(args)
+	}
+
+	// Handle the global config flag
+	configPath := ""
+	if a.AllowConfigFlag {
+		if configPath, args, err = extractConfig(args); err != nil {
+			return nil, usageError(err)
+		}
+	}
+
+	// Handle the global output flag
+	outputFormat := ""
+	if a.AllowOutput {
+		if outputFormat, args, err = extractOutput(args); err != nil {
+			return nil, usageError(err)
+		}
+	}
+
+	// Handle the global verbosity flags
+	if a.AllowVerbosity {
+		log.Verbosity, args = extractVerbosity(args)
+		inj.Provide(log.Verbosity)
+	}
+	args = unmask(args)
+
+	// Resolve the command
+	inv, err = a.resolve(inj, args, configPath)
+	inv.noColor = noColor
+	if errors.Is(err, ErrHelp) {
+		inv.inj = inj
+		inv.helpAll = errors.Is(err, ErrHelpAll)
+		return inv, a.help(a.helpOut(), inv)
+	} else if err != nil {
+		return inv, err
+	} else if watchErr != nil {
+		return inv, usageError(watchErr)
+	}
+	log.Debugf("resolved command %q with arguments %q", strings.Join(inv.Path, " "), inv.Args)
+	if err = checkPrivileges(inv); err != nil {
+		return inv, err
+	}
+	if err = a.confirm(inv, assumeYes, dryRun); err != nil {
+		return inv, err
+	}
+	release, detached, err := a.runDaemon(inv, detach, raw)
+	if err != nil || detached {
+		return inv, err
+	}
+	defer release()
+	output, err := a.newOutput(outputFormat)
+	if err != nil {
+		return inv, usageError(err)
+	}
+	events := &Events{app: a, log: log, output: output, clock: a.clock()}
+	inj.Provide(output, events)
+	streams := a.Streams()
+	streams.noColor = noColor
+	inj.Provide(inv, &ParseResult{inv: inv, raw: raw}, Args(inv.Args), Passthrough(inv.Passthrough), streams)
+	for _, opts := range inv.options {
+		inj.Provide(opts.Value.Interface())
+	}
+	inj.Provide(&ConfigReloader{
+		app:        a,
+		inj:        inj,
+		args:       append([]string(nil), args...),
+		configPath: configPath,
+	})
+	if err = callHooks(inj, onResolvedName, a.onResolved); err != nil {
+		return inv, err
+	}
+
+	// Construct the runner, applying middleware
+	var runner Runner = RunnerFunc(a.run)
+	for i := len(a.middleware) - 1; i >= 0; i-- {
+		runner = a.middleware[i](runner)
+	}
+	if a.AllowAudit {
+		sinks, err := auditSinks(inv.cfg)
+		if err != nil {
+			return inv, err
+		}
+		if len(sinks) > 0 {
+			runner = Audit(sinks...)(runner)
+		}
+	}
+
+	// Run the command, repeatedly if requested
+	if interval > 0 {
+		return inv, a.watch(ctx, interval, func() error {
+			return events.failed(runner.Run(ctx, inv, inj))
+		})
+	}
+
+	return inv, events.failed(runner.Run(ctx, inv, inj))
+}
+
+// resolve resolves the command from the arguments.  The global flags
+// are handled first, and are made available from the injector.
+// Resolution then descends through the subcommands for as long as the
+// next argument that is not a flag names a subcommand.  The defaults
+// of each command along the way are copied, receive any values they
+// inherit from their parent, and are populated from the flags; the
+// leaf command's positional arguments are then bound to its defaults.
+// The configuration is loaded first, from the file named by the
+// ConfigFlag if given.
+func (a *App) resolve(inj *Injector, args []string, configPath string) (*Invocation, error) {
+	inv := &Invocation{
+		Path:     []string{a.name()},
+		Commands: []ICommand{a.Root},
+		Command:  a.Root,
+		config:   configPath,
+	}
+
+	var ctx context.Context
+	if !inj.Get(&ctx) || ctx == nil {
+		ctx = context.Background()
+	}
+	var log *Logger
+	inj.Get(&log)
+	cfg, err := a.config(ctx, configPath, log)
+	if err != nil {
+		return inv, err
+	}
+	inv.cfg = cfg
+
+	stdin := &stdinSource{r: a.stdin()}
+	configDir := a.pathBase(inv)
+
+	// Handle the global flags
+	if inv.globals, err = a.newGlobals(cfg, configDir); err != nil {
+		return inv, err
+	}
+	for _, opts := range inv.globals {
+		opts.stdin = stdin
+	}
+	args, err = parseGlobals(inv.globals, args)
+	for _, opts := range inv.globals {
+		for _, warning := range opts.warnings() {
+			a.Warn("%s", warning)
+		}
+	}
+	if err != nil {
+		return inv, usageError(err)
+	}
+	for _, opts := range inv.globals {
+		if err := a.openFiles(inv, opts); err != nil {
+			return inv, err
+		}
+		if err := a.promptSecrets(opts); err != nil {
+			return inv, err
+		}
+		inj.Provide(opts.Value.Interface())
+	}
+	if err := validateAll(inv.globals); err != nil {
+		return inv, usageError(err)
+	}
+
+	var parent *options
+	var collected []string
+	for {
+		subs, err := resolveSubcommands(inv.Command, inj)
+		if err != nil {
+			return inv, err
+		}
+		if len(inv.Path) == 1 {
+			subs = a.withBuiltins(subs, inj)
+		}
+
+		// Construct the options
+		opts, err := newOptions(inv.Command)
+		if err != nil {
+			return inv, err
+		}
+		if opts != nil {
+			opts.configDir = configDir
+			if parent != nil {
+				inheritDefaults(opts.Value, parent.Value)
+				opts.inheritSources(parent)
+			}
+			if err := opts.applyConfig(cfg, inv.Path[1:]); err != nil {
+				return inv, err
+			}
+			if err := opts.applyEnv(a.EnvPrefix, inv.Path[1:]); err != nil {
+				return inv, err
+			}
+			opts.unknown = a.unknownFlags(inv.Command)
+			opts.strict = IsStrictOrder(inv.Command)
+			opts.windows = a.WindowsFlags
+			opts.insensitive = a.InsensitiveFlags
+			opts.locale = a.localeFormat()
+			opts.stdin = stdin
+			parent = opts
+			inv.Options = opts.Value.Interface()
+			inv.options = append(inv.options, opts)
+		} else {
+			inv.Options = nil
+		}
+
+		// Split off any passthrough arguments
+		head, tail := args, []string(nil)
+		if IsPassthrough(inv.Command) {
+			head, tail = splitPassthrough(args)
+		}
+
+		// Parse the arguments
+		positional, next, err := opts.parse(head, subs)
+		for _, warning := range opts.warnings() {
+			a.Warn("%s", warning)
+		}
+		if errors.Is(err, ErrHelp) {
+			return inv, err
+		} else if err != nil {
+			return inv, usageError(err)
+		}
+		collected = append(collected, opts.collectedFlags()...)
+		if err := a.openFiles(inv, opts); err != nil {
+			return inv, err
+		}
+		if err := a.promptSecrets(opts); err != nil {
+			return inv, err
+		}
+		if next < 0 {
+			inv.Args = positional
+			inv.Passthrough = tail
+			if collected != nil {
+				inv.Passthrough = append(collected, tail...)
+			}
+			if err := opts.bind(positional); err != nil {
+				return inv, usageError(err)
+			}
+			if err := validateAll(inv.options); err != nil {
+				return inv, usageError(err)
+			}
+			break
+		}
+
+		// Descend to the subcommand
+		name := args[next]
+		sub := subs[name]
+		inv.Path = append(inv.Path, name)
+		inv.Commands = append(inv.Commands, sub)
+		inv.Command = sub
+		args = args[next+1:]
+
+		// Handle deprecated commands
+		if dep := GetDeprecation(sub); dep != nil {
+			if err := dep.Check(name, a.clock().Now(), a.StrictDeprecation); err != nil {
+				return inv, err
+			}
+			a.Warn("%s", dep.Warning(name))
+		}
+	}
+
+	return inv, nil
+}
+
+// splitPassthrough is a helper that splits the arguments at the
+// first "--", returning the arguments preceding it and those
+// following it.  If there is no "--", all arguments are returned as
+// the first value.
+func splitPassthrough(args []string) ([]string, []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+
+	return args, nil
+}
+
+// run runs the resolved command, applying any hooks.
+func (a *App) run(ctx context.Context, inv *Invocation, inj *Injector) error {
+	inj.ProvideAs((*context.Context)(nil), ctx)
+
+	// Find the command to run
+	var target ICommand
+	var method string
+	var hooks []*HookedCommand
+	for cmd := inv.Command; cmd != nil; {
+		if hooked, ok := cmd.(*HookedCommand); ok {
+			hooks = append(hooks, hooked)
+		}
+		if method = runMethod(cmd); method != "" {
+			target = cmd
+			break
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	// If there's nothing to run, report a usage error or let the
+	// not found handler deal with it
+	if target == nil {
+		if len(inv.Args) == 0 {
+			return usageError(ErrMissingCommand)
+		} else if a.NotFound != nil {
+			return a.NotFound(ctx, inv, inv.Args[0], inv.Args[1:])
+		}
+		return UnknownCommand(inv.Args[0])
+	}
+
+	// Construct the runner, applying hooks from the inside out
+	runner := func() error {
+		result, err := callRun(inj, target, method)
+		if err != nil || outputIndirect(reflect.ValueOf(result)).Kind() == reflect.Invalid {
+			return err
+		}
+		var output *Output
+		if !inj.Get(&output) {
+			if output, err = a.newOutput(""); err != nil {
+				return err
+			}
+		}
+		return output.Render(result)
+	}
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooked, next := hooks[i], runner
+		runner = func() error {
+			return hooked.invoke(inj, next)
+		}
+	}
+
+	// Apply the persistent hooks, from the leaf to the root
+	for i := len(inv.Commands) - 1; i >= 0; i-- {
+		persistent := getPersistentHooks(inv.Commands[i])
+		if persistent == nil {
+			continue
+		}
+		next := runner
+		runner = func() error {
+			return (&HookedCommand{
+				Before: persistent.GetPersistentPreRun(),
+				After:  persistent.GetPersistentPostRun(),
+			}).invoke(inj, next)
+		}
+	}
+
+	return runner()
+}
+
+// callRun is a helper that calls the named run method of a command,
+// supplying its arguments from the injector.  Commands implementing
+// StaticRunner are called without reflection.
+func callRun(inj *Injector, cmd ICommand, method string) (interface{}, error) {
+	if static, ok := cmd.(StaticRunner); ok {
+		return static.RunStatic(inj)
+	}
+
+	return inj.callMethod(cmd, method)
+}
+
+// runMethod is a helper that returns the name of the method used to
+// run a command: RunMethod if the command has it, otherwise
+// ExecuteMethod if the command has it, otherwise "".
+func runMethod(cmd ICommand) string {
+	v := reflect.ValueOf(cmd)
+	for _, name := range []string{RunMethod, ExecuteMethod} {
+		if v.MethodByName(name).IsValid() {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// getPersistentHooks is a helper that locates the IPersistentHooks
+// implementation for a command, if any.  It examines the command and
+// any commands it wraps.
+func getPersistentHooks(cmd ICommand) IPersistentHooks {
+	for cmd != nil {
+		if tmp, ok := cmd.(IPersistentHooks); ok {
+			return tmp
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return nil
+}
+
+// name returns the name of the application.
+func (a *App) name() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	if len(os.Args) > 0 {
+		return filepath.Base(os.Args[0])
+	}
+
+	return ""
+}
+
+// unknownFlags returns the policy for handling unknown flags given
+// to a command.
+func (a *App) unknownFlags(cmd ICommand) UnknownFlags {
+	if policy := GetUnknownFlags(cmd); policy != UnknownFlagsDefault {
+		return policy
+	}
+
+	return a.UnknownFlags
+}
+
+// exiter returns the Exiter for the application, defaulting to one
+// that calls os.Exit.
+func (a *App) exiter() Exiter {
+	if a.Exiter != nil {
+		return a.Exiter
+	}
+
+	return ExiterFunc(os.Exit)
+}
+
+// stdin returns the standard input stream for the application.
+func (a *App) stdin() io.Reader {
+	if a.Stdin != nil {
+		return a.Stdin
+	}
+
+	return os.Stdin
+}
+
+// stdout returns the standard output stream for the application.
+func (a *App) stdout() io.Writer {
+	if a.Stdout != nil {
+		return a.Stdout
+	}
+
+	return os.Stdout
+}
+
+// helpOut returns the stream to which the application writes help.
+func (a *App) helpOut() io.Writer {
+	if a.HelpOut != nil {
+		return a.HelpOut
+	}
+
+	return a.stdout()
+}
+
+// stderr returns the standard error stream for the application.
+func (a *App) stderr() io.Writer {
+	if a.Stderr != nil {
+		return a.Stderr
+	}
+
+	return os.Stderr
+}
@@ -0,0 +1,1563 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/klmitch/nelson/internal/depinject"
+)
+
+type runCommand struct {
+	Command
+	mock.Mock
+}
+
+func (c *runCommand) Run(args Args, inv *Invocation) error {
+	result := c.MethodCalled("Run", args, inv)
+
+	return result.Error(0)
+}
+
+func newRunCommand(summary string, subs map[string]ICommand) *runCommand {
+	return &runCommand{
+		Command: Command{
+			Summary:     summary,
+			Subcommands: subs,
+		},
+	}
+}
+
+func TestExecute(t *testing.T) {
+	root := newRunCommand("Root command", nil)
+	root.On("Run", Args{"a1"}, mock.Anything).Return(nil)
+
+	result := Execute(root, []string{"a1"})
+
+	assert.Equal(t, 0, result)
+	root.AssertExpectations(t)
+}
+
+func TestNew(t *testing.T) {
+	result := New("app")
+
+	assert.Equal(t, &App{
+		Name: "app",
+	}, result)
+}
+
+func TestAppWithVersion(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithVersion("1.0")
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, "1.0", obj.Version)
+}
+
+func TestAppWithRoot(t *testing.T) {
+	root := &Command{}
+	obj := &App{}
+
+	result := obj.WithRoot(root)
+
+	assert.Same(t, obj, result)
+	assert.Same(t, root, obj.Root)
+}
+
+func TestAppWithInjector(t *testing.T) {
+	inj := NewInjector()
+	obj := &App{}
+
+	result := obj.WithInjector(inj)
+
+	assert.Same(t, obj, result)
+	assert.Same(t, inj, obj.Injector)
+}
+
+func TestAppWithIO(t *testing.T) {
+	stdin := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	obj := &App{}
+
+	result := obj.WithIO(stdin, stdout, stderr)
+
+	assert.Same(t, obj, result)
+	assert.Same(t, stdin, obj.Stdin)
+	assert.Same(t, stdout, obj.Stdout)
+	assert.Same(t, stderr, obj.Stderr)
+}
+
+func TestAppWithIONil(t *testing.T) {
+	stdin := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+
+	result := obj.WithIO(nil, nil, nil)
+
+	assert.Same(t, obj, result)
+	assert.Same(t, stdin, obj.Stdin)
+	assert.Same(t, stdout, obj.Stdout)
+	assert.Same(t, stderr, obj.Stderr)
+}
+
+func TestAppWithStreams(t *testing.T) {
+	stdin := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	help := &bytes.Buffer{}
+	obj := &App{}
+
+	result := obj.WithStreams(&IOStreams{
+		In:     stdin,
+		Out:    stdout,
+		ErrOut: stderr,
+		Help:   help,
+	})
+
+	assert.Same(t, obj, result)
+	assert.Same(t, stdin, obj.Stdin)
+	assert.Same(t, stdout, obj.Stdout)
+	assert.Same(t, stderr, obj.Stderr)
+	assert.Same(t, help, obj.HelpOut)
+}
+
+func TestAppWithStreamsNil(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	help := &bytes.Buffer{}
+	obj := &App{
+		Stdout:  stdout,
+		HelpOut: help,
+	}
+
+	result := obj.WithStreams(&IOStreams{})
+
+	assert.Same(t, obj, result)
+	assert.Same(t, stdout, obj.Stdout)
+	assert.Same(t, help, obj.HelpOut)
+}
+
+func TestIOStreamsHelp(t *testing.T) {
+	out := &bytes.Buffer{}
+	help := &bytes.Buffer{}
+	obj := &IOStreams{Out: out, Help: help}
+
+	assert.Same(t, help, obj.help())
+}
+
+func TestIOStreamsHelpDefault(t *testing.T) {
+	out := &bytes.Buffer{}
+	obj := &IOStreams{Out: out}
+
+	assert.Same(t, out, obj.help())
+}
+
+func TestAppWithUsageCode(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithUsageCode(64)
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, 64, obj.UsageCode)
+}
+
+func TestAppWithDryRun(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithDryRun(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.AllowDryRun)
+}
+
+func TestAppWithWatch(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithWatch(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.AllowWatch)
+}
+
+func TestAppWithNotFound(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithNotFound(func(context.Context, *Invocation, string, []string) error { return nil })
+
+	assert.Same(t, obj, result)
+	assert.NotNil(t, obj.NotFound)
+}
+
+func TestUnknownCommand(t *testing.T) {
+	result := UnknownCommand("bogus")
+
+	assert.ErrorIs(t, result, ErrUnknownCommand)
+	assert.EqualError(t, result, `unknown command "bogus"`)
+	code, usage := ExitControl(result)
+	assert.Equal(t, 2, code)
+	assert.True(t, usage)
+}
+
+func TestAppWithConfigFile(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithConfigFile("config.yaml")
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, "config.yaml", obj.ConfigFile)
+}
+
+func TestAppWithUnknownFlags(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithUnknownFlags(UnknownFlagsIgnore)
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, UnknownFlagsIgnore, obj.UnknownFlags)
+}
+
+func TestAppWithInsensitiveFlags(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithInsensitiveFlags(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.InsensitiveFlags)
+}
+
+func TestExiterFunc(t *testing.T) {
+	var code int
+	obj := ExiterFunc(func(c int) {
+		code = c
+	})
+
+	obj.Exit(3)
+
+	assert.Equal(t, 3, code)
+}
+
+func TestAppWithExiter(t *testing.T) {
+	exiter := ExiterFunc(func(int) {})
+	obj := &App{}
+
+	result := obj.WithExiter(exiter)
+
+	assert.Same(t, obj, result)
+	assert.NotNil(t, obj.Exiter)
+}
+
+func TestAppRun(t *testing.T) {
+	var code int
+	root := newRunCommand("Root command", nil)
+	root.On("Run", Args{"a1"}, mock.Anything).Return(&CommandError{Code: 4})
+
+	New("app").WithRoot(root).WithExiter(ExiterFunc(func(c int) {
+		code = c
+	})).Run(context.Background(), []string{"a1"})
+
+	assert.Equal(t, 4, code)
+	root.AssertExpectations(t)
+}
+
+func TestAppRunUsage(t *testing.T) {
+	var code int
+	stderr := &bytes.Buffer{}
+	root := &Command{
+		Subcommands: map[string]ICommand{"sub": newRunCommand("A subcommand", nil)},
+	}
+
+	New("app").WithRoot(root).WithIO(nil, nil, stderr).WithExiter(ExiterFunc(func(c int) {
+		code = c
+	})).Run(context.Background(), []string{"bogus"})
+
+	assert.Equal(t, 2, code)
+	assert.Equal(t, "app: unknown command \"bogus\"\nUsage: app COMMAND [ARGS...]\nSee 'app --help' for more information.\n", stderr.String())
+}
+
+func TestAppDispatchInjector(t *testing.T) {
+	inj := NewInjector()
+	inj.Provide("value")
+	var called string
+	root := &Command{
+		DynamicSubcommands: func(s string, subs Subcommands) {
+			called = s
+		},
+	}
+	obj := New("app").WithRoot(root).WithInjector(inj)
+
+	err := obj.Dispatch(context.Background(), []string{"sub"})
+
+	assert.ErrorIs(t, err, ErrUnknownCommand)
+	assert.Equal(t, "value", called)
+	assert.Len(t, inj.deps, 1)
+}
+
+func TestAppExecuteBase(t *testing.T) {
+	sub := newRunCommand("Sub command", nil)
+	root := &Command{
+		Subcommands: map[string]ICommand{
+			"sub": sub,
+		},
+	}
+	sub.On("Run", Args{"a1", "a2"}, &Invocation{
+		Path:     []string{"app", "sub"},
+		Commands: []ICommand{root, sub},
+		Command:  sub,
+		Args:     []string{"a1", "a2"},
+	}).Return(nil)
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Name:   "app",
+		Root:   root,
+		Stderr: stderr,
+	}
+
+	result := obj.Execute(context.Background(), []string{"sub", "a1", "a2"})
+
+	assert.Equal(t, 0, result)
+	assert.Equal(t, "", stderr.String())
+	sub.AssertExpectations(t)
+}
+
+func TestAppExecuteError(t *testing.T) {
+	root := newRunCommand("Root command", nil)
+	root.On("Run", Args{}, mock.Anything).Return(&CommandError{
+		Err:  assert.AnError,
+		Code: 5,
+	})
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Name:   "app",
+		Root:   root,
+		Stderr: stderr,
+	}
+
+	result := obj.Execute(context.Background(), []string{})
+
+	assert.Equal(t, 5, result)
+	assert.Equal(t, "app: "+assert.AnError.Error()+"\n", stderr.String())
+	root.AssertExpectations(t)
+}
+
+func TestAppExecuteSilentError(t *testing.T) {
+	root := newRunCommand("Root command", nil)
+	root.On("Run", Args{}, mock.Anything).Return(&CommandError{
+		Code: 3,
+	})
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Name:   "app",
+		Root:   root,
+		Stderr: stderr,
+	}
+
+	result := obj.Execute(context.Background(), []string{})
+
+	assert.Equal(t, 3, result)
+	assert.Equal(t, "", stderr.String())
+	root.AssertExpectations(t)
+}
+
+func TestAppExecuteUsageError(t *testing.T) {
+	root := &Command{
+		Subcommands: map[string]ICommand{
+			"sub2":   newRunCommand("Second sub command", nil),
+			"sub1":   newRunCommand("First sub command", nil),
+			"hidden": Hidden(newRunCommand("Hidden command", nil)),
+		},
+	}
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Name:   "app",
+		Root:   root,
+		Stderr: stderr,
+	}
+
+	result := obj.Execute(context.Background(), []string{"bogus"})
+
+	assert.Equal(t, 2, result)
+	assert.Equal(t, `app: unknown command "bogus"
+Usage: app COMMAND [ARGS...]
+See 'app --help' for more information.
+`, stderr.String())
+}
+
+func TestAppExecuteUsageCode(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Name:      "app",
+		Root:      &Command{Subcommands: map[string]ICommand{"sub": &Command{}}},
+		Stdout:    stdout,
+		Stderr:    stderr,
+		UsageCode: 64,
+	}
+
+	result := obj.Execute(context.Background(), []string{"bogus"})
+
+	assert.Equal(t, 64, result)
+	assert.Equal(t, "", stdout.String())
+	assert.Contains(t, stderr.String(), "Usage: app COMMAND [ARGS...]\n")
+}
+
+func TestAppExecuteUsageCodeOther(t *testing.T) {
+	root := newRunCommand("Root", nil)
+	root.On("Run", Args{}, mock.Anything).Return(&CommandError{Err: assert.AnError, Code: 3, Usage: true})
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Name:      "app",
+		Root:      root,
+		Stderr:    stderr,
+		UsageCode: 64,
+	}
+
+	result := obj.Execute(context.Background(), []string{})
+
+	assert.Equal(t, 3, result)
+}
+
+func TestAppExecuteUsageNoSubcommands(t *testing.T) {
+	root := &Command{
+		Subcommands: map[string]ICommand{
+			"sub": &Command{},
+		},
+	}
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Name:   "app",
+		Root:   root,
+		Stderr: stderr,
+	}
+
+	result := obj.Execute(context.Background(), []string{"sub"})
+
+	assert.Equal(t, 2, result)
+	assert.Equal(t, "app: missing command\nUsage: app sub [ARGS...]\nSee 'app sub --help' for more information.\n", stderr.String())
+}
+
+func TestAppDispatchBase(t *testing.T) {
+	root := newRunCommand("Root command", nil)
+	root.On("Run", Args{"a1"}, mock.Anything).Return(nil)
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"a1"})
+
+	assert.NoError(t, err)
+	root.AssertExpectations(t)
+}
+
+type contextCommand struct {
+	Command
+	ctx context.Context
+}
+
+func (c *contextCommand) Run(ctx context.Context) {
+	c.ctx = ctx
+}
+
+type contextKey struct{}
+
+func TestAppDispatchContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	root := &contextCommand{}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(ctx, []string{})
+
+	assert.NoError(t, err)
+	assert.Same(t, ctx, root.ctx)
+}
+
+func TestAppDispatchNilContext(t *testing.T) {
+	root := &contextCommand{}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(nil, []string{}) //nolint:staticcheck
+
+	assert.NoError(t, err)
+	assert.Equal(t, context.Background(), root.ctx)
+}
+
+func TestRunnerFuncImplementsRunner(t *testing.T) {
+	assert.Implements(t, (*Runner)(nil), RunnerFunc(nil))
+}
+
+func TestRunnerFuncRun(t *testing.T) {
+	ctx := context.Background()
+	inv := &Invocation{}
+	inj := NewInjector()
+	called := false
+	obj := RunnerFunc(func(c context.Context, i *Invocation, j *Injector) error {
+		assert.Equal(t, ctx, c)
+		assert.Same(t, inv, i)
+		assert.Same(t, inj, j)
+		called = true
+		return assert.AnError
+	})
+
+	err := obj.Run(ctx, inv, inj)
+
+	assert.Same(t, assert.AnError, err)
+	assert.True(t, called)
+}
+
+func TestAppUse(t *testing.T) {
+	mw1 := func(next Runner) Runner { return next }
+	mw2 := func(next Runner) Runner { return next }
+	obj := &App{}
+
+	result := obj.Use(mw1, mw2)
+
+	assert.Same(t, obj, result)
+	assert.Len(t, obj.middleware, 2)
+}
+
+func TestAppDispatchMiddleware(t *testing.T) {
+	calls := []string{}
+	root := &contextCommand{}
+	mw := func(name string) Middleware {
+		return func(next Runner) Runner {
+			return RunnerFunc(func(ctx context.Context, inv *Invocation, inj *Injector) error {
+				calls = append(calls, name+" before "+inv.Path[0])
+				err := next.Run(context.WithValue(ctx, contextKey{}, name), inv, inj)
+				calls = append(calls, name+" after")
+				return err
+			})
+		}
+	}
+	obj := (&App{
+		Name: "app",
+		Root: root,
+	}).Use(mw("first"), mw("second"))
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"first before app",
+		"second before app",
+		"second after",
+		"first after",
+	}, calls)
+	assert.Equal(t, "second", root.ctx.Value(contextKey{}))
+}
+
+func TestAppDispatchMiddlewareShortCircuit(t *testing.T) {
+	root := newRunCommand("Root command", nil)
+	obj := (&App{
+		Root: root,
+	}).Use(func(next Runner) Runner {
+		return RunnerFunc(func(ctx context.Context, inv *Invocation, inj *Injector) error {
+			return assert.AnError
+		})
+	})
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.Same(t, assert.AnError, err)
+	root.AssertExpectations(t)
+}
+
+func TestAppDispatchRunError(t *testing.T) {
+	root := newRunCommand("Root command", nil)
+	root.On("Run", Args{}, mock.Anything).Return(assert.AnError)
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.Same(t, assert.AnError, err)
+	root.AssertExpectations(t)
+}
+
+func TestAppDispatchNested(t *testing.T) {
+	leaf := newRunCommand("Leaf command", nil)
+	leaf.On("Run", Args{"arg"}, mock.Anything).Return(nil)
+	mid := newRunCommand("Mid command", map[string]ICommand{
+		"leaf": leaf,
+	})
+	root := &Command{
+		Subcommands: map[string]ICommand{
+			"mid": Alias(mid),
+		},
+	}
+	obj := &App{
+		Name: "app",
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"mid", "leaf", "arg"})
+
+	assert.NoError(t, err)
+	leaf.AssertExpectations(t)
+	mid.AssertExpectations(t)
+}
+
+func TestAppDispatchWrapped(t *testing.T) {
+	sub := newRunCommand("Sub command", nil)
+	sub.On("Run", Args{}, mock.Anything).Return(nil)
+	root := &Command{
+		Subcommands: map[string]ICommand{
+			"sub": Hidden(Alias(sub)),
+		},
+	}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"sub"})
+
+	assert.NoError(t, err)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchDynamic(t *testing.T) {
+	sub := newRunCommand("Sub command", nil)
+	sub.On("Run", Args{}, mock.Anything).Return(nil)
+	root := &Command{
+		DynamicSubcommands: func(app *App, subs Subcommands) {
+			subs[app.Name] = sub
+		},
+	}
+	obj := &App{
+		Name: "dynamic",
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"dynamic"})
+
+	assert.NoError(t, err)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchDynamicError(t *testing.T) {
+	root := &Command{
+		DynamicSubcommands: func() error {
+			return assert.AnError
+		},
+	}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"dynamic"})
+
+	assert.Same(t, assert.AnError, err)
+}
+
+func TestAppDispatchHooks(t *testing.T) {
+	calls := []string{}
+	sub := newRunCommand("Sub command", nil)
+	sub.On("Run", Args{}, mock.Anything).Return(nil).Run(func(mock.Arguments) {
+		calls = append(calls, "run")
+	})
+	root := &Command{
+		Subcommands: map[string]ICommand{
+			"sub": WithHooks(Hidden(WithHooks(sub, func() {
+				calls = append(calls, "inner before")
+			}, func() {
+				calls = append(calls, "inner after")
+			})), func(inv *Invocation) {
+				calls = append(calls, "outer before "+inv.Path[1])
+			}, func() {
+				calls = append(calls, "outer after")
+			}),
+		},
+	}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"sub"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"outer before sub",
+		"inner before",
+		"run",
+		"inner after",
+		"outer after",
+	}, calls)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchPersistentHooks(t *testing.T) {
+	calls := []string{}
+	leaf := newRunCommand("Leaf command", nil)
+	leaf.PersistentPreRun = func() {
+		calls = append(calls, "leaf pre")
+	}
+	leaf.On("Run", Args{}, mock.Anything).Return(nil).Run(func(mock.Arguments) {
+		calls = append(calls, "run")
+	})
+	mid := &Command{
+		Subcommands: map[string]ICommand{
+			"leaf": WithHooks(leaf, func() {
+				calls = append(calls, "hook before")
+			}, func() {
+				calls = append(calls, "hook after")
+			}),
+		},
+		PersistentPostRun: func(inv *Invocation) {
+			calls = append(calls, "mid post "+inv.Path[2])
+		},
+	}
+	root := &Command{
+		Subcommands: map[string]ICommand{
+			"mid": Hidden(mid),
+		},
+		PersistentPreRun: func() {
+			calls = append(calls, "root pre")
+		},
+		PersistentPostRun: func() {
+			calls = append(calls, "root post")
+		},
+	}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"mid", "leaf"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"root pre",
+		"leaf pre",
+		"hook before",
+		"run",
+		"hook after",
+		"mid post leaf",
+		"root post",
+	}, calls)
+	leaf.AssertExpectations(t)
+}
+
+func TestAppDispatchPersistentPreRunFails(t *testing.T) {
+	calls := []string{}
+	leaf := newRunCommand("Leaf command", nil)
+	root := &Command{
+		Subcommands: map[string]ICommand{
+			"leaf": leaf,
+		},
+		PersistentPreRun: func() error {
+			calls = append(calls, "root pre")
+			return assert.AnError
+		},
+		PersistentPostRun: func() {
+			calls = append(calls, "root post")
+		},
+	}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"leaf"})
+
+	assert.Same(t, assert.AnError, err)
+	assert.Equal(t, []string{"root pre"}, calls)
+	leaf.AssertExpectations(t)
+}
+
+func TestGetPersistentHooksBase(t *testing.T) {
+	cmd := &Command{}
+
+	result := getPersistentHooks(Hidden(cmd))
+
+	assert.Same(t, cmd, result)
+}
+
+func TestGetPersistentHooksNone(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := getPersistentHooks(Hidden(cmd))
+
+	assert.Nil(t, result)
+}
+
+func TestAppDispatchMissingCommand(t *testing.T) {
+	root := &Command{
+		Subcommands: map[string]ICommand{
+			"sub": &Command{},
+		},
+	}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.ErrorIs(t, err, ErrMissingCommand)
+	code, usage := ExitControl(err)
+	assert.Equal(t, 2, code)
+	assert.True(t, usage)
+}
+
+func TestAppDispatchUnknownCommand(t *testing.T) {
+	root := &Command{
+		Subcommands: map[string]ICommand{
+			"sub": &Command{},
+		},
+	}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"bogus"})
+
+	assert.ErrorIs(t, err, ErrUnknownCommand)
+	code, usage := ExitControl(err)
+	assert.Equal(t, 2, code)
+	assert.True(t, usage)
+}
+
+func TestAppDispatchDeprecated(t *testing.T) {
+	sub := newRunCommand("Sub command", nil)
+	sub.On("Run", Args{}, mock.Anything).Return(nil)
+	root := &Command{
+		Subcommands: map[string]ICommand{
+			"old": Deprecated(sub, "new"),
+		},
+	}
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Name:   "app",
+		Root:   root,
+		Stderr: stderr,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"old"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "app: warning: command \"old\" is deprecated; use \"new\" instead\n", stderr.String())
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchDeprecatedStrict(t *testing.T) {
+	sub := newRunCommand("Sub command", nil)
+	root := &Command{
+		Subcommands: map[string]ICommand{
+			"old": &DeprecatedCommand{
+				Wrapped:  sub,
+				RemoveBy: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Root:              root,
+		Stderr:            stderr,
+		StrictDeprecation: true,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"old"})
+
+	assert.ErrorIs(t, err, ErrRemovedCommand)
+	assert.Equal(t, "", stderr.String())
+	sub.AssertExpectations(t)
+}
+
+type missingValueCommand struct {
+	Command
+}
+
+func (c *missingValueCommand) Run(s string) {}
+
+func TestAppDispatchMissingValue(t *testing.T) {
+	obj := &App{
+		Root: &missingValueCommand{},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.ErrorIs(t, err, depinject.ErrMissingValue)
+}
+
+type RootOptions struct {
+	Verbose bool `opt:"verbose"`
+}
+
+type subOptions struct {
+	RootOptions
+	Name string `opt:"name"`
+	File string `arg:"FILE"`
+}
+
+type optionsCommand struct {
+	Command
+	root    *RootOptions
+	opts    *subOptions
+	streams *IOStreams
+}
+
+func (c *optionsCommand) Run(root *RootOptions, opts *subOptions, streams *IOStreams) {
+	c.root = root
+	c.opts = opts
+	c.streams = streams
+}
+
+func TestAppDispatchOptions(t *testing.T) {
+	sub := &optionsCommand{
+		Command: Command{Defaults: &subOptions{Name: "default"}},
+	}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	stdin := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Root:   root,
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--verbose", "sub", "file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &RootOptions{Verbose: true}, sub.root)
+	assert.Equal(t, &subOptions{
+		RootOptions: RootOptions{Verbose: true},
+		Name:        "default",
+		File:        "file",
+	}, sub.opts)
+	assert.Equal(t, &RootOptions{}, root.Defaults)
+	assert.Equal(t, &subOptions{Name: "default"}, sub.Defaults)
+	assert.Equal(t, &IOStreams{
+		In:     stdin,
+		Out:    stdout,
+		ErrOut: stderr,
+		Help:   stdout,
+	}, sub.streams)
+}
+
+func TestAppDispatchOptionsInvocation(t *testing.T) {
+	sub := newRunCommand("Subcommand", nil)
+	sub.Defaults = &subOptions{}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	obj := &App{
+		Root: root,
+	}
+	sub.On("Run", Args{"file"}, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		inv := args.Get(1).(*Invocation)
+		assert.Equal(t, &subOptions{Name: "n", File: "file"}, inv.Options)
+	})
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "file", "--name", "n"})
+
+	assert.NoError(t, err)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchOptionsNoDefaults(t *testing.T) {
+	sub := newRunCommand("Subcommand", nil)
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	obj := &App{
+		Root: root,
+	}
+	sub.On("Run", Args{"--verbose"}, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		inv := args.Get(1).(*Invocation)
+		assert.Nil(t, inv.Options)
+	})
+
+	err := obj.Dispatch(context.Background(), []string{"--verbose", "sub", "--verbose"})
+
+	assert.NoError(t, err)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchOptionsBadDefaults(t *testing.T) {
+	obj := &App{
+		Root: &Command{Defaults: "bogus"},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	code, usage := ExitControl(err)
+	assert.Equal(t, 1, code)
+	assert.False(t, usage)
+}
+
+func TestAppDispatchOptionsParseError(t *testing.T) {
+	obj := &App{
+		Root: &Command{Defaults: &RootOptions{}},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--bogus"})
+
+	assert.ErrorIs(t, err, ErrUnknownFlag)
+	code, usage := ExitControl(err)
+	assert.Equal(t, 2, code)
+	assert.True(t, usage)
+}
+
+func TestAppDispatchOptionsBindError(t *testing.T) {
+	obj := &App{
+		Root: &Command{Defaults: &subOptions{}},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.ErrorIs(t, err, ErrMissingArgument)
+	code, usage := ExitControl(err)
+	assert.Equal(t, 2, code)
+	assert.True(t, usage)
+}
+
+type executeCommand struct {
+	Command
+	args Args
+}
+
+func (c *executeCommand) Execute(args Args) {
+	c.args = args
+}
+
+func TestAppDispatchExecuteMethod(t *testing.T) {
+	root := &executeCommand{}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"a1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Args{"a1"}, root.args)
+}
+
+type passthroughCommand struct {
+	Command
+	args        Args
+	passthrough Passthrough
+}
+
+func (c *passthroughCommand) Run(args Args, passthrough Passthrough) {
+	c.args = args
+	c.passthrough = passthrough
+}
+
+func TestAppDispatchPassthrough(t *testing.T) {
+	sub := &passthroughCommand{
+		Command: Command{Passthrough: true},
+	}
+	root := &Command{
+		Passthrough: true,
+		Subcommands: map[string]ICommand{"exec": sub},
+	}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"exec", "a1", "--", "ssh", "-v", "--", "host"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Args{"a1"}, sub.args)
+	assert.Equal(t, Passthrough{"ssh", "-v", "--", "host"}, sub.passthrough)
+}
+
+func TestAppDispatchPassthroughNone(t *testing.T) {
+	root := &passthroughCommand{
+		Command: Command{Passthrough: true},
+	}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"a1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Args{"a1"}, root.args)
+	assert.Nil(t, root.passthrough)
+}
+
+func TestAppDispatchUnknownFlagsCollect(t *testing.T) {
+	sub := &passthroughCommand{
+		Command: Command{
+			Defaults:     &RootOptions{},
+			Passthrough:  true,
+			UnknownFlags: UnknownFlagsCollect,
+		},
+	}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"exec": sub},
+	}
+	obj := &App{
+		Root:         root,
+		UnknownFlags: UnknownFlagsCollect,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--root", "exec", "--verbose", "--color=auto", "a1", "--", "host"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Args{"a1"}, sub.args)
+	assert.Equal(t, Passthrough{"--root", "--color=auto", "host"}, sub.passthrough)
+}
+
+func TestAppDispatchUnknownFlagsIgnore(t *testing.T) {
+	root := &passthroughCommand{
+		Command: Command{Defaults: &RootOptions{}},
+	}
+	obj := &App{
+		Root:         root,
+		UnknownFlags: UnknownFlagsIgnore,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--color", "a1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Args{"a1"}, root.args)
+	assert.Nil(t, root.passthrough)
+}
+
+func TestAppDispatchUnknownFlagsCommandOverride(t *testing.T) {
+	root := &passthroughCommand{
+		Command: Command{
+			Defaults:     &RootOptions{},
+			UnknownFlags: UnknownFlagsError,
+		},
+	}
+	obj := &App{
+		Root:         root,
+		Stderr:       &bytes.Buffer{},
+		UnknownFlags: UnknownFlagsIgnore,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--color", "a1"})
+
+	assert.ErrorIs(t, err, ErrUnknownFlag)
+}
+
+func TestAppDispatchStrictOrder(t *testing.T) {
+	sub := &passthroughCommand{
+		Command: Command{
+			Defaults:    &RootOptions{},
+			StrictOrder: true,
+		},
+	}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"exec": sub},
+	}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"exec", "--verbose", "ls", "--verbose"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Args{"ls", "--verbose"}, sub.args)
+}
+
+func TestAppDispatchTerminator(t *testing.T) {
+	root := &passthroughCommand{
+		Command: Command{Defaults: &RootOptions{}},
+	}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"a1", "--", "--verbose", "--", "a2"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Args{"a1", "--verbose", "--", "a2"}, root.args)
+	assert.Nil(t, root.passthrough)
+	assert.Equal(t, &RootOptions{}, root.Defaults)
+}
+
+func TestAppDispatchTerminatorPassthrough(t *testing.T) {
+	root := &passthroughCommand{
+		Command: Command{
+			Defaults:    &RootOptions{},
+			Passthrough: true,
+		},
+	}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"a1", "--verbose", "--", "--verbose", "--", "a2"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Args{"a1"}, root.args)
+	assert.Equal(t, Passthrough{"--verbose", "--", "a2"}, root.passthrough)
+}
+
+func TestAppDispatchPassthroughDisabled(t *testing.T) {
+	root := &passthroughCommand{}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"a1", "--", "a2"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Args{"a1", "--", "a2"}, root.args)
+	assert.Nil(t, root.passthrough)
+}
+
+func TestSplitPassthrough(t *testing.T) {
+	head, tail := splitPassthrough([]string{"a1", "--", "a2", "--"})
+
+	assert.Equal(t, []string{"a1"}, head)
+	assert.Equal(t, []string{"a2", "--"}, tail)
+}
+
+func TestSplitPassthroughNone(t *testing.T) {
+	head, tail := splitPassthrough([]string{"a1", "a2"})
+
+	assert.Equal(t, []string{"a1", "a2"}, head)
+	assert.Nil(t, tail)
+}
+
+type dryRunCommand struct {
+	Command
+	args   Args
+	dryRun DryRun
+}
+
+func (c *dryRunCommand) Run(args Args, dryRun DryRun) {
+	c.args = args
+	c.dryRun = dryRun
+}
+
+func TestAppDispatchDryRun(t *testing.T) {
+	root := &dryRunCommand{}
+	obj := &App{
+		Root:        root,
+		AllowDryRun: true,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"a1", "--dry-run"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Args{"a1"}, root.args)
+	assert.Equal(t, DryRun(true), root.dryRun)
+}
+
+func TestAppDispatchDryRunDisallowed(t *testing.T) {
+	root := &dryRunCommand{}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"a1", "--dry-run"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Args{"a1", "--dry-run"}, root.args)
+	assert.Equal(t, DryRun(false), root.dryRun)
+}
+
+func TestAppDispatchWatch(t *testing.T) {
+	root := newRunCommand("Root command", nil)
+	root.On("Run", Args{"a1"}, mock.Anything).Return(nil).Once()
+	root.On("Run", Args{"a1"}, mock.Anything).Return(assert.AnError).Once()
+	obj := &App{
+		Root:       root,
+		Stdout:     &bytes.Buffer{},
+		AllowWatch: true,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--watch=1ms", "a1"})
+
+	assert.Same(t, assert.AnError, err)
+	root.AssertExpectations(t)
+}
+
+func TestAppDispatchWatchBadInterval(t *testing.T) {
+	root := newRunCommand("Root command", nil)
+	obj := &App{
+		Root:       root,
+		AllowWatch: true,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--watch=bogus", "a1"})
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	code, usage := ExitControl(err)
+	assert.Equal(t, 2, code)
+	assert.True(t, usage)
+	root.AssertExpectations(t)
+}
+
+func TestAppDispatchNotFound(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	root := &Command{
+		Subcommands: map[string]ICommand{"sub": &Command{}},
+	}
+	obj := &App{
+		Root: root,
+		NotFound: func(c context.Context, inv *Invocation, name string, args []string) error {
+			assert.Same(t, ctx, c)
+			assert.Same(t, root, inv.Command)
+			assert.Equal(t, "bogus", name)
+			assert.Equal(t, []string{"a1", "a2"}, args)
+			return assert.AnError
+		},
+	}
+
+	err := obj.Dispatch(ctx, []string{"bogus", "a1", "a2"})
+
+	assert.Same(t, assert.AnError, err)
+}
+
+func TestAppDispatchNotFoundMissing(t *testing.T) {
+	obj := &App{
+		Root: &Command{},
+		NotFound: func(context.Context, *Invocation, string, []string) error {
+			t.Error("unexpected call to NotFound")
+			return nil
+		},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.ErrorIs(t, err, ErrMissingCommand)
+}
+
+func TestAppDispatchDeprecatedFlag(t *testing.T) {
+	root := newRunCommand("Root command", nil)
+	root.Defaults = &deprecatedOptions{}
+	var msgs []string
+	obj := &App{
+		Root: root,
+		WarningHandler: func(msg string) {
+			msgs = append(msgs, msg)
+		},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--old", "v1", "--bogus"})
+
+	assert.ErrorIs(t, err, ErrUnknownFlag)
+	assert.Equal(t, []string{"flag --old is deprecated: use --new"}, msgs)
+}
+
+func TestAppDispatchInsensitiveFlags(t *testing.T) {
+	sub := &optionsCommand{
+		Command: Command{Defaults: &subOptions{}},
+	}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	obj := &App{
+		Root:             root,
+		InsensitiveFlags: true,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--VERBOSE", "sub", "--Name", "n", "file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &subOptions{
+		RootOptions: RootOptions{Verbose: true},
+		Name:        "n",
+		File:        "file",
+	}, sub.opts)
+}
+
+func TestAppHelpFlags(t *testing.T) {
+	type opts struct {
+		Verbose bool   `opt:"verbose,v" help:"Verbose output"`
+		Color   string `opt:"color|colour" help:"Color to use"`
+		Old     string `opt:"old" deprecated:"use --color"`
+		Format  string `opt:"format"`
+	}
+	buf := &bytes.Buffer{}
+	obj := &App{}
+	inv := &Invocation{
+		Path:    []string{"app"},
+		Command: &Command{Defaults: &opts{Color: "red", Format: "json"}},
+	}
+
+	obj.help(buf, inv)
+
+	assert.Equal(t, `Usage: app [flags] [ARGS...]
+
+Flags:
+  -v, --verbose          Verbose output
+      --color, --colour  Color to use (default "red")
+      --format           (default "json")
+`, buf.String())
+}
+
+func TestAppHelpFlagsOptional(t *testing.T) {
+	type opts struct {
+		Color string `opt:"color" help:"Colorize output" optional:"auto"`
+		Pager string `opt:"pager" optional:"less"`
+	}
+	buf := &bytes.Buffer{}
+	obj := &App{}
+	inv := &Invocation{
+		Path:    []string{"app"},
+		Command: &Command{Defaults: &opts{Color: "never"}},
+	}
+
+	obj.help(buf, inv)
+
+	assert.Equal(t, `Usage: app [flags] [ARGS...]
+
+Flags:
+      --color  Colorize output (default "never"; "auto" if no value)
+      --pager  ("less" if no value)
+`, buf.String())
+}
+
+func TestAppHelpFlagsHidden(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{}
+	inv := &Invocation{
+		Path: []string{"app"},
+		Command: &Command{Defaults: &struct {
+			Debug string `opt:"debug" hidden:"true"`
+			Old   string `opt:"old" deprecated:"gone"`
+		}{}},
+	}
+
+	obj.help(buf, inv)
+
+	assert.Equal(t, "Usage: app [ARGS...]\n", buf.String())
+}
+
+func TestAppHelpFlagsHelpAll(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{}
+	inv := &Invocation{
+		Path: []string{"app"},
+		Command: &Command{Defaults: &struct {
+			Debug string `opt:"debug" help:"Debugging mode" hidden:"true"`
+			Old   string `opt:"old" deprecated:"gone"`
+		}{}},
+		helpAll: true,
+	}
+
+	obj.help(buf, inv)
+
+	assert.Equal(t, "Usage: app [flags] [ARGS...]\n\nFlags:\n      --debug  Debugging mode\n      --old    (deprecated: gone)\n", buf.String())
+}
+
+func TestAppHelpBadDefaults(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{}
+	inv := &Invocation{
+		Path:    []string{"app"},
+		Command: &Command{Defaults: "bogus"},
+	}
+
+	obj.help(buf, inv)
+
+	assert.Equal(t, "Usage: app [ARGS...]\n", buf.String())
+}
+
+func TestAppName(t *testing.T) {
+	obj := &App{
+		Name: "app",
+	}
+
+	result := obj.name()
+
+	assert.Equal(t, "app", result)
+}
+
+func TestAppNameDefault(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"/usr/bin/tool"}
+	obj := &App{}
+
+	result := obj.name()
+
+	assert.Equal(t, "tool", result)
+}
+
+func TestAppNameNoArgs(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = nil
+	obj := &App{}
+
+	result := obj.name()
+
+	assert.Equal(t, "", result)
+}
+
+func TestAppExiterDefault(t *testing.T) {
+	obj := &App{}
+
+	result := obj.exiter()
+
+	assert.NotNil(t, result)
+}
+
+func TestAppExiterSet(t *testing.T) {
+	var code int
+	obj := &App{
+		Exiter: ExiterFunc(func(c int) {
+			code = c
+		}),
+	}
+
+	obj.exiter().Exit(5)
+
+	assert.Equal(t, 5, code)
+}
+
+func TestAppStreamsDefault(t *testing.T) {
+	obj := &App{}
+
+	assert.Same(t, os.Stdin, obj.stdin())
+	assert.Same(t, os.Stdout, obj.stdout())
+	assert.Same(t, os.Stderr, obj.stderr())
+}
+
+func TestAppStreamsSet(t *testing.T) {
+	stdin := &bytes.Buffer{}
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+
+	assert.Same(t, stdin, obj.stdin())
+	assert.Same(t, stdout, obj.stdout())
+	assert.Same(t, stderr, obj.stderr())
+}
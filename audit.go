@@ -0,0 +1,266 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AuditKey is the configuration key of the audit settings, honored
+// if the application allows auditing; see WithAudit.  Its value is a
+// section naming the sinks to which each invocation is recorded:
+//
+//	audit:
+//	  file: /var/log/tool/audit.log    # Appends to a file; see FileAuditSink
+//	  syslog: tool                     # Sends to syslog with a tag, or true; see SyslogAuditSink
+//	  url: https://audit.example.com/  # Posts to an endpoint; see HTTPAuditSink
+const AuditKey = "audit"
+
+// ErrBadAudit indicates that the audit settings in the configuration
+// are malformed.
+var ErrBadAudit = errors.New("invalid audit configuration")
+
+// ErrAudit indicates that an invocation could not be recorded by an
+// audit sink.
+var ErrAudit = errors.New("unable to record audit")
+
+// AuditRecord records a command invocation, for auditing.
+type AuditRecord struct {
+	Time     time.Time         `json:"time"`              // Time at which the command started
+	User     string            `json:"user"`              // Name of the user running the command
+	Command  string            `json:"command"`           // Path of the command, as "tool sub"
+	Flags    map[string]string `json:"flags,omitempty"`   // Flags given on the command line; see Invocation.FlagValues
+	ExitCode int               `json:"exit_code"`         // Exit code of the command
+	Duration int64             `json:"duration_ms"`       // Duration of the command in milliseconds
+	Error    string            `json:"error,omitempty"`   // Error that ended the command, if any
+	Version  string            `json:"version,omitempty"` // Version of the application
+}
+
+// AuditSink receives the records of command invocations; see Audit.
+type AuditSink interface {
+	// Record records an invocation.
+	Record(ctx context.Context, rec *AuditRecord) error
+}
+
+// AuditSinkFunc is an adapter allowing an ordinary function to be
+// used as an AuditSink.
+type AuditSinkFunc func(ctx context.Context, rec *AuditRecord) error
+
+// Record records an invocation.
+func (f AuditSinkFunc) Record(ctx context.Context, rec *AuditRecord) error {
+	return f(ctx, rec)
+}
+
+// FileAuditSink is an AuditSink that appends each record to a file,
+// as a line of JSON.  The file is created, readable only by its
+// owner, if it does not exist.
+type FileAuditSink struct {
+	Path string // Path of the file
+}
+
+// Record appends the record to the file.
+func (s *FileAuditSink) Record(ctx context.Context, rec *AuditRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// SyslogAuditSink is an AuditSink that sends each record to the
+// system logger, as JSON, with the facility LOG_AUTH and severity
+// LOG_NOTICE.  It is not available on Windows.
+type SyslogAuditSink struct {
+	Tag string // Tag of the messages; defaults to the executable name
+}
+
+// HTTPAuditSink is an AuditSink that posts each record to an HTTP
+// endpoint, as JSON.  Responses with a status other than 2xx are
+// errors.
+type HTTPAuditSink struct {
+	URL    string       // URL of the endpoint
+	Client *http.Client // Client making the request; defaults to http.DefaultClient
+	Header http.Header  // Optional headers of the request, e.g., for authorization
+}
+
+// Record posts the record to the endpoint.
+func (s *HTTPAuditSink) Record(ctx context.Context, rec *AuditRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for name, values := range s.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s: %s", s.URL, resp.Status)
+	}
+
+	return nil
+}
+
+// Hooks for testing.
+var currentUser = user.Current
+
+// auditUser is a helper that returns the name of the user running
+// the command.
+func auditUser() string {
+	if u, err := currentUser(); err == nil {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+
+	return os.Getenv("USERNAME")
+}
+
+// Audit returns middleware that records each command invocation to
+// the sinks, once the command has run: the time, the user, the
+// command path, the flags given on the command line--with the values
+// of secret flags redacted--the exit code, and the duration; see
+// AuditRecord.  An invocation that cannot be recorded is reported as
+// a warning, wrapping ErrAudit; the command's result is unaffected.
+// Use it with App.Use, or configure the sinks through AuditKey; see
+// WithAudit.
+func Audit(sinks ...AuditSink) Middleware {
+	return func(next Runner) Runner {
+		return RunnerFunc(func(ctx context.Context, inv *Invocation, inj *Injector) error {
+			var clock Clock
+			if !inj.Get(&clock) {
+				clock = defaultClock
+			}
+
+			start := clock.Now()
+			err := next.Run(ctx, inv, inj)
+			rec := &AuditRecord{
+				Time:     start.UTC(),
+				User:     auditUser(),
+				Command:  strings.Join(inv.Path, " "),
+				Flags:    inv.FlagValues(),
+				Duration: clock.Now().Sub(start).Milliseconds(),
+			}
+			if err != nil {
+				rec.ExitCode, _ = ExitControl(err)
+				rec.Error = err.Error()
+			}
+
+			var app *App
+			inj.Get(&app)
+			if app != nil {
+				rec.Version = app.Version
+			}
+			for _, sink := range sinks {
+				if sinkErr := sink.Record(ctx, rec); sinkErr != nil && app != nil {
+					app.Warn("%s", fmt.Errorf("%w: %s", ErrAudit, sinkErr))
+				}
+			}
+
+			return err
+		})
+	}
+}
+
+// WithAudit sets whether invocations are recorded to the sinks
+// configured under AuditKey.  Returns the App, to allow chaining.
+func (a *App) WithAudit(allow bool) *App {
+	a.AllowAudit = allow
+	return a
+}
+
+// auditSinks is a helper that constructs the audit sinks configured
+// under AuditKey.
+func auditSinks(cfg map[string]interface{}) ([]AuditSink, error) {
+	raw, ok := cfg[AuditKey]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	section, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %q must be a section", ErrBadAudit, AuditKey)
+	}
+
+	keys := make([]string, 0, len(section))
+	for key := range section {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sinks := []AuditSink{}
+	for _, key := range keys {
+		switch value := section[key].(type) {
+		case string:
+			switch key {
+			case "file":
+				sinks = append(sinks, &FileAuditSink{Path: value})
+				continue
+			case "url":
+				sinks = append(sinks, &HTTPAuditSink{URL: value})
+				continue
+			case "syslog":
+				sinks = append(sinks, &SyslogAuditSink{Tag: value})
+				continue
+			}
+		case bool:
+			if key == "syslog" {
+				if value {
+					sinks = append(sinks, &SyslogAuditSink{})
+				}
+				continue
+			}
+		}
+
+		return nil, fmt.Errorf("%w: bad value for %s.%s", ErrBadAudit, AuditKey, key)
+	}
+
+	return sinks, nil
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package nelson
+
+import (
+	"context"
+	"encoding/json"
+	"log/syslog"
+)
+
+// Hooks for testing.
+var newSyslog = syslog.New
+
+// Record sends the record to the system logger.
+func (s *SyslogAuditSink) Record(ctx context.Context, rec *AuditRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	w, err := newSyslog(syslog.LOG_AUTH|syslog.LOG_NOTICE, s.Tag)
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package nelson
+
+import (
+	"context"
+	"log/syslog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyslogAuditSinkRecordError(t *testing.T) {
+	old := newSyslog
+	var tag string
+	newSyslog = func(priority syslog.Priority, name string) (*syslog.Writer, error) {
+		tag = name
+		return nil, assert.AnError
+	}
+	defer func() { newSyslog = old }()
+	obj := &SyslogAuditSink{Tag: "tool"}
+
+	err := obj.Record(context.Background(), &AuditRecord{})
+
+	assert.Same(t, assert.AnError, err)
+	assert.Equal(t, "tool", tag)
+}
@@ -0,0 +1,389 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeUser is a helper that makes the currentUser hook report the
+// specified user, or fail if the name is empty, restoring it when
+// the test completes.
+func fakeUser(t *testing.T, name string) {
+	t.Helper()
+
+	old := currentUser
+	currentUser = func() (*user.User, error) {
+		if name == "" {
+			return nil, assert.AnError
+		}
+		return &user.User{Username: name}, nil
+	}
+	t.Cleanup(func() { currentUser = old })
+}
+
+// readAudit is a helper that reads the records from an audit file.
+func readAudit(t *testing.T, path string) []*AuditRecord {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs := []*AuditRecord{}
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		rec := &AuditRecord{}
+		if err := json.Unmarshal([]byte(line), rec); err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, rec)
+	}
+
+	return recs
+}
+
+func TestAuditSinkFuncImplementsAuditSink(t *testing.T) {
+	assert.Implements(t, (*AuditSink)(nil), AuditSinkFunc(nil))
+}
+
+func TestAuditSinkFuncRecord(t *testing.T) {
+	rec := &AuditRecord{}
+	var called *AuditRecord
+	obj := AuditSinkFunc(func(ctx context.Context, r *AuditRecord) error {
+		called = r
+		return assert.AnError
+	})
+
+	err := obj.Record(context.Background(), rec)
+
+	assert.Same(t, assert.AnError, err)
+	assert.Same(t, rec, called)
+}
+
+func TestFileAuditSinkRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	obj := &FileAuditSink{Path: path}
+
+	err1 := obj.Record(context.Background(), &AuditRecord{Command: "tool one"})
+	err2 := obj.Record(context.Background(), &AuditRecord{Command: "tool two", ExitCode: 2})
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	recs := readAudit(t, path)
+	assert.Len(t, recs, 2)
+	assert.Equal(t, "tool one", recs[0].Command)
+	assert.Equal(t, 2, recs[1].ExitCode)
+	info, _ := os.Stat(path)
+	if info != nil && os.PathSeparator == '/' {
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+}
+
+func TestFileAuditSinkRecordError(t *testing.T) {
+	obj := &FileAuditSink{Path: filepath.Join(t.TempDir(), "missing", "audit.log")}
+
+	err := obj.Record(context.Background(), &AuditRecord{})
+
+	assert.Error(t, err)
+}
+
+func TestHTTPAuditSinkRecord(t *testing.T) {
+	var rec AuditRecord
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&rec))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	obj := &HTTPAuditSink{
+		URL:    srv.URL + "/audit",
+		Client: srv.Client(),
+		Header: http.Header{"Authorization": {"Bearer token"}},
+	}
+
+	err := obj.Record(context.Background(), &AuditRecord{Command: "tool sub", User: "alice"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, AuditRecord{Command: "tool sub", User: "alice"}, rec)
+}
+
+func TestHTTPAuditSinkRecordStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+	obj := &HTTPAuditSink{URL: srv.URL}
+
+	err := obj.Record(context.Background(), &AuditRecord{})
+
+	assert.EqualError(t, err, srv.URL+": 403 Forbidden")
+}
+
+func TestHTTPAuditSinkRecordBadURL(t *testing.T) {
+	obj := &HTTPAuditSink{URL: "::bogus"}
+
+	err := obj.Record(context.Background(), &AuditRecord{})
+
+	assert.Error(t, err)
+}
+
+func TestAuditUser(t *testing.T) {
+	fakeUser(t, "alice")
+
+	assert.Equal(t, "alice", auditUser())
+}
+
+func TestAuditUserEnv(t *testing.T) {
+	fakeUser(t, "")
+	setEnv(t, "USER", "bob")
+
+	assert.Equal(t, "bob", auditUser())
+}
+
+func TestAuditUserEnvWindows(t *testing.T) {
+	fakeUser(t, "")
+	setEnv(t, "USER", "")
+	setEnv(t, "USERNAME", "carol")
+
+	assert.Equal(t, "carol", auditUser())
+}
+
+type auditOptions struct {
+	Name  string `opt:"name,n"`
+	Token string `opt:"token" kind:"secret"`
+}
+
+// auditApp is a helper that constructs an application whose command
+// advances the clock by a second, returning the specified error.
+func auditApp(clock *manualClock, err error) *App {
+	root := newRunCommand("Root", nil)
+	root.Defaults = &auditOptions{}
+	root.On("Run", Args{}, mock.Anything).Return(err).Run(func(mock.Arguments) {
+		clock.now = clock.now.Add(time.Second)
+	})
+	return &App{
+		Name:    "tool",
+		Version: "1.2.3",
+		Root:    root,
+		Stdin:   strings.NewReader(""),
+		Clock:   clock,
+	}
+}
+
+func TestAudit(t *testing.T) {
+	fakeUser(t, "alice")
+	start := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	clock := &manualClock{now: start}
+	recs := []*AuditRecord{}
+	obj := auditApp(clock, nil).Use(Audit(AuditSinkFunc(func(ctx context.Context, rec *AuditRecord) error {
+		recs = append(recs, rec)
+		return nil
+	})))
+
+	err := obj.Dispatch(context.Background(), []string{"-n", "name", "--token", "hunter2"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*AuditRecord{
+		{
+			Time:     start,
+			User:     "alice",
+			Command:  "tool",
+			Flags:    map[string]string{"--name": "name", "--token": Redacted},
+			Duration: 1000,
+			Version:  "1.2.3",
+		},
+	}, recs)
+}
+
+func TestAuditError(t *testing.T) {
+	fakeUser(t, "alice")
+	clock := &manualClock{now: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)}
+	recs := []*AuditRecord{}
+	obj := auditApp(clock, &CommandError{Err: assert.AnError, Code: 3}).Use(Audit(AuditSinkFunc(func(ctx context.Context, rec *AuditRecord) error {
+		recs = append(recs, rec)
+		return nil
+	})))
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Len(t, recs, 1)
+	assert.Equal(t, 3, recs[0].ExitCode)
+	assert.Equal(t, assert.AnError.Error(), recs[0].Error)
+}
+
+func TestAuditSinkFailure(t *testing.T) {
+	fakeUser(t, "alice")
+	clock := &manualClock{now: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)}
+	warnings := []string{}
+	recorded := false
+	obj := auditApp(clock, nil).Use(Audit(
+		AuditSinkFunc(func(ctx context.Context, rec *AuditRecord) error {
+			return assert.AnError
+		}),
+		AuditSinkFunc(func(ctx context.Context, rec *AuditRecord) error {
+			recorded = true
+			return nil
+		}),
+	))
+	obj.WarningHandler = func(msg string) {
+		warnings = append(warnings, msg)
+	}
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.NoError(t, err)
+	assert.True(t, recorded)
+	assert.Equal(t, []string{"unable to record audit: " + assert.AnError.Error()}, warnings)
+}
+
+func TestAppWithAudit(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithAudit(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.AllowAudit)
+}
+
+func TestAuditSinks(t *testing.T) {
+	result, err := auditSinks(map[string]interface{}{
+		AuditKey: map[string]interface{}{
+			"file":   "/var/log/audit.log",
+			"syslog": "tool",
+			"url":    "https://audit.example.com/",
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []AuditSink{
+		&FileAuditSink{Path: "/var/log/audit.log"},
+		&SyslogAuditSink{Tag: "tool"},
+		&HTTPAuditSink{URL: "https://audit.example.com/"},
+	}, result)
+}
+
+func TestAuditSinksSyslogBool(t *testing.T) {
+	result, err := auditSinks(map[string]interface{}{
+		AuditKey: map[string]interface{}{"syslog": true},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []AuditSink{&SyslogAuditSink{}}, result)
+}
+
+func TestAuditSinksSyslogDisabled(t *testing.T) {
+	result, err := auditSinks(map[string]interface{}{
+		AuditKey: map[string]interface{}{"syslog": false},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []AuditSink{}, result)
+}
+
+func TestAuditSinksNone(t *testing.T) {
+	result, err := auditSinks(nil)
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestAuditSinksNotSection(t *testing.T) {
+	_, err := auditSinks(map[string]interface{}{AuditKey: "file"})
+
+	assert.ErrorIs(t, err, ErrBadAudit)
+	assert.EqualError(t, err, `invalid audit configuration: "audit" must be a section`)
+}
+
+func TestAuditSinksBadKey(t *testing.T) {
+	_, err := auditSinks(map[string]interface{}{
+		AuditKey: map[string]interface{}{"file": true},
+	})
+
+	assert.ErrorIs(t, err, ErrBadAudit)
+	assert.EqualError(t, err, "invalid audit configuration: bad value for audit.file")
+}
+
+func TestAppDispatchAudit(t *testing.T) {
+	fakeUser(t, "alice")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	config := filepath.Join(dir, "tool.yaml")
+	if err := ioutil.WriteFile(config, []byte("audit:\n  file: "+path+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	clock := &manualClock{now: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)}
+	obj := auditApp(clock, nil)
+	obj.NoConfigSearch = true
+	obj.ConfigFile = config
+	obj.AllowAudit = true
+
+	err := obj.Dispatch(context.Background(), []string{"--name", "x"})
+
+	assert.NoError(t, err)
+	recs := readAudit(t, path)
+	assert.Len(t, recs, 1)
+	assert.Equal(t, "tool", recs[0].Command)
+	assert.Equal(t, map[string]string{"--name": "x"}, recs[0].Flags)
+}
+
+func TestAppDispatchAuditNotAllowed(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	config := filepath.Join(dir, "tool.yaml")
+	if err := ioutil.WriteFile(config, []byte("audit:\n  file: "+path+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	obj := auditApp(&manualClock{}, nil)
+	obj.NoConfigSearch = true
+	obj.ConfigFile = config
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.NoError(t, err)
+	assert.NoFileExists(t, path)
+}
+
+func TestAppDispatchAuditBadConfig(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "tool.yaml")
+	if err := ioutil.WriteFile(config, []byte("audit: yes\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	obj := &App{
+		Root:           newRunCommand("Root", nil),
+		NoConfigSearch: true,
+		ConfigFile:     config,
+		AllowAudit:     true,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.ErrorIs(t, err, ErrBadAudit)
+}
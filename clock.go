@@ -0,0 +1,148 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Clock is the source of time for the framework, used for the times
+// of events and log messages, for checking deprecations, and for
+// timing watched commands and configuration reloads.  The
+// application's clock is available from the injector, so that
+// commands whose behavior depends on time may be tested with a fake
+// clock; see WithClock.  By default, RealClock is used.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTimer returns a timer that fires once after the duration.
+	NewTimer(d time.Duration) Timer
+
+	// NewTicker returns a ticker that fires repeatedly at the
+	// interval.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a timer created by a Clock; see time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered when the
+	// timer fires.
+	C() <-chan time.Time
+
+	// Stop stops the timer, returning false if it has already
+	// fired or been stopped.
+	Stop() bool
+}
+
+// Ticker is a ticker created by a Clock; see time.Ticker.
+type Ticker interface {
+	// C returns the channel on which the time is delivered each
+	// time the ticker fires.
+	C() <-chan time.Time
+
+	// Stop stops the ticker.
+	Stop()
+}
+
+// RealClock is a Clock that uses the system time.
+type RealClock struct{}
+
+// Now returns the current time.
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTimer returns a timer that fires once after the duration.
+func (RealClock) NewTimer(d time.Duration) Timer {
+	return realTimer{t: time.NewTimer(d)}
+}
+
+// NewTicker returns a ticker that fires repeatedly at the interval.
+func (RealClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{t: time.NewTicker(d)}
+}
+
+// realTimer wraps a time.Timer to implement Timer.
+type realTimer struct {
+	t *time.Timer // The timer
+}
+
+// C returns the channel on which the time is delivered.
+func (t realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+// Stop stops the timer.
+func (t realTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+// realTicker wraps a time.Ticker to implement Ticker.
+type realTicker struct {
+	t *time.Ticker // The ticker
+}
+
+// C returns the channel on which the time is delivered.
+func (t realTicker) C() <-chan time.Time {
+	return t.t.C
+}
+
+// Stop stops the ticker.
+func (t realTicker) Stop() {
+	t.t.Stop()
+}
+
+// defaultClock is the Clock used if the application does not set
+// one; it is a hook for testing.
+var defaultClock Clock = RealClock{}
+
+// WithClock sets the application's clock.  Returns the App, to allow
+// chaining.
+func (a *App) WithClock(clock Clock) *App {
+	a.Clock = clock
+	return a
+}
+
+// WithRandSource sets the source of the random numbers available
+// from the injector.  Setting a source with a fixed seed makes
+// commands using random numbers deterministic.  Returns the App, to
+// allow chaining.
+func (a *App) WithRandSource(src rand.Source) *App {
+	a.RandSource = src
+	return a
+}
+
+// clock returns the application's clock.
+func (a *App) clock() Clock {
+	if a.Clock != nil {
+		return a.Clock
+	}
+
+	return defaultClock
+}
+
+// rand returns the random number generator provided by the injector,
+// using the application's source of random numbers, or a source
+// seeded from the clock if it has none.
+func (a *App) rand() *rand.Rand {
+	src := a.RandSource
+	if src == nil {
+		src = rand.NewSource(a.clock().Now().UnixNano())
+	}
+
+	return rand.New(src) //nolint:gosec
+}
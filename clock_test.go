@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixedClock is a Clock whose time never changes.
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func (c fixedClock) NewTimer(d time.Duration) Timer {
+	return RealClock{}.NewTimer(d)
+}
+
+func (c fixedClock) NewTicker(d time.Duration) Ticker {
+	return RealClock{}.NewTicker(d)
+}
+
+func TestRealClockNow(t *testing.T) {
+	before := time.Now()
+
+	result := RealClock{}.Now()
+
+	assert.False(t, result.Before(before))
+}
+
+func TestRealClockNewTimer(t *testing.T) {
+	timer := RealClock{}.NewTimer(time.Millisecond)
+
+	<-timer.C()
+
+	assert.False(t, timer.Stop())
+}
+
+func TestRealClockNewTicker(t *testing.T) {
+	ticker := RealClock{}.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+
+	<-ticker.C()
+	<-ticker.C()
+}
+
+func TestAppWithClock(t *testing.T) {
+	clock := fixedClock(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC))
+	obj := &App{}
+
+	result := obj.WithClock(clock)
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, clock, obj.Clock)
+	assert.Equal(t, clock, obj.clock())
+}
+
+func TestAppClockDefault(t *testing.T) {
+	obj := &App{}
+
+	result := obj.clock()
+
+	assert.Equal(t, RealClock{}, result)
+}
+
+func TestAppWithRandSource(t *testing.T) {
+	src := rand.NewSource(42)
+	obj := &App{}
+
+	result := obj.WithRandSource(src)
+
+	assert.Same(t, obj, result)
+	assert.Same(t, src, obj.RandSource)
+}
+
+func TestAppRandSource(t *testing.T) {
+	obj := (&App{}).WithRandSource(rand.NewSource(42))
+
+	result := obj.rand()
+
+	assert.Equal(t, rand.New(rand.NewSource(42)).Int63(), result.Int63())
+}
+
+func TestAppRandDefault(t *testing.T) {
+	obj := (&App{}).WithClock(fixedClock(time.Unix(0, 42)))
+
+	result := obj.rand()
+
+	assert.Equal(t, rand.New(rand.NewSource(42)).Int63(), result.Int63())
+}
+
+type clockCommand struct {
+	Command
+	now    time.Time
+	random int64
+}
+
+func (c *clockCommand) Run(clock Clock, rnd *rand.Rand) {
+	c.now = clock.Now()
+	c.random = rnd.Int63()
+}
+
+func TestAppDispatchClock(t *testing.T) {
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	cmd := &clockCommand{}
+	obj := (&App{
+		Name: "tool",
+		Root: cmd,
+	}).WithNoConfigSearch(true).WithClock(fixedClock(now)).WithRandSource(rand.NewSource(42))
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, now, cmd.now)
+	assert.Equal(t, rand.New(rand.NewSource(42)).Int63(), cmd.random)
+}
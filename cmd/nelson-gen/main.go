@@ -0,0 +1,87 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Command nelson-gen generates implementations of nelson.FlagStorer
+// for defaults structs, so that the values of their flags and
+// arguments are stored without reflection, and of
+// nelson.StaticRunner for commands, so that their run methods are
+// called without reflection.  It is intended for use with
+// go:generate:
+//
+//	//go:generate nelson-gen -type Options,ServeOptions -command Serve
+//
+// The generated file is named for the first type or command, e.g.,
+// "options_nelson.go", unless -output is given.  Reflection remains
+// the default for types and commands without generated code, and for
+// fields whose types are not supported by the generator.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klmitch/nelson/internal/gen"
+)
+
+func main() {
+	types := flag.String("type", "", "comma-separated list of defaults struct types")
+	commands := flag.String("command", "", "comma-separated list of command types")
+	output := flag.String("output", "", "output file name; defaults to <type>_nelson.go")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: nelson-gen [-type T[,T...]] [-command C[,C...]] [-output FILE] [DIR]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if (*types == "" && *commands == "") || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), split(*types), split(*commands), *output); err != nil {
+		fmt.Fprintf(os.Stderr, "nelson-gen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// split splits a comma-separated list, returning nil if it is empty.
+func split(list string) []string {
+	if list == "" {
+		return nil
+	}
+
+	return strings.Split(list, ",")
+}
+
+// run generates the code for the types and commands declared by the
+// package in the directory, writing it to the output file.
+func run(dir string, types, commands []string, output string) error {
+	if dir == "" {
+		dir = "."
+	}
+	if output == "" {
+		name := append(append([]string{}, types...), commands...)[0]
+		output = filepath.Join(dir, strings.ToLower(name)+"_nelson.go")
+	}
+
+	src, err := gen.Generate(dir, types, commands)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(output, src, 0o644) //nolint:gosec
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "options.go"), []byte("package opts\n\ntype Options struct {\n\tName string `opt:\"name\"`\n}\n"), 0o644))
+
+	err := run(dir, []string{"Options"}, nil, "")
+
+	assert.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "options_nelson.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "func (o *Options) StoreFlag(field, text string) (bool, error) {")
+}
+
+func TestRunOutput(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "options.go"), []byte("package opts\n\ntype Options struct {\n\tName string `opt:\"name\"`\n}\n"), 0o644))
+	output := filepath.Join(dir, "generated.go")
+
+	err := run(dir, []string{"Options"}, nil, output)
+
+	assert.NoError(t, err)
+	assert.FileExists(t, output)
+}
+
+func TestRunError(t *testing.T) {
+	dir := t.TempDir()
+
+	err := run(dir, []string{"Options"}, nil, "")
+
+	assert.Error(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, "options_nelson.go"))
+}
+
+func TestRunCommands(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "serve.go"), []byte("package cmds\n\ntype Serve struct{}\n\nfunc (c *Serve) Run() error {\n\treturn nil\n}\n"), 0o644))
+
+	err := run(dir, nil, []string{"Serve"}, "")
+
+	assert.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "serve_nelson.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "func (c *Serve) RunStatic(inj *nelson.Injector) (interface{}, error) {")
+}
+
+func TestSplit(t *testing.T) {
+	assert.Nil(t, split(""))
+	assert.Equal(t, []string{"a", "b"}, split("a,b"))
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Command nelson scaffolds applications built with nelson:
+//
+//	nelson init --name mytool --module example.com/mytool mytool
+//	nelson new command deploy
+//
+// "init" creates the main.go, root command, sample command, and
+// tests of a new application; "new command" adds a command to an
+// application created by "init".
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/klmitch/nelson"
+	"github.com/klmitch/nelson/internal/scaffold"
+)
+
+// initOptions are the flags and arguments of the init command.
+type initOptions struct {
+	Name   string `opt:"name,n" help:"Name of the application; defaults to the name of the directory"`
+	Module string `opt:"module,m" help:"Module path; if given, a go.mod is created"`
+	Dir    string `arg:"DIR" arity:"[0,1]" help:"Directory to create the application in"`
+}
+
+// initCommand creates a new application.
+type initCommand struct {
+	nelson.Command
+}
+
+// Run runs the init command.
+func (c *initCommand) Run(opts *initOptions, streams *nelson.IOStreams) error {
+	name := opts.Name
+	if name == "" {
+		abs, err := filepath.Abs(opts.Dir)
+		if err != nil {
+			return err
+		}
+		name = filepath.Base(abs)
+	}
+
+	files, err := scaffold.Init(opts.Dir, scaffold.Project{Name: name, Module: opts.Module})
+	report(streams, files)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(streams.Out, "\nNext, run \"go mod tidy\" and \"go test -update\" in %s to create the golden help files.\n", opts.Dir)
+	return nil
+}
+
+// newCommandOptions are the flags and arguments of the "new command"
+// command.
+type newCommandOptions struct {
+	Dir  string `opt:"dir,C" help:"Directory of the application"`
+	Name string `arg:"NAME" help:"Name of the command, in lower case with words separated by hyphens"`
+}
+
+// newCommandCommand adds a command to an application.
+type newCommandCommand struct {
+	nelson.Command
+}
+
+// Run runs the "new command" command.
+func (c *newCommandCommand) Run(opts *newCommandOptions, streams *nelson.IOStreams) error {
+	files, err := scaffold.NewCommand(opts.Dir, opts.Name)
+	report(streams, files)
+	return err
+}
+
+// report is a helper that lists the files created.
+func report(streams *nelson.IOStreams, files []string) {
+	for _, file := range files {
+		fmt.Fprintf(streams.Out, "created %s\n", file)
+	}
+}
+
+// newApp constructs the application.
+func newApp() *nelson.App {
+	return &nelson.App{
+		Name: "nelson",
+		Root: &nelson.Command{
+			Summary: "Scaffold applications built with nelson.",
+			Subcommands: map[string]nelson.ICommand{
+				"init": &initCommand{
+					Command: nelson.Command{
+						Summary:  "Create a new application.",
+						Defaults: &initOptions{Dir: "."},
+					},
+				},
+				"new": &nelson.Command{
+					Summary: "Add to an application.",
+					Subcommands: map[string]nelson.ICommand{
+						"command": &newCommandCommand{
+							Command: nelson.Command{
+								Summary:  "Add a command to an application.",
+								Defaults: &newCommandOptions{Dir: "."},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func main() {
+	newApp().WithNoConfigSearch(true).Run(context.Background(), os.Args[1:])
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/klmitch/nelson/internal/scaffold"
+)
+
+func runApp(args ...string) (string, error) {
+	stdout := &bytes.Buffer{}
+	app := newApp().WithNoConfigSearch(true)
+	app.Stdout = stdout
+
+	err := app.Dispatch(context.Background(), args)
+
+	return stdout.String(), err
+}
+
+func TestInit(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mytool")
+
+	result, err := runApp("init", dir)
+
+	assert.NoError(t, err)
+	assert.Contains(t, result, "created "+filepath.Join(dir, "main.go")+"\n")
+	assert.Contains(t, result, "go test -update")
+	assert.FileExists(t, filepath.Join(dir, "hello.go"))
+}
+
+func TestInitError(t *testing.T) {
+	dir := t.TempDir()
+	_, err := runApp("init", dir)
+	assert.NoError(t, err)
+
+	result, err := runApp("init", dir)
+
+	assert.ErrorIs(t, err, scaffold.ErrExists)
+	assert.Equal(t, "", result)
+}
+
+func TestNewCommand(t *testing.T) {
+	dir := t.TempDir()
+	_, err := runApp("init", "--name", "mytool", dir)
+	assert.NoError(t, err)
+
+	result, err := runApp("new", "command", "-C", dir, "deploy")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "created "+filepath.Join(dir, "deploy.go")+"\ncreated "+filepath.Join(dir, "deploy_test.go")+"\n", result)
+}
+
+func TestNewCommandNoProject(t *testing.T) {
+	_, err := runApp("new", "command", "-C", t.TempDir(), "deploy")
+
+	assert.ErrorIs(t, err, scaffold.ErrNoProject)
+}
@@ -0,0 +1,399 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Errors produced while parsing the command line.
+var (
+	ErrUnknownFlag     = errors.New("unknown flag")
+	ErrMissingValue    = errors.New("missing value for flag")
+	ErrInvalidValue    = errors.New("invalid value")
+	ErrMissingArgument = errors.New("missing argument")
+	ErrTooManyArgs     = errors.New("too many arguments")
+)
+
+// UnknownFlags selects how flags not declared by a command are
+// handled.
+type UnknownFlags int
+
+// Policies for handling unknown flags.
+const (
+	UnknownFlagsDefault UnknownFlags = iota // Use the App's policy; for the App, same as UnknownFlagsError
+	UnknownFlagsError                       // Unknown flags are usage errors
+	UnknownFlagsIgnore                      // Unknown flags are discarded
+	UnknownFlagsCollect                     // Unknown flags are collected into the Passthrough arguments
+)
+
+// usageError is a helper that wraps an error in a CommandError
+// requesting that usage be emitted, with UsageExitCode.
+func usageError(err error) error {
+	return &CommandError{
+		Err:   err,
+		Code:  UsageExitCode,
+		Usage: true,
+	}
+}
+
+// parse parses the command line arguments for a command, setting the
+// flags in the options.  Flags may be interspersed with positional
+// arguments, unless the options require strict ordering, in which
+// case the first positional argument ends flag parsing.  Parsing
+// stops at the first argument naming a subcommand, provided no
+// positional arguments precede it; the positional arguments and the
+// index of the subcommand name--or -1 if there was none--are
+// returned.  Negative numbers are positional arguments if the
+// command declares a numeric argument; see isNegativeNumber.  If help
+// is requested, ErrHelp or ErrHelpAll is returned; see HelpFlag.  The
+// argument "--" also ends flag parsing; it is discarded, and all
+// following arguments are positional, even if they begin with a dash
+// or name a subcommand.  (For commands that accept passthrough
+// arguments, the arguments following "--" have already been split
+// off; see IPassthrough.)  If the options are nil, the command has no
+// defaults and flags are not parsed; "--" is then an ordinary
+// positional argument.
+func (o *options) parse(args []string, subs map[string]ICommand) ([]string, int, error) {
+	positional := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		// Handle the end of the options
+		if o != nil && arg == "--" {
+			return append(positional, args[i+1:]...), -1, nil
+		}
+
+		// Handle requests for help
+		if o.isHelp(arg, positional) {
+			if arg == HelpAllFlag {
+				return positional, -1, ErrHelpAll
+			}
+			return positional, -1, ErrHelp
+		}
+
+		// Translate Windows-style flags
+		if flag, ok := o.windowsFlag(arg); ok {
+			args = append(append(append([]string(nil), args[:i]...), flag), args[i+1:]...)
+			arg = flag
+		}
+
+		// Handle positional arguments
+		if o == nil || arg == "-" || !strings.HasPrefix(arg, "-") || o.isNegativeNumber(arg) {
+			if _, ok := subs[arg]; ok && len(positional) == 0 {
+				return positional, i, nil
+			}
+			if o != nil && o.strict {
+				return append(positional, args[i:]...), -1, nil
+			}
+			positional = append(positional, arg)
+			continue
+		}
+
+		// Handle the flag
+		var err error
+		if strings.HasPrefix(arg, "--") {
+			i, err = o.parseLong(args, i)
+		} else {
+			i, err = o.parseShort(args, i)
+		}
+		if err != nil {
+			return positional, -1, err
+		}
+	}
+
+	return positional, -1, nil
+}
+
+// isNegativeNumber determines whether an argument beginning with a
+// dash is a negative number, such as "-5" or "-0.5", that should be
+// treated as a positional argument.  This is only the case if the
+// options declare a numeric argument and no short flag matches the
+// first character following the dash.
+func (o *options) isNegativeNumber(arg string) bool {
+	if !o.Set.hasNumericArg() {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(arg[1:])
+	if _, ok := o.Set.short[string(r)]; ok {
+		return false
+	}
+
+	if _, err := strconv.ParseFloat(arg, 64); err == nil {
+		return true
+	}
+	_, err := strconv.ParseInt(arg, 0, 64)
+	return err == nil
+}
+
+// parseLong parses the long flag at the specified index of the
+// arguments.  The value of the flag may be assigned, as in
+// "--name=value", in which case it may be empty or begin with a
+// dash; this also allows a boolean or counting flag to be set
+// explicitly.  Otherwise, a flag that takes a value consumes the
+// following argument, unless its value is optional, in which case
+// the flag's OptionalTag value is used.  Returns the index of the
+// last argument consumed.
+func (o *options) parseLong(args []string, i int) (int, error) {
+	name, value := args[i], ""
+	assigned := false
+	if eq := strings.Index(name, "="); eq >= 0 {
+		name, value = name[:eq], name[eq+1:]
+		assigned = true
+	}
+	opt, ok := o.lookupLong(name[2:])
+	if !ok {
+		return i, o.unknownFlag(name, args[i])
+	}
+	o.used(opt, name)
+
+	// Get the value
+	if !assigned {
+		if !opt.TakesValue() {
+			o.mark(opt)
+			return i, nil
+		}
+		if opt.Optional != nil {
+			return i, o.set(opt, name, *opt.Optional)
+		}
+		if i+1 >= len(args) {
+			return i, fmt.Errorf("%w %s", ErrMissingValue, name)
+		}
+		i++
+		value = args[i]
+	}
+
+	return i, o.set(opt, name, value)
+}
+
+// lookupLong looks up a flag by its long name.  If the options are
+// insensitive, a flag whose name is equal after normalization also
+// matches; an exact match is preferred, and otherwise the first flag
+// declared is selected.
+func (o *options) lookupLong(name string) (*option, bool) {
+	if opt, ok := o.Set.long[name]; ok || !o.insensitive {
+		return opt, ok
+	}
+
+	name = normalizeFlag(name)
+	for _, opt := range o.Set.Options {
+		for _, other := range append([]string{opt.Name}, opt.Aliases...) {
+			if other != "" && normalizeFlag(other) == name {
+				return opt, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// normalizeFlag is a helper that normalizes a long flag name for
+// insensitive matching: the name is converted to lower case, and
+// underscores are converted to dashes.
+func normalizeFlag(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+}
+
+// parseShort parses the short flags at the specified index of the
+// arguments.  Several boolean short flags may be combined, as in
+// "-abc"; the last flag may take a value, which is either the
+// remainder of the argument, as in "-ovalue", or the following
+// argument.  If the value is optional, only the remainder of the
+// argument is considered, and the flag's OptionalTag value is used if
+// it is empty.  Returns the index of the last argument consumed.
+func (o *options) parseShort(args []string, i int) (int, error) {
+	arg := args[i]
+	for j := 1; j < len(arg); {
+		r, size := utf8.DecodeRuneInString(arg[j:])
+		j += size
+		name := "-" + string(r)
+		opt, ok := o.Set.short[string(r)]
+		if !ok {
+			return i, o.unknownFlag(name, "-"+arg[j-size:])
+		}
+		o.used(opt, name)
+
+		// Handle flags that don't take values
+		if !opt.TakesValue() {
+			o.mark(opt)
+			continue
+		}
+
+		// Get the value
+		value := arg[j:]
+		if value == "" && opt.Optional != nil {
+			value = *opt.Optional
+		} else if value == "" {
+			if i+1 >= len(args) {
+				return i, fmt.Errorf("%w %s", ErrMissingValue, name)
+			}
+			i++
+			value = args[i]
+		}
+
+		return i, o.set(opt, name, value)
+	}
+
+	return i, nil
+}
+
+// unknownFlag handles an unknown flag according to the policy of the
+// options.  The name is the flag as given on the command line, and
+// the text is what is collected: the entire argument for a long
+// flag, or the unknown flag and the remainder of the argument for a
+// short flag.  Note that the value of an unknown flag cannot be
+// recognized unless it is assigned, as in "--name=value".
+func (o *options) unknownFlag(name, text string) error {
+	switch o.unknown {
+	case UnknownFlagsIgnore:
+		return nil
+
+	case UnknownFlagsCollect:
+		o.collected = append(o.collected, text)
+		return nil
+
+	default:
+		return fmt.Errorf("%w %s", ErrUnknownFlag, name)
+	}
+}
+
+// collectedFlags returns the unknown flags collected while parsing.
+func (o *options) collectedFlags() []string {
+	if o == nil {
+		return nil
+	}
+
+	return o.collected
+}
+
+// used records the use of a flag, generating a warning if the flag
+// is deprecated.  The name is the flag as given on the command line.
+// The first use of a slice flag replaces the values from the
+// defaults, the configuration, or the environment.
+func (o *options) used(opt *option, name string) {
+	if o.sources[opt.String()] != SourceFlag {
+		if field := o.Field(opt.Index); isList(field.Type()) {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+	o.setSource(opt, SourceFlag)
+	if opt.Deprecated == "" {
+		return
+	}
+
+	warning := fmt.Sprintf("flag %s is deprecated: %s", name, opt.Deprecated)
+	for _, tmp := range o.deprecated {
+		if tmp == warning {
+			return
+		}
+	}
+	o.deprecated = append(o.deprecated, warning)
+}
+
+// warnings returns the warnings for any deprecated flags that were
+// used.
+func (o *options) warnings() []string {
+	if o == nil {
+		return nil
+	}
+
+	return o.deprecated
+}
+
+// mark sets a flag that does not take a value: boolean flags are set
+// to true, and counting flags are incremented.  Pointers to booleans
+// are set to a newly allocated true value.
+func (o *options) mark(opt *option) {
+	v := o.Field(opt.Index)
+	switch {
+	case opt.Kind == KindCount:
+		v.SetInt(v.Int() + 1)
+
+	case v.Kind() == reflect.Ptr:
+		tmp := reflect.New(v.Type().Elem())
+		tmp.Elem().SetBool(true)
+		v.Set(tmp)
+
+	default:
+		v.SetBool(true)
+	}
+}
+
+// set sets a flag to the specified value, which is read from
+// standard input if the value is "-" and the flag permits it.  The
+// name is the flag as given on the command line, for use in error
+// messages.
+func (o *options) set(opt *option, name, value string) error {
+	value, err := o.fromStdin(name, opt.Stdin, value)
+	if err != nil {
+		return err
+	}
+	if err := o.store(opt, value); err != nil {
+		return fmt.Errorf("%w %s for %s: %s", ErrInvalidValue, opt.Display(value), name, err)
+	}
+
+	return nil
+}
+
+// bind binds positional arguments to the argument fields of the
+// options.  Each argument receives as many values as its arity
+// allows; values bound to a variadic argument replace its default.
+// Values are converted to the types of the fields, and conversion
+// errors identify the position of the offending argument.  A value
+// of "-" is read from standard input if the argument permits it.
+// If the options declare no arguments, no action is taken.
+func (o *options) bind(positional []string) error {
+	if o == nil || len(o.Set.Args) == 0 {
+		return nil
+	}
+
+	pos := 0
+	for _, arg := range o.Set.Args {
+		if int64(len(positional)) < arg.Arity.Start {
+			return fmt.Errorf("%w %s", ErrMissingArgument, arg.Name)
+		}
+
+		// Select the values for the argument
+		count := len(positional)
+		if int64(count) >= arg.Arity.End {
+			count = int(arg.Arity.End - 1)
+		}
+		field := o.Field(arg.Index)
+		if count > 0 && arg.Variadic() {
+			field.Set(reflect.Zero(field.Type()))
+		}
+		for _, value := range positional[:count] {
+			pos++
+			value, err := o.fromStdin(arg.Name, arg.Stdin, value)
+			if err != nil {
+				return err
+			}
+			if err := o.storeField(arg.Field, arg.Index, value, arg.Layouts, arg.Choices); err != nil {
+				return fmt.Errorf("%w %q for argument %d (%s): %s", ErrInvalidValue, value, pos, arg.Name, err)
+			}
+		}
+		positional = positional[count:]
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("%w: %s", ErrTooManyArgs, strings.Join(positional, " "))
+	}
+
+	return nil
+}
@@ -0,0 +1,769 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageError(t *testing.T) {
+	result := usageError(assert.AnError)
+
+	assert.Equal(t, &CommandError{
+		Err:   assert.AnError,
+		Code:  UsageExitCode,
+		Usage: true,
+	}, result)
+}
+
+func TestOptionsParse(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	positional, next, err := obj.parse([]string{"--verbose", "a1", "--name", "n", "--tag", "t1", "-", "--tag", "t2"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1", "-"}, positional)
+	assert.Equal(t, -1, next)
+	assert.Equal(t, &testOptions{
+		Verbose: true,
+		Name:    "n",
+		Tags:    []string{"t1", "t2"},
+	}, obj.Value.Interface())
+}
+
+func TestOptionsParseShortOnly(t *testing.T) {
+	type opts struct {
+		Verbose bool   `opt:",v"`
+		Name    string `opt:"name,"`
+	}
+	obj, _ := newOptions(&Command{Defaults: &opts{}})
+
+	_, _, err := obj.parse([]string{"-v", "--name", "n"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &opts{Verbose: true, Name: "n"}, obj.Value.Interface())
+	src, _ := obj.source("-v")
+	assert.Equal(t, SourceFlag, src)
+}
+
+func TestOptionsParseShortOnlyLong(t *testing.T) {
+	type opts struct {
+		Verbose bool `opt:",v"`
+	}
+	obj, _ := newOptions(&Command{Defaults: &opts{}})
+
+	_, _, err := obj.parse([]string{"--v"}, nil)
+
+	assert.ErrorIs(t, err, ErrUnknownFlag)
+}
+
+func TestOptionsParseAssigned(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{Verbose: true, Name: "default"}})
+
+	positional, _, err := obj.parse([]string{"--verbose=false", "--count=-3", "--tag=", "--tag=a=b", "a1"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, positional)
+	assert.Equal(t, &testOptions{
+		Name:  "default",
+		Count: -3,
+		Tags:  []string{"", "a=b"},
+	}, obj.Value.Interface())
+}
+
+func TestOptionsParseAssignedEmpty(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{Name: "default"}})
+
+	_, _, err := obj.parse([]string{"--name="}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &testOptions{}, obj.Value.Interface())
+}
+
+func TestOptionsParseSeparateDash(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	_, _, err := obj.parse([]string{"--name", "-x"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &testOptions{Name: "-x"}, obj.Value.Interface())
+}
+
+type insensitiveOptions struct {
+	LogLevel  string `opt:"log-level|loglevel"`
+	LogLevel2 string `opt:"Log_Level"`
+	Verbose   bool   `opt:"verbose,v"`
+}
+
+func TestOptionsLookupLongExact(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &insensitiveOptions{}})
+	obj.insensitive = true
+
+	result, ok := obj.lookupLong("Log_Level")
+
+	assert.True(t, ok)
+	assert.Same(t, obj.Set.Options[1], result)
+}
+
+func TestOptionsLookupLongSensitive(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &insensitiveOptions{}})
+
+	result, ok := obj.lookupLong("LOG-LEVEL")
+
+	assert.False(t, ok)
+	assert.Nil(t, result)
+}
+
+func TestOptionsLookupLongInsensitive(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &insensitiveOptions{}})
+	obj.insensitive = true
+
+	result, ok := obj.lookupLong("LOG_LEVEL")
+
+	assert.True(t, ok)
+	assert.Same(t, obj.Set.Options[0], result)
+}
+
+func TestOptionsLookupLongInsensitiveAlias(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &insensitiveOptions{}})
+	obj.insensitive = true
+
+	result, ok := obj.lookupLong("LogLevel")
+
+	assert.True(t, ok)
+	assert.Same(t, obj.Set.Options[0], result)
+}
+
+func TestOptionsLookupLongInsensitiveMissing(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &insensitiveOptions{}})
+	obj.insensitive = true
+
+	result, ok := obj.lookupLong("log-levels")
+
+	assert.False(t, ok)
+	assert.Nil(t, result)
+}
+
+func TestNormalizeFlag(t *testing.T) {
+	result := normalizeFlag("Log_Level")
+
+	assert.Equal(t, "log-level", result)
+}
+
+func TestOptionsParseInsensitive(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+	obj.insensitive = true
+
+	_, _, err := obj.parse([]string{"--Verbose", "--NAME=n"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &testOptions{Verbose: true, Name: "n"}, obj.Value.Interface())
+}
+
+func TestOptionsParseInsensitiveShort(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+	obj.insensitive = true
+
+	_, _, err := obj.parse([]string{"-V"}, nil)
+
+	assert.ErrorIs(t, err, ErrUnknownFlag)
+}
+
+func TestOptionsParseAssignedUnknown(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	_, _, err := obj.parse([]string{"--bogus=value"}, nil)
+
+	assert.ErrorIs(t, err, ErrUnknownFlag)
+	assert.EqualError(t, err, "unknown flag --bogus")
+}
+
+func TestOptionsParseAssignedInvalidBool(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	_, _, err := obj.parse([]string{"--verbose=bogus"}, nil)
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+}
+
+type countOptions struct {
+	Verbose int  `opt:"verbose,v" kind:"count"`
+	Quiet   bool `opt:"quiet,q"`
+}
+
+func TestOptionsParseCount(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &countOptions{}})
+
+	positional, _, err := obj.parse([]string{"-vqv", "a1", "--verbose", "-v"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, positional)
+	assert.Equal(t, &countOptions{Verbose: 4, Quiet: true}, obj.Value.Interface())
+}
+
+func TestOptionsParseCountAssigned(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &countOptions{}})
+
+	_, _, err := obj.parse([]string{"-v", "--verbose=3", "-v"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &countOptions{Verbose: 4}, obj.Value.Interface())
+}
+
+func TestOptionsParseTimeError(t *testing.T) {
+	type opts struct {
+		Since time.Time `opt:"since" layout:"2006-01-02"`
+	}
+	obj, _ := newOptions(&Command{Defaults: &opts{}})
+
+	_, _, err := obj.parse([]string{"--since", "yesterday"}, nil)
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.EqualError(t, err, `invalid value "yesterday" for --since: time does not match layout: expected 2006-01-02`)
+}
+
+func TestOptionsParseChoices(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &choicesOptions{}})
+
+	_, _, err := obj.parse([]string{"--format", "table", "--output=yaml", "--outputs", "json"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &choicesOptions{
+		Format:  "table",
+		Output:  "yaml",
+		Outputs: []testFormat{"json"},
+	}, obj.Value.Interface())
+}
+
+func TestOptionsParseBadChoice(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &choicesOptions{}})
+
+	_, _, err := obj.parse([]string{"--format", "xml"}, nil)
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.EqualError(t, err, `invalid value "xml" for --format: must be one of json, yaml, table`)
+}
+
+type deprecatedOptions struct {
+	Old string `opt:"old,o" deprecated:"use --new"`
+	New string `opt:"new"`
+}
+
+func TestOptionsParseDeprecated(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &deprecatedOptions{}})
+
+	_, _, err := obj.parse([]string{"--old", "v1", "-o", "v2", "--old=v3", "--new", "v4"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &deprecatedOptions{Old: "v3", New: "v4"}, obj.Value.Interface())
+	assert.Equal(t, []string{
+		"flag --old is deprecated: use --new",
+		"flag -o is deprecated: use --new",
+	}, obj.warnings())
+}
+
+func TestOptionsWarningsNil(t *testing.T) {
+	var obj *options
+
+	result := obj.warnings()
+
+	assert.Nil(t, result)
+}
+
+func TestOptionsParseAlias(t *testing.T) {
+	type opts struct {
+		Color []string `opt:"color|colour"`
+	}
+	obj, _ := newOptions(&Command{Defaults: &opts{}})
+
+	_, _, err := obj.parse([]string{"--color", "red", "--colour=blue"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &opts{Color: []string{"red", "blue"}}, obj.Value.Interface())
+}
+
+func TestOptionsParseSubcommand(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+	subs := map[string]ICommand{"sub": &Command{}}
+
+	positional, next, err := obj.parse([]string{"--verbose", "sub", "--name", "n"}, subs)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, positional)
+	assert.Equal(t, 1, next)
+	assert.Equal(t, &testOptions{Verbose: true}, obj.Value.Interface())
+}
+
+func TestOptionsParseSubcommandAfterPositional(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+	subs := map[string]ICommand{"sub": &Command{}}
+
+	positional, next, err := obj.parse([]string{"a1", "sub"}, subs)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1", "sub"}, positional)
+	assert.Equal(t, -1, next)
+}
+
+func TestOptionsParseStrictOrder(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+	obj.strict = true
+
+	positional, next, err := obj.parse([]string{"--verbose", "ls", "-l", "--name", "n"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ls", "-l", "--name", "n"}, positional)
+	assert.Equal(t, -1, next)
+	assert.Equal(t, &testOptions{Verbose: true}, obj.Value.Interface())
+}
+
+func TestOptionsParseStrictOrderSubcommand(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+	obj.strict = true
+
+	positional, next, err := obj.parse([]string{"--verbose", "sub", "-l"}, map[string]ICommand{"sub": &Command{}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, positional)
+	assert.Equal(t, 1, next)
+}
+
+func TestOptionsParseTerminator(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	positional, next, err := obj.parse([]string{"--verbose", "a1", "--", "--name", "sub", "--", "-"}, map[string]ICommand{"sub": &Command{}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1", "--name", "sub", "--", "-"}, positional)
+	assert.Equal(t, -1, next)
+	assert.Equal(t, &testOptions{Verbose: true}, obj.Value.Interface())
+}
+
+func TestOptionsParseTerminatorNil(t *testing.T) {
+	var obj *options
+
+	positional, next, err := obj.parse([]string{"a1", "--", "--name"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1", "--", "--name"}, positional)
+	assert.Equal(t, -1, next)
+}
+
+func TestOptionsParseNil(t *testing.T) {
+	var obj *options
+	subs := map[string]ICommand{"sub": &Command{}}
+
+	positional, next, err := obj.parse([]string{"--verbose", "sub"}, subs)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--verbose", "sub"}, positional)
+	assert.Equal(t, -1, next)
+}
+
+func TestOptionsParseNilSubcommand(t *testing.T) {
+	var obj *options
+	subs := map[string]ICommand{"sub": &Command{}}
+
+	positional, next, err := obj.parse([]string{"sub", "--verbose"}, subs)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, positional)
+	assert.Equal(t, 0, next)
+}
+
+func TestOptionsParseShort(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	positional, next, err := obj.parse([]string{"-v", "a1", "-c", "3"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, positional)
+	assert.Equal(t, -1, next)
+	assert.Equal(t, &testOptions{Verbose: true, Count: 3}, obj.Value.Interface())
+}
+
+func TestOptionsParseShortCombined(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	positional, _, err := obj.parse([]string{"-vc", "3", "a1"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, positional)
+	assert.Equal(t, &testOptions{Verbose: true, Count: 3}, obj.Value.Interface())
+}
+
+func TestOptionsParseShortAttached(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	positional, _, err := obj.parse([]string{"-vc3", "a1"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, positional)
+	assert.Equal(t, &testOptions{Verbose: true, Count: 3}, obj.Value.Interface())
+}
+
+type optionalValueOptions struct {
+	Verbose bool   `opt:"verbose,v"`
+	Color   string `opt:"color,c" optional:"auto"`
+}
+
+func TestOptionsParseOptionalBare(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &optionalValueOptions{Color: "never"}})
+
+	positional, _, err := obj.parse([]string{"--color", "a1"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, positional)
+	assert.Equal(t, &optionalValueOptions{Color: "auto"}, obj.Value.Interface())
+}
+
+func TestOptionsParseOptionalAssigned(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &optionalValueOptions{}})
+
+	positional, _, err := obj.parse([]string{"--color=always", "a1"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, positional)
+	assert.Equal(t, &optionalValueOptions{Color: "always"}, obj.Value.Interface())
+}
+
+func TestOptionsParseOptionalShortBare(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &optionalValueOptions{}})
+
+	positional, _, err := obj.parse([]string{"-vc", "a1"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, positional)
+	assert.Equal(t, &optionalValueOptions{Verbose: true, Color: "auto"}, obj.Value.Interface())
+}
+
+func TestOptionsParseOptionalShortAttached(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &optionalValueOptions{}})
+
+	positional, _, err := obj.parse([]string{"-calways", "a1"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, positional)
+	assert.Equal(t, &optionalValueOptions{Color: "always"}, obj.Value.Interface())
+}
+
+type pointerOptions struct {
+	Force    *bool `opt:"force,f"`
+	Replicas *int  `opt:"replicas,r"`
+}
+
+func TestOptionsParsePointers(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &pointerOptions{}})
+
+	_, _, err := obj.parse([]string{"--force", "--replicas=0"}, nil)
+
+	assert.NoError(t, err)
+	value := obj.Value.Interface().(*pointerOptions)
+	assert.True(t, *value.Force)
+	assert.Equal(t, 0, *value.Replicas)
+}
+
+func TestOptionsParsePointersShort(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &pointerOptions{}})
+
+	_, _, err := obj.parse([]string{"-fr", "3"}, nil)
+
+	assert.NoError(t, err)
+	value := obj.Value.Interface().(*pointerOptions)
+	assert.True(t, *value.Force)
+	assert.Equal(t, 3, *value.Replicas)
+}
+
+func TestOptionsParsePointersUnset(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &pointerOptions{}})
+
+	_, _, err := obj.parse([]string{}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &pointerOptions{}, obj.Value.Interface())
+}
+
+type numericOptions struct {
+	Verbose bool      `opt:"verbose,v"`
+	Five    bool      `opt:"five,5"`
+	Values  []float64 `arg:"VALUE" arity:"[0,)"`
+}
+
+func TestOptionsParseNegativeNumbers(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &numericOptions{}})
+
+	positional, _, err := obj.parse([]string{"-v", "-0.5", "-3", "-0x10", "-Inf"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-0.5", "-3", "-0x10", "-Inf"}, positional)
+	assert.Equal(t, &numericOptions{Verbose: true}, obj.Value.Interface())
+}
+
+func TestOptionsParseNegativeNumbersShortFlag(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &numericOptions{}})
+
+	positional, _, err := obj.parse([]string{"-5"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, positional)
+	assert.Equal(t, &numericOptions{Five: true}, obj.Value.Interface())
+}
+
+func TestOptionsParseNegativeNumbersNotNumber(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &numericOptions{}})
+
+	_, _, err := obj.parse([]string{"-1x"}, nil)
+
+	assert.ErrorIs(t, err, ErrUnknownFlag)
+}
+
+func TestOptionsParseNegativeNumbersNoNumericArg(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	_, _, err := obj.parse([]string{"-3"}, nil)
+
+	assert.ErrorIs(t, err, ErrUnknownFlag)
+}
+
+func TestOptionsBindNegativeNumbers(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &numericOptions{}})
+	positional, _, _ := obj.parse([]string{"-0.5", "2"}, nil)
+
+	err := obj.bind(positional)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &numericOptions{Values: []float64{-0.5, 2}}, obj.Value.Interface())
+}
+
+func TestOptionsParseShortUnknown(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	_, _, err := obj.parse([]string{"-vx"}, nil)
+
+	assert.ErrorIs(t, err, ErrUnknownFlag)
+	assert.EqualError(t, err, "unknown flag -x")
+}
+
+func TestOptionsParseShortMissingValue(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	_, _, err := obj.parse([]string{"-vc"}, nil)
+
+	assert.ErrorIs(t, err, ErrMissingValue)
+	assert.EqualError(t, err, "missing value for flag -c")
+}
+
+func TestOptionsParseShortInvalidValue(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	_, _, err := obj.parse([]string{"-cbogus"}, nil)
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+}
+
+func TestOptionsParseUnknown(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	_, _, err := obj.parse([]string{"--bogus"}, nil)
+
+	assert.ErrorIs(t, err, ErrUnknownFlag)
+	assert.EqualError(t, err, "unknown flag --bogus")
+}
+
+func TestOptionsParseUnknownIgnore(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+	obj.unknown = UnknownFlagsIgnore
+
+	positional, _, err := obj.parse([]string{"--bogus", "a1", "-vxc", "--bogus=value"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, positional)
+	assert.Equal(t, &testOptions{Verbose: true}, obj.Value.Interface())
+	assert.Nil(t, obj.collected)
+}
+
+func TestOptionsParseUnknownCollect(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+	obj.unknown = UnknownFlagsCollect
+
+	positional, _, err := obj.parse([]string{"--bogus", "a1", "-vxc", "--bogus=value"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, positional)
+	assert.Equal(t, &testOptions{Verbose: true}, obj.Value.Interface())
+	assert.Equal(t, []string{"--bogus", "-xc", "--bogus=value"}, obj.collected)
+}
+
+func TestOptionsParseUnknownError(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+	obj.unknown = UnknownFlagsError
+
+	_, _, err := obj.parse([]string{"--bogus"}, nil)
+
+	assert.ErrorIs(t, err, ErrUnknownFlag)
+}
+
+func TestOptionsParseMissingValue(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	_, _, err := obj.parse([]string{"--name"}, nil)
+
+	assert.ErrorIs(t, err, ErrMissingValue)
+	assert.EqualError(t, err, "missing value for flag --name")
+}
+
+func TestOptionsParseInvalidValue(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	_, _, err := obj.parse([]string{"--count", "bogus"}, nil)
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+}
+
+func TestOptionsBind(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	err := obj.bind([]string{"file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &testOptions{File: "file"}, obj.Value.Interface())
+}
+
+func TestOptionsBindNil(t *testing.T) {
+	var obj *options
+
+	err := obj.bind([]string{"a1"})
+
+	assert.NoError(t, err)
+}
+
+func TestOptionsBindNoArgs(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &EmbeddedOptions{}})
+
+	err := obj.bind([]string{"a1"})
+
+	assert.NoError(t, err)
+}
+
+func TestOptionsBindMissing(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	err := obj.bind([]string{})
+
+	assert.ErrorIs(t, err, ErrMissingArgument)
+	assert.EqualError(t, err, "missing argument FILE")
+}
+
+func TestOptionsBindTooMany(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	err := obj.bind([]string{"file", "a2", "a3"})
+
+	assert.ErrorIs(t, err, ErrTooManyArgs)
+	assert.EqualError(t, err, "too many arguments: a2 a3")
+}
+
+func TestOptionsBindBadChoice(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &choicesOptions{}})
+
+	err := obj.bind([]string{"c"})
+
+	assert.EqualError(t, err, `invalid value "c" for argument 1 (KIND): must be one of a, b`)
+}
+
+func TestOptionsBindInvalid(t *testing.T) {
+	type opts struct {
+		Count int `arg:"COUNT"`
+	}
+	obj, _ := newOptions(&Command{Defaults: &opts{}})
+
+	err := obj.bind([]string{"bogus"})
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+}
+
+func TestOptionsCollectedFlagsBase(t *testing.T) {
+	obj := &options{collected: []string{"--bogus"}}
+
+	result := obj.collectedFlags()
+
+	assert.Equal(t, []string{"--bogus"}, result)
+}
+
+func TestOptionsCollectedFlagsNil(t *testing.T) {
+	var obj *options
+
+	result := obj.collectedFlags()
+
+	assert.Nil(t, result)
+}
+
+type variadicOptions struct {
+	Dest  string   `arg:"DEST"`
+	Ports []int    `arg:"PORTS" arity:"[1,3]"`
+	Extra []string `opt:"extra"`
+}
+
+type optionalOptions struct {
+	Files []string `arg:"FILES" arity:"[0,)"`
+}
+
+func TestOptionsBindVariadic(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &variadicOptions{Ports: []int{80}}})
+
+	err := obj.bind([]string{"host", "1", "0x10", "3"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &variadicOptions{Dest: "host", Ports: []int{1, 16, 3}}, obj.Value.Interface())
+}
+
+func TestOptionsBindVariadicMissing(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &variadicOptions{}})
+
+	err := obj.bind([]string{"host"})
+
+	assert.ErrorIs(t, err, ErrMissingArgument)
+	assert.EqualError(t, err, "missing argument PORTS")
+}
+
+func TestOptionsBindVariadicTooMany(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &variadicOptions{}})
+
+	err := obj.bind([]string{"host", "1", "2", "3", "4"})
+
+	assert.ErrorIs(t, err, ErrTooManyArgs)
+	assert.EqualError(t, err, "too many arguments: 4")
+}
+
+func TestOptionsBindVariadicInvalid(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &variadicOptions{}})
+
+	err := obj.bind([]string{"host", "1", "bogus"})
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.EqualError(t, err, `invalid value "bogus" for argument 3 (PORTS): not a number`)
+}
+
+func TestOptionsBindVariadicEmpty(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &optionalOptions{Files: []string{"."}}})
+
+	err := obj.bind([]string{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &optionalOptions{Files: []string{"."}}, obj.Value.Interface())
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"io"
+	"os"
+)
+
+// NoColorFlag is the global flag that disables coloring, if the
+// application has a theme; see WithTheme.
+const NoColorFlag = "--no-color"
+
+// NoColorEnv is the environment variable that disables coloring if
+// it is set to a non-empty value; see https://no-color.org/.
+const NoColorEnv = "NO_COLOR"
+
+// Theme describes the ANSI colors used in help and error messages,
+// and by Styler.  Each style is a sequence of SGR parameters, such as
+// "1" for bold or "1;31" for bold red; an empty style leaves the text
+// uncolored.
+type Theme struct {
+	Heading string // Style of section headings in help
+	Command string // Style of subcommand names in help
+	Flag    string // Style of flag labels in help
+	Error   string // Style of the prefix of error messages, and of errors
+	Success string // Style of messages reporting success
+	Warning string // Style of warnings
+	Bold    string // Style of emphasized text
+	Dim     string // Style of deemphasized text
+}
+
+// DefaultTheme is a theme suitable for most terminals.
+var DefaultTheme = &Theme{
+	Heading: "1",
+	Command: "36",
+	Flag:    "32",
+	Error:   "1;31",
+	Success: "32",
+	Warning: "33",
+	Bold:    "1",
+	Dim:     "2",
+}
+
+// WithTheme sets the theme used to color help and error messages.
+// Coloring is disabled if the output is not a terminal, if the
+// NoColorEnv environment variable is set, or if the global
+// NoColorFlag is given.  A nil theme disables coloring and the
+// NoColorFlag.  Returns the App, to allow chaining.
+func (a *App) WithTheme(theme *Theme) *App {
+	a.Theme = theme
+	return a
+}
+
+// paint colors text with the specified style.  If the theme is nil,
+// the text is returned unchanged.
+func (t *Theme) paint(style, text string) string {
+	if t == nil || style == "" || text == "" {
+		return text
+	}
+
+	return "\x1b[" + style + "m" + text + "\x1b[0m"
+}
+
+// theme returns the theme used to color output written to the
+// specified stream, or nil if the output should not be colored.
+func (a *App) theme(w io.Writer, noColor bool) *Theme {
+	return colorTheme(a.Theme, w, noColor)
+}
+
+// colorTheme is a helper that returns the theme used to color output
+// written to the specified stream, or nil if the output should not be
+// colored: if the theme is nil, if coloring is disabled by the
+// NoColorFlag or the NoColorEnv environment variable, or if the
+// stream is not a terminal that interprets ANSI escape sequences.
+func colorTheme(theme *Theme, w io.Writer, noColor bool) *Theme {
+	if theme == nil || noColor || os.Getenv(NoColorEnv) != "" {
+		return nil
+	}
+	if !streamIsTerminal(w) || !enableVT(w.(*os.File)) {
+		return nil
+	}
+
+	return theme
+}
+
+// extractNoColor is a helper that removes the NoColorFlag from the
+// arguments preceding any "--", returning whether it was present and
+// the remaining arguments.
+func extractNoColor(args []string) (bool, []string) {
+	found := false
+	result := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+		if arg == NoColorFlag {
+			found = true
+			continue
+		}
+		result = append(result, arg)
+	}
+
+	return found, result
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// terminalFile is a helper that constructs a file, standing in for a
+// terminal, to which output may be written.  The fakeTerminal helper
+// must be used to make it appear to be a terminal.
+func terminalFile(t *testing.T) *os.File {
+	t.Helper()
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "tty"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	return f
+}
+
+// fileContent is a helper that reads the content written to a file.
+func fileContent(t *testing.T, f *os.File) string {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestAppWithTheme(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithTheme(DefaultTheme)
+
+	assert.Same(t, obj, result)
+	assert.Same(t, DefaultTheme, obj.Theme)
+}
+
+func TestThemePaintBase(t *testing.T) {
+	obj := &Theme{}
+
+	result := obj.paint("1;31", "text")
+
+	assert.Equal(t, "\x1b[1;31mtext\x1b[0m", result)
+}
+
+func TestThemePaintNil(t *testing.T) {
+	var obj *Theme
+
+	result := obj.paint("1;31", "text")
+
+	assert.Equal(t, "text", result)
+}
+
+func TestThemePaintNoStyle(t *testing.T) {
+	obj := &Theme{}
+
+	result := obj.paint("", "text")
+
+	assert.Equal(t, "text", result)
+}
+
+func TestThemePaintNoText(t *testing.T) {
+	obj := &Theme{}
+
+	result := obj.paint("1", "")
+
+	assert.Equal(t, "", result)
+}
+
+func TestAppThemeBase(t *testing.T) {
+	setEnv(t, NoColorEnv, "")
+	fakeTerminal(t, true, nil)
+	obj := &App{Theme: DefaultTheme}
+
+	result := obj.theme(os.Stdout, false)
+
+	assert.Same(t, DefaultTheme, result)
+}
+
+func TestAppThemeUnset(t *testing.T) {
+	setEnv(t, NoColorEnv, "")
+	fakeTerminal(t, true, nil)
+	obj := &App{}
+
+	result := obj.theme(os.Stdout, false)
+
+	assert.Nil(t, result)
+}
+
+func TestAppThemeNoColorFlag(t *testing.T) {
+	setEnv(t, NoColorEnv, "")
+	fakeTerminal(t, true, nil)
+	obj := &App{Theme: DefaultTheme}
+
+	result := obj.theme(os.Stdout, true)
+
+	assert.Nil(t, result)
+}
+
+func TestAppThemeNoColorEnv(t *testing.T) {
+	setEnv(t, NoColorEnv, "1")
+	fakeTerminal(t, true, nil)
+	obj := &App{Theme: DefaultTheme}
+
+	result := obj.theme(os.Stdout, false)
+
+	assert.Nil(t, result)
+}
+
+func TestAppThemeNotTerminal(t *testing.T) {
+	setEnv(t, NoColorEnv, "")
+	fakeTerminal(t, false, nil)
+	obj := &App{Theme: DefaultTheme}
+
+	result := obj.theme(os.Stdout, false)
+
+	assert.Nil(t, result)
+}
+
+func TestAppThemeNotFile(t *testing.T) {
+	setEnv(t, NoColorEnv, "")
+	fakeTerminal(t, true, nil)
+	obj := &App{Theme: DefaultTheme}
+
+	result := obj.theme(&bytes.Buffer{}, false)
+
+	assert.Nil(t, result)
+}
+
+func TestExtractNoColorBase(t *testing.T) {
+	found, args := extractNoColor([]string{"sub", NoColorFlag, "arg", "--", NoColorFlag})
+
+	assert.True(t, found)
+	assert.Equal(t, []string{"sub", "arg", "--", NoColorFlag}, args)
+}
+
+func TestExtractNoColorMissing(t *testing.T) {
+	found, args := extractNoColor([]string{"sub", "arg"})
+
+	assert.False(t, found)
+	assert.Equal(t, []string{"sub", "arg"}, args)
+}
+
+func TestThemeFuncsNil(t *testing.T) {
+	funcs := themeFuncs(nil)
+
+	assert.Equal(t, "text", funcs["heading"].(func(string) string)("text"))
+	assert.Equal(t, "text", funcs["command"].(func(string) string)("text"))
+	assert.Equal(t, "text", funcs["flag"].(func(string) string)("text"))
+}
+
+func TestThemeFuncsBase(t *testing.T) {
+	funcs := themeFuncs(&Theme{Heading: "1", Command: "2", Flag: "3"})
+
+	assert.Equal(t, "\x1b[1mtext\x1b[0m", funcs["heading"].(func(string) string)("text"))
+	assert.Equal(t, "\x1b[2mtext\x1b[0m", funcs["command"].(func(string) string)("text"))
+	assert.Equal(t, "\x1b[3mtext\x1b[0m", funcs["flag"].(func(string) string)("text"))
+}
+
+func TestAppDispatchHelpColored(t *testing.T) {
+	setEnv(t, NoColorEnv, "")
+	fakeTerminal(t, true, nil)
+	stdout := terminalFile(t)
+	obj := &App{
+		Name:   "app",
+		Root:   &Command{Subcommands: map[string]ICommand{"sub": &Command{Summary: "A subcommand"}}},
+		Stdout: stdout,
+		Theme:  DefaultTheme,
+		Width:  80,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"help"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "\x1b[1mUsage:\x1b[0m app COMMAND [ARGS...]\n\n"+
+		"\x1b[1mAvailable commands:\x1b[0m\n"+
+		"  \x1b[36mhelp\x1b[0m  Show help for a command\n"+
+		"  \x1b[36msub \x1b[0m  A subcommand\n", fileContent(t, stdout))
+}
+
+func TestAppDispatchHelpNoColor(t *testing.T) {
+	setEnv(t, NoColorEnv, "")
+	fakeTerminal(t, true, nil)
+	stdout := terminalFile(t)
+	obj := &App{
+		Name:   "app",
+		Root:   &Command{Subcommands: map[string]ICommand{"sub": &Command{Summary: "A subcommand"}}},
+		Stdout: stdout,
+		Theme:  DefaultTheme,
+		Width:  80,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{NoColorFlag, "help"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Usage: app COMMAND [ARGS...]
+
+Available commands:
+  help  Show help for a command
+  sub   A subcommand
+`, fileContent(t, stdout))
+}
+
+func TestAppExecuteErrorColored(t *testing.T) {
+	setEnv(t, NoColorEnv, "")
+	fakeTerminal(t, true, nil)
+	stderr := terminalFile(t)
+	obj := &App{
+		Name:   "app",
+		Root:   &Command{},
+		Stderr: stderr,
+		Theme:  &Theme{Error: "31"},
+		Width:  80,
+	}
+
+	result := obj.Execute(context.Background(), []string{"--bogus"})
+
+	assert.Equal(t, 2, result)
+	assert.Equal(t, "\x1b[31mapp:\x1b[0m unknown command \"--bogus\"\nUsage: app [ARGS...]\nSee 'app --help' for more information.\n", fileContent(t, stderr))
+}
@@ -14,6 +14,17 @@
 
 package nelson
 
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DateLayout is the layout used when formatting dates, such as the
+// deprecation dates of a DeprecatedCommand.
+const DateLayout = "2006-01-02"
+
 // ICommand is an interface for a command type.  A declared command
 // must implement this interface.
 type ICommand interface {
@@ -37,11 +48,28 @@ type ICommand interface {
 // Command describes a command.  This type is intended for embedding,
 // and implements the ICommand interface.
 type Command struct {
-	Summary     string              // The summary of the command
-	Description string              // The full description of the command
-	Group       string              // An optional group name for grouping related subcommands
-	Subcommands map[string]ICommand // Subcommands of the command
-	Defaults    interface{}         // Defaults for arguments
+	Summary            string                 // The summary of the command
+	Description        string                 // The full description of the command
+	Group              string                 // An optional group name for grouping related subcommands
+	Subcommands        map[string]ICommand    // Subcommands of the command
+	Defaults           interface{}            // Defaults for arguments
+	DynamicSubcommands interface{}            // Optional function to compute additional subcommands
+	PersistentPreRun   interface{}            // Optional function to call before this command or any descendant runs
+	PersistentPostRun  interface{}            // Optional function to call after this command or any descendant runs
+	Passthrough        bool                   // If true, arguments following "--" are passed through verbatim
+	UnknownFlags       UnknownFlags           // Handling of unknown flags; defaults to the App's policy
+	StrictOrder        bool                   // If true, the first positional argument ends flag parsing
+	ShallowDefaults    bool                   // If true, the values the defaults refer to are not copied for each invocation
+	Groups             []Group                // Optional descriptions of the groups of the subcommands, in the order listed in help
+	UsageLine          string                 // Optional usage line, following the command path, overriding the synthesized line
+	Examples           string                 // Optional examples of using the command, shown in its help
+	Translations       map[string]Translation // Optional translations of the summary and description, by language
+	UsageTemplate      string                 // Optional template for the command's usage message; see WithTemplates
+	HelpTemplate       string                 // Optional template for the command's full help; see WithTemplates
+	CommandOrder       SortOrder              // Order of the subcommands in help; defaults to SortAlphabetical
+	FlagOrder          SortOrder              // Order of the flags in help; defaults to SortDeclaration
+	Weight             int                    // Weight of the command, for parents listing subcommands by SortWeight
+	SeeAlso            []string               // Optional paths of related commands below the root, as in "remote add"
 }
 
 // GetSummary retrieves the command summary.
@@ -69,6 +97,442 @@ func (c *Command) GetDefaults() interface{} {
 	return c.Defaults
 }
 
+// GetDynamicSubcommands retrieves the function used to compute
+// additional subcommands for this command.
+func (c *Command) GetDynamicSubcommands() interface{} {
+	return c.DynamicSubcommands
+}
+
+// GetPersistentPreRun retrieves the function to call before this
+// command or any of its descendants is run.
+func (c *Command) GetPersistentPreRun() interface{} {
+	return c.PersistentPreRun
+}
+
+// GetPersistentPostRun retrieves the function to call after this
+// command or any of its descendants is run.
+func (c *Command) GetPersistentPostRun() interface{} {
+	return c.PersistentPostRun
+}
+
+// GetPassthrough retrieves whether arguments following "--" are
+// passed through to this command verbatim.
+func (c *Command) GetPassthrough() bool {
+	return c.Passthrough
+}
+
+// GetUnknownFlags retrieves the policy for handling unknown flags
+// given to this command.
+func (c *Command) GetUnknownFlags() UnknownFlags {
+	return c.UnknownFlags
+}
+
+// GetStrictOrder retrieves whether the first positional argument
+// given to this command ends flag parsing.
+func (c *Command) GetStrictOrder() bool {
+	return c.StrictOrder
+}
+
+// GetShallowDefaults retrieves whether the defaults for this command
+// are copied shallowly for each invocation.
+func (c *Command) GetShallowDefaults() bool {
+	return c.ShallowDefaults
+}
+
+// GetGroups retrieves the descriptions of the groups of this
+// command's subcommands.
+func (c *Command) GetGroups() []Group {
+	return c.Groups
+}
+
+// GetUsageLine retrieves the usage line of this command, which
+// follows the command path.
+func (c *Command) GetUsageLine() string {
+	return c.UsageLine
+}
+
+// GetTranslations retrieves the translations of this command's
+// summary and description, by language.
+func (c *Command) GetTranslations() map[string]Translation {
+	return c.Translations
+}
+
+// GetExamples retrieves the examples of using this command.
+func (c *Command) GetExamples() string {
+	return c.Examples
+}
+
+// GetUsageTemplate retrieves the template used to render the usage
+// message for this command.
+func (c *Command) GetUsageTemplate() string {
+	return c.UsageTemplate
+}
+
+// GetHelpTemplate retrieves the template used to render the full
+// help for this command.
+func (c *Command) GetHelpTemplate() string {
+	return c.HelpTemplate
+}
+
+// GetCommandOrder retrieves the order in which this command's
+// subcommands are listed in help.
+func (c *Command) GetCommandOrder() SortOrder {
+	return c.CommandOrder
+}
+
+// GetFlagOrder retrieves the order in which this command's flags are
+// listed in help.
+func (c *Command) GetFlagOrder() SortOrder {
+	return c.FlagOrder
+}
+
+// GetWeight retrieves the weight of this command.
+func (c *Command) GetWeight() int {
+	return c.Weight
+}
+
+// GetSeeAlso retrieves the paths of the commands related to this
+// command.
+func (c *Command) GetSeeAlso() []string {
+	return c.SeeAlso
+}
+
+// IPersistentHooks is an optional interface for commands that have
+// hooks to run around the execution of the command and all of its
+// descendants.  The hook functions are called through the injector,
+// so they may declare any arguments available from the injector,
+// and may return nothing or an error.  Pre-run hooks are called from
+// the root of the command path down, and post-run hooks are called
+// in the reverse order.  If a pre-run hook fails, neither the
+// command nor any later hooks are run; otherwise, the post-run hooks
+// are called regardless of whether the command succeeded.
+type IPersistentHooks interface {
+	// GetPersistentPreRun retrieves the function to call before
+	// this command or any of its descendants is run.
+	GetPersistentPreRun() interface{}
+
+	// GetPersistentPostRun retrieves the function to call after
+	// this command or any of its descendants is run.
+	GetPersistentPostRun() interface{}
+}
+
+// IPassthrough is an optional interface for commands that wrap other
+// programs.  If GetPassthrough returns true, all arguments following
+// the first "--" are delivered to the command verbatim as
+// Passthrough, rather than being parsed as flags or positional
+// arguments.
+type IPassthrough interface {
+	// GetPassthrough retrieves whether arguments following "--"
+	// are passed through to this command verbatim.
+	GetPassthrough() bool
+}
+
+// IsPassthrough is a helper that determines whether arguments
+// following "--" are passed through to a command verbatim.  It
+// examines the command and any commands it wraps, returning the
+// result from the first that implements IPassthrough.
+func IsPassthrough(cmd ICommand) bool {
+	for cmd != nil {
+		if tmp, ok := cmd.(IPassthrough); ok {
+			return tmp.GetPassthrough()
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return false
+}
+
+// IUnknownFlags is an optional interface for commands that select
+// how unknown flags are handled, typically commands that wrap other
+// programs.
+type IUnknownFlags interface {
+	// GetUnknownFlags retrieves the policy for handling unknown
+	// flags given to this command.
+	GetUnknownFlags() UnknownFlags
+}
+
+// GetUnknownFlags is a helper that retrieves the policy for handling
+// unknown flags given to a command.  It examines the command and any
+// commands it wraps, returning the result from the first that
+// implements IUnknownFlags.  Returns UnknownFlagsDefault if none
+// does.
+func GetUnknownFlags(cmd ICommand) UnknownFlags {
+	for cmd != nil {
+		if tmp, ok := cmd.(IUnknownFlags); ok {
+			return tmp.GetUnknownFlags()
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return UnknownFlagsDefault
+}
+
+// IStrictOrder is an optional interface for commands that require
+// strict POSIX ordering of their arguments.  If GetStrictOrder
+// returns true, the first positional argument ends flag parsing, and
+// all following arguments are positional even if they begin with a
+// dash.  Otherwise, flags and positional arguments may be
+// interspersed, in the GNU style.  Strict ordering is typically
+// needed by commands that wrap other programs, as in "tool exec ls
+// -l".
+type IStrictOrder interface {
+	// GetStrictOrder retrieves whether the first positional
+	// argument given to this command ends flag parsing.
+	GetStrictOrder() bool
+}
+
+// IsStrictOrder is a helper that determines whether a command
+// requires strict POSIX ordering of its arguments.  It examines the
+// command and any commands it wraps, returning the result from the
+// first that implements IStrictOrder.
+func IsStrictOrder(cmd ICommand) bool {
+	for cmd != nil {
+		if tmp, ok := cmd.(IStrictOrder); ok {
+			return tmp.GetStrictOrder()
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return false
+}
+
+// Group describes a group of related subcommands.  Subcommands are
+// placed in groups by their GetGroup method, and are listed under a
+// heading for each group in the help of their parent command.
+type Group struct {
+	Name  string // Name of the group; empty for ungrouped subcommands
+	Title string // Heading of the group in help; defaults to the name
+}
+
+// IGroups is an optional interface for commands that describe the
+// groups of their subcommands.  The groups are listed in the order
+// given, followed by any undescribed groups in alphabetical order.
+// The ungrouped subcommands are listed first unless a group with an
+// empty name is described.
+type IGroups interface {
+	// GetGroups retrieves the descriptions of the groups of this
+	// command's subcommands.
+	GetGroups() []Group
+}
+
+// GetGroups is a helper that retrieves the descriptions of the
+// groups of a command's subcommands.  It examines the command and
+// any commands it wraps, returning the result from the first that
+// implements IGroups.
+func GetGroups(cmd ICommand) []Group {
+	for cmd != nil {
+		if tmp, ok := cmd.(IGroups); ok {
+			return tmp.GetGroups()
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return nil
+}
+
+// ISortOrder is an optional interface for commands that control the
+// order in which their subcommands and flags are listed in help.
+type ISortOrder interface {
+	// GetCommandOrder retrieves the order in which this command's
+	// subcommands are listed in help.
+	GetCommandOrder() SortOrder
+
+	// GetFlagOrder retrieves the order in which this command's
+	// flags are listed in help.
+	GetFlagOrder() SortOrder
+}
+
+// getSortOrder is a helper that retrieves the ISortOrder
+// implementation for a command, if any.  It examines the command and
+// any commands it wraps.
+func getSortOrder(cmd ICommand) ISortOrder {
+	for cmd != nil {
+		if tmp, ok := cmd.(ISortOrder); ok {
+			return tmp
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return nil
+}
+
+// IWeight is an optional interface for commands that have a weight,
+// which determines their position when their parent lists its
+// subcommands by SortWeight.
+type IWeight interface {
+	// GetWeight retrieves the weight of this command.
+	GetWeight() int
+}
+
+// GetWeight is a helper that retrieves the weight of a command.  It
+// examines the command and any commands it wraps, returning the
+// result from the first that implements IWeight.
+func GetWeight(cmd ICommand) int {
+	for cmd != nil {
+		if tmp, ok := cmd.(IWeight); ok {
+			return tmp.GetWeight()
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return 0
+}
+
+// ISeeAlso is an optional interface for commands that refer to
+// related commands, which are listed in their help.  Each reference
+// is the path of a command below the root, with the names separated
+// by spaces, as in "remote add"; see CheckSeeAlso.
+type ISeeAlso interface {
+	// GetSeeAlso retrieves the paths of the commands related to
+	// this command.
+	GetSeeAlso() []string
+}
+
+// GetSeeAlso is a helper that retrieves the paths of the commands
+// related to a command.  It examines the command and any commands it
+// wraps, returning the result from the first that implements
+// ISeeAlso.
+func GetSeeAlso(cmd ICommand) []string {
+	for cmd != nil {
+		if tmp, ok := cmd.(ISeeAlso); ok {
+			return tmp.GetSeeAlso()
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return nil
+}
+
+// IUsageLine is an optional interface for commands that need a
+// custom usage line in their help, such as "[flags] SRC... DEST".
+// Otherwise, the usage line is composed from the command's flags,
+// subcommands, and positional arguments.
+type IUsageLine interface {
+	// GetUsageLine retrieves the usage line of this command,
+	// which follows the command path.
+	GetUsageLine() string
+}
+
+// GetUsageLine is a helper that retrieves the custom usage line of a
+// command, which follows the command path.  It examines the command
+// and any commands it wraps, returning the result from the first
+// that implements IUsageLine.
+func GetUsageLine(cmd ICommand) string {
+	for cmd != nil {
+		if tmp, ok := cmd.(IUsageLine); ok {
+			return tmp.GetUsageLine()
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return ""
+}
+
+// ITranslations is an optional interface for commands that provide
+// their summary and description in other languages.  The keys are
+// languages, as in "fr" or "fr_FR"; see WithLocale.
+type ITranslations interface {
+	// GetTranslations retrieves the translations of this
+	// command's summary and description, by language.
+	GetTranslations() map[string]Translation
+}
+
+// GetTranslations is a helper that retrieves the translations of a
+// command's summary and description.  It examines the command and
+// any commands it wraps, returning the result from the first that
+// implements ITranslations.
+func GetTranslations(cmd ICommand) map[string]Translation {
+	for cmd != nil {
+		if tmp, ok := cmd.(ITranslations); ok {
+			return tmp.GetTranslations()
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return nil
+}
+
+// IExamples is an optional interface for commands that provide
+// examples of their use, which are shown in their help.
+type IExamples interface {
+	// GetExamples retrieves the examples of using this command.
+	GetExamples() string
+}
+
+// GetExamples is a helper that retrieves the examples of using a
+// command.  It examines the command and any commands it wraps,
+// returning the result from the first that implements IExamples.
+func GetExamples(cmd ICommand) string {
+	for cmd != nil {
+		if tmp, ok := cmd.(IExamples); ok {
+			return tmp.GetExamples()
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return ""
+}
+
 // IWrapped is an interface for commands that wrap other commands.  It
 // allows the other commands to be unwrapped.
 type IWrapped interface {
@@ -120,11 +584,113 @@ func (c *HiddenCommand) Unwrap() ICommand {
 	return c.Wrapped
 }
 
+// IsHidden returns true if the command should be hidden from usage
+// messages.  A HiddenCommand is always hidden.
+func (c *HiddenCommand) IsHidden() bool {
+	return true
+}
+
+// IHidden is an interface for commands that control whether they are
+// visible in usage messages.
+type IHidden interface {
+	// IsHidden returns true if the command should be hidden from
+	// usage messages.
+	IsHidden() bool
+}
+
+// IsHidden is a helper that determines whether a command should be
+// hidden from usage messages.  It examines the command and any
+// commands it wraps, and returns true if any of them indicate that
+// the command should be hidden.
+func IsHidden(cmd ICommand) bool {
+	for cmd != nil {
+		if tmp, ok := cmd.(IHidden); ok && tmp.IsHidden() {
+			return true
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return false
+}
+
+// HiddenUnlessCommand wraps a command, causing it to be hidden from
+// the usage message unless a predicate returns true.  This allows
+// internal or debugging commands to be made visible only under
+// certain conditions.
+type HiddenUnlessCommand struct {
+	Wrapped   ICommand    // Wrapped command
+	Predicate func() bool // Predicate; if true, command is visible
+}
+
+// HiddenUnless wraps a command to indicate that it should be hidden
+// from usage messages unless the predicate returns true.
+func HiddenUnless(cmd ICommand, predicate func() bool) *HiddenUnlessCommand {
+	return &HiddenUnlessCommand{
+		Wrapped:   cmd,
+		Predicate: predicate,
+	}
+}
+
+// HiddenUnlessEnv wraps a command to indicate that it should be
+// hidden from usage messages unless the named environment variable
+// is set to a true value, as interpreted by strconv.ParseBool.
+func HiddenUnlessEnv(cmd ICommand, name string) *HiddenUnlessCommand {
+	return HiddenUnless(cmd, func() bool {
+		result, _ := strconv.ParseBool(os.Getenv(name))
+		return result
+	})
+}
+
+// GetSummary retrieves the command summary.
+func (c *HiddenUnlessCommand) GetSummary() string {
+	return c.Wrapped.GetSummary()
+}
+
+// GetDescription retrieves the command's full description.
+func (c *HiddenUnlessCommand) GetDescription() string {
+	return c.Wrapped.GetDescription()
+}
+
+// GetGroup retrieves the group name of the command.
+func (c *HiddenUnlessCommand) GetGroup() string {
+	return c.Wrapped.GetGroup()
+}
+
+// GetSubcommands retrieves subcommands for this command.
+func (c *HiddenUnlessCommand) GetSubcommands() map[string]ICommand {
+	return c.Wrapped.GetSubcommands()
+}
+
+// GetDefaults retrieves the defaults for arguments for this command.
+func (c *HiddenUnlessCommand) GetDefaults() interface{} {
+	return c.Wrapped.GetDefaults()
+}
+
+// Unwrap returns the wrapped command.
+func (c *HiddenUnlessCommand) Unwrap() ICommand {
+	return c.Wrapped
+}
+
+// IsHidden returns true if the command should be hidden from usage
+// messages.  The command is hidden unless the predicate returns
+// true.
+func (c *HiddenUnlessCommand) IsHidden() bool {
+	return c.Predicate == nil || !c.Predicate()
+}
+
 // DeprecatedCommand wraps a command, causing it to be marked
 // deprecated.
 type DeprecatedCommand struct {
-	Wrapped     ICommand // Wrapped command
-	Alternative string   // Alternative command to use
+	Wrapped     ICommand  // Wrapped command
+	Alternative string    // Alternative command to use
+	Since       time.Time // Optional date the command was deprecated
+	RemoveBy    time.Time // Optional date the command will be removed
 }
 
 // Deprecated wraps a command to indicate that it should be marked
@@ -166,6 +732,87 @@ func (c *DeprecatedCommand) Unwrap() ICommand {
 	return c.Wrapped
 }
 
+// Expired returns true if the command has a removal date and that
+// date has passed.
+func (c *DeprecatedCommand) Expired(now time.Time) bool {
+	return !c.RemoveBy.IsZero() && !now.Before(c.RemoveBy)
+}
+
+// Warning constructs the deprecation warning for the command.  The
+// name is the name the command was invoked by.
+func (c *DeprecatedCommand) Warning(name string) string {
+	msg := fmt.Sprintf("command %q is deprecated", name)
+	if !c.Since.IsZero() {
+		msg += " since " + c.Since.Format(DateLayout)
+	}
+	if !c.RemoveBy.IsZero() {
+		msg += " and will be removed on " + c.RemoveBy.Format(DateLayout)
+	}
+	if c.Alternative != "" {
+		msg += fmt.Sprintf("; use %q instead", c.Alternative)
+	}
+
+	return msg
+}
+
+// Note constructs the note used to annotate the command in help, as
+// in "deprecated: use new; since 2021-01-01; removed on 2021-06-01".
+// The dates are included only if set.
+func (c *DeprecatedCommand) Note() string {
+	return c.note(nil)
+}
+
+// note constructs the note used to annotate the command in help,
+// translated with the specified translator.
+func (c *DeprecatedCommand) note(tr translator) string {
+	note := tr.translate("deprecated")
+	if c.Alternative != "" {
+		note = fmt.Sprintf(tr.translate("deprecated: use %s"), c.Alternative)
+	}
+	if !c.Since.IsZero() {
+		note += fmt.Sprintf(tr.translate("; since %s"), c.Since.Format(DateLayout))
+	}
+	if !c.RemoveBy.IsZero() {
+		note += fmt.Sprintf(tr.translate("; removed on %s"), c.RemoveBy.Format(DateLayout))
+	}
+
+	return note
+}
+
+// Check checks whether the command may still be used.  If strict is
+// true and the removal date has passed, a CommandError wrapping
+// ErrRemovedCommand is returned.
+func (c *DeprecatedCommand) Check(name string, now time.Time, strict bool) error {
+	if !strict || !c.Expired(now) {
+		return nil
+	}
+
+	return &CommandError{
+		Err:  fmt.Errorf("%w: %s", ErrRemovedCommand, c.Warning(name)),
+		Code: 1,
+	}
+}
+
+// GetDeprecation is a helper that locates the DeprecatedCommand
+// wrapper for a command, if any.  It examines the command and any
+// commands it wraps, returning nil if the command is not deprecated.
+func GetDeprecation(cmd ICommand) *DeprecatedCommand {
+	for cmd != nil {
+		if tmp, ok := cmd.(*DeprecatedCommand); ok {
+			return tmp
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return nil
+}
+
 // AliasCommand wraps a command and acts as an alias for that command.
 type AliasCommand struct {
 	Wrapped ICommand // Wrapped command
@@ -208,3 +855,58 @@ func (c *AliasCommand) GetDefaults() interface{} {
 func (c *AliasCommand) Unwrap() ICommand {
 	return c.Wrapped
 }
+
+// IsAlias is a helper that determines whether a command is an alias
+// for another command.  It examines the command and any commands it
+// wraps, returning true if any of them is an AliasCommand.
+func IsAlias(cmd ICommand) bool {
+	for cmd != nil {
+		if _, ok := cmd.(*AliasCommand); ok {
+			return true
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return false
+}
+
+// IShallowDefaults is an optional interface for commands whose
+// defaults should be copied shallowly for each invocation.  By
+// default, the defaults are copied deeply--including the values
+// they refer to through pointers, slices, and maps--so that
+// repeated invocations, such as in watch mode or in tests, do not
+// leak values into one another.  Commands with very large defaults
+// may opt out of the deep copy, provided they do not modify what the
+// defaults refer to.
+type IShallowDefaults interface {
+	// GetShallowDefaults retrieves whether the defaults for this
+	// command are copied shallowly for each invocation.
+	GetShallowDefaults() bool
+}
+
+// IsShallowDefaults is a helper that determines whether the defaults
+// for a command are copied shallowly.  It examines the command and
+// any commands it wraps, returning the result from the first that
+// implements IShallowDefaults.
+func IsShallowDefaults(cmd ICommand) bool {
+	for cmd != nil {
+		if tmp, ok := cmd.(IShallowDefaults); ok {
+			return tmp.GetShallowDefaults()
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return false
+}
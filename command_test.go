@@ -15,7 +15,9 @@
 package nelson
 
 import (
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -118,6 +120,373 @@ func TestCommandGetDefaults(t *testing.T) {
 	assert.Equal(t, "defaults", result)
 }
 
+func TestCommandImplementsIDynamicSubcommands(t *testing.T) {
+	assert.Implements(t, (*IDynamicSubcommands)(nil), &Command{})
+}
+
+func TestCommandGetDynamicSubcommands(t *testing.T) {
+	obj := &Command{
+		DynamicSubcommands: "dynamic",
+	}
+
+	result := obj.GetDynamicSubcommands()
+
+	assert.Equal(t, "dynamic", result)
+}
+
+func TestCommandImplementsIPersistentHooks(t *testing.T) {
+	assert.Implements(t, (*IPersistentHooks)(nil), &Command{})
+}
+
+func TestCommandGetPersistentPreRun(t *testing.T) {
+	obj := &Command{
+		PersistentPreRun: "pre",
+	}
+
+	result := obj.GetPersistentPreRun()
+
+	assert.Equal(t, "pre", result)
+}
+
+func TestCommandGetPersistentPostRun(t *testing.T) {
+	obj := &Command{
+		PersistentPostRun: "post",
+	}
+
+	result := obj.GetPersistentPostRun()
+
+	assert.Equal(t, "post", result)
+}
+
+func TestCommandImplementsIPassthrough(t *testing.T) {
+	assert.Implements(t, (*IPassthrough)(nil), &Command{})
+}
+
+func TestCommandGetPassthrough(t *testing.T) {
+	obj := &Command{
+		Passthrough: true,
+	}
+
+	result := obj.GetPassthrough()
+
+	assert.True(t, result)
+}
+
+func TestIsPassthroughBase(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := IsPassthrough(cmd)
+
+	assert.False(t, result)
+}
+
+func TestIsPassthroughWrapped(t *testing.T) {
+	cmd := Hidden(&Command{Passthrough: true})
+
+	result := IsPassthrough(cmd)
+
+	assert.True(t, result)
+}
+
+func TestIsPassthroughWrappedFalse(t *testing.T) {
+	cmd := Hidden(&Command{})
+
+	result := IsPassthrough(cmd)
+
+	assert.False(t, result)
+}
+
+func TestCommandGetUnknownFlags(t *testing.T) {
+	obj := &Command{
+		UnknownFlags: UnknownFlagsCollect,
+	}
+
+	result := obj.GetUnknownFlags()
+
+	assert.Equal(t, UnknownFlagsCollect, result)
+}
+
+func TestGetUnknownFlagsBase(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := GetUnknownFlags(cmd)
+
+	assert.Equal(t, UnknownFlagsDefault, result)
+}
+
+func TestGetUnknownFlagsWrapped(t *testing.T) {
+	cmd := Hidden(&Command{UnknownFlags: UnknownFlagsIgnore})
+
+	result := GetUnknownFlags(cmd)
+
+	assert.Equal(t, UnknownFlagsIgnore, result)
+}
+
+func TestCommandGetStrictOrder(t *testing.T) {
+	obj := &Command{
+		StrictOrder: true,
+	}
+
+	result := obj.GetStrictOrder()
+
+	assert.True(t, result)
+}
+
+func TestIsStrictOrderBase(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := IsStrictOrder(cmd)
+
+	assert.False(t, result)
+}
+
+func TestIsStrictOrderWrapped(t *testing.T) {
+	cmd := Hidden(&Command{StrictOrder: true})
+
+	result := IsStrictOrder(cmd)
+
+	assert.True(t, result)
+}
+
+func TestCommandGetShallowDefaults(t *testing.T) {
+	obj := &Command{
+		ShallowDefaults: true,
+	}
+
+	result := obj.GetShallowDefaults()
+
+	assert.True(t, result)
+}
+
+func TestIsShallowDefaultsBase(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := IsShallowDefaults(cmd)
+
+	assert.False(t, result)
+}
+
+func TestIsShallowDefaultsWrapped(t *testing.T) {
+	cmd := Hidden(&Command{ShallowDefaults: true})
+
+	result := IsShallowDefaults(cmd)
+
+	assert.True(t, result)
+}
+
+func TestCommandGetGroups(t *testing.T) {
+	obj := &Command{
+		Groups: []Group{{Name: "group"}},
+	}
+
+	result := obj.GetGroups()
+
+	assert.Equal(t, []Group{{Name: "group"}}, result)
+}
+
+func TestGetGroupsBase(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := GetGroups(cmd)
+
+	assert.Nil(t, result)
+}
+
+func TestGetGroupsWrapped(t *testing.T) {
+	cmd := Hidden(&Command{Groups: []Group{{Name: "group"}}})
+
+	result := GetGroups(cmd)
+
+	assert.Equal(t, []Group{{Name: "group"}}, result)
+}
+
+func TestCommandGetCommandOrder(t *testing.T) {
+	obj := &Command{
+		CommandOrder: SortWeight,
+	}
+
+	result := obj.GetCommandOrder()
+
+	assert.Equal(t, SortWeight, result)
+}
+
+func TestCommandGetFlagOrder(t *testing.T) {
+	obj := &Command{
+		FlagOrder: SortAlphabetical,
+	}
+
+	result := obj.GetFlagOrder()
+
+	assert.Equal(t, SortAlphabetical, result)
+}
+
+func TestGetSortOrderBase(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := getSortOrder(cmd)
+
+	assert.Nil(t, result)
+}
+
+func TestGetSortOrderWrapped(t *testing.T) {
+	inner := &Command{CommandOrder: SortWeight}
+	cmd := Hidden(inner)
+
+	result := getSortOrder(cmd)
+
+	assert.Same(t, inner, result)
+}
+
+func TestCommandGetWeight(t *testing.T) {
+	obj := &Command{
+		Weight: 5,
+	}
+
+	result := obj.GetWeight()
+
+	assert.Equal(t, 5, result)
+}
+
+func TestGetWeightBase(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := GetWeight(cmd)
+
+	assert.Equal(t, 0, result)
+}
+
+func TestGetWeightWrapped(t *testing.T) {
+	cmd := Hidden(&Command{Weight: 5})
+
+	result := GetWeight(cmd)
+
+	assert.Equal(t, 5, result)
+}
+
+func TestCommandGetSeeAlso(t *testing.T) {
+	obj := &Command{
+		SeeAlso: []string{"remote add"},
+	}
+
+	result := obj.GetSeeAlso()
+
+	assert.Equal(t, []string{"remote add"}, result)
+}
+
+func TestGetSeeAlsoBase(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := GetSeeAlso(cmd)
+
+	assert.Nil(t, result)
+}
+
+func TestGetSeeAlsoWrapped(t *testing.T) {
+	cmd := Hidden(&Command{SeeAlso: []string{"remote add"}})
+
+	result := GetSeeAlso(cmd)
+
+	assert.Equal(t, []string{"remote add"}, result)
+}
+
+func TestCommandGetUsageLine(t *testing.T) {
+	obj := &Command{
+		UsageLine: "usage",
+	}
+
+	result := obj.GetUsageLine()
+
+	assert.Equal(t, "usage", result)
+}
+
+func TestGetUsageLineBase(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := GetUsageLine(cmd)
+
+	assert.Equal(t, "", result)
+}
+
+func TestGetUsageLineWrapped(t *testing.T) {
+	cmd := Hidden(&Command{UsageLine: "usage"})
+
+	result := GetUsageLine(cmd)
+
+	assert.Equal(t, "usage", result)
+}
+
+func TestCommandGetTranslations(t *testing.T) {
+	obj := &Command{
+		Translations: map[string]Translation{"fr": {Summary: "Résumé"}},
+	}
+
+	result := obj.GetTranslations()
+
+	assert.Equal(t, map[string]Translation{"fr": {Summary: "Résumé"}}, result)
+}
+
+func TestGetTranslationsBase(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := GetTranslations(cmd)
+
+	assert.Nil(t, result)
+}
+
+func TestGetTranslationsWrapped(t *testing.T) {
+	cmd := Hidden(&Command{Translations: map[string]Translation{"fr": {Summary: "Résumé"}}})
+
+	result := GetTranslations(cmd)
+
+	assert.Equal(t, map[string]Translation{"fr": {Summary: "Résumé"}}, result)
+}
+
+func TestCommandGetExamples(t *testing.T) {
+	obj := &Command{
+		Examples: "examples",
+	}
+
+	result := obj.GetExamples()
+
+	assert.Equal(t, "examples", result)
+}
+
+func TestGetExamplesBase(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := GetExamples(cmd)
+
+	assert.Equal(t, "", result)
+}
+
+func TestGetExamplesWrapped(t *testing.T) {
+	cmd := Hidden(&Command{Examples: "examples"})
+
+	result := GetExamples(cmd)
+
+	assert.Equal(t, "examples", result)
+}
+
+func TestCommandGetUsageTemplate(t *testing.T) {
+	obj := &Command{
+		UsageTemplate: "usage",
+	}
+
+	result := obj.GetUsageTemplate()
+
+	assert.Equal(t, "usage", result)
+}
+
+func TestCommandGetHelpTemplate(t *testing.T) {
+	obj := &Command{
+		HelpTemplate: "help",
+	}
+
+	result := obj.GetHelpTemplate()
+
+	assert.Equal(t, "help", result)
+}
+
 func TestHiddenCommandImplementsICommand(t *testing.T) {
 	assert.Implements(t, (*ICommand)(nil), &HiddenCommand{})
 }
@@ -215,6 +584,216 @@ func TestHiddenUnwrap(t *testing.T) {
 	assert.Same(t, cmd, result)
 }
 
+func TestHiddenCommandIsHidden(t *testing.T) {
+	obj := &HiddenCommand{}
+
+	result := obj.IsHidden()
+
+	assert.True(t, result)
+}
+
+func TestIsHiddenBase(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := IsHidden(cmd)
+
+	assert.False(t, result)
+}
+
+func TestIsHiddenHidden(t *testing.T) {
+	cmd := Hidden(&mockICommand{})
+
+	result := IsHidden(cmd)
+
+	assert.True(t, result)
+}
+
+func TestIsHiddenNested(t *testing.T) {
+	cmd := Alias(Hidden(&mockICommand{}))
+
+	result := IsHidden(cmd)
+
+	assert.True(t, result)
+}
+
+func TestIsHiddenNestedVisible(t *testing.T) {
+	cmd := Alias(HiddenUnless(&mockICommand{}, func() bool { return true }))
+
+	result := IsHidden(cmd)
+
+	assert.False(t, result)
+}
+
+func TestIsHiddenNil(t *testing.T) {
+	result := IsHidden(nil)
+
+	assert.False(t, result)
+}
+
+func TestHiddenUnlessCommandImplementsICommand(t *testing.T) {
+	assert.Implements(t, (*ICommand)(nil), &HiddenUnlessCommand{})
+}
+
+func TestHiddenUnlessCommandImplementsIWrapped(t *testing.T) {
+	assert.Implements(t, (*IWrapped)(nil), &HiddenUnlessCommand{})
+}
+
+func TestHiddenUnlessCommandImplementsIHidden(t *testing.T) {
+	assert.Implements(t, (*IHidden)(nil), &HiddenUnlessCommand{})
+}
+
+func TestHiddenUnless(t *testing.T) {
+	cmd := &mockICommand{}
+	called := false
+
+	result := HiddenUnless(cmd, func() bool {
+		called = true
+		return true
+	})
+
+	assert.Same(t, cmd, result.Wrapped)
+	assert.True(t, result.Predicate())
+	assert.True(t, called)
+}
+
+func TestHiddenUnlessEnvSet(t *testing.T) {
+	cmd := &mockICommand{}
+	os.Setenv("NELSON_TEST_VISIBLE", "true")
+	defer os.Unsetenv("NELSON_TEST_VISIBLE")
+
+	result := HiddenUnlessEnv(cmd, "NELSON_TEST_VISIBLE")
+
+	assert.Same(t, cmd, result.Wrapped)
+	assert.False(t, result.IsHidden())
+}
+
+func TestHiddenUnlessEnvFalse(t *testing.T) {
+	cmd := &mockICommand{}
+	os.Setenv("NELSON_TEST_VISIBLE", "false")
+	defer os.Unsetenv("NELSON_TEST_VISIBLE")
+
+	result := HiddenUnlessEnv(cmd, "NELSON_TEST_VISIBLE")
+
+	assert.Same(t, cmd, result.Wrapped)
+	assert.True(t, result.IsHidden())
+}
+
+func TestHiddenUnlessEnvUnset(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := HiddenUnlessEnv(cmd, "NELSON_TEST_UNSET_VARIABLE")
+
+	assert.Same(t, cmd, result.Wrapped)
+	assert.True(t, result.IsHidden())
+}
+
+func TestHiddenUnlessCommandGetSummary(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetSummary").Return("some text")
+	obj := &HiddenUnlessCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetSummary()
+
+	assert.Equal(t, "some text", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestHiddenUnlessCommandGetDescription(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetDescription").Return("some text")
+	obj := &HiddenUnlessCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetDescription()
+
+	assert.Equal(t, "some text", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestHiddenUnlessCommandGetGroup(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetGroup").Return("some text")
+	obj := &HiddenUnlessCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetGroup()
+
+	assert.Equal(t, "some text", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestHiddenUnlessCommandGetSubcommands(t *testing.T) {
+	subs := map[string]ICommand{
+		"sub": &mockICommand{},
+	}
+	cmd := &mockICommand{}
+	cmd.On("GetSubcommands").Return(subs)
+	obj := &HiddenUnlessCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetSubcommands()
+
+	assert.Equal(t, subs, result)
+	cmd.AssertExpectations(t)
+}
+
+func TestHiddenUnlessCommandGetDefaults(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetDefaults").Return("defaults")
+	obj := &HiddenUnlessCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetDefaults()
+
+	assert.Equal(t, "defaults", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestHiddenUnlessUnwrap(t *testing.T) {
+	cmd := &mockICommand{}
+	obj := &HiddenUnlessCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.Unwrap()
+
+	assert.Same(t, cmd, result)
+}
+
+func TestHiddenUnlessCommandIsHiddenVisible(t *testing.T) {
+	obj := &HiddenUnlessCommand{
+		Predicate: func() bool { return true },
+	}
+
+	result := obj.IsHidden()
+
+	assert.False(t, result)
+}
+
+func TestHiddenUnlessCommandIsHiddenHidden(t *testing.T) {
+	obj := &HiddenUnlessCommand{
+		Predicate: func() bool { return false },
+	}
+
+	result := obj.IsHidden()
+
+	assert.True(t, result)
+}
+
+func TestHiddenUnlessCommandIsHiddenNoPredicate(t *testing.T) {
+	obj := &HiddenUnlessCommand{}
+
+	result := obj.IsHidden()
+
+	assert.True(t, result)
+}
+
 func TestDeprecatedCommandImplementsICommand(t *testing.T) {
 	assert.Implements(t, (*ICommand)(nil), &DeprecatedCommand{})
 }
@@ -313,6 +892,160 @@ func TestDeprecatedUnwrap(t *testing.T) {
 	assert.Same(t, cmd, result)
 }
 
+func TestDeprecatedCommandExpiredNoDate(t *testing.T) {
+	obj := &DeprecatedCommand{}
+
+	result := obj.Expired(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.False(t, result)
+}
+
+func TestDeprecatedCommandExpiredBefore(t *testing.T) {
+	obj := &DeprecatedCommand{
+		RemoveBy: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	result := obj.Expired(time.Date(2021, 5, 31, 0, 0, 0, 0, time.UTC))
+
+	assert.False(t, result)
+}
+
+func TestDeprecatedCommandExpiredOn(t *testing.T) {
+	obj := &DeprecatedCommand{
+		RemoveBy: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	result := obj.Expired(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.True(t, result)
+}
+
+func TestDeprecatedCommandExpiredAfter(t *testing.T) {
+	obj := &DeprecatedCommand{
+		RemoveBy: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	result := obj.Expired(time.Date(2021, 6, 2, 0, 0, 0, 0, time.UTC))
+
+	assert.True(t, result)
+}
+
+func TestDeprecatedCommandWarningBase(t *testing.T) {
+	obj := &DeprecatedCommand{}
+
+	result := obj.Warning("cmd")
+
+	assert.Equal(t, `command "cmd" is deprecated`, result)
+}
+
+func TestDeprecatedCommandWarningAll(t *testing.T) {
+	obj := &DeprecatedCommand{
+		Alternative: "alt",
+		Since:       time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		RemoveBy:    time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	result := obj.Warning("cmd")
+
+	assert.Equal(t, `command "cmd" is deprecated since 2021-01-01 and will be removed on 2021-06-01; use "alt" instead`, result)
+}
+
+func TestDeprecatedCommandNoteBase(t *testing.T) {
+	obj := &DeprecatedCommand{}
+
+	result := obj.Note()
+
+	assert.Equal(t, "deprecated", result)
+}
+
+func TestDeprecatedCommandNoteAlternative(t *testing.T) {
+	obj := &DeprecatedCommand{Alternative: "alt"}
+
+	result := obj.Note()
+
+	assert.Equal(t, "deprecated: use alt", result)
+}
+
+func TestDeprecatedCommandNoteDates(t *testing.T) {
+	obj := &DeprecatedCommand{
+		Since:    time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		RemoveBy: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	result := obj.Note()
+
+	assert.Equal(t, "deprecated; since 2021-01-01; removed on 2021-06-01", result)
+}
+
+func TestDeprecatedCommandNoteAll(t *testing.T) {
+	obj := &DeprecatedCommand{
+		Alternative: "alt",
+		Since:       time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		RemoveBy:    time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	result := obj.Note()
+
+	assert.Equal(t, "deprecated: use alt; since 2021-01-01; removed on 2021-06-01", result)
+}
+
+func TestDeprecatedCommandCheckNotStrict(t *testing.T) {
+	obj := &DeprecatedCommand{
+		RemoveBy: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	err := obj.Check("cmd", time.Date(2021, 6, 2, 0, 0, 0, 0, time.UTC), false)
+
+	assert.NoError(t, err)
+}
+
+func TestDeprecatedCommandCheckNotExpired(t *testing.T) {
+	obj := &DeprecatedCommand{
+		RemoveBy: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	err := obj.Check("cmd", time.Date(2021, 5, 31, 0, 0, 0, 0, time.UTC), true)
+
+	assert.NoError(t, err)
+}
+
+func TestDeprecatedCommandCheckExpired(t *testing.T) {
+	obj := &DeprecatedCommand{
+		RemoveBy: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	err := obj.Check("cmd", time.Date(2021, 6, 2, 0, 0, 0, 0, time.UTC), true)
+
+	assert.ErrorIs(t, err, ErrRemovedCommand)
+	code, usage := ExitControl(err)
+	assert.Equal(t, 1, code)
+	assert.False(t, usage)
+}
+
+func TestGetDeprecationBase(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := GetDeprecation(cmd)
+
+	assert.Nil(t, result)
+}
+
+func TestGetDeprecationDeprecated(t *testing.T) {
+	cmd := Deprecated(&mockICommand{}, "alt")
+
+	result := GetDeprecation(cmd)
+
+	assert.Same(t, cmd, result)
+}
+
+func TestGetDeprecationNested(t *testing.T) {
+	cmd := Deprecated(&mockICommand{}, "alt")
+
+	result := GetDeprecation(Hidden(cmd))
+
+	assert.Same(t, cmd, result)
+}
+
 func TestAliasCommandImplementsICommand(t *testing.T) {
 	assert.Implements(t, (*ICommand)(nil), &AliasCommand{})
 }
@@ -409,3 +1142,27 @@ func TestAliasUnwrap(t *testing.T) {
 
 	assert.Same(t, cmd, result)
 }
+
+func TestIsAliasBase(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := IsAlias(cmd)
+
+	assert.False(t, result)
+}
+
+func TestIsAliasAlias(t *testing.T) {
+	cmd := Alias(&mockICommand{})
+
+	result := IsAlias(cmd)
+
+	assert.True(t, result)
+}
+
+func TestIsAliasNested(t *testing.T) {
+	cmd := Hidden(Alias(&mockICommand{}))
+
+	result := IsAlias(cmd)
+
+	assert.True(t, result)
+}
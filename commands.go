@@ -0,0 +1,328 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// CommandsCommand is the name of the subcommand that lists the
+// command tree, as in "tool commands --all".  It is added to the root
+// command if the application allows it; see WithCommands.
+const CommandsCommand = "commands"
+
+// Formats of the listing written by the CommandsCommand.
+const (
+	ListText = "text" // One line per command, giving its path and summary
+	ListJSON = "json" // The CommandEntry of the root command, as JSON
+	ListTree = "tree" // The command tree, drawn with box-drawing characters
+)
+
+// CommandEntry describes a command in the listing of the command
+// tree produced by App.Commands.
+type CommandEntry struct {
+	Name        string          `json:"name"`                  // Name of the command
+	Path        string          `json:"path"`                  // The command path, as in "tool remote add"
+	Summary     string          `json:"summary,omitempty"`     // Command summary, translated
+	Group       string          `json:"group,omitempty"`       // Group name
+	Hidden      bool            `json:"hidden,omitempty"`      // Command is hidden
+	Deprecated  string          `json:"deprecated,omitempty"`  // Deprecation note, if the command is deprecated
+	Alias       bool            `json:"alias,omitempty"`       // Command is an alias
+	Target      string          `json:"target,omitempty"`      // Path of the aliased command, if found in the tree
+	Subcommands []*CommandEntry `json:"subcommands,omitempty"` // Subcommands, in the order listed in help
+}
+
+// notes returns the notes describing the command in the text and
+// tree listings.
+func (e *CommandEntry) notes() []string {
+	notes := []string{}
+	switch {
+	case e.Target != "":
+		notes = append(notes, "alias of "+e.Target)
+	case e.Alias:
+		notes = append(notes, "alias")
+	}
+	if e.Group != "" {
+		notes = append(notes, "group "+e.Group)
+	}
+	if e.Hidden {
+		notes = append(notes, "hidden")
+	}
+	if e.Deprecated != "" {
+		notes = append(notes, e.Deprecated)
+	}
+
+	return notes
+}
+
+// WithCommands sets whether the CommandsCommand is added to the root
+// command.  Returns the App, to allow chaining.
+func (a *App) WithCommands(allow bool) *App {
+	a.AllowCommands = allow
+	return a
+}
+
+// Commands describes the command tree, including the built-in
+// subcommands of the root command, using the same model as help:
+// subcommands are listed in the order help lists them, and hidden
+// commands are omitted unless all is true.  The subcommands of
+// aliases are not included, since they are available through the
+// aliased command.
+func (a *App) Commands(all bool) (*CommandEntry, error) {
+	return a.commands(a.newInjector(nil), all)
+}
+
+// commands is a helper that describes the command tree, using the
+// injector to compute dynamic subcommands.
+func (a *App) commands(inj *Injector, all bool) (*CommandEntry, error) {
+	langs := a.languages()
+	l := &commandLister{
+		app:     a,
+		inj:     inj,
+		all:     all,
+		langs:   langs,
+		tr:      a.translator(langs),
+		paths:   map[ICommand]string{},
+		aliases: map[*CommandEntry]ICommand{},
+	}
+	root, err := l.list([]string{a.name()}, a.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	// Identify the aliased commands
+	for entry, target := range l.aliases {
+		if reflect.TypeOf(target).Comparable() {
+			entry.Target = l.paths[target]
+		}
+	}
+
+	return root, nil
+}
+
+// commandLister describes the command tree for App.Commands.
+type commandLister struct {
+	app     *App                       // The application
+	inj     *Injector                  // Injector used to compute dynamic subcommands
+	all     bool                       // True if hidden commands are included
+	langs   []string                   // Languages of the summaries
+	tr      translator                 // Translator for the deprecation notes
+	paths   map[ICommand]string        // Paths of the commands that are not aliases
+	aliases map[*CommandEntry]ICommand // Commands aliased by the alias entries
+}
+
+// list describes the command at the path, recursing into its
+// subcommands.
+func (l *commandLister) list(path []string, cmd ICommand) (*CommandEntry, error) {
+	summary, _ := translateCommand(cmd, l.langs)
+	entry := &CommandEntry{
+		Name:    path[len(path)-1],
+		Path:    strings.Join(path, " "),
+		Summary: strings.TrimSpace(summary),
+		Group:   cmd.GetGroup(),
+		Hidden:  IsHidden(cmd),
+		Alias:   IsAlias(cmd),
+	}
+	if dep := GetDeprecation(cmd); dep != nil {
+		entry.Deprecated = dep.note(l.tr)
+	}
+
+	// Don't recurse through aliases
+	if entry.Alias {
+		if target := aliasTarget(cmd); target != nil {
+			l.aliases[entry] = target
+		}
+		return entry, nil
+	}
+	if reflect.TypeOf(cmd).Comparable() {
+		l.paths[cmd] = entry.Path
+	}
+
+	// Order the subcommands as help does
+	subs, err := resolveSubcommands(cmd, l.inj)
+	if err != nil {
+		return nil, err
+	}
+	if len(path) == 1 {
+		subs = l.app.withBuiltins(subs, l.inj)
+	}
+	helps := []*SubcommandHelp{}
+	for name, sub := range subs {
+		if IsHidden(sub) && !l.all {
+			continue
+		}
+		helps = append(helps, &SubcommandHelp{
+			Name:   name,
+			Group:  sub.GetGroup(),
+			Weight: GetWeight(sub),
+		})
+	}
+	order := SortDefault
+	if tmp := getSortOrder(cmd); tmp != nil {
+		order = tmp.GetCommandOrder()
+	}
+	sortCommands(helps, order)
+	for _, group := range helpGroups(helps, GetGroups(cmd), l.tr) {
+		for _, sub := range group.Commands {
+			subPath := append(append([]string{}, path...), sub.Name)
+			subEntry, err := l.list(subPath, subs[sub.Name])
+			if err != nil {
+				return nil, err
+			}
+			entry.Subcommands = append(entry.Subcommands, subEntry)
+		}
+	}
+
+	return entry, nil
+}
+
+// aliasTarget is a helper that returns the command aliased by an
+// alias, which may be wrapped.
+func aliasTarget(cmd ICommand) ICommand {
+	for cmd != nil {
+		if alias, ok := cmd.(*AliasCommand); ok {
+			return alias.Wrapped
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return nil
+}
+
+// listText writes the listing of the subcommands of the entry, one
+// line per command.
+func listText(w io.Writer, root *CommandEntry) error {
+	entries := []*CommandEntry{}
+	var walk func(entry *CommandEntry)
+	walk = func(entry *CommandEntry) {
+		for _, sub := range entry.Subcommands {
+			entries = append(entries, sub)
+			walk(sub)
+		}
+	}
+	walk(root)
+
+	width := 0
+	for _, entry := range entries {
+		if len(entry.Path) > width {
+			width = len(entry.Path)
+		}
+	}
+	for _, entry := range entries {
+		line := strings.TrimRight(fmt.Sprintf("%-*s  %s", width, entry.Path, helpNote(entry.Summary, entry.notes())), " ")
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listTree writes the listing of the entry and its subcommands as a
+// tree.
+func listTree(w io.Writer, root *CommandEntry) error {
+	if _, err := fmt.Fprintln(w, root.Name); err != nil {
+		return err
+	}
+
+	return listBranch(w, root, "")
+}
+
+// listBranch is a helper for listTree that writes the subcommands of
+// the entry, each line beginning with the prefix.
+func listBranch(w io.Writer, entry *CommandEntry, prefix string) error {
+	width := 0
+	for _, sub := range entry.Subcommands {
+		if len(sub.Name) > width {
+			width = len(sub.Name)
+		}
+	}
+
+	for i, sub := range entry.Subcommands {
+		branch, indent := "├── ", "│   "
+		if i == len(entry.Subcommands)-1 {
+			branch, indent = "└── ", "    "
+		}
+		line := strings.TrimRight(fmt.Sprintf("%s%s%-*s  %s", prefix, branch, width, sub.Name, helpNote(sub.Summary, sub.notes())), " ")
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		if err := listBranch(w, sub, prefix+indent); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// commandsOptions are the flags of the CommandsCommand.
+type commandsOptions struct {
+	All    bool   `opt:"all,a" help:"Include hidden commands"`
+	Format string `opt:"format,f" choices:"text,json,tree" help:"Format of the listing"`
+}
+
+// commandsCommand implements the CommandsCommand.
+type commandsCommand struct {
+	Command
+	app *App      // The application
+	inj *Injector // Injector used to compute dynamic subcommands
+}
+
+// newCommandsCommand constructs the CommandsCommand for the
+// application.
+func (a *App) newCommandsCommand(inj *Injector) *commandsCommand {
+	return &commandsCommand{
+		Command: Command{
+			Summary:  "List the commands",
+			Defaults: &commandsOptions{Format: ListText},
+		},
+		app: a,
+		inj: inj,
+	}
+}
+
+// Run writes the listing of the command tree.
+func (c *commandsCommand) Run(opts *commandsOptions, streams *IOStreams) error {
+	root, err := c.app.commands(c.inj, opts.All)
+	if err != nil {
+		return err
+	}
+
+	switch opts.Format {
+	case ListJSON:
+		text, err := json.MarshalIndent(root, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = streams.Out.Write(append(text, '\n'))
+		return err
+
+	case ListTree:
+		return listTree(streams.Out, root)
+
+	default:
+		return listText(streams.Out, root)
+	}
+}
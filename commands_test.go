@@ -0,0 +1,208 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// commandsApp is a helper that constructs an application for testing
+// the CommandsCommand.
+func commandsApp(stdout *bytes.Buffer) *App {
+	remove := &Command{Summary: "Remove a thing"}
+	return (&App{
+		Name:   "tool",
+		Stdout: stdout,
+		Root: &Command{
+			Summary: "The tool",
+			Subcommands: map[string]ICommand{
+				"remote": &Command{
+					Summary: "Manage remotes",
+					Group:   "config",
+					Subcommands: map[string]ICommand{
+						"add":  &Command{Summary: "Add a remote"},
+						"list": &Command{Summary: "List remotes"},
+					},
+				},
+				"remove": remove,
+				"rm":     Alias(remove),
+				"secret": Hidden(&Command{Summary: "Secret"}),
+				"old":    Deprecated(&Command{Summary: "Old command"}, "remove"),
+			},
+		},
+	}).WithNoConfigSearch(true).WithCommands(true)
+}
+
+func TestAppWithCommands(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithCommands(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.AllowCommands)
+}
+
+func TestAppCommands(t *testing.T) {
+	obj := commandsApp(nil)
+
+	result, err := obj.Commands(false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &CommandEntry{
+		Name:    "tool",
+		Path:    "tool",
+		Summary: "The tool",
+		Subcommands: []*CommandEntry{
+			{Name: "commands", Path: "tool commands", Summary: "List the commands"},
+			{Name: "help", Path: "tool help", Summary: "Show help for a command"},
+			{Name: "old", Path: "tool old", Summary: "Old command", Deprecated: "deprecated: use remove"},
+			{Name: "remove", Path: "tool remove", Summary: "Remove a thing"},
+			{Name: "rm", Path: "tool rm", Summary: "Remove a thing", Alias: true, Target: "tool remove"},
+			{
+				Name:    "remote",
+				Path:    "tool remote",
+				Summary: "Manage remotes",
+				Group:   "config",
+				Subcommands: []*CommandEntry{
+					{Name: "add", Path: "tool remote add", Summary: "Add a remote"},
+					{Name: "list", Path: "tool remote list", Summary: "List remotes"},
+				},
+			},
+		},
+	}, result)
+}
+
+func TestAppCommandsAll(t *testing.T) {
+	obj := commandsApp(nil)
+
+	result, err := obj.Commands(true)
+
+	assert.NoError(t, err)
+	names := []string{}
+	for _, entry := range result.Subcommands {
+		names = append(names, entry.Name)
+	}
+	assert.Equal(t, []string{"__complete", "__lint", "__spec", "commands", "help", "old", "remove", "rm", "secret", "remote"}, names)
+	assert.True(t, result.Subcommands[8].Hidden)
+}
+
+func TestAppCommandsAliasUnknown(t *testing.T) {
+	obj := &App{
+		Name: "tool",
+		Root: &Command{
+			Subcommands: map[string]ICommand{
+				"rm": Alias(&Command{Summary: "Remove a thing"}),
+			},
+		},
+	}
+
+	result, err := obj.Commands(false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &CommandEntry{Name: "rm", Path: "tool rm", Summary: "Remove a thing", Alias: true}, result.Subcommands[1])
+}
+
+func TestAppDispatchCommandsText(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := commandsApp(stdout)
+
+	err := obj.Dispatch(context.Background(), []string{"commands"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `tool commands     List the commands
+tool help         Show help for a command
+tool old          Old command (deprecated: use remove)
+tool remove       Remove a thing
+tool rm           Remove a thing (alias of tool remove)
+tool remote       Manage remotes (group config)
+tool remote add   Add a remote
+tool remote list  List remotes
+`, stdout.String())
+}
+
+func TestAppDispatchCommandsTree(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := commandsApp(stdout)
+
+	err := obj.Dispatch(context.Background(), []string{"commands", "--format", "tree", "--all"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `tool
+├── __complete  Write the completion candidates for a command line (hidden)
+├── __lint      Check the command tree for problems (hidden)
+├── __spec      Write the specification of the command line interface (hidden)
+├── commands    List the commands
+├── help        Show help for a command
+├── old         Old command (deprecated: use remove)
+├── remove      Remove a thing
+├── rm          Remove a thing (alias of tool remove)
+├── secret      Secret (hidden)
+└── remote      Manage remotes (group config)
+    ├── add   Add a remote
+    └── list  List remotes
+`, stdout.String())
+}
+
+func TestAppDispatchCommandsJSON(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := commandsApp(stdout)
+	obj.Root = &Command{
+		Summary: "The tool",
+		Subcommands: map[string]ICommand{
+			"sub": &Command{Summary: "A subcommand"},
+		},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"commands", "-f", "json"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  "name": "tool",
+  "path": "tool",
+  "summary": "The tool",
+  "subcommands": [
+    {
+      "name": "commands",
+      "path": "tool commands",
+      "summary": "List the commands"
+    },
+    {
+      "name": "help",
+      "path": "tool help",
+      "summary": "Show help for a command"
+    },
+    {
+      "name": "sub",
+      "path": "tool sub",
+      "summary": "A subcommand"
+    }
+  ]
+}
+`, stdout.String())
+}
+
+func TestAppDispatchCommandsDisabled(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := commandsApp(stdout).WithCommands(false)
+
+	err := obj.Dispatch(context.Background(), []string{"commands"})
+
+	assert.Error(t, err)
+	assert.Equal(t, "", stdout.String())
+}
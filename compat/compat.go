@@ -0,0 +1,335 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package compat compares two machine-readable descriptions of a
+// command line interface, as produced by nelson.Export, and
+// classifies the differences as breaking, compatible, or additive.
+// This allows CLI authors to gate releases on backwards
+// compatibility, e.g., by comparing the current command tree with a
+// description checked in alongside the tests.
+package compat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/klmitch/nelson"
+	"github.com/klmitch/nelson/internal/interval"
+)
+
+// ErrBreaking indicates that breaking changes were detected.
+var ErrBreaking = errors.New("breaking changes detected")
+
+// Severity describes the severity of a change.
+type Severity int
+
+// Change severities.
+const (
+	Additive   Severity = iota // New surface, e.g., an added command
+	Compatible                 // Changed, but not breaking, e.g., newly hidden
+	Breaking                   // Breaks existing usage, e.g., a removed flag
+)
+
+// String returns the name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case Additive:
+		return "additive"
+
+	case Compatible:
+		return "compatible"
+
+	case Breaking:
+		return "breaking"
+	}
+
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Change describes a single difference between two command trees.
+type Change struct {
+	Severity Severity // The severity of the change
+	Path     string   // The command path, e.g., "tool sub"
+	Message  string   // Description of the change
+}
+
+// String returns a string describing the change.
+func (c Change) String() string {
+	if c.Path == "" {
+		return fmt.Sprintf("%s: %s", c.Severity, c.Message)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", c.Severity, c.Path, c.Message)
+}
+
+// Report is the result of comparing two command trees.
+type Report struct {
+	Changes []Change // The detected changes, in tree order
+}
+
+// Filter returns the changes with the specified severity.
+func (r *Report) Filter(sev Severity) []Change {
+	var result []Change
+	for _, c := range r.Changes {
+		if c.Severity == sev {
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
+
+// HasBreaking returns true if any breaking changes were detected.
+func (r *Report) HasBreaking() bool {
+	return len(r.Filter(Breaking)) > 0
+}
+
+// Err returns an error wrapping ErrBreaking and listing the breaking
+// changes, or nil if there are no breaking changes.
+func (r *Report) Err() error {
+	breaking := r.Filter(Breaking)
+	if len(breaking) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, len(breaking))
+	for i, c := range breaking {
+		msgs[i] = c.String()
+	}
+
+	return fmt.Errorf("%w:\n%s", ErrBreaking, strings.Join(msgs, "\n"))
+}
+
+// add is a helper to add a change to the report.
+func (r *Report) add(sev Severity, path string, format string, args ...interface{}) {
+	r.Changes = append(r.Changes, Change{
+		Severity: sev,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Diff compares two command trees, before and after a change, and
+// returns a report describing the differences.
+func Diff(before, after *nelson.CommandSpec) *Report {
+	r := &Report{}
+	r.diffFlags(before.Name, "global flag", before.Globals, after.Globals)
+	r.diffCommand(before.Name, before, after)
+
+	return r
+}
+
+// diffCommand compares two descriptions of the same command.
+func (r *Report) diffCommand(path string, before, after *nelson.CommandSpec) {
+	// Check the command properties
+	if !before.Hidden && after.Hidden {
+		r.add(Compatible, path, "command is now hidden")
+	} else if before.Hidden && !after.Hidden {
+		r.add(Additive, path, "command is no longer hidden")
+	}
+	if before.Deprecated == nil && after.Deprecated != nil {
+		r.add(Compatible, path, "command is now deprecated")
+	}
+
+	r.diffFlags(path, "flag", before.Flags, after.Flags)
+	r.diffArgs(path, before.Args, after.Args)
+
+	// Compare the subcommands
+	newSubs := map[string]*nelson.CommandSpec{}
+	for _, sub := range after.Subcommands {
+		newSubs[sub.Name] = sub
+	}
+	for _, oldSub := range before.Subcommands {
+		subPath := joinPath(path, oldSub.Name)
+		newSub, ok := newSubs[oldSub.Name]
+		if !ok {
+			r.add(Breaking, subPath, "command removed")
+			continue
+		}
+		delete(newSubs, oldSub.Name)
+		r.diffCommand(subPath, oldSub, newSub)
+	}
+	for _, newSub := range after.Subcommands {
+		if _, ok := newSubs[newSub.Name]; ok {
+			r.add(Additive, joinPath(path, newSub.Name), "command added")
+		}
+	}
+}
+
+// diffFlags compares the flags of a command, or the global flags.
+// The noun describes the flags in the changes, as "flag" or "global
+// flag".
+func (r *Report) diffFlags(path, noun string, before, after []*nelson.FlagSpec) {
+	newFlags := map[string]*nelson.FlagSpec{}
+	for _, flag := range after {
+		newFlags[flagKey(flag)] = flag
+	}
+
+	for _, oldFlag := range before {
+		key := flagKey(oldFlag)
+		name := noun + " " + key
+		newFlag, ok := newFlags[key]
+		if !ok {
+			r.add(Breaking, path, "%s removed", name)
+			continue
+		}
+		delete(newFlags, key)
+
+		// Compare the flag properties
+		if oldFlag.Short != "" && oldFlag.Short != newFlag.Short {
+			r.add(Breaking, path, "%s: short flag -%s removed", name, oldFlag.Short)
+		} else if oldFlag.Short == "" && newFlag.Short != "" {
+			r.add(Additive, path, "%s: short flag -%s added", name, newFlag.Short)
+		}
+		r.diffAliases(path, name, oldFlag.Aliases, newFlag.Aliases)
+		r.diffChoices(path, name, oldFlag.Choices, newFlag.Choices)
+		if oldFlag.Type != newFlag.Type {
+			r.add(Breaking, path, "%s: type changed from %q to %q", name, oldFlag.Type, newFlag.Type)
+		}
+		if oldFlag.Optional == nil && newFlag.Optional != nil {
+			r.add(Breaking, path, "%s: value is now optional", name)
+		} else if oldFlag.Optional != nil && newFlag.Optional == nil {
+			r.add(Breaking, path, "%s: value is now required", name)
+		}
+		if !oldFlag.Hidden && newFlag.Hidden {
+			r.add(Compatible, path, "%s is now hidden", name)
+		}
+		if oldFlag.Deprecated == "" && newFlag.Deprecated != "" {
+			r.add(Compatible, path, "%s is now deprecated", name)
+		}
+	}
+
+	for _, newFlag := range after {
+		key := flagKey(newFlag)
+		if _, ok := newFlags[key]; ok {
+			r.add(Additive, path, "%s %s added", noun, key)
+		}
+	}
+}
+
+// diffArgs compares the positional arguments of a command.
+func (r *Report) diffArgs(path string, before, after []*nelson.ArgSpec) {
+	for i := 0; i < len(before) || i < len(after); i++ {
+		switch {
+		case i >= len(after):
+			r.add(Breaking, path, "argument %d (%s) removed", i+1, before[i].Name)
+
+		case i >= len(before):
+			if parseArity(after[i].Arity).Includes(0) {
+				r.add(Additive, path, "optional argument %d (%s) added", i+1, after[i].Name)
+			} else {
+				r.add(Breaking, path, "required argument %d (%s) added", i+1, after[i].Name)
+			}
+
+		default:
+			if before[i].Type != after[i].Type {
+				r.add(Breaking, path, "argument %d (%s): type changed from %q to %q", i+1, after[i].Name, before[i].Type, after[i].Type)
+			}
+			oldArity := parseArity(before[i].Arity)
+			newArity := parseArity(after[i].Arity)
+			if oldArity == newArity {
+				continue
+			}
+			if newArity.Start <= oldArity.Start && newArity.End >= oldArity.End {
+				r.add(Additive, path, "argument %d (%s): arity widened from %s to %s", i+1, after[i].Name, oldArity, newArity)
+			} else {
+				r.add(Breaking, path, "argument %d (%s): arity changed from %s to %s", i+1, after[i].Name, oldArity, newArity)
+			}
+		}
+	}
+}
+
+// diffAliases compares the aliases of a flag, described by name.
+func (r *Report) diffAliases(path, name string, before, after []string) {
+	newAliases := map[string]bool{}
+	for _, alias := range after {
+		newAliases[alias] = true
+	}
+
+	for _, alias := range before {
+		if !newAliases[alias] {
+			r.add(Breaking, path, "%s: alias --%s removed", name, alias)
+		}
+		delete(newAliases, alias)
+	}
+
+	for _, alias := range after {
+		if newAliases[alias] {
+			r.add(Additive, path, "%s: alias --%s added", name, alias)
+		}
+	}
+}
+
+// diffChoices compares the allowed values of a flag, described by
+// name.  Removing a value breaks the commands that use it; adding
+// one is compatible.  Restricting a flag that previously accepted any
+// value is breaking.
+func (r *Report) diffChoices(path, name string, before, after []string) {
+	switch {
+	case len(before) == 0 && len(after) > 0:
+		r.add(Breaking, path, "%s: values are now restricted to %s", name, strings.Join(after, ", "))
+		return
+
+	case len(before) > 0 && len(after) == 0:
+		r.add(Compatible, path, "%s: values are no longer restricted", name)
+		return
+	}
+
+	newChoices := map[string]bool{}
+	for _, choice := range after {
+		newChoices[choice] = true
+	}
+
+	for _, choice := range before {
+		if !newChoices[choice] {
+			r.add(Breaking, path, "%s: value %q removed", name, choice)
+		}
+		delete(newChoices, choice)
+	}
+
+	for _, choice := range after {
+		if newChoices[choice] {
+			r.add(Compatible, path, "%s: value %q added", name, choice)
+		}
+	}
+}
+
+// flagKey returns the key used to match flags between command trees.
+func flagKey(flag *nelson.FlagSpec) string {
+	if flag.Name != "" {
+		return "--" + flag.Name
+	}
+
+	return "-" + flag.Short
+}
+
+// parseArity parses an arity; if the arity is empty or invalid, an
+// arity of exactly one is assumed.
+func parseArity(arity string) interval.Interval {
+	if ival, err := interval.Parse(arity); err == nil {
+		return ival
+	}
+
+	return interval.Interval{Start: 1, End: 2}
+}
+
+// joinPath joins a command name to a command path.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + " " + name
+}
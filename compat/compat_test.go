@@ -0,0 +1,406 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package compat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/klmitch/nelson"
+)
+
+func TestSeverityString(t *testing.T) {
+	assert.Equal(t, "additive", Additive.String())
+	assert.Equal(t, "compatible", Compatible.String())
+	assert.Equal(t, "breaking", Breaking.String())
+	assert.Equal(t, "Severity(42)", Severity(42).String())
+}
+
+func TestChangeStringBase(t *testing.T) {
+	obj := Change{
+		Severity: Breaking,
+		Path:     "tool sub",
+		Message:  "command removed",
+	}
+
+	result := obj.String()
+
+	assert.Equal(t, "breaking: tool sub: command removed", result)
+}
+
+func TestChangeStringNoPath(t *testing.T) {
+	obj := Change{
+		Severity: Additive,
+		Message:  "flag --foo added",
+	}
+
+	result := obj.String()
+
+	assert.Equal(t, "additive: flag --foo added", result)
+}
+
+func TestReportFilter(t *testing.T) {
+	obj := &Report{
+		Changes: []Change{
+			{Severity: Additive, Message: "one"},
+			{Severity: Breaking, Message: "two"},
+			{Severity: Additive, Message: "three"},
+		},
+	}
+
+	result := obj.Filter(Additive)
+
+	assert.Equal(t, []Change{
+		{Severity: Additive, Message: "one"},
+		{Severity: Additive, Message: "three"},
+	}, result)
+}
+
+func TestReportHasBreakingFalse(t *testing.T) {
+	obj := &Report{
+		Changes: []Change{
+			{Severity: Additive, Message: "one"},
+		},
+	}
+
+	result := obj.HasBreaking()
+
+	assert.False(t, result)
+}
+
+func TestReportHasBreakingTrue(t *testing.T) {
+	obj := &Report{
+		Changes: []Change{
+			{Severity: Additive, Message: "one"},
+			{Severity: Breaking, Message: "two"},
+		},
+	}
+
+	result := obj.HasBreaking()
+
+	assert.True(t, result)
+}
+
+func TestReportErrNil(t *testing.T) {
+	obj := &Report{
+		Changes: []Change{
+			{Severity: Additive, Message: "one"},
+		},
+	}
+
+	err := obj.Err()
+
+	assert.NoError(t, err)
+}
+
+func TestReportErrBreaking(t *testing.T) {
+	obj := &Report{
+		Changes: []Change{
+			{Severity: Breaking, Path: "tool", Message: "one"},
+			{Severity: Additive, Message: "two"},
+			{Severity: Breaking, Message: "three"},
+		},
+	}
+
+	err := obj.Err()
+
+	assert.ErrorIs(t, err, ErrBreaking)
+	assert.EqualError(t, err, "breaking changes detected:\nbreaking: tool: one\nbreaking: three")
+}
+
+func TestDiffIdentical(t *testing.T) {
+	spec := &nelson.CommandSpec{
+		Name: "tool",
+		Flags: []*nelson.FlagSpec{
+			{Name: "verbose", Short: "v"},
+		},
+		Args: []*nelson.ArgSpec{
+			{Name: "file"},
+		},
+		Subcommands: []*nelson.CommandSpec{
+			{Name: "sub"},
+		},
+	}
+
+	result := Diff(spec, spec)
+
+	assert.Equal(t, &Report{}, result)
+}
+
+func TestDiffCommands(t *testing.T) {
+	before := &nelson.CommandSpec{
+		Name: "tool",
+		Subcommands: []*nelson.CommandSpec{
+			{Name: "gone"},
+			{Name: "hide"},
+			{Name: "old"},
+			{
+				Name:   "unhide",
+				Hidden: true,
+			},
+		},
+	}
+	after := &nelson.CommandSpec{
+		Name: "tool",
+		Subcommands: []*nelson.CommandSpec{
+			{
+				Name:   "hide",
+				Hidden: true,
+			},
+			{Name: "new"},
+			{
+				Name:       "old",
+				Deprecated: &nelson.DeprecationSpec{},
+			},
+			{Name: "unhide"},
+		},
+	}
+
+	result := Diff(before, after)
+
+	assert.Equal(t, &Report{
+		Changes: []Change{
+			{Severity: Breaking, Path: "tool gone", Message: "command removed"},
+			{Severity: Compatible, Path: "tool hide", Message: "command is now hidden"},
+			{Severity: Compatible, Path: "tool old", Message: "command is now deprecated"},
+			{Severity: Additive, Path: "tool unhide", Message: "command is no longer hidden"},
+			{Severity: Additive, Path: "tool new", Message: "command added"},
+		},
+	}, result)
+}
+
+func TestDiffRootPath(t *testing.T) {
+	before := &nelson.CommandSpec{
+		Subcommands: []*nelson.CommandSpec{
+			{Name: "gone"},
+		},
+	}
+	after := &nelson.CommandSpec{}
+
+	result := Diff(before, after)
+
+	assert.Equal(t, &Report{
+		Changes: []Change{
+			{Severity: Breaking, Path: "gone", Message: "command removed"},
+		},
+	}, result)
+}
+
+func TestDiffFlags(t *testing.T) {
+	before := &nelson.CommandSpec{
+		Name: "tool",
+		Flags: []*nelson.FlagSpec{
+			{Name: "gone"},
+			{Name: "short", Short: "s"},
+			{Name: "addshort"},
+			{Name: "type", Type: "string"},
+			{Name: "hide"},
+			{Name: "old"},
+			{Short: "x"},
+		},
+	}
+	after := &nelson.CommandSpec{
+		Name: "tool",
+		Flags: []*nelson.FlagSpec{
+			{Name: "short", Short: "t"},
+			{Name: "addshort", Short: "a"},
+			{Name: "type", Type: "int"},
+			{Name: "hide", Hidden: true},
+			{Name: "old", Deprecated: "use --new"},
+			{Short: "x"},
+			{Name: "new"},
+		},
+	}
+
+	result := Diff(before, after)
+
+	assert.Equal(t, &Report{
+		Changes: []Change{
+			{Severity: Breaking, Path: "tool", Message: "flag --gone removed"},
+			{Severity: Breaking, Path: "tool", Message: "flag --short: short flag -s removed"},
+			{Severity: Additive, Path: "tool", Message: "flag --addshort: short flag -a added"},
+			{Severity: Breaking, Path: "tool", Message: `flag --type: type changed from "string" to "int"`},
+			{Severity: Compatible, Path: "tool", Message: "flag --hide is now hidden"},
+			{Severity: Compatible, Path: "tool", Message: "flag --old is now deprecated"},
+			{Severity: Additive, Path: "tool", Message: "flag --new added"},
+		},
+	}, result)
+}
+
+func TestDiffFlagAliases(t *testing.T) {
+	before := &nelson.CommandSpec{
+		Name: "tool",
+		Flags: []*nelson.FlagSpec{
+			{Name: "color", Aliases: []string{"colour", "hue"}},
+		},
+	}
+	after := &nelson.CommandSpec{
+		Name: "tool",
+		Flags: []*nelson.FlagSpec{
+			{Name: "color", Aliases: []string{"colour", "tint"}},
+		},
+	}
+
+	result := Diff(before, after)
+
+	assert.Equal(t, &Report{
+		Changes: []Change{
+			{Severity: Breaking, Path: "tool", Message: "flag --color: alias --hue removed"},
+			{Severity: Additive, Path: "tool", Message: "flag --color: alias --tint added"},
+		},
+	}, result)
+}
+
+func TestDiffFlagChoices(t *testing.T) {
+	before := &nelson.CommandSpec{
+		Name: "tool",
+		Flags: []*nelson.FlagSpec{
+			{Name: "mode", Choices: []string{"fast", "safe", "slow"}},
+			{Name: "restrict"},
+			{Name: "open", Choices: []string{"a", "b"}},
+		},
+	}
+	after := &nelson.CommandSpec{
+		Name: "tool",
+		Flags: []*nelson.FlagSpec{
+			{Name: "mode", Choices: []string{"fast", "safe", "turbo"}},
+			{Name: "restrict", Choices: []string{"x", "y"}},
+			{Name: "open"},
+		},
+	}
+
+	result := Diff(before, after)
+
+	assert.Equal(t, &Report{
+		Changes: []Change{
+			{Severity: Breaking, Path: "tool", Message: `flag --mode: value "slow" removed`},
+			{Severity: Compatible, Path: "tool", Message: `flag --mode: value "turbo" added`},
+			{Severity: Breaking, Path: "tool", Message: "flag --restrict: values are now restricted to x, y"},
+			{Severity: Compatible, Path: "tool", Message: "flag --open: values are no longer restricted"},
+		},
+	}, result)
+}
+
+func TestDiffGlobals(t *testing.T) {
+	before := &nelson.CommandSpec{
+		Name: "tool",
+		Globals: []*nelson.FlagSpec{
+			{Name: "verbose", Short: "v"},
+			{Name: "output", Choices: []string{"json", "text"}},
+		},
+	}
+	after := &nelson.CommandSpec{
+		Name: "tool",
+		Globals: []*nelson.FlagSpec{
+			{Name: "output", Choices: []string{"json"}},
+			{Name: "dry-run"},
+		},
+	}
+
+	result := Diff(before, after)
+
+	assert.Equal(t, &Report{
+		Changes: []Change{
+			{Severity: Breaking, Path: "tool", Message: "global flag --verbose removed"},
+			{Severity: Breaking, Path: "tool", Message: `global flag --output: value "text" removed`},
+			{Severity: Additive, Path: "tool", Message: "global flag --dry-run added"},
+		},
+	}, result)
+}
+
+func TestDiffFlagOptional(t *testing.T) {
+	auto := "auto"
+	before := &nelson.CommandSpec{
+		Name: "tool",
+		Flags: []*nelson.FlagSpec{
+			{Name: "color", Type: "string"},
+			{Name: "pager", Type: "string", Optional: &auto},
+			{Name: "same", Type: "string", Optional: &auto},
+		},
+	}
+	after := &nelson.CommandSpec{
+		Name: "tool",
+		Flags: []*nelson.FlagSpec{
+			{Name: "color", Type: "string", Optional: &auto},
+			{Name: "pager", Type: "string"},
+			{Name: "same", Type: "string", Optional: &auto},
+		},
+	}
+
+	result := Diff(before, after)
+
+	assert.Equal(t, &Report{
+		Changes: []Change{
+			{Severity: Breaking, Path: "tool", Message: "flag --color: value is now optional"},
+			{Severity: Breaking, Path: "tool", Message: "flag --pager: value is now required"},
+		},
+	}, result)
+}
+
+func TestDiffArgs(t *testing.T) {
+	before := &nelson.CommandSpec{
+		Name: "tool",
+		Args: []*nelson.ArgSpec{
+			{Name: "same"},
+			{Name: "type", Type: "string"},
+			{Name: "widen", Arity: "[1]"},
+			{Name: "narrow", Arity: "[0,)"},
+			{Name: "gone"},
+		},
+	}
+	after := &nelson.CommandSpec{
+		Name: "tool",
+		Args: []*nelson.ArgSpec{
+			{Name: "same", Arity: "[1]"},
+			{Name: "type", Type: "int"},
+			{Name: "widen", Arity: "[0,1]"},
+			{Name: "narrow", Arity: "[1,)"},
+		},
+	}
+
+	result := Diff(before, after)
+
+	assert.Equal(t, &Report{
+		Changes: []Change{
+			{Severity: Breaking, Path: "tool", Message: `argument 2 (type): type changed from "string" to "int"`},
+			{Severity: Additive, Path: "tool", Message: "argument 3 (widen): arity widened from [1] to [0,2)"},
+			{Severity: Breaking, Path: "tool", Message: "argument 4 (narrow): arity changed from [0,) to [1,)"},
+			{Severity: Breaking, Path: "tool", Message: "argument 5 (gone) removed"},
+		},
+	}, result)
+}
+
+func TestDiffArgsAdded(t *testing.T) {
+	before := &nelson.CommandSpec{
+		Name: "tool",
+	}
+	after := &nelson.CommandSpec{
+		Name: "tool",
+		Args: []*nelson.ArgSpec{
+			{Name: "required"},
+			{Name: "optional", Arity: "[0,1]"},
+		},
+	}
+
+	result := Diff(before, after)
+
+	assert.Equal(t, &Report{
+		Changes: []Change{
+			{Severity: Breaking, Path: "tool", Message: "required argument 1 (required) added"},
+			{Severity: Additive, Path: "tool", Message: "optional argument 2 (optional) added"},
+		},
+	}, result)
+}
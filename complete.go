@@ -0,0 +1,588 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// CompleteTag is the struct tag that describes how the values of a
+// flag or argument are completed, for shell completion and other
+// consumers of the specification; see App.Spec.  The tag's value is
+// a completion kind, optionally followed by options separated by
+// commas: `complete:"dir"` offers directories, and
+// `complete:"file,ext=yaml|yml"` offers directories and files with
+// the listed extensions.  Without the "ext" option, all files are
+// offered.
+const CompleteTag = "complete"
+
+// Completion kinds, selected with CompleteTag.
+const (
+	CompleteFile = "file" // Complete paths of files
+	CompleteDir  = "dir"  // Complete paths of directories
+)
+
+// Completion describes how the values of a flag or argument are
+// completed.
+type Completion struct {
+	Kind       string   // Completion kind, CompleteFile or CompleteDir
+	Extensions []string // Extensions of the files offered, without dots; empty for any
+}
+
+// completeTag is a helper that parses the value of CompleteTag for a
+// field, checking that it is valid.  Returns nil if the tag is empty.
+func completeTag(tag string) (*Completion, error) {
+	if tag == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(tag, ",")
+	comp := &Completion{Kind: parts[0]}
+	if comp.Kind != CompleteFile && comp.Kind != CompleteDir {
+		return nil, fmt.Errorf("bad %s tag %q", CompleteTag, tag)
+	}
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[0] != "ext" || kv[1] == "" || comp.Kind != CompleteFile {
+			return nil, fmt.Errorf("bad %s tag %q", CompleteTag, tag)
+		}
+		for _, ext := range strings.Split(kv[1], "|") {
+			comp.Extensions = append(comp.Extensions, strings.TrimPrefix(ext, "."))
+		}
+	}
+
+	return comp, nil
+}
+
+// String returns the completion in the form of CompleteTag, as in
+// "file,ext=yaml|yml".
+func (c *Completion) String() string {
+	if len(c.Extensions) == 0 {
+		return c.Kind
+	}
+
+	return c.Kind + ",ext=" + strings.Join(c.Extensions, "|")
+}
+
+// Matches determines whether a path should be offered as a
+// completion.  Directories are always offered, since they may
+// contain matching files; files are offered only for CompleteFile,
+// and only if they have one of the extensions, if any are listed.
+func (c *Completion) Matches(path string, isDir bool) bool {
+	if isDir {
+		return true
+	} else if c.Kind != CompleteFile {
+		return false
+	} else if len(c.Extensions) == 0 {
+		return true
+	}
+
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	for _, allowed := range c.Extensions {
+		if ext != "" && strings.EqualFold(ext, allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CompleteCommand is the name of the hidden subcommand implementing
+// the dynamic completion protocol, for use by shell completion
+// scripts.  The words of the command line following the application
+// name are passed after "--", as in "tool __complete -- remote ad";
+// the candidates for the last word are written to standard output,
+// one per line, in the form "value\tdescription"; see App.Complete.
+// It is added to the root command alongside the HelpCommand, unless
+// the root declares its own CompleteCommand.
+const CompleteCommand = "__complete"
+
+// Candidate is a completion candidate.  Shells such as zsh and fish
+// display the description, if any, next to the value.
+type Candidate struct {
+	Value       string // The completed word
+	Description string // Optional description of the value
+}
+
+// ParseCandidate parses a candidate of the form "value\tdescription";
+// text without a tab is a value without a description.
+func ParseCandidate(text string) Candidate {
+	parts := strings.SplitN(text, "\t", 2)
+	if len(parts) == 1 {
+		return Candidate{Value: parts[0]}
+	}
+
+	return Candidate{Value: parts[0], Description: parts[1]}
+}
+
+// String returns the candidate in the form "value\tdescription", or
+// just the value if there is no description.  Line breaks in the
+// description are replaced by spaces.
+func (c Candidate) String() string {
+	if c.Description == "" {
+		return c.Value
+	}
+
+	return c.Value + "\t" + strings.Join(strings.Fields(c.Description), " ")
+}
+
+// ICompleter is an optional interface for commands that compute
+// completion candidates for their positional arguments dynamically.
+type ICompleter interface {
+	// Complete returns the candidates for the word being
+	// completed, given the preceding positional arguments.  Each
+	// candidate may have the form "value\tdescription"; see
+	// ParseCandidate.  Candidates not beginning with the word are
+	// discarded.
+	Complete(args []string, word string) []string
+}
+
+// getCompleter is a helper that retrieves the ICompleter
+// implementation for a command, if any.  It examines the command and
+// any commands it wraps.
+func getCompleter(cmd ICommand) ICompleter {
+	for cmd != nil {
+		if tmp, ok := cmd.(ICompleter); ok {
+			return tmp
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return nil
+}
+
+// Complete computes the completion candidates for the last of the
+// arguments, which should not include the program name; the other
+// arguments select the command and the context of the word.  Words
+// beginning with a dash are completed with the flags of the command,
+// described by their help; the values of flags and positional
+// arguments are completed with their choices, described by their
+// types if they implement IChoiceDescriptions, or with paths, as
+// selected by CompleteTag; and other words are completed with the
+// names of the visible subcommands, described by their summaries,
+// followed by any candidates from the command's ICompleter.  The
+// context is available from the injector to functions computing
+// dynamic subcommands; if nil, context.Background() is used.
+func (a *App) Complete(ctx context.Context, args []string) ([]Candidate, error) {
+	return a.complete(a.newInjector(ctx), args)
+}
+
+// completer tracks the state of the command line preceding the word
+// being completed.
+type completer struct {
+	app        *App                // The application
+	inj        *Injector           // Injector used to compute dynamic subcommands
+	cmd        ICommand            // The selected command
+	subs       map[string]ICommand // Subcommands of the selected command
+	sets       []*options          // Options of the command, followed by the global options
+	positional []string            // Positional arguments of the command
+	pending    *option             // Flag awaiting a value, if any
+	end        bool                // True if "--" ended flag parsing
+}
+
+// complete implements Complete with the specified injector.
+func (a *App) complete(inj *Injector, args []string) ([]Candidate, error) {
+	word := ""
+	if len(args) > 0 {
+		word, args = args[len(args)-1], args[:len(args)-1]
+	}
+
+	c := &completer{app: a, inj: inj}
+	if err := c.selectCommand(a.Root, true); err != nil {
+		return nil, err
+	}
+	for _, arg := range args {
+		if err := c.consume(arg); err != nil {
+			return nil, err
+		}
+	}
+
+	// Select the candidates
+	var candidates []Candidate
+	switch {
+	case c.pending != nil:
+		candidates = valueCandidates(c.pending.Type, c.pending.Choices, c.pending.Complete, word)
+
+	case !c.end && strings.HasPrefix(word, "--") && strings.Contains(word, "="):
+		name := strings.SplitN(word, "=", 2)[0]
+		if opt := c.lookupFlag(name); opt != nil && opt.TakesValue() {
+			for _, cand := range valueCandidates(opt.Type, opt.Choices, opt.Complete, word[len(name)+1:]) {
+				cand.Value = name + "=" + cand.Value
+				candidates = append(candidates, cand)
+			}
+		}
+
+	case !c.end && strings.HasPrefix(word, "-"):
+		candidates = c.flagCandidates()
+
+	default:
+		candidates = c.argCandidates(word)
+	}
+
+	// Discard candidates not matching the word
+	result := []Candidate{}
+	for _, cand := range candidates {
+		if strings.HasPrefix(cand.Value, word) {
+			result = append(result, cand)
+		}
+	}
+
+	return result, nil
+}
+
+// selectCommand selects the command whose flags, arguments, and
+// subcommands are completed.
+func (c *completer) selectCommand(cmd ICommand, root bool) error {
+	subs, err := resolveSubcommands(cmd, c.inj)
+	if err != nil {
+		return err
+	}
+	if root {
+		subs = c.app.withBuiltins(subs, c.inj)
+	}
+	opts, _ := describeOptions(cmd)
+
+	c.cmd, c.subs, c.sets, c.positional = cmd, subs, []*options{opts}, nil
+	for _, obj := range c.app.Globals {
+		if opts, err := optionsFor(obj, false); err == nil && opts != nil {
+			c.sets = append(c.sets, opts)
+		}
+	}
+
+	return nil
+}
+
+// consume examines an argument preceding the word being completed.
+func (c *completer) consume(arg string) error {
+	switch {
+	case c.pending != nil:
+		c.pending = nil
+
+	case c.end || arg == "-" || !strings.HasPrefix(arg, "-"):
+		if sub, ok := c.subs[arg]; ok && !c.end && len(c.positional) == 0 {
+			return c.selectCommand(sub, false)
+		}
+		c.positional = append(c.positional, arg)
+
+	case arg == "--":
+		c.end = true
+
+	case !strings.Contains(arg, "=") && (strings.HasPrefix(arg, "--") || len(arg) == 2):
+		if opt := c.lookupFlag(arg); opt != nil && opt.TakesValue() && opt.Optional == nil {
+			c.pending = opt
+		}
+	}
+
+	return nil
+}
+
+// lookupFlag looks up a flag, given as "--name" or "-s", in the
+// options of the command and the global options.
+func (c *completer) lookupFlag(flag string) *option {
+	for _, opts := range c.sets {
+		if opts == nil {
+			continue
+		}
+		for _, opt := range opts.Set.Options {
+			if opt.Matches(flag) {
+				return opt
+			}
+		}
+	}
+
+	return nil
+}
+
+// declaredFlags walks the arguments preceding any "--" as completion
+// does, reporting for each whether it is a flag declared by the
+// command selected at that point or by the global options, or the
+// value of such a flag.  Global
+// flags recognized by the App, such as the OutputFlag, are not
+// extracted from the arguments when the command declares them itself.
+// The valueFlags are global flags that take a value, which is
+// skipped where the command does not declare them.
+func (a *App) declaredFlags(inj *Injector, args []string, valueFlags ...string) []bool {
+	values := map[string]bool{}
+	for _, flag := range valueFlags {
+		values[flag] = true
+	}
+
+	declared := make([]bool, len(args))
+	c := &completer{app: a, inj: inj}
+	if err := c.selectCommand(a.Root, true); err != nil {
+		return declared
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if c.pending != nil {
+			declared[i] = true
+		} else if len(arg) > 1 && strings.HasPrefix(arg, "-") {
+			declared[i] = c.lookupFlag(strings.SplitN(arg, "=", 2)[0]) != nil
+			if !declared[i] && values[arg] {
+				i++
+				continue
+			}
+		}
+		if err := c.consume(arg); err != nil {
+			break
+		}
+	}
+
+	return declared
+}
+
+// maskDeclared replaces the arguments reported by declaredFlags with
+// placeholders that match no global flag, so that the global flags
+// recognized by the App are not extracted from flags the command
+// declares itself, or from their values.  The returned function
+// restores the arguments once the global flags have been extracted.
+func (a *App) maskDeclared(inj *Injector, args []string) ([]string, func([]string) []string) {
+	var valueFlags []string
+	if a.AllowLogFormat {
+		valueFlags = append(valueFlags, LogFormatFlag)
+	}
+	if a.AllowConfigFlag {
+		valueFlags = append(valueFlags, ConfigFlag)
+	}
+	if a.AllowOutput {
+		valueFlags = append(valueFlags, OutputFlag, OutputShortFlag)
+	}
+
+	masked := map[string]string{}
+	result := append([]string(nil), args...)
+	for i, declared := range a.declaredFlags(inj, args, valueFlags...) {
+		if declared {
+			result[i] = fmt.Sprintf("\x00%d", i)
+			masked[result[i]] = args[i]
+		}
+	}
+
+	return result, func(args []string) []string {
+		for i, arg := range args {
+			if orig, ok := masked[arg]; ok {
+				args[i] = orig
+			}
+		}
+		return args
+	}
+}
+
+// flagCandidates returns the visible flags of the command and the
+// global flags, described by their help.
+func (c *completer) flagCandidates() []Candidate {
+	var candidates []Candidate
+	for _, opts := range c.sets {
+		if opts == nil {
+			continue
+		}
+		for _, opt := range opts.Set.Options {
+			if !opt.Hidden {
+				candidates = append(candidates, Candidate{Value: opt.String(), Description: opt.Help})
+			}
+		}
+	}
+
+	return candidates
+}
+
+// argCandidates returns the candidates for a positional argument:
+// the visible subcommands, if no positional arguments precede the
+// word, followed by the values of the argument and any candidates
+// from the command's ICompleter.
+func (c *completer) argCandidates(word string) []Candidate {
+	var candidates []Candidate
+	if !c.end && len(c.positional) == 0 {
+		langs := c.app.languages()
+		names := []string{}
+		for name, sub := range c.subs {
+			if !IsHidden(sub) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			summary, _ := translateCommand(c.subs[name], langs)
+			candidates = append(candidates, Candidate{Value: name, Description: summary})
+		}
+	}
+
+	if arg := c.argument(); arg != nil {
+		candidates = append(candidates, valueCandidates(arg.Type, arg.Choices, arg.Complete, word)...)
+	}
+	if completer := getCompleter(c.cmd); completer != nil {
+		for _, text := range completer.Complete(c.positional, word) {
+			candidates = append(candidates, ParseCandidate(text))
+		}
+	}
+
+	return candidates
+}
+
+// argument returns the positional argument receiving the word being
+// completed, if any.
+func (c *completer) argument() *argument {
+	if c.sets[0] == nil {
+		return nil
+	}
+
+	n := int64(len(c.positional))
+	for _, arg := range c.sets[0].Set.Args {
+		if n < arg.Arity.End-1 {
+			return arg
+		}
+		n -= arg.Arity.End - 1
+	}
+
+	return nil
+}
+
+// valueCandidates is a helper that returns the candidates for the
+// value of a flag or argument of the specified type: the choices, if
+// any, described by the type if it implements IChoiceDescriptions;
+// otherwise, the paths selected by the completion, if any.
+func valueCandidates(typ reflect.Type, choices []string, comp *Completion, word string) []Candidate {
+	if len(choices) > 0 {
+		descriptions := choiceDescriptions(typ)
+		candidates := make([]Candidate, 0, len(choices))
+		for _, choice := range choices {
+			candidates = append(candidates, Candidate{Value: choice, Description: descriptions[choice]})
+		}
+		return candidates
+	}
+	if comp != nil {
+		return comp.paths(word)
+	}
+
+	return nil
+}
+
+// choiceDescriptions is a helper that retrieves the descriptions of
+// the allowed values of a type--or its element type, for slices--if
+// it implements IChoiceDescriptions.  Pointer types are not examined,
+// to avoid calling ChoiceDescriptions on a nil pointer.
+func choiceDescriptions(typ reflect.Type) map[string]string {
+	if typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Ptr {
+		return nil
+	}
+	if tmp, ok := reflect.Zero(typ).Interface().(IChoiceDescriptions); ok {
+		return tmp.ChoiceDescriptions()
+	}
+
+	return nil
+}
+
+// paths returns the paths matching the completion that begin with the
+// word.  Directories are suffixed with a slash, and hidden files are
+// only offered if the word names them.
+func (c *Completion) paths(word string) []Candidate {
+	dir, base := filepath.Split(word)
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	}
+	entries, err := ioutil.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []Candidate
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		} else if !c.Matches(name, entry.IsDir()) {
+			continue
+		}
+		if entry.IsDir() {
+			name += "/"
+		}
+		candidates = append(candidates, Candidate{Value: dir + name})
+	}
+
+	return candidates
+}
+
+// completeCommand implements the CompleteCommand.
+type completeCommand struct {
+	Command
+	app *App      // The application
+	inj *Injector // Injector used to compute dynamic subcommands
+}
+
+// IsHidden returns true, since the CompleteCommand is not intended
+// for users.
+func (c *completeCommand) IsHidden() bool {
+	return true
+}
+
+// Run writes the completion candidates for the last argument.
+func (c *completeCommand) Run(args Args, streams *IOStreams) error {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	candidates, err := c.app.complete(c.inj, args)
+	if err != nil {
+		return err
+	}
+
+	for _, cand := range candidates {
+		if _, err := fmt.Fprintln(streams.Out, cand.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// withComplete adds the CompleteCommand to the subcommands of the
+// root command, unless the root has no subcommands or declares its
+// own.  The subcommands are copied rather than modified.
+func (a *App) withComplete(subs map[string]ICommand, inj *Injector) map[string]ICommand {
+	if _, ok := subs[CompleteCommand]; ok || len(subs) == 0 {
+		return subs
+	}
+
+	return withBuiltin(subs, map[string]ICommand{CompleteCommand: a.newCompleteCommand(inj)})
+}
+
+// newCompleteCommand constructs the CompleteCommand for the
+// application.
+func (a *App) newCompleteCommand(inj *Injector) *completeCommand {
+	return &completeCommand{
+		Command: Command{
+			Summary: "Write the completion candidates for a command line",
+		},
+		app: a,
+		inj: inj,
+	}
+}
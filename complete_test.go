@@ -0,0 +1,311 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompleteTagEmpty(t *testing.T) {
+	result, err := completeTag("")
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestCompleteTagFile(t *testing.T) {
+	result, err := completeTag("file")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Completion{Kind: CompleteFile}, result)
+}
+
+func TestCompleteTagDir(t *testing.T) {
+	result, err := completeTag("dir")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Completion{Kind: CompleteDir}, result)
+}
+
+func TestCompleteTagExtensions(t *testing.T) {
+	result, err := completeTag("file,ext=yaml|.yml,ext=json")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Completion{Kind: CompleteFile, Extensions: []string{"yaml", "yml", "json"}}, result)
+}
+
+func TestCompleteTagBad(t *testing.T) {
+	for _, tag := range []string{"bogus", "file,ext", "file,ext=", "file,exts=yaml", "dir,ext=yaml"} {
+		result, err := completeTag(tag)
+
+		assert.EqualError(t, err, "bad complete tag \""+tag+"\"")
+		assert.Nil(t, result)
+	}
+}
+
+func TestCompletionString(t *testing.T) {
+	assert.Equal(t, "dir", (&Completion{Kind: CompleteDir}).String())
+	assert.Equal(t, "file,ext=yaml|yml", (&Completion{Kind: CompleteFile, Extensions: []string{"yaml", "yml"}}).String())
+}
+
+func TestCompletionMatchesFile(t *testing.T) {
+	obj := &Completion{Kind: CompleteFile}
+
+	assert.True(t, obj.Matches("config.yaml", false))
+	assert.True(t, obj.Matches("Makefile", false))
+	assert.True(t, obj.Matches("conf.d", true))
+}
+
+func TestCompletionMatchesExtensions(t *testing.T) {
+	obj := &Completion{Kind: CompleteFile, Extensions: []string{"yaml", "yml"}}
+
+	assert.True(t, obj.Matches("config.yaml", false))
+	assert.True(t, obj.Matches("dir/CONFIG.YML", false))
+	assert.False(t, obj.Matches("config.json", false))
+	assert.False(t, obj.Matches("yaml", false))
+	assert.True(t, obj.Matches("conf.d", true))
+}
+
+func TestCompletionMatchesDir(t *testing.T) {
+	obj := &Completion{Kind: CompleteDir}
+
+	assert.False(t, obj.Matches("config.yaml", false))
+	assert.True(t, obj.Matches("conf.d", true))
+}
+
+func TestNewOptionSetComplete(t *testing.T) {
+	type opts struct {
+		Config string `opt:"config" complete:"file,ext=yaml"`
+		Dir    string `arg:"DIR" complete:"dir"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Completion{Kind: CompleteFile, Extensions: []string{"yaml"}}, result.Options[0].Complete)
+	assert.Equal(t, &Completion{Kind: CompleteDir}, result.Args[0].Complete)
+}
+
+func TestNewOptionSetCompleteBadFlag(t *testing.T) {
+	type opts struct {
+		Config string `opt:"config" complete:"bogus"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetCompleteBadArg(t *testing.T) {
+	type opts struct {
+		Dir string `arg:"DIR" complete:"dir,ext=yaml"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestExportComplete(t *testing.T) {
+	type opts struct {
+		Config string   `opt:"config" complete:"file,ext=yaml|yml"`
+		Files  []string `arg:"FILES" complete:"file"`
+	}
+
+	result := Export(&Command{Defaults: &opts{}})
+
+	assert.Equal(t, "file,ext=yaml|yml", result.Flags[0].Complete)
+	assert.Equal(t, "file", result.Args[0].Complete)
+}
+
+type completeFormat string
+
+func (f completeFormat) Choices() []string {
+	return []string{"json", "yaml"}
+}
+
+func (f completeFormat) ChoiceDescriptions() map[string]string {
+	return map[string]string{"json": "JSON output"}
+}
+
+type completeOptions struct {
+	Format completeFormat `opt:"format,f" help:"Output format"`
+	Config string         `opt:"config" complete:"file,ext=yaml"`
+	Secret bool           `opt:"secret" hidden:"true"`
+	Target string         `arg:"TARGET" choices:"alpha,beta"`
+}
+
+type completeDynamic struct {
+	Command
+}
+
+func (c *completeDynamic) Complete(args []string, word string) []string {
+	return []string{"gamma\tThe third", "delta"}
+}
+
+// completeApp is a helper that constructs an application for testing
+// completion.
+func completeApp(stdout *bytes.Buffer) *App {
+	return &App{
+		Name: "app",
+		Root: &Command{
+			Summary: "The application",
+			Subcommands: map[string]ICommand{
+				"run":    &Command{Summary: "Run things", Defaults: &completeOptions{}},
+				"remove": &Command{Summary: "Remove things"},
+				"dyn":    &completeDynamic{},
+				"secret": Hidden(&Command{Summary: "Secret things"}),
+			},
+		},
+		Globals: []interface{}{&globalOptions{}},
+		Stdout:  stdout,
+	}
+}
+
+func TestParseCandidate(t *testing.T) {
+	assert.Equal(t, Candidate{Value: "json", Description: "JSON output"}, ParseCandidate("json\tJSON output"))
+	assert.Equal(t, Candidate{Value: "json"}, ParseCandidate("json"))
+}
+
+func TestCandidateString(t *testing.T) {
+	assert.Equal(t, "json", Candidate{Value: "json"}.String())
+	assert.Equal(t, "json\tJSON output", Candidate{Value: "json", Description: "JSON\noutput"}.String())
+}
+
+func TestAppCompleteSubcommands(t *testing.T) {
+	obj := completeApp(nil)
+
+	result, err := obj.Complete(context.Background(), []string{"r"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Candidate{
+		{Value: "remove", Description: "Remove things"},
+		{Value: "run", Description: "Run things"},
+	}, result)
+}
+
+func TestAppCompleteFlags(t *testing.T) {
+	obj := completeApp(nil)
+
+	result, err := obj.Complete(context.Background(), []string{"run", "-"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Candidate{
+		{Value: "--format", Description: "Output format"},
+		{Value: "--config"},
+		{Value: "--log-level", Description: "Logging level"},
+		{Value: "--no-color", Description: "Disable color"},
+	}, result)
+}
+
+func TestAppCompleteFlagChoices(t *testing.T) {
+	obj := completeApp(nil)
+
+	result, err := obj.Complete(context.Background(), []string{"run", "-f", ""})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Candidate{
+		{Value: "json", Description: "JSON output"},
+		{Value: "yaml"},
+	}, result)
+}
+
+func TestAppCompleteFlagAssigned(t *testing.T) {
+	obj := completeApp(nil)
+
+	result, err := obj.Complete(context.Background(), []string{"run", "--format=y"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Candidate{{Value: "--format=yaml"}}, result)
+}
+
+func TestAppCompleteFlagPaths(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "app.yaml"), nil, 0o644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "app.json"), nil, 0o644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".hidden.yaml"), nil, 0o644))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "conf"), 0o755))
+	obj := completeApp(nil)
+
+	result, err := obj.Complete(context.Background(), []string{"run", "--config", dir + "/"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Candidate{
+		{Value: dir + "/app.yaml"},
+		{Value: dir + "/conf/"},
+	}, result)
+}
+
+func TestAppCompleteArgChoices(t *testing.T) {
+	obj := completeApp(nil)
+
+	result, err := obj.Complete(context.Background(), []string{"run", "--format", "json", ""})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Candidate{{Value: "alpha"}, {Value: "beta"}}, result)
+}
+
+func TestAppCompleteArgExhausted(t *testing.T) {
+	obj := completeApp(nil)
+
+	result, err := obj.Complete(context.Background(), []string{"run", "alpha", ""})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Candidate{}, result)
+}
+
+func TestAppCompleteCompleter(t *testing.T) {
+	obj := completeApp(nil)
+
+	result, err := obj.Complete(context.Background(), []string{"dyn", "g"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Candidate{{Value: "gamma", Description: "The third"}}, result)
+}
+
+func TestAppDispatchComplete(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := completeApp(stdout)
+
+	err := obj.Dispatch(context.Background(), []string{CompleteCommand, "--", "run", "--format", ""})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "json\tJSON output\nyaml\n", stdout.String())
+}
+
+func TestAppMaskDeclared(t *testing.T) {
+	obj := (&App{
+		Name: "tool",
+		Root: &Command{Subcommands: map[string]ICommand{
+			"grep": &Command{Defaults: &grepOptions{}},
+		}},
+	}).WithOutput(true).WithDryRun(true)
+	args := []string{"-o", "json", "grep", "--dry-run", "-e", "--dry-run", "--pattern=-v", "-o", "x", "--", "-e"}
+
+	result, unmask := obj.maskDeclared(NewInjector(), args)
+
+	assert.Equal(t, []string{"-o", "json", "grep", "--dry-run", "\x004", "\x005", "\x006", "-o", "x", "--", "-e"}, result)
+	assert.Equal(t, args, unmask(result))
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ErrUnknownShell indicates that the shell for which completion is
+// to be installed could not be determined or is not supported.
+var ErrUnknownShell = errors.New("unsupported shell")
+
+// Shells supported by the completion install helper.
+const (
+	ShellBash = "bash"
+	ShellZsh  = "zsh"
+	ShellFish = "fish"
+)
+
+// CompletionScript produces the completion script for the
+// application in the specified shell, one of ShellBash, ShellZsh, or
+// ShellFish.  The app is the name of the application.
+type CompletionScript func(shell, app string) ([]byte, error)
+
+// completionInstallOptions are the flags of the install subcommand.
+type completionInstallOptions struct {
+	Shell  string `opt:"shell" help:"Shell to install completion for; defaults to the shell in $SHELL" choices:"bash,zsh,fish"`
+	DryRun bool   `opt:"dry-run" help:"Report what would be done without doing it"`
+}
+
+// completionInstall implements the install subcommand.
+type completionInstall struct {
+	Command
+	script CompletionScript // Produces the completion script
+}
+
+// NewCompletionCommand constructs a "completion" command group for
+// the application, whose "install" subcommand writes the completion
+// script produced by the specified function to the location where
+// the user's shell looks for it, and reports what it did.  The shell
+// is selected by the "--shell" flag, or detected from the SHELL
+// environment variable.  For bash, the script is installed as
+// "completions/APP" in $BASH_COMPLETION_USER_DIR, or in
+// $XDG_DATA_HOME/bash-completion; for zsh, as ~/.zsh/completions/_APP;
+// and for fish, as $XDG_CONFIG_HOME/fish/conf.d/APP.fish.
+// XDG_DATA_HOME defaults to ~/.local/share, and XDG_CONFIG_HOME to
+// ~/.config.  The "--dry-run" flag, or the global DryRunFlag,
+// reports what would be done without writing anything.
+func NewCompletionCommand(script CompletionScript) *Command {
+	return &Command{
+		Summary: "Manage shell completion",
+		Subcommands: map[string]ICommand{
+			"install": &completionInstall{
+				Command: Command{
+					Summary:     "Install the shell completion script",
+					Description: "Write the completion script for the shell to the location where the shell looks for it.",
+					Defaults:    &completionInstallOptions{},
+				},
+				script: script,
+			},
+		},
+	}
+}
+
+// Run installs the completion script.
+func (c *completionInstall) Run(opts *completionInstallOptions, dryRun DryRun, inv *Invocation, streams *IOStreams) error {
+	dryRun = dryRun || DryRun(opts.DryRun)
+	shell := opts.Shell
+	if shell == "" {
+		shell = filepath.Base(os.Getenv("SHELL"))
+	}
+	app := inv.Path[0]
+	path, err := completionPath(shell, app)
+	if err != nil {
+		return err
+	}
+	script, err := c.script(shell, app)
+	if err != nil {
+		return err
+	}
+
+	// Write the script
+	if err := dryRun.Do(func() error {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, script, 0o644) //nolint:gosec
+	}); err != nil {
+		return err
+	}
+	dryRun.Fprintf(streams.Out, "Installed %s completion for %s in %s\n", shell, app, path)
+	if shell == ShellZsh {
+		dryRun.Fprintf(streams.Out, "Ensure %s is in your fpath and compinit is run in ~/.zshrc\n", filepath.Dir(path))
+	}
+
+	return nil
+}
+
+// completionPath is a helper that determines where the completion
+// script for the application is installed for the specified shell.
+func completionPath(shell, app string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	switch shell {
+	case ShellBash:
+		if dir := os.Getenv("BASH_COMPLETION_USER_DIR"); dir != "" {
+			return filepath.Join(dir, "completions", app), nil
+		}
+		return filepath.Join(xdgDir("XDG_DATA_HOME", home, ".local", "share"), "bash-completion", "completions", app), nil
+
+	case ShellZsh:
+		return filepath.Join(home, ".zsh", "completions", "_"+app), nil
+
+	case ShellFish:
+		return filepath.Join(xdgDir("XDG_CONFIG_HOME", home, ".config"), "fish", "conf.d", app+".fish"), nil
+	}
+
+	if shell == "" || shell == "." {
+		return "", usageError(fmt.Errorf("%w: cannot detect shell; use --shell", ErrUnknownShell))
+	}
+	return "", usageError(fmt.Errorf("%w %s", ErrUnknownShell, shell))
+}
+
+// xdgDir is a helper that returns the directory named by an XDG
+// environment variable, or the default below the home directory if
+// the variable is not set.
+func xdgDir(name, home string, def ...string) string {
+	if dir := os.Getenv(name); dir != "" {
+		return dir
+	}
+
+	return filepath.Join(append([]string{home}, def...)...)
+}
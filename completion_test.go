@@ -0,0 +1,182 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// completionApp is a helper that constructs an application with a
+// completion command for testing.  The home directory is set to a
+// temporary directory, which is returned.
+func completionApp(t *testing.T, stdout *bytes.Buffer) (*App, string) {
+	home := t.TempDir()
+	setEnv(t, "HOME", home)
+	setEnv(t, "SHELL", "/bin/bash")
+	setEnv(t, "BASH_COMPLETION_USER_DIR", "")
+	setEnv(t, "XDG_DATA_HOME", "")
+	setEnv(t, "XDG_CONFIG_HOME", "")
+
+	script := func(shell, app string) ([]byte, error) {
+		return []byte("# " + shell + " completion for " + app + "\n"), nil
+	}
+	return &App{
+		Name: "tool",
+		Root: &Command{
+			Subcommands: map[string]ICommand{
+				"completion": NewCompletionCommand(script),
+			},
+		},
+		Stdout: stdout,
+	}, home
+}
+
+// readFile is a helper that reads a file, failing the test if it
+// cannot be read.
+func readFile(t *testing.T, path string) string {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func TestCompletionInstallBash(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj, home := completionApp(t, stdout)
+	path := filepath.Join(home, ".local", "share", "bash-completion", "completions", "tool")
+
+	err := obj.Dispatch(context.Background(), []string{"completion", "install"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "# bash completion for tool\n", readFile(t, path))
+	assert.Equal(t, "Installed bash completion for tool in "+path+"\n", stdout.String())
+}
+
+func TestCompletionInstallBashUserDir(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj, home := completionApp(t, stdout)
+	setEnv(t, "BASH_COMPLETION_USER_DIR", filepath.Join(home, "bc"))
+	path := filepath.Join(home, "bc", "completions", "tool")
+
+	err := obj.Dispatch(context.Background(), []string{"completion", "install"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "# bash completion for tool\n", readFile(t, path))
+}
+
+func TestCompletionInstallZsh(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj, home := completionApp(t, stdout)
+	path := filepath.Join(home, ".zsh", "completions", "_tool")
+
+	err := obj.Dispatch(context.Background(), []string{"completion", "install", "--shell", "zsh"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "# zsh completion for tool\n", readFile(t, path))
+	assert.Contains(t, stdout.String(), "Ensure "+filepath.Dir(path)+" is in your fpath")
+}
+
+func TestCompletionInstallFish(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj, home := completionApp(t, stdout)
+	setEnv(t, "SHELL", "/usr/bin/fish")
+	setEnv(t, "XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+	path := filepath.Join(home, "cfg", "fish", "conf.d", "tool.fish")
+
+	err := obj.Dispatch(context.Background(), []string{"completion", "install"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "# fish completion for tool\n", readFile(t, path))
+}
+
+func TestCompletionInstallDryRun(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj, home := completionApp(t, stdout)
+	path := filepath.Join(home, ".local", "share", "bash-completion", "completions", "tool")
+
+	err := obj.Dispatch(context.Background(), []string{"completion", "install", "--dry-run"})
+
+	assert.NoError(t, err)
+	assert.NoFileExists(t, path)
+	assert.Equal(t, "(dry run) Installed bash completion for tool in "+path+"\n", stdout.String())
+}
+
+func TestCompletionInstallGlobalDryRun(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj, home := completionApp(t, stdout)
+	obj.AllowDryRun = true
+	path := filepath.Join(home, ".local", "share", "bash-completion", "completions", "tool")
+
+	err := obj.Dispatch(context.Background(), []string{"--dry-run", "completion", "install"})
+
+	assert.NoError(t, err)
+	assert.NoFileExists(t, path)
+	assert.Contains(t, stdout.String(), DryRunPrefix)
+}
+
+func TestCompletionInstallUndetected(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj, _ := completionApp(t, stdout)
+	setEnv(t, "SHELL", "")
+
+	err := obj.Dispatch(context.Background(), []string{"completion", "install"})
+
+	assert.ErrorIs(t, err, ErrUnknownShell)
+	assert.EqualError(t, err, "unsupported shell: cannot detect shell; use --shell")
+	_, usage := ExitControl(err)
+	assert.True(t, usage)
+}
+
+func TestCompletionInstallUnsupported(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj, _ := completionApp(t, stdout)
+	setEnv(t, "SHELL", "/bin/tcsh")
+
+	err := obj.Dispatch(context.Background(), []string{"completion", "install"})
+
+	assert.ErrorIs(t, err, ErrUnknownShell)
+	assert.EqualError(t, err, "unsupported shell tcsh")
+}
+
+func TestCompletionInstallScriptError(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj, _ := completionApp(t, stdout)
+	obj.Root.GetSubcommands()["completion"] = NewCompletionCommand(func(shell, app string) ([]byte, error) {
+		return nil, assert.AnError
+	})
+
+	err := obj.Dispatch(context.Background(), []string{"completion", "install"})
+
+	assert.Same(t, assert.AnError, err)
+	assert.Equal(t, "", stdout.String())
+}
+
+func TestCompletionInstallWriteError(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj, home := completionApp(t, stdout)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(home, ".zsh"), nil, 0o600))
+
+	err := obj.Dispatch(context.Background(), []string{"completion", "install", "--shell", "zsh"})
+
+	assert.Error(t, err)
+	assert.Equal(t, "", stdout.String())
+}
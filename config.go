@@ -0,0 +1,437 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klmitch/nelson/internal/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigTag is the struct tag used to bind a flag to a specific key
+// of the configuration file, given as a dotted path from the top of
+// the file, e.g., `config:"server.port"`.  The value "-" prevents the
+// flag from being set from the configuration file.
+const ConfigTag = "config"
+
+// Formats of the configuration file.
+const (
+	ConfigYAML = "yaml" // YAML, or JSON, which is a subset of YAML
+	ConfigTOML = "toml" // TOML
+	ConfigJSON = "json" // JSON
+)
+
+// ConfigFlag is the global flag that names the configuration file
+// explicitly, if the application allows it; see App.AllowConfigFlag.
+// The path may be given as the following argument or assigned, as in
+// "--config=tool.yaml".
+const ConfigFlag = "--config"
+
+// ErrBadConfig indicates that the configuration file could not be
+// parsed.
+var ErrBadConfig = errors.New("invalid configuration file")
+
+// ErrMissingConfig indicates that a configuration file named
+// explicitly does not exist.
+var ErrMissingConfig = errors.New("configuration file not found")
+
+// configDecoders maps the formats of the configuration file to the
+// functions that decode them.
+var configDecoders = map[string]func(data []byte) (map[string]interface{}, error){
+	ConfigYAML: decodeYAML,
+	ConfigTOML: toml.Unmarshal,
+	ConfigJSON: decodeJSON,
+}
+
+// configEncoders maps the formats of the configuration file to the
+// functions that encode them, for writing configuration files.
+var configEncoders = map[string]func(cfg map[string]interface{}) ([]byte, error){
+	ConfigYAML: func(cfg map[string]interface{}) ([]byte, error) {
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(cfg); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), enc.Close()
+	},
+	ConfigTOML: toml.Marshal,
+	ConfigJSON: func(cfg map[string]interface{}) ([]byte, error) {
+		data, err := json.MarshalIndent(cfg, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	},
+}
+
+// decodeYAML decodes a YAML configuration file.  Errors identify the
+// line, and where possible the column, at which they occurred.
+func decodeYAML(data []byte) (map[string]interface{}, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, errors.New(strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+	if len(node.Content) == 0 {
+		return nil, nil
+	}
+
+	doc := node.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d, column %d: expected a mapping", doc.Line, doc.Column)
+	}
+	var cfg map[string]interface{}
+	if err := doc.Decode(&cfg); err != nil {
+		return nil, errors.New(strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+
+	return cfg, nil
+}
+
+// decodeJSON decodes a JSON configuration file.  Numbers are decoded
+// as json.Number, so that large integers are not rounded.  Errors
+// identify the line and column at which they occurred.
+func decodeJSON(data []byte) (map[string]interface{}, error) {
+	var cfg map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := dec.Decode(&cfg)
+
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		// The offset follows the offending character
+		offset = syntaxErr.Offset - 1
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+		err = fmt.Errorf("expected an object, not %s", typeErr.Value)
+	case err != nil:
+		return nil, err
+	default:
+		return cfg, nil
+	}
+
+	line, column := position(data, offset)
+	return nil, fmt.Errorf("line %d, column %d: %s", line, column, err)
+}
+
+// position is a helper that converts an offset within data into a
+// line and column, both counted from 1.
+func position(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	} else if offset < 0 {
+		offset = 0
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+
+	return line, column
+}
+
+// configFormat is a helper that selects the format of the
+// configuration file.  If no format is given, it is selected by the
+// file's extension: ".toml" selects ConfigTOML, ".json" selects
+// ConfigJSON, and any other extension selects ConfigYAML.
+func configFormat(path, format string) string {
+	if format != "" {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return ConfigTOML
+	case ".json":
+		return ConfigJSON
+	}
+
+	return ConfigYAML
+}
+
+// loadConfig loads the configuration file, which must contain a
+// mapping of keys to values in the specified format; see
+// configFormat.  Keys that are not strings, such as numbers, are
+// converted to text.  A missing file, or an empty path, results in a
+// nil configuration.
+func loadConfig(path, format string) (map[string]interface{}, error) {
+	if path == "" {
+		return nil, nil
+	}
+	format = configFormat(path, format)
+	decode, ok := configDecoders[format]
+	if !ok {
+		return nil, fmt.Errorf("%w %s: unknown format %q", ErrBadConfig, path, format)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	cfg, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %s", ErrBadConfig, path, err)
+	}
+
+	return normalizeConfig(cfg).(map[string]interface{}), nil
+}
+
+// loadConfigs loads the configuration files in order, merging each
+// into the configuration loaded from those before it; see
+// mergeConfig.  Missing files are ignored; if no files are found, the
+// configuration is nil.  The files loaded are reported to the
+// logger, if any.
+func loadConfigs(paths []string, format string, log *Logger) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	for _, path := range paths {
+		cfg, err := loadConfig(path, format)
+		if err != nil {
+			return nil, err
+		}
+		if cfg == nil {
+			continue
+		}
+		log.Debugf("loaded configuration file %s", path)
+		if result == nil {
+			result = map[string]interface{}{}
+		}
+		mergeConfig(result, cfg)
+	}
+
+	return result, nil
+}
+
+// mergeConfig merges the source configuration into the destination,
+// key by key: sections present in both are merged recursively, and
+// any other value in the source replaces the value in the
+// destination.  Lists are replaced, not concatenated.  The sections
+// of the source are copied, so the destination never shares them.
+func mergeConfig(dst, src map[string]interface{}) {
+	for key, value := range src {
+		section, ok := value.(map[string]interface{})
+		if !ok {
+			dst[key] = value
+			continue
+		}
+
+		existing, ok := dst[key].(map[string]interface{})
+		if !ok {
+			existing = map[string]interface{}{}
+			dst[key] = existing
+		}
+		mergeConfig(existing, section)
+	}
+}
+
+// extractConfig is a helper that removes the ConfigFlag, and the path
+// it names, from the arguments preceding any "--", returning the
+// path--or "" if the flag was not present--and the remaining
+// arguments.
+func extractConfig(args []string) (string, []string, error) {
+	path := ""
+	result := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+
+		// Check for the flag
+		switch {
+		case arg == ConfigFlag:
+			if i+1 >= len(args) {
+				return "", args, fmt.Errorf("%w %s", ErrMissingValue, ConfigFlag)
+			}
+			i++
+			path = args[i]
+		case strings.HasPrefix(arg, ConfigFlag+"="):
+			path = arg[len(ConfigFlag)+1:]
+		default:
+			result = append(result, arg)
+		}
+	}
+
+	return path, result, nil
+}
+
+// normalizeConfig is a helper that converts the mappings of a
+// configuration value with keys that are not strings into mappings
+// with string keys, so that every section of the configuration is a
+// map[string]interface{}.
+func normalizeConfig(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeConfig(item)
+		}
+		return v
+
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[fmt.Sprint(key)] = normalizeConfig(item)
+		}
+		return result
+
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeConfig(item)
+		}
+		return v
+	}
+
+	return value
+}
+
+// configText converts a value from the configuration to text, for
+// conversion in the same manner as a flag given on the command line.
+// Floating point numbers are rendered without exponents, so that
+// "1e3" sets an integer flag to 1000, and timestamps are rendered
+// using the first of the flag's layouts, or time.RFC3339.  Returns
+// false if the value is null, which leaves the flag unset.
+func configText(value interface{}, layouts []string) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", false
+
+	case string:
+		return v, true
+
+	case json.Number:
+		return v.String(), true
+
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+
+	case time.Time:
+		if len(layouts) == 0 {
+			layouts = DefaultLayouts
+		}
+		return v.Format(layouts[0]), true
+	}
+
+	return fmt.Sprint(value), true
+}
+
+// configLookup looks up a key, given as a list of path elements, in
+// the configuration.  Sections of the configuration are themselves
+// mappings; a mapping is never returned as a value.
+func configLookup(cfg map[string]interface{}, keys []string) (interface{}, bool) {
+	for i, key := range keys {
+		value, ok := cfg[key]
+		if !ok {
+			return nil, false
+		}
+
+		section, isSection := value.(map[string]interface{})
+		if i == len(keys)-1 {
+			return value, !isSection
+		}
+		if !isSection {
+			return nil, false
+		}
+		cfg = section
+	}
+
+	return nil, false
+}
+
+// configKeys returns the key of the configuration file the flag is
+// bound to, as a list of path elements, given the names of the
+// commands below the root; see applyConfig.  Returns nil if the flag
+// is not bound to a key.
+func (o *option) configKeys(path []string) []string {
+	if o.Config != nil {
+		if *o.Config == "-" {
+			return nil
+		}
+		return strings.Split(*o.Config, ".")
+	}
+	if o.Name == "" {
+		return nil
+	}
+
+	return append(append([]string{}, path...), o.Name)
+}
+
+// applyConfig sets flags from the configuration.  Unless overridden
+// with ConfigTag, a flag is set from the key named by its long name,
+// within the section named by each command below the root; for
+// instance, the flag "--fetch-url" of the command "tool remote add"
+// is set from the key "fetch-url" in the section "add" within the
+// section "remote".  Slice flags may be set from a list of values.
+// Values are converted as if given on the command line; see
+// configText.  Null values are ignored.
+func (o *options) applyConfig(cfg map[string]interface{}, path []string) error {
+	if o == nil || cfg == nil {
+		return nil
+	}
+
+	for _, opt := range o.Set.Options {
+		keys := opt.configKeys(path)
+		if keys == nil {
+			continue
+		}
+		value, ok := configLookup(cfg, keys)
+		if !ok || value == nil {
+			continue
+		}
+
+		// Set the flag, replacing the default values of slices
+		name := strings.Join(keys, ".")
+		field := o.Field(opt.Index)
+		set := false
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+		if isList(field.Type()) {
+			field.Set(reflect.Zero(field.Type()))
+			set = true
+		}
+		for _, v := range values {
+			text, ok := configText(v, opt.Layouts)
+			if !ok {
+				continue
+			}
+			if err := o.store(opt, text); err != nil {
+				return fmt.Errorf("%w %s for config key %s: %s", ErrInvalidValue, opt.Display(text), name, err)
+			}
+			set = true
+		}
+		if set {
+			o.setSource(opt, SourceConfig)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,838 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type configOptions struct {
+	Verbose bool     `opt:"verbose,v"`
+	Port    int      `opt:"port"`
+	Tags    []string `opt:"tag"`
+	Format  string   `opt:"format" choices:"json,yaml"`
+	Host    string   `opt:"host" config:"server.host"`
+	Skipped string   `opt:"skipped" config:"-"`
+}
+
+func TestLoadConfigBase(t *testing.T) {
+	path := makeArgFile(t, "verbose: true\nsub:\n  port: 80\n")
+
+	result, err := loadConfig(path, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"verbose": true,
+		"sub": map[string]interface{}{
+			"port": 80,
+		},
+	}, result)
+}
+
+func TestLoadConfigNoPath(t *testing.T) {
+	result, err := loadConfig("", "")
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestLoadConfigMissing(t *testing.T) {
+	result, err := loadConfig(filepath.Join(t.TempDir(), "missing"), "")
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestLoadConfigUnreadable(t *testing.T) {
+	result, err := loadConfig(t.TempDir(), "")
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestLoadConfigBadYAML(t *testing.T) {
+	path := makeArgFile(t, "- a\n- b\n")
+
+	result, err := loadConfig(path, "")
+
+	assert.ErrorIs(t, err, ErrBadConfig)
+	assert.Nil(t, result)
+}
+
+func TestConfigLookupBase(t *testing.T) {
+	cfg := map[string]interface{}{
+		"a": map[string]interface{}{
+			"b": "value",
+		},
+	}
+
+	result, ok := configLookup(cfg, []string{"a", "b"})
+
+	assert.True(t, ok)
+	assert.Equal(t, "value", result)
+}
+
+func TestConfigLookupMissing(t *testing.T) {
+	cfg := map[string]interface{}{
+		"a": map[string]interface{}{},
+	}
+
+	result, ok := configLookup(cfg, []string{"a", "b"})
+
+	assert.False(t, ok)
+	assert.Nil(t, result)
+}
+
+func TestConfigLookupSection(t *testing.T) {
+	cfg := map[string]interface{}{
+		"a": map[string]interface{}{},
+	}
+
+	result, ok := configLookup(cfg, []string{"a"})
+
+	assert.False(t, ok)
+	assert.Equal(t, map[string]interface{}{}, result)
+}
+
+func TestConfigLookupNotSection(t *testing.T) {
+	cfg := map[string]interface{}{
+		"a": "value",
+	}
+
+	result, ok := configLookup(cfg, []string{"a", "b"})
+
+	assert.False(t, ok)
+	assert.Nil(t, result)
+}
+
+func TestConfigLookupEmpty(t *testing.T) {
+	result, ok := configLookup(map[string]interface{}{}, nil)
+
+	assert.False(t, ok)
+	assert.Nil(t, result)
+}
+
+func TestOptionsApplyConfigBase(t *testing.T) {
+	cfg := map[string]interface{}{
+		"sub": map[string]interface{}{
+			"verbose": true,
+			"port":    8080,
+			"tag":     []interface{}{"a", "b"},
+			"format":  "yaml",
+			"skipped": "skipped",
+		},
+		"server": map[string]interface{}{
+			"host": "example.com",
+		},
+	}
+	opts, _ := newOptions(&Command{Defaults: &configOptions{Tags: []string{"default"}}})
+
+	err := opts.applyConfig(cfg, []string{"sub"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &configOptions{
+		Verbose: true,
+		Port:    8080,
+		Tags:    []string{"a", "b"},
+		Format:  "yaml",
+		Host:    "example.com",
+	}, opts.Value.Interface())
+	assert.Equal(t, map[string]Source{
+		"--verbose": SourceConfig,
+		"--port":    SourceConfig,
+		"--tag":     SourceConfig,
+		"--format":  SourceConfig,
+		"--host":    SourceConfig,
+	}, opts.sources)
+}
+
+func TestOptionsApplyConfigNil(t *testing.T) {
+	var opts *options
+
+	err := opts.applyConfig(map[string]interface{}{}, nil)
+
+	assert.NoError(t, err)
+}
+
+func TestOptionsApplyConfigNoConfig(t *testing.T) {
+	opts, _ := newOptions(&Command{Defaults: &configOptions{}})
+
+	err := opts.applyConfig(nil, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &configOptions{}, opts.Value.Interface())
+}
+
+func TestOptionsApplyConfigBadValue(t *testing.T) {
+	cfg := map[string]interface{}{
+		"format": "xml",
+	}
+	opts, _ := newOptions(&Command{Defaults: &configOptions{}})
+
+	err := opts.applyConfig(cfg, nil)
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.EqualError(t, err, `invalid value "xml" for config key format: must be one of json, yaml`)
+}
+
+func TestAppDispatchConfig(t *testing.T) {
+	path := makeArgFile(t, "verbose: true\nsub:\n  name: config\n")
+	sub := &optionsCommand{
+		Command: Command{Defaults: &subOptions{Name: "default"}},
+	}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	obj := &App{
+		Name:       "tool",
+		Root:       root,
+		ConfigFile: path,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &subOptions{
+		RootOptions: RootOptions{Verbose: true},
+		Name:        "config",
+		File:        "file",
+	}, sub.opts)
+}
+
+func TestAppDispatchConfigPrecedence(t *testing.T) {
+	setEnv(t, "TOOL_SUB_NAME", "env")
+	path := makeArgFile(t, "verbose: true\nsub:\n  name: config\n")
+	sub := newRunCommand("Subcommand", nil)
+	sub.Defaults = &subOptions{}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	obj := &App{
+		Name:       "tool",
+		Root:       root,
+		ConfigFile: path,
+		EnvPrefix:  "tool",
+	}
+	var inv *Invocation
+	sub.On("Run", Args{"file"}, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		inv = args.Get(1).(*Invocation)
+	})
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &subOptions{
+		RootOptions: RootOptions{Verbose: true},
+		Name:        "env",
+		File:        "file",
+	}, inv.Options)
+	assert.Equal(t, SourceConfig, inv.Source("--verbose"))
+	assert.Equal(t, SourceEnv, inv.Source("--name"))
+}
+
+type sliceOptions struct {
+	Tags []string `opt:"tag" help:"Tags to apply"`
+}
+
+type sliceCommand struct {
+	Command
+	tags []string
+}
+
+func (c *sliceCommand) Run(opts *sliceOptions) {
+	c.tags = opts.Tags
+}
+
+func TestAppDispatchConfigPrecedenceSlice(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		env    string
+		args   []string
+		result []string
+	}{
+		{"Default", "", "", nil, []string{"d"}},
+		{"ConfigScalar", "tag: x\n", "", nil, []string{"x"}},
+		{"ConfigList", "tag: [x, y]\n", "", nil, []string{"x", "y"}},
+		{"Env", "tag: [x, y]\n", "e1,e2", nil, []string{"e1", "e2"}},
+		{"FlagOverConfig", "tag: [x, y]\n", "", []string{"--tag", "a", "--tag", "b"}, []string{"a", "b"}},
+		{"FlagOverEnv", "", "e1,e2", []string{"--tag", "a"}, []string{"a"}},
+		{"FlagOverDefault", "", "", []string{"--tag", "a"}, []string{"a"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.env != "" {
+				setEnv(t, "TOOL_TAG", test.env)
+			}
+			cmd := &sliceCommand{Command: Command{Defaults: &sliceOptions{Tags: []string{"d"}}}}
+			obj := &App{
+				Name:           "tool",
+				Root:           cmd,
+				ConfigFile:     makeArgFile(t, test.config),
+				EnvPrefix:      "tool",
+				NoConfigSearch: true,
+			}
+
+			err := obj.Dispatch(context.Background(), test.args)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.result, cmd.tags)
+		})
+	}
+}
+
+func TestAppDispatchConfigBadFile(t *testing.T) {
+	path := makeArgFile(t, "- a\n")
+	obj := &App{
+		Root:       &Command{},
+		ConfigFile: path,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.ErrorIs(t, err, ErrBadConfig)
+}
+
+func TestAppDispatchConfigBadValue(t *testing.T) {
+	path := makeArgFile(t, "verbose: maybe\n")
+	obj := &App{
+		Root:       &Command{Defaults: &RootOptions{}},
+		ConfigFile: path,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+}
+
+func TestOptionsApplyConfigPointers(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &pointerOptions{}})
+
+	err := obj.applyConfig(map[string]interface{}{"force": false}, nil)
+
+	assert.NoError(t, err)
+	value := obj.Value.Interface().(*pointerOptions)
+	assert.False(t, *value.Force)
+	assert.Nil(t, value.Replicas)
+}
+
+func TestLoadConfigKeys(t *testing.T) {
+	path := makeArgFile(t, "ports:\n  80: http\n  443: https\nlist:\n  - 1: one\n")
+
+	result, err := loadConfig(path, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"ports": map[string]interface{}{
+			"80":  "http",
+			"443": "https",
+		},
+		"list": []interface{}{
+			map[string]interface{}{"1": "one"},
+		},
+	}, result)
+}
+
+func TestConfigText(t *testing.T) {
+	when := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	for _, tc := range []struct {
+		value   interface{}
+		layouts []string
+		text    string
+	}{
+		{"text", nil, "text"},
+		{8080, nil, "8080"},
+		{true, nil, "true"},
+		{1.5, nil, "1.5"},
+		{1e6, nil, "1000000"},
+		{when, nil, "2021-03-04T05:06:07Z"},
+		{when, []string{DateLayout}, "2021-03-04"},
+	} {
+		text, ok := configText(tc.value, tc.layouts)
+
+		assert.True(t, ok)
+		assert.Equal(t, tc.text, text)
+	}
+}
+
+func TestConfigTextNull(t *testing.T) {
+	text, ok := configText(nil, nil)
+
+	assert.False(t, ok)
+	assert.Equal(t, "", text)
+}
+
+func TestOptionsApplyConfigCoercion(t *testing.T) {
+	type opts struct {
+		Port  int           `opt:"port"`
+		Since time.Time     `opt:"since" layout:"2006-01-02"`
+		Wait  time.Duration `opt:"wait"`
+		Name  string        `opt:"name"`
+	}
+	path := makeArgFile(t, "port: 1e3\nsince: 2021-03-04\nwait: 5s\nname: ~\n")
+	cfg, _ := loadConfig(path, "")
+	obj, _ := newOptions(&Command{Defaults: &opts{Name: "default"}})
+
+	err := obj.applyConfig(cfg, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &opts{
+		Port:  1000,
+		Since: time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC),
+		Wait:  5 * time.Second,
+		Name:  "default",
+	}, obj.Value.Interface())
+	assert.Equal(t, map[string]Source{
+		"--port":  SourceConfig,
+		"--since": SourceConfig,
+		"--wait":  SourceConfig,
+	}, obj.sources)
+}
+
+func TestConfigFormat(t *testing.T) {
+	assert.Equal(t, ConfigTOML, configFormat("/etc/tool/config.toml", ""))
+	assert.Equal(t, ConfigTOML, configFormat("CONFIG.TOML", ""))
+	assert.Equal(t, ConfigYAML, configFormat("config.yaml", ""))
+	assert.Equal(t, ConfigYAML, configFormat("config", ""))
+	assert.Equal(t, ConfigTOML, configFormat("config", ConfigTOML))
+	assert.Equal(t, ConfigYAML, configFormat("config.toml", ConfigYAML))
+}
+
+func TestLoadConfigTOML(t *testing.T) {
+	path := makeArgFile(t, "verbose = true\n\n[sub]\nport = 80\n")
+
+	result, err := loadConfig(path, ConfigTOML)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"verbose": true,
+		"sub": map[string]interface{}{
+			"port": int64(80),
+		},
+	}, result)
+}
+
+func TestLoadConfigBadTOML(t *testing.T) {
+	path := makeArgFile(t, "verbose =\n")
+
+	result, err := loadConfig(path, ConfigTOML)
+
+	assert.ErrorIs(t, err, ErrBadConfig)
+	assert.Nil(t, result)
+}
+
+func TestLoadConfigUnknownFormat(t *testing.T) {
+	path := makeArgFile(t, "verbose: true\n")
+
+	result, err := loadConfig(path, "ini")
+
+	assert.ErrorIs(t, err, ErrBadConfig)
+	assert.EqualError(t, err, "invalid configuration file "+path+": unknown format \"ini\"")
+	assert.Nil(t, result)
+}
+
+func TestAppDispatchConfigTOML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool.toml")
+	if err := ioutil.WriteFile(path, []byte("verbose = true\n\n[sub]\nname = \"config\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sub := &optionsCommand{
+		Command: Command{Defaults: &subOptions{Name: "default"}},
+	}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	obj := (&App{Name: "tool", Root: root}).WithConfigFile(path)
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &subOptions{
+		RootOptions: RootOptions{Verbose: true},
+		Name:        "config",
+		File:        "file",
+	}, sub.opts)
+}
+
+func TestAppWithConfigFormat(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithConfigFormat(ConfigTOML)
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, ConfigTOML, obj.ConfigFormat)
+}
+
+func TestLoadConfigJSON(t *testing.T) {
+	path := makeArgFile(t, `{"verbose": true, "sub": {"port": 80, "big": 12345678901234567890}}`)
+
+	result, err := loadConfig(path, ConfigJSON)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"verbose": true,
+		"sub": map[string]interface{}{
+			"port": json.Number("80"),
+			"big":  json.Number("12345678901234567890"),
+		},
+	}, result)
+}
+
+func TestLoadConfigBadJSON(t *testing.T) {
+	path := makeArgFile(t, `{"verbose": }`)
+
+	result, err := loadConfig(path, ConfigJSON)
+
+	assert.ErrorIs(t, err, ErrBadConfig)
+	assert.Nil(t, result)
+}
+
+func TestConfigFormatJSON(t *testing.T) {
+	assert.Equal(t, ConfigJSON, configFormat("config.json", ""))
+	assert.Equal(t, ConfigJSON, configFormat("CONFIG.JSON", ""))
+}
+
+func TestConfigTextJSONNumber(t *testing.T) {
+	text, ok := configText(json.Number("8080"), nil)
+
+	assert.True(t, ok)
+	assert.Equal(t, "8080", text)
+}
+
+func TestMergeConfig(t *testing.T) {
+	shared := map[string]interface{}{"port": 80}
+	dst := map[string]interface{}{
+		"verbose": false,
+		"tags":    []interface{}{"a", "b"},
+		"server": map[string]interface{}{
+			"host": "example.com",
+			"port": 8080,
+		},
+		"format": map[string]interface{}{"type": "json"},
+	}
+
+	mergeConfig(dst, map[string]interface{}{
+		"verbose": true,
+		"tags":    []interface{}{"c"},
+		"server": map[string]interface{}{
+			"port": 9090,
+		},
+		"format": "yaml",
+		"client": shared,
+	})
+
+	assert.Equal(t, map[string]interface{}{
+		"verbose": true,
+		"tags":    []interface{}{"c"},
+		"server": map[string]interface{}{
+			"host": "example.com",
+			"port": 9090,
+		},
+		"format": "yaml",
+		"client": map[string]interface{}{"port": 80},
+	}, dst)
+	dst["client"].(map[string]interface{})["port"] = 443
+	assert.Equal(t, 80, shared["port"])
+}
+
+func TestLoadConfigs(t *testing.T) {
+	dir := t.TempDir()
+	system := filepath.Join(dir, "system.yaml")
+	user := filepath.Join(dir, "user.toml")
+	project := filepath.Join(dir, "project.json")
+	assert.NoError(t, ioutil.WriteFile(system, []byte("verbose: true\nsub:\n  port: 80\n  name: system\n"), 0o600))
+	assert.NoError(t, ioutil.WriteFile(user, []byte("[sub]\nport = 8080\n"), 0o600))
+	assert.NoError(t, ioutil.WriteFile(project, []byte(`{"sub": {"name": "project"}}`), 0o600))
+
+	result, err := loadConfigs([]string{system, filepath.Join(dir, "missing"), user, project}, "", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"verbose": true,
+		"sub": map[string]interface{}{
+			"port": int64(8080),
+			"name": "project",
+		},
+	}, result)
+}
+
+func TestLoadConfigsNone(t *testing.T) {
+	result, err := loadConfigs([]string{filepath.Join(t.TempDir(), "missing")}, "", nil)
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestLoadConfigsError(t *testing.T) {
+	path := makeArgFile(t, "- a\n")
+
+	result, err := loadConfigs([]string{path}, "", nil)
+
+	assert.ErrorIs(t, err, ErrBadConfig)
+	assert.Nil(t, result)
+}
+
+func TestAppConfig(t *testing.T) {
+	first := makeArgFile(t, "verbose: true\nname: first\n")
+	second := makeArgFile(t, "name: second\n")
+	obj := (&App{}).WithConfigFiles(first).WithConfigFile(second)
+
+	result, err := obj.Config(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"verbose": true,
+		"name":    "second",
+	}, result)
+}
+
+func TestAppWithConfigFiles(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithConfigFiles("a", "b")
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, []string{"a", "b"}, obj.ConfigFiles)
+}
+
+func TestAppDispatchConfigFiles(t *testing.T) {
+	system := makeArgFile(t, "verbose: true\nsub:\n  name: system\n")
+	user := makeArgFile(t, "sub:\n  name: user\n")
+	sub := &optionsCommand{
+		Command: Command{Defaults: &subOptions{Name: "default"}},
+	}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	obj := (&App{Name: "tool", Root: root}).WithConfigFiles(system, user)
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &subOptions{
+		RootOptions: RootOptions{Verbose: true},
+		Name:        "user",
+		File:        "file",
+	}, sub.opts)
+}
+
+func TestLoadConfigYAMLSyntaxPosition(t *testing.T) {
+	path := makeArgFile(t, "a: 1\nb: [1, 2\n")
+
+	result, err := loadConfig(path, ConfigYAML)
+
+	assert.ErrorIs(t, err, ErrBadConfig)
+	assert.Contains(t, err.Error(), "invalid configuration file "+path+": line ")
+	assert.Nil(t, result)
+}
+
+func TestLoadConfigYAMLNotMapping(t *testing.T) {
+	path := makeArgFile(t, "# comment\n  - a\n  - b\n")
+
+	result, err := loadConfig(path, ConfigYAML)
+
+	assert.ErrorIs(t, err, ErrBadConfig)
+	assert.EqualError(t, err, "invalid configuration file "+path+": line 2, column 3: expected a mapping")
+	assert.Nil(t, result)
+}
+
+func TestLoadConfigYAMLEmpty(t *testing.T) {
+	path := makeArgFile(t, "# nothing\n")
+
+	result, err := loadConfig(path, ConfigYAML)
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestLoadConfigJSONSyntaxPosition(t *testing.T) {
+	path := makeArgFile(t, "{\n  \"a\": 1,\n  \"b\": }\n")
+
+	result, err := loadConfig(path, ConfigJSON)
+
+	assert.ErrorIs(t, err, ErrBadConfig)
+	assert.EqualError(t, err, "invalid configuration file "+path+": line 3, column 8: invalid character '}' looking for beginning of value")
+	assert.Nil(t, result)
+}
+
+func TestLoadConfigJSONNotObject(t *testing.T) {
+	path := makeArgFile(t, "\n[1, 2]\n")
+
+	result, err := loadConfig(path, ConfigJSON)
+
+	assert.ErrorIs(t, err, ErrBadConfig)
+	assert.EqualError(t, err, "invalid configuration file "+path+": line 2, column 1: expected an object, not array")
+	assert.Nil(t, result)
+}
+
+func TestPosition(t *testing.T) {
+	data := []byte("ab\ncd\nef")
+
+	line, column := position(data, 0)
+	assert.Equal(t, 1, line)
+	assert.Equal(t, 1, column)
+	line, column = position(data, 4)
+	assert.Equal(t, 2, line)
+	assert.Equal(t, 2, column)
+	line, column = position(data, -1)
+	assert.Equal(t, 1, line)
+	assert.Equal(t, 1, column)
+	line, column = position(data, 100)
+	assert.Equal(t, 3, line)
+	assert.Equal(t, 3, column)
+}
+
+func TestExtractConfig(t *testing.T) {
+	path, args, err := extractConfig([]string{"sub", "--config", "a.yaml", "arg", "--", "--config", "b.yaml"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "a.yaml", path)
+	assert.Equal(t, []string{"sub", "arg", "--", "--config", "b.yaml"}, args)
+}
+
+func TestExtractConfigAssigned(t *testing.T) {
+	path, args, err := extractConfig([]string{"--config=a.yaml", "sub"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "a.yaml", path)
+	assert.Equal(t, []string{"sub"}, args)
+}
+
+func TestExtractConfigMissingValue(t *testing.T) {
+	_, _, err := extractConfig([]string{"sub", "--config"})
+
+	assert.ErrorIs(t, err, ErrMissingValue)
+	assert.EqualError(t, err, "missing value for flag --config")
+}
+
+func TestAppWithConfigFlag(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithConfigFlag(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.AllowConfigFlag)
+}
+
+func TestAppConfigEnv(t *testing.T) {
+	assert.Equal(t, "TOOL_CONFIG", (&App{Name: "tool"}).ConfigEnv())
+	assert.Equal(t, "MY_TOOL_CONFIG", (&App{Name: "tool", EnvPrefix: "my-tool"}).ConfigEnv())
+}
+
+// configFlagApp is a helper that constructs an application allowing
+// the ConfigFlag, with a configuration file that should be bypassed.
+func configFlagApp(t *testing.T) (*App, *optionsCommand) {
+	sub := &optionsCommand{
+		Command: Command{Defaults: &subOptions{Name: "default"}},
+	}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	obj := (&App{Name: "tool", Root: root}).
+		WithConfigFlag(true).
+		WithConfigFile(makeArgFile(t, "verbose: true\nsub:\n  name: default-file\n"))
+
+	return obj, sub
+}
+
+func TestAppDispatchConfigFlag(t *testing.T) {
+	setEnv(t, "TOOL_CONFIG", makeArgFile(t, "sub:\n  name: env\n"))
+	obj, sub := configFlagApp(t)
+	path := makeArgFile(t, "sub:\n  name: flag\n")
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "--config", path, "file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &subOptions{Name: "flag", File: "file"}, sub.opts)
+}
+
+func TestAppDispatchConfigFlagEnv(t *testing.T) {
+	setEnv(t, "TOOL_CONFIG", makeArgFile(t, "sub:\n  name: env\n"))
+	obj, sub := configFlagApp(t)
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &subOptions{Name: "env", File: "file"}, sub.opts)
+}
+
+func TestAppDispatchConfigFlagUnset(t *testing.T) {
+	setEnv(t, "TOOL_CONFIG", "")
+	obj, sub := configFlagApp(t)
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &subOptions{
+		RootOptions: RootOptions{Verbose: true},
+		Name:        "default-file",
+		File:        "file",
+	}, sub.opts)
+}
+
+func TestAppDispatchConfigFlagEmptyFile(t *testing.T) {
+	setEnv(t, "TOOL_CONFIG", "")
+	obj, sub := configFlagApp(t)
+	path := makeArgFile(t, "")
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "--config=" + path, "file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &subOptions{Name: "default", File: "file"}, sub.opts)
+}
+
+func TestAppDispatchConfigFlagMissingFile(t *testing.T) {
+	setEnv(t, "TOOL_CONFIG", "")
+	obj, _ := configFlagApp(t)
+	path := filepath.Join(t.TempDir(), "missing.yaml")
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "--config", path, "file"})
+
+	assert.ErrorIs(t, err, ErrMissingConfig)
+	assert.EqualError(t, err, "configuration file not found: "+path)
+}
+
+func TestAppDispatchConfigFlagMissingValue(t *testing.T) {
+	obj, _ := configFlagApp(t)
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "--config"})
+
+	assert.ErrorIs(t, err, ErrMissingValue)
+	code, usage := ExitControl(err)
+	assert.Equal(t, UsageExitCode, code)
+	assert.True(t, usage)
+}
+
+func TestAppDispatchConfigFlagNotAllowed(t *testing.T) {
+	obj, _ := configFlagApp(t)
+	obj.AllowConfigFlag = false
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "--config", "x", "file"})
+
+	assert.ErrorIs(t, err, ErrUnknownFlag)
+}
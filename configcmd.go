@@ -0,0 +1,563 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/klmitch/nelson/internal/shlex"
+)
+
+// Errors produced by the configuration management commands.
+var (
+	ErrUnknownConfigKey = errors.New("unknown configuration key")
+	ErrConfigKeyNotSet  = errors.New("configuration key not set")
+	ErrNoConfigFile     = errors.New("no configuration file")
+)
+
+// DefaultEditor is the editor used by the "config edit" command if
+// neither VISUAL nor EDITOR is set.
+const DefaultEditor = "vi"
+
+// configKeyOptions are the arguments of the get and unset
+// subcommands.
+type configKeyOptions struct {
+	Key string `arg:"KEY" help:"Configuration key, as a dotted path"`
+}
+
+// configSetOptions are the arguments of the set subcommand.
+type configSetOptions struct {
+	Key    string   `arg:"KEY" help:"Configuration key, as a dotted path"`
+	Values []string `arg:"VALUE" arity:"[1,)" help:"Value to set; list-valued keys accept several"`
+}
+
+// configListOptions are the flags of the list subcommand.
+type configListOptions struct {
+	All bool `opt:"all" help:"Also list known keys that are not set"`
+}
+
+// configPathOptions are the flags of the path subcommand.
+type configPathOptions struct {
+	All bool `opt:"all" help:"List every configuration file, in the order loaded"`
+}
+
+// Subcommands of the configuration command group.
+type (
+	configGet      struct{ Command }
+	configSet      struct{ Command }
+	configUnset    struct{ Command }
+	configList     struct{ Command }
+	configEdit     struct{ Command }
+	configPath     struct{ Command }
+	configValidate struct{ Command }
+	configSample   struct{ Command }
+)
+
+// NewConfigCommand constructs a "config" command group managing the
+// application's configuration files, with the subcommands "get",
+// "set", "unset", "list", "edit", "path", "validate", and "sample".
+// Keys are the dotted paths to which flags are bound; see ConfigTag.
+// The get and list subcommands report the merged configuration, while
+// set, unset, and edit modify a single file: the file named by the
+// ConfigFlag or its environment variable, if given; otherwise, the
+// ConfigFile, or the last of the ConfigFiles; otherwise, if the
+// application searches for configuration files, "config.yaml" in the
+// user's configuration directory, "$XDG_CONFIG_HOME/<app>".  The path
+// subcommand reports that file.  Values given to set are checked by
+// converting them as if given on the command line, and only keys
+// bound to flags may be set.  Note that rewriting a file discards any
+// comments it contains.  The validate subcommand checks each
+// configuration file; see App.ValidateConfig.  The sample subcommand
+// prints a sample configuration file; see App.WriteSampleConfig.
+func NewConfigCommand() *Command {
+	return &Command{
+		Summary: "Manage the configuration",
+		Subcommands: map[string]ICommand{
+			"get": &configGet{Command{
+				Summary:  "Print a configuration value",
+				Defaults: &configKeyOptions{},
+			}},
+			"set": &configSet{Command{
+				Summary:  "Set a configuration value",
+				Defaults: &configSetOptions{},
+			}},
+			"unset": &configUnset{Command{
+				Summary:  "Remove a configuration value",
+				Defaults: &configKeyOptions{},
+			}},
+			"list": &configList{Command{
+				Summary:  "List the configuration values",
+				Defaults: &configListOptions{},
+			}},
+			"edit": &configEdit{Command{
+				Summary:     "Edit the configuration file",
+				Description: "Open the configuration file in the editor named by VISUAL or EDITOR.",
+			}},
+			"path": &configPath{Command{
+				Summary:  "Print the path of the configuration file",
+				Defaults: &configPathOptions{},
+			}},
+			"validate": &configValidate{Command{
+				Summary: "Check the configuration files",
+			}},
+			"sample": &configSample{Command{
+				Summary: "Print a sample configuration file",
+			}},
+		},
+	}
+}
+
+// Run prints a configuration value.  Lists are printed one value per
+// line.
+func (c *configGet) Run(ctx context.Context, opts *configKeyOptions, app *App, inv *Invocation, streams *IOStreams) error {
+	if _, err := app.configEntry(opts.Key); err != nil {
+		return err
+	}
+	cfg, err := app.config(ctx, inv.config, nil)
+	if err != nil {
+		return err
+	}
+	value, ok := configLookup(cfg, strings.Split(opts.Key, "."))
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrConfigKeyNotSet, opts.Key)
+	}
+
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	for _, v := range values {
+		if text, ok := configText(v, nil); ok {
+			fmt.Fprintln(streams.Out, text)
+		}
+	}
+
+	return nil
+}
+
+// Run sets a configuration value.
+func (c *configSet) Run(opts *configSetOptions, app *App, inv *Invocation, streams *IOStreams) error {
+	entry, err := app.configEntry(opts.Key)
+	if err != nil {
+		return err
+	}
+	value, err := entry.value(opts.Key, opts.Values)
+	if err != nil {
+		return err
+	}
+
+	return app.updateConfig(inv, func(cfg map[string]interface{}) error {
+		setConfigKey(cfg, strings.Split(opts.Key, "."), value)
+		return nil
+	})
+}
+
+// Run removes a configuration value.
+func (c *configUnset) Run(opts *configKeyOptions, app *App, inv *Invocation) error {
+	if _, err := app.configEntry(opts.Key); err != nil {
+		return err
+	}
+
+	return app.updateConfig(inv, func(cfg map[string]interface{}) error {
+		if !unsetConfigKey(cfg, strings.Split(opts.Key, ".")) {
+			return fmt.Errorf("%w: %s", ErrConfigKeyNotSet, opts.Key)
+		}
+		return nil
+	})
+}
+
+// Run lists the configuration values as "key = value", sorted by
+// key.
+func (c *configList) Run(ctx context.Context, opts *configListOptions, app *App, inv *Invocation, streams *IOStreams) error {
+	cfg, err := app.config(ctx, inv.config, nil)
+	if err != nil {
+		return err
+	}
+	values := map[string]string{}
+	flattenConfig(values, nil, cfg)
+
+	// Select the keys to list
+	entries := app.configEntries()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	if opts.All {
+		for key := range entries {
+			if _, ok := values[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if value, ok := values[key]; ok {
+			fmt.Fprintf(streams.Out, "%s = %s\n", key, value)
+		} else if text, ok := entries[key].opts.DefaultText(entries[key].opt); ok {
+			fmt.Fprintf(streams.Out, "%s (default %s)\n", key, text)
+		} else {
+			fmt.Fprintln(streams.Out, key)
+		}
+	}
+
+	return nil
+}
+
+// Run opens the configuration file in the user's editor, then checks
+// that the edited file can be loaded.
+func (c *configEdit) Run(app *App, inv *Invocation, streams *IOStreams) error {
+	path, err := app.configTarget(inv)
+	if err != nil {
+		return err
+	}
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = DefaultEditor
+	}
+	words, err := shlex.Split(editor)
+	if err != nil {
+		return err
+	} else if len(words) == 0 {
+		words = []string{DefaultEditor}
+	}
+
+	// Run the editor
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	cmd := exec.Command(words[0], append(words[1:], path)...) //nolint:gosec
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = streams.In, streams.Out, streams.ErrOut
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	_, err = loadConfig(path, app.ConfigFormat)
+	return err
+}
+
+// Run prints the path of the configuration file modified by the
+// other subcommands, or of every configuration file.
+func (c *configPath) Run(opts *configPathOptions, app *App, inv *Invocation, streams *IOStreams) error {
+	if !opts.All {
+		path, err := app.configTarget(inv)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(streams.Out, path)
+		return nil
+	}
+
+	for _, path := range app.configPaths(inv) {
+		fmt.Fprintln(streams.Out, path)
+	}
+
+	return nil
+}
+
+// Run checks each configuration file, reporting every failure,
+// labeled with the path of the file.
+func (c *configValidate) Run(app *App, inv *Invocation) error {
+	var failures []error
+	for _, path := range app.configPaths(inv) {
+		cfg, err := loadConfig(path, app.ConfigFormat)
+		if err != nil {
+			return err
+		}
+		var verr *ValidationError
+		if errors.As(app.ValidateConfig(cfg), &verr) {
+			for _, failure := range verr.Failures {
+				failures = append(failures, fmt.Errorf("%s: %w", path, failure))
+			}
+		}
+	}
+	if len(failures) > 0 {
+		return &ValidationError{Failures: failures}
+	}
+
+	return nil
+}
+
+// Run prints a sample configuration file.
+func (c *configSample) Run(app *App, streams *IOStreams) error {
+	return app.WriteSampleConfig(streams.Out)
+}
+
+// configPaths returns the paths of every configuration file, in the
+// order loaded: the file named by the ConfigFlag or its environment
+// variable, if given, or the files returned by configFiles.
+func (a *App) configPaths(inv *Invocation) []string {
+	if inv.config != "" {
+		return []string{inv.config}
+	} else if env := os.Getenv(a.ConfigEnv()); a.AllowConfigFlag && env != "" {
+		return []string{env}
+	}
+
+	return a.configFiles()
+}
+
+// configEntry describes a flag bound to a configuration key.
+type configEntry struct {
+	opts *options // Options of the command declaring the flag
+	opt  *option  // The flag
+}
+
+// configEntries returns the flags bound to configuration keys, by
+// key: the application-global flags, and the flags of every command
+// reachable without computing dynamic subcommands.  Aliases are not
+// followed.  If several flags are bound to the same key, the first
+// found is returned.
+func (a *App) configEntries() map[string]configEntry {
+	entries := map[string]configEntry{}
+	add := func(opts *options, path []string) {
+		if opts == nil {
+			return
+		}
+		for _, opt := range opts.Set.Options {
+			keys := opt.configKeys(path)
+			if keys == nil {
+				continue
+			}
+			if key := strings.Join(keys, "."); entries[key].opt == nil {
+				entries[key] = configEntry{opts: opts, opt: opt}
+			}
+		}
+	}
+
+	for _, obj := range a.Globals {
+		opts, _ := optionsFor(obj, false)
+		add(opts, nil)
+	}
+	var walk func(cmd ICommand, path []string)
+	walk = func(cmd ICommand, path []string) {
+		opts, _ := newOptions(cmd)
+		add(opts, path)
+		for name, sub := range cmd.GetSubcommands() {
+			if !IsAlias(sub) {
+				walk(sub, append(append([]string{}, path...), name))
+			}
+		}
+	}
+	walk(a.Root, nil)
+
+	return entries
+}
+
+// configEntry returns the flag bound to a configuration key, or an
+// error if the key is not bound to a flag.
+func (a *App) configEntry(key string) (configEntry, error) {
+	entry, ok := a.configEntries()[key]
+	if !ok {
+		return entry, fmt.Errorf("%w: %s", ErrUnknownConfigKey, key)
+	}
+
+	return entry, nil
+}
+
+// value checks the values given for a key by converting them as if
+// given on the command line, returning the value to store in the
+// configuration: a list, for list-valued flags, or a single value.
+// Booleans and numbers are stored as such; see configScalar.
+func (e configEntry) value(key string, texts []string) (interface{}, error) {
+	list := isList(e.opt.Type)
+	if !list && len(texts) > 1 {
+		return nil, usageError(fmt.Errorf("%w: %s", ErrTooManyArgs, strings.Join(texts[1:], " ")))
+	}
+
+	typ := e.opt.Type
+	if list {
+		typ = typ.Elem()
+	}
+	values := make([]interface{}, 0, len(texts))
+	for _, text := range texts {
+		if err := e.opts.store(e.opt, text); err != nil {
+			return nil, fmt.Errorf("%w %s for config key %s: %s", ErrInvalidValue, e.opt.Display(text), key, err)
+		}
+		values = append(values, configScalar(typ, text))
+	}
+
+	if list {
+		return values, nil
+	}
+	return values[0], nil
+}
+
+// configScalar is a helper that converts text, which has already
+// been checked, to the value stored in the configuration for a flag
+// of the specified type: booleans and numbers are stored as such, so
+// that the configuration file is idiomatic, while other values,
+// including durations and types that convert text themselves, are
+// stored as text.
+func configScalar(typ reflect.Type, text string) interface{} {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	ptr := reflect.PtrTo(typ)
+	if typ == durationType || ptr.Implements(valueType) || ptr.Implements(textUnmarshalerType) {
+		return text
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(text); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, err := strconv.ParseInt(text, 0, 64); err == nil {
+			return i
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u, err := strconv.ParseUint(text, 0, 64); err == nil {
+			return u
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	}
+
+	return text
+}
+
+// configTarget returns the path of the configuration file modified
+// by the configuration management commands; see NewConfigCommand.
+func (a *App) configTarget(inv *Invocation) (string, error) {
+	switch {
+	case inv.config != "":
+		return inv.config, nil
+	case a.AllowConfigFlag && os.Getenv(a.ConfigEnv()) != "":
+		return os.Getenv(a.ConfigEnv()), nil
+	case a.ConfigFile != "":
+		return a.ConfigFile, nil
+	case len(a.ConfigFiles) > 0:
+		return a.ConfigFiles[len(a.ConfigFiles)-1], nil
+	case a.NoConfigSearch:
+		return "", ErrNoConfigFile
+	}
+
+	home, _ := os.UserHomeDir()
+	return filepath.Join(xdgDir("XDG_CONFIG_HOME", home, ".config"), a.name(), "config.yaml"), nil
+}
+
+// updateConfig loads the configuration file modified by the
+// configuration management commands, applies a change to it, and
+// writes it back in the same format, creating it if necessary.
+func (a *App) updateConfig(inv *Invocation, change func(cfg map[string]interface{}) error) error {
+	path, err := a.configTarget(inv)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(path, a.ConfigFormat)
+	if err != nil {
+		return err
+	} else if cfg == nil {
+		cfg = map[string]interface{}{}
+	}
+	if err := change(cfg); err != nil {
+		return err
+	}
+
+	// Write the file
+	format := configFormat(path, a.ConfigFormat)
+	encode, ok := configEncoders[format]
+	if !ok {
+		return fmt.Errorf("%w %s: unknown format %q", ErrBadConfig, path, format)
+	}
+	data, err := encode(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0o644) //nolint:gosec
+}
+
+// setConfigKey sets a key, given as a list of path elements, in the
+// configuration, creating sections as needed and replacing any value
+// in the way.
+func setConfigKey(cfg map[string]interface{}, keys []string, value interface{}) {
+	for _, key := range keys[:len(keys)-1] {
+		section, ok := cfg[key].(map[string]interface{})
+		if !ok {
+			section = map[string]interface{}{}
+			cfg[key] = section
+		}
+		cfg = section
+	}
+	cfg[keys[len(keys)-1]] = value
+}
+
+// unsetConfigKey removes a key, given as a list of path elements,
+// from the configuration, along with any sections left empty.
+// Returns false if the key was not set.
+func unsetConfigKey(cfg map[string]interface{}, keys []string) bool {
+	if len(keys) == 1 {
+		if _, ok := cfg[keys[0]]; !ok {
+			return false
+		}
+		delete(cfg, keys[0])
+		return true
+	}
+
+	section, ok := cfg[keys[0]].(map[string]interface{})
+	if !ok || !unsetConfigKey(section, keys[1:]) {
+		return false
+	}
+	if len(section) == 0 {
+		delete(cfg, keys[0])
+	}
+
+	return true
+}
+
+// flattenConfig is a helper that flattens the configuration into a
+// map from dotted keys to the text of their values.  Lists are
+// rendered as "[a, b]".
+func flattenConfig(result map[string]string, prefix []string, cfg map[string]interface{}) {
+	for key, value := range cfg {
+		path := append(append([]string{}, prefix...), key)
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flattenConfig(result, path, v)
+
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				text, _ := configText(item, nil)
+				items = append(items, text)
+			}
+			result[strings.Join(path, ".")] = "[" + strings.Join(items, ", ") + "]"
+
+		default:
+			text, _ := configText(v, nil)
+			result[strings.Join(path, ".")] = text
+		}
+	}
+}
@@ -0,0 +1,324 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type configCmdOptions struct {
+	Port    int           `opt:"port" default:"80"`
+	Tags    []string      `opt:"tag"`
+	Format  string        `opt:"format" choices:"json,yaml"`
+	Timeout time.Duration `opt:"timeout"`
+	Host    string        `opt:"host" config:"server.host"`
+}
+
+// configCmdApp is a helper that constructs an application with the
+// configuration command group and a configuration file with the
+// specified name and content.
+func configCmdApp(t *testing.T, name, content string) (*App, *bytes.Buffer, string) {
+	path := filepath.Join(t.TempDir(), name)
+	if content != "" {
+		if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stdout := &bytes.Buffer{}
+	obj := (&App{
+		Name: "tool",
+		Root: &Command{
+			Subcommands: map[string]ICommand{
+				"config": NewConfigCommand(),
+				"serve":  &Command{Defaults: &configCmdOptions{}},
+				"alias":  Alias(&Command{Defaults: &configCmdOptions{}}),
+			},
+		},
+		Stdout: stdout,
+	}).WithGlobals(&globalOptions{}).WithNoConfigSearch(true).WithConfigFile(path)
+
+	return obj, stdout, path
+}
+
+func TestConfigCommandGet(t *testing.T) {
+	obj, stdout, _ := configCmdApp(t, "tool.yaml", "serve:\n  port: 8080\n")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "get", "serve.port"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "8080\n", stdout.String())
+}
+
+func TestConfigCommandGetList(t *testing.T) {
+	obj, stdout, _ := configCmdApp(t, "tool.yaml", "serve:\n  tag: [a, b]\n")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "get", "serve.tag"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "a\nb\n", stdout.String())
+}
+
+func TestConfigCommandGetNotSet(t *testing.T) {
+	obj, _, _ := configCmdApp(t, "tool.yaml", "")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "get", "serve.port"})
+
+	assert.ErrorIs(t, err, ErrConfigKeyNotSet)
+	assert.EqualError(t, err, "configuration key not set: serve.port")
+}
+
+func TestConfigCommandGetUnknown(t *testing.T) {
+	obj, _, _ := configCmdApp(t, "tool.yaml", "")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "get", "alias.port"})
+
+	assert.ErrorIs(t, err, ErrUnknownConfigKey)
+	assert.EqualError(t, err, "unknown configuration key: alias.port")
+}
+
+func TestConfigCommandSetYAML(t *testing.T) {
+	obj, _, path := configCmdApp(t, "tool.yaml", "serve:\n  format: json\n")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "set", "serve.port", "8080"})
+	assert.NoError(t, err)
+	err = obj.Dispatch(context.Background(), []string{"config", "set", "server.host", "example.com"})
+	assert.NoError(t, err)
+	err = obj.Dispatch(context.Background(), []string{"config", "set", "serve.tag", "a", "b"})
+	assert.NoError(t, err)
+	err = obj.Dispatch(context.Background(), []string{"config", "set", "log-level", "debug"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, `log-level: debug
+serve:
+  format: json
+  port: 8080
+  tag:
+  - a
+  - b
+server:
+  host: example.com
+`, readFile(t, path))
+}
+
+func TestConfigCommandSetTOML(t *testing.T) {
+	obj, _, path := configCmdApp(t, "tool.toml", "")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "set", "serve.timeout", "5s"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "[serve]\ntimeout = \"5s\"\n", readFile(t, path))
+}
+
+func TestConfigCommandSetJSON(t *testing.T) {
+	obj, _, path := configCmdApp(t, "tool.json", `{"serve": {"port": 80}}`)
+
+	err := obj.Dispatch(context.Background(), []string{"config", "set", "serve.format", "yaml"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"serve\": {\n    \"format\": \"yaml\",\n    \"port\": 80\n  }\n}\n", readFile(t, path))
+}
+
+func TestConfigCommandSetInvalid(t *testing.T) {
+	obj, _, _ := configCmdApp(t, "tool.yaml", "")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "set", "serve.format", "xml"})
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.EqualError(t, err, `invalid value "xml" for config key serve.format: must be one of json, yaml`)
+}
+
+func TestConfigCommandSetTooMany(t *testing.T) {
+	obj, _, _ := configCmdApp(t, "tool.yaml", "")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "set", "serve.port", "1", "2"})
+
+	assert.ErrorIs(t, err, ErrTooManyArgs)
+}
+
+func TestConfigCommandSetUnknown(t *testing.T) {
+	obj, _, _ := configCmdApp(t, "tool.yaml", "")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "set", "serve.bogus", "1"})
+
+	assert.ErrorIs(t, err, ErrUnknownConfigKey)
+}
+
+func TestConfigCommandUnset(t *testing.T) {
+	obj, _, path := configCmdApp(t, "tool.yaml", "verbose: true\nserve:\n  port: 8080\n")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "unset", "serve.port"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "verbose: true\n", readFile(t, path))
+}
+
+func TestConfigCommandUnsetNotSet(t *testing.T) {
+	obj, _, _ := configCmdApp(t, "tool.yaml", "serve:\n  port: 8080\n")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "unset", "serve.format"})
+
+	assert.ErrorIs(t, err, ErrConfigKeyNotSet)
+}
+
+func TestConfigCommandList(t *testing.T) {
+	obj, stdout, _ := configCmdApp(t, "tool.yaml", "other: x\nserve:\n  port: 8080\n  tag: [a, b]\n")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "list"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "other = x\nserve.port = 8080\nserve.tag = [a, b]\n", stdout.String())
+}
+
+func TestConfigCommandListAll(t *testing.T) {
+	obj, stdout, _ := configCmdApp(t, "tool.yaml", "serve:\n  format: json\n")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "list", "--all"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `config.list.all
+config.path.all
+log-level
+no-color
+serve.format = json
+serve.port (default 80)
+serve.tag
+serve.timeout
+server.host
+`, stdout.String())
+}
+
+func TestConfigCommandPath(t *testing.T) {
+	obj, stdout, path := configCmdApp(t, "tool.yaml", "")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "path"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, path+"\n", stdout.String())
+}
+
+func TestConfigCommandPathAll(t *testing.T) {
+	obj, stdout, path := configCmdApp(t, "tool.yaml", "")
+	obj.ConfigFiles = []string{"/etc/tool.yaml"}
+
+	err := obj.Dispatch(context.Background(), []string{"config", "path", "--all"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/tool.yaml\n"+path+"\n", stdout.String())
+}
+
+func TestConfigCommandPathConfigFlag(t *testing.T) {
+	obj, stdout, _ := configCmdApp(t, "tool.yaml", "")
+	obj.AllowConfigFlag = true
+	path := makeArgFile(t, "")
+
+	err := obj.Dispatch(context.Background(), []string{"--config", path, "config", "path", "--all"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, path+"\n", stdout.String())
+}
+
+func TestConfigCommandEdit(t *testing.T) {
+	obj, _, path := configCmdApp(t, "tool.yaml", "")
+	editor := filepath.Join(t.TempDir(), "editor")
+	assert.NoError(t, ioutil.WriteFile(editor, []byte("#!/bin/sh\necho \"$1: edited\" > \"$2\"\n"), 0o700))
+	setEnv(t, "VISUAL", "")
+	setEnv(t, "EDITOR", editor+" key")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "edit"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "key: edited\n", readFile(t, path))
+}
+
+func TestConfigCommandEditInvalid(t *testing.T) {
+	obj, _, _ := configCmdApp(t, "tool.yaml", "")
+	editor := filepath.Join(t.TempDir(), "editor")
+	assert.NoError(t, ioutil.WriteFile(editor, []byte("#!/bin/sh\necho '- bad' > \"$1\"\n"), 0o700))
+	setEnv(t, "VISUAL", editor)
+
+	err := obj.Dispatch(context.Background(), []string{"config", "edit"})
+
+	assert.ErrorIs(t, err, ErrBadConfig)
+}
+
+func TestAppConfigTarget(t *testing.T) {
+	setEnv(t, "XDG_CONFIG_HOME", "/xdg")
+	setEnv(t, "TOOL_CONFIG", "env.yaml")
+
+	result, err := (&App{Name: "tool"}).configTarget(&Invocation{})
+	assert.NoError(t, err)
+	assert.Equal(t, "/xdg/tool/config.yaml", result)
+
+	result, err = (&App{Name: "tool", ConfigFiles: []string{"a", "b"}}).configTarget(&Invocation{})
+	assert.NoError(t, err)
+	assert.Equal(t, "b", result)
+
+	result, err = (&App{Name: "tool", AllowConfigFlag: true, ConfigFile: "c"}).configTarget(&Invocation{})
+	assert.NoError(t, err)
+	assert.Equal(t, "env.yaml", result)
+
+	result, err = (&App{Name: "tool", AllowConfigFlag: true}).configTarget(&Invocation{config: "flag.yaml"})
+	assert.NoError(t, err)
+	assert.Equal(t, "flag.yaml", result)
+
+	_, err = (&App{Name: "tool", NoConfigSearch: true}).configTarget(&Invocation{})
+	assert.ErrorIs(t, err, ErrNoConfigFile)
+}
+
+func TestConfigScalar(t *testing.T) {
+	assert.Equal(t, true, configScalar(reflect.TypeOf(false), "true"))
+	assert.Equal(t, int64(16), configScalar(reflect.TypeOf(0), "0x10"))
+	assert.Equal(t, uint64(5), configScalar(reflect.TypeOf(uint(0)), "5"))
+	assert.Equal(t, 1.5, configScalar(reflect.TypeOf(0.0), "1.5"))
+	assert.Equal(t, int64(3), configScalar(reflect.TypeOf((*int)(nil)), "3"))
+	assert.Equal(t, "5s", configScalar(reflect.TypeOf(time.Second), "5s"))
+	assert.Equal(t, "text", configScalar(reflect.TypeOf(""), "text"))
+}
+
+func TestUnsetConfigKey(t *testing.T) {
+	cfg := map[string]interface{}{
+		"a": map[string]interface{}{
+			"b": map[string]interface{}{"c": 1},
+			"d": 2,
+		},
+		"e": 3,
+	}
+
+	assert.True(t, unsetConfigKey(cfg, []string{"a", "b", "c"}))
+	assert.False(t, unsetConfigKey(cfg, []string{"a", "b", "c"}))
+	assert.False(t, unsetConfigKey(cfg, []string{"e", "f"}))
+	assert.Equal(t, map[string]interface{}{
+		"a": map[string]interface{}{"d": 2},
+		"e": 3,
+	}, cfg)
+}
+
+func TestSetConfigKey(t *testing.T) {
+	cfg := map[string]interface{}{"a": "scalar"}
+
+	setConfigKey(cfg, []string{"a", "b"}, 1)
+
+	assert.Equal(t, map[string]interface{}{
+		"a": map[string]interface{}{"b": 1},
+	}, cfg)
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrConfigType indicates that a configuration value is of the wrong
+// type: a section where a value is expected, or vice versa, or a list
+// for a flag that takes a single value.
+var ErrConfigType = errors.New("wrong type")
+
+// ValidateConfig checks a configuration, such as one returned by
+// Config, against the flags bound to configuration keys: keys must be
+// bound to flags, or be sections containing such keys; values must
+// convert as if given on the command line; and the converted values
+// must pass the flags' validation, such as RangeTag.  Only the
+// commands reachable without computing dynamic subcommands are
+// known.  Returns a ValidationError describing every failure,
+// labeled with the dotted path of the key, or nil if the
+// configuration is valid.
+func (a *App) ValidateConfig(cfg map[string]interface{}) error {
+	entries := a.configEntries()
+	sections := map[string]bool{}
+	for key := range entries {
+		parts := strings.Split(key, ".")
+		for i := 1; i < len(parts); i++ {
+			sections[strings.Join(parts[:i], ".")] = true
+		}
+	}
+
+	errs := validateConfigSection(nil, cfg, nil, entries, sections)
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Failures: errs}
+}
+
+// validateConfigSection is a helper that checks a section of the
+// configuration, given the keys leading to it, appending failures to
+// the list of errors in order of key.
+func validateConfigSection(errs []error, cfg map[string]interface{}, path []string, entries map[string]configEntry, sections map[string]bool) []error {
+	names := make([]string, 0, len(cfg))
+	for name := range cfg {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := cfg[name]
+		keys := append(append([]string{}, path...), name)
+		key := strings.Join(keys, ".")
+		entry, isEntry := entries[key]
+		section, isSection := value.(map[string]interface{})
+
+		switch {
+		case isSection && sections[key]:
+			errs = validateConfigSection(errs, section, keys, entries, sections)
+		case isEntry:
+			errs = entry.validate(errs, key, value)
+		case sections[key] && value != nil:
+			errs = append(errs, fmt.Errorf("%s: %w: expected a section", key, ErrConfigType))
+		case !sections[key]:
+			errs = append(errs, fmt.Errorf("%s: %w", key, ErrUnknownConfigKey))
+		}
+	}
+
+	return errs
+}
+
+// validate checks the value of the key bound to the flag, appending
+// failures to the list of errors.
+func (e configEntry) validate(errs []error, key string, value interface{}) []error {
+	field := e.opts.Field(e.opt.Index)
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	} else if !isList(field.Type()) {
+		return append(errs, fmt.Errorf("%s: %w: expected a single value, not a list", key, ErrConfigType))
+	}
+
+	field.Set(reflect.Zero(field.Type()))
+	for _, v := range values {
+		switch v.(type) {
+		case map[string]interface{}:
+			return append(errs, fmt.Errorf("%s: %w: expected a value, not a section", key, ErrConfigType))
+		case []interface{}:
+			return append(errs, fmt.Errorf("%s: %w: expected a value, not a list", key, ErrConfigType))
+		}
+		text, ok := configText(v, e.opt.Layouts)
+		if !ok {
+			continue
+		}
+		if err := e.opts.store(e.opt, text); err != nil {
+			return append(errs, fmt.Errorf("%s: %w %s: %s", key, ErrInvalidValue, e.opt.Display(text), err))
+		}
+	}
+
+	return runChecks(errs, key, field, e.opt.Checks)
+}
+
+// sampleSection describes a section of a sample configuration file.
+type sampleSection struct {
+	entries  map[string]configEntry    // Keys bound to flags, by name
+	sections map[string]*sampleSection // Sections, by name
+}
+
+// WriteSampleConfig writes a sample configuration file, in YAML,
+// describing every key bound to a flag that is not hidden: its help
+// text, type, choices, and default.  The keys are commented out, so
+// that the sample may be installed as is, and the sections of the
+// file are left empty until a key within is uncommented.  Only the
+// commands reachable without computing dynamic subcommands are
+// described.
+func (a *App) WriteSampleConfig(w io.Writer) error {
+	root := &sampleSection{}
+	for key, entry := range a.configEntries() {
+		if entry.opt.Hidden {
+			continue
+		}
+		keys := strings.Split(key, ".")
+		section := root
+		for _, name := range keys[:len(keys)-1] {
+			if section.sections == nil {
+				section.sections = map[string]*sampleSection{}
+			}
+			if section.sections[name] == nil {
+				section.sections[name] = &sampleSection{}
+			}
+			section = section.sections[name]
+		}
+		if section.entries == nil {
+			section.entries = map[string]configEntry{}
+		}
+		section.entries[keys[len(keys)-1]] = entry
+	}
+
+	sw := &sampleWriter{w: w}
+	sw.printf("# Sample configuration file for %s.  Uncomment a key to change\n", a.name())
+	sw.printf("# its value.\n")
+	sw.section(root, "")
+
+	return sw.err
+}
+
+// sampleWriter writes a sample configuration file, retaining the
+// first error encountered.
+type sampleWriter struct {
+	w   io.Writer // The stream to write to
+	err error     // The first error
+}
+
+// printf writes formatted text.
+func (sw *sampleWriter) printf(format string, args ...interface{}) {
+	if sw.err == nil {
+		_, sw.err = fmt.Fprintf(sw.w, format, args...)
+	}
+}
+
+// section writes a section of the file, at the specified
+// indentation: its keys, followed by its sections, each in order of
+// name, separated by blank lines.
+func (sw *sampleWriter) section(s *sampleSection, indent string) {
+	sep := indent == ""
+	separate := func() {
+		if sep {
+			sw.printf("\n")
+		}
+		sep = true
+	}
+
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		entry := s.entries[name]
+		opt := entry.opt
+		separate()
+		for _, line := range strings.Split(opt.Help, "\n") {
+			if line != "" {
+				sw.printf("%s# %s\n", indent, line)
+			}
+		}
+		if opt.Deprecated != "" {
+			sw.printf("%s# Deprecated: %s\n", indent, opt.Deprecated)
+		}
+		typ := opt.Kind
+		if typ == "" {
+			typ = typeName(opt.Type)
+		}
+		sw.printf("%s# Type: %s\n", indent, typ)
+		if len(opt.Choices) > 0 {
+			sw.printf("%s# Choices: %s\n", indent, strings.Join(opt.Choices, ", "))
+		}
+		if text, ok := entry.opts.DefaultText(opt); ok {
+			sw.printf("%s# %s: %s\n", indent, name, text)
+		} else {
+			sw.printf("%s# %s:\n", indent, name)
+		}
+	}
+
+	names = names[:0]
+	for name := range s.sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		separate()
+		sw.printf("%s%s:\n", indent, name)
+		sw.section(s.sections[name], indent+"  ")
+	}
+}
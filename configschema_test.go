@@ -0,0 +1,175 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type schemaOptions struct {
+	Port  int      `opt:"port" range:"[1,65535]" help:"Port to listen on"`
+	Tags  []string `opt:"tag" help:"Tags to apply"`
+	Mode  string   `opt:"mode" choices:"fast,slow" deprecated:"use --speed"`
+	Token string   `opt:"token" hidden:"true"`
+	Host  string   `opt:"host" config:"server.host"`
+	Debug bool     `opt:"debug"`
+}
+
+func schemaApp() *App {
+	return (&App{
+		Name: "tool",
+		Root: &Command{
+			Defaults: &RootOptions{},
+			Subcommands: map[string]ICommand{
+				"serve": &Command{Defaults: &schemaOptions{Port: 80, Mode: "fast"}},
+			},
+		},
+	}).WithNoConfigSearch(true)
+}
+
+func TestAppValidateConfig(t *testing.T) {
+	obj := schemaApp()
+
+	err := obj.ValidateConfig(map[string]interface{}{
+		"verbose": true,
+		"serve": map[string]interface{}{
+			"port":  8080,
+			"tag":   []interface{}{"a", "b"},
+			"mode":  nil,
+			"token": "secret",
+		},
+		"server": map[string]interface{}{"host": "example.com"},
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestAppValidateConfigNil(t *testing.T) {
+	obj := schemaApp()
+
+	err := obj.ValidateConfig(nil)
+
+	assert.NoError(t, err)
+}
+
+func TestAppValidateConfigFailures(t *testing.T) {
+	obj := schemaApp()
+
+	err := obj.ValidateConfig(map[string]interface{}{
+		"verbose": map[string]interface{}{"a": 1},
+		"extra":   1,
+		"serve": map[string]interface{}{
+			"port":  70000,
+			"tag":   []interface{}{"a", []interface{}{"b"}},
+			"mode":  "medium",
+			"debug": []interface{}{true},
+			"other": "x",
+		},
+		"server": "example.com",
+	})
+
+	assert.ErrorIs(t, err, ErrValidation)
+	failures := err.(*ValidationError).Failures
+	assert.ErrorIs(t, failures[0], ErrUnknownConfigKey)
+	assert.ErrorIs(t, failures[1], ErrConfigType)
+	assert.ErrorIs(t, failures[2], ErrInvalidValue)
+	assert.ErrorIs(t, failures[4], ErrNotInRange)
+	assert.EqualError(t, err, "validation failed: "+
+		"extra: unknown configuration key; "+
+		"serve.debug: wrong type: expected a single value, not a list; "+
+		`serve.mode: invalid value "medium": must be one of fast, slow; `+
+		"serve.other: unknown configuration key; "+
+		"serve.port: must be in [1,65535]; "+
+		"serve.tag: wrong type: expected a value, not a list; "+
+		"server: wrong type: expected a section; "+
+		"verbose: wrong type: expected a value, not a section")
+}
+
+func TestAppWriteSampleConfig(t *testing.T) {
+	obj := schemaApp()
+	buf := &bytes.Buffer{}
+
+	err := obj.WriteSampleConfig(buf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `# Sample configuration file for tool.  Uncomment a key to change
+# its value.
+
+# Type: bool
+# verbose:
+
+serve:
+  # Type: bool
+  # debug:
+
+  # Deprecated: use --speed
+  # Type: string
+  # Choices: fast, slow
+  # mode: "fast"
+
+  # Port to listen on
+  # Type: int
+  # port: 80
+
+  # Tags to apply
+  # Type: []string
+  # tag:
+
+server:
+  # Type: string
+  # host:
+`, buf.String())
+}
+
+func TestConfigCommandValidate(t *testing.T) {
+	obj, _, path := configCmdApp(t, "tool.yaml", "serve:\n  port: 8080\n  extra: 1\nother: 2\n")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "validate"})
+
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.EqualError(t, err, "validation failed: "+
+		path+": other: unknown configuration key; "+
+		path+": serve.extra: unknown configuration key")
+}
+
+func TestConfigCommandValidateValid(t *testing.T) {
+	obj, _, _ := configCmdApp(t, "tool.yaml", "serve:\n  port: 8080\n")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "validate"})
+
+	assert.NoError(t, err)
+}
+
+func TestConfigCommandValidateBadFile(t *testing.T) {
+	obj, _, _ := configCmdApp(t, "tool.yaml", "- a\n")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "validate"})
+
+	assert.ErrorIs(t, err, ErrBadConfig)
+}
+
+func TestConfigCommandSample(t *testing.T) {
+	obj, stdout, _ := configCmdApp(t, "tool.yaml", "")
+
+	err := obj.Dispatch(context.Background(), []string{"config", "sample"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "serve:\n")
+	assert.Contains(t, stdout.String(), "\n  # port: 80\n")
+}
@@ -0,0 +1,493 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DetachFlag is the global flag that runs a daemon command in the
+// background, if the application allows it; see App.AllowDetach and
+// Daemonize.
+const DetachFlag = "--detach"
+
+// DefaultStopTimeout is how long the stop command waits for a daemon
+// to exit if the Daemon sets no timeout.
+const DefaultStopTimeout = 10 * time.Second
+
+// Exit codes of the status command, following the conventions of LSB
+// init scripts.  A running daemon exits with 0.
+const (
+	StatusStale      = 1 // The daemon is not running, but its pidfile remains
+	StatusNotRunning = 3 // The daemon is not running
+)
+
+// detachedEnv is the environment variable marking the process started
+// by DetachFlag, which must not detach again.
+const detachedEnv = "NELSON_DETACHED"
+
+// stopPoll is the interval at which the stop command checks whether
+// the daemon has exited.
+const stopPoll = 100 * time.Millisecond
+
+// Errors describing the state of daemons.
+var (
+	ErrDaemonRunning = errors.New("already running")
+	ErrNotDaemon     = errors.New("cannot be detached")
+	ErrStopTimeout   = errors.New("did not stop")
+	ErrBadPidFile    = errors.New("invalid pidfile")
+)
+
+// errLocked is returned by lockFile if another process holds the
+// lock.
+var errLocked = errors.New("file is locked")
+
+// Hooks for testing.
+var (
+	osExecutable = os.Executable
+	startProcess = func(cmd *exec.Cmd) error { return cmd.Start() }
+)
+
+// Daemon describes how a long-running command, such as a server,
+// runs as a daemon; see Daemonize.
+type Daemon struct {
+	PidFile     string        // Path of the pidfile; if empty, no pidfile is written
+	LogFile     string        // File to which a detached command's output is appended; if empty, it is discarded
+	StopTimeout time.Duration // How long stop waits for the daemon to exit; defaults to DefaultStopTimeout
+}
+
+// IDaemon is an optional interface for commands that run as daemons.
+// While such a command runs, its pidfile records its process ID, so
+// only one instance runs at a time; if the application allows the
+// DetachFlag, the command may also be run in the background.
+// Commands may implement it directly, or be wrapped with Daemonize.
+type IDaemon interface {
+	// GetDaemon retrieves the description of the daemon.
+	GetDaemon() *Daemon
+}
+
+// DaemonCommand wraps a command, making it a daemon.
+type DaemonCommand struct {
+	Wrapped ICommand // Wrapped command
+	Daemon  *Daemon  // Description of the daemon
+}
+
+// Daemonize wraps a command to run it as a daemon, as in:
+//
+//	d := &nelson.Daemon{PidFile: "/run/tool.pid", LogFile: "/var/log/tool.log"}
+//	subs["serve"] = nelson.Daemonize(serve, d)
+//	for name, cmd := range nelson.NewDaemonCommands(d) {
+//		subs[name] = cmd
+//	}
+func Daemonize(cmd ICommand, d *Daemon) *DaemonCommand {
+	return &DaemonCommand{
+		Wrapped: cmd,
+		Daemon:  d,
+	}
+}
+
+// GetSummary retrieves the command summary.
+func (c *DaemonCommand) GetSummary() string {
+	return c.Wrapped.GetSummary()
+}
+
+// GetDescription retrieves the command's full description.
+func (c *DaemonCommand) GetDescription() string {
+	return c.Wrapped.GetDescription()
+}
+
+// GetGroup retrieves the group name of the command.
+func (c *DaemonCommand) GetGroup() string {
+	return c.Wrapped.GetGroup()
+}
+
+// GetSubcommands retrieves subcommands for this command.
+func (c *DaemonCommand) GetSubcommands() map[string]ICommand {
+	return c.Wrapped.GetSubcommands()
+}
+
+// GetDefaults retrieves the defaults for arguments for this command.
+func (c *DaemonCommand) GetDefaults() interface{} {
+	return c.Wrapped.GetDefaults()
+}
+
+// Unwrap returns the wrapped command.
+func (c *DaemonCommand) Unwrap() ICommand {
+	return c.Wrapped
+}
+
+// GetDaemon retrieves the description of the daemon.
+func (c *DaemonCommand) GetDaemon() *Daemon {
+	return c.Daemon
+}
+
+// GetDaemon is a helper that retrieves the description of the daemon
+// run by a command.  It examines the command and any commands it
+// wraps, returning nil if the command is not a daemon.
+func GetDaemon(cmd ICommand) *Daemon {
+	for cmd != nil {
+		if tmp, ok := cmd.(IDaemon); ok {
+			return tmp.GetDaemon()
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return nil
+}
+
+// WithDetach sets whether the global DetachFlag is recognized.
+// Returns the App, to allow chaining.
+func (a *App) WithDetach(allow bool) *App {
+	a.AllowDetach = allow
+	return a
+}
+
+// extractDetach is a helper that removes the DetachFlag from the
+// arguments preceding any "--", returning whether it was present and
+// the remaining arguments.  In a process started by the DetachFlag,
+// the flag is removed but reported absent.
+func extractDetach(args []string) (bool, []string) {
+	found := false
+	result := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+		if arg == DetachFlag {
+			found = true
+			continue
+		}
+		result = append(result, arg)
+	}
+
+	return found && os.Getenv(detachedEnv) == "", result
+}
+
+// PidFile is a pidfile held by the running process; see
+// AcquirePidFile.
+type PidFile struct {
+	Path string // Path of the pidfile
+	Pid  int    // The process ID recorded in it
+
+	file *os.File // The open pidfile, locked until it is released
+}
+
+// AcquirePidFile creates a pidfile recording the ID of the running
+// process, holding an advisory lock on it until it is released, so
+// that processes starting at the same time cannot both acquire it.
+// If another process holds the lock, or the pidfile names a running
+// process, an error wrapping ErrDaemonRunning is returned; a stale
+// pidfile, left by a process that is no longer running, is replaced.
+func AcquirePidFile(path string) (*PidFile, error) {
+	pid := os.Getpid()
+	for {
+		f, err := openPidFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		// Lock the pidfile, retrying if it was removed by its
+		// previous holder in the meantime
+		if err = lockFile(f); err != nil {
+			f.Close()
+			if errors.Is(err, errLocked) {
+				return nil, runningError(path)
+			}
+			return nil, err
+		}
+		if !sameFile(f, path) {
+			f.Close()
+			continue
+		}
+
+		// Check for a running process that does not lock the
+		// pidfile, then record the process ID
+		if other, err := ReadPidFile(path); err == nil && processAlive(other) {
+			f.Close()
+			return nil, runningError(path)
+		}
+		if err = writePid(f, pid); err != nil {
+			os.Remove(path)
+			f.Close()
+			return nil, err
+		}
+
+		return &PidFile{Path: path, Pid: pid, file: f}, nil
+	}
+}
+
+// runningError constructs the error returned by AcquirePidFile when
+// the daemon is already running, including its process ID if the
+// pidfile records it.
+func runningError(path string) error {
+	if other, err := ReadPidFile(path); err == nil {
+		return fmt.Errorf("%w with pid %d (%s)", ErrDaemonRunning, other, path)
+	}
+
+	return fmt.Errorf("%w (%s)", ErrDaemonRunning, path)
+}
+
+// sameFile determines whether an open file is still the file at the
+// specified path.
+func sameFile(f *os.File, path string) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	pi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(fi, pi)
+}
+
+// writePid replaces the contents of an open pidfile with the process
+// ID.
+func writePid(f *os.File, pid int) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := f.WriteAt([]byte(fmt.Sprintf("%d\n", pid)), 0)
+	return err
+}
+
+// Release removes the pidfile, unless it has since been replaced by
+// another process, and releases its lock.
+func (p *PidFile) Release() error {
+	if p.file != nil {
+		defer p.file.Close()
+	}
+	if pid, err := ReadPidFile(p.Path); err != nil || pid != p.Pid {
+		return nil
+	}
+
+	return os.Remove(p.Path)
+}
+
+// ReadPidFile reads the process ID recorded in a pidfile.  If the
+// file does not exist, the error satisfies os.IsNotExist; if it does
+// not contain a process ID, the error wraps ErrBadPidFile.
+func ReadPidFile(path string) (int, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("%w: %s", ErrBadPidFile, path)
+	}
+
+	return pid, nil
+}
+
+// Status reports the process ID of the daemon, read from its
+// pidfile, and whether it is running.  If the pidfile does not exist,
+// the process ID is 0.
+func (d *Daemon) Status() (int, bool, error) {
+	pid, err := ReadPidFile(d.PidFile)
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+
+	return pid, processAlive(pid), nil
+}
+
+// Stop asks the daemon to stop--with SIGTERM, or by terminating it on
+// Windows--and waits for it to exit, for at most the StopTimeout.
+// Returns the process ID of the daemon, or 0 if it was not running.
+func (d *Daemon) Stop(ctx context.Context, clock Clock) (int, error) {
+	pid, running, err := d.Status()
+	if err != nil || !running {
+		return 0, err
+	}
+	if err := stopProcess(pid); err != nil {
+		return pid, err
+	}
+
+	timeout := d.StopTimeout
+	if timeout <= 0 {
+		timeout = DefaultStopTimeout
+	}
+	timer := clock.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := clock.NewTicker(stopPoll)
+	defer ticker.Stop()
+	for processAlive(pid) {
+		select {
+		case <-ctx.Done():
+			return pid, ctx.Err()
+		case <-timer.C():
+			return pid, fmt.Errorf("%w: pid %d is still running after %s", ErrStopTimeout, pid, timeout)
+		case <-ticker.C():
+		}
+	}
+
+	return pid, nil
+}
+
+// detach runs the daemon command in the background, starting the
+// executable again with the arguments, which must not include the
+// DetachFlag.  The new process runs in its own session, with its
+// output appended to the daemon's LogFile.
+func (a *App) detach(d *Daemon, args []string) error {
+	if d.PidFile != "" {
+		if pid, running, err := d.Status(); err != nil {
+			return err
+		} else if running {
+			return fmt.Errorf("%w with pid %d (%s)", ErrDaemonRunning, pid, d.PidFile)
+		}
+	}
+
+	exe, err := osExecutable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, args...) //nolint:gosec
+	cmd.Env = append(os.Environ(), detachedEnv+"=1")
+	cmd.SysProcAttr = detachAttr()
+	if d.LogFile != "" {
+		f, err := os.OpenFile(d.LogFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		cmd.Stdout, cmd.Stderr = f, f
+	}
+
+	if err := startProcess(cmd); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stderr(), "%s: started in the background with pid %d\n", a.name(), cmd.Process.Pid)
+
+	return cmd.Process.Release()
+}
+
+// runDaemon prepares to run the resolved command: if it is a daemon,
+// it is run in the background if detach is set, and otherwise its
+// pidfile is acquired, to be released by the returned function.  The
+// boolean result is true if the command was detached, in which case
+// there is nothing more to do.
+func (a *App) runDaemon(inv *Invocation, detach bool, raw []string) (func(), bool, error) {
+	d := GetDaemon(inv.Command)
+	if d == nil {
+		if detach {
+			return nil, false, usageError(fmt.Errorf("%w: command %q is not a daemon", ErrNotDaemon, strings.Join(inv.Path, " ")))
+		}
+		return func() {}, false, nil
+	}
+
+	if detach {
+		_, args := extractDetach(raw)
+		return nil, true, a.detach(d, args)
+	}
+	os.Unsetenv(detachedEnv)
+	if d.PidFile == "" {
+		return func() {}, false, nil
+	}
+
+	pf, err := AcquirePidFile(d.PidFile)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return func() { _ = pf.Release() }, false, nil
+}
+
+// Commands controlling a daemon.
+type (
+	daemonStop struct {
+		Command
+		daemon *Daemon
+	}
+	daemonStatus struct {
+		Command
+		daemon *Daemon
+	}
+)
+
+// NewDaemonCommands constructs the commands "stop" and "status",
+// which control the daemon described by d through its pidfile; add
+// them to the subcommands of the daemon command's parent.  The stop
+// command asks the daemon to stop and waits for it to exit; see
+// Daemon.Stop.  The status command reports whether the daemon is
+// running, exiting with StatusNotRunning or StatusStale if it is
+// not.
+func NewDaemonCommands(d *Daemon) map[string]ICommand {
+	return map[string]ICommand{
+		"stop": &daemonStop{
+			Command: Command{
+				Summary: "Stop the daemon",
+			},
+			daemon: d,
+		},
+		"status": &daemonStatus{
+			Command: Command{
+				Summary: "Report whether the daemon is running",
+			},
+			daemon: d,
+		},
+	}
+}
+
+// Run stops the daemon.
+func (c *daemonStop) Run(ctx context.Context, clock Clock, streams *IOStreams) error {
+	pid, err := c.daemon.Stop(ctx, clock)
+	if err != nil {
+		return err
+	} else if pid == 0 {
+		fmt.Fprintln(streams.Out, "not running")
+		return nil
+	}
+
+	fmt.Fprintf(streams.Out, "stopped (pid %d)\n", pid)
+	return nil
+}
+
+// Run reports whether the daemon is running.
+func (c *daemonStatus) Run(streams *IOStreams) error {
+	pid, running, err := c.daemon.Status()
+	switch {
+	case err != nil:
+		return err
+	case running:
+		fmt.Fprintf(streams.Out, "running (pid %d)\n", pid)
+		return nil
+	case pid != 0:
+		fmt.Fprintf(streams.Out, "not running (stale pidfile %s)\n", c.daemon.PidFile)
+		return &CommandError{Code: StatusStale}
+	}
+
+	fmt.Fprintln(streams.Out, "not running")
+	return &CommandError{Code: StatusNotRunning}
+}
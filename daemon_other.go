@@ -0,0 +1,58 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package nelson
+
+import (
+	"os"
+	"syscall"
+)
+
+// detachAttr returns the attributes of a detached process, which
+// runs in a new session, without a controlling terminal.
+func detachAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// processAlive determines whether a process is running.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// openPidFile opens a pidfile for locking and writing, creating it if
+// necessary.
+func openPidFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+}
+
+// lockFile places an exclusive advisory lock on an open file, which
+// is held until the file is closed.  If another process holds the
+// lock, errLocked is returned.
+func lockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return errLocked
+	}
+
+	return err
+}
+
+// stopProcess asks a process to stop.
+func stopProcess(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
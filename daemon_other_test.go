@@ -0,0 +1,61 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package nelson
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessAlive(t *testing.T) {
+	assert.True(t, processAlive(os.Getpid()))
+	assert.False(t, processAlive(deadPid))
+}
+
+func TestDaemonStop(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skip("cannot start process:", err)
+	}
+	// Reap the process as soon as it exits, so it does not linger
+	// as a zombie that would still appear to be alive
+	done := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(done)
+	}()
+	path := filepath.Join(t.TempDir(), "tool.pid")
+	if err := ioutil.WriteFile(path, []byte(fmt.Sprint(cmd.Process.Pid)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	obj := &Daemon{PidFile: path, StopTimeout: 5 * time.Second}
+
+	pid, err := obj.Stop(context.Background(), RealClock{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, cmd.Process.Pid, pid)
+	<-done
+}
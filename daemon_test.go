@@ -0,0 +1,615 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// deadPid is a process ID that is never in use.
+const deadPid = 0x7ffffffe
+
+// writePidFile is a helper that writes a pidfile in a temporary
+// directory, returning its path.
+func writePidFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "tool.pid")
+	if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// fakeStart is a helper that replaces the hooks used to start a
+// detached process, recording the command instead of starting it.
+func fakeStart(t *testing.T, err error) *[]*exec.Cmd {
+	t.Helper()
+
+	cmds := []*exec.Cmd{}
+	oldExecutable, oldStart := osExecutable, startProcess
+	osExecutable = func() (string, error) { return "/usr/bin/tool", nil }
+	startProcess = func(cmd *exec.Cmd) error {
+		cmds = append(cmds, cmd)
+		if err == nil {
+			cmd.Process = &os.Process{Pid: 4242}
+		}
+		return err
+	}
+	t.Cleanup(func() {
+		osExecutable, startProcess = oldExecutable, oldStart
+	})
+
+	return &cmds
+}
+
+func TestDaemonCommandImplementsICommand(t *testing.T) {
+	assert.Implements(t, (*ICommand)(nil), &DaemonCommand{})
+}
+
+func TestDaemonCommandImplementsIWrapped(t *testing.T) {
+	assert.Implements(t, (*IWrapped)(nil), &DaemonCommand{})
+}
+
+func TestDaemonCommandImplementsIDaemon(t *testing.T) {
+	assert.Implements(t, (*IDaemon)(nil), &DaemonCommand{})
+}
+
+func TestDaemonize(t *testing.T) {
+	cmd := &mockICommand{}
+	d := &Daemon{PidFile: "tool.pid"}
+
+	result := Daemonize(cmd, d)
+
+	assert.Same(t, cmd, result.Wrapped)
+	assert.Same(t, d, result.Daemon)
+}
+
+func TestDaemonCommandGetSummary(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetSummary").Return("some text")
+	obj := &DaemonCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetSummary()
+
+	assert.Equal(t, "some text", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestDaemonCommandGetDescription(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetDescription").Return("some text")
+	obj := &DaemonCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetDescription()
+
+	assert.Equal(t, "some text", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestDaemonCommandGetGroup(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetGroup").Return("some text")
+	obj := &DaemonCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetGroup()
+
+	assert.Equal(t, "some text", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestDaemonCommandGetSubcommands(t *testing.T) {
+	subs := map[string]ICommand{
+		"sub": &mockICommand{},
+	}
+	cmd := &mockICommand{}
+	cmd.On("GetSubcommands").Return(subs)
+	obj := &DaemonCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetSubcommands()
+
+	assert.Equal(t, subs, result)
+	cmd.AssertExpectations(t)
+}
+
+func TestDaemonCommandGetDefaults(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetDefaults").Return("defaults")
+	obj := &DaemonCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetDefaults()
+
+	assert.Equal(t, "defaults", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestDaemonCommandUnwrap(t *testing.T) {
+	cmd := &mockICommand{}
+	obj := &DaemonCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.Unwrap()
+
+	assert.Same(t, cmd, result)
+}
+
+func TestDaemonCommandGetDaemon(t *testing.T) {
+	d := &Daemon{}
+	obj := &DaemonCommand{Daemon: d}
+
+	result := obj.GetDaemon()
+
+	assert.Same(t, d, result)
+}
+
+func TestGetDaemon(t *testing.T) {
+	d := &Daemon{}
+
+	result := GetDaemon(Requires(Daemonize(&mockICommand{}, d)))
+
+	assert.Same(t, d, result)
+}
+
+func TestGetDaemonNone(t *testing.T) {
+	result := GetDaemon(Requires(&mockICommand{}))
+
+	assert.Nil(t, result)
+}
+
+func TestAppWithDetach(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithDetach(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.AllowDetach)
+}
+
+func TestExtractDetach(t *testing.T) {
+	setEnv(t, detachedEnv, "")
+
+	detach, args := extractDetach([]string{"serve", "--detach", "--", "--detach"})
+
+	assert.True(t, detach)
+	assert.Equal(t, []string{"serve", "--", "--detach"}, args)
+}
+
+func TestExtractDetachAbsent(t *testing.T) {
+	setEnv(t, detachedEnv, "")
+
+	detach, args := extractDetach([]string{"serve"})
+
+	assert.False(t, detach)
+	assert.Equal(t, []string{"serve"}, args)
+}
+
+func TestExtractDetachDetached(t *testing.T) {
+	setEnv(t, detachedEnv, "1")
+
+	detach, args := extractDetach([]string{"serve", "--detach"})
+
+	assert.False(t, detach)
+	assert.Equal(t, []string{"serve"}, args)
+}
+
+func TestAcquirePidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool.pid")
+
+	result, err := AcquirePidFile(path)
+
+	assert.NoError(t, err)
+	defer result.Release()
+	assert.Equal(t, path, result.Path)
+	assert.Equal(t, os.Getpid(), result.Pid)
+	data, _ := ioutil.ReadFile(path)
+	assert.Equal(t, fmt.Sprintf("%d\n", os.Getpid()), string(data))
+}
+
+func TestAcquirePidFileLocked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool.pid")
+	held, err := AcquirePidFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Release()
+	if _, err := held.file.WriteAt([]byte(fmt.Sprintf("%d\n", deadPid)), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = AcquirePidFile(path)
+
+	assert.ErrorIs(t, err, ErrDaemonRunning)
+	assert.EqualError(t, err, fmt.Sprintf("already running with pid %d (%s)", deadPid, path))
+}
+
+func TestAcquirePidFileRunning(t *testing.T) {
+	path := writePidFile(t, fmt.Sprintf("%d\n", os.Getpid()))
+
+	_, err := AcquirePidFile(path)
+
+	assert.ErrorIs(t, err, ErrDaemonRunning)
+	assert.EqualError(t, err, fmt.Sprintf("already running with pid %d (%s)", os.Getpid(), path))
+}
+
+func TestAcquirePidFileStale(t *testing.T) {
+	path := writePidFile(t, fmt.Sprintf("%d\n", deadPid))
+
+	result, err := AcquirePidFile(path)
+
+	assert.NoError(t, err)
+	defer result.Release()
+	assert.Equal(t, os.Getpid(), result.Pid)
+	pid, _ := ReadPidFile(path)
+	assert.Equal(t, os.Getpid(), pid)
+}
+
+func TestAcquirePidFileGarbage(t *testing.T) {
+	path := writePidFile(t, "garbage")
+
+	result, err := AcquirePidFile(path)
+
+	assert.NoError(t, err)
+	defer result.Release()
+	assert.Equal(t, os.Getpid(), result.Pid)
+	data, _ := ioutil.ReadFile(path)
+	assert.Equal(t, fmt.Sprintf("%d\n", os.Getpid()), string(data))
+}
+
+func TestAcquirePidFileMissingDir(t *testing.T) {
+	_, err := AcquirePidFile(filepath.Join(t.TempDir(), "missing", "tool.pid"))
+
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPidFileRelease(t *testing.T) {
+	path := writePidFile(t, "42\n")
+	obj := &PidFile{Path: path, Pid: 42}
+
+	err := obj.Release()
+
+	assert.NoError(t, err)
+	assert.NoFileExists(t, path)
+}
+
+func TestPidFileReleaseAcquired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tool.pid")
+	obj, err := AcquirePidFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = obj.Release()
+
+	assert.NoError(t, err)
+	assert.NoFileExists(t, path)
+	result, err := AcquirePidFile(path)
+	assert.NoError(t, err)
+	result.Release()
+}
+
+func TestPidFileReleaseReplaced(t *testing.T) {
+	path := writePidFile(t, "43\n")
+	obj := &PidFile{Path: path, Pid: 42}
+
+	err := obj.Release()
+
+	assert.NoError(t, err)
+	assert.FileExists(t, path)
+}
+
+func TestReadPidFile(t *testing.T) {
+	result, err := ReadPidFile(writePidFile(t, " 42\n"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 42, result)
+}
+
+func TestReadPidFileBad(t *testing.T) {
+	for _, content := range []string{"", "bogus", "-1", "0"} {
+		_, err := ReadPidFile(writePidFile(t, content))
+
+		assert.ErrorIs(t, err, ErrBadPidFile, content)
+	}
+}
+
+func TestReadPidFileMissing(t *testing.T) {
+	_, err := ReadPidFile(filepath.Join(t.TempDir(), "tool.pid"))
+
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDaemonStatusRunning(t *testing.T) {
+	obj := &Daemon{PidFile: writePidFile(t, fmt.Sprint(os.Getpid()))}
+
+	pid, running, err := obj.Status()
+
+	assert.NoError(t, err)
+	assert.Equal(t, os.Getpid(), pid)
+	assert.True(t, running)
+}
+
+func TestDaemonStatusStale(t *testing.T) {
+	obj := &Daemon{PidFile: writePidFile(t, fmt.Sprint(deadPid))}
+
+	pid, running, err := obj.Status()
+
+	assert.NoError(t, err)
+	assert.Equal(t, deadPid, pid)
+	assert.False(t, running)
+}
+
+func TestDaemonStatusMissing(t *testing.T) {
+	obj := &Daemon{PidFile: filepath.Join(t.TempDir(), "tool.pid")}
+
+	pid, running, err := obj.Status()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, pid)
+	assert.False(t, running)
+}
+
+func TestDaemonStatusBad(t *testing.T) {
+	obj := &Daemon{PidFile: writePidFile(t, "bogus")}
+
+	_, _, err := obj.Status()
+
+	assert.ErrorIs(t, err, ErrBadPidFile)
+}
+
+func TestDaemonStopNotRunning(t *testing.T) {
+	obj := &Daemon{PidFile: writePidFile(t, fmt.Sprint(deadPid))}
+
+	pid, err := obj.Stop(context.Background(), RealClock{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, pid)
+}
+
+func TestAppDetach(t *testing.T) {
+	cmds := fakeStart(t, nil)
+	log := filepath.Join(t.TempDir(), "tool.log")
+	stderr := &bytes.Buffer{}
+	obj := &App{Name: "tool", Stderr: stderr}
+	d := &Daemon{PidFile: filepath.Join(t.TempDir(), "tool.pid"), LogFile: log}
+
+	err := obj.detach(d, []string{"serve", "--port", "80"})
+
+	assert.NoError(t, err)
+	assert.Len(t, *cmds, 1)
+	cmd := (*cmds)[0]
+	assert.Equal(t, []string{"/usr/bin/tool", "serve", "--port", "80"}, cmd.Args)
+	assert.Contains(t, cmd.Env, detachedEnv+"=1")
+	assert.NotNil(t, cmd.SysProcAttr)
+	assert.NotNil(t, cmd.Stdout)
+	assert.Same(t, cmd.Stdout, cmd.Stderr)
+	assert.Nil(t, cmd.Stdin)
+	assert.FileExists(t, log)
+	assert.Equal(t, "tool: started in the background with pid 4242\n", stderr.String())
+}
+
+func TestAppDetachNoLog(t *testing.T) {
+	cmds := fakeStart(t, nil)
+	obj := &App{Name: "tool", Stderr: &bytes.Buffer{}}
+
+	err := obj.detach(&Daemon{}, []string{"serve"})
+
+	assert.NoError(t, err)
+	assert.Nil(t, (*cmds)[0].Stdout)
+}
+
+func TestAppDetachRunning(t *testing.T) {
+	cmds := fakeStart(t, nil)
+	path := writePidFile(t, fmt.Sprint(os.Getpid()))
+	obj := &App{Name: "tool"}
+
+	err := obj.detach(&Daemon{PidFile: path}, []string{"serve"})
+
+	assert.ErrorIs(t, err, ErrDaemonRunning)
+	assert.Len(t, *cmds, 0)
+}
+
+func TestAppDetachBadPidFile(t *testing.T) {
+	fakeStart(t, nil)
+	obj := &App{Name: "tool"}
+
+	err := obj.detach(&Daemon{PidFile: writePidFile(t, "bogus")}, []string{"serve"})
+
+	assert.ErrorIs(t, err, ErrBadPidFile)
+}
+
+func TestAppDetachBadLog(t *testing.T) {
+	fakeStart(t, nil)
+	obj := &App{Name: "tool"}
+
+	err := obj.detach(&Daemon{LogFile: filepath.Join(t.TempDir(), "missing", "tool.log")}, []string{"serve"})
+
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestAppDetachExecutableError(t *testing.T) {
+	fakeStart(t, nil)
+	osExecutable = func() (string, error) { return "", assert.AnError }
+	obj := &App{Name: "tool"}
+
+	err := obj.detach(&Daemon{}, []string{"serve"})
+
+	assert.Same(t, assert.AnError, err)
+}
+
+func TestAppDetachStartError(t *testing.T) {
+	fakeStart(t, assert.AnError)
+	obj := &App{Name: "tool"}
+
+	err := obj.detach(&Daemon{}, []string{"serve"})
+
+	assert.Same(t, assert.AnError, err)
+}
+
+// daemonApp is a helper that constructs an application with a daemon
+// command and the commands controlling it.
+func daemonApp(t *testing.T) (*App, *runCommand, *Daemon) {
+	d := &Daemon{PidFile: filepath.Join(t.TempDir(), "tool.pid")}
+	serve := newRunCommand("Serve requests", nil)
+	subs := NewDaemonCommands(d)
+	subs["serve"] = Daemonize(serve, d)
+	subs["other"] = newRunCommand("Do something else", nil)
+	return &App{
+		Name:        "tool",
+		Stdout:      &bytes.Buffer{},
+		Stderr:      &bytes.Buffer{},
+		AllowDetach: true,
+		Root:        &Command{Subcommands: subs},
+	}, serve, d
+}
+
+func TestAppDispatchDaemon(t *testing.T) {
+	setEnv(t, detachedEnv, "1")
+	obj, serve, d := daemonApp(t)
+	var pid int
+	serve.On("Run", Args{}, mock.Anything).Return(nil).Run(func(mock.Arguments) {
+		pid, _ = ReadPidFile(d.PidFile)
+	})
+
+	err := obj.Dispatch(context.Background(), []string{"serve", "--detach"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, os.Getpid(), pid)
+	assert.NoFileExists(t, d.PidFile)
+	assert.Equal(t, "", os.Getenv(detachedEnv))
+	serve.AssertExpectations(t)
+}
+
+func TestAppDispatchDaemonRunning(t *testing.T) {
+	obj, serve, d := daemonApp(t)
+	if err := ioutil.WriteFile(d.PidFile, []byte(fmt.Sprint(os.Getpid())), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"serve"})
+
+	assert.ErrorIs(t, err, ErrDaemonRunning)
+	serve.AssertExpectations(t)
+}
+
+func TestAppDispatchDaemonDetach(t *testing.T) {
+	setEnv(t, detachedEnv, "")
+	cmds := fakeStart(t, nil)
+	obj, serve, _ := daemonApp(t)
+
+	err := obj.Dispatch(context.Background(), []string{"serve", "--detach"})
+
+	assert.NoError(t, err)
+	assert.Len(t, *cmds, 1)
+	assert.Equal(t, []string{"/usr/bin/tool", "serve"}, (*cmds)[0].Args)
+	serve.AssertExpectations(t)
+}
+
+func TestAppDispatchDaemonDetachNotDaemon(t *testing.T) {
+	setEnv(t, detachedEnv, "")
+	obj, _, _ := daemonApp(t)
+
+	err := obj.Dispatch(context.Background(), []string{"other", "--detach"})
+
+	assert.ErrorIs(t, err, ErrNotDaemon)
+	assert.EqualError(t, err, `cannot be detached: command "tool other" is not a daemon`)
+}
+
+func TestAppDispatchDaemonStatusRunning(t *testing.T) {
+	obj, _, d := daemonApp(t)
+	if err := ioutil.WriteFile(d.PidFile, []byte(fmt.Sprint(os.Getpid())), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"status"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("running (pid %d)\n", os.Getpid()), obj.Stdout.(*bytes.Buffer).String())
+}
+
+func TestAppDispatchDaemonStatusStale(t *testing.T) {
+	obj, _, d := daemonApp(t)
+	if err := ioutil.WriteFile(d.PidFile, []byte(fmt.Sprint(deadPid)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"status"})
+
+	code, _ := ExitControl(err)
+	assert.Equal(t, StatusStale, code)
+	assert.Equal(t, fmt.Sprintf("not running (stale pidfile %s)\n", d.PidFile), obj.Stdout.(*bytes.Buffer).String())
+}
+
+func TestAppDispatchDaemonStatusNotRunning(t *testing.T) {
+	obj, _, _ := daemonApp(t)
+
+	err := obj.Dispatch(context.Background(), []string{"status"})
+
+	code, _ := ExitControl(err)
+	assert.Equal(t, StatusNotRunning, code)
+	assert.Equal(t, "not running\n", obj.Stdout.(*bytes.Buffer).String())
+}
+
+func TestAppDispatchDaemonStatusBad(t *testing.T) {
+	obj, _, d := daemonApp(t)
+	if err := ioutil.WriteFile(d.PidFile, []byte("bogus"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"status"})
+
+	assert.ErrorIs(t, err, ErrBadPidFile)
+}
+
+func TestAppDispatchDaemonStopNotRunning(t *testing.T) {
+	obj, _, _ := daemonApp(t)
+
+	err := obj.Dispatch(context.Background(), []string{"stop"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "not running\n", obj.Stdout.(*bytes.Buffer).String())
+}
+
+func TestAppDispatchDaemonStopBad(t *testing.T) {
+	obj, _, d := daemonApp(t)
+	if err := ioutil.WriteFile(d.PidFile, []byte("bogus"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"stop"})
+
+	assert.ErrorIs(t, err, ErrBadPidFile)
+}
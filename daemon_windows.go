@@ -0,0 +1,108 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package nelson
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Process creation flags and access rights, from the Windows API.
+const (
+	detachedProcess                = 0x00000008
+	createNewProcessGroup          = 0x00000200
+	processQueryLimitedInformation = 0x1000
+	stillActive                    = 259
+	lockfileFailImmediately        = 0x00000001
+	lockfileExclusiveLock          = 0x00000002
+	errorLockViolation             = 33
+)
+
+// lockFileEx is the LockFileEx function of the Windows API.
+var lockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+// detachAttr returns the attributes of a detached process, which
+// runs in a new process group, without a console.
+func detachAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: detachedProcess | createNewProcessGroup}
+}
+
+// processAlive determines whether a process is running.
+func processAlive(pid int) bool {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h) //nolint:errcheck
+
+	var code uint32
+	return syscall.GetExitCodeProcess(h, &code) == nil && code == stillActive
+}
+
+// openPidFile opens a pidfile for locking and writing, creating it if
+// necessary.  Unlike os.OpenFile, the file may be removed while it is
+// open, so that Release can remove it before giving up the lock.
+func openPidFile(path string) (*os.File, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := syscall.CreateFile(
+		name,
+		syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil,
+		syscall.OPEN_ALWAYS,
+		syscall.FILE_ATTRIBUTE_NORMAL,
+		0,
+	)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	return os.NewFile(uintptr(h), path), nil
+}
+
+// lockFile places an exclusive lock on an open file, which is held
+// until the file is closed.  If another process holds the lock,
+// errLocked is returned.  Locks on Windows are mandatory, so the
+// lock covers a byte far beyond the end of the file, leaving the
+// contents readable.
+func lockFile(f *os.File) error {
+	ol := &syscall.Overlapped{Offset: 0xffffffff, OffsetHigh: 0x7fffffff}
+	ok, _, err := lockFileEx.Call(uintptr(f.Fd()), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(ol)))
+	if ok != 0 {
+		return nil
+	} else if err == syscall.Errno(errorLockViolation) {
+		return errLocked
+	}
+
+	return err
+}
+
+// stopProcess asks a process to stop.  Windows has no equivalent of
+// SIGTERM for arbitrary processes, so the process is terminated.
+func stopProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	return p.Kill()
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strings"
+
+	"github.com/klmitch/nelson/internal/depinject"
+)
+
+// Hidden global flags for diagnosing applications.  They are
+// recognized unless the application disables them, and are not
+// listed in help.  CPUProfileFlag, MemProfileFlag, and TraceFlag
+// take the file to which the CPU profile, heap profile, or execution
+// trace is written, as the following argument or assigned, as in
+// "--cpuprofile=cpu.out"; the profiles may be examined with "go tool
+// pprof" and the trace with "go tool trace".  DebugInjectorFlag
+// requests that, if the command fails, the values available from the
+// injector and the dependencies of the command be written to
+// standard error.
+const (
+	CPUProfileFlag    = "--cpuprofile"
+	MemProfileFlag    = "--memprofile"
+	TraceFlag         = "--trace"
+	DebugInjectorFlag = "--debug-injector"
+)
+
+// debugFlags contains the settings of the diagnostic flags.
+type debugFlags struct {
+	cpuProfile string // File receiving the CPU profile, if any
+	memProfile string // File receiving the heap profile, if any
+	trace      string // File receiving the execution trace, if any
+	injector   bool   // True if the injector is dumped on failure
+}
+
+// WithNoDebugFlags disables, or re-enables, the hidden diagnostic
+// flags, such as CPUProfileFlag and DebugInjectorFlag, so that
+// commands may use those flags themselves.  Returns the App, to allow
+// chaining.
+func (a *App) WithNoDebugFlags(disable bool) *App {
+	a.NoDebugFlags = disable
+	return a
+}
+
+// extractDebug is a helper that removes the diagnostic flags, and the
+// files they name, from the arguments preceding any "--", returning
+// their settings and the remaining arguments.
+func extractDebug(args []string) (*debugFlags, []string, error) {
+	debug := &debugFlags{}
+	files := map[string]*string{
+		CPUProfileFlag: &debug.cpuProfile,
+		MemProfileFlag: &debug.memProfile,
+		TraceFlag:      &debug.trace,
+	}
+	result := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+		if arg == DebugInjectorFlag {
+			debug.injector = true
+			continue
+		}
+
+		// Check for the flags naming files
+		name, value := arg, ""
+		assigned := false
+		if j := strings.Index(arg, "="); j >= 0 {
+			name, value, assigned = arg[:j], arg[j+1:], true
+		}
+		file, ok := files[name]
+		switch {
+		case !ok:
+			result = append(result, arg)
+			continue
+		case assigned:
+		case i+1 >= len(args):
+			return nil, args, fmt.Errorf("%w %s", ErrMissingValue, arg)
+		default:
+			i++
+			value = args[i]
+		}
+		if value == "" {
+			return nil, args, fmt.Errorf("%w %s", ErrMissingValue, name)
+		}
+		*file = value
+	}
+
+	return debug, result, nil
+}
+
+// start begins the CPU profile and the execution trace, if
+// requested, returning a function that stops them and writes the
+// heap profile, if requested.  The files are created before anything
+// is started, so that a bad path is reported at once.
+func (d *debugFlags) start() (func() error, error) {
+	var cpuFile, memFile, traceFile *os.File
+	closeAll := func() error {
+		var err error
+		for _, f := range []*os.File{cpuFile, memFile, traceFile} {
+			if f == nil {
+				continue
+			}
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}
+
+	// Create the files
+	var err error
+	for _, file := range []struct {
+		path string
+		f    **os.File
+	}{
+		{d.cpuProfile, &cpuFile},
+		{d.memProfile, &memFile},
+		{d.trace, &traceFile},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if *file.f, err = os.Create(file.path); err != nil {
+			_ = closeAll()
+			return nil, err
+		}
+	}
+
+	// Start the profile and the trace
+	if cpuFile != nil {
+		if err := pprof.StartCPUProfile(cpuFile); err != nil {
+			_ = closeAll()
+			return nil, err
+		}
+	}
+	if traceFile != nil {
+		if err := trace.Start(traceFile); err != nil {
+			if cpuFile != nil {
+				pprof.StopCPUProfile()
+			}
+			_ = closeAll()
+			return nil, err
+		}
+	}
+
+	return func() error {
+		if traceFile != nil {
+			trace.Stop()
+		}
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+		}
+		var err error
+		if memFile != nil {
+			runtime.GC()
+			err = pprof.Lookup("heap").WriteTo(memFile, 0)
+		}
+		if closeErr := closeAll(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+// dumpInjector writes the values available from the injector and,
+// if the command was resolved, the dependencies of the method that
+// runs it, noting those that are missing, for diagnosing a failed
+// command; see DebugInjectorFlag.
+func dumpInjector(w io.Writer, inj *Injector, inv *Invocation) {
+	fmt.Fprintln(w, "injector values:")
+	for _, typ := range inj.types() {
+		fmt.Fprintf(w, "  %s\n", typ)
+	}
+	if inv == nil {
+		return
+	}
+
+	// Describe the dependencies of the command
+	target, method := runTarget(inv.Command)
+	if target == nil {
+		fmt.Fprintf(w, "command %q has no %s method\n", strings.Join(inv.Path, " "), RunMethod)
+		return
+	}
+	meth, err := depinject.NewResult(target, method)
+	if err != nil {
+		fmt.Fprintf(w, "command %q: %s\n", strings.Join(inv.Path, " "), err)
+		return
+	}
+	fmt.Fprintf(w, "dependencies of %q (%T.%s):\n", strings.Join(inv.Path, " "), target, method)
+	for _, typ := range meth.Args {
+		note := ""
+		if val, ok := inj.deps[typ]; !ok || !val.IsValid() {
+			note = " (missing)"
+		}
+		fmt.Fprintf(w, "  %s%s\n", typ, note)
+	}
+}
+
+// runTarget is a helper that locates the command that runs the
+// specified command, examining the commands it wraps, returning it
+// and the name of its run method; see runMethod.  It returns nil if
+// no command has a run method.
+func runTarget(cmd ICommand) (ICommand, string) {
+	for cmd != nil {
+		if method := runMethod(cmd); method != "" {
+			return cmd, method
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return nil, ""
+}
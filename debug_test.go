@@ -0,0 +1,289 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type debugCommand struct {
+	Command
+	err error
+}
+
+func (c *debugCommand) Run(args Args, missing *debugFlags) error {
+	return c.err
+}
+
+func TestAppWithNoDebugFlags(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithNoDebugFlags(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.NoDebugFlags)
+}
+
+func TestExtractDebug(t *testing.T) {
+	debug, args, err := extractDebug([]string{
+		"sub", "--cpuprofile", "cpu.out", "--memprofile=mem.out",
+		"arg", "--trace", "trace.out", "--debug-injector", "--", "--trace",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &debugFlags{
+		cpuProfile: "cpu.out",
+		memProfile: "mem.out",
+		trace:      "trace.out",
+		injector:   true,
+	}, debug)
+	assert.Equal(t, []string{"sub", "arg", "--", "--trace"}, args)
+}
+
+func TestExtractDebugAbsent(t *testing.T) {
+	debug, args, err := extractDebug([]string{"sub", "--tracer=x", "arg"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &debugFlags{}, debug)
+	assert.Equal(t, []string{"sub", "--tracer=x", "arg"}, args)
+}
+
+func TestExtractDebugMissing(t *testing.T) {
+	_, _, err := extractDebug([]string{"sub", "--cpuprofile"})
+
+	assert.ErrorIs(t, err, ErrMissingValue)
+	assert.EqualError(t, err, "missing value for flag --cpuprofile")
+}
+
+func TestExtractDebugEmpty(t *testing.T) {
+	_, _, err := extractDebug([]string{"sub", "--trace="})
+
+	assert.ErrorIs(t, err, ErrMissingValue)
+	assert.EqualError(t, err, "missing value for flag --trace")
+}
+
+func TestDebugFlagsStart(t *testing.T) {
+	dir := t.TempDir()
+	obj := &debugFlags{
+		cpuProfile: filepath.Join(dir, "cpu.out"),
+		memProfile: filepath.Join(dir, "mem.out"),
+		trace:      filepath.Join(dir, "trace.out"),
+	}
+
+	stop, err := obj.start()
+	assert.NoError(t, err)
+	err = stop()
+
+	assert.NoError(t, err)
+	for _, path := range []string{obj.cpuProfile, obj.memProfile, obj.trace} {
+		info, err := os.Stat(path)
+		if assert.NoError(t, err) {
+			assert.NotZero(t, info.Size(), path)
+		}
+	}
+}
+
+func TestDebugFlagsStartNone(t *testing.T) {
+	obj := &debugFlags{}
+
+	stop, err := obj.start()
+	assert.NoError(t, err)
+	err = stop()
+
+	assert.NoError(t, err)
+}
+
+func TestDebugFlagsStartBadPath(t *testing.T) {
+	dir := t.TempDir()
+	obj := &debugFlags{
+		cpuProfile: filepath.Join(dir, "cpu.out"),
+		trace:      filepath.Join(dir, "missing", "trace.out"),
+	}
+
+	_, err := obj.start()
+
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDebugFlagsStartBusy(t *testing.T) {
+	dir := t.TempDir()
+	first := &debugFlags{cpuProfile: filepath.Join(dir, "first.out")}
+	stop, err := first.start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	obj := &debugFlags{cpuProfile: filepath.Join(dir, "cpu.out")}
+
+	_, err = obj.start()
+
+	assert.Error(t, err)
+}
+
+func TestDumpInjector(t *testing.T) {
+	inj := NewInjector()
+	inj.Provide(Args{"a1"}, 5)
+	inv := &Invocation{
+		Path:    []string{"tool", "sub"},
+		Command: Requires(&debugCommand{}),
+	}
+	buf := &bytes.Buffer{}
+
+	dumpInjector(buf, inj, inv)
+
+	assert.Equal(t, `injector values:
+  int
+  nelson.Args
+dependencies of "tool sub" (*nelson.debugCommand.Run):
+  nelson.Args
+  *nelson.debugFlags (missing)
+`, buf.String())
+}
+
+func TestDumpInjectorUnresolved(t *testing.T) {
+	inj := NewInjector()
+	inj.Provide(5)
+	buf := &bytes.Buffer{}
+
+	dumpInjector(buf, inj, nil)
+
+	assert.Equal(t, "injector values:\n  int\n", buf.String())
+}
+
+func TestDumpInjectorNoRun(t *testing.T) {
+	inv := &Invocation{
+		Path:    []string{"tool"},
+		Command: &Command{},
+	}
+	buf := &bytes.Buffer{}
+
+	dumpInjector(buf, NewInjector(), inv)
+
+	assert.Equal(t, "injector values:\ncommand \"tool\" has no Run method\n", buf.String())
+}
+
+func TestDumpInjectorBadRun(t *testing.T) {
+	inv := &Invocation{
+		Path:    []string{"tool"},
+		Command: &badRunCommand{},
+	}
+	buf := &bytes.Buffer{}
+
+	dumpInjector(buf, NewInjector(), inv)
+
+	assert.Equal(t, "injector values:\ncommand \"tool\": \"Run\": method is not a function\n", buf.String())
+}
+
+type badRunCommand struct {
+	Command
+}
+
+func (c *badRunCommand) Run(args ...string) {}
+
+func TestAppDispatchDebugInjector(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Name:   "tool",
+		Stderr: stderr,
+		Root:   &debugCommand{err: assert.AnError},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--debug-injector", "a1"})
+
+	assert.Error(t, err)
+	assert.Contains(t, stderr.String(), "injector values:\n")
+	assert.Contains(t, stderr.String(), "  *nelson.App\n")
+	assert.Contains(t, stderr.String(), "dependencies of \"tool\" (*nelson.debugCommand.Run):\n  nelson.Args\n  *nelson.debugFlags (missing)\n")
+}
+
+func TestAppDispatchDebugInjectorSuccess(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	root := newRunCommand("Root command", nil)
+	root.On("Run", Args{"a1"}, mock.Anything).Return(nil)
+	obj := &App{
+		Name:   "tool",
+		Stderr: stderr,
+		Root:   root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"a1", "--debug-injector"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", stderr.String())
+	root.AssertExpectations(t)
+}
+
+func TestAppDispatchDebugProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mem.out")
+	root := newRunCommand("Root command", nil)
+	root.On("Run", Args{"a1"}, mock.Anything).Return(nil)
+	obj := &App{
+		Name: "tool",
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--memprofile", path, "a1"})
+
+	assert.NoError(t, err)
+	assert.FileExists(t, path)
+	root.AssertExpectations(t)
+}
+
+func TestAppDispatchDebugMissing(t *testing.T) {
+	obj := &App{
+		Name: "tool",
+		Root: newRunCommand("Root command", nil),
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"a1", "--trace"})
+
+	assert.ErrorIs(t, err, ErrMissingValue)
+	code, usage := ExitControl(err)
+	assert.Equal(t, UsageExitCode, code)
+	assert.True(t, usage)
+}
+
+func TestAppDispatchDebugBadPath(t *testing.T) {
+	obj := &App{
+		Name: "tool",
+		Root: newRunCommand("Root command", nil),
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--trace", filepath.Join(t.TempDir(), "missing", "trace.out")})
+
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestAppDispatchNoDebugFlags(t *testing.T) {
+	root := newRunCommand("Root command", nil)
+	root.On("Run", Args{"--debug-injector"}, mock.Anything).Return(nil)
+	obj := (&App{
+		Name:   "tool",
+		Stderr: &bytes.Buffer{},
+		Root:   root,
+	}).WithNoDebugFlags(true)
+
+	err := obj.Dispatch(context.Background(), []string{"--debug-injector"})
+
+	assert.NoError(t, err)
+	root.AssertExpectations(t)
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import "reflect"
+
+// InheritTag is the struct tag used to mark a field of a defaults
+// struct as inheriting its value from the parent command's defaults.
+// The tag value names the field in the parent's defaults to inherit
+// from; if empty, the field with the same name is used.
+const InheritTag = "inherit"
+
+// inheritDefaults copies values from the parent command's defaults
+// into the child command's defaults.  Both values must be pointers
+// to structs; if either is not, no action is taken.  Two mechanisms
+// are supported: an anonymous (embedded) field whose type is the
+// same as the parent's defaults type--or a pointer to it--receives a
+// copy of the parent's defaults; and a field tagged with InheritTag
+// receives the value of the named field from the parent's defaults,
+// if the types are compatible.  Note that embedded types must be
+// exported for their fields to be set.  Because commands are
+// processed from the root down, values propagate through multiple
+// levels.
+func inheritDefaults(child, parent reflect.Value) {
+	// Make sure we have pointers to structs
+	if !isStructPtr(child) || !isStructPtr(parent) {
+		return
+	}
+	child = child.Elem()
+	parent = parent.Elem()
+
+	childType := child.Type()
+	for i := 0; i < childType.NumField(); i++ {
+		field := childType.Field(i)
+		fieldVal := child.Field(i)
+		if !fieldVal.CanSet() {
+			continue
+		}
+
+		// Handle embedded parent defaults
+		if field.Anonymous {
+			switch field.Type {
+			case parent.Type():
+				fieldVal.Set(parent)
+				continue
+
+			case reflect.PtrTo(parent.Type()):
+				tmp := reflect.New(parent.Type())
+				tmp.Elem().Set(parent)
+				fieldVal.Set(tmp)
+				continue
+			}
+		}
+
+		// Handle explicitly inherited fields
+		name, ok := field.Tag.Lookup(InheritTag)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		src := parent.FieldByName(name)
+		if src.IsValid() && src.Type().AssignableTo(field.Type) {
+			fieldVal.Set(src)
+		}
+	}
+}
+
+// isStructPtr is a helper that tests whether a value is a non-nil
+// pointer to a struct.
+func isStructPtr(v reflect.Value) bool {
+	return v.IsValid() && v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Struct
+}
+
+// deepCopy copies the value src into dst, which must be settable,
+// along with the values src refers to through pointers, slices,
+// maps, arrays, and exported struct fields, so that changes made
+// through dst do not affect src.  Shared and cyclic pointers are
+// preserved, using seen to map the pointers of src to their copies.
+// Interfaces, functions, and channels are copied shallowly, as are
+// unexported struct fields and pointers to structs with unexported
+// fields, such as *os.File, since these cannot be faithfully copied.
+func deepCopy(dst, src reflect.Value, seen map[uintptr]reflect.Value) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() || isOpaque(src.Type().Elem()) {
+			dst.Set(src)
+			return
+		}
+		if ptr, ok := seen[src.Pointer()]; ok && ptr.Type() == src.Type() {
+			dst.Set(ptr)
+			return
+		}
+		ptr := reflect.New(src.Type().Elem())
+		seen[src.Pointer()] = ptr
+		deepCopy(ptr.Elem(), src.Elem(), seen)
+		dst.Set(ptr)
+
+	case reflect.Slice:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+		tmp := reflect.MakeSlice(src.Type(), src.Len(), src.Cap())
+		for i := 0; i < src.Len(); i++ {
+			deepCopy(tmp.Index(i), src.Index(i), seen)
+		}
+		dst.Set(tmp)
+
+	case reflect.Map:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+		tmp := reflect.MakeMapWithSize(src.Type(), src.Len())
+		for iter := src.MapRange(); iter.Next(); {
+			elem := reflect.New(src.Type().Elem()).Elem()
+			deepCopy(elem, iter.Value(), seen)
+			tmp.SetMapIndex(iter.Key(), elem)
+		}
+		dst.Set(tmp)
+
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			deepCopy(dst.Index(i), src.Index(i), seen)
+		}
+
+	case reflect.Struct:
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if src.Type().Field(i).PkgPath == "" {
+				deepCopy(dst.Field(i), src.Field(i), seen)
+			}
+		}
+
+	default:
+		dst.Set(src)
+	}
+}
+
+// isOpaque is a helper that tests whether a type is a struct with
+// unexported fields.
+func isOpaque(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).PkgPath != "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type InheritParent struct {
+	Endpoint string
+	Timeout  int
+	Region   string
+}
+
+type childEmbedded struct {
+	InheritParent
+	Name string
+}
+
+type childEmbeddedPtr struct {
+	*InheritParent
+	Name string
+}
+
+type childTagged struct {
+	Endpoint string `inherit:""`
+	Zone     string `inherit:"Region"`
+	Timeout  string `inherit:""`
+	Missing  string `inherit:"NoSuchField"`
+	Name     string
+	private  string `inherit:"Region"` //nolint:structcheck,unused
+}
+
+func TestInheritDefaultsEmbedded(t *testing.T) {
+	parent := &InheritParent{
+		Endpoint: "http://example.com",
+		Timeout:  5,
+	}
+	child := &childEmbedded{
+		Name: "child",
+	}
+
+	inheritDefaults(reflect.ValueOf(child), reflect.ValueOf(parent))
+
+	assert.Equal(t, &childEmbedded{
+		InheritParent: InheritParent{
+			Endpoint: "http://example.com",
+			Timeout:  5,
+		},
+		Name: "child",
+	}, child)
+}
+
+func TestInheritDefaultsEmbeddedPtr(t *testing.T) {
+	parent := &InheritParent{
+		Endpoint: "http://example.com",
+		Timeout:  5,
+	}
+	child := &childEmbeddedPtr{
+		Name: "child",
+	}
+
+	inheritDefaults(reflect.ValueOf(child), reflect.ValueOf(parent))
+
+	assert.Equal(t, &childEmbeddedPtr{
+		InheritParent: &InheritParent{
+			Endpoint: "http://example.com",
+			Timeout:  5,
+		},
+		Name: "child",
+	}, child)
+	assert.NotSame(t, parent, child.InheritParent)
+}
+
+func TestInheritDefaultsTagged(t *testing.T) {
+	parent := &InheritParent{
+		Endpoint: "http://example.com",
+		Timeout:  5,
+		Region:   "east",
+	}
+	child := &childTagged{
+		Timeout: "timeout",
+		Name:    "child",
+	}
+
+	inheritDefaults(reflect.ValueOf(child), reflect.ValueOf(parent))
+
+	assert.Equal(t, &childTagged{
+		Endpoint: "http://example.com",
+		Zone:     "east",
+		Timeout:  "timeout",
+		Name:     "child",
+	}, child)
+}
+
+func TestInheritDefaultsNotPointers(t *testing.T) {
+	parent := InheritParent{
+		Endpoint: "http://example.com",
+	}
+	child := &childEmbedded{}
+
+	inheritDefaults(reflect.ValueOf(child), reflect.ValueOf(parent))
+
+	assert.Equal(t, &childEmbedded{}, child)
+}
+
+func TestIsStructPtrBase(t *testing.T) {
+	result := isStructPtr(reflect.ValueOf(&InheritParent{}))
+
+	assert.True(t, result)
+}
+
+func TestIsStructPtrInvalid(t *testing.T) {
+	result := isStructPtr(reflect.Value{})
+
+	assert.False(t, result)
+}
+
+func TestIsStructPtrNotPtr(t *testing.T) {
+	result := isStructPtr(reflect.ValueOf(InheritParent{}))
+
+	assert.False(t, result)
+}
+
+func TestIsStructPtrNil(t *testing.T) {
+	result := isStructPtr(reflect.ValueOf((*InheritParent)(nil)))
+
+	assert.False(t, result)
+}
+
+func TestIsStructPtrNotStruct(t *testing.T) {
+	i := 5
+
+	result := isStructPtr(reflect.ValueOf(&i))
+
+	assert.False(t, result)
+}
+
+type copyNode struct {
+	Name string
+	Next *copyNode
+}
+
+type copyInner struct {
+	Values []int
+}
+
+type copyOptions struct {
+	Tags     []string
+	Labels   map[string][]string
+	Inner    *copyInner
+	Array    [2]*copyInner
+	Embedded copyInner
+	Node     *copyNode
+	Shared1  *copyInner
+	Shared2  *copyInner
+	File     *os.File
+	Time     time.Time
+	Any      interface{}
+	Nil      []string
+	hidden   []string
+}
+
+func TestDeepCopy(t *testing.T) {
+	shared := &copyInner{Values: []int{1}}
+	node := &copyNode{Name: "node"}
+	node.Next = node
+	src := copyOptions{
+		Tags:     []string{"a", "b"},
+		Labels:   map[string][]string{"k": {"v"}},
+		Inner:    &copyInner{Values: []int{1, 2}},
+		Array:    [2]*copyInner{{Values: []int{3}}},
+		Embedded: copyInner{Values: []int{4}},
+		Node:     node,
+		Shared1:  shared,
+		Shared2:  shared,
+		File:     os.Stdout,
+		Time:     time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+		Any:      shared,
+		hidden:   []string{"x"},
+	}
+	dst := copyOptions{}
+
+	deepCopy(reflect.ValueOf(&dst).Elem(), reflect.ValueOf(src), map[uintptr]reflect.Value{})
+
+	assert.Equal(t, src, dst)
+	dst.Tags[0] = "changed"
+	dst.Labels["k"][0] = "changed"
+	dst.Inner.Values[0] = 0
+	dst.Array[0].Values[0] = 0
+	dst.Embedded.Values[0] = 0
+	dst.Node.Name = "changed"
+	dst.Shared1.Values[0] = 0
+	assert.Equal(t, []string{"a", "b"}, src.Tags)
+	assert.Equal(t, map[string][]string{"k": {"v"}}, src.Labels)
+	assert.Equal(t, []int{1, 2}, src.Inner.Values)
+	assert.Equal(t, []int{3}, src.Array[0].Values)
+	assert.Equal(t, []int{4}, src.Embedded.Values)
+	assert.Equal(t, "node", src.Node.Name)
+	assert.Same(t, dst.Node, dst.Node.Next)
+	assert.Same(t, dst.Shared1, dst.Shared2)
+	assert.Equal(t, []int{1}, shared.Values)
+	assert.Same(t, os.Stdout, dst.File)
+	assert.Same(t, shared, dst.Any)
+	assert.Nil(t, dst.Nil)
+	assert.Equal(t, []string{"x"}, dst.hidden)
+}
+
+func TestIsOpaqueBase(t *testing.T) {
+	assert.True(t, isOpaque(reflect.TypeOf(os.File{})))
+	assert.False(t, isOpaque(reflect.TypeOf(copyInner{})))
+	assert.False(t, isOpaque(reflect.TypeOf("")))
+}
@@ -0,0 +1,271 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// YesFlag and ForceFlag are the global flags that confirm the
+// operations of destructive commands in advance, if the application
+// allows them; see App.AllowConfirmFlags and Destructive.
+const (
+	YesFlag   = "--yes"
+	ForceFlag = "--force"
+)
+
+// DefaultConfirmPrompt is the template used to render the question
+// asked before running a destructive command, if the command does
+// not supply its own.  The template is executed with a ConfirmData.
+const DefaultConfirmPrompt = `Really run "{{.Name}}"{{with .Target}} on {{.}}{{end}}?`
+
+// ErrNotConfirmed indicates that a destructive command was not run
+// because its operation could not be confirmed: standard input is
+// not a terminal, and neither YesFlag nor ForceFlag was given.
+var ErrNotConfirmed = errors.New("confirmation required")
+
+// ErrDeclined indicates that the user declined to confirm the
+// operation of a destructive command.
+var ErrDeclined = errors.New("operation declined")
+
+// AssumeYes indicates that the operations of destructive commands
+// have been confirmed in advance.  It is available from the
+// injector, and is set from the global YesFlag or ForceFlag if the
+// application allows them; commands that ask questions of their own
+// may consult it.
+type AssumeYes bool
+
+// Confirmer is a function that asks the user a yes-or-no question,
+// returning true if the user answers yes.  The prompt is the
+// question, as rendered from a command's confirmation prompt.
+type Confirmer func(prompt string) (bool, error)
+
+// ConfirmData is the data model passed to the templates of
+// confirmation prompts.
+type ConfirmData struct {
+	App     string      // Name of the application
+	Name    string      // The command path, as in "tool bucket delete"
+	Args    []string    // The positional arguments of the command
+	Target  string      // The positional arguments, separated by spaces
+	Options interface{} // Pointer to the populated defaults of the command, if any
+}
+
+// IDestructive is an optional interface for commands whose operation
+// is destructive.  The dispatcher asks the user to confirm before
+// running such a command, unless the operation was confirmed with
+// YesFlag or ForceFlag or this is a dry run.  Commands may implement
+// it directly, or be wrapped with Destructive.
+type IDestructive interface {
+	// GetConfirmPrompt retrieves the template of the question
+	// asked to confirm the operation; if empty, the
+	// DefaultConfirmPrompt is used.  The template is executed
+	// with a ConfirmData.
+	GetConfirmPrompt() string
+}
+
+// DestructiveCommand wraps a command, marking it as destructive.
+type DestructiveCommand struct {
+	Wrapped ICommand // Wrapped command
+	Prompt  string   // Template of the confirmation question; see IDestructive
+}
+
+// Destructive wraps a command to mark it as destructive.  The prompt
+// is the template of the question asked to confirm the operation,
+// and may name the resource affected, as in:
+//
+//	nelson.Destructive(cmd, `Delete the bucket "{{index .Args 0}}"?`)
+func Destructive(cmd ICommand, prompt string) *DestructiveCommand {
+	return &DestructiveCommand{
+		Wrapped: cmd,
+		Prompt:  prompt,
+	}
+}
+
+// GetSummary retrieves the command summary.
+func (c *DestructiveCommand) GetSummary() string {
+	return c.Wrapped.GetSummary()
+}
+
+// GetDescription retrieves the command's full description.
+func (c *DestructiveCommand) GetDescription() string {
+	return c.Wrapped.GetDescription()
+}
+
+// GetGroup retrieves the group name of the command.
+func (c *DestructiveCommand) GetGroup() string {
+	return c.Wrapped.GetGroup()
+}
+
+// GetSubcommands retrieves subcommands for this command.
+func (c *DestructiveCommand) GetSubcommands() map[string]ICommand {
+	return c.Wrapped.GetSubcommands()
+}
+
+// GetDefaults retrieves the defaults for arguments for this command.
+func (c *DestructiveCommand) GetDefaults() interface{} {
+	return c.Wrapped.GetDefaults()
+}
+
+// Unwrap returns the wrapped command.
+func (c *DestructiveCommand) Unwrap() ICommand {
+	return c.Wrapped
+}
+
+// GetConfirmPrompt retrieves the template of the question asked to
+// confirm the operation.
+func (c *DestructiveCommand) GetConfirmPrompt() string {
+	return c.Prompt
+}
+
+// GetDestructive is a helper that retrieves the IDestructive
+// implementation of a command.  It examines the command and any
+// commands it wraps, returning nil if none is destructive.
+func GetDestructive(cmd ICommand) IDestructive {
+	for cmd != nil {
+		if tmp, ok := cmd.(IDestructive); ok {
+			return tmp
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return nil
+}
+
+// WithConfirmFlags sets whether the global YesFlag and ForceFlag are
+// recognized.  Returns the App, to allow chaining.
+func (a *App) WithConfirmFlags(allow bool) *App {
+	a.AllowConfirmFlags = allow
+	return a
+}
+
+// WithConfirmer sets the function used to ask the user to confirm
+// the operations of destructive commands.  Returns the App, to allow
+// chaining.
+func (a *App) WithConfirmer(confirmer Confirmer) *App {
+	a.Confirmer = confirmer
+	return a
+}
+
+// extractAssumeYes is a helper that removes the YesFlag and
+// ForceFlag from the arguments preceding any "--", returning whether
+// either was present and the remaining arguments.
+func extractAssumeYes(args []string) (AssumeYes, []string) {
+	found := AssumeYes(false)
+	result := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+		if arg == YesFlag || arg == ForceFlag {
+			found = true
+			continue
+		}
+		result = append(result, arg)
+	}
+
+	return found, result
+}
+
+// confirmPrompt renders the confirmation question of a destructive
+// command for an invocation.
+func (a *App) confirmPrompt(inv *Invocation, cmd IDestructive) (string, error) {
+	text := cmd.GetConfirmPrompt()
+	if text == "" {
+		text = DefaultConfirmPrompt
+	}
+	tmpl, err := template.New("confirm").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("bad confirmation prompt: %w", err)
+	}
+
+	buf := &strings.Builder{}
+	if err := tmpl.Execute(buf, &ConfirmData{
+		App:     a.name(),
+		Name:    strings.Join(inv.Path, " "),
+		Args:    inv.Args,
+		Target:  strings.Join(inv.Args, " "),
+		Options: inv.Options,
+	}); err != nil {
+		return "", fmt.Errorf("bad confirmation prompt: %w", err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// confirm asks the user to confirm the operation of the resolved
+// command, if it is destructive.  No confirmation is needed if the
+// operation was confirmed in advance or this is a dry run.
+func (a *App) confirm(inv *Invocation, yes AssumeYes, dryRun DryRun) error {
+	cmd := GetDestructive(inv.Command)
+	if cmd == nil || bool(yes) || bool(dryRun) {
+		return nil
+	}
+
+	prompt, err := a.confirmPrompt(inv, cmd)
+	if err != nil {
+		return err
+	}
+
+	if !streamIsTerminal(a.stdin()) {
+		msg := fmt.Sprintf("command %q is destructive and standard input is not a terminal", strings.Join(inv.Path, " "))
+		if a.AllowConfirmFlags {
+			msg += fmt.Sprintf("; use %s to confirm", YesFlag)
+		}
+		return usageError(fmt.Errorf("%w: %s", ErrNotConfirmed, msg))
+	}
+
+	confirmer := a.Confirmer
+	if confirmer == nil {
+		confirmer = a.readConfirm
+	}
+	ok, err := confirmer(prompt)
+	if err != nil {
+		return err
+	} else if !ok {
+		return ErrDeclined
+	}
+
+	return nil
+}
+
+// readConfirm is the default Confirmer.  It asks the question on the
+// App's standard error and reads a line from standard input; only
+// "y" or "yes", in any case, is taken as confirmation.
+func (a *App) readConfirm(prompt string) (bool, error) {
+	fmt.Fprintf(a.stderr(), "%s [y/N] ", prompt)
+
+	text, err := bufio.NewReader(a.stdin()).ReadString('\n')
+	if err != nil && text == "" {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "y", "yes":
+		return true, nil
+	}
+
+	return false, nil
+}
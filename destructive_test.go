@@ -0,0 +1,413 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDestructiveCommandImplementsICommand(t *testing.T) {
+	assert.Implements(t, (*ICommand)(nil), &DestructiveCommand{})
+}
+
+func TestDestructiveCommandImplementsIWrapped(t *testing.T) {
+	assert.Implements(t, (*IWrapped)(nil), &DestructiveCommand{})
+}
+
+func TestDestructiveCommandImplementsIDestructive(t *testing.T) {
+	assert.Implements(t, (*IDestructive)(nil), &DestructiveCommand{})
+}
+
+func TestDestructive(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := Destructive(cmd, "Delete it?")
+
+	assert.Same(t, cmd, result.Wrapped)
+	assert.Equal(t, "Delete it?", result.Prompt)
+}
+
+func TestDestructiveCommandGetSummary(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetSummary").Return("some text")
+	obj := &DestructiveCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetSummary()
+
+	assert.Equal(t, "some text", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestDestructiveCommandGetDescription(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetDescription").Return("some text")
+	obj := &DestructiveCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetDescription()
+
+	assert.Equal(t, "some text", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestDestructiveCommandGetGroup(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetGroup").Return("some text")
+	obj := &DestructiveCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetGroup()
+
+	assert.Equal(t, "some text", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestDestructiveCommandGetSubcommands(t *testing.T) {
+	subs := map[string]ICommand{
+		"sub": &mockICommand{},
+	}
+	cmd := &mockICommand{}
+	cmd.On("GetSubcommands").Return(subs)
+	obj := &DestructiveCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetSubcommands()
+
+	assert.Equal(t, subs, result)
+	cmd.AssertExpectations(t)
+}
+
+func TestDestructiveCommandGetDefaults(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetDefaults").Return("defaults")
+	obj := &DestructiveCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetDefaults()
+
+	assert.Equal(t, "defaults", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestDestructiveCommandUnwrap(t *testing.T) {
+	cmd := &mockICommand{}
+	obj := &DestructiveCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.Unwrap()
+
+	assert.Same(t, cmd, result)
+}
+
+func TestDestructiveCommandGetConfirmPrompt(t *testing.T) {
+	obj := &DestructiveCommand{Prompt: "Delete it?"}
+
+	result := obj.GetConfirmPrompt()
+
+	assert.Equal(t, "Delete it?", result)
+}
+
+func TestGetDestructiveBase(t *testing.T) {
+	cmd := Destructive(&mockICommand{}, "Delete it?")
+
+	result := GetDestructive(cmd)
+
+	assert.Same(t, cmd, result)
+}
+
+func TestGetDestructiveWrapped(t *testing.T) {
+	cmd := Destructive(&mockICommand{}, "Delete it?")
+
+	result := GetDestructive(Requires(cmd))
+
+	assert.Same(t, cmd, result)
+}
+
+func TestGetDestructiveNone(t *testing.T) {
+	result := GetDestructive(Requires(&mockICommand{}))
+
+	assert.Nil(t, result)
+}
+
+func TestAppWithConfirmFlags(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithConfirmFlags(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.AllowConfirmFlags)
+}
+
+func TestAppWithConfirmer(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithConfirmer(func(prompt string) (bool, error) {
+		return true, nil
+	})
+
+	assert.Same(t, obj, result)
+	assert.NotNil(t, obj.Confirmer)
+}
+
+func TestExtractAssumeYes(t *testing.T) {
+	yes, args := extractAssumeYes([]string{"a1", "--yes", "a2"})
+
+	assert.True(t, bool(yes))
+	assert.Equal(t, []string{"a1", "a2"}, args)
+}
+
+func TestExtractAssumeYesForce(t *testing.T) {
+	yes, args := extractAssumeYes([]string{"--force", "a1"})
+
+	assert.True(t, bool(yes))
+	assert.Equal(t, []string{"a1"}, args)
+}
+
+func TestExtractAssumeYesAbsent(t *testing.T) {
+	yes, args := extractAssumeYes([]string{"a1", "--", "--yes"})
+
+	assert.False(t, bool(yes))
+	assert.Equal(t, []string{"a1", "--", "--yes"}, args)
+}
+
+func TestAppConfirmPromptDefault(t *testing.T) {
+	obj := &App{Name: "tool"}
+	inv := &Invocation{Path: []string{"tool", "rm"}, Args: []string{"a1", "a2"}}
+
+	result, err := obj.confirmPrompt(inv, &DestructiveCommand{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Really run "tool rm" on a1 a2?`, result)
+}
+
+func TestAppConfirmPromptDefaultNoTarget(t *testing.T) {
+	obj := &App{Name: "tool"}
+	inv := &Invocation{Path: []string{"tool", "purge"}}
+
+	result, err := obj.confirmPrompt(inv, &DestructiveCommand{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Really run "tool purge"?`, result)
+}
+
+func TestAppConfirmPromptCustom(t *testing.T) {
+	obj := &App{Name: "tool"}
+	inv := &Invocation{Path: []string{"tool", "rm"}, Args: []string{"photos"}}
+
+	result, err := obj.confirmPrompt(inv, &DestructiveCommand{
+		Prompt: `Delete the bucket "{{index .Args 0}}" from {{.App}}?`,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Delete the bucket "photos" from tool?`, result)
+}
+
+func TestAppConfirmPromptBadTemplate(t *testing.T) {
+	obj := &App{Name: "tool"}
+	inv := &Invocation{Path: []string{"tool", "rm"}}
+
+	_, err := obj.confirmPrompt(inv, &DestructiveCommand{Prompt: "{{.Bogus"})
+
+	assert.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "bad confirmation prompt: "))
+}
+
+func TestAppConfirmPromptExecuteError(t *testing.T) {
+	obj := &App{Name: "tool"}
+	inv := &Invocation{Path: []string{"tool", "rm"}}
+
+	_, err := obj.confirmPrompt(inv, &DestructiveCommand{Prompt: "{{index .Args 0}}"})
+
+	assert.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "bad confirmation prompt: "))
+}
+
+func TestAppReadConfirmYes(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	obj := &App{Stdin: strings.NewReader("Yes\n"), Stderr: stderr}
+
+	result, err := obj.readConfirm("Delete it?")
+
+	assert.NoError(t, err)
+	assert.True(t, result)
+	assert.Equal(t, "Delete it? [y/N] ", stderr.String())
+}
+
+func TestAppReadConfirmNo(t *testing.T) {
+	obj := &App{Stdin: strings.NewReader("nope\n"), Stderr: &bytes.Buffer{}}
+
+	result, err := obj.readConfirm("Delete it?")
+
+	assert.NoError(t, err)
+	assert.False(t, result)
+}
+
+func TestAppReadConfirmNoNewline(t *testing.T) {
+	obj := &App{Stdin: strings.NewReader("y"), Stderr: &bytes.Buffer{}}
+
+	result, err := obj.readConfirm("Delete it?")
+
+	assert.NoError(t, err)
+	assert.True(t, result)
+}
+
+func TestAppReadConfirmEOF(t *testing.T) {
+	obj := &App{Stdin: strings.NewReader(""), Stderr: &bytes.Buffer{}}
+
+	result, err := obj.readConfirm("Delete it?")
+
+	assert.Error(t, err)
+	assert.False(t, result)
+}
+
+// destructiveApp is a helper that constructs an application with a
+// destructive command.
+func destructiveApp(confirmer Confirmer) (*App, *runCommand) {
+	sub := newRunCommand("Remove a bucket", nil)
+	return &App{
+		Name:      "tool",
+		Stdin:     os.Stdin,
+		Stderr:    &bytes.Buffer{},
+		Confirmer: confirmer,
+		Root: &Command{
+			Subcommands: map[string]ICommand{
+				"rm": Destructive(sub, `Delete the bucket "{{.Target}}"?`),
+			},
+		},
+	}, sub
+}
+
+func TestAppDispatchDestructiveConfirmed(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	prompts := []string{}
+	obj, sub := destructiveApp(func(prompt string) (bool, error) {
+		prompts = append(prompts, prompt)
+		return true, nil
+	})
+	sub.On("Run", Args{"photos"}, mock.Anything).Return(nil)
+
+	err := obj.Dispatch(context.Background(), []string{"rm", "photos"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`Delete the bucket "photos"?`}, prompts)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchDestructiveDeclined(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	obj, sub := destructiveApp(func(prompt string) (bool, error) {
+		return false, nil
+	})
+
+	err := obj.Dispatch(context.Background(), []string{"rm", "photos"})
+
+	assert.ErrorIs(t, err, ErrDeclined)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchDestructiveConfirmerError(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	obj, sub := destructiveApp(func(prompt string) (bool, error) {
+		return false, assert.AnError
+	})
+
+	err := obj.Dispatch(context.Background(), []string{"rm", "photos"})
+
+	assert.Same(t, assert.AnError, err)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchDestructiveNotTerminal(t *testing.T) {
+	fakeTerminal(t, false, nil)
+	obj, sub := destructiveApp(nil)
+
+	err := obj.Dispatch(context.Background(), []string{"rm", "photos"})
+
+	assert.ErrorIs(t, err, ErrNotConfirmed)
+	assert.EqualError(t, err, `confirmation required: command "tool rm" is destructive and standard input is not a terminal`)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchDestructiveNotTerminalFlags(t *testing.T) {
+	fakeTerminal(t, false, nil)
+	obj, sub := destructiveApp(nil)
+	obj.AllowConfirmFlags = true
+
+	err := obj.Dispatch(context.Background(), []string{"rm", "photos"})
+
+	assert.EqualError(t, err, `confirmation required: command "tool rm" is destructive and standard input is not a terminal; use --yes to confirm`)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchDestructiveYes(t *testing.T) {
+	fakeTerminal(t, false, nil)
+	obj, sub := destructiveApp(nil)
+	obj.AllowConfirmFlags = true
+	sub.On("Run", Args{"photos"}, mock.Anything).Return(nil)
+
+	err := obj.Dispatch(context.Background(), []string{"rm", "--force", "photos"})
+
+	assert.NoError(t, err)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchDestructiveYesNotAllowed(t *testing.T) {
+	fakeTerminal(t, false, nil)
+	obj, sub := destructiveApp(nil)
+
+	err := obj.Dispatch(context.Background(), []string{"rm", "--yes", "photos"})
+
+	assert.ErrorIs(t, err, ErrNotConfirmed)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchDestructiveDryRun(t *testing.T) {
+	fakeTerminal(t, false, nil)
+	obj, sub := destructiveApp(nil)
+	obj.AllowDryRun = true
+	sub.On("Run", Args{"photos"}, mock.Anything).Return(nil)
+
+	err := obj.Dispatch(context.Background(), []string{"rm", "--dry-run", "photos"})
+
+	assert.NoError(t, err)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchDestructiveHelp(t *testing.T) {
+	fakeTerminal(t, false, nil)
+	obj, sub := destructiveApp(nil)
+	stdout := &bytes.Buffer{}
+	obj.Stdout = stdout
+
+	err := obj.Dispatch(context.Background(), []string{"rm", "--help"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Remove a bucket")
+	sub.AssertExpectations(t)
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ConfigExtensions are the extensions of the configuration files
+// searched for in the configuration directories of the application;
+// see DefaultConfigSearch.
+var ConfigExtensions = []string{".yaml", ".yml", ".toml", ".json"}
+
+// DefaultConfigSearch returns the paths searched for the
+// configuration files of the named application, from lowest to
+// highest precedence:
+//
+// 1. "config" files in the system directory, "/etc/<app>";
+//
+// 2. "config" files in the user's configuration directory,
+// "$XDG_CONFIG_HOME/<app>", or "~/.config/<app>" if XDG_CONFIG_HOME
+// is not set;
+//
+// 3. "~/.<app>.yaml", in the user's home directory; and
+//
+// 4. "./.<app>.yaml", in the current directory, for project
+// configuration.
+//
+// In the directories, a file is searched for with each of the
+// ConfigExtensions, in order.  Paths within the home directory are
+// omitted if it cannot be determined.
+func DefaultConfigSearch(app string) []string {
+	home, _ := os.UserHomeDir()
+
+	var paths []string
+	paths = append(paths, configDirPaths(filepath.Join("/etc", app))...)
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		paths = append(paths, configDirPaths(filepath.Join(dir, app))...)
+	} else if home != "" {
+		paths = append(paths, configDirPaths(filepath.Join(home, ".config", app))...)
+	}
+	if home != "" {
+		paths = append(paths, filepath.Join(home, "."+app+".yaml"))
+	}
+	paths = append(paths, "."+app+".yaml")
+
+	return paths
+}
+
+// configDirPaths is a helper that returns the paths of the
+// configuration files searched for in a directory.
+func configDirPaths(dir string) []string {
+	paths := make([]string, 0, len(ConfigExtensions))
+	for _, ext := range ConfigExtensions {
+		paths = append(paths, filepath.Join(dir, "config"+ext))
+	}
+
+	return paths
+}
+
+// WithConfigSearch sets the paths searched for configuration files,
+// from lowest to highest precedence, replacing DefaultConfigSearch.
+// Every file found is loaded, and the files override each other key
+// by key, in the manner described by WithConfigFiles.  Returns the
+// App, to allow chaining.
+func (a *App) WithConfigSearch(paths ...string) *App {
+	a.ConfigSearch = paths
+	return a
+}
+
+// WithNoConfigSearch disables, or re-enables, the search for
+// configuration files, so that only the files set by WithConfigFiles
+// and WithConfigFile are loaded.  Returns the App, to allow chaining.
+func (a *App) WithNoConfigSearch(disable bool) *App {
+	a.NoConfigSearch = disable
+	return a
+}
+
+// configSearch returns the paths searched for configuration files.
+func (a *App) configSearch() []string {
+	switch {
+	case a.NoConfigSearch:
+		return nil
+	case a.ConfigSearch != nil:
+		return a.ConfigSearch
+	}
+
+	return DefaultConfigSearch(a.name())
+}
+
+// hasConfig returns true if the application sets its configuration
+// files, search paths, or providers explicitly, in which case the
+// configuration keys bound to flags are described by help and by
+// Spec.  Discovery of configuration files through DefaultConfigSearch
+// alone is not advertised.
+func (a *App) hasConfig() bool {
+	return a.ConfigFile != "" || len(a.ConfigFiles) > 0 || len(a.ConfigProviders) > 0 || (!a.NoConfigSearch && len(a.ConfigSearch) > 0)
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultConfigSearchXDG(t *testing.T) {
+	setEnv(t, "HOME", "/home/user")
+	setEnv(t, "XDG_CONFIG_HOME", "/xdg")
+
+	result := DefaultConfigSearch("tool")
+
+	assert.Equal(t, []string{
+		"/etc/tool/config.yaml",
+		"/etc/tool/config.yml",
+		"/etc/tool/config.toml",
+		"/etc/tool/config.json",
+		"/xdg/tool/config.yaml",
+		"/xdg/tool/config.yml",
+		"/xdg/tool/config.toml",
+		"/xdg/tool/config.json",
+		"/home/user/.tool.yaml",
+		".tool.yaml",
+	}, result)
+}
+
+func TestDefaultConfigSearchHome(t *testing.T) {
+	setEnv(t, "HOME", "/home/user")
+	setEnv(t, "XDG_CONFIG_HOME", "")
+
+	result := DefaultConfigSearch("tool")
+
+	assert.Equal(t, []string{
+		"/etc/tool/config.yaml",
+		"/etc/tool/config.yml",
+		"/etc/tool/config.toml",
+		"/etc/tool/config.json",
+		"/home/user/.config/tool/config.yaml",
+		"/home/user/.config/tool/config.yml",
+		"/home/user/.config/tool/config.toml",
+		"/home/user/.config/tool/config.json",
+		"/home/user/.tool.yaml",
+		".tool.yaml",
+	}, result)
+}
+
+func TestAppWithConfigSearch(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithConfigSearch("a", "b")
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, []string{"a", "b"}, obj.ConfigSearch)
+}
+
+func TestAppWithNoConfigSearch(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithNoConfigSearch(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.NoConfigSearch)
+}
+
+func TestAppConfigSearchDefault(t *testing.T) {
+	setEnv(t, "HOME", "/home/user")
+	setEnv(t, "XDG_CONFIG_HOME", "/xdg")
+	obj := &App{Name: "tool"}
+
+	result := obj.configSearch()
+
+	assert.Equal(t, DefaultConfigSearch("tool"), result)
+}
+
+func TestAppConfigSearchOverride(t *testing.T) {
+	obj := &App{Name: "tool", ConfigSearch: []string{"a"}}
+
+	result := obj.configSearch()
+
+	assert.Equal(t, []string{"a"}, result)
+}
+
+func TestAppConfigSearchDisabled(t *testing.T) {
+	obj := &App{Name: "tool", ConfigSearch: []string{"a"}, NoConfigSearch: true}
+
+	result := obj.configSearch()
+
+	assert.Nil(t, result)
+}
+
+func TestAppConfigFilesOrder(t *testing.T) {
+	obj := &App{
+		Name:         "tool",
+		ConfigSearch: []string{"search"},
+		ConfigFiles:  []string{"first", "second"},
+		ConfigFile:   "last",
+	}
+
+	result := obj.configFiles()
+
+	assert.Equal(t, []string{"search", "first", "second", "last"}, result)
+	assert.Equal(t, []string{"search"}, obj.ConfigSearch)
+}
+
+func TestAppHasConfig(t *testing.T) {
+	assert.False(t, (&App{}).hasConfig())
+	assert.True(t, (&App{ConfigFile: "a"}).hasConfig())
+	assert.True(t, (&App{ConfigFiles: []string{"a"}}).hasConfig())
+	assert.True(t, (&App{ConfigSearch: []string{"a"}}).hasConfig())
+	assert.False(t, (&App{ConfigSearch: []string{"a"}, NoConfigSearch: true}).hasConfig())
+}
+
+func TestAppDispatchConfigSearch(t *testing.T) {
+	home := t.TempDir()
+	setEnv(t, "HOME", home)
+	setEnv(t, "XDG_CONFIG_HOME", "")
+	dir := filepath.Join(home, ".config", "tool")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(home, ".tool.yaml"), []byte("sub:\n  name: home\n"), 0o600))
+	assert.NoError(t, os.MkdirAll(dir, 0o755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "config.toml"), []byte("verbose = true\n[sub]\nname = \"xdg\"\n"), 0o600))
+	sub := &optionsCommand{
+		Command: Command{Defaults: &subOptions{Name: "default"}},
+	}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	obj := &App{Name: "tool", Root: root}
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &subOptions{
+		RootOptions: RootOptions{Verbose: true},
+		Name:        "home",
+		File:        "file",
+	}, sub.opts)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"fmt"
+	"io"
+)
+
+// DryRunFlag is the global flag that requests a dry run, if the
+// application allows it; see App.AllowDryRun.
+const DryRunFlag = "--dry-run"
+
+// DryRunPrefix is the prefix applied to output produced during a dry
+// run.
+const DryRunPrefix = "(dry run) "
+
+// DryRun indicates whether the command should merely report what it
+// would do, rather than doing it.  It is available from the
+// injector, and is set from the global DryRunFlag if the application
+// allows it.
+type DryRun bool
+
+// Do calls the specified function unless this is a dry run, in which
+// case it returns nil without calling it.
+func (d DryRun) Do(fn func() error) error {
+	if d {
+		return nil
+	}
+
+	return fn()
+}
+
+// Prefix returns the text with DryRunPrefix prepended if this is a
+// dry run; otherwise, the text is returned unchanged.
+func (d DryRun) Prefix(text string) string {
+	if d {
+		return DryRunPrefix + text
+	}
+
+	return text
+}
+
+// Fprintf formats according to a format specifier and writes to w,
+// prepending DryRunPrefix if this is a dry run.
+func (d DryRun) Fprintf(w io.Writer, format string, args ...interface{}) (int, error) {
+	return fmt.Fprint(w, d.Prefix(fmt.Sprintf(format, args...)))
+}
+
+// extractDryRun is a helper that removes the DryRunFlag from the
+// arguments preceding any "--", returning whether it was present and
+// the remaining arguments.
+func extractDryRun(args []string) (DryRun, []string) {
+	found := DryRun(false)
+	result := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+		if arg == DryRunFlag {
+			found = true
+			continue
+		}
+		result = append(result, arg)
+	}
+
+	return found, result
+}
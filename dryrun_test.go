@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRunDoFalse(t *testing.T) {
+	called := false
+
+	err := DryRun(false).Do(func() error {
+		called = true
+		return assert.AnError
+	})
+
+	assert.Same(t, assert.AnError, err)
+	assert.True(t, called)
+}
+
+func TestDryRunDoTrue(t *testing.T) {
+	called := false
+
+	err := DryRun(true).Do(func() error {
+		called = true
+		return assert.AnError
+	})
+
+	assert.NoError(t, err)
+	assert.False(t, called)
+}
+
+func TestDryRunPrefixFalse(t *testing.T) {
+	result := DryRun(false).Prefix("text")
+
+	assert.Equal(t, "text", result)
+}
+
+func TestDryRunPrefixTrue(t *testing.T) {
+	result := DryRun(true).Prefix("text")
+
+	assert.Equal(t, "(dry run) text", result)
+}
+
+func TestDryRunFprintf(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	n, err := DryRun(true).Fprintf(buf, "deleting %s\n", "file")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 24, n)
+	assert.Equal(t, "(dry run) deleting file\n", buf.String())
+}
+
+func TestExtractDryRun(t *testing.T) {
+	dryRun, args := extractDryRun([]string{"a1", "--dry-run", "a2", "--", "--dry-run"})
+
+	assert.Equal(t, DryRun(true), dryRun)
+	assert.Equal(t, []string{"a1", "a2", "--", "--dry-run"}, args)
+}
+
+func TestExtractDryRunAbsent(t *testing.T) {
+	dryRun, args := extractDryRun([]string{"a1", "a2"})
+
+	assert.Equal(t, DryRun(false), dryRun)
+	assert.Equal(t, []string{"a1", "a2"}, args)
+}
+
+type dryRunGrepCommand struct {
+	Command
+	pattern string
+	args    Args
+	dryRun  DryRun
+}
+
+func (c *dryRunGrepCommand) Run(opts *grepOptions, args Args, dryRun DryRun) {
+	c.pattern, c.args, c.dryRun = opts.Pattern, args, dryRun
+}
+
+func TestAppDispatchDryRunDeclaredValue(t *testing.T) {
+	grep := &dryRunGrepCommand{Command: Command{Defaults: &grepOptions{}}}
+	obj := (&App{
+		Name: "tool",
+		Root: &Command{Subcommands: map[string]ICommand{"grep": grep}},
+	}).WithNoConfigSearch(true).WithDryRun(true)
+
+	err := obj.Dispatch(context.Background(), []string{"grep", "-e", "--dry-run", "x"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "--dry-run", grep.pattern)
+	assert.Equal(t, Args{"x"}, grep.args)
+	assert.Equal(t, DryRun(false), grep.dryRun)
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// EnvTag is the struct tag used to bind a flag to a specific
+// environment variable, overriding the name derived from the
+// application's environment prefix.  The value "-" prevents the flag
+// from being bound to any environment variable.
+const EnvTag = "env"
+
+// ErrEnvCollision indicates that two flags of a command are bound to
+// the same environment variable.
+var ErrEnvCollision = errors.New("environment variable collision")
+
+// WithEnvPrefix sets the prefix of the environment variables that
+// flags are bound to.  With a prefix, each flag is bound to an
+// environment variable named by EnvName, unless its EnvTag says
+// otherwise; values from the environment take precedence over the
+// defaults and the configuration file, but are overridden by the
+// command line.  Returns the
+// App, to allow chaining.
+func (a *App) WithEnvPrefix(prefix string) *App {
+	a.EnvPrefix = prefix
+	return a
+}
+
+// EnvName computes the name of the environment variable for a flag.
+// The name is formed by joining the prefix, the names of the
+// commands below the root, and the long name of the flag with
+// underscores; converting the result to upper case; and replacing
+// every character that is not an ASCII letter or digit with an
+// underscore.  For instance, with prefix "mytool", the flag
+// "--fetch-url" of the command "tool remote add" is bound to
+// MYTOOL_REMOTE_ADD_FETCH_URL.  If the prefix is empty, the empty
+// string is returned.
+func EnvName(prefix string, path []string, flag string) string {
+	if prefix == "" {
+		return ""
+	}
+
+	parts := append(append([]string{prefix}, path...), flag)
+	return strings.Map(func(r rune) rune {
+		r = unicode.ToUpper(r)
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.Join(parts, "_"))
+}
+
+// envNames computes the environment variable names for the flags of
+// a command, given the names of the commands below the root.  Flags
+// not bound to an environment variable are omitted; short-only flags
+// are bound only through EnvTag.  Returns an
+// error wrapping ErrEnvCollision if two flags are bound to the same
+// variable.
+func (s *optionSet) envNames(prefix string, path []string) (map[*option]string, error) {
+	names := map[*option]string{}
+	seen := map[string]*option{}
+	for _, opt := range s.Options {
+		name := ""
+		if opt.Name != "" {
+			name = EnvName(prefix, path, opt.Name)
+		}
+		if opt.Env != nil {
+			name = *opt.Env
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+
+		if other, ok := seen[name]; ok {
+			return nil, fmt.Errorf("%w: %s bound to both %s and %s", ErrEnvCollision, name, other, opt)
+		}
+		seen[name] = opt
+		names[opt] = name
+	}
+
+	return names, nil
+}
+
+// applyEnv sets flags from the environment variables they are bound
+// to.  Values for slice flags are separated by commas.
+func (o *options) applyEnv(prefix string, path []string) error {
+	if o == nil {
+		return nil
+	}
+
+	names, err := o.Set.envNames(prefix, path)
+	if err != nil {
+		return err
+	}
+
+	for _, opt := range o.Set.Options {
+		name, ok := names[opt]
+		if !ok {
+			continue
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		// Set the flag
+		field := o.Field(opt.Index)
+		values := []string{value}
+		if isList(field.Type()) {
+			field.Set(reflect.Zero(field.Type()))
+			values = strings.Split(value, ",")
+		}
+		for _, v := range values {
+			if err := o.store(opt, v); err != nil {
+				return fmt.Errorf("%w %s for %s: %s", ErrInvalidValue, opt.Display(v), name, err)
+			}
+		}
+		o.setSource(opt, SourceEnv)
+	}
+
+	return nil
+}
+
+// CheckEnv checks the static command tree for environment variable
+// collisions, returning an error describing the first one found.
+// Collisions may occur between the flags of a single command, or
+// between the flags of different commands, as when "tool foo-bar
+// --baz" and "tool foo --bar-baz" are both bound to TOOL_FOO_BAR_BAZ.
+func (a *App) CheckEnv() error {
+	seen := map[string]string{}
+	return a.checkEnv(a.Root, nil, seen)
+}
+
+// checkEnv is a helper for CheckEnv that checks a single command and
+// recurses into its subcommands.
+func (a *App) checkEnv(cmd ICommand, path []string, seen map[string]string) error {
+	opts, err := newOptions(cmd)
+	if err != nil {
+		return err
+	}
+	if opts != nil {
+		names, err := opts.Set.envNames(a.EnvPrefix, path)
+		if err != nil {
+			return err
+		}
+		for _, opt := range opts.Set.Options {
+			name, ok := names[opt]
+			if !ok {
+				continue
+			}
+			flag := strings.Join(append(append([]string{a.name()}, path...), opt.String()), " ")
+			if other, ok := seen[name]; ok {
+				return fmt.Errorf("%w: %s bound to both %q and %q", ErrEnvCollision, name, other, flag)
+			}
+			seen[name] = flag
+		}
+	}
+
+	// Recurse into the subcommands in a stable order
+	if IsAlias(cmd) {
+		return nil
+	}
+	subs := cmd.GetSubcommands()
+	subNames := make([]string, 0, len(subs))
+	for name := range subs {
+		subNames = append(subNames, name)
+	}
+	sort.Strings(subNames)
+	for _, name := range subNames {
+		if err := a.checkEnv(subs[name], append(append([]string{}, path...), name), seen); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
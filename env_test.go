@@ -0,0 +1,336 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setEnv sets an environment variable for the duration of a test.
+func setEnv(t *testing.T, name, value string) {
+	t.Helper()
+
+	old, ok := os.LookupEnv(name)
+	os.Setenv(name, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(name, old)
+		} else {
+			os.Unsetenv(name)
+		}
+	})
+}
+
+type envOptions struct {
+	Verbose  bool     `opt:"verbose,v"`
+	Count    int      `opt:"count,c" kind:"count"`
+	FetchURL string   `opt:"fetch-url"`
+	Tags     []string `opt:"tag"`
+	Format   string   `opt:"format" choices:"json,yaml"`
+	Named    string   `opt:"named" env:"NAMED_VAR"`
+	Skipped  string   `opt:"skipped" env:"-"`
+}
+
+type envCollisionOptions struct {
+	LogLevel  string `opt:"log-level"`
+	LogLevel2 string `opt:"log_level"`
+}
+
+func TestWithEnvPrefix(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithEnvPrefix("mytool")
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, "mytool", obj.EnvPrefix)
+}
+
+func TestEnvNameBase(t *testing.T) {
+	result := EnvName("mytool", []string{"remote", "add"}, "fetch-url")
+
+	assert.Equal(t, "MYTOOL_REMOTE_ADD_FETCH_URL", result)
+}
+
+func TestEnvNameRoot(t *testing.T) {
+	result := EnvName("my-tool", nil, "verbose")
+
+	assert.Equal(t, "MY_TOOL_VERBOSE", result)
+}
+
+func TestEnvNameNoPrefix(t *testing.T) {
+	result := EnvName("", []string{"sub"}, "verbose")
+
+	assert.Equal(t, "", result)
+}
+
+func TestOptionSetEnvNames(t *testing.T) {
+	set, _ := newOptionSet(reflect.TypeOf(envOptions{}))
+
+	result, err := set.envNames("tool", []string{"sub"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"verbose":   "TOOL_SUB_VERBOSE",
+		"count":     "TOOL_SUB_COUNT",
+		"fetch-url": "TOOL_SUB_FETCH_URL",
+		"tag":       "TOOL_SUB_TAG",
+		"format":    "TOOL_SUB_FORMAT",
+		"named":     "NAMED_VAR",
+	}, envNamesByFlag(result))
+}
+
+func TestOptionSetEnvNamesShortOnly(t *testing.T) {
+	type opts struct {
+		Quiet bool   `opt:",q"`
+		Level string `opt:",l" env:"LEVEL"`
+	}
+	set, _ := newOptionSet(reflect.TypeOf(opts{}))
+
+	result, err := set.envNames("tool", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[*option]string{
+		set.Options[1]: "LEVEL",
+	}, result)
+}
+
+func TestOptionSetEnvNamesNoPrefix(t *testing.T) {
+	set, _ := newOptionSet(reflect.TypeOf(envOptions{}))
+
+	result, err := set.envNames("", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"named": "NAMED_VAR",
+	}, envNamesByFlag(result))
+}
+
+func TestOptionSetEnvNamesCollision(t *testing.T) {
+	set, _ := newOptionSet(reflect.TypeOf(envCollisionOptions{}))
+
+	result, err := set.envNames("tool", nil)
+
+	assert.ErrorIs(t, err, ErrEnvCollision)
+	assert.EqualError(t, err, "environment variable collision: TOOL_LOG_LEVEL bound to both --log-level and --log_level")
+	assert.Nil(t, result)
+}
+
+func TestOptionsApplyEnvBase(t *testing.T) {
+	setEnv(t, "TOOL_VERBOSE", "true")
+	setEnv(t, "TOOL_COUNT", "3")
+	setEnv(t, "TOOL_FETCH_URL", "http://example.com")
+	setEnv(t, "TOOL_TAG", "a,b")
+	setEnv(t, "TOOL_FORMAT", "yaml")
+	setEnv(t, "NAMED_VAR", "named")
+	setEnv(t, "TOOL_SKIPPED", "skipped")
+	opts, _ := newOptions(&Command{Defaults: &envOptions{Tags: []string{"default"}}})
+
+	err := opts.applyEnv("tool", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &envOptions{
+		Verbose:  true,
+		Count:    3,
+		FetchURL: "http://example.com",
+		Tags:     []string{"a", "b"},
+		Format:   "yaml",
+		Named:    "named",
+	}, opts.Value.Interface())
+}
+
+func TestOptionsApplyEnvNil(t *testing.T) {
+	var opts *options
+
+	err := opts.applyEnv("tool", nil)
+
+	assert.NoError(t, err)
+}
+
+func TestOptionsApplyEnvCollision(t *testing.T) {
+	opts, _ := newOptions(&Command{Defaults: &envCollisionOptions{}})
+
+	err := opts.applyEnv("tool", nil)
+
+	assert.ErrorIs(t, err, ErrEnvCollision)
+}
+
+func TestOptionsApplyEnvBadValue(t *testing.T) {
+	setEnv(t, "TOOL_FORMAT", "xml")
+	opts, _ := newOptions(&Command{Defaults: &envOptions{}})
+
+	err := opts.applyEnv("tool", nil)
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.EqualError(t, err, `invalid value "xml" for TOOL_FORMAT: must be one of json, yaml`)
+}
+
+func TestAppDispatchEnv(t *testing.T) {
+	setEnv(t, "TOOL_VERBOSE", "true")
+	setEnv(t, "TOOL_SUB_NAME", "env")
+	sub := &optionsCommand{
+		Command: Command{Defaults: &subOptions{Name: "default"}},
+	}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	obj := &App{
+		Name:      "tool",
+		Root:      root,
+		EnvPrefix: "tool",
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &RootOptions{Verbose: true}, sub.root)
+	assert.Equal(t, &subOptions{
+		RootOptions: RootOptions{Verbose: true},
+		Name:        "env",
+		File:        "file",
+	}, sub.opts)
+}
+
+func TestAppDispatchEnvOverridden(t *testing.T) {
+	setEnv(t, "TOOL_SUB_NAME", "env")
+	sub := &optionsCommand{
+		Command: Command{Defaults: &subOptions{Name: "default"}},
+	}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	obj := &App{
+		Name:      "tool",
+		Root:      root,
+		EnvPrefix: "tool",
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "--name", "cli", "file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "cli", sub.opts.Name)
+}
+
+func TestAppDispatchEnvError(t *testing.T) {
+	root := &Command{
+		Defaults: &envCollisionOptions{},
+	}
+	obj := &App{
+		Name:      "tool",
+		Root:      root,
+		EnvPrefix: "tool",
+	}
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.ErrorIs(t, err, ErrEnvCollision)
+}
+
+func TestAppCheckEnvBase(t *testing.T) {
+	obj := &App{
+		Name: "tool",
+		Root: &Command{
+			Defaults: &RootOptions{},
+			Subcommands: map[string]ICommand{
+				"sub":   &Command{Defaults: &subOptions{}},
+				"alias": Alias(&Command{Defaults: &subOptions{}}),
+				"plain": &Command{},
+			},
+		},
+		EnvPrefix: "tool",
+	}
+
+	err := obj.CheckEnv()
+
+	assert.NoError(t, err)
+}
+
+func TestAppCheckEnvAcrossCommands(t *testing.T) {
+	obj := &App{
+		Name: "tool",
+		Root: &Command{
+			Subcommands: map[string]ICommand{
+				"foo-bar": &Command{Defaults: &struct {
+					Baz bool `opt:"baz"`
+				}{}},
+				"foo": &Command{Defaults: &struct {
+					BarBaz bool `opt:"bar-baz"`
+				}{}},
+			},
+		},
+		EnvPrefix: "tool",
+	}
+
+	err := obj.CheckEnv()
+
+	assert.ErrorIs(t, err, ErrEnvCollision)
+	assert.EqualError(t, err, `environment variable collision: TOOL_FOO_BAR_BAZ bound to both "tool foo --bar-baz" and "tool foo-bar --baz"`)
+}
+
+func TestAppCheckEnvWithinCommand(t *testing.T) {
+	obj := &App{
+		Name: "tool",
+		Root: &Command{
+			Defaults: &envCollisionOptions{},
+		},
+		EnvPrefix: "tool",
+	}
+
+	err := obj.CheckEnv()
+
+	assert.ErrorIs(t, err, ErrEnvCollision)
+}
+
+func TestAppCheckEnvBadOptions(t *testing.T) {
+	obj := &App{
+		Name: "tool",
+		Root: &Command{
+			Defaults: "bad",
+		},
+		EnvPrefix: "tool",
+	}
+
+	err := obj.CheckEnv()
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+}
+
+// envNamesByFlag is a helper that converts the result of envNames
+// into a map keyed by flag name.
+func envNamesByFlag(names map[*option]string) map[string]string {
+	result := map[string]string{}
+	for opt, name := range names {
+		result[opt.Name] = name
+	}
+	return result
+}
+
+func TestOptionsApplyEnvPointers(t *testing.T) {
+	setEnv(t, "TOOL_REPLICAS", "0")
+	obj, _ := newOptions(&Command{Defaults: &pointerOptions{}})
+
+	err := obj.applyEnv("tool", nil)
+
+	assert.NoError(t, err)
+	value := obj.Value.Interface().(*pointerOptions)
+	assert.Nil(t, value.Force)
+	assert.Equal(t, 0, *value.Replicas)
+}
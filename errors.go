@@ -16,6 +16,16 @@ package nelson
 
 import "errors"
 
+// ErrRemovedCommand indicates that a deprecated command has passed
+// its removal date and may no longer be used.
+var ErrRemovedCommand = errors.New("command has been removed")
+
+// UsageExitCode is the exit code for usage errors, such as unknown
+// flags or missing arguments, following the shell convention for
+// incorrect usage.  Applications may select a different code; see
+// WithUsageCode.
+const UsageExitCode = 2
+
 // CommandError is an implementation of the error interface that wraps
 // another error and associates with it an error code to return.
 type CommandError struct {
@@ -24,8 +34,13 @@ type CommandError struct {
 	Usage bool  // If true, emit a usage message
 }
 
-// Error returns the error message.
+// Error returns the error message.  If there is no wrapped error,
+// the message is empty, which suppresses error reporting.
 func (e *CommandError) Error() string {
+	if e.Err == nil {
+		return ""
+	}
+
 	return e.Err.Error()
 }
 
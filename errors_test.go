@@ -36,6 +36,14 @@ func TestCommandErrorError(t *testing.T) {
 	assert.Equal(t, "some random error", result)
 }
 
+func TestCommandErrorErrorNil(t *testing.T) {
+	obj := &CommandError{}
+
+	result := obj.Error()
+
+	assert.Equal(t, "", result)
+}
+
 func TestCommandErrorUnwrap(t *testing.T) {
 	obj := &CommandError{
 		Err: assert.AnError,
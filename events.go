@@ -0,0 +1,195 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// OutputNDJSON is the name of the output format that reports the
+// progress, warnings, results, and errors of a command as a stream of
+// events, one JSON object per line, for consumption by other
+// programs; see Event.
+const OutputNDJSON = "ndjson"
+
+// EventVersion is the version of the schema of Event.  It changes
+// only if the meaning of an existing field changes; fields may be
+// added without changing it.
+const EventVersion = 1
+
+// Types of events.
+const (
+	EventProgress = "progress" // Progress of a long-running command
+	EventWarning  = "warning"  // A warning
+	EventResult   = "result"   // Data returned by a command
+	EventError    = "error"    // The error that ended a command
+)
+
+// Event is a single event of the OutputNDJSON format.
+type Event struct {
+	Version int         `json:"version"`           // EventVersion
+	Type    string      `json:"type"`              // Type of the event, e.g., EventProgress
+	Time    time.Time   `json:"time"`              // Time at which the event occurred
+	Message string      `json:"message,omitempty"` // Message, for progress, warning, and error events
+	Current int64       `json:"current,omitempty"` // Units of work completed, for progress events
+	Total   int64       `json:"total,omitempty"`   // Units of work in total, if known, for progress events
+	Data    interface{} `json:"data,omitempty"`    // Data returned by the command, for result events
+}
+
+// newEvent is a helper that constructs an event of the specified
+// type, occurring now.
+func (e *Events) newEvent(typ string) *Event {
+	return &Event{
+		Version: EventVersion,
+		Type:    typ,
+		Time:    e.now().UTC(),
+	}
+}
+
+// now returns the current time, from the clock of the events.
+func (e *Events) now() time.Time {
+	if e.clock != nil {
+		return e.clock.Now()
+	}
+
+	return defaultClock.Now()
+}
+
+// Events reports the progress, warnings, and results of a command,
+// as events if the OutputNDJSON format is selected, and otherwise in
+// human-readable form: progress is reported to the Logger at
+// VerbosityVerbose, warnings are reported with App.Warn, and results
+// are rendered by the Output.  It is available from the injector.
+// If the command fails, an error event is emitted by the framework.
+type Events struct {
+	app    *App       // The application
+	log    *Logger    // The logger
+	output *Output    // Renders the results
+	clock  Clock      // The source of the times of events
+	lock   sync.Mutex // Serializes the events
+}
+
+// Enabled returns true if events are emitted, that is, if the
+// OutputNDJSON format is selected.
+func (e *Events) Enabled() bool {
+	return e.output.Format == OutputNDJSON
+}
+
+// Emit emits an event, if events are enabled.  The version and time
+// of the event are set if they are not.
+func (e *Events) Emit(event *Event) error {
+	if !e.Enabled() {
+		return nil
+	}
+	if event.Version == 0 {
+		event.Version = EventVersion
+	}
+	if event.Time.IsZero() {
+		event.Time = e.now().UTC()
+	}
+
+	return e.write(event)
+}
+
+// write is a helper that writes an event to the output.
+func (e *Events) write(event *Event) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return json.NewEncoder(e.output.Writer).Encode(event)
+}
+
+// Progress reports the progress of the command: the units of work
+// completed, and the units in total, or 0 if the total is not known.
+func (e *Events) Progress(message string, current, total int64) error {
+	if !e.Enabled() {
+		switch {
+		case total > 0:
+			e.log.Infof("%s (%d/%d)", message, current, total)
+		case current > 0:
+			e.log.Infof("%s (%d)", message, current)
+		default:
+			e.log.Infof("%s", message)
+		}
+		return nil
+	}
+
+	event := e.newEvent(EventProgress)
+	event.Message = message
+	event.Current = current
+	event.Total = total
+	return e.write(event)
+}
+
+// Warning reports a warning.  The arguments are interpreted as for
+// fmt.Sprintf.
+func (e *Events) Warning(format string, args ...interface{}) error {
+	if !e.Enabled() {
+		e.app.Warn(format, args...)
+		return nil
+	}
+
+	event := e.newEvent(EventWarning)
+	event.Message = fmt.Sprintf(format, args...)
+	return e.write(event)
+}
+
+// Result reports data produced by the command, rendering it with the
+// Output.  It may be called more than once, e.g., for each item
+// processed.
+func (e *Events) Result(value interface{}) error {
+	return e.output.Render(value)
+}
+
+// failed is a helper that emits an error event, if events are
+// enabled and the command failed.  Returns the error.
+func (e *Events) failed(err error) error {
+	if err != nil && e.Enabled() {
+		event := e.newEvent(EventError)
+		event.Message = err.Error()
+		e.write(event) //nolint:errcheck
+	}
+
+	return err
+}
+
+// ndjsonFormatter is the OutputFormatter for OutputNDJSON.  It
+// renders data as result events, timestamped using the App's Clock.
+type ndjsonFormatter struct {
+	clock Clock // The clock to timestamp events with
+}
+
+// formatNDJSON constructs the formatter for OutputNDJSON.
+func formatNDJSON(arg string) (OutputFormatter, error) {
+	if arg != "" {
+		return nil, errors.New("format takes no argument")
+	}
+	return &ndjsonFormatter{clock: defaultClock}, nil
+}
+
+// Format renders data as a result event.
+func (f *ndjsonFormatter) Format(w io.Writer, value interface{}) error {
+	return json.NewEncoder(w).Encode(&Event{
+		Version: EventVersion,
+		Type:    EventResult,
+		Time:    f.clock.Now().UTC(),
+		Data:    value,
+	})
+}
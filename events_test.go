@@ -0,0 +1,144 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEventNow is a helper that fixes the time of events for the
+// duration of a test.
+func fakeEventNow(t *testing.T) {
+	old := defaultClock
+	defaultClock = fixedClock(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC))
+	t.Cleanup(func() {
+		defaultClock = old
+	})
+}
+
+type eventsCommand struct {
+	Command
+	err error
+}
+
+func (c *eventsCommand) Run(events *Events) (interface{}, error) {
+	events.Progress("copying", 1, 2)
+	events.Warning("skipped %s", "b")
+	if c.err != nil {
+		return nil, c.err
+	}
+	return []string{"a"}, nil
+}
+
+// eventsApp is a helper that constructs an application whose root
+// command reports events.
+func eventsApp(err error) (*App, *bytes.Buffer, *bytes.Buffer) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	obj := (&App{
+		Name:   "tool",
+		Root:   &eventsCommand{err: err},
+		Stdout: stdout,
+		Stderr: stderr,
+	}).WithNoConfigSearch(true).WithOutput(true).WithVerbosity(true)
+
+	return obj, stdout, stderr
+}
+
+func TestAppDispatchEvents(t *testing.T) {
+	fakeEventNow(t)
+	obj, stdout, stderr := eventsApp(nil)
+
+	err := obj.Dispatch(context.Background(), []string{"-o", "ndjson"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"version":1,"type":"progress","time":"2021-03-04T05:06:07Z","message":"copying","current":1,"total":2}
+{"version":1,"type":"warning","time":"2021-03-04T05:06:07Z","message":"skipped b"}
+{"version":1,"type":"result","time":"2021-03-04T05:06:07Z","data":["a"]}
+`, stdout.String())
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestAppDispatchEventsClock(t *testing.T) {
+	obj, stdout, _ := eventsApp(nil)
+	obj.WithClock(fixedClock(time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)))
+
+	err := obj.Dispatch(context.Background(), []string{"-o", "ndjson"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"version":1,"type":"progress","time":"2022-01-02T03:04:05Z","message":"copying","current":1,"total":2}
+{"version":1,"type":"warning","time":"2022-01-02T03:04:05Z","message":"skipped b"}
+{"version":1,"type":"result","time":"2022-01-02T03:04:05Z","data":["a"]}
+`, stdout.String())
+}
+
+func TestAppDispatchEventsError(t *testing.T) {
+	fakeEventNow(t)
+	obj, stdout, _ := eventsApp(errors.New("failed"))
+
+	err := obj.Dispatch(context.Background(), []string{"-o", "ndjson"})
+
+	assert.EqualError(t, err, "failed")
+	assert.Contains(t, stdout.String(), `{"version":1,"type":"error","time":"2021-03-04T05:06:07Z","message":"failed"}`+"\n")
+}
+
+func TestAppDispatchEventsDisabled(t *testing.T) {
+	obj, stdout, stderr := eventsApp(nil)
+
+	err := obj.Dispatch(context.Background(), []string{"-v"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "a\n", stdout.String())
+	assert.Equal(t, "tool: copying (1/2)\ntool: warning: skipped b\n", stderr.String())
+}
+
+func TestEventsEmit(t *testing.T) {
+	fakeEventNow(t)
+	buf := &bytes.Buffer{}
+	obj := &Events{output: &Output{Format: OutputNDJSON, Writer: buf}}
+
+	err := obj.Emit(&Event{Type: "custom", Data: 5})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"version":1,"type":"custom","time":"2021-03-04T05:06:07Z","data":5}`+"\n", buf.String())
+}
+
+func TestEventsEmitDisabled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &Events{output: &Output{Format: OutputJSON, Writer: buf}}
+
+	err := obj.Emit(&Event{Type: "custom"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+}
+
+func TestEventsProgressDisabled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &Events{
+		log:    NewLogger(buf, "", VerbosityVerbose),
+		output: &Output{Format: OutputTable},
+	}
+
+	obj.Progress("counted", 5, 0)
+	obj.Progress("waiting", 0, 0)
+
+	assert.Equal(t, "counted (5)\nwaiting\n", buf.String())
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// TemplateData is the data made available to Go templates embedded
+// in command summaries and descriptions.  The templates are
+// evaluated when help is rendered, so that, for instance, a
+// description that refers to {{.ExecutableName}} remains correct
+// when the binary is renamed.  The available variables are:
+//
+//	{{.AppName}}         The name of the application
+//	{{.Version}}         The version of the application
+//	{{.ExecutableName}}  The base name of the running executable
+//	{{.CommandPath}}     The full path of the command, e.g., "tool sub"
+//	{{.CommandName}}     The name of the command, e.g., "sub"
+type TemplateData struct {
+	AppName        string // The name of the application
+	Version        string // The version of the application
+	ExecutableName string // The base name of the running executable
+	CommandPath    string // The full path of the command
+	CommandName    string // The name of the command
+}
+
+// NewTemplateData constructs a TemplateData for the specified
+// application name and version.  The ExecutableName is derived from
+// os.Args[0].
+func NewTemplateData(appName, version string) *TemplateData {
+	data := &TemplateData{
+		AppName: appName,
+		Version: version,
+	}
+	if len(os.Args) > 0 {
+		data.ExecutableName = filepath.Base(os.Args[0])
+	}
+
+	return data
+}
+
+// ForCommand returns a copy of the template data describing the
+// command at the specified command path.
+func (d *TemplateData) ForCommand(path []string) *TemplateData {
+	tmp := *d
+	tmp.CommandPath = strings.Join(path, " ")
+	if len(path) > 0 {
+		tmp.CommandName = path[len(path)-1]
+	}
+
+	return &tmp
+}
+
+// Expand expands the template text using the template data.  Text
+// that does not contain a template action is returned unchanged.
+func (d *TemplateData) Expand(text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New("text").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return text, err
+	}
+
+	buf := &strings.Builder{}
+	if err := tmpl.Execute(buf, d); err != nil {
+		return text, err
+	}
+
+	return buf.String(), nil
+}
+
+// TryExpand is similar to Expand, but if the template cannot be
+// expanded, the unexpanded text is returned.  This is used when
+// rendering help, where a malformed template should not prevent the
+// help from being displayed.
+func (d *TemplateData) TryExpand(text string) string {
+	result, _ := d.Expand(text)
+	return result
+}
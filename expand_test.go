@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTemplateData(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"/usr/bin/tool", "arg"}
+
+	result := NewTemplateData("app", "1.0")
+
+	assert.Equal(t, &TemplateData{
+		AppName:        "app",
+		Version:        "1.0",
+		ExecutableName: "tool",
+	}, result)
+}
+
+func TestNewTemplateDataNoArgs(t *testing.T) {
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = nil
+
+	result := NewTemplateData("app", "1.0")
+
+	assert.Equal(t, &TemplateData{
+		AppName: "app",
+		Version: "1.0",
+	}, result)
+}
+
+func TestTemplateDataForCommand(t *testing.T) {
+	obj := &TemplateData{
+		AppName: "app",
+	}
+
+	result := obj.ForCommand([]string{"tool", "sub"})
+
+	assert.Equal(t, &TemplateData{
+		AppName:     "app",
+		CommandPath: "tool sub",
+		CommandName: "sub",
+	}, result)
+	assert.Equal(t, &TemplateData{
+		AppName: "app",
+	}, obj)
+}
+
+func TestTemplateDataForCommandEmpty(t *testing.T) {
+	obj := &TemplateData{
+		AppName: "app",
+	}
+
+	result := obj.ForCommand(nil)
+
+	assert.Equal(t, &TemplateData{
+		AppName: "app",
+	}, result)
+}
+
+func TestTemplateDataExpandPlain(t *testing.T) {
+	obj := &TemplateData{}
+
+	result, err := obj.Expand("plain text")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "plain text", result)
+}
+
+func TestTemplateDataExpandTemplate(t *testing.T) {
+	obj := &TemplateData{
+		AppName:        "app",
+		Version:        "1.0",
+		ExecutableName: "tool",
+	}
+
+	result, err := obj.Expand("Run {{.ExecutableName}} to use {{.AppName}} {{.Version}}.")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Run tool to use app 1.0.", result)
+}
+
+func TestTemplateDataExpandBadTemplate(t *testing.T) {
+	obj := &TemplateData{}
+
+	result, err := obj.Expand("Run {{.ExecutableName")
+
+	assert.Error(t, err)
+	assert.Equal(t, "Run {{.ExecutableName", result)
+}
+
+func TestTemplateDataExpandBadField(t *testing.T) {
+	obj := &TemplateData{}
+
+	result, err := obj.Expand("Run {{.NoSuchField}}")
+
+	assert.Error(t, err)
+	assert.Equal(t, "Run {{.NoSuchField}}", result)
+}
+
+func TestTemplateDataTryExpand(t *testing.T) {
+	obj := &TemplateData{
+		AppName: "app",
+	}
+
+	result := obj.TryExpand("Use {{.AppName}}")
+
+	assert.Equal(t, "Use app", result)
+}
+
+func TestTemplateDataTryExpandError(t *testing.T) {
+	obj := &TemplateData{}
+
+	result := obj.TryExpand("Use {{.AppName")
+
+	assert.Equal(t, "Use {{.AppName", result)
+}
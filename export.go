@@ -0,0 +1,248 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CommandSpec is a machine-readable description of a command and
+// its subcommands.  It is produced by Export, and may be rendered as
+// JSON or YAML for use by documentation generators and compatibility
+// checkers.
+type CommandSpec struct {
+	Schema      string           `json:"$schema,omitempty" yaml:"$schema,omitempty"`         // Identifier of the schema the specification conforms to; see SpecSchema
+	Name        string           `json:"name" yaml:"name"`                                   // Name of the command
+	Summary     string           `json:"summary,omitempty" yaml:"summary,omitempty"`         // Command summary
+	Description string           `json:"description,omitempty" yaml:"description,omitempty"` // Full description
+	Group       string           `json:"group,omitempty" yaml:"group,omitempty"`             // Group name
+	Hidden      bool             `json:"hidden,omitempty" yaml:"hidden,omitempty"`           // Command is hidden
+	Alias       bool             `json:"alias,omitempty" yaml:"alias,omitempty"`             // Command is an alias
+	Deprecated  *DeprecationSpec `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`   // Deprecation data
+	Flags       []*FlagSpec      `json:"flags,omitempty" yaml:"flags,omitempty"`             // Flags, in declaration order
+	Globals     []*FlagSpec      `json:"globals,omitempty" yaml:"globals,omitempty"`         // Application-global flags, for the root command only
+	Args        []*ArgSpec       `json:"args,omitempty" yaml:"args,omitempty"`               // Positional arguments, in order
+	Subcommands []*CommandSpec   `json:"subcommands,omitempty" yaml:"subcommands,omitempty"` // Subcommands, sorted by name
+}
+
+// FlagSpec is a machine-readable description of a flag accepted by
+// a command.
+type FlagSpec struct {
+	Name       string   `json:"name,omitempty" yaml:"name,omitempty"`             // Long name of the flag
+	Aliases    []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`       // Alternate long names of the flag
+	Short      string   `json:"short,omitempty" yaml:"short,omitempty"`           // Short name of the flag
+	Type       string   `json:"type,omitempty" yaml:"type,omitempty"`             // Type of the flag's value or kind of flag; empty for boolean flags
+	Help       string   `json:"help,omitempty" yaml:"help,omitempty"`             // Help text for the flag
+	Hint       string   `json:"hint,omitempty" yaml:"hint,omitempty"`             // Placeholder describing the flag's value, for completion
+	Choices    []string `json:"choices,omitempty" yaml:"choices,omitempty"`       // Allowed values, if restricted
+	Default    *string  `json:"default,omitempty" yaml:"default,omitempty"`       // Default value, if any
+	Optional   *string  `json:"optional,omitempty" yaml:"optional,omitempty"`     // Value used if the flag is given without one, if the value is optional
+	Hidden     bool     `json:"hidden,omitempty" yaml:"hidden,omitempty"`         // Flag is hidden
+	Deprecated string   `json:"deprecated,omitempty" yaml:"deprecated,omitempty"` // Deprecation message
+	Complete   string   `json:"complete,omitempty" yaml:"complete,omitempty"`     // How the flag's value is completed, in the form of CompleteTag
+	Env        string   `json:"env,omitempty" yaml:"env,omitempty"`               // Environment variable bound to the flag, if any
+	Config     string   `json:"config,omitempty" yaml:"config,omitempty"`         // Configuration key bound to the flag, if any
+}
+
+// ArgSpec is a machine-readable description of a positional argument
+// accepted by a command.
+type ArgSpec struct {
+	Name     string   `json:"name" yaml:"name"`                             // Name of the argument
+	Type     string   `json:"type,omitempty" yaml:"type,omitempty"`         // Type of the argument
+	Arity    string   `json:"arity,omitempty" yaml:"arity,omitempty"`       // Number of values, in interval notation
+	Help     string   `json:"help,omitempty" yaml:"help,omitempty"`         // Help text for the argument
+	Hint     string   `json:"hint,omitempty" yaml:"hint,omitempty"`         // Placeholder describing the argument's values, for completion
+	Choices  []string `json:"choices,omitempty" yaml:"choices,omitempty"`   // Allowed values, if restricted
+	Complete string   `json:"complete,omitempty" yaml:"complete,omitempty"` // How the argument's values are completed, in the form of CompleteTag
+}
+
+// DeprecationSpec is a machine-readable description of a command's
+// deprecation.
+type DeprecationSpec struct {
+	Alternative string `json:"alternative,omitempty" yaml:"alternative,omitempty"` // Alternative command
+	Since       string `json:"since,omitempty" yaml:"since,omitempty"`             // Deprecation date
+	RemoveBy    string `json:"removeBy,omitempty" yaml:"removeBy,omitempty"`       // Removal date
+}
+
+// Export produces a machine-readable description of the command tree
+// rooted at root.  The root command has an empty name.  The
+// subcommands of aliases are not included, since they are available
+// through the aliased command.  Since the environment variables and
+// configuration keys bound to flags depend on the application, they
+// are not included; see App.Spec.
+func Export(root ICommand) *CommandSpec {
+	return (&exporter{}).command("", root, nil)
+}
+
+// exporter constructs the machine-readable descriptions of commands.
+type exporter struct {
+	env       bool   // True if flags are bound to environment variables
+	envPrefix string // Prefix of the environment variables bound to flags
+	config    bool   // True if flags are bound to configuration keys
+
+	inj *Injector // Injector used to compute dynamic subcommands, if any
+}
+
+// command constructs the CommandSpec for a single command and
+// recurses into its subcommands.  The path is the names of the
+// commands below the root.
+func (e *exporter) command(name string, cmd ICommand, path []string) *CommandSpec {
+	spec := &CommandSpec{
+		Name:        name,
+		Summary:     cmd.GetSummary(),
+		Description: cmd.GetDescription(),
+		Group:       cmd.GetGroup(),
+		Hidden:      IsHidden(cmd),
+		Alias:       IsAlias(cmd),
+	}
+
+	// Describe the deprecation
+	if dep := GetDeprecation(cmd); dep != nil {
+		spec.Deprecated = &DeprecationSpec{
+			Alternative: dep.Alternative,
+		}
+		if !dep.Since.IsZero() {
+			spec.Deprecated.Since = dep.Since.Format(DateLayout)
+		}
+		if !dep.RemoveBy.IsZero() {
+			spec.Deprecated.RemoveBy = dep.RemoveBy.Format(DateLayout)
+		}
+	}
+
+	// Describe the flags and arguments
+	if opts, err := describeOptions(cmd); err == nil && opts != nil {
+		spec.Flags = e.flags(opts, path)
+		for _, arg := range opts.Set.Args {
+			argSpec := &ArgSpec{
+				Name:    arg.Name,
+				Type:    typeName(arg.Type),
+				Help:    arg.Help,
+				Hint:    typeHint(arg.Type),
+				Choices: arg.Choices,
+			}
+			if arg.Variadic() || arg.Arity.Start != 1 {
+				argSpec.Arity = arg.Arity.String()
+			}
+			if arg.Complete != nil {
+				argSpec.Complete = arg.Complete.String()
+			}
+			spec.Args = append(spec.Args, argSpec)
+		}
+	}
+
+	// Don't recurse through aliases
+	if spec.Alias {
+		return spec
+	}
+
+	// Describe the subcommands in a stable order
+	subs := cmd.GetSubcommands()
+	if e.inj != nil {
+		if tmp, err := resolveSubcommands(cmd, e.inj); err == nil {
+			subs = tmp
+		}
+	}
+	names := make([]string, 0, len(subs))
+	for subName := range subs {
+		names = append(names, subName)
+	}
+	sort.Strings(names)
+	for _, subName := range names {
+		subPath := append(append([]string{}, path...), subName)
+		spec.Subcommands = append(spec.Subcommands, e.command(subName, subs[subName], subPath))
+	}
+
+	return spec
+}
+
+// flags constructs the FlagSpecs for the flags of the options, given
+// the names of the commands below the root.
+func (e *exporter) flags(opts *options, path []string) []*FlagSpec {
+	var envNames map[*option]string
+	if e.env {
+		envNames, _ = opts.Set.envNames(e.envPrefix, path)
+	}
+
+	var flags []*FlagSpec
+	for _, opt := range opts.Set.Options {
+		flag := &FlagSpec{
+			Name:       opt.Name,
+			Aliases:    opt.Aliases,
+			Short:      opt.Short,
+			Help:       opt.Help,
+			Hint:       typeHint(opt.Type),
+			Choices:    opt.Choices,
+			Optional:   opt.Optional,
+			Hidden:     opt.Hidden,
+			Deprecated: opt.Deprecated,
+			Env:        envNames[opt],
+		}
+		if text, ok := opts.DefaultText(opt); ok {
+			flag.Default = &text
+		}
+		if opt.Complete != nil {
+			flag.Complete = opt.Complete.String()
+		}
+		if opt.Kind != "" {
+			flag.Type = opt.Kind
+		} else if opt.TakesValue() {
+			flag.Type = typeName(opt.Type)
+		}
+		if keys := opt.configKeys(path); e.config && keys != nil {
+			flag.Config = strings.Join(keys, ".")
+		}
+		flags = append(flags, flag)
+	}
+
+	return flags
+}
+
+// typeName is a helper that returns the name of the type of a flag
+// or argument.  Pointer types are described by the types they point
+// to, since pointers merely distinguish values that were not set.
+func typeName(typ reflect.Type) string {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	return typ.String()
+}
+
+// JSON renders the command specification as indented JSON.
+func (s *CommandSpec) JSON() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// YAML renders the command specification as YAML.
+func (s *CommandSpec) YAML() ([]byte, error) {
+	return yaml.Marshal(s)
+}
+
+// ReadSpec reads a command specification, rendered as either JSON or
+// YAML, from a stream.
+func ReadSpec(r io.Reader) (*CommandSpec, error) {
+	spec := &CommandSpec{}
+	if err := yaml.NewDecoder(r).Decode(spec); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
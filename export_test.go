@@ -0,0 +1,265 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func exportTree() ICommand {
+	sub := &Command{
+		Summary: "Sub command",
+		Group:   "group",
+	}
+	return &Command{
+		Summary:     "Root command",
+		Description: "The root command.",
+		Subcommands: map[string]ICommand{
+			"sub":    sub,
+			"alias":  Alias(sub),
+			"hidden": Hidden(&Command{Summary: "Hidden command"}),
+			"old": &DeprecatedCommand{
+				Wrapped: &Command{
+					Summary: "Old command",
+					Subcommands: map[string]ICommand{
+						"child": &Command{Summary: "Child command"},
+					},
+				},
+				Alternative: "sub",
+				Since:       time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+				RemoveBy:    time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+}
+
+func TestExport(t *testing.T) {
+	result := Export(exportTree())
+
+	assert.Equal(t, &CommandSpec{
+		Summary:     "Root command",
+		Description: "The root command.",
+		Subcommands: []*CommandSpec{
+			{
+				Name:    "alias",
+				Summary: "Sub command",
+				Group:   "group",
+				Alias:   true,
+			},
+			{
+				Name:    "hidden",
+				Summary: "Hidden command",
+				Hidden:  true,
+			},
+			{
+				Name:    "old",
+				Summary: "Old command",
+				Deprecated: &DeprecationSpec{
+					Alternative: "sub",
+					Since:       "2021-01-01",
+					RemoveBy:    "2021-06-01",
+				},
+				Subcommands: []*CommandSpec{
+					{
+						Name:    "child",
+						Summary: "Child command",
+					},
+				},
+			},
+			{
+				Name:    "sub",
+				Summary: "Sub command",
+				Group:   "group",
+			},
+		},
+	}, result)
+}
+
+func TestExportDeprecatedNoDates(t *testing.T) {
+	result := Export(Deprecated(&Command{}, "alt"))
+
+	assert.Equal(t, &CommandSpec{
+		Deprecated: &DeprecationSpec{
+			Alternative: "alt",
+		},
+	}, result)
+}
+
+func TestExportOptions(t *testing.T) {
+	result := Export(&Command{Defaults: &testOptions{}})
+
+	assert.Equal(t, &CommandSpec{
+		Flags: []*FlagSpec{
+			{Name: "verbose", Short: "v", Help: "Verbose output"},
+			{Name: "name", Type: "string"},
+			{Name: "count", Short: "c", Type: "int"},
+			{Name: "tag", Type: "[]string"},
+		},
+		Args: []*ArgSpec{
+			{Name: "FILE", Type: "string", Help: "Input file"},
+		},
+	}, result)
+}
+
+func TestExportPointers(t *testing.T) {
+	result := Export(&Command{Defaults: &pointerOptions{}})
+
+	assert.Equal(t, "", result.Flags[0].Type)
+	assert.Equal(t, "int", result.Flags[1].Type)
+}
+
+func TestExportArity(t *testing.T) {
+	result := Export(&Command{Defaults: &variadicOptions{}})
+
+	assert.Equal(t, []*ArgSpec{
+		{Name: "DEST", Type: "string"},
+		{Name: "PORTS", Type: "[]int", Arity: "[1,4)"},
+	}, result.Args)
+}
+
+func TestExportCount(t *testing.T) {
+	result := Export(&Command{Defaults: &countOptions{}})
+
+	assert.Equal(t, []*FlagSpec{
+		{Name: "verbose", Short: "v", Type: "count"},
+		{Name: "quiet", Short: "q"},
+	}, result.Flags)
+}
+
+func TestExportChoices(t *testing.T) {
+	result := Export(&Command{Defaults: &choicesOptions{}})
+
+	assert.Equal(t, []string{"json", "yaml", "table"}, result.Flags[0].Choices)
+	assert.Equal(t, []string{"a", "b"}, result.Args[0].Choices)
+}
+
+func TestExportDeprecatedFlag(t *testing.T) {
+	result := Export(&Command{Defaults: &deprecatedOptions{}})
+
+	assert.Equal(t, []*FlagSpec{
+		{Name: "old", Short: "o", Type: "string", Deprecated: "use --new"},
+		{Name: "new", Type: "string"},
+	}, result.Flags)
+}
+
+func TestExportAliases(t *testing.T) {
+	type opts struct {
+		Color string `opt:"color|colour"`
+	}
+
+	result := Export(&Command{Defaults: &opts{}})
+
+	assert.Equal(t, []*FlagSpec{
+		{Name: "color", Aliases: []string{"colour"}, Type: "string"},
+	}, result.Flags)
+}
+
+func TestExportDefault(t *testing.T) {
+	empty := ""
+	result := Export(&Command{Defaults: &defaultOptions{Name: "name", Set: &empty}})
+
+	assert.Equal(t, `"name"`, *result.Flags[0].Default)
+	assert.Nil(t, result.Flags[1].Default)
+	assert.Equal(t, `""`, *result.Flags[6].Default)
+}
+
+func TestExportOptional(t *testing.T) {
+	result := Export(&Command{Defaults: &struct {
+		Color string `opt:"color" optional:"auto"`
+	}{}})
+
+	assert.Equal(t, "auto", *result.Flags[0].Optional)
+}
+
+func TestCommandSpecJSON(t *testing.T) {
+	obj := &CommandSpec{
+		Summary: "Root command",
+		Subcommands: []*CommandSpec{
+			{
+				Name:   "sub",
+				Hidden: true,
+			},
+		},
+	}
+
+	result, err := obj.JSON()
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"","summary":"Root command","subcommands":[{"name":"sub","hidden":true}]}`, string(result))
+}
+
+func TestCommandSpecYAML(t *testing.T) {
+	obj := &CommandSpec{
+		Summary: "Root command",
+		Subcommands: []*CommandSpec{
+			{
+				Name:   "sub",
+				Hidden: true,
+			},
+		},
+	}
+
+	result, err := obj.YAML()
+
+	assert.NoError(t, err)
+	assert.YAMLEq(t, "name: \"\"\nsummary: Root command\nsubcommands:\n- name: sub\n  hidden: true\n", string(result))
+}
+
+func TestReadSpecJSON(t *testing.T) {
+	result, err := ReadSpec(strings.NewReader(`{"name":"","summary":"Root command","flags":[{"name":"flag"}],"args":[{"name":"arg","arity":"[1]"}],"subcommands":[{"name":"sub","hidden":true}]}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &CommandSpec{
+		Summary: "Root command",
+		Flags: []*FlagSpec{
+			{Name: "flag"},
+		},
+		Args: []*ArgSpec{
+			{Name: "arg", Arity: "[1]"},
+		},
+		Subcommands: []*CommandSpec{
+			{
+				Name:   "sub",
+				Hidden: true,
+			},
+		},
+	}, result)
+}
+
+func TestReadSpecYAML(t *testing.T) {
+	result, err := ReadSpec(strings.NewReader("name: \"\"\nsummary: Root command\nsubcommands:\n- name: sub\n  hidden: true\n"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &CommandSpec{
+		Summary: "Root command",
+		Subcommands: []*CommandSpec{
+			{
+				Name:   "sub",
+				Hidden: true,
+			},
+		},
+	}, result)
+}
+
+func TestReadSpecError(t *testing.T) {
+	result, err := ReadSpec(strings.NewReader("name: [\n"))
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+)
+
+// Flag kinds that open files, selected with KindTag.  The flag's
+// value is a path, or "-" for the App's standard input or output;
+// the file is opened once the command line has been parsed, and
+// closed once the command has run.  The flag's field must be an
+// *os.File, or an io.Reader or io.ReadCloser for KindInput, or an
+// io.Writer or io.WriteCloser for KindOutput.  An *os.File field
+// accepts "-" only if the corresponding stream is an *os.File.
+const (
+	KindInput  = "input"  // Flag opens a file for reading
+	KindOutput = "output" // Flag creates or truncates a file for writing
+)
+
+// fileTypes lists the field types allowed for each file kind.
+var fileTypes = map[string][]reflect.Type{
+	KindInput: {
+		reflect.TypeOf((*os.File)(nil)),
+		reflect.TypeOf((*io.Reader)(nil)).Elem(),
+		reflect.TypeOf((*io.ReadCloser)(nil)).Elem(),
+	},
+	KindOutput: {
+		reflect.TypeOf((*os.File)(nil)),
+		reflect.TypeOf((*io.Writer)(nil)).Elem(),
+		reflect.TypeOf((*io.WriteCloser)(nil)).Elem(),
+	},
+}
+
+// isFileKind is a helper that determines whether a flag kind opens a
+// file.
+func isFileKind(kind string) bool {
+	_, ok := fileTypes[kind]
+	return ok
+}
+
+// checkFileKind checks that a field has a type allowed for a file
+// kind.
+func checkFileKind(kind string, typ reflect.Type) bool {
+	for _, allowed := range fileTypes[kind] {
+		if typ == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nopWriteCloser wraps an io.Writer with a Close method that does
+// nothing, so that the App's standard output is not closed.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// openFiles opens the files named by file-kind flags of the options,
+// storing them in the flags' fields.  The files are added to the
+// invocation, to be closed once the command has run.
+func (a *App) openFiles(inv *Invocation, o *options) error {
+	if o == nil {
+		return nil
+	}
+
+	for _, opt := range o.Set.Options {
+		path, ok := o.paths[opt]
+		if !ok {
+			continue
+		}
+
+		f, err := a.openFile(opt, path)
+		if err != nil {
+			return err
+		}
+		if f, ok := f.(*os.File); ok && path != "-" {
+			inv.closers = append(inv.closers, f)
+		}
+		o.Field(opt.Index).Set(reflect.ValueOf(f))
+	}
+
+	return nil
+}
+
+// openFile opens the file for a file-kind flag.  The result is
+// suitable for storing in the flag's field.
+func (a *App) openFile(opt *option, path string) (interface{}, error) {
+	if path != "-" {
+		if opt.Kind == KindOutput {
+			return os.Create(path)
+		}
+		return os.Open(path)
+	}
+
+	// Select the standard stream
+	var stream interface{} = ioutil.NopCloser(a.stdin())
+	if opt.Kind == KindOutput {
+		stream = nopWriteCloser{a.stdout()}
+	}
+	if opt.Type != fileTypes[opt.Kind][0] {
+		return stream, nil
+	}
+
+	// The field is an *os.File
+	f, ok := a.stdin().(*os.File)
+	if opt.Kind == KindOutput {
+		f, ok = a.stdout().(*os.File)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w %q for %s: standard stream is not a file", ErrInvalidValue, path, opt)
+	}
+
+	return f, nil
+}
+
+// closeFiles closes the files opened for the invocation, returning
+// the first error encountered.
+func (inv *Invocation) closeFiles() error {
+	var result error
+	for _, c := range inv.closers {
+		if err := c.Close(); err != nil && result == nil {
+			result = err
+		}
+	}
+	inv.closers = nil
+
+	return result
+}
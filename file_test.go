@@ -0,0 +1,290 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fileOptions struct {
+	File   *os.File  `opt:"file" kind:"input"`
+	Reader io.Reader `opt:"reader" kind:"input"`
+	Writer io.Writer `opt:"writer" kind:"output"`
+}
+
+type inheritFileOptions struct {
+	Reader io.Reader `inherit:""`
+	Writer io.Writer `opt:"writer" kind:"output"`
+}
+
+type inheritFileCommand struct {
+	Command
+}
+
+func (c *inheritFileCommand) Run(opts *inheritFileOptions) error {
+	_, err := io.Copy(opts.Writer, opts.Reader)
+	return err
+}
+
+type fileCommand struct {
+	Command
+	data string
+}
+
+func (c *fileCommand) Run(opts *fileOptions) error {
+	data, err := ioutil.ReadAll(opts.Reader)
+	if err != nil {
+		return err
+	}
+	c.data = string(data)
+	_, err = opts.Writer.Write(data)
+	return err
+}
+
+type errCloser struct{}
+
+func (errCloser) Close() error {
+	return assert.AnError
+}
+
+func TestNewOptionSetFileKind(t *testing.T) {
+	type opts struct {
+		In  io.ReadCloser `opt:"in" kind:"input"`
+		Out *os.File      `opt:"out" kind:"output"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.NoError(t, err)
+	assert.True(t, result.Options[0].TakesValue())
+	assert.True(t, result.Options[1].TakesValue())
+}
+
+func TestNewOptionSetFileKindBadType(t *testing.T) {
+	type opts struct {
+		Out io.Reader `opt:"out" kind:"output"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.EqualError(t, err, "invalid options declaration: field Out: output flag cannot be a io.Reader")
+	assert.Nil(t, result)
+}
+
+func TestIsFileKind(t *testing.T) {
+	assert.True(t, isFileKind(KindInput))
+	assert.True(t, isFileKind(KindOutput))
+	assert.False(t, isFileKind(KindCount))
+	assert.False(t, isFileKind(""))
+}
+
+func TestNopWriteCloser(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := nopWriteCloser{buf}
+
+	_, _ = obj.Write([]byte("text"))
+	err := obj.Close()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "text", buf.String())
+}
+
+func TestOptionsStoreFile(t *testing.T) {
+	type opts struct {
+		In io.Reader `opt:"in" kind:"input"`
+	}
+	obj, _ := newOptions(&Command{Defaults: &opts{}})
+
+	err := obj.store(obj.Set.Options[0], "path")
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[*option]string{obj.Set.Options[0]: "path"}, obj.paths)
+	assert.Nil(t, obj.Value.Interface().(*opts).In)
+}
+
+func TestAppOpenFilesBase(t *testing.T) {
+	path := makeArgFile(t, "data")
+	out := filepath.Join(t.TempDir(), "out")
+	type opts struct {
+		In   io.ReadCloser  `opt:"in" kind:"input"`
+		File *os.File       `opt:"file" kind:"output"`
+		Out  io.WriteCloser `opt:"out" kind:"output"`
+	}
+	o, _ := newOptions(&Command{Defaults: &opts{}})
+	_, _, _ = o.parse([]string{"--in", path, "--file", out, "--out", "-"}, nil)
+	inv := &Invocation{}
+	stdout := &bytes.Buffer{}
+	obj := &App{Stdout: stdout}
+
+	err := obj.openFiles(inv, o)
+
+	assert.NoError(t, err)
+	assert.Len(t, inv.closers, 2)
+	value := o.Value.Interface().(*opts)
+	data, _ := ioutil.ReadAll(value.In)
+	assert.Equal(t, "data", string(data))
+	assert.Equal(t, out, value.File.Name())
+	assert.Equal(t, nopWriteCloser{stdout}, value.Out)
+	assert.NoError(t, inv.closeFiles())
+	assert.Nil(t, inv.closers)
+}
+
+func TestAppOpenFilesNil(t *testing.T) {
+	obj := &App{}
+
+	err := obj.openFiles(&Invocation{}, nil)
+
+	assert.NoError(t, err)
+}
+
+func TestAppOpenFilesError(t *testing.T) {
+	o, _ := newOptions(&Command{Defaults: &fileOptions{}})
+	_, _, _ = o.parse([]string{"--reader", filepath.Join(t.TempDir(), "missing")}, nil)
+	obj := &App{}
+
+	err := obj.openFiles(&Invocation{}, o)
+
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestAppOpenFileStdin(t *testing.T) {
+	stdin := &bytes.Buffer{}
+	obj := &App{Stdin: stdin}
+	opt := &option{Name: "in", Kind: KindInput, Type: reflect.TypeOf((*io.Reader)(nil)).Elem()}
+
+	result, err := obj.openFile(opt, "-")
+
+	assert.NoError(t, err)
+	assert.Equal(t, ioutil.NopCloser(stdin), result)
+}
+
+func TestAppOpenFileStdinFile(t *testing.T) {
+	obj := &App{Stdin: os.Stdin}
+	opt := &option{Name: "in", Kind: KindInput, Type: reflect.TypeOf((*os.File)(nil))}
+
+	result, err := obj.openFile(opt, "-")
+
+	assert.NoError(t, err)
+	assert.Same(t, os.Stdin, result)
+}
+
+func TestAppOpenFileStdoutFile(t *testing.T) {
+	obj := &App{Stdout: os.Stdout}
+	opt := &option{Name: "out", Kind: KindOutput, Type: reflect.TypeOf((*os.File)(nil))}
+
+	result, err := obj.openFile(opt, "-")
+
+	assert.NoError(t, err)
+	assert.Same(t, os.Stdout, result)
+}
+
+func TestAppOpenFileStdinNotFile(t *testing.T) {
+	obj := &App{Stdin: &bytes.Buffer{}}
+	opt := &option{Name: "in", Kind: KindInput, Type: reflect.TypeOf((*os.File)(nil))}
+
+	result, err := obj.openFile(opt, "-")
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.EqualError(t, err, `invalid value "-" for --in: standard stream is not a file`)
+	assert.Nil(t, result)
+}
+
+func TestInvocationCloseFilesError(t *testing.T) {
+	obj := &Invocation{closers: []io.Closer{errCloser{}, errCloser{}}}
+
+	err := obj.closeFiles()
+
+	assert.Same(t, assert.AnError, err)
+	assert.Nil(t, obj.closers)
+}
+
+func TestAppDispatchFiles(t *testing.T) {
+	in := makeArgFile(t, "data")
+	out := filepath.Join(t.TempDir(), "out")
+	root := &fileCommand{
+		Command: Command{Defaults: &fileOptions{}},
+	}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--reader", in, "--writer", out})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "data", root.data)
+	data, _ := ioutil.ReadFile(out)
+	assert.Equal(t, "data", string(data))
+}
+
+func TestAppDispatchFilesStreams(t *testing.T) {
+	root := &fileCommand{
+		Command: Command{Defaults: &fileOptions{}},
+	}
+	stdout := &bytes.Buffer{}
+	obj := &App{
+		Root:   root,
+		Stdin:  bytes.NewBufferString("data"),
+		Stdout: stdout,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--reader", "-", "--writer", "-"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "data", stdout.String())
+}
+
+func TestAppDispatchFilesInherited(t *testing.T) {
+	in := makeArgFile(t, "data")
+	sub := &inheritFileCommand{
+		Command: Command{Defaults: &inheritFileOptions{}},
+	}
+	root := &Command{
+		Defaults:    &fileOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	stdout := &bytes.Buffer{}
+	obj := &App{
+		Root:   root,
+		Stdout: stdout,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--reader", in, "sub", "--writer", "-"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "data", stdout.String())
+}
+
+func TestAppDispatchFilesError(t *testing.T) {
+	root := &fileCommand{
+		Command: Command{Defaults: &fileOptions{}},
+	}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--reader", filepath.Join(t.TempDir(), "missing")})
+
+	assert.True(t, os.IsNotExist(err))
+}
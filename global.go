@@ -0,0 +1,148 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// WithGlobals registers structs declaring application-global flags,
+// such as "--log-level" or "--no-color", which are recognized
+// anywhere preceding "--" on the command line, regardless of the
+// command.  Each struct is declared like a command's defaults and
+// copied in the same way; the populated copies are available from
+// the injector, both to commands and to the functions that construct
+// dynamic subcommands.  Global flags are parsed before the command
+// is resolved, and take precedence over any command flags of the
+// same name.  A global short flag may be combined only with other
+// short flags from the same struct.  Global flags are listed in the
+// help of the root command, and in the help of every command
+// requested with HelpAllFlag.  Returns the App, to allow chaining.
+func (a *App) WithGlobals(globals ...interface{}) *App {
+	a.Globals = append(a.Globals, globals...)
+	return a
+}
+
+// newGlobals constructs the options for the application-global
+// flags, populated from the configuration and the environment.
+// Global flags are bound to the top level of the configuration file
+// and to environment variables without a command path.  The
+// configDir is the base of relative paths for KindPath flags; see
+// pathBase.
+func (a *App) newGlobals(cfg map[string]interface{}, configDir string) ([]*options, error) {
+	var globals []*options
+	long := map[string]bool{}
+	short := map[string]bool{}
+	for _, obj := range a.Globals {
+		opts, err := optionsFor(obj, true)
+		if err != nil {
+			return nil, err
+		} else if opts == nil {
+			continue
+		}
+
+		// Check for flags declared by more than one struct
+		for _, opt := range opts.Set.Options {
+			for _, name := range append([]string{opt.Name}, opt.Aliases...) {
+				if name == "" {
+					continue
+				} else if long[name] {
+					return nil, fmt.Errorf("%w: duplicate global flag --%s", ErrBadOptions, name)
+				}
+				long[name] = true
+			}
+			if opt.Short == "" {
+				continue
+			}
+			if short[opt.Short] {
+				return nil, fmt.Errorf("%w: duplicate global flag -%s", ErrBadOptions, opt.Short)
+			}
+			short[opt.Short] = true
+		}
+
+		opts.insensitive = a.InsensitiveFlags
+		opts.configDir = configDir
+		if err := opts.applyConfig(cfg, nil); err != nil {
+			return nil, err
+		}
+		if err := opts.applyEnv(a.EnvPrefix, nil); err != nil {
+			return nil, err
+		}
+		opts.locale = a.localeFormat()
+		globals = append(globals, opts)
+	}
+
+	return globals, nil
+}
+
+// globalFor is a helper that returns the global options declaring
+// the flag in the specified argument, or nil if the argument is not
+// a global flag.  For short flags, only the first flag in the
+// argument is considered.
+func globalFor(globals []*options, arg string) *options {
+	for _, opts := range globals {
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			name := strings.SplitN(arg[2:], "=", 2)[0]
+			if _, ok := opts.lookupLong(name); ok {
+				return opts
+			}
+
+		case len(arg) > 1 && arg[0] == '-':
+			r, _ := utf8.DecodeRuneInString(arg[1:])
+			if _, ok := opts.Set.short[string(r)]; ok {
+				return opts
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseGlobals removes the global flags, and their values, from the
+// arguments preceding any "--", setting them in the global options.
+// Returns the remaining arguments.
+func parseGlobals(globals []*options, args []string) ([]string, error) {
+	result := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+
+		// Check for a global flag
+		opts := globalFor(globals, arg)
+		if opts == nil {
+			result = append(result, arg)
+			continue
+		}
+
+		// Handle the flag
+		var err error
+		if strings.HasPrefix(arg, "--") {
+			i, err = opts.parseLong(args, i)
+		} else {
+			i, err = opts.parseShort(args, i)
+		}
+		if err != nil {
+			return args, err
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,434 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type globalOptions struct {
+	LogLevel string `opt:"log-level,l" help:"Logging level" choices:"debug,info,warn"`
+	NoColor  bool   `opt:"no-color" help:"Disable color"`
+}
+
+type moreGlobalOptions struct {
+	Profile string `opt:"profile" deprecated:"use --config"`
+	Quiet   bool   `opt:"quiet,q" help:"Suppress output"`
+}
+
+type globalCommand struct {
+	Command
+	globals *globalOptions
+	opts    *subOptions
+}
+
+func (c *globalCommand) Run(globals *globalOptions, opts *subOptions) {
+	c.globals = globals
+	c.opts = opts
+}
+
+// globalsFor is a helper that constructs the global options for the
+// specified structs.
+func globalsFor(t *testing.T, objs ...interface{}) []*options {
+	t.Helper()
+
+	globals, err := (&App{Globals: objs}).newGlobals(nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return globals
+}
+
+func TestAppWithGlobals(t *testing.T) {
+	global1 := &globalOptions{}
+	global2 := &moreGlobalOptions{}
+	obj := &App{}
+
+	result := obj.WithGlobals(global1).WithGlobals(global2)
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, []interface{}{global1, global2}, obj.Globals)
+}
+
+func TestAppNewGlobalsBase(t *testing.T) {
+	obj := &App{
+		Globals: []interface{}{&globalOptions{LogLevel: "info"}, nil, moreGlobalOptions{}},
+	}
+
+	result, err := obj.newGlobals(nil, "")
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, &globalOptions{LogLevel: "info"}, result[0].Value.Interface())
+	assert.Equal(t, &moreGlobalOptions{}, result[1].Value.Interface())
+}
+
+func TestAppNewGlobalsNone(t *testing.T) {
+	obj := &App{}
+
+	result, err := obj.newGlobals(nil, "")
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestAppNewGlobalsBadStruct(t *testing.T) {
+	obj := &App{
+		Globals: []interface{}{"bogus"},
+	}
+
+	result, err := obj.newGlobals(nil, "")
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestAppNewGlobalsDuplicateLong(t *testing.T) {
+	obj := &App{
+		Globals: []interface{}{&globalOptions{}, &struct {
+			Color bool `opt:"color|no-color"`
+		}{}},
+	}
+
+	result, err := obj.newGlobals(nil, "")
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.EqualError(t, err, "invalid options declaration: duplicate global flag --no-color")
+	assert.Nil(t, result)
+}
+
+func TestAppNewGlobalsDuplicateShort(t *testing.T) {
+	obj := &App{
+		Globals: []interface{}{&globalOptions{}, &struct {
+			Log string `opt:"log,l"`
+		}{}},
+	}
+
+	result, err := obj.newGlobals(nil, "")
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.EqualError(t, err, "invalid options declaration: duplicate global flag -l")
+	assert.Nil(t, result)
+}
+
+func TestAppNewGlobalsSources(t *testing.T) {
+	setEnv(t, "TOOL_NO_COLOR", "true")
+	obj := &App{
+		Globals:   []interface{}{&globalOptions{}},
+		EnvPrefix: "tool",
+	}
+
+	result, err := obj.newGlobals(map[string]interface{}{"log-level": "warn"}, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &globalOptions{LogLevel: "warn", NoColor: true}, result[0].Value.Interface())
+}
+
+func TestAppNewGlobalsBadConfig(t *testing.T) {
+	obj := &App{
+		Globals: []interface{}{&globalOptions{}},
+	}
+
+	result, err := obj.newGlobals(map[string]interface{}{"log-level": "bogus"}, "")
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.Nil(t, result)
+}
+
+func TestAppNewGlobalsBadEnv(t *testing.T) {
+	setEnv(t, "TOOL_LOG_LEVEL", "bogus")
+	obj := &App{
+		Globals:   []interface{}{&globalOptions{}},
+		EnvPrefix: "tool",
+	}
+
+	result, err := obj.newGlobals(nil, "")
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.Nil(t, result)
+}
+
+func TestGlobalForLong(t *testing.T) {
+	globals := globalsFor(t, &globalOptions{}, &moreGlobalOptions{})
+
+	assert.Same(t, globals[0], globalFor(globals, "--log-level"))
+	assert.Same(t, globals[0], globalFor(globals, "--log-level=info"))
+	assert.Same(t, globals[1], globalFor(globals, "--quiet"))
+	assert.Nil(t, globalFor(globals, "--verbose"))
+}
+
+func TestGlobalForInsensitive(t *testing.T) {
+	globals, _ := (&App{
+		Globals:          []interface{}{&globalOptions{}},
+		InsensitiveFlags: true,
+	}).newGlobals(nil, "")
+
+	assert.Same(t, globals[0], globalFor(globals, "--LOG_LEVEL=info"))
+}
+
+func TestGlobalForShort(t *testing.T) {
+	globals := globalsFor(t, &globalOptions{}, &moreGlobalOptions{})
+
+	assert.Same(t, globals[0], globalFor(globals, "-l"))
+	assert.Same(t, globals[0], globalFor(globals, "-linfo"))
+	assert.Same(t, globals[1], globalFor(globals, "-q"))
+	assert.Nil(t, globalFor(globals, "-v"))
+	assert.Nil(t, globalFor(globals, "-vq"))
+}
+
+func TestGlobalForPositional(t *testing.T) {
+	globals := globalsFor(t, &globalOptions{})
+
+	assert.Nil(t, globalFor(globals, "-"))
+	assert.Nil(t, globalFor(globals, "log-level"))
+}
+
+func TestParseGlobalsBase(t *testing.T) {
+	globals := globalsFor(t, &globalOptions{}, &moreGlobalOptions{})
+
+	result, err := parseGlobals(globals, []string{
+		"sub", "--log-level", "debug", "-v", "--no-color", "file", "-q", "--", "--quiet",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sub", "-v", "file", "--", "--quiet"}, result)
+	assert.Equal(t, &globalOptions{LogLevel: "debug", NoColor: true}, globals[0].Value.Interface())
+	assert.Equal(t, &moreGlobalOptions{Quiet: true}, globals[1].Value.Interface())
+	src, _ := globals[0].source("--log-level")
+	assert.Equal(t, SourceFlag, src)
+}
+
+func TestParseGlobalsShort(t *testing.T) {
+	globals := globalsFor(t, &globalOptions{})
+
+	result, err := parseGlobals(globals, []string{"-lwarn", "arg"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"arg"}, result)
+	assert.Equal(t, &globalOptions{LogLevel: "warn"}, globals[0].Value.Interface())
+}
+
+func TestParseGlobalsNone(t *testing.T) {
+	result, err := parseGlobals(nil, []string{"--verbose", "arg"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--verbose", "arg"}, result)
+}
+
+func TestParseGlobalsLongError(t *testing.T) {
+	globals := globalsFor(t, &globalOptions{})
+	args := []string{"arg", "--log-level"}
+
+	result, err := parseGlobals(globals, args)
+
+	assert.ErrorIs(t, err, ErrMissingValue)
+	assert.Equal(t, args, result)
+}
+
+func TestParseGlobalsShortError(t *testing.T) {
+	globals := globalsFor(t, &globalOptions{})
+	args := []string{"-l", "bogus"}
+
+	result, err := parseGlobals(globals, args)
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.Equal(t, args, result)
+}
+
+func TestAppDispatchGlobals(t *testing.T) {
+	sub := &globalCommand{
+		Command: Command{Defaults: &subOptions{}},
+	}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	global := &globalOptions{LogLevel: "info"}
+	obj := &App{
+		Root:    root,
+		Globals: []interface{}{global},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--no-color", "sub", "--name", "n", "-l", "debug", "file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &globalOptions{LogLevel: "debug", NoColor: true}, sub.globals)
+	assert.Equal(t, &subOptions{Name: "n", File: "file"}, sub.opts)
+	assert.Equal(t, &globalOptions{LogLevel: "info"}, global)
+}
+
+func TestAppDispatchGlobalsSource(t *testing.T) {
+	var inv *Invocation
+	root := newRunCommand("Root", nil)
+	root.Defaults = &RootOptions{}
+	root.On("Run", Args{}, mock.Anything).Return(nil)
+	obj := &App{
+		Root:    root,
+		Globals: []interface{}{&globalOptions{}},
+	}
+	obj.OnCommandResolved(func(i *Invocation) {
+		inv = i
+	})
+
+	err := obj.Dispatch(context.Background(), []string{"--no-color", "--verbose"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, SourceFlag, inv.Source("--no-color"))
+	assert.Equal(t, SourceFlag, inv.Source("--verbose"))
+	assert.Equal(t, SourceDefault, inv.Source("--log-level"))
+}
+
+func TestAppDispatchGlobalsWarning(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	root := newRunCommand("Root", nil)
+	root.On("Run", Args{}, mock.Anything).Return(nil)
+	obj := &App{
+		Name:    "app",
+		Root:    root,
+		Globals: []interface{}{&moreGlobalOptions{}},
+		Stderr:  stderr,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--profile", "prod"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, stderr.String(), "flag --profile is deprecated: use --config")
+}
+
+func TestAppDispatchGlobalsBad(t *testing.T) {
+	obj := &App{
+		Root:    newRunCommand("Root", nil),
+		Globals: []interface{}{"bogus"},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+}
+
+func TestAppDispatchGlobalsParseError(t *testing.T) {
+	obj := &App{
+		Root:    newRunCommand("Root", nil),
+		Globals: []interface{}{&globalOptions{}},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--log-level", "bogus"})
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	code, usage := ExitControl(err)
+	assert.Equal(t, 2, code)
+	assert.True(t, usage)
+}
+
+func TestAppDispatchGlobalsFileError(t *testing.T) {
+	obj := &App{
+		Root: newRunCommand("Root", nil),
+		Globals: []interface{}{&struct {
+			Log io.Writer `opt:"log" kind:"output"`
+		}{}},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--log", t.TempDir()})
+
+	assert.Error(t, err)
+}
+
+func TestAppDispatchGlobalsSecretError(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	obj := &App{
+		Root:  newRunCommand("Root", nil),
+		Stdin: os.Stdin,
+		Globals: []interface{}{&struct {
+			Token string `opt:"token" kind:"secret"`
+		}{}},
+		SecretPrompter: func(prompt string) (string, error) {
+			return "", assert.AnError
+		},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.Same(t, assert.AnError, err)
+}
+
+func TestAppDispatchGlobalsValidation(t *testing.T) {
+	obj := &App{
+		Root: newRunCommand("Root", nil),
+		Globals: []interface{}{&struct {
+			Name string `opt:"name" match:"^[a-z]+$"`
+		}{}},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--name", "BAD"})
+
+	assert.ErrorIs(t, err, ErrValidation)
+	_, usage := ExitControl(err)
+	assert.True(t, usage)
+}
+
+func TestAppHelpGlobals(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{
+		Globals: []interface{}{&globalOptions{LogLevel: "info"}, "bogus", &moreGlobalOptions{}},
+	}
+	inv := &Invocation{
+		Path: []string{"app"},
+		Command: &Command{Defaults: &struct {
+			Verbose bool `opt:"verbose,v" help:"Verbose output"`
+		}{}},
+	}
+
+	obj.help(buf, inv)
+
+	assert.Equal(t, `Usage: app [flags] [ARGS...]
+
+Flags:
+  -v, --verbose  Verbose output
+
+Global flags:
+  -l, --log-level  Logging level (default "info")
+      --no-color   Disable color
+  -q, --quiet      Suppress output
+`, buf.String())
+}
+
+func TestAppDispatchHelpGlobals(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout).WithGlobals(&globalOptions{})
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "--help"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Usage: app sub [flags] [ARGS...]\n")
+	assert.NotContains(t, stdout.String(), "Global flags:")
+}
+
+func TestAppDispatchHelpAllGlobals(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout).WithGlobals(&globalOptions{})
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "--help-all"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Global flags:\n  -l, --log-level  Logging level\n")
+}
@@ -2,4 +2,7 @@ module github.com/klmitch/nelson
 
 go 1.15
 
-require github.com/stretchr/testify v1.7.0
+require (
+	github.com/stretchr/testify v1.7.0
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
+)
@@ -0,0 +1,208 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// HelpCommand is the name of the subcommand that displays help for
+// other commands, as in "tool help remote add".  It is added to the
+// root command if the root has subcommands and does not declare its
+// own HelpCommand.
+const HelpCommand = "help"
+
+// Flags that request help for a command, unless the command declares
+// flags with the same names.  If the command has no defaults, the
+// flags are only recognized before any positional arguments.  The
+// HelpAllFlag also includes hidden commands and flags in the help,
+// along with the application-global flags inherited by subcommands.
+const (
+	HelpFlag      = "--help"
+	HelpShortFlag = "-h"
+	HelpAllFlag   = "--help-all"
+)
+
+// ErrHelp indicates that help was requested with HelpFlag or
+// HelpShortFlag.  It is handled by the dispatcher, which displays
+// the help and exits successfully.
+var ErrHelp = errors.New("help requested")
+
+// ErrHelpAll indicates that help including hidden commands and
+// flags was requested with HelpAllFlag.  It wraps ErrHelp.
+var ErrHelpAll = fmt.Errorf("%w: all", ErrHelp)
+
+// helpCommand implements the HelpCommand.
+type helpCommand struct {
+	Command
+	app *App      // The application
+	inj *Injector // Injector used to compute dynamic subcommands
+}
+
+// newHelpCommand constructs the HelpCommand for the application.  Its
+// summary and description are translated by the application's
+// catalogs; see WithCatalog.
+func newHelpCommand(a *App, inj *Injector) *helpCommand {
+	cmd := &helpCommand{
+		Command: Command{
+			Summary:      "Show help for a command",
+			Description:  "Show help for the command named by the arguments, or for the application if there are none.",
+			Translations: map[string]Translation{},
+		},
+		app: a,
+		inj: inj,
+	}
+	for lang, catalog := range a.Catalogs {
+		cmd.Translations[lang] = Translation{
+			Summary:     catalog[cmd.Summary],
+			Description: catalog[cmd.Description],
+		}
+	}
+
+	return cmd
+}
+
+// Run displays the help for the command named by the arguments.  If
+// a single argument names a help topic rather than a command, the
+// topic is displayed instead; see WithTopic.
+func (c *helpCommand) Run(args Args, streams *IOStreams, current *Invocation) error {
+	inv, err := c.app.helpPath(c.inj, args)
+	if err != nil {
+		if topic, ok := c.app.Topics[args[0]]; ok && len(args) == 1 {
+			return c.app.topic(streams.help(), topic)
+		}
+		return err
+	}
+	inv.noColor = current.noColor
+	inv.helpAll = current.helpAll
+
+	return c.app.help(streams.help(), inv)
+}
+
+// isHelp is a helper that determines whether an argument requests
+// help.  The flags are not recognized if the options declare them;
+// if the options are nil, they are not recognized once positional
+// arguments have been seen, since these may be passed on to other
+// programs.
+func (o *options) isHelp(arg string, positional []string) bool {
+	if arg != HelpFlag && arg != HelpShortFlag && arg != HelpAllFlag {
+		return false
+	}
+	if o == nil {
+		return len(positional) == 0
+	}
+
+	for _, opt := range o.Set.Options {
+		if opt.Matches(arg) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// withHelp adds the HelpCommand to the subcommands of the root
+// command, unless the root has no subcommands or declares its own.
+// The subcommands are copied rather than modified.
+func (a *App) withHelp(subs map[string]ICommand, inj *Injector) map[string]ICommand {
+	if _, ok := subs[HelpCommand]; ok || len(subs) == 0 {
+		return subs
+	}
+
+	return withBuiltin(subs, map[string]ICommand{HelpCommand: newHelpCommand(a, inj)})
+}
+
+// withBuiltins adds the built-in subcommands to the subcommands of
+// the root command: the HelpCommand, SpecCommand, LintCommand, and
+// CompleteCommand, and the CommandsCommand and ShellAliasCommand if
+// the application allows them.  Built-ins the root declares itself
+// are not replaced.
+func (a *App) withBuiltins(subs map[string]ICommand, inj *Injector) map[string]ICommand {
+	if len(subs) == 0 {
+		return subs
+	}
+
+	builtins := map[string]ICommand{
+		HelpCommand:     newHelpCommand(a, inj),
+		SpecCommand:     a.newSpecCommand(),
+		LintCommand:     a.newLintCommand(),
+		CompleteCommand: a.newCompleteCommand(inj),
+	}
+	if a.AllowCommands {
+		builtins[CommandsCommand] = a.newCommandsCommand(inj)
+	}
+	if a.AllowShellAliases {
+		builtins[ShellAliasCommand] = a.newShellAliasCommand(inj)
+	}
+
+	return withBuiltin(subs, builtins)
+}
+
+// withBuiltin is a helper that copies the subcommands over the
+// built-in subcommands, so that the subcommands take precedence.
+// The subcommands are copied only once, which matters for large
+// command trees.
+func withBuiltin(subs, builtins map[string]ICommand) map[string]ICommand {
+	result := make(map[string]ICommand, len(subs)+len(builtins))
+	for name, cmd := range builtins {
+		result[name] = cmd
+	}
+	for name, sub := range subs {
+		result[name] = sub
+	}
+
+	return result
+}
+
+// helpPath is a helper that resolves the invocation describing the
+// command named by a path of subcommand names, for use with help and
+// Invoke.  No arguments are parsed.
+func (a *App) helpPath(inj *Injector, names []string) (*Invocation, error) {
+	inv := &Invocation{
+		Path:     []string{a.name()},
+		Commands: []ICommand{a.Root},
+		Command:  a.Root,
+		inj:      inj,
+	}
+
+	for _, name := range names {
+		subs, err := resolveSubcommands(inv.Command, inj)
+		if err != nil {
+			return nil, err
+		}
+		if len(inv.Path) == 1 {
+			subs = a.withBuiltins(subs, inj)
+		}
+		sub, ok := subs[name]
+		if !ok {
+			return nil, UnknownCommand(name)
+		}
+		inv.Path = append(inv.Path, name)
+		inv.Commands = append(inv.Commands, sub)
+		inv.Command = sub
+	}
+
+	return inv, nil
+}
+
+// help emits the full help for the invocation, rendered with the
+// help template; see WithTemplates.  By default, this is the
+// command's description--or its summary, if it has no
+// description--followed by its usage and examples.
+func (a *App) help(w io.Writer, inv *Invocation) error {
+	return a.render(w, "help", inv)
+}
@@ -0,0 +1,428 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type hostOptions struct {
+	Host string `opt:"host,h"`
+}
+
+type helpOptions struct {
+	Verbose bool   `opt:"verbose,v" help:"Verbose output"`
+	Name    string `opt:"name" help:"Name to use"`
+}
+
+// helpApp is a helper that constructs an application for testing
+// help.
+func helpApp(stdout *bytes.Buffer) *App {
+	sub := newRunCommand("A subcommand", nil)
+	sub.Description = "The full description\nof the subcommand.\n"
+	sub.Defaults = &helpOptions{}
+	root := &Command{
+		Summary: "The application",
+		Subcommands: map[string]ICommand{
+			"sub":  sub,
+			"host": &Command{Summary: "Uses -h", Defaults: &hostOptions{}},
+		},
+	}
+
+	return &App{
+		Name:   "app",
+		Root:   root,
+		Stdout: stdout,
+	}
+}
+
+func TestOptionsIsHelpBase(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	assert.True(t, obj.isHelp("--help", nil))
+	assert.True(t, obj.isHelp("-h", []string{"a1"}))
+	assert.True(t, obj.isHelp("--help-all", nil))
+	assert.False(t, obj.isHelp("--verbose", nil))
+	assert.False(t, obj.isHelp("help", nil))
+}
+
+func TestOptionsIsHelpDeclared(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &hostOptions{}})
+
+	assert.True(t, obj.isHelp("--help", nil))
+	assert.False(t, obj.isHelp("-h", nil))
+}
+
+func TestOptionsIsHelpNil(t *testing.T) {
+	var obj *options
+
+	assert.True(t, obj.isHelp("--help", []string{}))
+	assert.False(t, obj.isHelp("--help", []string{"plugin"}))
+}
+
+func TestOptionsParseHelp(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	positional, next, err := obj.parse([]string{"a1", "--help", "--bogus"}, nil)
+
+	assert.Same(t, ErrHelp, err)
+	assert.Equal(t, []string{"a1"}, positional)
+	assert.Equal(t, -1, next)
+}
+
+func TestOptionsParseHelpAll(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	positional, next, err := obj.parse([]string{"a1", "--help-all"}, nil)
+
+	assert.Same(t, ErrHelpAll, err)
+	assert.ErrorIs(t, err, ErrHelp)
+	assert.Equal(t, []string{"a1"}, positional)
+	assert.Equal(t, -1, next)
+}
+
+func TestOptionsParseHelpAfterDashes(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	positional, _, err := obj.parse([]string{"--", "--help"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--help"}, positional)
+}
+
+func TestAppWithHelpBase(t *testing.T) {
+	sub := &Command{}
+	subs := map[string]ICommand{"sub": sub}
+	obj := &App{}
+
+	result := obj.withHelp(subs, nil)
+
+	assert.Len(t, result, 2)
+	assert.Same(t, sub, result["sub"])
+	assert.IsType(t, &helpCommand{}, result[HelpCommand])
+	assert.Len(t, subs, 1)
+}
+
+func TestAppWithHelpNoSubcommands(t *testing.T) {
+	obj := &App{}
+
+	result := obj.withHelp(nil, nil)
+
+	assert.Nil(t, result)
+}
+
+func TestAppWithHelpDeclared(t *testing.T) {
+	help := &Command{}
+	subs := map[string]ICommand{HelpCommand: help}
+	obj := &App{}
+
+	result := obj.withHelp(subs, nil)
+
+	assert.Same(t, help, result[HelpCommand])
+}
+
+func TestAppHelpPathBase(t *testing.T) {
+	obj := helpApp(nil)
+
+	result, err := obj.helpPath(NewInjector(), []string{"sub"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app", "sub"}, result.Path)
+	assert.Same(t, obj.Root.GetSubcommands()["sub"], result.Command)
+	assert.Len(t, result.Commands, 2)
+}
+
+func TestAppHelpPathUnknown(t *testing.T) {
+	obj := helpApp(nil)
+
+	result, err := obj.helpPath(NewInjector(), []string{"bogus"})
+
+	assert.ErrorIs(t, err, ErrUnknownCommand)
+	assert.Nil(t, result)
+}
+
+func TestAppHelpPathDynamicError(t *testing.T) {
+	obj := &App{
+		Root: &Command{
+			DynamicSubcommands: func(subs Subcommands) error {
+				return assert.AnError
+			},
+		},
+	}
+
+	result, err := obj.helpPath(NewInjector(), []string{"sub"})
+
+	assert.Same(t, assert.AnError, err)
+	assert.Nil(t, result)
+}
+
+func TestAppHelpDescription(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{}
+	inv := &Invocation{
+		Path:    []string{"app", "sub"},
+		Command: &Command{Summary: "Summary", Description: "Description.\n"},
+	}
+
+	obj.help(buf, inv)
+
+	assert.Equal(t, "Description.\n\nUsage: app sub [ARGS...]\n", buf.String())
+}
+
+func TestAppHelpSummary(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{}
+	inv := &Invocation{
+		Path:    []string{"app", "sub"},
+		Command: &Command{Summary: "Summary"},
+	}
+
+	obj.help(buf, inv)
+
+	assert.Equal(t, "Summary\n\nUsage: app sub [ARGS...]\n", buf.String())
+}
+
+func TestAppHelpEmpty(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{}
+	inv := &Invocation{
+		Path:    []string{"app", "sub"},
+		Command: &Command{},
+	}
+
+	obj.help(buf, inv)
+
+	assert.Equal(t, "Usage: app sub [ARGS...]\n", buf.String())
+}
+
+func TestAppDispatchHelpFlag(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout)
+
+	err := obj.Dispatch(context.Background(), []string{"--help"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `The application
+
+Usage: app COMMAND [ARGS...]
+
+Available commands:
+  help  Show help for a command
+  host  Uses -h
+  sub   A subcommand
+`, stdout.String())
+}
+
+func TestAppDispatchHelpAll(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout)
+	obj.Root.GetSubcommands()["debug"] = Hidden(&Command{Summary: "Debugging tools"})
+
+	err := obj.Dispatch(context.Background(), []string{"--help-all"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `The application
+
+Usage: app COMMAND [ARGS...]
+
+Available commands:
+  __complete  Write the completion candidates for a command line
+  __lint      Check the command tree for problems
+  __spec      Write the specification of the command line interface
+  debug       Debugging tools
+  help        Show help for a command
+  host        Uses -h
+  sub         A subcommand
+`, stdout.String())
+}
+
+func TestAppDispatchHelpDynamic(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout)
+	obj.Root.(*Command).DynamicSubcommands = func(subs Subcommands) {
+		subs["plugin"] = &Command{Summary: "A dynamic subcommand"}
+	}
+
+	for _, args := range [][]string{{"--help"}, {"help"}} {
+		stdout.Reset()
+
+		err := obj.Dispatch(context.Background(), args)
+
+		assert.NoError(t, err)
+		assert.Contains(t, stdout.String(), "  plugin  A dynamic subcommand\n", args)
+	}
+}
+
+func TestAppDispatchHelpTemplate(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout)
+	obj.Version = "1.2.3"
+	obj.Root.(*Command).Summary = "The {{.AppName}} application"
+	obj.Root.(*Command).Description = "Manages {{.AppName}} {{.Version}}.\n"
+	obj.Root.(*Command).Subcommands["host"] = &Command{Summary: "Runs {{.CommandPath}}"}
+
+	err := obj.Dispatch(context.Background(), []string{"--help"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Manages app 1.2.3.\n")
+	assert.Contains(t, stdout.String(), "  host  Runs app host\n")
+	assert.NotContains(t, stdout.String(), "{{")
+}
+
+func TestAppDispatchHelpFlagSubcommand(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout)
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "--name", "n", "-h"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `The full description
+of the subcommand.
+
+Usage: app sub [flags] [ARGS...]
+
+Flags:
+  -v, --verbose  Verbose output
+      --name     Name to use
+`, stdout.String())
+}
+
+func TestAppDispatchHelpFlagDeclared(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout)
+
+	err := obj.Dispatch(context.Background(), []string{"host", "-h", "example.com"})
+
+	assert.ErrorIs(t, err, ErrMissingCommand)
+	assert.Equal(t, "", stdout.String())
+}
+
+func TestAppDispatchHelpCommand(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout)
+
+	err := obj.Dispatch(context.Background(), []string{"help", "sub"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "The full description\nof the subcommand.\n\nUsage: app sub [flags] [ARGS...]\n")
+}
+
+func TestAppDispatchHelpCommandBuiltin(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout).WithCommands(true)
+
+	for _, name := range []string{CommandsCommand, SpecCommand, HelpCommand} {
+		stdout.Reset()
+
+		err := obj.Dispatch(context.Background(), []string{"help", name})
+
+		assert.NoError(t, err, name)
+		assert.Contains(t, stdout.String(), "Usage: app "+name, name)
+	}
+}
+
+func TestAppDispatchHelpOut(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	help := &bytes.Buffer{}
+	obj := helpApp(stdout).WithStreams(&IOStreams{Help: help})
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "--help"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", stdout.String())
+	assert.Contains(t, help.String(), "Usage: app sub [flags] [ARGS...]\n")
+}
+
+func TestAppDispatchHelpCommandHelpOut(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	help := &bytes.Buffer{}
+	obj := helpApp(stdout).WithStreams(&IOStreams{Help: help})
+
+	err := obj.Dispatch(context.Background(), []string{"help", "sub"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", stdout.String())
+	assert.Contains(t, help.String(), "Usage: app sub [flags] [ARGS...]\n")
+}
+
+func TestAppDispatchHelpCommandUnknown(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout)
+
+	err := obj.Dispatch(context.Background(), []string{"help", "bogus"})
+
+	assert.ErrorIs(t, err, ErrUnknownCommand)
+	assert.Equal(t, "", stdout.String())
+}
+
+func TestAppDispatchHelpNoSubcommands(t *testing.T) {
+	var args Args
+	root := newRunCommand("Root", nil)
+	root.On("Run", Args{"help"}, mock.Anything).Return(nil).Run(func(a mock.Arguments) {
+		args = a.Get(0).(Args)
+	})
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"help"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Args{"help"}, args)
+}
+
+// largeApp is a helper that constructs an application with a large
+// command tree, for benchmarking.
+func largeApp() *App {
+	subs := map[string]ICommand{}
+	for i := 0; i < 500; i++ {
+		sub := newRunCommand(fmt.Sprintf("Subcommand %d", i), nil)
+		sub.Defaults = &helpOptions{}
+		subs[fmt.Sprintf("sub%03d", i)] = sub
+	}
+
+	return (&App{
+		Name:   "app",
+		Root:   &Command{Summary: "The application", Defaults: &hostOptions{}, Subcommands: subs},
+		Stdout: ioutil.Discard,
+	}).WithNoConfigSearch(true)
+}
+
+func BenchmarkAppDispatchHelpLargeTree(b *testing.B) {
+	obj := largeApp()
+
+	for i := 0; i < b.N; i++ {
+		if err := obj.Dispatch(context.Background(), []string{"--help"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAppDispatchSubcommandHelpLargeTree(b *testing.B) {
+	obj := largeApp()
+
+	for i := 0; i < b.N; i++ {
+		if err := obj.Dispatch(context.Background(), []string{"sub250", "--help"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
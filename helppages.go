@@ -0,0 +1,89 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"sort"
+)
+
+// HelpPage is the full help of a single command, as produced by
+// App.HelpPages.
+type HelpPage struct {
+	Path []string // The command path, beginning with the application name
+	Text string   // The rendered help
+}
+
+// HelpPages renders the full help of every command in the tree, in
+// tree order with subcommands sorted by name, for use in testing
+// help for regressions; see the nelsontest package.  The help is
+// rendered without color and wrapped to the App's Width, or to
+// DefaultWidth if it is not set, regardless of the terminal, so
+// that it is deterministic.  Aliases and the built-in subcommands
+// are omitted.
+func (a *App) HelpPages() ([]HelpPage, error) {
+	width := a.Width
+	if width <= 0 {
+		width = DefaultWidth
+	}
+
+	inv := &Invocation{
+		Path:     []string{a.name()},
+		Commands: []ICommand{a.Root},
+		Command:  a.Root,
+		noColor:  true,
+	}
+	return a.helpPages(a.newInjector(nil), inv, width, nil)
+}
+
+// helpPages is a helper that appends the help pages of the command
+// described by the invocation and its subcommands to the pages.
+func (a *App) helpPages(inj *Injector, inv *Invocation, width int, pages []HelpPage) ([]HelpPage, error) {
+	inv.inj = inj
+	buf := &bytes.Buffer{}
+	if err := a.renderWidth(buf, "help", inv, width); err != nil {
+		return nil, err
+	}
+	pages = append(pages, HelpPage{
+		Path: inv.Path,
+		Text: buf.String(),
+	})
+
+	// Recurse into the subcommands in a stable order
+	subs, err := resolveSubcommands(inv.Command, inj)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(subs))
+	for name, sub := range subs {
+		if !IsAlias(sub) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		subInv := &Invocation{
+			Path:     append(append([]string{}, inv.Path...), name),
+			Commands: append(append([]ICommand{}, inv.Commands...), subs[name]),
+			Command:  subs[name],
+			noColor:  true,
+		}
+		if pages, err = a.helpPages(inj, subInv, width, pages); err != nil {
+			return nil, err
+		}
+	}
+
+	return pages, nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppHelpPages(t *testing.T) {
+	setEnv(t, ColumnsEnv, "20")
+	obj := helpApp(&bytes.Buffer{})
+	obj.Root.GetSubcommands()["alias"] = Alias(obj.Root.GetSubcommands()["sub"])
+
+	result, err := obj.HelpPages()
+
+	assert.NoError(t, err)
+	paths := [][]string{}
+	for _, page := range result {
+		paths = append(paths, page.Path)
+	}
+	assert.Equal(t, [][]string{
+		{"app"},
+		{"app", "host"},
+		{"app", "sub"},
+	}, paths)
+	buf := &bytes.Buffer{}
+	obj.Stdout = buf
+	obj.Width = DefaultWidth
+	assert.NoError(t, obj.Dispatch(context.Background(), []string{"sub", "--help"}))
+	assert.Equal(t, buf.String(), result[2].Text)
+}
+
+func TestAppHelpPagesWidth(t *testing.T) {
+	obj := helpApp(&bytes.Buffer{})
+	obj.Width = 20
+
+	result, err := obj.HelpPages()
+
+	assert.NoError(t, err)
+	buf := &bytes.Buffer{}
+	obj.Stdout = buf
+	assert.NoError(t, obj.Dispatch(context.Background(), []string{"--help"}))
+	assert.Equal(t, buf.String(), result[0].Text)
+}
+
+func TestAppHelpPagesDynamicError(t *testing.T) {
+	obj := &App{
+		Name: "app",
+		Root: &Command{
+			DynamicSubcommands: func() error {
+				return errors.New("failed")
+			},
+		},
+	}
+
+	result, err := obj.HelpPages()
+
+	assert.EqualError(t, err, "failed")
+	assert.Nil(t, result)
+}
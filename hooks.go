@@ -0,0 +1,88 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+// HookedCommand wraps a command, arranging for hook functions to be
+// called before and after the command is executed.  The hooks are
+// invoked through the injector, so they may declare any arguments
+// that are available to the command itself.  This allows auditing,
+// locking, or environment setup to be applied to a command without
+// modifying the command type.
+type HookedCommand struct {
+	Wrapped ICommand    // Wrapped command
+	Before  interface{} // Optional function to call before execution
+	After   interface{} // Optional function to call after execution
+}
+
+// WithHooks wraps a command to arrange for the before and after hook
+// functions to be called around its execution.  Either function may
+// be nil.  If the before hook returns an error, the command will not
+// be executed; if the before hook succeeds, the after hook will be
+// called regardless of whether the command succeeded.
+func WithHooks(cmd ICommand, before, after interface{}) *HookedCommand {
+	return &HookedCommand{
+		Wrapped: cmd,
+		Before:  before,
+		After:   after,
+	}
+}
+
+// GetSummary retrieves the command summary.
+func (c *HookedCommand) GetSummary() string {
+	return c.Wrapped.GetSummary()
+}
+
+// GetDescription retrieves the command's full description.
+func (c *HookedCommand) GetDescription() string {
+	return c.Wrapped.GetDescription()
+}
+
+// GetGroup retrieves the group name of the command.
+func (c *HookedCommand) GetGroup() string {
+	return c.Wrapped.GetGroup()
+}
+
+// GetSubcommands retrieves subcommands for this command.
+func (c *HookedCommand) GetSubcommands() map[string]ICommand {
+	return c.Wrapped.GetSubcommands()
+}
+
+// GetDefaults retrieves the defaults for arguments for this command.
+func (c *HookedCommand) GetDefaults() interface{} {
+	return c.Wrapped.GetDefaults()
+}
+
+// Unwrap returns the wrapped command.
+func (c *HookedCommand) Unwrap() ICommand {
+	return c.Wrapped
+}
+
+// invoke calls the run function, surrounding it with calls to the
+// hooks.  The hooks are called with arguments drawn from the
+// injector.
+func (c *HookedCommand) invoke(inj *Injector, run func() error) error {
+	// Call the before hook
+	if err := inj.call("Before", c.Before); err != nil {
+		return err
+	}
+
+	// Run the command and call the after hook
+	err := run()
+	if afterErr := inj.call("After", c.After); err == nil {
+		err = afterErr
+	}
+
+	return err
+}
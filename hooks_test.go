@@ -0,0 +1,243 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/klmitch/nelson/internal/depinject"
+)
+
+func TestHookedCommandImplementsICommand(t *testing.T) {
+	assert.Implements(t, (*ICommand)(nil), &HookedCommand{})
+}
+
+func TestHookedCommandImplementsIWrapped(t *testing.T) {
+	assert.Implements(t, (*IWrapped)(nil), &HookedCommand{})
+}
+
+func TestWithHooks(t *testing.T) {
+	cmd := &mockICommand{}
+	before := func() {}
+	after := func() {}
+
+	result := WithHooks(cmd, before, after)
+
+	assert.Same(t, cmd, result.Wrapped)
+	assert.NotNil(t, result.Before)
+	assert.NotNil(t, result.After)
+}
+
+func TestHookedCommandGetSummary(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetSummary").Return("some text")
+	obj := &HookedCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetSummary()
+
+	assert.Equal(t, "some text", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestHookedCommandGetDescription(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetDescription").Return("some text")
+	obj := &HookedCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetDescription()
+
+	assert.Equal(t, "some text", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestHookedCommandGetGroup(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetGroup").Return("some text")
+	obj := &HookedCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetGroup()
+
+	assert.Equal(t, "some text", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestHookedCommandGetSubcommands(t *testing.T) {
+	subs := map[string]ICommand{
+		"sub": &mockICommand{},
+	}
+	cmd := &mockICommand{}
+	cmd.On("GetSubcommands").Return(subs)
+	obj := &HookedCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetSubcommands()
+
+	assert.Equal(t, subs, result)
+	cmd.AssertExpectations(t)
+}
+
+func TestHookedCommandGetDefaults(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetDefaults").Return("defaults")
+	obj := &HookedCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetDefaults()
+
+	assert.Equal(t, "defaults", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestHookedCommandUnwrap(t *testing.T) {
+	cmd := &mockICommand{}
+	obj := &HookedCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.Unwrap()
+
+	assert.Same(t, cmd, result)
+}
+
+func TestHookedCommandInvokeBase(t *testing.T) {
+	calls := []string{}
+	inj := NewInjector()
+	inj.Provide("value")
+	obj := &HookedCommand{
+		Before: func(s string) {
+			calls = append(calls, "before "+s)
+		},
+		After: func(s string) error {
+			calls = append(calls, "after "+s)
+			return nil
+		},
+	}
+
+	err := obj.invoke(inj, func() error {
+		calls = append(calls, "run")
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"before value", "run", "after value"}, calls)
+}
+
+func TestHookedCommandInvokeNoHooks(t *testing.T) {
+	calls := []string{}
+	obj := &HookedCommand{}
+
+	err := obj.invoke(NewInjector(), func() error {
+		calls = append(calls, "run")
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"run"}, calls)
+}
+
+func TestHookedCommandInvokeBeforeFails(t *testing.T) {
+	calls := []string{}
+	obj := &HookedCommand{
+		Before: func() error {
+			calls = append(calls, "before")
+			return assert.AnError
+		},
+		After: func() {
+			calls = append(calls, "after")
+		},
+	}
+
+	err := obj.invoke(NewInjector(), func() error {
+		calls = append(calls, "run")
+		return nil
+	})
+
+	assert.Same(t, assert.AnError, err)
+	assert.Equal(t, []string{"before"}, calls)
+}
+
+func TestHookedCommandInvokeRunFails(t *testing.T) {
+	calls := []string{}
+	obj := &HookedCommand{
+		Before: func() {
+			calls = append(calls, "before")
+		},
+		After: func() error {
+			calls = append(calls, "after")
+			return errors.New("after error") //nolint:goerr113
+		},
+	}
+
+	err := obj.invoke(NewInjector(), func() error {
+		calls = append(calls, "run")
+		return assert.AnError
+	})
+
+	assert.Same(t, assert.AnError, err)
+	assert.Equal(t, []string{"before", "run", "after"}, calls)
+}
+
+func TestHookedCommandInvokeAfterFails(t *testing.T) {
+	calls := []string{}
+	obj := &HookedCommand{
+		After: func() error {
+			calls = append(calls, "after")
+			return assert.AnError
+		},
+	}
+
+	err := obj.invoke(NewInjector(), func() error {
+		calls = append(calls, "run")
+		return nil
+	})
+
+	assert.Same(t, assert.AnError, err)
+	assert.Equal(t, []string{"run", "after"}, calls)
+}
+
+func TestHookedCommandInvokeBadHook(t *testing.T) {
+	obj := &HookedCommand{
+		Before: "not a function",
+	}
+
+	err := obj.invoke(NewInjector(), func() error {
+		return nil
+	})
+
+	assert.ErrorIs(t, err, depinject.ErrBadMethod)
+}
+
+func TestHookedCommandInvokeMissingValue(t *testing.T) {
+	obj := &HookedCommand{
+		Before: func(s string) {},
+	}
+
+	err := obj.invoke(NewInjector(), func() error {
+		return nil
+	})
+
+	assert.ErrorIs(t, err, depinject.ErrMissingValue)
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/klmitch/nelson/internal/depinject"
+)
+
+// Injector holds the values available for injection into command
+// methods and hook functions.  Values are keyed by type; a method or
+// function called through the injector receives, for each of its
+// arguments, the value registered for that argument's type.
+type Injector struct {
+	deps depinject.Deps
+}
+
+// NewInjector constructs a new, empty Injector.
+func NewInjector() *Injector {
+	return &Injector{
+		deps: depinject.Deps{},
+	}
+}
+
+// Provide registers values with the injector, keyed by their
+// concrete types.  Use ProvideAs to register a value for an
+// interface type.
+func (i *Injector) Provide(values ...interface{}) {
+	for _, v := range values {
+		i.deps.Set(v)
+	}
+}
+
+// ProvideAs registers a value with the injector for the type
+// identified by ptr, which must be a nil pointer of the desired type,
+// e.g., (*context.Context)(nil).
+func (i *Injector) ProvideAs(ptr interface{}, v interface{}) {
+	i.deps.SetAs(ptr, v)
+}
+
+// Get retrieves the value registered for the type of the value ptr
+// points to, storing it in *ptr.  It returns false if no value has
+// been registered for that type.
+func (i *Injector) Get(ptr interface{}) bool {
+	target := reflect.ValueOf(ptr).Elem()
+	val, ok := i.deps[target.Type()]
+	if !ok || !val.IsValid() {
+		return false
+	}
+
+	target.Set(val)
+	return true
+}
+
+// Clone returns a copy of the injector.  Values registered with the
+// copy are not visible in the original.
+func (i *Injector) Clone() *Injector {
+	return &Injector{
+		deps: i.deps.Clone(),
+	}
+}
+
+// Call calls a function, supplying its arguments from the injector.
+// The function may return nothing or an error.
+func (i *Injector) Call(fn interface{}) error {
+	return i.call("function", fn)
+}
+
+// call is a helper that calls a function, supplying its arguments
+// from the injector.  The name is used in error messages.  A nil
+// function is ignored.
+func (i *Injector) call(name string, fn interface{}) error {
+	if fn == nil {
+		return nil
+	}
+
+	meth, err := depinject.NewFunc(name, fn)
+	if err != nil {
+		return err
+	}
+
+	return meth.Call(i.deps)
+}
+
+// Inject supplies the arguments of a method called without
+// reflection: each of ptrs receives the value registered for the type
+// it points to, as with Get.  The name of the method is used in the
+// error returned if a value is missing; see StaticRunner.
+func (i *Injector) Inject(name string, ptrs ...interface{}) error {
+	for _, ptr := range ptrs {
+		if !i.Get(ptr) {
+			return fmt.Errorf("%q: %w %s", name, depinject.ErrMissingValue, reflect.TypeOf(ptr).Elem())
+		}
+	}
+
+	return nil
+}
+
+// callMethod is a helper that calls the named method of an object,
+// supplying its arguments from the injector.  Returns the result of
+// the method, if it returns one before its error.
+func (i *Injector) callMethod(obj interface{}, name string) (interface{}, error) {
+	meth, err := depinject.NewResult(obj, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return meth.CallResult(i.deps)
+}
+
+// types returns the names of the types of the values registered with
+// the injector, in sorted order.
+func (i *Injector) types() []string {
+	types := make([]string, 0, len(i.deps))
+	for typ, val := range i.deps {
+		if val.IsValid() {
+			types = append(types, typ.String())
+		}
+	}
+	sort.Strings(types)
+
+	return types
+}
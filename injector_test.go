@@ -0,0 +1,190 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/klmitch/nelson/internal/depinject"
+)
+
+func TestNewInjector(t *testing.T) {
+	result := NewInjector()
+
+	assert.Equal(t, &Injector{
+		deps: depinject.Deps{},
+	}, result)
+}
+
+func TestInjectorProvide(t *testing.T) {
+	obj := NewInjector()
+
+	obj.Provide("value", 5)
+
+	assert.Equal(t, depinject.Deps{
+		reflect.TypeOf(""): reflect.ValueOf("value"),
+		reflect.TypeOf(0):  reflect.ValueOf(5),
+	}, obj.deps)
+}
+
+func TestInjectorProvideAs(t *testing.T) {
+	obj := NewInjector()
+	ctx := context.Background()
+
+	obj.ProvideAs((*context.Context)(nil), ctx)
+
+	var result context.Context
+	assert.True(t, obj.Get(&result))
+	assert.Equal(t, ctx, result)
+}
+
+func TestInjectorGetBase(t *testing.T) {
+	obj := NewInjector()
+	obj.Provide("value")
+
+	var result string
+	ok := obj.Get(&result)
+
+	assert.True(t, ok)
+	assert.Equal(t, "value", result)
+}
+
+func TestInjectorGetMissing(t *testing.T) {
+	obj := NewInjector()
+
+	var result string
+	ok := obj.Get(&result)
+
+	assert.False(t, ok)
+	assert.Equal(t, "", result)
+}
+
+func TestInjectorInjectBase(t *testing.T) {
+	obj := NewInjector()
+	obj.Provide("value", 42)
+
+	var s string
+	var i int
+	err := obj.Inject("Run", &s, &i)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "value", s)
+	assert.Equal(t, 42, i)
+}
+
+func TestInjectorInjectMissing(t *testing.T) {
+	obj := NewInjector()
+	obj.Provide("value")
+
+	var s string
+	var i int
+	err := obj.Inject("Run", &s, &i)
+
+	assert.ErrorIs(t, err, depinject.ErrMissingValue)
+	assert.EqualError(t, err, `"Run": missing input for type int`)
+}
+
+func TestInjectorClone(t *testing.T) {
+	obj := NewInjector()
+	obj.Provide("value")
+
+	result := obj.Clone()
+
+	assert.Equal(t, obj, result)
+	result.Provide(5)
+	assert.Len(t, obj.deps, 1)
+}
+
+func TestInjectorCallBase(t *testing.T) {
+	obj := NewInjector()
+	obj.Provide("value", 5)
+	var called string
+
+	err := obj.Call(func(s string, i int) {
+		called = s
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "value", called)
+}
+
+func TestInjectorCallError(t *testing.T) {
+	obj := NewInjector()
+
+	err := obj.Call(func() error {
+		return assert.AnError
+	})
+
+	assert.Same(t, assert.AnError, err)
+}
+
+func TestInjectorCallNil(t *testing.T) {
+	obj := NewInjector()
+
+	err := obj.Call(nil)
+
+	assert.NoError(t, err)
+}
+
+func TestInjectorCallBadFunc(t *testing.T) {
+	obj := NewInjector()
+
+	err := obj.Call("not a function")
+
+	assert.ErrorIs(t, err, depinject.ErrBadMethod)
+}
+
+type injectorMethods struct {
+	called string
+}
+
+func (m *injectorMethods) Method(s string) {
+	m.called = s
+}
+
+func TestInjectorCallMethodBase(t *testing.T) {
+	obj := NewInjector()
+	obj.Provide("value")
+	target := &injectorMethods{}
+
+	result, err := obj.callMethod(target, "Method")
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+	assert.Equal(t, "value", target.called)
+}
+
+func TestInjectorCallMethodMissing(t *testing.T) {
+	obj := NewInjector()
+	target := &injectorMethods{}
+
+	_, err := obj.callMethod(target, "NoSuchMethod")
+
+	assert.ErrorIs(t, err, depinject.ErrNoMethod)
+}
+
+func TestInjectorTypes(t *testing.T) {
+	obj := NewInjector()
+	obj.Provide("value", 5)
+	obj.deps[reflect.TypeOf(1.5)] = reflect.Value{}
+
+	result := obj.types()
+
+	assert.Equal(t, []string{"int", "string"}, result)
+}
@@ -72,6 +72,36 @@ func (d Deps) Copy() Deps {
 	return result
 }
 
+// Clone constructs a new Deps from an existing one, including the
+// values.
+func (d Deps) Clone() Deps {
+	result := Deps{}
+	for typ, val := range d {
+		result[typ] = val
+	}
+	return result
+}
+
+// Set sets the value for the type of the specified value.  Note that
+// this sets the concrete type of v; use SetAs to set the value for
+// an interface type.
+func (d Deps) Set(v interface{}) {
+	val := reflect.ValueOf(v)
+	d[val.Type()] = val
+}
+
+// SetAs sets the value for the type identified by ptr, which must be
+// a pointer to a value of that type, e.g., (*error)(nil).  This
+// allows values to be set for interface types.
+func (d Deps) SetAs(ptr interface{}, v interface{}) {
+	typ := reflect.TypeOf(ptr).Elem()
+	val := reflect.ValueOf(v)
+	if !val.IsValid() {
+		val = reflect.Zero(typ)
+	}
+	d[typ] = val.Convert(typ)
+}
+
 // Method is a type that identifies a specific method.  It collects
 // together its dependencies, and can be used to call that method on a
 // specific object.
@@ -80,10 +110,26 @@ type Method struct {
 	Method reflect.Value  // The actual method
 	Deps   Deps           // The dependencies of the method
 	Args   []reflect.Type // Ordered list of arguments
+	Result bool           // True if the method returns a result preceding its error
 }
 
 // New constructs a new Method object for a specific method.
 func New(obj interface{}, method string) (*Method, error) {
+	return newNamed(obj, method, false)
+}
+
+// NewResult constructs a new Method object for a specific method,
+// like New, except that the method may also return a result
+// preceding its error, as in "func (o *T) Method() (R, error)"; see
+// Method.CallResult.
+func NewResult(obj interface{}, method string) (*Method, error) {
+	return newNamed(obj, method, true)
+}
+
+// newNamed is a helper that constructs the Method object for a
+// specific method.  If allowResult is true, the method may return a
+// result preceding its error.
+func newNamed(obj interface{}, method string, allowResult bool) (*Method, error) {
 	// Get the Value of the object
 	if obj == nil {
 		return nil, fmt.Errorf("%w %q", ErrNoMethod, method)
@@ -99,9 +145,44 @@ func New(obj interface{}, method string) (*Method, error) {
 		return nil, fmt.Errorf("%w %q", ErrNoMethod, method)
 	}
 
+	return newMethod(method, meth, allowResult)
+}
+
+// NewFunc constructs a new Method object for a function.  The name
+// is used in error messages.
+func NewFunc(name string, fn interface{}) (*Method, error) {
+	// Get the Value of the function
+	if fn == nil {
+		return nil, fmt.Errorf("%w %q", ErrNoMethod, name)
+	}
+	val, ok := fn.(reflect.Value)
+	if !ok {
+		val = reflect.ValueOf(fn)
+	}
+
+	// Make sure it's a function
+	if val.Kind() != reflect.Func || val.IsNil() {
+		return nil, fmt.Errorf("%q: %w", name, ErrBadMethod)
+	}
+
+	return newMethod(name, val, false)
+}
+
+// newMethod is a helper that constructs the Method object for the
+// method or function.  If allowResult is true, the method may return
+// a result preceding its error.
+func newMethod(method string, meth reflect.Value, allowResult bool) (*Method, error) {
 	// Check the method type information
 	mType := meth.Type()
-	if mType.IsVariadic() || mType.NumOut() > 1 || (mType.NumOut() == 1 && !mType.Out(0).AssignableTo(errType)) {
+	outs := make([]reflect.Type, mType.NumOut())
+	for i := range outs {
+		outs[i] = mType.Out(i)
+	}
+	hasResult := allowResult && len(outs) == 2
+	if hasResult {
+		outs = outs[1:]
+	}
+	if mType.IsVariadic() || len(outs) > 1 || (len(outs) == 1 && !outs[0].AssignableTo(errType)) {
 		return nil, fmt.Errorf("%q: %w", method, ErrBadMethod)
 	}
 
@@ -110,6 +191,7 @@ func New(obj interface{}, method string) (*Method, error) {
 		Name:   method,
 		Method: meth,
 		Deps:   Deps{},
+		Result: hasResult,
 	}
 
 	// Account for inputs
@@ -125,14 +207,22 @@ func New(obj interface{}, method string) (*Method, error) {
 	return result, nil
 }
 
-// Call calls the method.  Inputs are a completed Deps.
+// Call calls the method.  Inputs are a completed Deps.  Any result
+// the method returns is discarded.
 func (m *Method) Call(inputs Deps) error {
+	_, err := m.CallResult(inputs)
+	return err
+}
+
+// CallResult calls the method, returning its result, if it returns
+// one, and its error.  Inputs are a completed Deps.
+func (m *Method) CallResult(inputs Deps) (interface{}, error) {
 	// Assemble inputs
 	values := []reflect.Value{}
 	for _, typ := range m.Args {
 		tmp := inputs[typ]
 		if !tmp.IsValid() {
-			return fmt.Errorf("%q: %w %s", m.Name, ErrMissingValue, typ.String())
+			return nil, fmt.Errorf("%q: %w %s", m.Name, ErrMissingValue, typ.String())
 		}
 
 		values = append(values, tmp)
@@ -142,8 +232,25 @@ func (m *Method) Call(inputs Deps) error {
 	result := m.Method.Call(values)
 
 	// Return the result
-	if len(result) > 0 {
-		return result[0].Interface().(error)
+	var value interface{}
+	if m.Result {
+		value = result[0].Interface()
+		result = result[1:]
+	}
+	if len(result) > 0 && !isNil(result[0]) {
+		return value, result[0].Interface().(error)
 	}
-	return nil
+	return value, nil
+}
+
+// isNil is a helper that determines whether a value is nil.  Values
+// of kinds that cannot be nil, such as a struct implementing error,
+// are never nil.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return v.IsNil()
+	}
+
+	return false
 }
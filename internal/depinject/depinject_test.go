@@ -80,6 +80,51 @@ func TestDepsCopy(t *testing.T) {
 	assert.NotEqual(t, result, obj)
 }
 
+func TestDepsClone(t *testing.T) {
+	obj := Deps{
+		reflect.TypeOf(""): reflect.ValueOf("test"),
+		reflect.TypeOf(0):  reflect.ValueOf(5),
+	}
+
+	result := obj.Clone()
+
+	assert.Equal(t, obj, result)
+	result[reflect.TypeOf(0)] = reflect.ValueOf(6)
+	assert.Equal(t, 5, obj[reflect.TypeOf(0)].Interface())
+}
+
+func TestDepsSet(t *testing.T) {
+	obj := Deps{}
+
+	obj.Set("test")
+
+	assert.Equal(t, Deps{
+		reflect.TypeOf(""): reflect.ValueOf("test"),
+	}, obj)
+}
+
+func TestDepsSetAs(t *testing.T) {
+	obj := Deps{}
+	errType := reflect.TypeOf((*error)(nil)).Elem()
+
+	obj.SetAs((*error)(nil), assert.AnError)
+
+	assert.Len(t, obj, 1)
+	assert.Equal(t, errType, obj[errType].Type())
+	assert.Same(t, assert.AnError, obj[errType].Interface())
+}
+
+func TestDepsSetAsNil(t *testing.T) {
+	obj := Deps{}
+	errType := reflect.TypeOf((*error)(nil)).Elem()
+
+	obj.SetAs((*error)(nil), nil)
+
+	assert.Len(t, obj, 1)
+	assert.True(t, obj[errType].IsValid())
+	assert.Nil(t, obj[errType].Interface())
+}
+
 type methods struct {
 	mock.Mock
 }
@@ -118,6 +163,32 @@ func (m *methods) DuplicatedInput(a int, b int) {
 	m.MethodCalled("DuplicatedInput", a, b)
 }
 
+type structError struct {
+	msg string
+}
+
+func (e structError) Error() string {
+	return e.msg
+}
+
+func (m *methods) StructErr() structError {
+	args := m.MethodCalled("StructErr")
+
+	return args.Get(0).(structError)
+}
+
+type ptrError struct{}
+
+func (e *ptrError) Error() string {
+	return "ptr error"
+}
+
+func (m *methods) PtrErr() *ptrError {
+	m.MethodCalled("PtrErr")
+
+	return nil
+}
+
 func TestNewNiladic(t *testing.T) {
 	val := &methods{}
 	val.On("Niladic").Once()
@@ -246,6 +317,74 @@ func TestNewDuplicatedInput(t *testing.T) {
 	val.AssertExpectations(t)
 }
 
+func TestNewFuncBase(t *testing.T) {
+	called := false
+	fn := func(i int, s string) error {
+		called = true
+		return nil
+	}
+
+	result, err := NewFunc("func", fn)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "func", result.Name)
+	assert.Equal(t, Deps{
+		reflect.TypeOf(5):      reflect.Value{},
+		reflect.TypeOf("test"): reflect.Value{},
+	}, result.Deps)
+	assert.Equal(t, []reflect.Type{
+		reflect.TypeOf(5),
+		reflect.TypeOf("test"),
+	}, result.Args)
+	result.Method.Call([]reflect.Value{
+		reflect.ValueOf(5),
+		reflect.ValueOf("test"),
+	})
+	assert.True(t, called)
+}
+
+func TestNewFuncValue(t *testing.T) {
+	fn := func() {}
+
+	result, err := NewFunc("func", reflect.ValueOf(fn))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "func", result.Name)
+	assert.Equal(t, Deps{}, result.Deps)
+}
+
+func TestNewFuncNil(t *testing.T) {
+	result, err := NewFunc("func", nil)
+
+	assert.ErrorIs(t, err, ErrNoMethod)
+	assert.Nil(t, result)
+}
+
+func TestNewFuncNilFunc(t *testing.T) {
+	var fn func()
+
+	result, err := NewFunc("func", fn)
+
+	assert.ErrorIs(t, err, ErrBadMethod)
+	assert.Nil(t, result)
+}
+
+func TestNewFuncNotFunc(t *testing.T) {
+	result, err := NewFunc("func", 5)
+
+	assert.ErrorIs(t, err, ErrBadMethod)
+	assert.Nil(t, result)
+}
+
+func TestNewFuncBadSignature(t *testing.T) {
+	fn := func() int { return 5 }
+
+	result, err := NewFunc("func", fn)
+
+	assert.ErrorIs(t, err, ErrBadMethod)
+	assert.Nil(t, result)
+}
+
 func TestMethodCallNiladic(t *testing.T) {
 	val := &methods{}
 	val.On("Niladic")
@@ -278,6 +417,56 @@ func TestMethodCallNiladicErr(t *testing.T) {
 	val.AssertExpectations(t)
 }
 
+func TestMethodCallNiladicNilErr(t *testing.T) {
+	val := &methods{}
+	val.On("NiladicErr").Return(nil)
+	args := Deps{}
+	obj := &Method{
+		Name:   "NiladicErr",
+		Method: reflect.ValueOf(val).MethodByName("NiladicErr"),
+		Deps:   Deps{},
+	}
+
+	result := obj.Call(args)
+
+	assert.NoError(t, result)
+	val.AssertExpectations(t)
+}
+
+func TestMethodCallStructErr(t *testing.T) {
+	val := &methods{}
+	val.On("StructErr").Return(structError{msg: "failed"})
+	obj, err := New(val, "StructErr")
+	assert.NoError(t, err)
+
+	result := obj.Call(Deps{})
+
+	assert.Equal(t, structError{msg: "failed"}, result)
+	val.AssertExpectations(t)
+}
+
+func TestMethodCallNilPtrErr(t *testing.T) {
+	val := &methods{}
+	val.On("PtrErr")
+	obj, err := New(val, "PtrErr")
+	assert.NoError(t, err)
+
+	result := obj.Call(Deps{})
+
+	assert.NoError(t, result)
+	val.AssertExpectations(t)
+}
+
+func TestIsNil(t *testing.T) {
+	var err error
+	var ptr *ptrError
+
+	assert.True(t, isNil(reflect.ValueOf(&err).Elem()))
+	assert.True(t, isNil(reflect.ValueOf(ptr)))
+	assert.False(t, isNil(reflect.ValueOf(structError{})))
+	assert.False(t, isNil(reflect.ValueOf(5)))
+}
+
 func TestMethodCallBasic(t *testing.T) {
 	val := &methods{}
 	val.On("Basic", 5, "test")
@@ -327,3 +516,84 @@ func TestMethodCallMissingValue(t *testing.T) {
 	assert.ErrorIs(t, result, ErrMissingValue)
 	val.AssertExpectations(t)
 }
+
+func TestNewResultTwoReturn(t *testing.T) {
+	val := &methods{}
+
+	result, err := NewResult(val, "TwoReturn")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "TwoReturn", result.Name)
+	assert.True(t, result.Result)
+	val.AssertExpectations(t)
+}
+
+func TestNewResultNiladicErr(t *testing.T) {
+	val := &methods{}
+
+	result, err := NewResult(val, "NiladicErr")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "NiladicErr", result.Name)
+	assert.False(t, result.Result)
+	val.AssertExpectations(t)
+}
+
+func TestNewResultNonError(t *testing.T) {
+	val := &methods{}
+
+	result, err := NewResult(val, "NonError")
+
+	assert.ErrorIs(t, err, ErrBadMethod)
+	assert.Nil(t, result)
+	val.AssertExpectations(t)
+}
+
+func TestMethodCallResult(t *testing.T) {
+	val := &methods{}
+	val.On("TwoReturn").Return("result", assert.AnError)
+	obj := &Method{
+		Name:   "TwoReturn",
+		Method: reflect.ValueOf(val).MethodByName("TwoReturn"),
+		Deps:   Deps{},
+		Result: true,
+	}
+
+	result, err := obj.CallResult(Deps{})
+
+	assert.Same(t, assert.AnError, err)
+	assert.Equal(t, "result", result)
+	val.AssertExpectations(t)
+}
+
+func TestMethodCallResultNoResult(t *testing.T) {
+	val := &methods{}
+	val.On("NiladicErr").Return(nil)
+	obj := &Method{
+		Name:   "NiladicErr",
+		Method: reflect.ValueOf(val).MethodByName("NiladicErr"),
+		Deps:   Deps{},
+	}
+
+	result, err := obj.CallResult(Deps{})
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+	val.AssertExpectations(t)
+}
+
+func TestMethodCallDiscardsResult(t *testing.T) {
+	val := &methods{}
+	val.On("TwoReturn").Return("result", nil)
+	obj := &Method{
+		Name:   "TwoReturn",
+		Method: reflect.ValueOf(val).MethodByName("TwoReturn"),
+		Deps:   Deps{},
+		Result: true,
+	}
+
+	err := obj.Call(Deps{})
+
+	assert.NoError(t, err)
+	val.AssertExpectations(t)
+}
@@ -0,0 +1,546 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package gen generates code for the nelson-gen tool, so that
+// applications may avoid reflection on the hot paths of dispatch.
+// The source of the package declaring the types is parsed; for each
+// defaults struct, a StoreFlag method is generated that converts and
+// stores the values of its flags and arguments, implementing
+// nelson.FlagStorer, and for each command, a RunStatic method is
+// generated that calls its run method directly, implementing
+// nelson.StaticRunner.  Fields whose types are not supported are
+// left to the reflective path.  The flags themselves are still
+// described by reflecting on the struct tags, once for each type;
+// generating those descriptions is left as a follow-up.
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Errors reported by Generate.
+var (
+	ErrNoPackage   = errors.New("no package found")
+	ErrNoType      = errors.New("type not found")
+	ErrNotStruct   = errors.New("type is not a struct")
+	ErrNoFields    = errors.New("no supported fields")
+	ErrManyPackage = errors.New("multiple packages found")
+	ErrNoRun       = errors.New("no run method")
+	ErrBadRun      = errors.New("unsupported run method")
+	ErrBadImport   = errors.New("unresolvable import")
+)
+
+// Struct tags examined by the generator; these match those of the
+// nelson package.
+const (
+	optTag  = "opt"
+	argTag  = "arg"
+	kindTag = "kind"
+)
+
+// nelsonPath is the import path of the nelson package.
+const nelsonPath = "github.com/klmitch/nelson"
+
+// runMethods are the names of the methods that run a command, in
+// order of preference; these match nelson.RunMethod and
+// nelson.ExecuteMethod.
+var runMethods = []string{"Run", "Execute"}
+
+// basicType describes a supported type: the nelson function used to
+// convert text to the type, or "" for strings, and the bit size
+// passed to it, if any.
+type basicType struct {
+	parse string // Conversion function, e.g., "ParseInt"
+	bits  string // Bit size argument, or "" if none
+}
+
+// basicTypes maps the names of the supported types to their
+// descriptions.
+var basicTypes = map[string]basicType{
+	"string":        {},
+	"bool":          {"ParseBool", ""},
+	"int":           {"ParseInt", "0"},
+	"int8":          {"ParseInt", "8"},
+	"int16":         {"ParseInt", "16"},
+	"int32":         {"ParseInt", "32"},
+	"int64":         {"ParseInt", "64"},
+	"uint":          {"ParseUint", "0"},
+	"uint8":         {"ParseUint", "8"},
+	"uint16":        {"ParseUint", "16"},
+	"uint32":        {"ParseUint", "32"},
+	"uint64":        {"ParseUint", "64"},
+	"float32":       {"ParseFloat", "32"},
+	"float64":       {"ParseFloat", "64"},
+	"time.Duration": {"ParseDuration", ""},
+}
+
+// field describes a field for which code is generated.
+type field struct {
+	path  string    // Dotted path of the field, e.g., "Common.Verbose"
+	typ   string    // Name of the type, e.g., "int"
+	basic basicType // Description of the type
+	list  bool      // True if the field is a slice of the type
+}
+
+// structDecl describes a struct declared by the package.
+type structDecl struct {
+	typ  *ast.StructType // The struct type
+	file *ast.File       // The file declaring the struct
+}
+
+// methodDecl describes a method declared by the package.
+type methodDecl struct {
+	fn   *ast.FuncDecl // The method
+	file *ast.File     // The file declaring the method
+}
+
+// runner describes the run method of a command for which code is
+// generated.
+type runner struct {
+	method  string   // Name of the method, e.g., "Run"
+	recv    string   // Type of the receiver, e.g., "*Serve"
+	args    []string // Types of the arguments, as source
+	results int      // Number of results: none, an error, or a result and an error
+}
+
+// Generate generates the source of a file implementing
+// nelson.FlagStorer for each of the named struct types, and
+// nelson.StaticRunner for each of the named command types, all of
+// which must be declared by the package in the specified directory.
+// Test files are not examined.  The source is formatted.
+func Generate(dir string, types, commands []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoPackage, dir)
+	} else if len(pkgs) > 1 {
+		return nil, fmt.Errorf("%w in %s", ErrManyPackage, dir)
+	}
+
+	// Collect the struct and method declarations
+	var pkg *ast.Package
+	for _, tmp := range pkgs {
+		pkg = tmp
+	}
+	structs := map[string]structDecl{}
+	methods := map[string]map[string]methodDecl{}
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				if decl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					ts := spec.(*ast.TypeSpec)
+					if st, ok := ts.Type.(*ast.StructType); ok {
+						structs[ts.Name.Name] = structDecl{typ: st, file: file}
+					}
+				}
+
+			case *ast.FuncDecl:
+				if name := recvName(decl); name != "" {
+					if methods[name] == nil {
+						methods[name] = map[string]methodDecl{}
+					}
+					methods[name][decl.Name.Name] = methodDecl{fn: decl, file: file}
+				}
+			}
+		}
+	}
+
+	// Generate the code
+	imports := map[string]string{"nelson": nelsonPath}
+	body := &bytes.Buffer{}
+	for _, name := range types {
+		decl, ok := structs[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNoType, name)
+		}
+		fields := collect(structs, decl, []string{name}, map[string]bool{name: true})
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrNoFields, name)
+		}
+		emitStorer(body, name, fields)
+	}
+	for _, name := range commands {
+		run, err := collectRun(fset, name, methods[name], imports)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, name)
+		}
+		emitRunner(body, name, run)
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by nelson-gen; DO NOT EDIT.\n\npackage %s\n\n", pkg.Name)
+	emitImports(buf, imports)
+	buf.Write(body.Bytes())
+
+	return format.Source(buf.Bytes())
+}
+
+// recvName returns the name of the type of the receiver of a method,
+// or "" if the function is not a method.
+func recvName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) != 1 {
+		return ""
+	}
+
+	typ := fn.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	if ident, ok := typ.(*ast.Ident); ok {
+		return ident.Name
+	}
+
+	return ""
+}
+
+// collect collects the supported fields of a struct, recursing into
+// embedded structs declared by the package.  The prefix is the path
+// of the struct, beginning with the name of the outermost struct
+// type, as for nelson.FlagStorer, and seen contains the names of the
+// structs being examined, to prevent infinite recursion.
+func collect(structs map[string]structDecl, decl structDecl, prefix []string, seen map[string]bool) []field {
+	var fields []field
+	for _, f := range decl.typ.Fields.List {
+		tag := reflect.StructTag("")
+		if f.Tag != nil {
+			if text, err := strconv.Unquote(f.Tag.Value); err == nil {
+				tag = reflect.StructTag(text)
+			}
+		}
+		_, isOpt := tag.Lookup(optTag)
+		_, isArg := tag.Lookup(argTag)
+
+		// Handle embedded structs; pointers are left to reflection
+		if len(f.Names) == 0 {
+			ident, ok := f.Type.(*ast.Ident)
+			if !ok || isOpt || isArg || seen[ident.Name] {
+				continue
+			}
+			if embedded, ok := structs[ident.Name]; ok {
+				seen[ident.Name] = true
+				fields = append(fields, collect(structs, embedded, append(append([]string{}, prefix...), ident.Name), seen)...)
+				delete(seen, ident.Name)
+			}
+			continue
+		}
+
+		// Skip fields that are not flags or arguments, and special
+		// kinds, which are handled by the framework
+		if !isOpt && !isArg {
+			continue
+		}
+		if kind, ok := tag.Lookup(kindTag); ok && kind != "secret" {
+			continue
+		}
+
+		// Determine the type
+		typ, list := f.Type, false
+		if arr, ok := typ.(*ast.ArrayType); ok && arr.Len == nil {
+			typ, list = arr.Elt, true
+		}
+		name := typeName(decl.file, typ)
+		basic, ok := basicTypes[name]
+		if !ok {
+			continue
+		}
+
+		for _, ident := range f.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			fields = append(fields, field{
+				path:  strings.Join(append(append([]string{}, prefix...), ident.Name), "."),
+				typ:   name,
+				basic: basic,
+				list:  list,
+			})
+		}
+	}
+
+	return fields
+}
+
+// collectRun describes the run method of a command, given the name
+// of its type and the methods declared for it.  The imports needed
+// by the types of the method's arguments are added to imports, which
+// maps the names of imported packages to their paths.  Methods that
+// the injector could not call, such as variadic methods or methods
+// with two arguments of the same type, are rejected.
+func collectRun(fset *token.FileSet, name string, methods map[string]methodDecl, imports map[string]string) (*runner, error) {
+	var decl methodDecl
+	run := &runner{recv: name}
+	for _, name := range runMethods {
+		if tmp, ok := methods[name]; ok {
+			decl, run.method = tmp, name
+			break
+		}
+	}
+	if run.method == "" {
+		return nil, ErrNoRun
+	}
+	if _, ok := decl.fn.Recv.List[0].Type.(*ast.StarExpr); ok {
+		run.recv = "*" + name
+	}
+
+	// Describe the arguments
+	seen := map[string]bool{}
+	for _, param := range decl.fn.Type.Params.List {
+		if _, ok := param.Type.(*ast.Ellipsis); ok {
+			return nil, fmt.Errorf("%w: %s is variadic", ErrBadRun, run.method)
+		}
+		if err := addImports(decl.file, param.Type, imports); err != nil {
+			return nil, err
+		}
+		buf := &bytes.Buffer{}
+		if err := printer.Fprint(buf, fset, param.Type); err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(param.Names) || i == 0; i++ {
+			if seen[buf.String()] {
+				return nil, fmt.Errorf("%w: %s has multiple arguments of type %s", ErrBadRun, run.method, buf)
+			}
+			seen[buf.String()] = true
+			run.args = append(run.args, buf.String())
+		}
+	}
+
+	// Check the results
+	var results []ast.Expr
+	if decl.fn.Type.Results != nil {
+		for _, result := range decl.fn.Type.Results.List {
+			for i := 0; i < len(result.Names) || i == 0; i++ {
+				results = append(results, result.Type)
+			}
+		}
+	}
+	if len(results) > 2 {
+		return nil, fmt.Errorf("%w: %s returns too many values", ErrBadRun, run.method)
+	} else if len(results) > 0 {
+		if ident, ok := results[len(results)-1].(*ast.Ident); !ok || ident.Name != "error" || ident.Obj != nil {
+			return nil, fmt.Errorf("%w: %s does not return an error", ErrBadRun, run.method)
+		}
+	}
+	run.results = len(results)
+
+	return run, nil
+}
+
+// addImports adds the imports needed by a type expression, declared
+// in the specified file, to imports, which maps the names of
+// imported packages to their paths.
+func addImports(file *ast.File, expr ast.Expr, imports map[string]string) error {
+	var err error
+	ast.Inspect(expr, func(node ast.Node) bool {
+		sel, ok := node.(*ast.SelectorExpr)
+		if !ok || err != nil {
+			return err == nil
+		}
+		pkg, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		path := ""
+		for _, imp := range file.Imports {
+			if importName(imp) == pkg.Name {
+				path, _ = strconv.Unquote(imp.Path.Value)
+				break
+			}
+		}
+		if path == "" {
+			err = fmt.Errorf("%w: package %s", ErrBadImport, pkg.Name)
+		} else if other, ok := imports[pkg.Name]; ok && other != path {
+			err = fmt.Errorf("%w: package %s is both %q and %q", ErrBadImport, pkg.Name, other, path)
+		} else {
+			imports[pkg.Name] = path
+		}
+
+		return false
+	})
+
+	return err
+}
+
+// importName returns the name by which an import is referred to: its
+// explicit name, if any, and otherwise the last element of its path,
+// ignoring any major version suffix and extension, as in "yaml" for
+// "gopkg.in/yaml.v2".
+func importName(imp *ast.ImportSpec) string {
+	if imp.Name != nil {
+		return imp.Name.Name
+	}
+
+	path, _ := strconv.Unquote(imp.Path.Value)
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.Index(name, "."); i > 0 {
+		name = name[:i]
+	}
+
+	return name
+}
+
+// typeName returns the name of a type expression, for looking up in
+// basicTypes.  Package selectors are resolved through the imports of
+// the file, so that only the time package's Duration is recognized.
+func typeName(file *ast.File, expr ast.Expr) string {
+	switch typ := expr.(type) {
+	case *ast.Ident:
+		if typ.Obj != nil {
+			return "" // Declared by the package, shadowing a basic type
+		}
+		return typ.Name
+
+	case *ast.SelectorExpr:
+		pkg, ok := typ.X.(*ast.Ident)
+		if !ok {
+			return ""
+		}
+		for _, imp := range file.Imports {
+			if importName(imp) == pkg.Name {
+				path, _ := strconv.Unquote(imp.Path.Value)
+				return path + "." + typ.Sel.Name
+			}
+		}
+	}
+
+	return ""
+}
+
+// emitImports emits the import declaration for the imports, which
+// map the names of imported packages to their paths.  Names that
+// differ from the last element of the path are given explicitly, and
+// the standard library is grouped first, as goimports would.
+func emitImports(buf *bytes.Buffer, imports map[string]string) {
+	names := make([]string, 0, len(imports))
+	for name := range imports {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if std := isStd(imports[names[i]]); std != isStd(imports[names[j]]) {
+			return std
+		}
+		return imports[names[i]] < imports[names[j]]
+	})
+
+	if len(names) == 1 {
+		fmt.Fprintf(buf, "import %q\n", imports[names[0]])
+		return
+	}
+
+	buf.WriteString("import (\n")
+	for i, name := range names {
+		path := imports[name]
+		if i > 0 && isStd(imports[names[i-1]]) && !isStd(path) {
+			buf.WriteString("\n")
+		}
+		if name == path[strings.LastIndex(path, "/")+1:] {
+			fmt.Fprintf(buf, "\t%q\n", path)
+		} else {
+			fmt.Fprintf(buf, "\t%s %q\n", name, path)
+		}
+	}
+	buf.WriteString(")\n")
+}
+
+// isStd returns true if an import path is in the standard library,
+// which is the case if its first element has no dot.
+func isStd(path string) bool {
+	return !strings.Contains(strings.SplitN(path, "/", 2)[0], ".")
+}
+
+// emitStorer emits the StoreFlag method for a struct.
+func emitStorer(buf *bytes.Buffer, name string, fields []field) {
+	fmt.Fprintf(buf, "\nvar _ nelson.FlagStorer = (*%s)(nil)\n", name)
+	fmt.Fprintf(buf, "\n// StoreFlag implements nelson.FlagStorer.\nfunc (o *%s) StoreFlag(field, text string) (bool, error) {\n\tswitch field {\n", name)
+	for _, f := range fields {
+		target := "o" + f.path[strings.Index(f.path, "."):]
+		fmt.Fprintf(buf, "\tcase %q:\n", f.path)
+
+		// Convert the text
+		value := "text"
+		if f.basic.parse != "" {
+			args := "text"
+			if f.basic.bits != "" {
+				args += ", " + f.basic.bits
+			}
+			fmt.Fprintf(buf, "\t\tv, err := nelson.%s(%s)\n\t\tif err != nil {\n\t\t\treturn true, err\n\t\t}\n", f.basic.parse, args)
+			value = "v"
+			if f.basic.bits != "" && f.typ != "int64" && f.typ != "uint64" && f.typ != "float64" {
+				value = fmt.Sprintf("%s(v)", f.typ)
+			}
+		}
+
+		// Store the value
+		if f.list {
+			fmt.Fprintf(buf, "\t\t%s = append(%s, %s)\n", target, target, value)
+		} else {
+			fmt.Fprintf(buf, "\t\t%s = %s\n", target, value)
+		}
+	}
+	buf.WriteString("\tdefault:\n\t\treturn false, nil\n\t}\n\n\treturn true, nil\n}\n")
+}
+
+// emitRunner emits the RunStatic method for a command.
+func emitRunner(buf *bytes.Buffer, name string, run *runner) {
+	fmt.Fprintf(buf, "\nvar _ nelson.StaticRunner = (*%s)(nil)\n", name)
+	fmt.Fprintf(buf, "\n// RunStatic implements nelson.StaticRunner.\nfunc (c %s) RunStatic(inj *nelson.Injector) (interface{}, error) {\n", run.recv)
+
+	// Supply the arguments
+	args := make([]string, len(run.args))
+	ptrs := make([]string, len(run.args))
+	for i, typ := range run.args {
+		args[i] = fmt.Sprintf("a%d", i)
+		ptrs[i] = "&" + args[i]
+		fmt.Fprintf(buf, "\tvar %s %s\n", args[i], typ)
+	}
+	if len(args) > 0 {
+		fmt.Fprintf(buf, "\tif err := inj.Inject(%q, %s); err != nil {\n\t\treturn nil, err\n\t}\n\n", run.method, strings.Join(ptrs, ", "))
+	}
+
+	// Call the method
+	call := fmt.Sprintf("c.%s(%s)", run.method, strings.Join(args, ", "))
+	switch run.results {
+	case 0:
+		fmt.Fprintf(buf, "\t%s\n\n\treturn nil, nil\n}\n", call)
+	case 1:
+		fmt.Fprintf(buf, "\treturn nil, %s\n}\n", call)
+	default:
+		fmt.Fprintf(buf, "\treturn %s\n}\n", call)
+	}
+}
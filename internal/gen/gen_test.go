@@ -0,0 +1,143 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gen
+
+import (
+	"flag"
+	"go/ast"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGenerate(t *testing.T) {
+	golden := filepath.Join("testdata", "basic.golden")
+
+	result, err := Generate(filepath.Join("testdata", "basic"), []string{"Options", "Common"}, []string{"Serve", "List", "Ping"})
+
+	assert.NoError(t, err)
+	if *update {
+		assert.NoError(t, ioutil.WriteFile(golden, result, 0o644))
+	}
+	expected, err := ioutil.ReadFile(golden)
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), string(result))
+}
+
+func TestGenerateNoType(t *testing.T) {
+	result, err := Generate(filepath.Join("testdata", "basic"), []string{"Missing"}, nil)
+
+	assert.ErrorIs(t, err, ErrNoType)
+	assert.Nil(t, result)
+}
+
+func TestGenerateNoFields(t *testing.T) {
+	result, err := Generate(filepath.Join("testdata", "basic"), []string{"Empty"}, nil)
+
+	assert.ErrorIs(t, err, ErrNoFields)
+	assert.Nil(t, result)
+}
+
+func TestGenerateNotStruct(t *testing.T) {
+	result, err := Generate(filepath.Join("testdata", "basic"), []string{"NotStruct"}, nil)
+
+	assert.ErrorIs(t, err, ErrNoType)
+	assert.Nil(t, result)
+}
+
+func TestGenerateNoRun(t *testing.T) {
+	result, err := Generate(filepath.Join("testdata", "basic"), nil, []string{"Options"})
+
+	assert.ErrorIs(t, err, ErrNoRun)
+	assert.Nil(t, result)
+}
+
+func TestGenerateBadRun(t *testing.T) {
+	for _, name := range []string{"Variadic", "Duplicate", "NoError", "TooMany"} {
+		t.Run(name, func(t *testing.T) {
+			result, err := Generate(filepath.Join("testdata", "basic"), nil, []string{name})
+
+			assert.ErrorIs(t, err, ErrBadRun)
+			assert.Nil(t, result)
+		})
+	}
+}
+
+func TestGenerateUnresolvedImport(t *testing.T) {
+	result, err := Generate(filepath.Join("testdata", "basic"), nil, []string{"Unresolved"})
+
+	assert.ErrorIs(t, err, ErrBadImport)
+	assert.Nil(t, result)
+}
+
+func TestGenerateConflictingImports(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nimport \"math/rand\"\n\ntype A struct{}\n\nfunc (c *A) Run(r *rand.Rand) {}\n"), 0o644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "b.go"), []byte("package a\n\nimport \"crypto/rand\"\n\ntype B struct{}\n\nfunc (c *B) Run(r rand.Reader) {}\n"), 0o644))
+
+	result, err := Generate(dir, nil, []string{"A", "B"})
+
+	assert.ErrorIs(t, err, ErrBadImport)
+	assert.Nil(t, result)
+}
+
+func TestImportName(t *testing.T) {
+	for path, name := range map[string]string{
+		"time":                   "time",
+		"github.com/a/b":         "b",
+		"gopkg.in/yaml.v2":       "yaml",
+		"github.com/a/b/v2":      "b",
+		"github.com/a/v2ray/foo": "foo",
+	} {
+		t.Run(path, func(t *testing.T) {
+			result := importName(&ast.ImportSpec{Path: &ast.BasicLit{Value: strconv.Quote(path)}})
+
+			assert.Equal(t, name, result)
+		})
+	}
+}
+
+func TestGenerateNoPackage(t *testing.T) {
+	result, err := Generate(t.TempDir(), []string{"Options"}, nil)
+
+	assert.ErrorIs(t, err, ErrNoPackage)
+	assert.Nil(t, result)
+}
+
+func TestGenerateManyPackages(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0o644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "b.go"), []byte("package b\n"), 0o644))
+
+	result, err := Generate(dir, []string{"Options"}, nil)
+
+	assert.ErrorIs(t, err, ErrManyPackage)
+	assert.Nil(t, result)
+}
+
+func TestGenerateParseError(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\nfunc {\n"), 0o644))
+
+	result, err := Generate(dir, []string{"Options"}, nil)
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
@@ -0,0 +1,59 @@
+package basic
+
+import (
+	"context"
+	"io"
+
+	nl "github.com/klmitch/nelson"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type Serve struct{}
+
+func (c *Serve) Run(ctx context.Context, opts *Options, inv *nl.Invocation) error {
+	return nil
+}
+
+type List struct{}
+
+func (c List) Execute(w io.Writer, m yaml.MapSlice) (result []string, err error) {
+	return nil, nil
+}
+
+type Ping struct{}
+
+func (c *Ping) Run() {}
+
+func (c *Ping) Execute() error {
+	return nil
+}
+
+type Variadic struct{}
+
+func (c *Variadic) Run(args ...string) error {
+	return nil
+}
+
+type Duplicate struct{}
+
+func (c *Duplicate) Run(a, b string) error {
+	return nil
+}
+
+type NoError struct{}
+
+func (c *NoError) Run() string {
+	return ""
+}
+
+type TooMany struct{}
+
+func (c *TooMany) Run() (string, int, error) {
+	return "", 0, nil
+}
+
+type Unresolved struct{}
+
+func (c *Unresolved) Run(v missing.Value) error {
+	return nil
+}
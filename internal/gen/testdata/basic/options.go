@@ -0,0 +1,40 @@
+package basic
+
+import (
+	"net"
+	"time"
+)
+
+type Common struct {
+	Verbose bool `opt:"verbose,v"`
+	Debug   int  `opt:"debug" kind:"count"`
+}
+
+type Options struct {
+	Common
+	*Pointer
+	Name     string        `opt:"name"`
+	Tags     []string      `opt:"tag"`
+	Level    int8          `opt:"level"`
+	Size     uint64        `opt:"size"`
+	Ratio    float32       `opt:"ratio"`
+	Wait     time.Duration `opt:"wait"`
+	Waits    []int         `opt:"waits"`
+	Addr     net.IP        `opt:"addr"`
+	When     time.Time     `opt:"when"`
+	Optional *string       `opt:"optional"`
+	Token    string        `opt:"token" kind:"secret"`
+	File     string        `arg:"FILE"`
+	Other    string
+	hidden   string `opt:"hidden"` //nolint:unused,structcheck
+}
+
+type Pointer struct {
+	Value string `opt:"value"`
+}
+
+type Empty struct {
+	Other string
+}
+
+type NotStruct int
@@ -14,7 +14,10 @@
 
 package interval
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+)
 
 // Interval describes a interval of values.  A Interval is normalized
 // to be a half-open interval, but the input text uses "[]" and "()"
@@ -31,8 +34,18 @@ func (r Interval) String() string {
 		return fmt.Sprintf("[%d]", r.Start)
 	}
 
+	// Handle unbounded intervals
+	start := fmt.Sprintf("[%d", r.Start)
+	if r.Start == math.MinInt64 {
+		start = "("
+	}
+	end := fmt.Sprintf("%d)", r.End)
+	if r.End == math.MaxInt64 {
+		end = ")"
+	}
+
 	// OK, construct the interval notation
-	return fmt.Sprintf("[%d,%d)", r.Start, r.End)
+	return start + "," + end
 }
 
 // Includes tests to see if a specified number falls within the
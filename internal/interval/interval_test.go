@@ -1,6 +1,7 @@
 package interval
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -28,6 +29,39 @@ func TestIntervalStringOne(t *testing.T) {
 	assert.Equal(t, "[1]", result)
 }
 
+func TestIntervalStringUnboundedStart(t *testing.T) {
+	obj := Interval{
+		Start: math.MinInt64,
+		End:   7,
+	}
+
+	result := obj.String()
+
+	assert.Equal(t, "(,7)", result)
+}
+
+func TestIntervalStringUnboundedEnd(t *testing.T) {
+	obj := Interval{
+		Start: 1,
+		End:   math.MaxInt64,
+	}
+
+	result := obj.String()
+
+	assert.Equal(t, "[1,)", result)
+}
+
+func TestIntervalStringUnbounded(t *testing.T) {
+	obj := Interval{
+		Start: math.MinInt64,
+		End:   math.MaxInt64,
+	}
+
+	result := obj.String()
+
+	assert.Equal(t, "(,)", result)
+}
+
 func TestIntervalIncludesLow(t *testing.T) {
 	obj := Interval{
 		Start: 1,
@@ -0,0 +1,181 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package scaffold generates the skeleton of an application built
+// with nelson, and of the commands added to it.
+package scaffold
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Errors returned by the scaffolding.
+var (
+	ErrBadName   = errors.New("invalid command name")
+	ErrNoName    = errors.New("application name is required")
+	ErrExists    = errors.New("file already exists")
+	ErrNoProject = errors.New("no scaffolded application found")
+)
+
+// SampleCommand is the name of the sample command created by Init.
+const SampleCommand = "hello"
+
+// RootFile is the name of the file declaring the root command.  Its
+// presence identifies a scaffolded application.
+const RootFile = "root.go"
+
+// Project describes the application to scaffold.
+type Project struct {
+	Name   string // Name of the application, used as the executable name
+	Module string // Module path; if set, a go.mod is created
+}
+
+// command describes a command to scaffold.
+type command struct {
+	Name  string // Name of the command, as typed by the user
+	Ident string // Go identifier derived from the name
+	Type  string // Exported form of the identifier
+}
+
+// file describes a file to create.
+type file struct {
+	name  string             // Name of the file, relative to the directory
+	tmpl  *template.Template // Template producing the contents
+	data  interface{}        // Data for the template
+	gofmt bool               // If true, the contents are Go source
+}
+
+// namePattern describes valid command names.
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`)
+
+// Init creates the skeleton of an application in the directory: a
+// main.go constructing the App, a root command, the sample command,
+// and tests of them using the nelsontest harness.  No file is
+// written if any of them already exists.  Returns the names of the
+// files created.
+func Init(dir string, p Project) ([]string, error) {
+	if p.Name == "" {
+		return nil, ErrNoName
+	}
+	cmd, err := newCommand(SampleCommand)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []file{
+		{name: "main.go", tmpl: mainTmpl, data: p, gofmt: true},
+		{name: RootFile, tmpl: rootTmpl, data: p, gofmt: true},
+		{name: "main_test.go", tmpl: mainTestTmpl, data: p, gofmt: true},
+	}
+	if p.Module != "" {
+		files = append(files, file{name: "go.mod", tmpl: modTmpl, data: p})
+	}
+	files = append(files, commandFiles(cmd)...)
+
+	return write(dir, files)
+}
+
+// NewCommand adds a command to the application scaffolded in the
+// directory, in a file named for the command, along with its test.
+// The command registers itself as a subcommand of the root command.
+// Returns the names of the files created.
+func NewCommand(dir, name string) ([]string, error) {
+	cmd, err := newCommand(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, RootFile)); err != nil {
+		return nil, fmt.Errorf("%w in %s: %s", ErrNoProject, dir, err)
+	}
+
+	return write(dir, commandFiles(cmd))
+}
+
+// newCommand is a helper that validates the name of a command and
+// derives its identifiers, e.g., "list-all" becomes "listAll" and
+// "ListAll".
+func newCommand(name string) (command, error) {
+	if !namePattern.MatchString(name) {
+		return command{}, fmt.Errorf("%w %q: must be lower-case words separated by hyphens", ErrBadName, name)
+	}
+
+	words := strings.Split(name, "-")
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+	}
+
+	ident := strings.Join(words, "")
+	return command{
+		Name:  name,
+		Ident: ident,
+		Type:  strings.ToUpper(ident[:1]) + ident[1:],
+	}, nil
+}
+
+// commandFiles is a helper that returns the files of a command.
+func commandFiles(cmd command) []file {
+	base := strings.ReplaceAll(cmd.Name, "-", "_")
+	return []file{
+		{name: base + ".go", tmpl: commandTmpl, data: cmd, gofmt: true},
+		{name: base + "_test.go", tmpl: commandTestTmpl, data: cmd, gofmt: true},
+	}
+}
+
+// write is a helper that renders the files and writes them to the
+// directory, after checking that none of them exist.
+func write(dir string, files []file) ([]string, error) {
+	contents := make([][]byte, len(files))
+	for i, f := range files {
+		path := filepath.Join(dir, f.name)
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("%w: %s", ErrExists, path)
+		}
+
+		buf := &bytes.Buffer{}
+		if err := f.tmpl.Execute(buf, f.data); err != nil {
+			return nil, err
+		}
+		contents[i] = buf.Bytes()
+		if f.gofmt {
+			src, err := format.Source(contents[i])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.name, err)
+			}
+			contents[i] = src
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	created := make([]string, 0, len(files))
+	for i, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := ioutil.WriteFile(path, contents[i], 0o644); err != nil { //nolint:gosec
+			return created, err
+		}
+		created = append(created, path)
+	}
+
+	return created, nil
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package scaffold
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInit(t *testing.T) {
+	dir := t.TempDir()
+
+	result, err := Init(dir, Project{Name: "demo"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "main.go"),
+		filepath.Join(dir, "root.go"),
+		filepath.Join(dir, "main_test.go"),
+		filepath.Join(dir, "hello.go"),
+		filepath.Join(dir, "hello_test.go"),
+	}, result)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "main.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "\t\tName: \"demo\",\n")
+	data, err = ioutil.ReadFile(filepath.Join(dir, "hello.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "\tsubcommands[\"hello\"] = newHelloCommand()\n")
+	assert.NoFileExists(t, filepath.Join(dir, "go.mod"))
+}
+
+func TestInitModule(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := Init(dir, Project{Name: "demo", Module: "example.com/demo"})
+
+	assert.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	assert.NoError(t, err)
+	assert.Equal(t, "module example.com/demo\n\ngo 1.15\n", string(data))
+}
+
+func TestInitCreatesDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "demo")
+
+	_, err := Init(dir, Project{Name: "demo"})
+
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "main.go"))
+}
+
+func TestInitNoName(t *testing.T) {
+	dir := t.TempDir()
+
+	result, err := Init(dir, Project{})
+
+	assert.ErrorIs(t, err, ErrNoName)
+	assert.Nil(t, result)
+	assert.NoFileExists(t, filepath.Join(dir, "main.go"))
+}
+
+func TestInitExists(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "hello.go"), []byte("package main\n"), 0o644))
+
+	result, err := Init(dir, Project{Name: "demo"})
+
+	assert.ErrorIs(t, err, ErrExists)
+	assert.Nil(t, result)
+	assert.NoFileExists(t, filepath.Join(dir, "main.go"))
+}
+
+func TestNewCommand(t *testing.T) {
+	dir := t.TempDir()
+	_, err := Init(dir, Project{Name: "demo"})
+	assert.NoError(t, err)
+
+	result, err := NewCommand(dir, "list-all")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "list_all.go"),
+		filepath.Join(dir, "list_all_test.go"),
+	}, result)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "list_all.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "\tsubcommands[\"list-all\"] = newListAllCommand()\n")
+	assert.Contains(t, string(data), "type listAllOptions struct {\n")
+	data, err = ioutil.ReadFile(filepath.Join(dir, "list_all_test.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "func TestListAllCommand(t *testing.T) {\n")
+}
+
+func TestNewCommandNoProject(t *testing.T) {
+	dir := t.TempDir()
+
+	result, err := NewCommand(dir, "list")
+
+	assert.ErrorIs(t, err, ErrNoProject)
+	assert.Nil(t, result)
+	assert.NoFileExists(t, filepath.Join(dir, "list.go"))
+}
+
+func TestNewCommandBadName(t *testing.T) {
+	dir := t.TempDir()
+
+	result, err := NewCommand(dir, "List")
+
+	assert.ErrorIs(t, err, ErrBadName)
+	assert.Nil(t, result)
+}
+
+func TestNewCommandIdent(t *testing.T) {
+	result, err := newCommand("list-all2-x")
+
+	assert.NoError(t, err)
+	assert.Equal(t, command{Name: "list-all2-x", Ident: "listAll2X", Type: "ListAll2X"}, result)
+}
+
+func TestNewCommandInvalid(t *testing.T) {
+	for _, name := range []string{"", "2go", "a--b", "a-", "a_b", "Ab"} {
+		_, err := newCommand(name)
+
+		assert.ErrorIs(t, err, ErrBadName, name)
+	}
+}
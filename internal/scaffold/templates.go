@@ -0,0 +1,159 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package scaffold
+
+import "text/template"
+
+// mainTmpl is the template for main.go.
+var mainTmpl = template.Must(template.New("main.go").Parse(`// Command {{.Name}} is built with the nelson command line framework.
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/klmitch/nelson"
+)
+
+// version is the version of the application.
+var version = "0.1.0"
+
+// newApp constructs the application.
+func newApp() *nelson.App {
+	return (&nelson.App{
+		Name: {{printf "%q" .Name}},
+		Root: newRootCommand(),
+	}).WithVersion(version).WithVerbosity(true)
+}
+
+func main() {
+	newApp().Run(context.Background(), os.Args[1:])
+}
+`))
+
+// rootTmpl is the template for the root command.
+var rootTmpl = template.Must(template.New("root.go").Parse(`package main
+
+import "github.com/klmitch/nelson"
+
+// subcommands are the subcommands of the root command.  Each command
+// adds itself from an init function in its own file.
+var subcommands = map[string]nelson.ICommand{}
+
+// newRootCommand constructs the root command.
+func newRootCommand() nelson.ICommand {
+	return &nelson.Command{
+		Summary:     {{printf "%q" (printf "The %s tool." .Name)}},
+		Subcommands: subcommands,
+	}
+}
+`))
+
+// mainTestTmpl is the template for the tests of the application.
+var mainTestTmpl = template.Must(template.New("main_test.go").Parse(`package main
+
+import (
+	"testing"
+
+	"github.com/klmitch/nelson/nelsontest"
+)
+
+// TestHelp compares the help of every command with the golden files
+// in testdata.  Run "go test -update" to regenerate them after
+// changing the commands.
+func TestHelp(t *testing.T) {
+	nelsontest.AssertHelp(t, newApp(), "testdata")
+}
+
+// TestSpec compares the command line interface with the snapshot in
+// testdata, so that changes to it are explicit.  Run "go test
+// -update" to regenerate it after changing the commands.
+func TestSpec(t *testing.T) {
+	nelsontest.AssertSpec(t, newApp(), "testdata/cli.json")
+}
+`))
+
+// modTmpl is the template for go.mod.
+var modTmpl = template.Must(template.New("go.mod").Parse(`module {{.Module}}
+
+go 1.15
+`))
+
+// commandTmpl is the template for a command.
+var commandTmpl = template.Must(template.New("command").Parse(`package main
+
+import (
+	"fmt"
+
+	"github.com/klmitch/nelson"
+)
+
+func init() {
+	subcommands[{{printf "%q" .Name}}] = new{{.Type}}Command()
+}
+
+// {{.Ident}}Options are the flags and arguments of the {{.Name}}
+// command.
+type {{.Ident}}Options struct {
+	Greeting string ` + "`" + `opt:"greeting,g" help:"The greeting to use"` + "`" + `
+	Name     string ` + "`" + `arg:"NAME" arity:"[0,1]" help:"The name to greet"` + "`" + `
+}
+
+// {{.Ident}}Command implements the {{.Name}} command.
+type {{.Ident}}Command struct {
+	nelson.Command
+}
+
+// new{{.Type}}Command constructs the {{.Name}} command.
+func new{{.Type}}Command() *{{.Ident}}Command {
+	return &{{.Ident}}Command{
+		Command: nelson.Command{
+			Summary:  "Print a greeting.",
+			Defaults: &{{.Ident}}Options{Greeting: "Hello", Name: "world"},
+		},
+	}
+}
+
+// Run runs the {{.Name}} command.
+func (c *{{.Ident}}Command) Run(opts *{{.Ident}}Options, streams *nelson.IOStreams) error {
+	_, err := fmt.Fprintf(streams.Out, "%s, %s!\n", opts.Greeting, opts.Name)
+	return err
+}
+`))
+
+// commandTestTmpl is the template for the tests of a command.
+var commandTestTmpl = template.Must(template.New("command_test").Parse(`package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func Test{{.Type}}Command(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	app := newApp()
+	app.Stdout = stdout
+
+	err := app.Dispatch(context.Background(), []string{ {{- printf "%q" .Name}}, "--greeting", "Hi", "there"})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result := stdout.String(); result != "Hi, there!\n" {
+		t.Errorf("unexpected output %q", result)
+	}
+}
+`))
@@ -0,0 +1,135 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package shlex
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/klmitch/nelson/internal/parser"
+)
+
+// ErrUnterminated indicates that the text ended in the middle of a
+// quoted string or escape sequence.
+var ErrUnterminated = errors.New("unterminated quote or escape")
+
+// Splitter states.
+const (
+	stateSpace        = iota // Between words
+	stateWord                // Reading an unquoted word
+	stateEscape              // After a backslash in a word
+	stateSingle              // Within single quotes
+	stateDouble              // Within double quotes
+	stateDoubleEscape        // After a backslash within double quotes
+)
+
+// state describes the splitter state.
+type state struct {
+	Words  []string        // The words split from the text
+	Word   strings.Builder // The word being constructed
+	InWord bool            // Flag indicating a word has been started
+	State  int             // State of the splitter
+}
+
+// Parse processes a single character from the input.
+func (s *state) Parse(pos int, char rune) error {
+	switch s.State {
+	case stateSpace, stateWord:
+		switch {
+		case unicode.IsSpace(char):
+			s.finish()
+			s.State = stateSpace
+		case char == '\\':
+			s.State = stateEscape
+		case char == '\'':
+			s.State = stateSingle
+		case char == '"':
+			s.State = stateDouble
+		default:
+			s.Word.WriteRune(char)
+			s.State = stateWord
+		}
+		s.InWord = s.InWord || s.State != stateSpace
+
+	case stateEscape:
+		if char != '\n' {
+			s.Word.WriteRune(char)
+		}
+		s.State = stateWord
+
+	case stateSingle:
+		if char == '\'' {
+			s.State = stateWord
+		} else {
+			s.Word.WriteRune(char)
+		}
+
+	case stateDouble:
+		switch char {
+		case '"':
+			s.State = stateWord
+		case '\\':
+			s.State = stateDoubleEscape
+		default:
+			s.Word.WriteRune(char)
+		}
+
+	case stateDoubleEscape:
+		switch char {
+		case '"', '\\', '$', '`':
+			s.Word.WriteRune(char)
+		case '\n':
+		default:
+			s.Word.WriteRune('\\')
+			s.Word.WriteRune(char)
+		}
+		s.State = stateDouble
+	}
+
+	return nil
+}
+
+// finish completes the word being constructed, if any.
+func (s *state) finish() {
+	if s.InWord {
+		s.Words = append(s.Words, s.Word.String())
+		s.Word.Reset()
+		s.InWord = false
+	}
+}
+
+// Split splits text into words separated by whitespace, in the
+// manner of a POSIX shell: single quotes preserve their contents
+// literally; double quotes preserve their contents, except that a
+// backslash escapes a following double quote, backslash, dollar
+// sign, or backquote; and outside of quotes, a backslash escapes
+// the following character.  No other shell expansions are
+// performed.
+func Split(text string) ([]string, error) {
+	s := &state{}
+
+	// Split the text; the splitter itself never fails
+	_ = parser.Parse(text, s)
+
+	// Make sure we finished processing
+	if s.State != stateSpace && s.State != stateWord {
+		return nil, fmt.Errorf("%w in %q", ErrUnterminated, text)
+	}
+	s.finish()
+
+	return s.Words, nil
+}
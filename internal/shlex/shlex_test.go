@@ -0,0 +1,83 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package shlex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/klmitch/nelson/internal/parser"
+)
+
+func TestStateImplementsState(t *testing.T) {
+	assert.Implements(t, (*parser.State)(nil), &state{})
+}
+
+func TestSplitBase(t *testing.T) {
+	result, err := Split("  one two\tthree\n\nfour  ")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one", "two", "three", "four"}, result)
+}
+
+func TestSplitEmpty(t *testing.T) {
+	result, err := Split(" \n ")
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestSplitSingleQuotes(t *testing.T) {
+	result, err := Split(`'one two' th'r\ee' ''`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one two", `thr\ee`, ""}, result)
+}
+
+func TestSplitDoubleQuotes(t *testing.T) {
+	result, err := Split(`"one \"two\"" "a\b\\c\$" "line\` + "\n" + `cont" ""`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`one "two"`, `a\b\c$`, "linecont", ""}, result)
+}
+
+func TestSplitEscape(t *testing.T) {
+	result, err := Split(`one\ two \'three\` + "\n" + `four`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one two", "'threefour"}, result)
+}
+
+func TestSplitUnterminatedSingle(t *testing.T) {
+	result, err := Split(`one 'two`)
+
+	assert.ErrorIs(t, err, ErrUnterminated)
+	assert.Nil(t, result)
+}
+
+func TestSplitUnterminatedDouble(t *testing.T) {
+	result, err := Split(`one "two\`)
+
+	assert.ErrorIs(t, err, ErrUnterminated)
+	assert.Nil(t, result)
+}
+
+func TestSplitUnterminatedEscape(t *testing.T) {
+	result, err := Split(`one \`)
+
+	assert.ErrorIs(t, err, ErrUnterminated)
+	assert.Nil(t, result)
+}
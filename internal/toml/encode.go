@@ -0,0 +1,245 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package toml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedType indicates that a value cannot be encoded as
+// TOML.
+var ErrUnsupportedType = errors.New("toml: unsupported type")
+
+// Marshal encodes a map as a TOML document.  Keys are sorted; values
+// that are not tables precede the tables of each level.  Tables are
+// map[string]interface{}, and arrays are []interface{} or []string;
+// arrays consisting entirely of tables are encoded as arrays of
+// tables.  Scalars may be strings, booleans, integers, floats,
+// time.Time values, or values with a String method, such as
+// json.Number, whose text is a valid TOML scalar.
+func Marshal(doc map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeTable(&buf, nil, doc); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeTable encodes the contents of a table, whose path is given.
+func encodeTable(buf *bytes.Buffer, path []string, table map[string]interface{}) error {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Encode the key/value pairs, then the tables
+	var tables, arrays []string
+	for _, key := range keys {
+		switch value := table[key].(type) {
+		case map[string]interface{}:
+			tables = append(tables, key)
+			continue
+		case []interface{}:
+			if isTableArray(value) {
+				arrays = append(arrays, key)
+				continue
+			}
+		}
+		text, err := encodeValue(table[key])
+		if err != nil {
+			return fmt.Errorf("%w for key %s", err, strings.Join(append(path, key), "."))
+		}
+		fmt.Fprintf(buf, "%s = %s\n", encodeKey(key), text)
+	}
+	for _, key := range tables {
+		sub := append(append([]string{}, path...), key)
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(buf, "[%s]\n", encodePath(sub))
+		if err := encodeTable(buf, sub, table[key].(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+	for _, key := range arrays {
+		sub := append(append([]string{}, path...), key)
+		for _, item := range table[key].([]interface{}) {
+			if buf.Len() > 0 {
+				buf.WriteString("\n")
+			}
+			fmt.Fprintf(buf, "[[%s]]\n", encodePath(sub))
+			if err := encodeTable(buf, sub, item.(map[string]interface{})); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// isTableArray returns true if an array is non-empty and consists
+// entirely of tables.
+func isTableArray(values []interface{}) bool {
+	for _, value := range values {
+		if _, ok := value.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+
+	return len(values) > 0
+}
+
+// encodePath encodes the path of a table.
+func encodePath(path []string) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = encodeKey(key)
+	}
+
+	return strings.Join(keys, ".")
+}
+
+// encodeKey encodes a key, quoting it unless it is a bare key.
+func encodeKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for i := 0; i < len(key); i++ {
+		if !isBare(key[i]) {
+			return encodeString(key)
+		}
+	}
+
+	return key
+}
+
+// encodeString encodes a basic string.
+func encodeString(text string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range text {
+		switch r {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+
+	return sb.String()
+}
+
+// encodeValue encodes a value other than a table or array of tables.
+func encodeValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return encodeString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return encodeFloat(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case fmt.Stringer:
+		return v.String(), nil
+
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return encodeValue(items)
+
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			text, err := encodeValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = text
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, key := range keys {
+			text, err := encodeValue(v[key])
+			if err != nil {
+				return "", err
+			}
+			items[i] = encodeKey(key) + " = " + text
+		}
+		return "{" + strings.Join(items, ", ") + "}", nil
+	}
+
+	return "", fmt.Errorf("%w %T", ErrUnsupportedType, value)
+}
+
+// encodeFloat encodes a float, ensuring that it is not mistaken for
+// an integer.
+func encodeFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+
+	text := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(text, ".eEn") {
+		text += ".0"
+	}
+
+	return text
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package toml
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshal(t *testing.T) {
+	result, err := Marshal(map[string]interface{}{
+		"name":    "tool \"quoted\"\n",
+		"verbose": true,
+		"count":   3,
+		"big":     int64(1 << 40),
+		"ratio":   2.0,
+		"number":  json.Number("8080"),
+		"when":    time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+		"tags":    []interface{}{"a", "b"},
+		"names":   []string{"x"},
+		"key.dot": "quoted",
+		"server": map[string]interface{}{
+			"port": int64(80),
+			"tls": map[string]interface{}{
+				"enabled": false,
+			},
+		},
+		"remote": []interface{}{
+			map[string]interface{}{"name": "origin"},
+			map[string]interface{}{"name": "upstream"},
+		},
+		"mixed": []interface{}{int64(1), map[string]interface{}{"a": "b"}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `big = 1099511627776
+count = 3
+"key.dot" = "quoted"
+mixed = [1, {a = "b"}]
+name = "tool \"quoted\"\n"
+names = ["x"]
+number = 8080
+ratio = 2.0
+tags = ["a", "b"]
+verbose = true
+when = 2021-03-04T05:06:07Z
+
+[server]
+port = 80
+
+[server.tls]
+enabled = false
+
+[[remote]]
+name = "origin"
+
+[[remote]]
+name = "upstream"
+`, string(result))
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	doc := map[string]interface{}{
+		"text":  "tab\there \u0001",
+		"float": 1.5e300,
+		"inf":   math.Inf(-1),
+		"a": map[string]interface{}{
+			"b": map[string]interface{}{"c": []interface{}{int64(1), int64(2)}},
+			"":  "empty key",
+		},
+	}
+
+	data, err := Marshal(doc)
+	assert.NoError(t, err)
+	result, err := Unmarshal(data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, doc, result)
+}
+
+func TestMarshalUnsupported(t *testing.T) {
+	result, err := Marshal(map[string]interface{}{
+		"a": map[string]interface{}{"b": struct{}{}},
+	})
+
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+	assert.EqualError(t, err, "toml: unsupported type struct {} for key a.b")
+	assert.Nil(t, result)
+}
+
+func TestEncodeFloat(t *testing.T) {
+	assert.Equal(t, "1.0", encodeFloat(1))
+	assert.Equal(t, "0.5", encodeFloat(0.5))
+	assert.Equal(t, "1e+21", encodeFloat(1e21))
+	assert.Equal(t, "inf", encodeFloat(math.Inf(1)))
+	assert.Equal(t, "nan", encodeFloat(math.NaN()))
+}
@@ -0,0 +1,750 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package toml decodes and encodes the subset of TOML needed for
+// configuration files: tables, arrays of tables, dotted keys,
+// strings, integers, floats, booleans, dates and times, arrays, and
+// inline tables.  Documents are decoded into the same shapes produced
+// by decoding YAML into an interface{}: tables become
+// map[string]interface{}, arrays become []interface{}, integers
+// become int64, offset and local date-times and local dates become
+// time.Time (in UTC, if the offset is not given), and local times
+// remain strings.
+package toml
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrSyntax indicates that a document is not valid TOML.
+var ErrSyntax = errors.New("toml syntax error")
+
+// Layouts of date and time values, tried in order.
+var timeLayouts = []struct {
+	layout string
+	local  bool
+}{
+	{"2006-01-02T15:04:05.999999999Z07:00", false},
+	{"2006-01-02T15:04:05.999999999", true},
+	{"2006-01-02", true},
+}
+
+// decoder holds the state of the decoder.
+type decoder struct {
+	text    string                 // The document
+	pos     int                    // Position of the next character
+	line    int                    // Current line number
+	start   int                    // Position of the start of the current line
+	root    map[string]interface{} // The root table
+	current map[string]interface{} // The table receiving key/value pairs
+	defined map[string]bool        // Tables defined by headers
+	inline  map[string]bool        // Paths of inline tables and static arrays, which may not be extended
+	prefix  []string               // Path of the current table
+}
+
+// Unmarshal decodes a TOML document into a map.
+func Unmarshal(data []byte) (map[string]interface{}, error) {
+	d := &decoder{
+		text:    string(data),
+		line:    1,
+		root:    map[string]interface{}{},
+		defined: map[string]bool{},
+		inline:  map[string]bool{},
+	}
+	d.current = d.root
+
+	if err := d.document(); err != nil {
+		return nil, err
+	}
+
+	return d.root, nil
+}
+
+// errorf constructs an error describing a syntax error at the current
+// line and column.
+func (d *decoder) errorf(format string, args ...interface{}) error {
+	return d.errorAt(d.pos, format, args...)
+}
+
+// errorAt constructs an error describing a syntax error at the
+// specified position of the current line.
+func (d *decoder) errorAt(pos int, format string, args ...interface{}) error {
+	column := utf8.RuneCountInString(d.text[d.start:pos]) + 1
+	return fmt.Errorf("%w at line %d, column %d: %s", ErrSyntax, d.line, column, fmt.Sprintf(format, args...))
+}
+
+// eof returns true if the entire document has been consumed.
+func (d *decoder) eof() bool {
+	return d.pos >= len(d.text)
+}
+
+// peek returns the next character without consuming it, or 0 at the
+// end of the document.
+func (d *decoder) peek() byte {
+	if d.eof() {
+		return 0
+	}
+
+	return d.text[d.pos]
+}
+
+// next consumes the next character, counting lines.
+func (d *decoder) next() byte {
+	c := d.text[d.pos]
+	d.pos++
+	if c == '\n' {
+		d.line++
+		d.start = d.pos
+	}
+
+	return c
+}
+
+// consume consumes the prefix, if present, returning true if it was.
+func (d *decoder) consume(prefix string) bool {
+	if !strings.HasPrefix(d.text[d.pos:], prefix) {
+		return false
+	}
+	for range prefix {
+		d.next()
+	}
+
+	return true
+}
+
+// skipSpace skips spaces and tabs.
+func (d *decoder) skipSpace() {
+	for c := d.peek(); c == ' ' || c == '\t'; c = d.peek() {
+		d.next()
+	}
+}
+
+// skipComment skips a comment, if present, up to the end of the line.
+func (d *decoder) skipComment() {
+	if d.peek() != '#' {
+		return
+	}
+	for !d.eof() && d.peek() != '\n' {
+		d.next()
+	}
+}
+
+// skipBlank skips whitespace, line breaks, and comments.
+func (d *decoder) skipBlank() {
+	for {
+		d.skipSpace()
+		d.skipComment()
+		switch {
+		case d.consume("\n"), d.consume("\r\n"):
+		default:
+			return
+		}
+	}
+}
+
+// endOfLine consumes the end of a line, which may contain a comment.
+func (d *decoder) endOfLine() error {
+	d.skipSpace()
+	d.skipComment()
+	if d.eof() || d.consume("\n") || d.consume("\r\n") {
+		return nil
+	}
+
+	return d.errorf("unexpected %q after value", d.peek())
+}
+
+// document decodes the document.
+func (d *decoder) document() error {
+	for {
+		d.skipBlank()
+		if d.eof() {
+			return nil
+		}
+
+		var err error
+		switch {
+		case d.consume("[["):
+			err = d.arrayTable()
+		case d.consume("["):
+			err = d.table()
+		default:
+			err = d.keyValue(d.current, d.prefix)
+		}
+		if err != nil {
+			return err
+		}
+		if err := d.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// pathKey is a helper that constructs a key identifying a path.
+func pathKey(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+// descend returns the table at the path within the root table,
+// creating tables as needed.  If a key names an array of tables, the
+// last table of the array is selected.
+func (d *decoder) descend(path []string) (map[string]interface{}, error) {
+	table := d.root
+	for i, key := range path {
+		if d.inline[pathKey(path[:i+1])] {
+			return nil, d.errorf("cannot extend %s", strings.Join(path[:i+1], "."))
+		}
+		switch value := table[key].(type) {
+		case nil:
+			tmp := map[string]interface{}{}
+			table[key] = tmp
+			table = tmp
+
+		case map[string]interface{}:
+			table = value
+
+		case []interface{}:
+			last, ok := value[len(value)-1].(map[string]interface{})
+			if !ok {
+				return nil, d.errorf("key %s is not a table", strings.Join(path[:i+1], "."))
+			}
+			table = last
+
+		default:
+			return nil, d.errorf("key %s is not a table", strings.Join(path[:i+1], "."))
+		}
+	}
+
+	return table, nil
+}
+
+// table decodes a table header.
+func (d *decoder) table() error {
+	path, err := d.key()
+	if err != nil {
+		return err
+	}
+	if !d.consume("]") {
+		return d.errorf("expected ] after table name")
+	}
+
+	name := pathKey(path)
+	if d.defined[name] {
+		return d.errorf("table %s defined more than once", strings.Join(path, "."))
+	}
+	if _, isArray := d.lookup(path).([]interface{}); isArray {
+		return d.errorf("key %s is not a table", strings.Join(path, "."))
+	}
+	table, err := d.descend(path)
+	if err != nil {
+		return err
+	}
+	d.defined[name] = true
+	d.current, d.prefix = table, path
+
+	return nil
+}
+
+// arrayTable decodes the header of a table in an array of tables.
+func (d *decoder) arrayTable() error {
+	path, err := d.key()
+	if err != nil {
+		return err
+	}
+	if !d.consume("]]") {
+		return d.errorf("expected ]] after table name")
+	}
+
+	parent, err := d.descend(path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	last := path[len(path)-1]
+	if d.inline[pathKey(path)] {
+		return d.errorf("cannot extend %s", strings.Join(path, "."))
+	}
+	var array []interface{}
+	switch value := parent[last].(type) {
+	case nil:
+	case []interface{}:
+		array = value
+	default:
+		return d.errorf("key %s is not an array of tables", strings.Join(path, "."))
+	}
+
+	// Tables below the path may be defined again in the new table
+	name := pathKey(path)
+	for key := range d.defined {
+		if strings.HasPrefix(key, name+"\x00") {
+			delete(d.defined, key)
+		}
+	}
+
+	table := map[string]interface{}{}
+	parent[last] = append(array, table)
+	d.current, d.prefix = table, path
+
+	return nil
+}
+
+// lookup returns the value at the path within the root table, if
+// any, without descending into arrays of tables.
+func (d *decoder) lookup(path []string) interface{} {
+	var value interface{} = d.root
+	for _, key := range path {
+		table, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = table[key]
+	}
+
+	return value
+}
+
+// keyValue decodes a key/value pair into the table, whose path is
+// given.
+func (d *decoder) keyValue(table map[string]interface{}, prefix []string) error {
+	path, err := d.key()
+	if err != nil {
+		return err
+	}
+	d.skipSpace()
+	if !d.consume("=") {
+		return d.errorf("expected = after key")
+	}
+	d.skipSpace()
+	value, err := d.value(append(append([]string{}, prefix...), path...))
+	if err != nil {
+		return err
+	}
+
+	// Descend through the dotted keys
+	for i, key := range path[:len(path)-1] {
+		switch tmp := table[key].(type) {
+		case nil:
+			sub := map[string]interface{}{}
+			table[key] = sub
+			table = sub
+
+		case map[string]interface{}:
+			if d.inline[pathKey(append(append([]string{}, prefix...), path[:i+1]...))] {
+				return d.errorf("cannot extend %s", strings.Join(path[:i+1], "."))
+			}
+			table = tmp
+
+		default:
+			return d.errorf("key %s is not a table", strings.Join(path[:i+1], "."))
+		}
+	}
+
+	last := path[len(path)-1]
+	if _, ok := table[last]; ok {
+		return d.errorf("key %s defined more than once", strings.Join(path, "."))
+	}
+	table[last] = value
+
+	return nil
+}
+
+// key decodes a possibly dotted key, returning its elements.
+func (d *decoder) key() ([]string, error) {
+	var path []string
+	for {
+		d.skipSpace()
+		elem, err := d.simpleKey()
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, elem)
+		d.skipSpace()
+		if !d.consume(".") {
+			return path, nil
+		}
+	}
+}
+
+// simpleKey decodes a bare or quoted key.
+func (d *decoder) simpleKey() (string, error) {
+	switch d.peek() {
+	case '"':
+		d.next()
+		return d.basicString()
+	case '\'':
+		d.next()
+		return d.literalString()
+	}
+
+	start := d.pos
+	for c := d.peek(); isBare(c); c = d.peek() {
+		d.next()
+	}
+	if d.pos == start {
+		return "", d.errorf("expected key")
+	}
+
+	return d.text[start:d.pos], nil
+}
+
+// isBare returns true if the character may appear in a bare key.
+func isBare(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// value decodes a value.  The path identifies the value, for
+// recording inline tables and arrays.
+func (d *decoder) value(path []string) (interface{}, error) {
+	switch {
+	case d.consume(`"""`):
+		return d.multilineBasicString()
+	case d.consume(`"`):
+		return d.basicString()
+	case d.consume("'''"):
+		return d.multilineLiteralString()
+	case d.consume("'"):
+		return d.literalString()
+	case d.consume("["):
+		d.inline[pathKey(path)] = true
+		return d.array(path)
+	case d.consume("{"):
+		d.inline[pathKey(path)] = true
+		return d.inlineTable(path)
+	}
+
+	return d.scalar()
+}
+
+// basicString decodes a basic string, following the opening quote.
+func (d *decoder) basicString() (string, error) {
+	var sb strings.Builder
+	for {
+		switch {
+		case d.eof() || d.peek() == '\n':
+			return "", d.errorf("unterminated string")
+		case d.consume(`"`):
+			return sb.String(), nil
+		case d.peek() == '\\':
+			if err := d.escape(&sb, false); err != nil {
+				return "", err
+			}
+		default:
+			sb.WriteByte(d.next())
+		}
+	}
+}
+
+// multilineBasicString decodes a multi-line basic string, following
+// the opening quotes.
+func (d *decoder) multilineBasicString() (string, error) {
+	var sb strings.Builder
+	if !d.consume("\n") {
+		d.consume("\r\n")
+	}
+	for {
+		switch {
+		case d.eof():
+			return "", d.errorf("unterminated string")
+		case d.consume(`"""`):
+			// Up to two additional quotes belong to the string
+			for i := 0; i < 2 && d.consume(`"`); i++ {
+				sb.WriteByte('"')
+			}
+			return sb.String(), nil
+		case d.peek() == '\\':
+			if err := d.escape(&sb, true); err != nil {
+				return "", err
+			}
+		default:
+			sb.WriteByte(d.next())
+		}
+	}
+}
+
+// escape decodes an escape sequence in a basic string.  In multi-line
+// strings, a backslash at the end of a line removes the line break
+// and any whitespace following it.
+func (d *decoder) escape(sb *strings.Builder, multiline bool) error {
+	d.next()
+	if d.eof() {
+		return d.errorf("unterminated string")
+	}
+
+	c := d.next()
+	switch c {
+	case 'b':
+		sb.WriteByte('\b')
+	case 't':
+		sb.WriteByte('\t')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'r':
+		sb.WriteByte('\r')
+	case '"', '\\':
+		sb.WriteByte(c)
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if d.pos+size > len(d.text) {
+			return d.errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(d.text[d.pos:d.pos+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return d.errorf("invalid unicode escape")
+		}
+		d.pos += size
+		sb.WriteRune(rune(code))
+	case ' ', '\t', '\r', '\n':
+		if !multiline {
+			return d.errorf("invalid escape %q", c)
+		}
+		d.pos--
+		if c == '\n' {
+			d.line--
+			d.start = strings.LastIndexByte(d.text[:d.pos], '\n') + 1
+		}
+		d.skipSpace()
+		if d.peek() != '\n' && !strings.HasPrefix(d.text[d.pos:], "\r\n") {
+			return d.errorf("invalid escape")
+		}
+		for c := d.peek(); c == ' ' || c == '\t' || c == '\r' || c == '\n'; c = d.peek() {
+			d.next()
+		}
+	default:
+		return d.errorf("invalid escape %q", c)
+	}
+
+	return nil
+}
+
+// literalString decodes a literal string, following the opening
+// quote.
+func (d *decoder) literalString() (string, error) {
+	start := d.pos
+	for {
+		switch {
+		case d.eof() || d.peek() == '\n':
+			return "", d.errorf("unterminated string")
+		case d.peek() == '\'':
+			text := d.text[start:d.pos]
+			d.next()
+			return text, nil
+		default:
+			d.next()
+		}
+	}
+}
+
+// multilineLiteralString decodes a multi-line literal string,
+// following the opening quotes.
+func (d *decoder) multilineLiteralString() (string, error) {
+	if !d.consume("\n") {
+		d.consume("\r\n")
+	}
+	start := d.pos
+	for {
+		switch {
+		case d.eof():
+			return "", d.errorf("unterminated string")
+		case strings.HasPrefix(d.text[d.pos:], "'''"):
+			end := d.pos
+			d.consume("'''")
+			// Up to two additional quotes belong to the string
+			for i := 0; i < 2 && d.consume("'"); i++ {
+				end++
+			}
+			return d.text[start:end], nil
+		default:
+			d.next()
+		}
+	}
+}
+
+// array decodes an array, following the opening bracket.
+func (d *decoder) array(path []string) (interface{}, error) {
+	values := []interface{}{}
+	for {
+		d.skipBlank()
+		if d.consume("]") {
+			return values, nil
+		}
+		value, err := d.value(append(append([]string{}, path...), strconv.Itoa(len(values))))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		d.skipBlank()
+		if d.consume("]") {
+			return values, nil
+		}
+		if !d.consume(",") {
+			return nil, d.errorf("expected , or ] in array")
+		}
+	}
+}
+
+// inlineTable decodes an inline table, following the opening brace.
+func (d *decoder) inlineTable(path []string) (interface{}, error) {
+	table := map[string]interface{}{}
+	d.skipSpace()
+	if d.consume("}") {
+		return table, nil
+	}
+	for {
+		if err := d.keyValue(table, path); err != nil {
+			return nil, err
+		}
+		d.skipSpace()
+		if d.consume("}") {
+			return table, nil
+		}
+		if !d.consume(",") {
+			return nil, d.errorf("expected , or } in inline table")
+		}
+	}
+}
+
+// scalar decodes a boolean, number, date, or time.
+func (d *decoder) scalar() (interface{}, error) {
+	start := d.pos
+	for c := d.peek(); c != 0 && !strings.ContainsRune(" \t\r\n,]}#", rune(c)); c = d.peek() {
+		d.next()
+	}
+
+	// A date may be separated from a time by a space
+	if d.pos-start == 10 && isDate(d.text[start:d.pos]) && len(d.text) > d.pos+3 && d.text[d.pos] == ' ' && d.text[d.pos+3] == ':' {
+		d.next()
+		for c := d.peek(); c != 0 && !strings.ContainsRune(" \t\r\n,]}#", rune(c)); c = d.peek() {
+			d.next()
+		}
+	}
+
+	text := d.text[start:d.pos]
+	switch text {
+	case "":
+		return nil, d.errorf("expected value")
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+
+	if isDate(text) || len(text) > 2 && text[2] == ':' {
+		if value, ok := datetime(text); ok {
+			return value, nil
+		}
+		return nil, d.errorAt(start, "invalid date or time %q", text)
+	}
+	if value, ok := number(text); ok {
+		return value, nil
+	}
+
+	return nil, d.errorAt(start, "invalid number %q", text)
+}
+
+// isDate returns true if the text begins with a date.
+func isDate(text string) bool {
+	return len(text) >= 10 && text[4] == '-' && text[7] == '-'
+}
+
+// datetime decodes a date, time, or date-time.  Returns false if the
+// text is not valid.
+func datetime(text string) (interface{}, bool) {
+	if len(text) > 10 && (text[10] == ' ' || text[10] == 't') {
+		text = text[:10] + "T" + text[11:]
+	}
+	text = strings.Replace(text, "z", "Z", 1)
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout.layout, text); err == nil {
+			if layout.local {
+				t = t.UTC()
+			}
+			return t, true
+		}
+	}
+	if _, err := time.Parse("15:04:05.999999999", text); err == nil {
+		return text, true
+	}
+
+	return nil, false
+}
+
+// number decodes an integer or float.  Returns false if the text is
+// not valid.
+func number(text string) (interface{}, bool) {
+	if !validUnderscores(text) {
+		return nil, false
+	}
+	digits := strings.ReplaceAll(text, "_", "")
+
+	// Handle prefixed integers
+	if len(digits) > 2 && digits[0] == '0' && strings.ContainsRune("xob", rune(digits[1])) {
+		if i, err := strconv.ParseInt(digits, 0, 64); err == nil {
+			return i, true
+		}
+		return nil, false
+	}
+
+	// Leading zeros are not permitted
+	unsigned := strings.TrimLeft(digits, "+-")
+	if len(unsigned) > 1 && unsigned[0] == '0' && unsigned[1] >= '0' && unsigned[1] <= '9' {
+		return nil, false
+	}
+
+	if i, err := strconv.ParseInt(digits, 10, 64); err == nil {
+		return i, true
+	}
+	if strings.ContainsAny(unsigned, "0123456789") && !strings.ContainsAny(unsigned, "xXpP") {
+		if f, err := strconv.ParseFloat(digits, 64); err == nil && !strings.HasPrefix(unsigned, ".") && !strings.HasSuffix(unsigned, ".") {
+			return f, true
+		}
+	}
+
+	return nil, false
+}
+
+// validUnderscores returns true if each underscore in a number is
+// surrounded by digits.
+func validUnderscores(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] != '_' {
+			continue
+		}
+		if i == 0 || i == len(text)-1 || !isHexDigit(text[i-1]) || !isHexDigit(text[i+1]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isHexDigit returns true if the character is a hexadecimal digit.
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package toml
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalEmpty(t *testing.T) {
+	result, err := Unmarshal([]byte("# just a comment\n\n"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{}, result)
+}
+
+func TestUnmarshalKeys(t *testing.T) {
+	result, err := Unmarshal([]byte(`
+bare = 1
+bare-key_2 = 2
+"quoted key" = 3
+'literal.key' = 4
+dotted . key = 5
+dotted.other = 6
+`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"bare":        int64(1),
+		"bare-key_2":  int64(2),
+		"quoted key":  int64(3),
+		"literal.key": int64(4),
+		"dotted": map[string]interface{}{
+			"key":   int64(5),
+			"other": int64(6),
+		},
+	}, result)
+}
+
+func TestUnmarshalTables(t *testing.T) {
+	result, err := Unmarshal([]byte(`
+top = "top"
+
+[server]
+port = 8080 # the port
+
+[server.tls]
+enabled = true
+
+[client . retry]
+count = 3
+`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"top": "top",
+		"server": map[string]interface{}{
+			"port": int64(8080),
+			"tls": map[string]interface{}{
+				"enabled": true,
+			},
+		},
+		"client": map[string]interface{}{
+			"retry": map[string]interface{}{
+				"count": int64(3),
+			},
+		},
+	}, result)
+}
+
+func TestUnmarshalArrayTables(t *testing.T) {
+	result, err := Unmarshal([]byte(`
+[[remote]]
+name = "origin"
+
+[remote.fetch]
+refs = "all"
+
+[[remote]]
+name = "upstream"
+
+[remote.fetch]
+refs = "main"
+`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"remote": []interface{}{
+			map[string]interface{}{
+				"name":  "origin",
+				"fetch": map[string]interface{}{"refs": "all"},
+			},
+			map[string]interface{}{
+				"name":  "upstream",
+				"fetch": map[string]interface{}{"refs": "main"},
+			},
+		},
+	}, result)
+}
+
+func TestUnmarshalStrings(t *testing.T) {
+	result, err := Unmarshal([]byte(`
+basic = "tab\there \"quoted\" \u00e9 \U0001F600"
+literal = 'C:\path'
+multi = """
+line one
+line two \
+    joined"""
+quotes = """say "hi"""""
+raw = '''
+first
+second'''
+`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"basic":   "tab\there \"quoted\" \u00e9 \U0001F600",
+		"literal": `C:\path`,
+		"multi":   "line one\nline two joined",
+		"quotes":  `say "hi""`,
+		"raw":     "first\nsecond",
+	}, result)
+}
+
+func TestUnmarshalNumbers(t *testing.T) {
+	result, err := Unmarshal([]byte(`
+int = +42
+neg = -17
+big = 1_000_000
+hex = 0xdead_beef
+oct = 0o755
+bin = 0b1010
+float = 3.14
+exp = 5e+22
+under = 9_224.5
+`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"int":   int64(42),
+		"neg":   int64(-17),
+		"big":   int64(1000000),
+		"hex":   int64(0xdeadbeef),
+		"oct":   int64(0o755),
+		"bin":   int64(10),
+		"float": 3.14,
+		"exp":   5e+22,
+		"under": 9224.5,
+	}, result)
+}
+
+func TestUnmarshalSpecialFloats(t *testing.T) {
+	result, err := Unmarshal([]byte("a = inf\nb = -inf\nc = nan\n"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, math.Inf(1), result["a"])
+	assert.Equal(t, math.Inf(-1), result["b"])
+	assert.True(t, math.IsNaN(result["c"].(float64)))
+}
+
+func TestUnmarshalDates(t *testing.T) {
+	result, err := Unmarshal([]byte(`
+offset = 1979-05-27T07:32:00-07:00
+space = 1979-05-27 07:32:00Z
+local = 1979-05-27T07:32:00.5
+date = 1979-05-27
+time = 07:32:00
+`))
+
+	assert.NoError(t, err)
+	assert.True(t, time.Date(1979, 5, 27, 14, 32, 0, 0, time.UTC).Equal(result["offset"].(time.Time)))
+	assert.Equal(t, time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC), result["space"])
+	assert.Equal(t, time.Date(1979, 5, 27, 7, 32, 0, 500000000, time.UTC), result["local"])
+	assert.Equal(t, time.Date(1979, 5, 27, 0, 0, 0, 0, time.UTC), result["date"])
+	assert.Equal(t, "07:32:00", result["time"])
+}
+
+func TestUnmarshalArrays(t *testing.T) {
+	result, err := Unmarshal([]byte(`
+empty = []
+ints = [1, 2, 3]
+nested = [[1, 2], ["a"]]
+multi = [
+  "a", # first
+  "b",
+]
+`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"empty":  []interface{}{},
+		"ints":   []interface{}{int64(1), int64(2), int64(3)},
+		"nested": []interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{"a"}},
+		"multi":  []interface{}{"a", "b"},
+	}, result)
+}
+
+func TestUnmarshalInlineTables(t *testing.T) {
+	result, err := Unmarshal([]byte(`point = { x = 1, y.z = 2 }
+empty = {}
+`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"point": map[string]interface{}{
+			"x": int64(1),
+			"y": map[string]interface{}{"z": int64(2)},
+		},
+		"empty": map[string]interface{}{},
+	}, result)
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	for text, msg := range map[string]string{
+		"key":                "toml syntax error at line 1, column 4: expected = after key",
+		"= 1":                "toml syntax error at line 1, column 1: expected key",
+		"a = 1\na = 2":       "toml syntax error at line 2, column 6: key a defined more than once",
+		"[a]\n[a]":           "toml syntax error at line 2, column 4: table a defined more than once",
+		"a = 1\n[a]":         "toml syntax error at line 2, column 4: key a is not a table",
+		"a = 1 2":            "toml syntax error at line 1, column 7: unexpected '2' after value",
+		"a = \"open":         "toml syntax error at line 1, column 10: unterminated string",
+		"a = 'open":          "toml syntax error at line 1, column 10: unterminated string",
+		"a = \"\"\"open":     "toml syntax error at line 1, column 12: unterminated string",
+		"a = '''open":        "toml syntax error at line 1, column 12: unterminated string",
+		"a = \"\\q\"":        "toml syntax error at line 1, column 8: invalid escape 'q'",
+		"a = \"\\u12\"":      "toml syntax error at line 1, column 8: invalid unicode escape",
+		"a = [1 2]":          "toml syntax error at line 1, column 8: expected , or ] in array",
+		"a = {b = 1 c = 2}":  "toml syntax error at line 1, column 12: expected , or } in inline table",
+		"a = bogus":          "toml syntax error at line 1, column 5: invalid number \"bogus\"",
+		"a = 012":            "toml syntax error at line 1, column 5: invalid number \"012\"",
+		"a = 1__0":           "toml syntax error at line 1, column 5: invalid number \"1__0\"",
+		"a = 1.":             "toml syntax error at line 1, column 5: invalid number \"1.\"",
+		"a = 1979-13-45":     "toml syntax error at line 1, column 5: invalid date or time \"1979-13-45\"",
+		"a = {b = 1}\n[a]":   "toml syntax error at line 2, column 4: cannot extend a",
+		"a = {b = 1}\n[a.c]": "toml syntax error at line 2, column 6: cannot extend a",
+		"a = [1]\n[[a]]":     "toml syntax error at line 2, column 6: cannot extend a",
+		"[[a]]\n[a]":         "toml syntax error at line 2, column 4: key a is not a table",
+		"a = 1\n[[a]]":       "toml syntax error at line 2, column 6: key a is not an array of tables",
+		"a =":                "toml syntax error at line 1, column 4: expected value",
+		"[a":                 "toml syntax error at line 1, column 3: expected ] after table name",
+		"[[a]":               "toml syntax error at line 1, column 4: expected ]] after table name",
+	} {
+		result, err := Unmarshal([]byte(text))
+
+		assert.ErrorIs(t, err, ErrSyntax, text)
+		assert.EqualError(t, err, msg, text)
+		assert.Nil(t, result, text)
+	}
+}
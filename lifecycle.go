@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+// Names of the lifecycle hooks, used in error messages.
+const (
+	onStartName    = "OnStart"
+	onResolvedName = "OnCommandResolved"
+	onExitName     = "OnExit"
+)
+
+// OnStart registers a function to be called when the application
+// begins dispatching, before the command is resolved.  The function
+// is called through the injector, so it may declare any arguments
+// available from the injector; it may return nothing or an error.
+// If the function returns an error, dispatching stops and the error
+// is returned.  Returns the App, to allow chaining.
+func (a *App) OnStart(fn interface{}) *App {
+	a.onStart = append(a.onStart, fn)
+	return a
+}
+
+// OnCommandResolved registers a function to be called once the
+// command has been resolved, before it is run.  The function is
+// called through the injector, and the resolved Invocation is
+// available.  If the function returns an error, the command is not
+// run and the error is returned.  Returns the App, to allow
+// chaining.
+func (a *App) OnCommandResolved(fn interface{}) *App {
+	a.onResolved = append(a.onResolved, fn)
+	return a
+}
+
+// OnExit registers a function to be called when dispatching
+// completes, whether or not it succeeded.  The function is called
+// through the injector, and the outcome of dispatching is available
+// as an error, which is nil on success.  All OnExit functions are
+// called; if dispatching succeeded, the first error returned by an
+// OnExit function is returned.  Returns the App, to allow chaining.
+func (a *App) OnExit(fn interface{}) *App {
+	a.onExit = append(a.onExit, fn)
+	return a
+}
+
+// callHooks is a helper that calls lifecycle hook functions through
+// the injector, stopping at the first error.
+func callHooks(inj *Injector, name string, hooks []interface{}) error {
+	for _, fn := range hooks {
+		if err := inj.call(name, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exitHooks is a helper that calls the OnExit functions with the
+// outcome of dispatching, returning the final outcome.
+func (a *App) exitHooks(inj *Injector, result error) error {
+	inj.ProvideAs((*error)(nil), result)
+	for _, fn := range a.onExit {
+		if err := inj.call(onExitName, fn); err != nil && result == nil {
+			result = err
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAppOnStart(t *testing.T) {
+	fn := func() {}
+	obj := &App{}
+
+	result := obj.OnStart(fn)
+
+	assert.Same(t, obj, result)
+	assert.Len(t, obj.onStart, 1)
+}
+
+func TestAppOnCommandResolved(t *testing.T) {
+	fn := func() {}
+	obj := &App{}
+
+	result := obj.OnCommandResolved(fn)
+
+	assert.Same(t, obj, result)
+	assert.Len(t, obj.onResolved, 1)
+}
+
+func TestAppOnExit(t *testing.T) {
+	fn := func() {}
+	obj := &App{}
+
+	result := obj.OnExit(fn)
+
+	assert.Same(t, obj, result)
+	assert.Len(t, obj.onExit, 1)
+}
+
+func TestCallHooks(t *testing.T) {
+	calls := []string{}
+	inj := NewInjector()
+
+	err := callHooks(inj, "hook", []interface{}{
+		func() { calls = append(calls, "first") },
+		func() error {
+			calls = append(calls, "second")
+			return assert.AnError
+		},
+		func() { calls = append(calls, "third") },
+	})
+
+	assert.Same(t, assert.AnError, err)
+	assert.Equal(t, []string{"first", "second"}, calls)
+}
+
+func TestAppExitHooks(t *testing.T) {
+	var seen error
+	obj := &App{
+		onExit: []interface{}{
+			func(err error) { seen = err },
+			func() error { return assert.AnError },
+		},
+	}
+
+	result := obj.exitHooks(NewInjector(), nil)
+
+	assert.Nil(t, seen)
+	assert.Same(t, assert.AnError, result)
+}
+
+func TestAppExitHooksFailed(t *testing.T) {
+	var seen error
+	failure := &CommandError{Code: 3}
+	obj := &App{
+		onExit: []interface{}{
+			func() error { return assert.AnError },
+			func(err error) { seen = err },
+		},
+	}
+
+	result := obj.exitHooks(NewInjector(), failure)
+
+	assert.Same(t, failure, seen)
+	assert.Same(t, failure, result)
+}
+
+func TestAppDispatchLifecycle(t *testing.T) {
+	calls := []string{}
+	root := newRunCommand("Root command", nil)
+	root.On("Run", Args{"a1"}, mock.Anything).Return(nil).Run(func(mock.Arguments) {
+		calls = append(calls, "run")
+	})
+	obj := New("app").WithRoot(root).
+		OnStart(func(app *App) {
+			calls = append(calls, "start")
+		}).
+		OnCommandResolved(func(inv *Invocation) {
+			calls = append(calls, "resolved")
+		}).
+		OnExit(func(err error) {
+			calls = append(calls, "exit")
+		})
+
+	err := obj.Dispatch(context.Background(), []string{"a1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"start", "resolved", "run", "exit"}, calls)
+	root.AssertExpectations(t)
+}
+
+func TestAppDispatchOnStartFails(t *testing.T) {
+	var seen error
+	root := newRunCommand("Root command", nil)
+	obj := New("app").WithRoot(root).
+		OnStart(func() error {
+			return assert.AnError
+		}).
+		OnExit(func(err error) {
+			seen = err
+		})
+
+	err := obj.Dispatch(context.Background(), []string{"a1"})
+
+	assert.Same(t, assert.AnError, err)
+	assert.Same(t, assert.AnError, seen)
+	root.AssertExpectations(t)
+}
+
+func TestAppDispatchOnCommandResolvedFails(t *testing.T) {
+	root := newRunCommand("Root command", nil)
+	obj := New("app").WithRoot(root).
+		OnCommandResolved(func() error {
+			return assert.AnError
+		})
+
+	err := obj.Dispatch(context.Background(), []string{"a1"})
+
+	assert.Same(t, assert.AnError, err)
+	root.AssertExpectations(t)
+}
+
+func TestAppExecuteOnStartUsage(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	obj := New("app").WithRoot(&Command{}).WithIO(nil, nil, stderr).
+		OnStart(func() error {
+			return &CommandError{Err: assert.AnError, Code: 2, Usage: true}
+		})
+
+	result := obj.Execute(context.Background(), []string{})
+
+	assert.Equal(t, 2, result)
+	assert.Equal(t, "app: "+assert.AnError.Error()+"\n", stderr.String())
+}
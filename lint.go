@@ -0,0 +1,329 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// LintCommand is the name of the hidden subcommand that checks the
+// command tree with App.Lint, writing the diagnostics to standard
+// output, as in "tool __lint".  Arguments restrict the rules that
+// are checked.  It is added to the root command alongside the
+// HelpCommand, unless the root declares its own LintCommand.
+const LintCommand = "__lint"
+
+// Rules checked by App.Lint.  The rule IDs are stable, so that teams
+// may enforce a subset of them.
+const (
+	LintDuplicateFlag   = "duplicate-flag"   // A command's flag has the same name as a global flag
+	LintShadowedFlag    = "shadowed-flag"    // A command's flag has the same name as an ancestor's flag, without inheriting it
+	LintMissingSummary  = "missing-summary"  // A command has no summary
+	LintMissingExamples = "missing-examples" // A command without subcommands has no examples
+	LintEmptyGroup      = "empty-group"      // A described group of subcommands has no visible subcommands
+	LintMissingHelp     = "missing-help"     // A visible flag has no help text
+	LintDuplicateChoice = "duplicate-choice" // A flag or argument lists the same choice more than once
+)
+
+// LintRules lists the rules checked by App.Lint, in the order in
+// which they are checked for each command.
+var LintRules = []string{
+	LintDuplicateFlag,
+	LintShadowedFlag,
+	LintMissingSummary,
+	LintMissingExamples,
+	LintEmptyGroup,
+	LintMissingHelp,
+	LintDuplicateChoice,
+}
+
+// Errors reported by linting.
+var (
+	ErrLint        = errors.New("lint found problems")
+	ErrUnknownRule = errors.New("unknown lint rule")
+)
+
+// Diagnostic describes a problem found by App.Lint.
+type Diagnostic struct {
+	Rule    string // The ID of the rule, e.g., LintMissingHelp
+	Path    string // The command path, e.g., "tool sub"
+	Message string // Description of the problem and how to fix it
+}
+
+// String returns a string describing the problem.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", d.Path, d.Rule, d.Message)
+}
+
+// Lint checks the static command tree for problems that are not
+// errors, but that make the command line interface harder to use,
+// returning a diagnostic for each.  If rules are specified, only
+// those rules are checked; see LintRules.  Commands are checked in
+// tree order, with subcommands sorted by name.  Commands added by
+// DynamicSubcommands cannot be checked, and aliases are skipped.
+func (a *App) Lint(rules ...string) ([]Diagnostic, error) {
+	l := &linter{
+		app:   a,
+		rules: map[string]bool{},
+	}
+	if len(rules) == 0 {
+		rules = LintRules
+	}
+	known := map[string]bool{}
+	for _, rule := range LintRules {
+		known[rule] = true
+	}
+	for _, rule := range rules {
+		if !known[rule] {
+			return nil, fmt.Errorf("%w %q", ErrUnknownRule, rule)
+		}
+		l.rules[rule] = true
+	}
+
+	// Collect the global flags
+	for _, obj := range a.Globals {
+		opts, err := optionsFor(obj, false)
+		if err != nil {
+			return nil, err
+		}
+		if opts != nil {
+			l.globals = append(l.globals, opts.Set.Options...)
+		}
+	}
+
+	if err := l.command(a.Root, []string{a.name()}, nil); err != nil {
+		return nil, err
+	}
+
+	return l.diags, nil
+}
+
+// linter implements App.Lint.
+type linter struct {
+	app     *App            // The application
+	rules   map[string]bool // The rules to check
+	globals []*option       // The global flags
+	diags   []Diagnostic    // The diagnostics found
+}
+
+// report records a diagnostic, if its rule is being checked.
+func (l *linter) report(rule string, path []string, format string, args ...interface{}) {
+	if !l.rules[rule] {
+		return
+	}
+
+	l.diags = append(l.diags, Diagnostic{
+		Rule:    rule,
+		Path:    strings.Join(path, " "),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// command checks a single command and recurses into its subcommands.
+// The ancestors are the option sets of the ancestors of the command
+// that declare flags.
+func (l *linter) command(cmd ICommand, path []string, ancestors []*optionSet) error {
+	opts, err := newOptions(cmd)
+	if err != nil {
+		return fmt.Errorf("%s: %w", strings.Join(path, " "), err)
+	}
+	if opts != nil {
+		l.flags(opts.Set, path, ancestors)
+		ancestors = append(append([]*optionSet{}, ancestors...), opts.Set)
+	}
+
+	subs := cmd.GetSubcommands()
+	if cmd.GetSummary() == "" {
+		l.report(LintMissingSummary, path, "command has no summary; set the command's Summary")
+	}
+	if len(subs) == 0 && GetExamples(cmd) == "" {
+		l.report(LintMissingExamples, path, "command has no examples; set the command's Examples")
+	}
+	l.groups(cmd, subs, path)
+
+	// Recurse into the subcommands in a stable order
+	names := make([]string, 0, len(subs))
+	for name, sub := range subs {
+		if !IsAlias(sub) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := l.command(subs[name], append(append([]string{}, path...), name), ancestors); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flags checks the flags and arguments of a command.
+func (l *linter) flags(set *optionSet, path []string, ancestors []*optionSet) {
+	for _, opt := range set.Options {
+		for _, global := range l.globals {
+			if name := sharedName(opt, global); name != "" {
+				l.report(LintDuplicateFlag, path, "flag %s has the same name as a global flag; rename one of them", name)
+			}
+		}
+		if !inherited(set.Type, opt.Index, ancestors) {
+			for _, ancestor := range ancestors {
+				for _, other := range ancestor.Options {
+					if name := sharedName(opt, other); name != "" {
+						l.report(LintShadowedFlag, path, "flag %s shadows a flag of a parent command; rename it, or inherit the parent's flag", name)
+					}
+				}
+			}
+		}
+		if opt.Help == "" && !opt.Hidden && opt.Deprecated == "" {
+			l.report(LintMissingHelp, path, "flag %s has no help text; add a %q tag", opt, HelpTag)
+		}
+		if choice := duplicateChoice(opt.Choices); choice != "" {
+			l.report(LintDuplicateChoice, path, "flag %s lists choice %q more than once", opt, choice)
+		}
+	}
+	for _, arg := range set.Args {
+		if choice := duplicateChoice(arg.Choices); choice != "" {
+			l.report(LintDuplicateChoice, path, "argument %s lists choice %q more than once", arg.Name, choice)
+		}
+	}
+}
+
+// groups checks the described groups of a command's subcommands.
+func (l *linter) groups(cmd ICommand, subs map[string]ICommand, path []string) {
+	visible := map[string]bool{}
+	for _, sub := range subs {
+		if !IsHidden(sub) {
+			visible[sub.GetGroup()] = true
+		}
+	}
+
+	for _, group := range GetGroups(cmd) {
+		if group.Name != "" && !visible[group.Name] {
+			l.report(LintEmptyGroup, path, "group %q has no visible subcommands; remove its description or unhide its subcommands", group.Name)
+		}
+	}
+}
+
+// sharedName is a helper that returns a name shared by two flags, as
+// "--name" or "-s", or "" if they share none.
+func sharedName(opt, other *option) string {
+	for _, name := range append([]string{opt.Name}, opt.Aliases...) {
+		if name != "" && other.Matches("--"+name) {
+			return "--" + name
+		}
+	}
+	if opt.Short != "" && other.Matches("-"+opt.Short) {
+		return "-" + opt.Short
+	}
+
+	return ""
+}
+
+// inherited is a helper that determines whether the field of a
+// defaults struct with the specified index inherits its value from
+// an ancestor, either because it is tagged with InheritTag or
+// because it is declared by an embedded ancestor's defaults; see
+// inheritDefaults.
+func inherited(typ reflect.Type, index []int, ancestors []*optionSet) bool {
+	for i, idx := range index {
+		field := typ.Field(idx)
+		if i == len(index)-1 {
+			_, ok := field.Tag.Lookup(InheritTag)
+			return ok
+		}
+
+		typ = field.Type
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		if field.Anonymous {
+			for _, ancestor := range ancestors {
+				if typ == ancestor.Type {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// duplicateChoice is a helper that returns the first choice that is
+// listed more than once, or "" if there is none.
+func duplicateChoice(choices []string) string {
+	seen := map[string]bool{}
+	for _, choice := range choices {
+		if seen[choice] {
+			return choice
+		}
+		seen[choice] = true
+	}
+
+	return ""
+}
+
+// lintCommand implements the LintCommand.
+type lintCommand struct {
+	Command
+	app *App // The application
+}
+
+// IsHidden returns true, since the LintCommand is not intended for
+// users.
+func (c *lintCommand) IsHidden() bool {
+	return true
+}
+
+// Run checks the command tree, writing the diagnostics.
+func (c *lintCommand) Run(args Args, streams *IOStreams) error {
+	diags, err := c.app.Lint(args...)
+	if err != nil {
+		return usageError(err)
+	}
+	for _, diag := range diags {
+		fmt.Fprintln(streams.Out, diag)
+	}
+	if len(diags) > 0 {
+		return fmt.Errorf("%w: %d diagnostics", ErrLint, len(diags))
+	}
+
+	return nil
+}
+
+// withLint adds the LintCommand to the subcommands of the root
+// command, unless the root has no subcommands or declares its own.
+// The subcommands are copied rather than modified.
+func (a *App) withLint(subs map[string]ICommand) map[string]ICommand {
+	if _, ok := subs[LintCommand]; ok || len(subs) == 0 {
+		return subs
+	}
+
+	return withBuiltin(subs, map[string]ICommand{LintCommand: a.newLintCommand()})
+}
+
+// newLintCommand constructs the LintCommand for the application.
+func (a *App) newLintCommand() *lintCommand {
+	return &lintCommand{
+		Command: Command{
+			Summary: "Check the command tree for problems",
+		},
+		app: a,
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type lintRootOptions struct {
+	Verbose bool   `opt:"verbose,v" help:"Verbose output"`
+	Region  string `opt:"region" help:"Region to use"`
+}
+
+type lintInheritOptions struct {
+	lintRootOptions
+	Name string `opt:"name" help:"Name to use"`
+}
+
+type lintTaggedOptions struct {
+	Region string `opt:"region" help:"Region to use" inherit:""`
+}
+
+type lintProblemOptions struct {
+	Verbose bool   `opt:"verbose" help:"Verbose output"`
+	Trace   bool   `opt:"trace"`
+	Secret  bool   `opt:"secret" hidden:"true"`
+	Color   string `opt:"color" help:"Color to use" choices:"red,green,red"`
+	Shape   string `arg:"shape" choices:"round,round"`
+}
+
+type lintGlobals struct {
+	Debug bool `opt:"debug" help:"Debug output"`
+}
+
+type lintDebugOptions struct {
+	Debug bool `opt:"debug" help:"Debug output"`
+}
+
+// lintApp is a helper that constructs an application for testing
+// linting.
+func lintApp(subs map[string]ICommand) *App {
+	return &App{
+		Name: "tool",
+		Root: &Command{
+			Summary:     "The tool",
+			Defaults:    &lintRootOptions{},
+			Subcommands: subs,
+		},
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	obj := Diagnostic{
+		Rule:    LintMissingSummary,
+		Path:    "tool sub",
+		Message: "command has no summary",
+	}
+
+	result := obj.String()
+
+	assert.Equal(t, "tool sub: missing-summary: command has no summary", result)
+}
+
+func TestAppLintClean(t *testing.T) {
+	obj := lintApp(map[string]ICommand{
+		"embedded": &Command{Summary: "Embeds", Examples: "tool embedded", Defaults: &lintInheritOptions{}},
+		"tagged":   &Command{Summary: "Inherits", Examples: "tool tagged", Defaults: &lintTaggedOptions{}},
+		"alias":    Alias(&Command{}),
+	})
+
+	result, err := obj.Lint()
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestAppLintProblems(t *testing.T) {
+	obj := lintApp(map[string]ICommand{
+		"problem": &Command{Defaults: &lintProblemOptions{}},
+		"debug":   &Command{Summary: "Debugs", Examples: "tool debug", Defaults: &lintDebugOptions{}},
+		"grouped": &Command{
+			Summary: "Has groups",
+			Groups: []Group{
+				{Name: "visible"},
+				{Name: "hidden"},
+				{Name: "missing"},
+			},
+			Subcommands: map[string]ICommand{
+				"one": &Command{Summary: "One", Examples: "tool grouped one", Group: "visible"},
+				"two": Hidden(&Command{Summary: "Two", Examples: "tool grouped two", Group: "hidden"}),
+			},
+		},
+	})
+	obj.WithGlobals(&lintGlobals{})
+
+	result, err := obj.Lint()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Diagnostic{
+		{LintDuplicateFlag, "tool debug", "flag --debug has the same name as a global flag; rename one of them"},
+		{LintEmptyGroup, "tool grouped", `group "hidden" has no visible subcommands; remove its description or unhide its subcommands`},
+		{LintEmptyGroup, "tool grouped", `group "missing" has no visible subcommands; remove its description or unhide its subcommands`},
+		{LintShadowedFlag, "tool problem", "flag --verbose shadows a flag of a parent command; rename it, or inherit the parent's flag"},
+		{LintMissingHelp, "tool problem", `flag --trace has no help text; add a "help" tag`},
+		{LintDuplicateChoice, "tool problem", `flag --color lists choice "red" more than once`},
+		{LintDuplicateChoice, "tool problem", `argument shape lists choice "round" more than once`},
+		{LintMissingSummary, "tool problem", "command has no summary; set the command's Summary"},
+		{LintMissingExamples, "tool problem", "command has no examples; set the command's Examples"},
+	}, result)
+}
+
+func TestAppLintSubset(t *testing.T) {
+	obj := lintApp(map[string]ICommand{
+		"problem": &Command{Defaults: &lintProblemOptions{}},
+	})
+
+	result, err := obj.Lint(LintMissingSummary, LintMissingHelp)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Diagnostic{
+		{LintMissingHelp, "tool problem", `flag --trace has no help text; add a "help" tag`},
+		{LintMissingSummary, "tool problem", "command has no summary; set the command's Summary"},
+	}, result)
+}
+
+func TestAppLintUnknownRule(t *testing.T) {
+	obj := lintApp(nil)
+
+	result, err := obj.Lint("bogus")
+
+	assert.ErrorIs(t, err, ErrUnknownRule)
+	assert.Nil(t, result)
+}
+
+func TestAppLintBadOptions(t *testing.T) {
+	obj := lintApp(map[string]ICommand{
+		"bad": &Command{Defaults: 42},
+	})
+
+	result, err := obj.Lint()
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestAppLintBadGlobals(t *testing.T) {
+	obj := lintApp(nil)
+	obj.WithGlobals(42)
+
+	result, err := obj.Lint()
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestAppDispatchLint(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := lintApp(map[string]ICommand{
+		"problem": &Command{Summary: "A problem", Examples: "tool problem", Defaults: &lintProblemOptions{}},
+	})
+	obj.Stdout = stdout
+	obj.WithNoConfigSearch(true)
+
+	err := obj.Dispatch(context.Background(), []string{LintCommand, LintMissingHelp})
+
+	assert.ErrorIs(t, err, ErrLint)
+	assert.Equal(t, "tool problem: missing-help: flag --trace has no help text; add a \"help\" tag\n", stdout.String())
+}
+
+func TestAppDispatchLintClean(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := lintApp(map[string]ICommand{
+		"problem": &Command{Summary: "A problem", Examples: "tool problem", Defaults: &lintProblemOptions{}},
+	})
+	obj.Stdout = stdout
+	obj.WithNoConfigSearch(true)
+
+	err := obj.Dispatch(context.Background(), []string{LintCommand, LintMissingSummary})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", stdout.String())
+}
+
+func TestAppDispatchLintUnknownRule(t *testing.T) {
+	obj := lintApp(map[string]ICommand{
+		"sub": &Command{},
+	})
+	obj.Stdout = &bytes.Buffer{}
+	obj.WithNoConfigSearch(true)
+
+	err := obj.Dispatch(context.Background(), []string{LintCommand, "bogus"})
+
+	assert.ErrorIs(t, err, ErrUnknownRule)
+	assert.True(t, err.(*CommandError).Usage)
+}
+
+func TestAppWithLintBase(t *testing.T) {
+	sub := &Command{}
+	subs := map[string]ICommand{"sub": sub}
+	obj := &App{}
+
+	result := obj.withLint(subs)
+
+	assert.Len(t, result, 2)
+	assert.Same(t, sub, result["sub"])
+	assert.True(t, IsHidden(result[LintCommand]))
+	assert.Len(t, subs, 1)
+}
+
+func TestAppWithLintNoSubcommands(t *testing.T) {
+	obj := &App{}
+
+	result := obj.withLint(nil)
+
+	assert.Nil(t, result)
+}
+
+func TestAppWithLintDeclared(t *testing.T) {
+	lint := &Command{}
+	subs := map[string]ICommand{LintCommand: lint}
+	obj := &App{}
+
+	result := obj.withLint(subs)
+
+	assert.Same(t, lint, result[LintCommand])
+}
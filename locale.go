@@ -0,0 +1,145 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"os"
+	"strings"
+)
+
+// LocaleEnvs are the environment variables consulted, in order, to
+// select the language of help, unless the application selects one;
+// see WithLocale.
+var LocaleEnvs = []string{"LC_ALL", "LC_MESSAGES", "LANG"}
+
+// Catalog translates the text generated for help, such as section
+// headings, into a language.  The keys are the English text, as in
+// "Usage:" or "default %s"; text missing from the catalog is
+// displayed in English.
+type Catalog map[string]string
+
+// Translation is the summary and description of a command in a
+// language; see ITranslations.  Empty fields are displayed in
+// English.
+type Translation struct {
+	Summary     string // Translated summary of the command
+	Description string // Translated description of the command
+}
+
+// WithLocale selects the language of help, such as "fr_FR", overriding
+// the locale given by the LocaleEnvs environment variables.  Returns
+// the App, to allow chaining.
+func (a *App) WithLocale(locale string) *App {
+	a.Locale = locale
+	return a
+}
+
+// WithCatalog sets the catalog used to translate the text generated
+// for help into a language, such as "fr" or "fr_FR".  A catalog for a
+// language and territory is preferred over one for the language
+// alone.  Returns the App, to allow chaining.
+func (a *App) WithCatalog(lang string, catalog Catalog) *App {
+	if a.Catalogs == nil {
+		a.Catalogs = map[string]Catalog{}
+	}
+	a.Catalogs[lang] = catalog
+	return a
+}
+
+// languages returns the keys used to look up translations for the
+// selected locale, most specific first.
+func (a *App) languages() []string {
+	locale := a.Locale
+	for _, name := range LocaleEnvs {
+		if locale != "" {
+			break
+		}
+		locale = os.Getenv(name)
+	}
+
+	return localeKeys(locale)
+}
+
+// localeKeys is a helper that returns the keys used to look up
+// translations for a locale, such as "fr_FR.UTF-8": the language and
+// territory, followed by the language alone.  The "C" and "POSIX"
+// locales have no keys.
+func localeKeys(locale string) []string {
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return nil
+	}
+
+	keys := []string{locale}
+	if i := strings.IndexAny(locale, "_-"); i > 0 {
+		keys = append(keys, locale[:i])
+	}
+
+	return keys
+}
+
+// translator translates text using a list of catalogs, most specific
+// first.
+type translator []Catalog
+
+// translator constructs the translator for the specified language
+// keys.
+func (a *App) translator(langs []string) translator {
+	var tr translator
+	for _, lang := range langs {
+		if catalog, ok := a.Catalogs[lang]; ok {
+			tr = append(tr, catalog)
+		}
+	}
+
+	return tr
+}
+
+// translate translates text, returning it unchanged if no catalog
+// translates it.
+func (t translator) translate(text string) string {
+	for _, catalog := range t {
+		if result, ok := catalog[text]; ok {
+			return result
+		}
+	}
+
+	return text
+}
+
+// translateCommand is a helper that returns the summary and
+// description of a command in the first of the specified languages
+// for which the command has a translation; see ITranslations.
+func translateCommand(cmd ICommand, langs []string) (string, string) {
+	summary, description := cmd.GetSummary(), cmd.GetDescription()
+	translations := GetTranslations(cmd)
+	for _, lang := range langs {
+		trans, ok := translations[lang]
+		if !ok {
+			continue
+		}
+		if trans.Summary != "" {
+			summary = trans.Summary
+		}
+		if trans.Description != "" {
+			description = trans.Description
+		}
+		break
+	}
+
+	return summary, description
+}
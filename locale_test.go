@@ -0,0 +1,213 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// frenchCatalog is a partial catalog for testing translation.
+var frenchCatalog = Catalog{
+	"Usage:":                  "Utilisation :",
+	"Flags:":                  "Options :",
+	"Available commands":      "Commandes disponibles",
+	"[flags]":                 "[options]",
+	"default %s":              "défaut %s",
+	"deprecated: use %s":      "obsolète : utilisez %s",
+	"Show help for a command": "Afficher l'aide d'une commande",
+}
+
+func TestAppWithLocale(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithLocale("fr_FR")
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, "fr_FR", obj.Locale)
+}
+
+func TestAppWithCatalog(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithCatalog("fr", frenchCatalog)
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, map[string]Catalog{"fr": frenchCatalog}, obj.Catalogs)
+}
+
+func TestAppLanguagesLocale(t *testing.T) {
+	setEnv(t, "LC_ALL", "de_DE.UTF-8")
+	obj := &App{Locale: "fr_CA"}
+
+	result := obj.languages()
+
+	assert.Equal(t, []string{"fr_CA", "fr"}, result)
+}
+
+func TestAppLanguagesEnv(t *testing.T) {
+	setEnv(t, "LC_ALL", "")
+	setEnv(t, "LC_MESSAGES", "de_DE.UTF-8")
+	setEnv(t, "LANG", "en_US.UTF-8")
+	obj := &App{}
+
+	result := obj.languages()
+
+	assert.Equal(t, []string{"de_DE", "de"}, result)
+}
+
+func TestAppLanguagesUnset(t *testing.T) {
+	setEnv(t, "LC_ALL", "")
+	setEnv(t, "LC_MESSAGES", "")
+	setEnv(t, "LANG", "")
+	obj := &App{}
+
+	result := obj.languages()
+
+	assert.Nil(t, result)
+}
+
+func TestLocaleKeys(t *testing.T) {
+	tests := []struct {
+		locale string
+		keys   []string
+	}{
+		{"", nil},
+		{"C", nil},
+		{"C.UTF-8", nil},
+		{"POSIX", nil},
+		{"fr", []string{"fr"}},
+		{"fr_FR", []string{"fr_FR", "fr"}},
+		{"fr_FR.UTF-8", []string{"fr_FR", "fr"}},
+		{"de_DE@euro", []string{"de_DE", "de"}},
+		{"pt-BR", []string{"pt-BR", "pt"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.locale, func(t *testing.T) {
+			result := localeKeys(test.locale)
+
+			assert.Equal(t, test.keys, result)
+		})
+	}
+}
+
+func TestAppTranslator(t *testing.T) {
+	fr, frCA := Catalog{"a": "fr"}, Catalog{"a": "fr_CA"}
+	obj := &App{Catalogs: map[string]Catalog{"fr": fr, "fr_CA": frCA}}
+
+	result := obj.translator([]string{"fr_CA", "fr", "de"})
+
+	assert.Equal(t, translator{frCA, fr}, result)
+}
+
+func TestTranslatorTranslate(t *testing.T) {
+	obj := translator{Catalog{"a": "specific"}, Catalog{"a": "general", "b": "general"}}
+
+	assert.Equal(t, "specific", obj.translate("a"))
+	assert.Equal(t, "general", obj.translate("b"))
+	assert.Equal(t, "c", obj.translate("c"))
+}
+
+func TestTranslatorTranslateNil(t *testing.T) {
+	var obj translator
+
+	result := obj.translate("text")
+
+	assert.Equal(t, "text", result)
+}
+
+func TestTranslateCommandBase(t *testing.T) {
+	cmd := &Command{
+		Summary:     "Summary",
+		Description: "Description",
+		Translations: map[string]Translation{
+			"de": {Summary: "Zusammenfassung"},
+			"fr": {Summary: "Résumé", Description: "La description"},
+		},
+	}
+
+	summary, description := translateCommand(cmd, []string{"fr_FR", "fr", "de"})
+
+	assert.Equal(t, "Résumé", summary)
+	assert.Equal(t, "La description", description)
+}
+
+func TestTranslateCommandPartial(t *testing.T) {
+	cmd := &Command{
+		Summary:     "Summary",
+		Description: "Description",
+		Translations: map[string]Translation{
+			"de": {Summary: "Zusammenfassung"},
+		},
+	}
+
+	summary, description := translateCommand(cmd, []string{"de"})
+
+	assert.Equal(t, "Zusammenfassung", summary)
+	assert.Equal(t, "Description", description)
+}
+
+func TestTranslateCommandUntranslated(t *testing.T) {
+	cmd := &Command{Summary: "Summary", Description: "Description"}
+
+	summary, description := translateCommand(cmd, []string{"fr"})
+
+	assert.Equal(t, "Summary", summary)
+	assert.Equal(t, "Description", description)
+}
+
+func TestAppDispatchHelpTranslated(t *testing.T) {
+	type opts struct {
+		Format string `opt:"format" help:"Output format"`
+	}
+	stdout := &bytes.Buffer{}
+	obj := &App{
+		Name:   "app",
+		Stdout: stdout,
+		Width:  80,
+		Locale: "fr_FR.UTF-8",
+		Root: &Command{
+			Summary:      "The application",
+			Translations: map[string]Translation{"fr": {Summary: "L'application"}},
+			Defaults:     &opts{Format: "json"},
+			Subcommands: map[string]ICommand{
+				"old": Deprecated(&Command{
+					Summary:      "Old command",
+					Translations: map[string]Translation{"fr_FR": {Summary: "Ancienne commande"}},
+				}, "new"),
+			},
+		},
+	}
+	obj.WithCatalog("fr", frenchCatalog)
+
+	err := obj.Dispatch(context.Background(), []string{"--help"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `L'application
+
+Utilisation : app [options] COMMAND [ARGS...]
+
+Commandes disponibles:
+  help  Afficher l'aide d'une commande
+  old   Ancienne commande (obsolète : utilisez new)
+
+Options :
+      --format  Output format (défaut "json")
+`, stdout.String())
+}
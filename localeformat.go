@@ -0,0 +1,193 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"os"
+	"reflect"
+	"strings"
+)
+
+// LocaleFormatEnvs are the environment variables consulted, in order,
+// to select the locale whose conventions are used to parse numbers
+// and dates, unless the application selects one; see
+// WithLocaleValues.
+var LocaleFormatEnvs = []string{"LC_ALL", "LC_NUMERIC", "LC_TIME", "LANG"}
+
+// LocaleFormat describes how numbers and dates are written in a
+// locale; see WithLocaleValues.
+type LocaleFormat struct {
+	Decimal string   // Decimal separator, as in ","
+	Group   []string // Separators between groups of three digits, as in "." or "\u00a0"
+	Layouts []string // Layouts for dates, as in "2/1/2006"
+}
+
+// DefaultLocaleFormats are the formats of common locales, by
+// language or by language and territory, as in "fr" or "en_GB"; see
+// WithLocaleFormat.
+var DefaultLocaleFormats = map[string]LocaleFormat{
+	"en":    {Decimal: ".", Group: []string{","}, Layouts: []string{"1/2/2006"}},
+	"en_AU": {Decimal: ".", Group: []string{","}, Layouts: []string{"2/1/2006"}},
+	"en_GB": {Decimal: ".", Group: []string{","}, Layouts: []string{"2/1/2006"}},
+	"en_IE": {Decimal: ".", Group: []string{","}, Layouts: []string{"2/1/2006"}},
+	"en_NZ": {Decimal: ".", Group: []string{","}, Layouts: []string{"2/1/2006"}},
+	"cs":    {Decimal: ",", Group: []string{"\u00a0", " "}, Layouts: []string{"2.1.2006", "2. 1. 2006"}},
+	"da":    {Decimal: ",", Group: []string{"."}, Layouts: []string{"2.1.2006", "2/1/2006"}},
+	"de":    {Decimal: ",", Group: []string{"."}, Layouts: []string{"2.1.2006"}},
+	"de_CH": {Decimal: ".", Group: []string{"'", "\u2019"}, Layouts: []string{"2.1.2006"}},
+	"es":    {Decimal: ",", Group: []string{"."}, Layouts: []string{"2/1/2006"}},
+	"fi":    {Decimal: ",", Group: []string{"\u00a0", " "}, Layouts: []string{"2.1.2006"}},
+	"fr":    {Decimal: ",", Group: []string{"\u202f", "\u00a0", " "}, Layouts: []string{"2/1/2006"}},
+	"fr_CH": {Decimal: ",", Group: []string{"\u202f", "\u00a0", " "}, Layouts: []string{"2.1.2006"}},
+	"it":    {Decimal: ",", Group: []string{"."}, Layouts: []string{"2/1/2006"}},
+	"ja":    {Decimal: ".", Group: []string{","}, Layouts: []string{"2006/1/2"}},
+	"ko":    {Decimal: ".", Group: []string{","}, Layouts: []string{"2006. 1. 2.", "2006.1.2"}},
+	"nb":    {Decimal: ",", Group: []string{"\u00a0", " "}, Layouts: []string{"2.1.2006"}},
+	"nl":    {Decimal: ",", Group: []string{"."}, Layouts: []string{"2-1-2006"}},
+	"pl":    {Decimal: ",", Group: []string{"\u00a0", " "}, Layouts: []string{"2.1.2006"}},
+	"pt":    {Decimal: ",", Group: []string{"."}, Layouts: []string{"2/1/2006"}},
+	"ru":    {Decimal: ",", Group: []string{"\u00a0", " "}, Layouts: []string{"2.1.2006"}},
+	"sv":    {Decimal: ",", Group: []string{"\u00a0", " "}, Layouts: []string{"2006-01-02"}},
+	"tr":    {Decimal: ",", Group: []string{"."}, Layouts: []string{"2.1.2006"}},
+	"zh":    {Decimal: ".", Group: []string{","}, Layouts: []string{"2006/1/2"}},
+}
+
+// WithLocaleValues sets whether numbers and dates given on the
+// command line are parsed according to the conventions of the user's
+// locale, such as decimal commas and day-month-year dates.  The
+// locale is the one selected with WithLocale, if any, or else the one
+// given by the LocaleFormatEnvs environment variables; its format is
+// looked up in the formats set with WithLocaleFormat and then in
+// DefaultLocaleFormats.  Numbers written in the locale's format are
+// converted before being parsed; dates are parsed with the locale's
+// layouts, followed by DefaultLayouts, unless the field has its own
+// layouts given with LayoutTag.  Values from configuration files and
+// the environment are not affected.  Returns the App, to allow
+// chaining.
+func (a *App) WithLocaleValues(allow bool) *App {
+	a.LocaleValues = allow
+	return a
+}
+
+// WithLocaleFormat sets the format of numbers and dates in a locale,
+// such as "fr" or "fr_CA", overriding any in DefaultLocaleFormats;
+// see WithLocaleValues.  Returns the App, to allow chaining.
+func (a *App) WithLocaleFormat(locale string, format LocaleFormat) *App {
+	if a.LocaleFormats == nil {
+		a.LocaleFormats = map[string]LocaleFormat{}
+	}
+	a.LocaleFormats[locale] = format
+	return a
+}
+
+// localeFormat returns the format of numbers and dates in the
+// selected locale, or nil if the application does not parse values
+// according to the locale or the locale's format is unknown.
+func (a *App) localeFormat() *LocaleFormat {
+	if !a.LocaleValues {
+		return nil
+	}
+
+	locale := a.Locale
+	for _, name := range LocaleFormatEnvs {
+		if locale != "" {
+			break
+		}
+		locale = os.Getenv(name)
+	}
+
+	for _, key := range localeKeys(locale) {
+		key = strings.Replace(key, "-", "_", 1)
+		if format, ok := a.LocaleFormats[key]; ok {
+			return &format
+		}
+		if format, ok := DefaultLocaleFormats[key]; ok {
+			return &format
+		}
+	}
+
+	return nil
+}
+
+// localize converts text written in the locale's format for storage
+// in a field of the specified type.  Numbers are rewritten in the
+// form understood by ParseInt, ParseUint, and ParseFloat; for dates,
+// the layouts are returned, with the locale's layouts preceding
+// DefaultLayouts if the field has no layouts of its own.  Types that
+// convert text themselves are unaffected.
+func (f *LocaleFormat) localize(typ reflect.Type, text string, layouts []string) (string, []string) {
+	for typ.Kind() == reflect.Ptr || isList(typ) {
+		typ = typ.Elem()
+	}
+
+	switch {
+	case typ == timeType:
+		if len(layouts) == 0 {
+			layouts = append(append([]string(nil), f.Layouts...), DefaultLayouts...)
+		}
+		return text, layouts
+
+	case typ == durationType:
+		return text, layouts
+
+	case reflect.PtrTo(typ).Implements(valueType) || reflect.PtrTo(typ).Implements(textUnmarshalerType):
+		return text, layouts
+	}
+
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return f.number(text), layouts
+	}
+
+	return text, layouts
+}
+
+// number rewrites a number written in the locale's format, as in
+// "1.234,5", in the form understood by strconv, as in "1234.5".  If
+// the digits following a group separator do not form a group of
+// three, the text is returned unchanged.
+func (f *LocaleFormat) number(text string) string {
+	intPart, fracPart := text, ""
+	hasFrac := false
+	if f.Decimal != "" {
+		if i := strings.LastIndex(text, f.Decimal); i >= 0 {
+			intPart, fracPart, hasFrac = text[:i], text[i+len(f.Decimal):], true
+		}
+	}
+
+	// Split the integral part into its groups of digits
+	groups := []string{intPart}
+	for _, sep := range f.Group {
+		var tmp []string
+		for _, group := range groups {
+			tmp = append(tmp, strings.Split(group, sep)...)
+		}
+		groups = tmp
+	}
+	for _, group := range groups[1:] {
+		if len(group) != 3 || strings.Trim(group, "0123456789") != "" {
+			return text
+		}
+	}
+
+	result := strings.Join(groups, "")
+	if hasFrac {
+		result += "." + fracPart
+	}
+
+	return result
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAppWithLocaleValues(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithLocaleValues(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.LocaleValues)
+}
+
+func TestAppWithLocaleFormat(t *testing.T) {
+	obj := &App{}
+	format := LocaleFormat{Decimal: ","}
+
+	result := obj.WithLocaleFormat("fr_CA", format)
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, map[string]LocaleFormat{"fr_CA": format}, obj.LocaleFormats)
+}
+
+func TestAppLocaleFormatDisabled(t *testing.T) {
+	obj := &App{Locale: "de_DE"}
+
+	result := obj.localeFormat()
+
+	assert.Nil(t, result)
+}
+
+func TestAppLocaleFormatLanguage(t *testing.T) {
+	obj := &App{Locale: "de_DE.UTF-8", LocaleValues: true}
+
+	result := obj.localeFormat()
+
+	assert.Equal(t, DefaultLocaleFormats["de"], *result)
+}
+
+func TestAppLocaleFormatTerritory(t *testing.T) {
+	obj := &App{Locale: "de-CH", LocaleValues: true}
+
+	result := obj.localeFormat()
+
+	assert.Equal(t, DefaultLocaleFormats["de_CH"], *result)
+}
+
+func TestAppLocaleFormatOverride(t *testing.T) {
+	format := LocaleFormat{Decimal: ","}
+	obj := &App{
+		Locale:        "de_DE",
+		LocaleValues:  true,
+		LocaleFormats: map[string]LocaleFormat{"de_DE": format},
+	}
+
+	result := obj.localeFormat()
+
+	assert.Equal(t, format, *result)
+}
+
+func TestAppLocaleFormatEnv(t *testing.T) {
+	for _, name := range LocaleFormatEnvs {
+		setEnv(t, name, "")
+	}
+	setEnv(t, "LC_NUMERIC", "fr_FR.UTF-8")
+	obj := &App{LocaleValues: true}
+
+	result := obj.localeFormat()
+
+	assert.Equal(t, DefaultLocaleFormats["fr"], *result)
+}
+
+func TestAppLocaleFormatUnknown(t *testing.T) {
+	obj := &App{Locale: "xx_YY", LocaleValues: true}
+
+	result := obj.localeFormat()
+
+	assert.Nil(t, result)
+}
+
+func TestAppLocaleFormatPOSIX(t *testing.T) {
+	obj := &App{Locale: "C", LocaleValues: true}
+
+	result := obj.localeFormat()
+
+	assert.Nil(t, result)
+}
+
+func TestLocaleFormatNumber(t *testing.T) {
+	de := DefaultLocaleFormats["de"]
+	fr := DefaultLocaleFormats["fr"]
+	en := DefaultLocaleFormats["en"]
+	tests := []struct {
+		format LocaleFormat
+		text   string
+		result string
+	}{
+		{de, "1.234,5", "1234.5"},
+		{de, "1.234.567", "1234567"},
+		{de, "-0,25", "-0.25"},
+		{de, "42", "42"},
+		{de, "1.5", "1.5"},
+		{de, "0x1F", "0x1F"},
+		{fr, "1 234,5", "1234.5"},
+		{fr, "1 234", "1234"},
+		{en, "1,234.5", "1234.5"},
+		{en, "1,23", "1,23"},
+		{en, "1e3", "1e3"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.result, test.format.number(test.text), test.text)
+	}
+}
+
+func TestLocaleFormatLocalizeNumbers(t *testing.T) {
+	format := DefaultLocaleFormats["de"]
+	var i *int
+	var u []uint16
+	var f float64
+
+	for _, typ := range []reflect.Type{reflect.TypeOf(i), reflect.TypeOf(u), reflect.TypeOf(f)} {
+		text, layouts := format.localize(typ, "1.234", nil)
+
+		assert.Equal(t, "1234", text, typ.String())
+		assert.Nil(t, layouts)
+	}
+}
+
+func TestLocaleFormatLocalizeUnaffected(t *testing.T) {
+	format := DefaultLocaleFormats["de"]
+	var s string
+	var d time.Duration
+	var ip net.IP
+
+	for _, typ := range []reflect.Type{reflect.TypeOf(s), reflect.TypeOf(d), reflect.TypeOf(ip)} {
+		text, layouts := format.localize(typ, "1.234", []string{"layout"})
+
+		assert.Equal(t, "1.234", text, typ.String())
+		assert.Equal(t, []string{"layout"}, layouts)
+	}
+}
+
+func TestLocaleFormatLocalizeTime(t *testing.T) {
+	format := DefaultLocaleFormats["de"]
+
+	text, layouts := format.localize(reflect.TypeOf(time.Time{}), "1.2.2021", nil)
+
+	assert.Equal(t, "1.2.2021", text)
+	assert.Equal(t, append([]string{"2.1.2006"}, DefaultLayouts...), layouts)
+}
+
+func TestLocaleFormatLocalizeTimeLayouts(t *testing.T) {
+	format := DefaultLocaleFormats["de"]
+
+	text, layouts := format.localize(reflect.TypeOf([]time.Time{}), "1.2.2021", []string{"2006"})
+
+	assert.Equal(t, "1.2.2021", text)
+	assert.Equal(t, []string{"2006"}, layouts)
+}
+
+type localeOptions struct {
+	Ratio float64   `opt:"ratio"`
+	Count int       `opt:"count"`
+	Date  time.Time `opt:"date"`
+	Year  time.Time `opt:"year" layout:"2006"`
+}
+
+// localeApp is a helper that constructs an application parsing
+// values in the specified locale.
+func localeApp(locale string) (*App, *runCommand) {
+	root := newRunCommand("Root", nil)
+	root.Defaults = &localeOptions{}
+	return &App{
+		Root:         root,
+		Locale:       locale,
+		LocaleValues: true,
+	}, root
+}
+
+func TestAppDispatchLocaleValues(t *testing.T) {
+	obj, root := localeApp("de_DE")
+	var opts *localeOptions
+	root.On("Run", Args{}, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		opts = args.Get(1).(*Invocation).Options.(*localeOptions)
+	})
+
+	err := obj.Dispatch(context.Background(), []string{"--ratio", "1.234,5", "--count=12.000", "--date", "3.2.2021", "--year", "2021"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1234.5, opts.Ratio)
+	assert.Equal(t, 12000, opts.Count)
+	assert.Equal(t, time.Date(2021, 2, 3, 0, 0, 0, 0, time.UTC), opts.Date)
+	assert.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), opts.Year)
+	root.AssertExpectations(t)
+}
+
+func TestAppDispatchLocaleValuesExplicitLayout(t *testing.T) {
+	obj, root := localeApp("de_DE")
+
+	err := obj.Dispatch(context.Background(), []string{"--year", "3.2.2021"})
+
+	assert.EqualError(t, err, `invalid value "3.2.2021" for --year: time does not match layout: expected 2006`)
+	root.AssertExpectations(t)
+}
+
+func TestAppDispatchLocaleValuesDisabled(t *testing.T) {
+	obj, root := localeApp("de_DE")
+	obj.LocaleValues = false
+
+	err := obj.Dispatch(context.Background(), []string{"--ratio", "1,5"})
+
+	assert.EqualError(t, err, `invalid value "1,5" for --ratio: not a number`)
+	root.AssertExpectations(t)
+}
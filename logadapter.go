@@ -0,0 +1,71 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"log"
+	"strings"
+)
+
+// LogHandler receives the messages reported by a Logger, in place of
+// the Logger's stream.  It allows the messages of the framework and
+// of commands to be routed to a logging library, such as zap or
+// logrus, with a small shim; see App.WithLogHandler.
+type LogHandler interface {
+	// Log handles a message reported at the specified level.  The
+	// message has no trailing newline.
+	Log(level Verbosity, msg string)
+}
+
+// LogHandlerFunc is an adaptor allowing a function to be used as a
+// LogHandler.
+type LogHandlerFunc func(level Verbosity, msg string)
+
+// Log handles a message reported at the specified level.
+func (f LogHandlerFunc) Log(level Verbosity, msg string) {
+	f(level, msg)
+}
+
+// WithLogHandler sets the handler receiving the messages reported
+// by the application's Logger.  Returns the App, to allow chaining.
+func (a *App) WithLogHandler(handler LogHandler) *App {
+	a.LogHandler = handler
+	return a
+}
+
+// StdLogger returns a standard library logger reporting each message
+// through the Logger at the specified level, for use with packages
+// that log through the log package.  The application's injector
+// provides one reporting at VerbosityVerbose, so commands may simply
+// declare a *log.Logger parameter.
+func (l *Logger) StdLogger(level Verbosity) *log.Logger {
+	return log.New(&logWriter{log: l, level: level}, "", 0)
+}
+
+// logWriter is an io.Writer reporting each line written to it
+// through a Logger.
+type logWriter struct {
+	log   *Logger   // The logger to report through
+	level Verbosity // The level to report at
+}
+
+// Write reports the text through the logger.
+func (w *logWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		w.log.Logf(w.level, "%s", line)
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logMessage struct {
+	level Verbosity
+	msg   string
+}
+
+func TestLogHandlerFunc(t *testing.T) {
+	var messages []logMessage
+	obj := LogHandlerFunc(func(level Verbosity, msg string) {
+		messages = append(messages, logMessage{level, msg})
+	})
+
+	obj.Log(VerbosityDebug, "message")
+
+	assert.Equal(t, []logMessage{{VerbosityDebug, "message"}}, messages)
+}
+
+func TestAppWithLogHandler(t *testing.T) {
+	handler := LogHandlerFunc(func(level Verbosity, msg string) {})
+	obj := &App{}
+
+	result := obj.WithLogHandler(handler)
+
+	assert.Same(t, obj, result)
+	assert.NotNil(t, obj.LogHandler)
+}
+
+func TestLoggerHandler(t *testing.T) {
+	var messages []logMessage
+	buf := &bytes.Buffer{}
+	obj := NewLogger(buf, "tool", VerbosityVerbose)
+	obj.Handler = LogHandlerFunc(func(level Verbosity, msg string) {
+		messages = append(messages, logMessage{level, msg})
+	})
+
+	obj.Warnf("warning %d", 1)
+	obj.Infof("info %d", 2)
+	obj.Debugf("debug %d", 3)
+
+	assert.Equal(t, []logMessage{
+		{VerbosityNormal, "warning 1"},
+		{VerbosityVerbose, "info 2"},
+	}, messages)
+	assert.Equal(t, "", buf.String())
+}
+
+func TestLoggerStdLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := NewLogger(buf, "tool", VerbosityNormal)
+
+	result := obj.StdLogger(VerbosityNormal)
+	result.Printf("line %d\nline %d", 1, 2)
+
+	assert.Equal(t, "tool: warning: line 1\ntool: warning: line 2\n", buf.String())
+}
+
+func TestLoggerStdLoggerFiltered(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := NewLogger(buf, "tool", VerbosityNormal)
+
+	result := obj.StdLogger(VerbosityVerbose)
+	result.Print("message")
+
+	assert.Equal(t, "", buf.String())
+}
+
+type stdLoggerCommand struct {
+	Command
+}
+
+func (c *stdLoggerCommand) Run(log *log.Logger) {
+	log.Print("running")
+}
+
+func TestAppDispatchStdLogger(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	obj := (&App{
+		Name:   "tool",
+		Root:   &stdLoggerCommand{},
+		Stderr: stderr,
+	}).WithNoConfigSearch(true).WithVerbosity(true)
+
+	err := obj.Dispatch(context.Background(), []string{"-v"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "tool: running\n", stderr.String())
+}
+
+func TestAppDispatchLogHandler(t *testing.T) {
+	var messages []logMessage
+	stderr := &bytes.Buffer{}
+	obj := (&App{
+		Name:   "tool",
+		Root:   &stdLoggerCommand{},
+		Stderr: stderr,
+	}).WithNoConfigSearch(true).WithVerbosity(true).WithLogHandler(LogHandlerFunc(func(level Verbosity, msg string) {
+		messages = append(messages, logMessage{level, msg})
+	}))
+
+	err := obj.Dispatch(context.Background(), []string{"--verbose"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []logMessage{{VerbosityVerbose, "running"}}, messages)
+	assert.Equal(t, "", stderr.String())
+}
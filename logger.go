@@ -0,0 +1,258 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Verbosity is the level of detail of the messages the application
+// reports; see Logger.  It is available from the injector, and is
+// set from the global verbosity flags if the application allows
+// them; see App.AllowVerbosity.
+type Verbosity int
+
+// Levels of verbosity.  Each VerboseFlag increases the verbosity by
+// one level, beyond VerbosityDebug if repeated.
+const (
+	VerbosityQuiet   Verbosity = -1 // Only errors are reported
+	VerbosityNormal  Verbosity = 0  // Errors and warnings are reported
+	VerbosityVerbose Verbosity = 1  // Informational messages are also reported
+	VerbosityDebug   Verbosity = 2  // Debugging messages are also reported
+)
+
+// Global flags controlling the verbosity, if the application allows
+// them.  Each VerboseFlag or VerboseShortFlag increases the
+// verbosity, and short flags may be repeated, as in "-vv"; QuietFlag
+// or QuietShortFlag selects VerbosityQuiet.
+const (
+	VerboseFlag      = "--verbose"
+	VerboseShortFlag = "-v"
+	QuietFlag        = "--quiet"
+	QuietShortFlag   = "-q"
+)
+
+// LogFormatFlag is the global flag selecting the format of the
+// messages reported by the Logger, if the application allows it;
+// see App.AllowLogFormat.  Its value is one of LogFormatText or
+// LogFormatJSON.
+const LogFormatFlag = "--log-format"
+
+// Formats of the messages reported by the Logger.
+const (
+	LogFormatText = "text" // Messages are reported as prefixed lines of text
+	LogFormatJSON = "json" // Messages are reported as JSON objects, one per line
+)
+
+// Logger reports messages to the user at levels of verbosity,
+// discarding messages whose level exceeds the selected verbosity.
+// The application's logger is available from the injector, and
+// writes to the application's standard error, prefixed by the
+// application name.  The framework itself reports the progress of
+// dispatching at VerbosityDebug.  A nil Logger discards all messages.
+type Logger struct {
+	Verbosity Verbosity  // The selected verbosity
+	Format    string     // The format of messages; defaults to LogFormatText
+	Handler   LogHandler // Optional handler receiving messages instead of the stream
+
+	w      io.Writer // The stream to write to
+	prefix string    // The prefix of each message
+	clock  Clock     // The source of the times of messages
+}
+
+// NewLogger constructs a Logger that writes to the specified stream,
+// prefixing each message with the prefix and ": ", if the prefix is
+// not empty.
+func NewLogger(w io.Writer, prefix string, verbosity Verbosity) *Logger {
+	return &Logger{
+		Verbosity: verbosity,
+		w:         w,
+		prefix:    prefix,
+	}
+}
+
+// Enabled returns true if messages at the specified level are
+// reported.
+func (l *Logger) Enabled(level Verbosity) bool {
+	return l != nil && level <= l.Verbosity
+}
+
+// Logf reports a message at the specified level.  The arguments are
+// interpreted as for fmt.Sprintf.
+func (l *Logger) Logf(level Verbosity, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	if l.Handler != nil {
+		l.Handler.Log(level, msg)
+		return
+	}
+
+	var buf strings.Builder
+	if l.Format == LogFormatJSON {
+		data, _ := json.Marshal(logRecord{
+			Time:    l.now().UTC(),
+			Level:   levelName(level),
+			Logger:  l.prefix,
+			Message: msg,
+		})
+		buf.Write(data)
+	} else {
+		if l.prefix != "" {
+			buf.WriteString(l.prefix + ": ")
+		}
+		if level != VerbosityVerbose {
+			buf.WriteString(levelName(level) + ": ")
+		}
+		buf.WriteString(msg)
+	}
+	buf.WriteString("\n")
+	io.WriteString(l.w, buf.String()) //nolint:errcheck
+}
+
+// now returns the current time, from the logger's clock.
+func (l *Logger) now() time.Time {
+	if l.clock != nil {
+		return l.clock.Now()
+	}
+
+	return defaultClock.Now()
+}
+
+// logRecord describes a message reported in LogFormatJSON.
+type logRecord struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Logger  string    `json:"logger,omitempty"`
+	Message string    `json:"msg"`
+}
+
+// levelName is a helper that returns the name of a level of
+// verbosity, as used to label messages.
+func levelName(level Verbosity) string {
+	switch {
+	case level <= VerbosityQuiet:
+		return "error"
+	case level == VerbosityNormal:
+		return "warning"
+	case level == VerbosityVerbose:
+		return "info"
+	}
+
+	return "debug"
+}
+
+// Errorf reports an error, at VerbosityQuiet.
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.Logf(VerbosityQuiet, format, args...)
+}
+
+// Warnf reports a warning, at VerbosityNormal.
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.Logf(VerbosityNormal, format, args...)
+}
+
+// Infof reports an informational message, at VerbosityVerbose.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.Logf(VerbosityVerbose, format, args...)
+}
+
+// Debugf reports a debugging message, at VerbosityDebug.
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.Logf(VerbosityDebug, format, args...)
+}
+
+// WithVerbosity sets whether the global verbosity flags are
+// recognized; see VerboseFlag.  Returns the App, to allow chaining.
+func (a *App) WithVerbosity(allow bool) *App {
+	a.AllowVerbosity = allow
+	return a
+}
+
+// WithLogFormat sets whether the global LogFormatFlag is recognized.
+// Returns the App, to allow chaining.
+func (a *App) WithLogFormat(allow bool) *App {
+	a.AllowLogFormat = allow
+	return a
+}
+
+// extractVerbosity is a helper that removes the verbosity flags from
+// the arguments preceding any "--", returning the verbosity they
+// select and the remaining arguments.
+func extractVerbosity(args []string) (Verbosity, []string) {
+	verbosity := VerbosityNormal
+	result := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+
+		// Check for the flags
+		switch {
+		case arg == VerboseFlag:
+			verbosity++
+		case arg == QuietFlag || arg == QuietShortFlag:
+			verbosity = VerbosityQuiet
+		case len(arg) > 1 && strings.Trim(arg, "v") == "-":
+			verbosity += Verbosity(len(arg) - 1)
+		default:
+			result = append(result, arg)
+		}
+	}
+
+	return verbosity, result
+}
+
+// extractLogFormat is a helper that removes the LogFormatFlag from
+// the arguments preceding any "--", returning the format it selects
+// and the remaining arguments.
+func extractLogFormat(args []string) (string, []string, error) {
+	format := ""
+	result := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+
+		// Check for the flag
+		switch {
+		case arg == LogFormatFlag:
+			if i+1 >= len(args) {
+				return "", args, fmt.Errorf("%w %s", ErrMissingValue, arg)
+			}
+			i++
+			format = args[i]
+		case strings.HasPrefix(arg, LogFormatFlag+"="):
+			format = arg[len(LogFormatFlag)+1:]
+		default:
+			result = append(result, arg)
+			continue
+		}
+
+		if format != LogFormatText && format != LogFormatJSON {
+			return "", args, fmt.Errorf("%w %s %q: must be %q or %q", ErrInvalidValue, LogFormatFlag, format, LogFormatText, LogFormatJSON)
+		}
+	}
+
+	return format, result, nil
+}
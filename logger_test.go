@@ -0,0 +1,327 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	result := NewLogger(buf, "tool", VerbosityDebug)
+
+	assert.Equal(t, &Logger{
+		Verbosity: VerbosityDebug,
+		w:         buf,
+		prefix:    "tool",
+	}, result)
+}
+
+func TestLoggerEnabled(t *testing.T) {
+	obj := NewLogger(&bytes.Buffer{}, "tool", VerbosityNormal)
+
+	assert.True(t, obj.Enabled(VerbosityQuiet))
+	assert.True(t, obj.Enabled(VerbosityNormal))
+	assert.False(t, obj.Enabled(VerbosityVerbose))
+}
+
+func TestLoggerEnabledNil(t *testing.T) {
+	var obj *Logger
+
+	assert.False(t, obj.Enabled(VerbosityQuiet))
+}
+
+func TestLoggerLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := NewLogger(buf, "tool", VerbosityDebug)
+
+	obj.Errorf("error %d", 1)
+	obj.Warnf("warning %d", 2)
+	obj.Infof("info %d", 3)
+	obj.Debugf("debug %d", 4)
+
+	assert.Equal(t, `tool: error: error 1
+tool: warning: warning 2
+tool: info 3
+tool: debug: debug 4
+`, buf.String())
+}
+
+func TestLoggerFiltered(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := NewLogger(buf, "", VerbosityQuiet)
+
+	obj.Errorf("error")
+	obj.Warnf("warning")
+	obj.Infof("info")
+	obj.Debugf("debug")
+
+	assert.Equal(t, "error: error\n", buf.String())
+}
+
+func TestLoggerNil(t *testing.T) {
+	var obj *Logger
+
+	obj.Errorf("error")
+}
+
+func TestAppWithVerbosity(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithVerbosity(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.AllowVerbosity)
+}
+
+func TestExtractVerbosity(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		verbosity Verbosity
+		remaining []string
+	}{
+		{"None", []string{"sub", "arg"}, VerbosityNormal, []string{"sub", "arg"}},
+		{"Short", []string{"-v", "sub"}, VerbosityVerbose, []string{"sub"}},
+		{"Long", []string{"sub", "--verbose", "--verbose"}, VerbosityDebug, []string{"sub"}},
+		{"Repeated", []string{"-vvv", "sub"}, 3, []string{"sub"}},
+		{"Quiet", []string{"-q", "sub"}, VerbosityQuiet, []string{"sub"}},
+		{"QuietLong", []string{"sub", "--quiet"}, VerbosityQuiet, []string{"sub"}},
+		{"Passthrough", []string{"sub", "--", "-v"}, VerbosityNormal, []string{"sub", "--", "-v"}},
+		{"Other", []string{"-vx", "-"}, VerbosityNormal, []string{"-vx", "-"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verbosity, args := extractVerbosity(test.args)
+
+			assert.Equal(t, test.verbosity, verbosity)
+			assert.Equal(t, test.remaining, args)
+		})
+	}
+}
+
+type verbosityCommand struct {
+	Command
+	verbosity Verbosity
+}
+
+func (c *verbosityCommand) Run(verbosity Verbosity, log *Logger) {
+	c.verbosity = verbosity
+	log.Infof("running")
+}
+
+func TestAppDispatchVerbosity(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	root := &verbosityCommand{}
+	obj := (&App{
+		Name:   "tool",
+		Root:   root,
+		Stderr: stderr,
+	}).WithNoConfigSearch(true).WithVerbosity(true)
+
+	err := obj.Dispatch(context.Background(), []string{"-vv"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, VerbosityDebug, root.verbosity)
+	assert.Equal(t, `tool: debug: resolved command "tool" with arguments []
+tool: running
+`, stderr.String())
+}
+
+type versionOptions struct {
+	Version bool `opt:"version,v" help:"Show the version"`
+}
+
+type versionCommand struct {
+	verbosityCommand
+	version bool
+}
+
+func (c *versionCommand) Run(opts *versionOptions, verbosity Verbosity) {
+	c.version, c.verbosity = opts.Version, verbosity
+}
+
+func TestAppDispatchVerbosityDeclared(t *testing.T) {
+	sub := &versionCommand{verbosityCommand: verbosityCommand{Command: Command{Defaults: &versionOptions{}}}}
+	obj := (&App{
+		Name:   "tool",
+		Root:   &Command{Subcommands: map[string]ICommand{"sub": sub}},
+		Stderr: &bytes.Buffer{},
+	}).WithNoConfigSearch(true).WithVerbosity(true).WithOutput(true)
+
+	err := obj.Dispatch(context.Background(), []string{"-o", "json", "-v", "sub", "-v"})
+
+	assert.NoError(t, err)
+	assert.True(t, sub.version)
+	assert.Equal(t, VerbosityVerbose, sub.verbosity)
+}
+
+type grepOptions struct {
+	Pattern string `opt:"pattern,e" help:"Pattern to match"`
+}
+
+type grepCommand struct {
+	Command
+	pattern   string
+	args      Args
+	verbosity Verbosity
+}
+
+func (c *grepCommand) Run(opts *grepOptions, args Args, verbosity Verbosity) {
+	c.pattern, c.args, c.verbosity = opts.Pattern, args, verbosity
+}
+
+func TestAppDispatchVerbosityDeclaredValue(t *testing.T) {
+	grep := &grepCommand{Command: Command{Defaults: &grepOptions{}}}
+	obj := (&App{
+		Name:   "tool",
+		Root:   &Command{Subcommands: map[string]ICommand{"grep": grep}},
+		Stderr: &bytes.Buffer{},
+	}).WithNoConfigSearch(true).WithVerbosity(true)
+
+	err := obj.Dispatch(context.Background(), []string{"grep", "--pattern", "-v", "x"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "-v", grep.pattern)
+	assert.Equal(t, Args{"x"}, grep.args)
+	assert.Equal(t, VerbosityNormal, grep.verbosity)
+}
+
+func TestAppDispatchVerbosityDefault(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	root := &verbosityCommand{verbosity: VerbosityDebug}
+	obj := (&App{
+		Name:   "tool",
+		Root:   root,
+		Stderr: stderr,
+	}).WithNoConfigSearch(true)
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, VerbosityNormal, root.verbosity)
+	assert.Equal(t, "", stderr.String())
+}
+
+func TestAppDispatchVerbosityConfig(t *testing.T) {
+	path := makeArgFile(t, "verbose: true\n")
+	stderr := &bytes.Buffer{}
+	obj := (&App{
+		Name:   "tool",
+		Root:   &verbosityCommand{},
+		Stderr: stderr,
+	}).WithNoConfigSearch(true).WithConfigFile(path).WithVerbosity(true)
+
+	err := obj.Dispatch(context.Background(), []string{"-vv"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, stderr.String(), "tool: debug: loaded configuration file "+path+"\n")
+}
+
+func TestLoggerJSON(t *testing.T) {
+	fakeEventNow(t)
+	buf := &bytes.Buffer{}
+	obj := NewLogger(buf, "tool", VerbosityDebug)
+	obj.Format = LogFormatJSON
+
+	obj.Warnf("warning %d", 2)
+	obj.Debugf("debug %d", 4)
+
+	assert.Equal(t, `{"time":"2021-03-04T05:06:07Z","level":"warning","logger":"tool","msg":"warning 2"}
+{"time":"2021-03-04T05:06:07Z","level":"debug","logger":"tool","msg":"debug 4"}
+`, buf.String())
+}
+
+func TestLoggerJSONNoPrefix(t *testing.T) {
+	fakeEventNow(t)
+	buf := &bytes.Buffer{}
+	obj := NewLogger(buf, "", VerbosityNormal)
+	obj.Format = LogFormatJSON
+
+	obj.Errorf("error")
+
+	assert.Equal(t, `{"time":"2021-03-04T05:06:07Z","level":"error","msg":"error"}
+`, buf.String())
+}
+
+func TestAppWithLogFormat(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithLogFormat(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.AllowLogFormat)
+}
+
+func TestExtractLogFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		format    string
+		remaining []string
+		err       error
+	}{
+		{"None", []string{"sub", "arg"}, "", []string{"sub", "arg"}, nil},
+		{"Separate", []string{"--log-format", "json", "sub"}, LogFormatJSON, []string{"sub"}, nil},
+		{"Joined", []string{"sub", "--log-format=text"}, LogFormatText, []string{"sub"}, nil},
+		{"Passthrough", []string{"sub", "--", "--log-format=json"}, "", []string{"sub", "--", "--log-format=json"}, nil},
+		{"Missing", []string{"sub", "--log-format"}, "", []string{"sub", "--log-format"}, ErrMissingValue},
+		{"Invalid", []string{"--log-format=xml", "sub"}, "", []string{"--log-format=xml", "sub"}, ErrInvalidValue},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, args, err := extractLogFormat(test.args)
+
+			assert.ErrorIs(t, err, test.err)
+			assert.Equal(t, test.format, format)
+			assert.Equal(t, test.remaining, args)
+		})
+	}
+}
+
+func TestAppDispatchLogFormat(t *testing.T) {
+	fakeEventNow(t)
+	stderr := &bytes.Buffer{}
+	obj := (&App{
+		Name:   "tool",
+		Root:   &verbosityCommand{},
+		Stderr: stderr,
+	}).WithNoConfigSearch(true).WithVerbosity(true).WithLogFormat(true)
+
+	err := obj.Dispatch(context.Background(), []string{"-v", "--log-format", "json"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"time":"2021-03-04T05:06:07Z","level":"info","logger":"tool","msg":"running"}
+`, stderr.String())
+}
+
+func TestAppDispatchLogFormatInvalid(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	obj := (&App{
+		Name:   "tool",
+		Root:   &verbosityCommand{},
+		Stderr: stderr,
+	}).WithNoConfigSearch(true).WithLogFormat(true)
+
+	err := obj.Dispatch(context.Background(), []string{"--log-format=xml"})
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.True(t, err.(*CommandError).Usage)
+}
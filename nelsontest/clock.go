@@ -0,0 +1,157 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelsontest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/klmitch/nelson"
+)
+
+// FakeClock is a nelson.Clock for tests.  Its time only changes when
+// it is advanced, at which point any timers and tickers that are due
+// fire.  Install it with App.WithClock.
+type FakeClock struct {
+	sync.Mutex
+	now    time.Time    // The current time
+	timers []*fakeTimer // The timers and tickers that have not stopped
+}
+
+// NewFakeClock constructs a FakeClock with the specified time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the current time.
+func (c *FakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.now
+}
+
+// NewTimer returns a timer that fires once the clock has been
+// advanced by the duration.
+func (c *FakeClock) NewTimer(d time.Duration) nelson.Timer {
+	return c.add(d, 0)
+}
+
+// NewTicker returns a ticker that fires each time the clock has been
+// advanced past another interval.
+func (c *FakeClock) NewTicker(d time.Duration) nelson.Ticker {
+	return fakeTicker{c.add(d, d)}
+}
+
+// Advance advances the clock by the duration, firing any timers and
+// tickers that are due.  As with time.Ticker, a ticker whose channel
+// is full drops ticks rather than blocking.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.when.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+
+		select {
+		case t.ch <- c.now:
+		default:
+		}
+		if t.period > 0 {
+			for !t.when.After(c.now) {
+				t.when = t.when.Add(t.period)
+			}
+			pending = append(pending, t)
+		}
+	}
+	c.timers = pending
+}
+
+// Pending returns the number of timers and tickers that have neither
+// fired nor been stopped.  Tests may use it to wait until the code
+// under test has started a timer before advancing the clock.
+func (c *FakeClock) Pending() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return len(c.timers)
+}
+
+// add is a helper that adds a timer, or a ticker if the period is
+// non-zero.
+func (c *FakeClock) add(d, period time.Duration) *fakeTimer {
+	c.Lock()
+	defer c.Unlock()
+
+	t := &fakeTimer{
+		clock:  c,
+		ch:     make(chan time.Time, 1),
+		when:   c.now.Add(d),
+		period: period,
+	}
+	c.timers = append(c.timers, t)
+
+	return t
+}
+
+// remove is a helper that removes a timer, returning false if it
+// had already fired or been stopped.
+func (c *FakeClock) remove(t *fakeTimer) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	for i, other := range c.timers {
+		if other == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// fakeTimer is a timer or ticker of a FakeClock.
+type fakeTimer struct {
+	clock  *FakeClock     // The clock
+	ch     chan time.Time // The channel the time is delivered on
+	when   time.Time      // When the timer next fires
+	period time.Duration  // The interval of a ticker, or 0
+}
+
+// C returns the channel on which the time is delivered.
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+// Stop stops the timer or ticker, returning false if it has already
+// fired or been stopped.
+func (t *fakeTimer) Stop() bool {
+	return t.clock.remove(t)
+}
+
+// fakeTicker adapts a fakeTimer to the Ticker interface.
+type fakeTicker struct {
+	*fakeTimer
+}
+
+// Stop stops the ticker.
+func (t fakeTicker) Stop() {
+	t.clock.remove(t.fakeTimer)
+}
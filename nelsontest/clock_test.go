@@ -0,0 +1,88 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelsontest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/klmitch/nelson"
+)
+
+var epoch = time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+func TestFakeClockImplementsClock(t *testing.T) {
+	assert.Implements(t, (*nelson.Clock)(nil), &FakeClock{})
+}
+
+func TestFakeClockAdvance(t *testing.T) {
+	obj := NewFakeClock(epoch)
+
+	obj.Advance(time.Minute)
+
+	assert.Equal(t, epoch.Add(time.Minute), obj.Now())
+}
+
+func TestFakeClockTimer(t *testing.T) {
+	obj := NewFakeClock(epoch)
+	timer := obj.NewTimer(time.Minute)
+
+	obj.Advance(30 * time.Second)
+	assert.Len(t, timer.C(), 0)
+	assert.Equal(t, 1, obj.Pending())
+	obj.Advance(30 * time.Second)
+
+	assert.Equal(t, epoch.Add(time.Minute), <-timer.C())
+	assert.Equal(t, 0, obj.Pending())
+	assert.False(t, timer.Stop())
+}
+
+func TestFakeClockTimerStop(t *testing.T) {
+	obj := NewFakeClock(epoch)
+	timer := obj.NewTimer(time.Minute)
+
+	assert.True(t, timer.Stop())
+	obj.Advance(time.Minute)
+
+	assert.Len(t, timer.C(), 0)
+	assert.Equal(t, 0, obj.Pending())
+}
+
+func TestFakeClockTicker(t *testing.T) {
+	obj := NewFakeClock(epoch)
+	ticker := obj.NewTicker(time.Minute)
+
+	obj.Advance(time.Minute)
+	assert.Equal(t, epoch.Add(time.Minute), <-ticker.C())
+	obj.Advance(150 * time.Second)
+	obj.Advance(30 * time.Second)
+
+	assert.Equal(t, epoch.Add(210*time.Second), <-ticker.C())
+	assert.Len(t, ticker.C(), 0)
+	assert.Equal(t, 1, obj.Pending())
+}
+
+func TestFakeClockTickerStop(t *testing.T) {
+	obj := NewFakeClock(epoch)
+	ticker := obj.NewTicker(time.Minute)
+
+	ticker.Stop()
+	obj.Advance(time.Minute)
+
+	assert.Len(t, ticker.C(), 0)
+	assert.Equal(t, 0, obj.Pending())
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package nelsontest contains helpers for testing applications built
+// with nelson.
+package nelsontest
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/klmitch/nelson"
+)
+
+// GoldenExt is the extension of the golden files.
+const GoldenExt = ".golden"
+
+// Update is the "-update" test flag.  When set, the golden files are
+// rewritten from the current output rather than compared with it.
+var Update = flag.Bool("update", false, "update golden files")
+
+// GoldenName returns the name of the golden file containing the help
+// for the command with the specified path, which begins with the
+// application name; e.g., "tool_sub.golden".
+func GoldenName(path []string) string {
+	return strings.Join(path, "_") + GoldenExt
+}
+
+// AssertHelp compares the full help of every command in the
+// application's command tree, as produced by App.HelpPages, with the
+// golden files in the specified directory, one per command; see
+// GoldenName.  A test failure is reported for each command whose
+// help differs from its golden file, as well as for golden files of
+// commands no longer in the tree.  If the Update flag is set, the
+// golden files are instead rewritten and stale golden files are
+// removed.  Returns true if the help matched.
+func AssertHelp(t testing.TB, app *nelson.App, dir string) bool {
+	t.Helper()
+
+	pages, err := app.HelpPages()
+	if err != nil {
+		t.Errorf("unable to render help: %s", err)
+		return false
+	}
+
+	// Compare or update the golden files
+	ok := true
+	seen := map[string]bool{}
+	for _, page := range pages {
+		name := GoldenName(page.Path)
+		seen[name] = true
+		if !checkGolden(t, filepath.Join(dir, name), strings.Join(page.Path, " "), page.Text) {
+			ok = false
+		}
+	}
+
+	// Check for stale golden files
+	stale, err := filepath.Glob(filepath.Join(dir, "*"+GoldenExt))
+	if err != nil {
+		t.Errorf("unable to list golden files: %s", err)
+		return false
+	}
+	sort.Strings(stale)
+	for _, file := range stale {
+		if seen[filepath.Base(file)] {
+			continue
+		}
+		if *Update {
+			if err := os.Remove(file); err != nil {
+				t.Errorf("unable to remove stale golden file: %s", err)
+				ok = false
+			}
+			continue
+		}
+		t.Errorf("golden file %s does not match any command; run the tests with -update to remove it", file)
+		ok = false
+	}
+
+	return ok
+}
+
+// checkGolden is a helper that compares the text with a golden file,
+// or rewrites the golden file if the Update flag is set.
+func checkGolden(t testing.TB, file, name, text string) bool {
+	t.Helper()
+
+	if *Update {
+		return writeGolden(t, file, text)
+	}
+
+	want, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Errorf("unable to read golden file for %q: %s; run the tests with -update to create it", name, err)
+		return false
+	}
+
+	return assert.Equalf(t, string(want), text, "help for %q differs from %s; run the tests with -update to accept the change", name, file)
+}
+
+// writeGolden is a helper that writes a golden file, creating its
+// directory if necessary.
+func writeGolden(t testing.TB, file, text string) bool {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Errorf("unable to update golden file: %s", err)
+		return false
+	}
+	if err := ioutil.WriteFile(file, []byte(text), 0o644); err != nil { //nolint:gosec
+		t.Errorf("unable to update golden file: %s", err)
+		return false
+	}
+
+	return true
+}
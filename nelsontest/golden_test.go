@@ -0,0 +1,172 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelsontest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/klmitch/nelson"
+)
+
+// fakeT is a testing.TB that records reported errors.
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Name() string {
+	return "fake"
+}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+// setUpdate is a helper that sets the Update flag for the duration
+// of a test.
+func setUpdate(t *testing.T, update bool) {
+	old := *Update
+	*Update = update
+	t.Cleanup(func() { *Update = old })
+}
+
+// goldenApp is a helper that constructs an application for testing
+// the golden file helpers.
+func goldenApp(summary string) *nelson.App {
+	return &nelson.App{
+		Name:  "tool",
+		Width: 40,
+		Root: &nelson.Command{
+			Summary: "The tool",
+			Subcommands: map[string]nelson.ICommand{
+				"sub": &nelson.Command{Summary: summary},
+			},
+		},
+	}
+}
+
+func TestGoldenName(t *testing.T) {
+	assert.Equal(t, "tool.golden", GoldenName([]string{"tool"}))
+	assert.Equal(t, "tool_sub_cmd.golden", GoldenName([]string{"tool", "sub", "cmd"}))
+}
+
+func TestAssertHelpUpdate(t *testing.T) {
+	setUpdate(t, true)
+	dir := filepath.Join(t.TempDir(), "testdata")
+	app := goldenApp("A subcommand")
+	fake := &fakeT{}
+
+	result := AssertHelp(fake, app, dir)
+
+	assert.True(t, result)
+	assert.Nil(t, fake.errors)
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	assert.Equal(t, []string{
+		filepath.Join(dir, "tool.golden"),
+		filepath.Join(dir, "tool_sub.golden"),
+	}, files)
+	pages, _ := app.HelpPages()
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "tool_sub.golden"))
+	assert.Equal(t, pages[1].Text, string(data))
+}
+
+func TestAssertHelpMatch(t *testing.T) {
+	setUpdate(t, true)
+	dir := t.TempDir()
+	AssertHelp(t, goldenApp("A subcommand"), dir)
+	setUpdate(t, false)
+	fake := &fakeT{}
+
+	result := AssertHelp(fake, goldenApp("A subcommand"), dir)
+
+	assert.True(t, result)
+	assert.Nil(t, fake.errors)
+}
+
+func TestAssertHelpDiffers(t *testing.T) {
+	setUpdate(t, true)
+	dir := t.TempDir()
+	AssertHelp(t, goldenApp("A subcommand"), dir)
+	setUpdate(t, false)
+	fake := &fakeT{}
+
+	result := AssertHelp(fake, goldenApp("A changed subcommand"), dir)
+
+	assert.False(t, result)
+	assert.Len(t, fake.errors, 2)
+	assert.Contains(t, fake.errors[0], `help for "tool" differs`)
+	assert.Contains(t, fake.errors[1], `help for "tool sub" differs`)
+}
+
+func TestAssertHelpMissing(t *testing.T) {
+	setUpdate(t, false)
+	fake := &fakeT{}
+
+	result := AssertHelp(fake, goldenApp("A subcommand"), t.TempDir())
+
+	assert.False(t, result)
+	assert.Len(t, fake.errors, 2)
+	assert.Contains(t, fake.errors[0], `unable to read golden file for "tool"`)
+}
+
+func TestAssertHelpStale(t *testing.T) {
+	setUpdate(t, true)
+	dir := t.TempDir()
+	AssertHelp(t, goldenApp("A subcommand"), dir)
+	stale := filepath.Join(dir, "tool_gone.golden")
+	assert.NoError(t, ioutil.WriteFile(stale, []byte("gone"), 0o644))
+	setUpdate(t, false)
+	fake := &fakeT{}
+
+	result := AssertHelp(fake, goldenApp("A subcommand"), dir)
+
+	assert.False(t, result)
+	assert.Equal(t, []string{
+		fmt.Sprintf("golden file %s does not match any command; run the tests with -update to remove it", stale),
+	}, fake.errors)
+}
+
+func TestAssertHelpStaleUpdate(t *testing.T) {
+	setUpdate(t, true)
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "tool_gone.golden")
+	assert.NoError(t, ioutil.WriteFile(stale, []byte("gone"), 0o644))
+	fake := &fakeT{}
+
+	result := AssertHelp(fake, goldenApp("A subcommand"), dir)
+
+	assert.True(t, result)
+	assert.Nil(t, fake.errors)
+	assert.NoFileExists(t, stale)
+}
+
+func TestAssertHelpRenderError(t *testing.T) {
+	app := goldenApp("A subcommand")
+	app.HelpTemplate = "{{ .Bogus }}"
+	fake := &fakeT{}
+
+	result := AssertHelp(fake, app, t.TempDir())
+
+	assert.False(t, result)
+	assert.Len(t, fake.errors, 1)
+	assert.Contains(t, fake.errors[0], "unable to render help")
+}
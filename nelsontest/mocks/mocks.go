@@ -0,0 +1,199 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package mocks contains testify mocks of the interfaces of the
+// nelson framework, for use in testing applications built with it.
+// Each mock records its calls through mock.Mock.MethodCalled, so
+// expectations are set with On and verified with AssertExpectations
+// as usual.
+package mocks
+
+import (
+	"context"
+	"io"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/klmitch/nelson"
+)
+
+// ICommand is a mock of nelson.ICommand.
+type ICommand struct {
+	mock.Mock
+}
+
+// GetSummary retrieves the command summary.
+func (m *ICommand) GetSummary() string {
+	args := m.MethodCalled("GetSummary")
+
+	return args.String(0)
+}
+
+// GetDescription retrieves the command's full description.
+func (m *ICommand) GetDescription() string {
+	args := m.MethodCalled("GetDescription")
+
+	return args.String(0)
+}
+
+// GetGroup retrieves the group name of the command.
+func (m *ICommand) GetGroup() string {
+	args := m.MethodCalled("GetGroup")
+
+	return args.String(0)
+}
+
+// GetSubcommands retrieves subcommands for this command.
+func (m *ICommand) GetSubcommands() map[string]nelson.ICommand {
+	args := m.MethodCalled("GetSubcommands")
+
+	if tmp := args.Get(0); tmp != nil {
+		return tmp.(map[string]nelson.ICommand)
+	}
+
+	return nil
+}
+
+// GetDefaults retrieves the defaults for arguments for this command.
+func (m *ICommand) GetDefaults() interface{} {
+	args := m.MethodCalled("GetDefaults")
+
+	return args.Get(0)
+}
+
+// IWrapped is a mock of a command implementing nelson.IWrapped.  It
+// also implements nelson.ICommand, since wrappers are commands.
+type IWrapped struct {
+	ICommand
+}
+
+// Unwrap returns the wrapped command.
+func (m *IWrapped) Unwrap() nelson.ICommand {
+	args := m.MethodCalled("Unwrap")
+
+	if tmp := args.Get(0); tmp != nil {
+		return tmp.(nelson.ICommand)
+	}
+
+	return nil
+}
+
+// Runner is a mock of nelson.Runner.
+type Runner struct {
+	mock.Mock
+}
+
+// Run runs the command invocation.
+func (m *Runner) Run(ctx context.Context, inv *nelson.Invocation, inj *nelson.Injector) error {
+	args := m.MethodCalled("Run", ctx, inv, inj)
+
+	return args.Error(0)
+}
+
+// OutputFormatter is a mock of nelson.OutputFormatter.
+type OutputFormatter struct {
+	mock.Mock
+}
+
+// Format renders the data to the stream.
+func (m *OutputFormatter) Format(w io.Writer, value interface{}) error {
+	args := m.MethodCalled("Format", w, value)
+
+	return args.Error(0)
+}
+
+// ConfigProvider is a mock of nelson.ConfigProvider.
+type ConfigProvider struct {
+	mock.Mock
+}
+
+// Load loads the configuration.
+func (m *ConfigProvider) Load(ctx context.Context) (map[string]interface{}, error) {
+	args := m.MethodCalled("Load", ctx)
+
+	if tmp := args.Get(0); tmp != nil {
+		return tmp.(map[string]interface{}), args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+// Exiter is a mock of nelson.Exiter.
+type Exiter struct {
+	mock.Mock
+}
+
+// Exit exits the program with the specified exit code.
+func (m *Exiter) Exit(code int) {
+	m.MethodCalled("Exit", code)
+}
+
+// LogHandler is a mock of nelson.LogHandler.
+type LogHandler struct {
+	mock.Mock
+}
+
+// Log handles a message reported at the specified level.
+func (m *LogHandler) Log(level nelson.Verbosity, msg string) {
+	m.MethodCalled("Log", level, msg)
+}
+
+// Tracer is a mock of nelson.Tracer.
+type Tracer struct {
+	mock.Mock
+}
+
+// Start starts a span with the specified name.
+func (m *Tracer) Start(ctx context.Context, name string) (context.Context, nelson.Span) {
+	args := m.MethodCalled("Start", ctx, name)
+
+	var span nelson.Span
+	if tmp := args.Get(1); tmp != nil {
+		span = tmp.(nelson.Span)
+	}
+
+	return args.Get(0).(context.Context), span
+}
+
+// Span is a mock of nelson.Span.
+type Span struct {
+	mock.Mock
+}
+
+// SetAttribute sets an attribute of the span.
+func (m *Span) SetAttribute(key string, value interface{}) {
+	m.MethodCalled("SetAttribute", key, value)
+}
+
+// RecordError records the error that ended the command.
+func (m *Span) RecordError(err error) {
+	m.MethodCalled("RecordError", err)
+}
+
+// End ends the span.
+func (m *Span) End() {
+	m.MethodCalled("End")
+}
+
+// AuditSink is a mock of nelson.AuditSink.
+type AuditSink struct {
+	mock.Mock
+}
+
+// Record records an invocation.
+func (m *AuditSink) Record(ctx context.Context, rec *nelson.AuditRecord) error {
+	args := m.MethodCalled("Record", ctx, rec)
+
+	return args.Error(0)
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/klmitch/nelson"
+)
+
+func TestICommandImplementsICommand(t *testing.T) {
+	assert.Implements(t, (*nelson.ICommand)(nil), &ICommand{})
+}
+
+func TestICommandGetters(t *testing.T) {
+	subs := map[string]nelson.ICommand{"sub": &ICommand{}}
+	obj := &ICommand{}
+	obj.On("GetSummary").Return("summary")
+	obj.On("GetDescription").Return("description")
+	obj.On("GetGroup").Return("group")
+	obj.On("GetSubcommands").Return(subs)
+	obj.On("GetDefaults").Return("defaults")
+
+	assert.Equal(t, "summary", obj.GetSummary())
+	assert.Equal(t, "description", obj.GetDescription())
+	assert.Equal(t, "group", obj.GetGroup())
+	assert.Equal(t, subs, obj.GetSubcommands())
+	assert.Equal(t, "defaults", obj.GetDefaults())
+	obj.AssertExpectations(t)
+}
+
+func TestICommandGetSubcommandsNil(t *testing.T) {
+	obj := &ICommand{}
+	obj.On("GetSubcommands").Return(nil)
+
+	result := obj.GetSubcommands()
+
+	assert.Nil(t, result)
+	obj.AssertExpectations(t)
+}
+
+func TestIWrappedImplementsIWrapped(t *testing.T) {
+	assert.Implements(t, (*nelson.IWrapped)(nil), &IWrapped{})
+	assert.Implements(t, (*nelson.ICommand)(nil), &IWrapped{})
+}
+
+func TestIWrappedUnwrap(t *testing.T) {
+	cmd := &ICommand{}
+	obj := &IWrapped{}
+	obj.On("Unwrap").Return(cmd)
+
+	result := obj.Unwrap()
+
+	assert.Same(t, cmd, result)
+	obj.AssertExpectations(t)
+}
+
+func TestIWrappedUnwrapNil(t *testing.T) {
+	obj := &IWrapped{}
+	obj.On("Unwrap").Return(nil)
+
+	result := obj.Unwrap()
+
+	assert.Nil(t, result)
+	obj.AssertExpectations(t)
+}
+
+func TestRunnerImplementsRunner(t *testing.T) {
+	assert.Implements(t, (*nelson.Runner)(nil), &Runner{})
+}
+
+func TestRunnerRun(t *testing.T) {
+	ctx := context.Background()
+	inv := &nelson.Invocation{}
+	inj := nelson.NewInjector()
+	obj := &Runner{}
+	obj.On("Run", ctx, inv, inj).Return(assert.AnError)
+
+	err := obj.Run(ctx, inv, inj)
+
+	assert.Same(t, assert.AnError, err)
+	obj.AssertExpectations(t)
+}
+
+func TestOutputFormatterImplementsOutputFormatter(t *testing.T) {
+	assert.Implements(t, (*nelson.OutputFormatter)(nil), &OutputFormatter{})
+}
+
+func TestOutputFormatterFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &OutputFormatter{}
+	obj.On("Format", buf, "value").Return(nil)
+
+	err := obj.Format(buf, "value")
+
+	assert.NoError(t, err)
+	obj.AssertExpectations(t)
+}
+
+func TestConfigProviderImplementsConfigProvider(t *testing.T) {
+	assert.Implements(t, (*nelson.ConfigProvider)(nil), &ConfigProvider{})
+}
+
+func TestConfigProviderLoad(t *testing.T) {
+	cfg := map[string]interface{}{"key": "value"}
+	obj := &ConfigProvider{}
+	obj.On("Load", mock.Anything).Return(cfg, nil)
+
+	result, err := obj.Load(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, cfg, result)
+	obj.AssertExpectations(t)
+}
+
+func TestConfigProviderLoadError(t *testing.T) {
+	obj := &ConfigProvider{}
+	obj.On("Load", mock.Anything).Return(nil, errors.New("failed"))
+
+	result, err := obj.Load(context.Background())
+
+	assert.EqualError(t, err, "failed")
+	assert.Nil(t, result)
+	obj.AssertExpectations(t)
+}
+
+func TestExiterImplementsExiter(t *testing.T) {
+	assert.Implements(t, (*nelson.Exiter)(nil), &Exiter{})
+}
+
+func TestExiterExit(t *testing.T) {
+	obj := &Exiter{}
+	obj.On("Exit", 2)
+
+	obj.Exit(2)
+
+	obj.AssertExpectations(t)
+}
+
+func TestLogHandlerImplementsLogHandler(t *testing.T) {
+	assert.Implements(t, (*nelson.LogHandler)(nil), &LogHandler{})
+}
+
+func TestLogHandlerLog(t *testing.T) {
+	obj := &LogHandler{}
+	obj.On("Log", nelson.VerbosityDebug, "message")
+
+	obj.Log(nelson.VerbosityDebug, "message")
+
+	obj.AssertExpectations(t)
+}
+
+func TestTracerImplementsTracer(t *testing.T) {
+	assert.Implements(t, (*nelson.Tracer)(nil), &Tracer{})
+}
+
+func TestTracerStart(t *testing.T) {
+	ctx := context.Background()
+	span := &Span{}
+	obj := &Tracer{}
+	obj.On("Start", ctx, "tool sub").Return(ctx, span)
+
+	resultCtx, resultSpan := obj.Start(ctx, "tool sub")
+
+	assert.Same(t, span, resultSpan)
+	assert.Equal(t, ctx, resultCtx)
+	obj.AssertExpectations(t)
+}
+
+func TestTracerStartNil(t *testing.T) {
+	ctx := context.Background()
+	obj := &Tracer{}
+	obj.On("Start", ctx, "tool").Return(ctx, nil)
+
+	_, result := obj.Start(ctx, "tool")
+
+	assert.Nil(t, result)
+	obj.AssertExpectations(t)
+}
+
+func TestSpanImplementsSpan(t *testing.T) {
+	assert.Implements(t, (*nelson.Span)(nil), &Span{})
+}
+
+func TestSpan(t *testing.T) {
+	obj := &Span{}
+	obj.On("SetAttribute", nelson.AttrExitCode, 0)
+	obj.On("RecordError", assert.AnError)
+	obj.On("End")
+
+	obj.SetAttribute(nelson.AttrExitCode, 0)
+	obj.RecordError(assert.AnError)
+	obj.End()
+
+	obj.AssertExpectations(t)
+}
+
+func TestAuditSinkImplementsAuditSink(t *testing.T) {
+	assert.Implements(t, (*nelson.AuditSink)(nil), &AuditSink{})
+}
+
+func TestAuditSinkRecord(t *testing.T) {
+	ctx := context.Background()
+	rec := &nelson.AuditRecord{Command: "tool sub"}
+	obj := &AuditSink{}
+	obj.On("Record", ctx, rec).Return(assert.AnError)
+
+	err := obj.Record(ctx, rec)
+
+	assert.Same(t, assert.AnError, err)
+	obj.AssertExpectations(t)
+}
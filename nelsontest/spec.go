@@ -0,0 +1,75 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelsontest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/klmitch/nelson"
+	"github.com/klmitch/nelson/compat"
+)
+
+// AssertSpec compares the machine-readable specification of the
+// application's command line interface, as produced by App.Spec,
+// with the snapshot in the specified file, which is intended to be
+// checked in alongside the tests.  A test failure listing the
+// changes, classified as by compat.Diff, is reported if the
+// interface differs from the snapshot; this makes any change to the
+// interface an explicit part of the change under review.  If the
+// Update flag is set, the snapshot is instead rewritten.  Returns
+// true if the interface matched.
+func AssertSpec(t testing.TB, app *nelson.App, file string) bool {
+	t.Helper()
+
+	spec := app.Spec()
+	data, err := spec.JSON()
+	if err != nil {
+		t.Errorf("unable to render specification: %s", err)
+		return false
+	}
+	text := string(data) + "\n"
+	if *Update {
+		return writeGolden(t, file, text)
+	}
+
+	want, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Errorf("unable to read specification snapshot: %s; run the tests with -update to create it", err)
+		return false
+	}
+	if string(want) == text {
+		return true
+	}
+
+	// Describe the changes
+	before := &nelson.CommandSpec{}
+	if err := json.Unmarshal(want, before); err != nil {
+		t.Errorf("unable to parse specification snapshot %s: %s; run the tests with -update to regenerate it", file, err)
+		return false
+	}
+	changes := []string{}
+	for _, c := range compat.Diff(before, spec).Changes {
+		changes = append(changes, "  "+c.String())
+	}
+	if len(changes) == 0 {
+		changes = append(changes, "  no changes in usage; descriptive text differs")
+	}
+	t.Errorf("command line interface differs from %s; run the tests with -update to accept the changes:\n%s", file, strings.Join(changes, "\n"))
+
+	return false
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelsontest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/klmitch/nelson"
+)
+
+type specOptions struct {
+	Force bool `opt:"force,f" help:"Force the operation"`
+}
+
+func TestAssertSpecUpdate(t *testing.T) {
+	setUpdate(t, true)
+	file := filepath.Join(t.TempDir(), "testdata", "cli.json")
+	app := goldenApp("A subcommand")
+	fake := &fakeT{}
+
+	result := AssertSpec(fake, app, file)
+
+	assert.True(t, result)
+	assert.Nil(t, fake.errors)
+	data, _ := ioutil.ReadFile(file)
+	text, _ := app.Spec().JSON()
+	assert.Equal(t, string(text)+"\n", string(data))
+}
+
+func TestAssertSpecMatch(t *testing.T) {
+	setUpdate(t, true)
+	file := filepath.Join(t.TempDir(), "cli.json")
+	AssertSpec(t, goldenApp("A subcommand"), file)
+	setUpdate(t, false)
+	fake := &fakeT{}
+
+	result := AssertSpec(fake, goldenApp("A subcommand"), file)
+
+	assert.True(t, result)
+	assert.Nil(t, fake.errors)
+}
+
+func TestAssertSpecChanged(t *testing.T) {
+	setUpdate(t, true)
+	file := filepath.Join(t.TempDir(), "cli.json")
+	AssertSpec(t, goldenApp("A subcommand"), file)
+	setUpdate(t, false)
+	app := goldenApp("A subcommand")
+	app.Root.(*nelson.Command).Subcommands["sub"] = &nelson.Command{
+		Summary:  "A subcommand",
+		Defaults: &specOptions{},
+	}
+	fake := &fakeT{}
+
+	result := AssertSpec(fake, app, file)
+
+	assert.False(t, result)
+	assert.Len(t, fake.errors, 1)
+	assert.Contains(t, fake.errors[0], "command line interface differs from "+file)
+	assert.Contains(t, fake.errors[0], "\n  additive: tool sub: flag --force added")
+}
+
+func TestAssertSpecTextChanged(t *testing.T) {
+	setUpdate(t, true)
+	file := filepath.Join(t.TempDir(), "cli.json")
+	AssertSpec(t, goldenApp("A subcommand"), file)
+	setUpdate(t, false)
+	fake := &fakeT{}
+
+	result := AssertSpec(fake, goldenApp("A changed subcommand"), file)
+
+	assert.False(t, result)
+	assert.Len(t, fake.errors, 1)
+	assert.Contains(t, fake.errors[0], "\n  no changes in usage; descriptive text differs")
+}
+
+func TestAssertSpecMissing(t *testing.T) {
+	setUpdate(t, false)
+	fake := &fakeT{}
+
+	result := AssertSpec(fake, goldenApp("A subcommand"), filepath.Join(t.TempDir(), "cli.json"))
+
+	assert.False(t, result)
+	assert.Len(t, fake.errors, 1)
+	assert.Contains(t, fake.errors[0], "unable to read specification snapshot")
+}
+
+func TestAssertSpecCorrupt(t *testing.T) {
+	setUpdate(t, false)
+	file := filepath.Join(t.TempDir(), "cli.json")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("{"), 0o644))
+	fake := &fakeT{}
+
+	result := AssertSpec(fake, goldenApp("A subcommand"), file)
+
+	assert.False(t, result)
+	assert.Len(t, fake.errors, 1)
+	assert.Contains(t, fake.errors[0], "unable to parse specification snapshot")
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+)
+
+// Errors describing network values that could not be converted.
+var (
+	ErrNotIP       = errors.New("not an IP address")
+	ErrNotCIDR     = errors.New("not a CIDR block, as in 192.0.2.0/24")
+	ErrNotHostPort = errors.New("not a host:port pair")
+	ErrNotMAC      = errors.New("not a MAC address")
+)
+
+// Network types with special handling.
+var (
+	ipType           = reflect.TypeOf(net.IP{})
+	ipNetType        = reflect.TypeOf(net.IPNet{})
+	ipNetPtrType     = reflect.TypeOf(&net.IPNet{})
+	hardwareAddrType = reflect.TypeOf(net.HardwareAddr{})
+	hostPortType     = reflect.TypeOf(HostPort{})
+)
+
+// HostPort is a network address consisting of a host and a port, as
+// in "example.com:443" or "[2001:db8::1]:53".  It may be used as the
+// type of a flag or argument.
+type HostPort struct {
+	Host string // The host name or IP address
+	Port uint16 // The port number
+}
+
+// String returns the address in "host:port" form.
+func (hp HostPort) String() string {
+	return net.JoinHostPort(hp.Host, strconv.Itoa(int(hp.Port)))
+}
+
+// UnmarshalText sets the address from text in "host:port" form.  The
+// host may be empty, but the port must be a number.
+func (hp *HostPort) UnmarshalText(text []byte) error {
+	host, portText, err := net.SplitHostPort(string(text))
+	if err != nil {
+		return ErrNotHostPort
+	}
+	port, err := strconv.ParseUint(portText, 10, 16)
+	if err != nil {
+		return ErrNotHostPort
+	}
+
+	hp.Host = host
+	hp.Port = uint16(port)
+	return nil
+}
+
+// typeHints maps types to placeholders describing their values, for
+// use in help and completion.
+var typeHints = map[reflect.Type]string{
+	ipType:           "IP",
+	ipNetType:        "CIDR",
+	ipNetPtrType:     "CIDR",
+	hardwareAddrType: "MAC",
+	hostPortType:     "HOST:PORT",
+}
+
+// typeHint returns a placeholder describing the values of a type, or
+// the empty string if the type has none.  For lists, the placeholder
+// describes an element.
+func typeHint(typ reflect.Type) string {
+	if hint, ok := typeHints[typ]; ok {
+		return hint
+	}
+	if isList(typ) {
+		return typeHints[typ.Elem()]
+	}
+
+	return ""
+}
+
+// setNetValue converts text and stores it in the specified value, if
+// the value has one of the network types requiring special handling:
+// net.IP, net.IPNet or *net.IPNet, or net.HardwareAddr.  Returns
+// false if the value has some other type.
+func setNetValue(v reflect.Value, text string) (bool, error) {
+	switch v.Type() {
+	case ipType:
+		ip := net.ParseIP(text)
+		if ip == nil {
+			return true, ErrNotIP
+		}
+		v.Set(reflect.ValueOf(ip))
+
+	case ipNetType, ipNetPtrType:
+		_, ipNet, err := net.ParseCIDR(text)
+		if err != nil {
+			return true, ErrNotCIDR
+		}
+		if v.Kind() == reflect.Ptr {
+			v.Set(reflect.ValueOf(ipNet))
+		} else {
+			v.Set(reflect.ValueOf(*ipNet))
+		}
+
+	case hardwareAddrType:
+		mac, err := net.ParseMAC(text)
+		if err != nil {
+			return true, ErrNotMAC
+		}
+		v.Set(reflect.ValueOf(mac))
+
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type networkOptions struct {
+	Addr    net.IP           `opt:"addr"`
+	Network net.IPNet        `opt:"network"`
+	Allow   []*net.IPNet     `opt:"allow"`
+	MAC     net.HardwareAddr `opt:"mac"`
+	Listen  HostPort         `opt:"listen"`
+	Peers   []HostPort       `arg:"PEERS" arity:"[0,)"`
+}
+
+func TestHostPortString(t *testing.T) {
+	obj := HostPort{Host: "2001:db8::1", Port: 53}
+
+	result := obj.String()
+
+	assert.Equal(t, "[2001:db8::1]:53", result)
+}
+
+func TestHostPortUnmarshalText(t *testing.T) {
+	obj := &HostPort{}
+
+	err := obj.UnmarshalText([]byte("example.com:443"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &HostPort{Host: "example.com", Port: 443}, obj)
+}
+
+func TestHostPortUnmarshalTextNoHost(t *testing.T) {
+	obj := &HostPort{}
+
+	err := obj.UnmarshalText([]byte(":8080"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &HostPort{Port: 8080}, obj)
+}
+
+func TestHostPortUnmarshalTextNoPort(t *testing.T) {
+	obj := &HostPort{}
+
+	err := obj.UnmarshalText([]byte("example.com"))
+
+	assert.Same(t, ErrNotHostPort, err)
+	assert.Equal(t, &HostPort{}, obj)
+}
+
+func TestHostPortUnmarshalTextBadPort(t *testing.T) {
+	obj := &HostPort{}
+
+	err := obj.UnmarshalText([]byte("example.com:https"))
+
+	assert.Same(t, ErrNotHostPort, err)
+	assert.Equal(t, &HostPort{}, obj)
+}
+
+func TestTypeHint(t *testing.T) {
+	assert.Equal(t, "IP", typeHint(reflect.TypeOf(net.IP{})))
+	assert.Equal(t, "CIDR", typeHint(reflect.TypeOf([]*net.IPNet{})))
+	assert.Equal(t, "MAC", typeHint(reflect.TypeOf(net.HardwareAddr{})))
+	assert.Equal(t, "HOST:PORT", typeHint(reflect.TypeOf([]HostPort{})))
+	assert.Equal(t, "", typeHint(reflect.TypeOf("")))
+	assert.Equal(t, "", typeHint(reflect.TypeOf([]string{})))
+}
+
+func TestSetValueIP(t *testing.T) {
+	var target net.IP
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "2001:db8::1", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, net.ParseIP("2001:db8::1"), target)
+}
+
+func TestSetValueIPError(t *testing.T) {
+	var target net.IP
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "192.0.2", nil)
+
+	assert.Same(t, ErrNotIP, err)
+	assert.Nil(t, target)
+}
+
+func TestSetValueIPNet(t *testing.T) {
+	var target net.IPNet
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "192.0.2.1/24", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "192.0.2.0/24", target.String())
+}
+
+func TestSetValueIPNetPtrSlice(t *testing.T) {
+	var target []*net.IPNet
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "2001:db8::/32", nil)
+
+	assert.NoError(t, err)
+	assert.Len(t, target, 1)
+	assert.Equal(t, "2001:db8::/32", target[0].String())
+}
+
+func TestSetValueIPNetError(t *testing.T) {
+	var target net.IPNet
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "192.0.2.1", nil)
+
+	assert.Same(t, ErrNotCIDR, err)
+}
+
+func TestSetValueMAC(t *testing.T) {
+	var target net.HardwareAddr
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "00:00:5e:00:53:01", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "00:00:5e:00:53:01", target.String())
+}
+
+func TestSetValueMACError(t *testing.T) {
+	var target net.HardwareAddr
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "00:00:5e", nil)
+
+	assert.Same(t, ErrNotMAC, err)
+	assert.Nil(t, target)
+}
+
+func TestOptionsParseNetwork(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &networkOptions{}})
+
+	positional, _, err := obj.parse([]string{"--addr", "192.0.2.1", "--network", "192.0.2.0/24", "--allow", "10.0.0.0/8", "--mac", "00:00:5e:00:53:01", "--listen", ":80", "a:1", "b:2"}, nil)
+	bindErr := obj.bind(positional)
+
+	assert.NoError(t, err)
+	assert.NoError(t, bindErr)
+	result := obj.Value.Interface().(*networkOptions)
+	assert.Equal(t, "192.0.2.1", result.Addr.String())
+	assert.Equal(t, "192.0.2.0/24", result.Network.String())
+	assert.Equal(t, "10.0.0.0/8", result.Allow[0].String())
+	assert.Equal(t, "00:00:5e:00:53:01", result.MAC.String())
+	assert.Equal(t, HostPort{Port: 80}, result.Listen)
+	assert.Equal(t, []HostPort{{Host: "a", Port: 1}, {Host: "b", Port: 2}}, result.Peers)
+}
+
+func TestOptionsBindNetworkError(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &networkOptions{}})
+
+	err := obj.bind([]string{"a:1", "b"})
+
+	assert.EqualError(t, err, `invalid value "b" for argument 2 (PEERS): not a host:port pair`)
+}
+
+func TestExportNetwork(t *testing.T) {
+	result := Export(&Command{Defaults: &networkOptions{}})
+
+	assert.Equal(t, "IP", result.Flags[0].Hint)
+	assert.Equal(t, "CIDR", result.Flags[2].Hint)
+	assert.Equal(t, "MAC", result.Flags[3].Hint)
+	assert.Equal(t, "HOST:PORT", result.Flags[4].Hint)
+	assert.Equal(t, "HOST:PORT", result.Args[0].Hint)
+}
@@ -0,0 +1,706 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/klmitch/nelson/internal/interval"
+)
+
+// Struct tags recognized in defaults structs.
+const (
+	OptTag        = "opt"        // Marks a flag: `opt:"name|alias,s"` for --name, --alias, and -s; `opt:",s"` for only -s
+	ArgTag        = "arg"        // Marks a positional argument: `arg:"NAME"`
+	HelpTag       = "help"       // Help text for a flag or argument
+	KindTag       = "kind"       // Selects a special kind of flag: `kind:"count"`
+	LayoutTag     = "layout"     // Layouts for time.Time values, separated by "|"
+	ChoicesTag    = "choices"    // Allowed values, separated by ",": `choices:"json,yaml"`
+	DeprecatedTag = "deprecated" // Marks a flag deprecated: `deprecated:"use --new-name"`
+	DefaultTag    = "default"    // Overrides the display of a flag's default value
+	ArityTag      = "arity"      // Number of values of an argument, in interval notation: `arity:"[0,)"`
+	OptionalTag   = "optional"   // Makes a flag's value optional, giving the value used without one: `optional:"auto"`
+	HiddenTag     = "hidden"     // Hides a flag from help, except with HelpAllFlag: `hidden:"true"`
+	WeightTag     = "weight"     // Position of a flag in help under SortWeight: `weight:"10"`
+)
+
+// Flag kinds, selected with KindTag.
+const (
+	KindCount = "count" // Flag counts its occurrences, as in -vvv; requires an int field
+)
+
+// ErrBadOptions indicates that a defaults struct is malformed, e.g.,
+// it declares the same flag twice.
+var ErrBadOptions = errors.New("invalid options declaration")
+
+// option describes a single flag, declared by a field of a defaults
+// struct tagged with OptTag.
+type option struct {
+	Name       string       // Long name of the flag, without dashes; empty for short-only flags
+	Aliases    []string     // Alternate long names of the flag
+	Short      string       // Short name of the flag, without dash
+	Help       string       // Help text for the flag
+	Deprecated string       // Deprecation message, if the flag is deprecated
+	Hidden     bool         // True if the flag is hidden from help
+	Weight     int          // Weight of the flag, for SortWeight
+	Default    *string      // Display of the default value, if overridden
+	Env        *string      // Environment variable bound to the flag, if overridden
+	Config     *string      // Configuration key bound to the flag, if overridden
+	Optional   *string      // Value used if the flag is given without one, if the value is optional
+	Kind       string       // Special kind of the flag, if any
+	Layouts    []string     // Layouts for time.Time values
+	Base       string       // Base of relative paths, for KindPath
+	Choices    []string     // Allowed values, if restricted
+	Checks     []check      // Validation checks for the value
+	Stdin      string       // Whether "-" reads the value from standard input
+	Complete   *Completion  // How the value is completed, if specified
+	Index      []int        // Index of the field in the struct
+	Field      string       // Name of the field in the struct; see FlagStorer
+	Type       reflect.Type // Type of the field
+}
+
+// TakesValue returns true if the flag takes a value.  Boolean flags,
+// including pointers to booleans, and counting flags do not.
+func (o *option) TakesValue() bool {
+	typ := o.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	return o.Kind != KindCount && typ.Kind() != reflect.Bool
+}
+
+// String returns the name of the flag, for use in messages.
+func (o *option) String() string {
+	if o.Name != "" {
+		return "--" + o.Name
+	}
+
+	return "-" + o.Short
+}
+
+// Matches returns true if the flag has the specified name, given as
+// "--name" or "-s".
+func (o *option) Matches(flag string) bool {
+	if strings.HasPrefix(flag, "--") {
+		for _, name := range append([]string{o.Name}, o.Aliases...) {
+			if name != "" && flag[2:] == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	return o.Short != "" && flag == "-"+o.Short
+}
+
+// Label returns the names of the flag, for use in help.  The label
+// always allows room for the short name, so that long names line up.
+func (o *option) Label() string {
+	if o.Name == "" {
+		return "-" + o.Short
+	}
+
+	names := []string{}
+	for _, name := range append([]string{o.Name}, o.Aliases...) {
+		names = append(names, "--"+name)
+	}
+	if o.Short != "" {
+		return "-" + o.Short + ", " + strings.Join(names, ", ")
+	}
+
+	return "    " + strings.Join(names, ", ")
+}
+
+// argument describes a positional argument, declared by a field of a
+// defaults struct tagged with ArgTag.
+type argument struct {
+	Name     string            // Name of the argument
+	Help     string            // Help text for the argument
+	Arity    interval.Interval // Number of values the argument accepts
+	Layouts  []string          // Layouts for time.Time values
+	Choices  []string          // Allowed values, if restricted
+	Checks   []check           // Validation checks for the values
+	Stdin    string            // Whether "-" reads the value from standard input
+	Complete *Completion       // How the values are completed, if specified
+	Index    []int             // Index of the field in the struct
+	Field    string            // Name of the field in the struct; see FlagStorer
+	Type     reflect.Type      // Type of the field
+}
+
+// Variadic returns true if the argument accepts a variable number of
+// values.
+func (a *argument) Variadic() bool {
+	return a.Arity.End != a.Arity.Start+1
+}
+
+// optionSet describes the flags and positional arguments declared by
+// a defaults struct.
+type optionSet struct {
+	Type    reflect.Type       // The struct type
+	Options []*option          // Flags, in declaration order
+	Args    []*argument        // Positional arguments, in order
+	long    map[string]*option // Flags by long name
+	short   map[string]*option // Flags by short name
+}
+
+// newOptionSet constructs an optionSet describing the specified
+// struct type.  Exported fields tagged with OptTag declare flags;
+// exported fields tagged with ArgTag declare positional arguments;
+// and untagged anonymous (embedded) struct fields are examined for
+// further declarations.  Since an optionSet is not modified once
+// constructed, it is constructed only once for each type; failures
+// are not cached.
+func newOptionSet(typ reflect.Type) (*optionSet, error) {
+	if set, ok := optionSets.Load(typ); ok {
+		return set.(*optionSet), nil
+	}
+
+	set, err := buildOptionSet(typ)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := optionSets.LoadOrStore(typ, set)
+
+	return actual.(*optionSet), nil
+}
+
+// optionSets caches the optionSets constructed by newOptionSet, by
+// struct type.
+var optionSets sync.Map
+
+// buildOptionSet constructs the optionSet describing the specified
+// struct type, for newOptionSet.
+func buildOptionSet(typ reflect.Type) (*optionSet, error) {
+	set := &optionSet{
+		Type:  typ,
+		long:  map[string]*option{},
+		short: map[string]*option{},
+	}
+
+	if err := set.addFields(typ, nil); err != nil {
+		return nil, err
+	}
+	for _, opt := range set.Options {
+		opt.Field = fieldPath(typ, opt.Index)
+	}
+	for _, arg := range set.Args {
+		arg.Field = fieldPath(typ, arg.Index)
+	}
+
+	return set, nil
+}
+
+// fieldPath is a helper that returns the name of the field of a
+// struct type with the specified index, as a dotted path beginning
+// with the name of the type and passing through any embedded
+// structs, e.g., "Options.Common.Verbose".  The path of a field of
+// an unnamed type does not include the type name.
+func fieldPath(typ reflect.Type, index []int) string {
+	var names []string
+	if typ.Name() != "" {
+		names = append(names, typ.Name())
+	}
+	for _, idx := range index {
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		field := typ.Field(idx)
+		names = append(names, field.Name)
+		typ = field.Type
+	}
+
+	return strings.Join(names, ".")
+}
+
+// hasNumericArg returns true if any positional argument is numeric,
+// or is a slice of or pointer to numbers.
+func (s *optionSet) hasNumericArg() bool {
+	for _, arg := range s.Args {
+		typ := arg.Type
+		for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+			typ = typ.Elem()
+		}
+		switch typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return true
+		}
+	}
+
+	return false
+}
+
+// addFields adds the fields of a struct type to the optionSet.  The
+// prefix is the index of the struct within the top-level struct.
+func (s *optionSet) addFields(typ reflect.Type, prefix []int) error {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		index := append(append([]int{}, prefix...), i)
+
+		// Process the field
+		if tag, ok := field.Tag.Lookup(OptTag); ok {
+			if err := s.addOption(field, tag, index); err != nil {
+				return err
+			}
+		} else if tag, ok := field.Tag.Lookup(ArgTag); ok {
+			if err := s.addArg(field, tag, index); err != nil {
+				return err
+			}
+		} else if field.Anonymous {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() != reflect.Struct {
+				continue
+			}
+			if err := s.addFields(embedded, index); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// addArg adds a positional argument to the optionSet.  By default,
+// an argument accepts exactly one value; an argument declaring a
+// different arity with ArityTag must be a slice, unless it accepts at
+// most one value, and only the last argument may accept a variable
+// number of values.
+func (s *optionSet) addArg(field reflect.StructField, tag string, index []int) error {
+	arg := &argument{
+		Name:    tag,
+		Help:    field.Tag.Get(HelpTag),
+		Arity:   interval.Interval{Start: 1, End: 2},
+		Layouts: layouts(field),
+		Choices: choices(field),
+		Index:   index,
+		Type:    field.Type,
+	}
+
+	// Check the arity
+	if text, ok := field.Tag.Lookup(ArityTag); ok {
+		arity, err := interval.Parse(text)
+		if err != nil {
+			return fmt.Errorf("%w: argument %s: %s", ErrBadOptions, tag, err)
+		}
+		if arity.Start < 0 {
+			arity.Start = 0
+		}
+		if arity.End > 2 && !isList(field.Type) {
+			return fmt.Errorf("%w: argument %s accepts multiple values but is not a slice", ErrBadOptions, tag)
+		}
+		arg.Arity = arity
+	}
+	if len(s.Args) > 0 && s.Args[len(s.Args)-1].Variadic() {
+		return fmt.Errorf("%w: argument %s follows variadic argument %s", ErrBadOptions, tag, s.Args[len(s.Args)-1].Name)
+	}
+
+	// Set up the validation checks
+	chks, err := checks(field)
+	if err != nil {
+		return fmt.Errorf("%w: argument %s: %s", ErrBadOptions, tag, err)
+	}
+	arg.Checks = chks
+	if arg.Stdin, err = stdinTag(field.Tag.Get(StdinTag)); err != nil {
+		return fmt.Errorf("%w: argument %s: %s", ErrBadOptions, tag, err)
+	}
+	if arg.Complete, err = completeTag(field.Tag.Get(CompleteTag)); err != nil {
+		return fmt.Errorf("%w: argument %s: %s", ErrBadOptions, tag, err)
+	}
+
+	s.Args = append(s.Args, arg)
+	return nil
+}
+
+// addOption adds a flag to the optionSet.
+func (s *optionSet) addOption(field reflect.StructField, tag string, index []int) error {
+	opt := &option{
+		Help:       field.Tag.Get(HelpTag),
+		Default:    lookupTag(field, DefaultTag),
+		Env:        lookupTag(field, EnvTag),
+		Config:     lookupTag(field, ConfigTag),
+		Optional:   lookupTag(field, OptionalTag),
+		Deprecated: field.Tag.Get(DeprecatedTag),
+		Kind:       field.Tag.Get(KindTag),
+		Layouts:    layouts(field),
+		Base:       field.Tag.Get(BaseTag),
+		Choices:    choices(field),
+		Index:      index,
+		Type:       field.Type,
+	}
+
+	// Parse the tag
+	parts := strings.Split(tag, ",")
+	names := strings.Split(parts[0], "|")
+	opt.Name = names[0]
+	if len(names) > 1 {
+		opt.Aliases = names[1:]
+	}
+	if len(parts) > 1 {
+		opt.Short = parts[1]
+	}
+	if len(parts) > 2 || (opt.Short != "" && utf8.RuneCountInString(opt.Short) != 1) {
+		return fmt.Errorf("%w: field %s: bad %s tag %q", ErrBadOptions, field.Name, OptTag, tag)
+	}
+	if opt.Name == "" && opt.Short != "" && len(names) == 1 {
+		names = nil // Short-only flag
+	}
+	for _, name := range names {
+		if name == "" {
+			return fmt.Errorf("%w: field %s: bad %s tag %q", ErrBadOptions, field.Name, OptTag, tag)
+		}
+	}
+
+	// Set up the validation checks
+	chks, err := checks(field)
+	if err != nil {
+		return fmt.Errorf("%w: field %s: %s", ErrBadOptions, field.Name, err)
+	}
+	opt.Checks = chks
+	if opt.Stdin, err = stdinTag(field.Tag.Get(StdinTag)); err != nil {
+		return fmt.Errorf("%w: field %s: %s", ErrBadOptions, field.Name, err)
+	}
+	if opt.Complete, err = completeTag(field.Tag.Get(CompleteTag)); err != nil {
+		return fmt.Errorf("%w: field %s: %s", ErrBadOptions, field.Name, err)
+	}
+
+	if text := field.Tag.Get(HiddenTag); text != "" {
+		if opt.Hidden, err = strconv.ParseBool(text); err != nil {
+			return fmt.Errorf("%w: field %s: bad %s tag %q", ErrBadOptions, field.Name, HiddenTag, text)
+		}
+	}
+
+	if text := field.Tag.Get(WeightTag); text != "" {
+		if opt.Weight, err = strconv.Atoi(text); err != nil {
+			return fmt.Errorf("%w: field %s: bad %s tag %q", ErrBadOptions, field.Name, WeightTag, text)
+		}
+	}
+
+	if opt.Optional != nil && !opt.TakesValue() {
+		return fmt.Errorf("%w: field %s: only flags that take values may have optional values", ErrBadOptions, field.Name)
+	}
+
+	// Check the kind
+	switch opt.Kind {
+	case "":
+	case KindCount:
+		switch opt.Type.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		default:
+			return fmt.Errorf("%w: field %s: %s flag must be an int", ErrBadOptions, field.Name, opt.Kind)
+		}
+	case KindSecret:
+		if opt.Type.Kind() != reflect.String {
+			return fmt.Errorf("%w: field %s: %s flag must be a string", ErrBadOptions, field.Name, opt.Kind)
+		}
+	case KindInput, KindOutput:
+		if !checkFileKind(opt.Kind, opt.Type) {
+			return fmt.Errorf("%w: field %s: %s flag cannot be a %s", ErrBadOptions, field.Name, opt.Kind, opt.Type)
+		}
+	case KindPath:
+		if !checkPathKind(opt.Type) {
+			return fmt.Errorf("%w: field %s: %s flag cannot be a %s", ErrBadOptions, field.Name, opt.Kind, opt.Type)
+		}
+		if opt.Complete == nil {
+			opt.Complete = &Completion{Kind: CompleteFile}
+		}
+	default:
+		return fmt.Errorf("%w: field %s: unknown %s %q", ErrBadOptions, field.Name, KindTag, opt.Kind)
+	}
+	if err := checkBase(opt); err != nil {
+		return fmt.Errorf("%w: field %s: %s", ErrBadOptions, field.Name, err)
+	}
+
+	// Check for duplicates
+	for i, name := range names {
+		_, dup := s.long[name]
+		for _, other := range names[:i] {
+			dup = dup || name == other
+		}
+		if dup {
+			return fmt.Errorf("%w: duplicate flag --%s", ErrBadOptions, name)
+		}
+	}
+	if _, ok := s.short[opt.Short]; ok && opt.Short != "" {
+		return fmt.Errorf("%w: duplicate flag -%s", ErrBadOptions, opt.Short)
+	}
+
+	// Add the option
+	s.Options = append(s.Options, opt)
+	for _, name := range names {
+		s.long[name] = opt
+	}
+	if opt.Short != "" {
+		s.short[opt.Short] = opt
+	}
+	if opt.Kind == KindSecret {
+		return s.addSecretFile(opt)
+	}
+
+	return nil
+}
+
+// lookupTag is a helper that looks up a tag of a field, returning a
+// pointer to its value, or nil if the tag is not present.
+func lookupTag(field reflect.StructField, key string) *string {
+	if tag, ok := field.Tag.Lookup(key); ok {
+		return &tag
+	}
+
+	return nil
+}
+
+// layouts is a helper that retrieves the time layouts specified by
+// the LayoutTag of a field, if any.
+func layouts(field reflect.StructField) []string {
+	if tag := field.Tag.Get(LayoutTag); tag != "" {
+		return strings.Split(tag, "|")
+	}
+
+	return nil
+}
+
+// choices is a helper that retrieves the allowed values for a field.
+// These are specified by the ChoicesTag of the field or, failing
+// that, by the field's type--or its element type, for slices--if it
+// implements IChoices.  Pointer types are not examined, to avoid
+// calling Choices on a nil pointer.
+func choices(field reflect.StructField) []string {
+	if tag := field.Tag.Get(ChoicesTag); tag != "" {
+		return strings.Split(tag, ",")
+	}
+
+	typ := field.Type
+	if typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Ptr {
+		return nil
+	}
+	if tmp, ok := reflect.Zero(typ).Interface().(IChoices); ok {
+		return tmp.Choices()
+	}
+
+	return nil
+}
+
+// field retrieves the field with the specified index from a struct
+// value, allocating any nil embedded struct pointers along the way.
+func field(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+
+	return v
+}
+
+// options holds the populated options for a single command.
+type options struct {
+	Set   *optionSet    // Description of the options
+	Value reflect.Value // Pointer to the populated struct
+
+	unknown     UnknownFlags       // Policy for handling unknown flags
+	strict      bool               // If true, the first positional argument ends flag parsing
+	windows     bool               // If true, Windows-style flags are recognized
+	insensitive bool               // If true, long flags are matched after normalization
+	deprecated  []string           // Warnings for deprecated flags that were used
+	sources     map[string]Source  // Sources of flag values, if not defaults
+	collected   []string           // Unknown flags that were collected
+	paths       map[*option]string // Paths given to file-kind flags
+	stdin       *stdinSource       // Standard input, for values given as "-"
+	locale      *LocaleFormat      // Format of numbers and dates given on the command line, if localized
+	configDir   string             // Base of relative paths for KindPath flags with BaseConfig
+}
+
+// newOptions constructs the options for a command.  The command's
+// defaults, which must be a struct or a pointer to a struct, are
+// copied into a newly allocated struct; unless the command requests
+// a shallow copy, the values the defaults refer to are copied as
+// well, so that invocations do not share them.  If the command has
+// no defaults, nil is returned.
+func newOptions(cmd ICommand) (*options, error) {
+	return optionsFor(cmd.GetDefaults(), !IsShallowDefaults(cmd))
+}
+
+// flagDescriber is implemented by commands that accept flags they do
+// not declare in their defaults, such as plugins, so that the flags
+// may be described by help, specifications, and completion.
+type flagDescriber interface {
+	// describeFlags constructs the options describing the flags.
+	describeFlags() (*options, error)
+}
+
+// describeOptions constructs the options describing the flags of a
+// command for help, specifications, and completion; see
+// flagDescriber.
+func describeOptions(cmd ICommand) (*options, error) {
+	if describer, ok := cmd.(flagDescriber); ok {
+		return describer.describeFlags()
+	}
+
+	return newOptions(cmd)
+}
+
+// optionsFor constructs options from a defaults struct, or a pointer
+// to one, which is copied into a newly allocated struct.  If deep is
+// true, the values the defaults refer to are copied as well; see
+// deepCopy.  If the defaults are nil, nil is returned.
+func optionsFor(obj interface{}, deep bool) (*options, error) {
+	defaults := reflect.ValueOf(obj)
+	if !defaults.IsValid() {
+		return nil, nil
+	}
+	if defaults.Kind() == reflect.Ptr {
+		if defaults.IsNil() {
+			return nil, nil
+		}
+		defaults = defaults.Elem()
+	}
+	if defaults.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: defaults must be a struct, not %s", ErrBadOptions, defaults.Type())
+	}
+
+	// Describe the options
+	set, err := newOptionSet(defaults.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the defaults
+	value := reflect.New(defaults.Type())
+	if deep {
+		deepCopy(value.Elem(), defaults, map[uintptr]reflect.Value{})
+	} else {
+		value.Elem().Set(defaults)
+	}
+
+	return &options{
+		Set:   set,
+		Value: value,
+	}, nil
+}
+
+// Field retrieves the field for the flag or argument with the
+// specified index.
+func (o *options) Field(index []int) reflect.Value {
+	return field(o.Value.Elem(), index)
+}
+
+// store converts text and stores it in the field for a flag.  For
+// file-kind flags, the text is a path, which is recorded to be opened
+// later; for the flags that read secrets from files, the text is the
+// path of the file containing the secret.  The paths given to
+// KindPath flags are expanded; see ExpandPath.
+func (o *options) store(opt *option, text string) error {
+	if opt.Kind == KindPath {
+		path, err := o.expandPath(opt, text)
+		if err != nil {
+			return err
+		}
+		text = path
+	} else if opt.Kind == kindSecretFile {
+		secret, err := readSecretFile(text)
+		if err != nil {
+			return err
+		}
+		text = secret
+	} else if isFileKind(opt.Kind) {
+		if o.paths == nil {
+			o.paths = map[*option]string{}
+		}
+		o.paths[opt] = text
+		return nil
+	}
+
+	return o.storeField(opt.Field, opt.Index, text, opt.Layouts, opt.Choices)
+}
+
+// storeField converts text and stores it in the field of a flag or
+// argument, identified both by its name and by its index.  The
+// text is first converted from the locale's format for numbers and
+// dates, if any; see App.WithLocaleValues.  The field is then set
+// with the defaults' FlagStorer, if implemented, and otherwise
+// through reflection.
+func (o *options) storeField(name string, index []int, text string, layouts, choices []string) error {
+	field := o.Field(index)
+	if o.locale != nil {
+		text, layouts = o.locale.localize(field.Type(), text, layouts)
+	}
+
+	if storer, ok := o.Value.Interface().(FlagStorer); ok {
+		if err := checkChoice(text, choices); err != nil {
+			return err
+		}
+		if handled, err := storer.StoreFlag(name, text); handled {
+			return err
+		}
+	}
+
+	return storeValue(field, text, layouts, choices)
+}
+
+// DefaultText returns the text describing the default value of a
+// flag, and a boolean indicating whether the flag has a default.  The
+// default is the value of the flag's field, which should not yet
+// have been populated from the command line.  If the flag's
+// DefaultTag is present, its value is used instead.  Otherwise, a
+// zero value is not considered a default, with the exception of
+// pointer fields: a nil pointer indicates no default, while a
+// non-nil pointer always indicates a default, even if it points to a
+// zero value.  String defaults are quoted, so that an empty default
+// is distinguishable.  Secret flags never have a default.
+func (o *options) DefaultText(opt *option) (string, bool) {
+	if opt.IsSecret() {
+		return "", false
+	}
+	if opt.Default != nil {
+		return *opt.Default, true
+	}
+
+	v := o.Field(opt.Index)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	} else if v.IsZero() {
+		return "", false
+	}
+
+	if v.Kind() == reflect.String {
+		return strconv.Quote(v.String()), true
+	}
+
+	return fmt.Sprint(v.Interface()), true
+}
@@ -0,0 +1,811 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/klmitch/nelson/internal/interval"
+)
+
+type testOptions struct {
+	Verbose bool     `opt:"verbose,v" help:"Verbose output"`
+	Name    string   `opt:"name"`
+	Count   int      `opt:"count,c"`
+	Tags    []string `opt:"tag"`
+	File    string   `arg:"FILE" help:"Input file"`
+	Other   string
+	private string //nolint:unused,structcheck
+}
+
+type EmbeddedOptions struct {
+	Debug bool `opt:"debug"`
+}
+
+type embedOptions struct {
+	EmbeddedOptions
+	Level int `opt:"level"`
+}
+
+type embedPtrOptions struct {
+	*EmbeddedOptions
+	Level int `opt:"level"`
+}
+
+type embedNonStruct struct {
+	Args
+	Level int `opt:"level"`
+}
+
+type testFormat string
+
+func (f testFormat) Choices() []string {
+	return []string{"json", "yaml"}
+}
+
+type choicesOptions struct {
+	Format  string       `opt:"format" choices:"json,yaml,table"`
+	Output  testFormat   `opt:"output"`
+	Outputs []testFormat `opt:"outputs"`
+	Ptr     *testFormat  `opt:"ptr"`
+	Kind    testFormat   `arg:"KIND" choices:"a,b"`
+}
+
+func TestOptionTakesValueTrue(t *testing.T) {
+	obj := &option{Type: reflect.TypeOf("")}
+
+	result := obj.TakesValue()
+
+	assert.True(t, result)
+}
+
+func TestOptionTakesValueBool(t *testing.T) {
+	obj := &option{Type: reflect.TypeOf(true)}
+
+	result := obj.TakesValue()
+
+	assert.False(t, result)
+}
+
+func TestOptionTakesValueBoolPtr(t *testing.T) {
+	obj := &option{Type: reflect.TypeOf((*bool)(nil))}
+
+	result := obj.TakesValue()
+
+	assert.False(t, result)
+}
+
+func TestOptionTakesValueCount(t *testing.T) {
+	obj := &option{Kind: KindCount, Type: reflect.TypeOf(0)}
+
+	result := obj.TakesValue()
+
+	assert.False(t, result)
+}
+
+func TestOptionStringLong(t *testing.T) {
+	obj := &option{Name: "verbose", Short: "v"}
+
+	result := obj.String()
+
+	assert.Equal(t, "--verbose", result)
+}
+
+func TestOptionStringShort(t *testing.T) {
+	obj := &option{Short: "v"}
+
+	result := obj.String()
+
+	assert.Equal(t, "-v", result)
+}
+
+func TestOptionMatchesLong(t *testing.T) {
+	obj := &option{Name: "color", Aliases: []string{"colour"}, Short: "c"}
+
+	assert.True(t, obj.Matches("--color"))
+	assert.True(t, obj.Matches("--colour"))
+	assert.False(t, obj.Matches("--c"))
+	assert.False(t, obj.Matches("color"))
+}
+
+func TestOptionMatchesShort(t *testing.T) {
+	obj := &option{Name: "color", Short: "c"}
+
+	assert.True(t, obj.Matches("-c"))
+	assert.False(t, obj.Matches("-color"))
+}
+
+func TestOptionMatchesShortOnly(t *testing.T) {
+	obj := &option{Short: "c"}
+
+	assert.True(t, obj.Matches("-c"))
+	assert.False(t, obj.Matches("--"))
+	assert.False(t, obj.Matches("-"))
+}
+
+func TestOptionLabelShort(t *testing.T) {
+	obj := &option{Name: "color", Aliases: []string{"colour"}, Short: "c"}
+
+	result := obj.Label()
+
+	assert.Equal(t, "-c, --color, --colour", result)
+}
+
+func TestOptionLabelLong(t *testing.T) {
+	obj := &option{Name: "color"}
+
+	result := obj.Label()
+
+	assert.Equal(t, "    --color", result)
+}
+
+func TestOptionLabelShortOnly(t *testing.T) {
+	obj := &option{Short: "v"}
+
+	result := obj.Label()
+
+	assert.Equal(t, "-v", result)
+}
+
+func TestNewOptionSet(t *testing.T) {
+	typ := reflect.TypeOf(testOptions{})
+
+	result, err := newOptionSet(typ)
+
+	assert.NoError(t, err)
+	assert.Equal(t, typ, result.Type)
+	assert.Equal(t, []*option{
+		{Name: "verbose", Short: "v", Help: "Verbose output", Index: []int{0}, Field: "testOptions.Verbose", Type: reflect.TypeOf(true)},
+		{Name: "name", Index: []int{1}, Field: "testOptions.Name", Type: reflect.TypeOf("")},
+		{Name: "count", Short: "c", Index: []int{2}, Field: "testOptions.Count", Type: reflect.TypeOf(0)},
+		{Name: "tag", Index: []int{3}, Field: "testOptions.Tags", Type: reflect.TypeOf([]string{})},
+	}, result.Options)
+	assert.Equal(t, []*argument{
+		{Name: "FILE", Help: "Input file", Arity: interval.Interval{Start: 1, End: 2}, Index: []int{4}, Field: "testOptions.File", Type: reflect.TypeOf("")},
+	}, result.Args)
+	assert.Same(t, result.Options[0], result.long["verbose"])
+	assert.Same(t, result.Options[0], result.short["v"])
+	assert.Same(t, result.Options[1], result.long["name"])
+	assert.Len(t, result.long, 4)
+	assert.Len(t, result.short, 2)
+}
+
+func TestNewOptionSetEmbedded(t *testing.T) {
+	result, err := newOptionSet(reflect.TypeOf(embedOptions{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*option{
+		{Name: "debug", Index: []int{0, 0}, Field: "embedOptions.EmbeddedOptions.Debug", Type: reflect.TypeOf(true)},
+		{Name: "level", Index: []int{1}, Field: "embedOptions.Level", Type: reflect.TypeOf(0)},
+	}, result.Options)
+}
+
+func TestNewOptionSetEmbeddedPtr(t *testing.T) {
+	result, err := newOptionSet(reflect.TypeOf(embedPtrOptions{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*option{
+		{Name: "debug", Index: []int{0, 0}, Field: "embedPtrOptions.EmbeddedOptions.Debug", Type: reflect.TypeOf(true)},
+		{Name: "level", Index: []int{1}, Field: "embedPtrOptions.Level", Type: reflect.TypeOf(0)},
+	}, result.Options)
+}
+
+func TestNewOptionSetEmbeddedNonStruct(t *testing.T) {
+	result, err := newOptionSet(reflect.TypeOf(embedNonStruct{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*option{
+		{Name: "level", Index: []int{1}, Field: "embedNonStruct.Level", Type: reflect.TypeOf(0)},
+	}, result.Options)
+}
+
+func TestNewOptionSetBadTagEmpty(t *testing.T) {
+	type opts struct {
+		Flag bool `opt:""`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestOptionSetHasNumericArg(t *testing.T) {
+	type intOpts struct {
+		Count *int `arg:"COUNT"`
+	}
+	type floatOpts struct {
+		Values []float32 `arg:"VALUE" arity:"[0,)"`
+	}
+	type stringOpts struct {
+		Name  string `arg:"NAME"`
+		Count int    `opt:"count"`
+	}
+	intSet, _ := newOptionSet(reflect.TypeOf(intOpts{}))
+	floatSet, _ := newOptionSet(reflect.TypeOf(floatOpts{}))
+	stringSet, _ := newOptionSet(reflect.TypeOf(stringOpts{}))
+
+	assert.True(t, intSet.hasNumericArg())
+	assert.True(t, floatSet.hasNumericArg())
+	assert.False(t, stringSet.hasNumericArg())
+}
+
+func TestNewOptionSetShortOnly(t *testing.T) {
+	type opts struct {
+		Verbose bool `opt:",v"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", result.Options[0].Name)
+	assert.Equal(t, "v", result.Options[0].Short)
+	assert.Equal(t, map[string]*option{}, result.long)
+	assert.Same(t, result.Options[0], result.short["v"])
+}
+
+func TestNewOptionSetLongOnly(t *testing.T) {
+	type opts struct {
+		Verbose bool `opt:"verbose,"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "verbose", result.Options[0].Name)
+	assert.Equal(t, "", result.Options[0].Short)
+	assert.Same(t, result.Options[0], result.long["verbose"])
+	assert.Equal(t, map[string]*option{}, result.short)
+}
+
+func TestNewOptionSetBadTagNoNames(t *testing.T) {
+	type opts struct {
+		Flag bool `opt:","`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetBadTagShortOnlyAlias(t *testing.T) {
+	type opts struct {
+		Flag bool `opt:"|flag,f"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetDuplicateShortOnly(t *testing.T) {
+	type opts struct {
+		Flag1 bool `opt:",f"`
+		Flag2 bool `opt:",f"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetOptional(t *testing.T) {
+	type opts struct {
+		Color string `opt:"color" optional:"auto"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "auto", *result.Options[0].Optional)
+}
+
+func TestNewOptionSetHidden(t *testing.T) {
+	type opts struct {
+		Debug bool `opt:"debug" hidden:"true"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.NoError(t, err)
+	assert.True(t, result.Options[0].Hidden)
+}
+
+func TestNewOptionSetHiddenBad(t *testing.T) {
+	type opts struct {
+		Debug bool `opt:"debug" hidden:"sometimes"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetWeight(t *testing.T) {
+	type opts struct {
+		Debug bool `opt:"debug" weight:"-5"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, -5, result.Options[0].Weight)
+}
+
+func TestNewOptionSetWeightBad(t *testing.T) {
+	type opts struct {
+		Debug bool `opt:"debug" weight:"heavy"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetOptionalBool(t *testing.T) {
+	type opts struct {
+		Verbose bool `opt:"verbose" optional:"true"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetBadTagParts(t *testing.T) {
+	type opts struct {
+		Flag bool `opt:"flag,f,x"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetBadTagShort(t *testing.T) {
+	type opts struct {
+		Flag bool `opt:"flag,fl"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetCount(t *testing.T) {
+	type opts struct {
+		Verbose int8 `opt:"verbose,v" kind:"count"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*option{
+		{Name: "verbose", Short: "v", Kind: KindCount, Index: []int{0}, Field: "opts.Verbose", Type: reflect.TypeOf(int8(0))},
+	}, result.Options)
+}
+
+func TestNewOptionSetArity(t *testing.T) {
+	type opts struct {
+		Source string   `arg:"SOURCE"`
+		Files  []string `arg:"FILES" arity:"[1,)"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, interval.Interval{Start: 1, End: 2}, result.Args[0].Arity)
+	assert.Equal(t, interval.Interval{Start: 1, End: math.MaxInt64}, result.Args[1].Arity)
+}
+
+func TestNewOptionSetArityOptional(t *testing.T) {
+	type opts struct {
+		Source string `arg:"SOURCE" arity:"[0,1]"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, interval.Interval{Start: 0, End: 2}, result.Args[0].Arity)
+}
+
+func TestNewOptionSetArityUnboundedStart(t *testing.T) {
+	type opts struct {
+		Files []string `arg:"FILES" arity:"(,3]"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, interval.Interval{Start: 0, End: 4}, result.Args[0].Arity)
+}
+
+func TestNewOptionSetArityBad(t *testing.T) {
+	type opts struct {
+		Files []string `arg:"FILES" arity:"[1,"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetArityNotSlice(t *testing.T) {
+	type opts struct {
+		Files string `arg:"FILES" arity:"[0,)"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.EqualError(t, err, "invalid options declaration: argument FILES accepts multiple values but is not a slice")
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetArityNotLast(t *testing.T) {
+	type opts struct {
+		Files []string `arg:"FILES" arity:"[0,)"`
+		Dest  string   `arg:"DEST"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.EqualError(t, err, "invalid options declaration: argument DEST follows variadic argument FILES")
+	assert.Nil(t, result)
+}
+
+func TestArgumentVariadic(t *testing.T) {
+	assert.False(t, (&argument{Arity: interval.Interval{Start: 1, End: 2}}).Variadic())
+	assert.True(t, (&argument{Arity: interval.Interval{Start: 0, End: 2}}).Variadic())
+	assert.True(t, (&argument{Arity: interval.Interval{Start: 1, End: math.MaxInt64}}).Variadic())
+}
+
+func TestNewOptionSetCountNotInt(t *testing.T) {
+	type opts struct {
+		Verbose string `opt:"verbose,v" kind:"count"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetUnknownKind(t *testing.T) {
+	type opts struct {
+		Verbose int `opt:"verbose,v" kind:"bogus"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetLayouts(t *testing.T) {
+	type opts struct {
+		Since time.Time `opt:"since" layout:"2006|2006-01"`
+		Until time.Time `arg:"UNTIL" layout:"2006-01-02"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2006", "2006-01"}, result.Options[0].Layouts)
+	assert.Equal(t, []string{"2006-01-02"}, result.Args[0].Layouts)
+}
+
+func TestNewOptionSetChoices(t *testing.T) {
+	result, err := newOptionSet(reflect.TypeOf(choicesOptions{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"json", "yaml", "table"}, result.Options[0].Choices)
+	assert.Equal(t, []string{"json", "yaml"}, result.Options[1].Choices)
+	assert.Equal(t, []string{"json", "yaml"}, result.Options[2].Choices)
+	assert.Nil(t, result.Options[3].Choices)
+	assert.Equal(t, []string{"a", "b"}, result.Args[0].Choices)
+}
+
+func TestNewOptionSetAliases(t *testing.T) {
+	type opts struct {
+		Color string `opt:"color|colour,c"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*option{
+		{Name: "color", Aliases: []string{"colour"}, Short: "c", Index: []int{0}, Field: "opts.Color", Type: reflect.TypeOf("")},
+	}, result.Options)
+	assert.Same(t, result.Options[0], result.long["color"])
+	assert.Same(t, result.Options[0], result.long["colour"])
+}
+
+func TestNewOptionSetBadAlias(t *testing.T) {
+	type opts struct {
+		Color string `opt:"color|,c"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetDuplicateAlias(t *testing.T) {
+	type opts struct {
+		Color string `opt:"color|color"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetDuplicateLong(t *testing.T) {
+	type opts struct {
+		Flag1 bool `opt:"flag"`
+		Flag2 bool `opt:"flag"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetDuplicateShort(t *testing.T) {
+	type opts struct {
+		Flag1 bool `opt:"flag1,f"`
+		Flag2 bool `opt:"flag2,f"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetDuplicateEmbedded(t *testing.T) {
+	type opts struct {
+		EmbeddedOptions
+		Debug bool `opt:"debug"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestField(t *testing.T) {
+	v := reflect.ValueOf(&testOptions{}).Elem()
+
+	result := field(v, []int{1})
+
+	result.SetString("value")
+	assert.Equal(t, "value", v.Interface().(testOptions).Name)
+}
+
+func TestFieldEmbeddedPtr(t *testing.T) {
+	obj := &embedPtrOptions{}
+	v := reflect.ValueOf(obj).Elem()
+
+	result := field(v, []int{0, 0})
+
+	result.SetBool(true)
+	assert.Equal(t, &EmbeddedOptions{Debug: true}, obj.EmbeddedOptions)
+}
+
+func TestFieldEmbeddedPtrExisting(t *testing.T) {
+	embedded := &EmbeddedOptions{}
+	obj := &embedPtrOptions{EmbeddedOptions: embedded}
+	v := reflect.ValueOf(obj).Elem()
+
+	result := field(v, []int{0, 0})
+
+	result.SetBool(true)
+	assert.Same(t, embedded, obj.EmbeddedOptions)
+	assert.True(t, embedded.Debug)
+}
+
+func TestNewOptionsDeepCopy(t *testing.T) {
+	defaults := &testOptions{Tags: []string{"t1", "t2"}}
+	obj, _ := newOptions(&Command{Defaults: defaults})
+
+	obj.Value.Interface().(*testOptions).Tags[0] = "changed"
+
+	assert.Equal(t, []string{"t1", "t2"}, defaults.Tags)
+}
+
+func TestNewOptionsShallowCopy(t *testing.T) {
+	defaults := &testOptions{Tags: []string{"t1", "t2"}}
+	obj, _ := newOptions(&Command{Defaults: defaults, ShallowDefaults: true})
+
+	obj.Value.Interface().(*testOptions).Tags[0] = "changed"
+
+	assert.Equal(t, []string{"changed", "t2"}, defaults.Tags)
+}
+
+func TestNewOptionsNil(t *testing.T) {
+	cmd := &Command{}
+
+	result, err := newOptions(cmd)
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionsNilPtr(t *testing.T) {
+	cmd := &Command{Defaults: (*testOptions)(nil)}
+
+	result, err := newOptions(cmd)
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionsPtr(t *testing.T) {
+	defaults := &testOptions{Name: "default"}
+	cmd := &Command{Defaults: defaults}
+
+	result, err := newOptions(cmd)
+
+	assert.NoError(t, err)
+	assert.Equal(t, reflect.TypeOf(testOptions{}), result.Set.Type)
+	assert.Equal(t, defaults, result.Value.Interface())
+	assert.NotSame(t, defaults, result.Value.Interface())
+}
+
+func TestNewOptionsStruct(t *testing.T) {
+	cmd := &Command{Defaults: testOptions{Name: "default"}}
+
+	result, err := newOptions(cmd)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &testOptions{Name: "default"}, result.Value.Interface())
+}
+
+func TestNewOptionsNotStruct(t *testing.T) {
+	cmd := &Command{Defaults: "defaults"}
+
+	result, err := newOptions(cmd)
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionsBadOptions(t *testing.T) {
+	type opts struct {
+		Flag bool `opt:""`
+	}
+	cmd := &Command{Defaults: &opts{}}
+
+	result, err := newOptions(cmd)
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestOptionsField(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	result := obj.Field([]int{1})
+
+	result.SetString("value")
+	assert.Equal(t, "value", obj.Value.Interface().(*testOptions).Name)
+}
+
+func TestLookupTagPresent(t *testing.T) {
+	field := reflect.StructField{Tag: `default:""`}
+
+	result := lookupTag(field, DefaultTag)
+
+	assert.Equal(t, "", *result)
+}
+
+func TestLookupTagAbsent(t *testing.T) {
+	field := reflect.StructField{Tag: `opt:"flag"`}
+
+	result := lookupTag(field, DefaultTag)
+
+	assert.Nil(t, result)
+}
+
+type defaultOptions struct {
+	Name     string        `opt:"name"`
+	Empty    string        `opt:"empty"`
+	Count    int           `opt:"count"`
+	Wait     time.Duration `opt:"wait"`
+	Override string        `opt:"override" default:"$HOME"`
+	Unset    *string       `opt:"unset"`
+	Set      *string       `opt:"set"`
+}
+
+func TestOptionsDefaultText(t *testing.T) {
+	empty := ""
+	obj, _ := newOptions(&Command{Defaults: &defaultOptions{
+		Name:     "name",
+		Count:    3,
+		Wait:     time.Second,
+		Override: "/home/user",
+		Set:      &empty,
+	}})
+
+	results := []string{}
+	for _, opt := range obj.Set.Options {
+		text, ok := obj.DefaultText(opt)
+		if ok {
+			results = append(results, text)
+		} else {
+			results = append(results, "<none>")
+		}
+	}
+
+	assert.Equal(t, []string{`"name"`, "<none>", "3", "1s", "$HOME", "<none>", `""`}, results)
+}
+
+func TestNewOptionSetCached(t *testing.T) {
+	typ := reflect.TypeOf(testOptions{})
+
+	first, err := newOptionSet(typ)
+	assert.NoError(t, err)
+	second, err := newOptionSet(typ)
+
+	assert.NoError(t, err)
+	assert.Same(t, first, second)
+}
+
+func TestNewOptionSetErrorNotCached(t *testing.T) {
+	type badOptions struct {
+		Value string `opt:"value" validate:"cache-test"`
+	}
+	typ := reflect.TypeOf(badOptions{})
+	_, err := newOptionSet(typ)
+	assert.Error(t, err)
+	Validators["cache-test"] = func(text string) error { return nil }
+	defer delete(Validators, "cache-test")
+
+	result, err := newOptionSet(typ)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+}
+
+func BenchmarkNewOptions(b *testing.B) {
+	cmd := &Command{Defaults: &testOptions{}}
+
+	for i := 0; i < b.N; i++ {
+		if _, err := newOptions(cmd); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import "sort"
+
+// SortOrder selects the order in which subcommands or flags are
+// listed in help; see ISortOrder.  All orders are stable, so help
+// output is deterministic.
+type SortOrder int
+
+// Orders for listing subcommands and flags in help.
+const (
+	SortDefault      SortOrder = iota // Subcommands are listed alphabetically, and flags in declaration order
+	SortAlphabetical                  // Listed alphabetically by name
+	SortDeclaration                   // Listed in declaration order; subcommands, declared in a map, are listed alphabetically
+	SortWeight                        // Listed by ascending weight, then by the default order; see IWeight and WeightTag
+)
+
+// sortCommands is a helper for helpData that sorts subcommands in the
+// specified order.
+func sortCommands(subs []*SubcommandHelp, order SortOrder) {
+	sort.SliceStable(subs, func(i, j int) bool {
+		if order == SortWeight && subs[i].Weight != subs[j].Weight {
+			return subs[i].Weight < subs[j].Weight
+		}
+		return subs[i].Name < subs[j].Name
+	})
+}
+
+// sortFlags is a helper for helpData that sorts flags, given in
+// declaration order, in the specified order.  Flags without a long
+// name are sorted by their short names.
+func sortFlags(flags []*FlagHelp, order SortOrder) {
+	switch order {
+	case SortAlphabetical:
+		sort.SliceStable(flags, func(i, j int) bool {
+			return flags[i].sortName() < flags[j].sortName()
+		})
+
+	case SortWeight:
+		sort.SliceStable(flags, func(i, j int) bool {
+			return flags[i].Weight < flags[j].Weight
+		})
+	}
+}
+
+// sortName returns the name by which the flag is sorted
+// alphabetically.
+func (f *FlagHelp) sortName() string {
+	if f.Name != "" {
+		return f.Name
+	}
+
+	return f.Short
+}
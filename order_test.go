@@ -0,0 +1,142 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// commandNames is a helper that returns the names of subcommands.
+func commandNames(subs []*SubcommandHelp) []string {
+	names := []string{}
+	for _, sub := range subs {
+		names = append(names, sub.Name)
+	}
+
+	return names
+}
+
+// flagNames is a helper that returns the sort names of flags.
+func flagNames(flags []*FlagHelp) []string {
+	names := []string{}
+	for _, flag := range flags {
+		names = append(names, flag.sortName())
+	}
+
+	return names
+}
+
+func TestSortCommandsDefault(t *testing.T) {
+	subs := []*SubcommandHelp{{Name: "gamma"}, {Name: "alpha", Weight: 1}, {Name: "beta"}}
+
+	sortCommands(subs, SortDefault)
+
+	assert.Equal(t, []string{"alpha", "beta", "gamma"}, commandNames(subs))
+}
+
+func TestSortCommandsDeclaration(t *testing.T) {
+	subs := []*SubcommandHelp{{Name: "gamma"}, {Name: "alpha"}, {Name: "beta"}}
+
+	sortCommands(subs, SortDeclaration)
+
+	assert.Equal(t, []string{"alpha", "beta", "gamma"}, commandNames(subs))
+}
+
+func TestSortCommandsWeight(t *testing.T) {
+	subs := []*SubcommandHelp{
+		{Name: "gamma"},
+		{Name: "alpha", Weight: 10},
+		{Name: "delta", Weight: -1},
+		{Name: "beta"},
+	}
+
+	sortCommands(subs, SortWeight)
+
+	assert.Equal(t, []string{"delta", "beta", "gamma", "alpha"}, commandNames(subs))
+}
+
+func TestSortFlagsDefault(t *testing.T) {
+	flags := []*FlagHelp{{Name: "verbose"}, {Short: "q"}, {Name: "color", Weight: -1}}
+
+	sortFlags(flags, SortDefault)
+
+	assert.Equal(t, []string{"verbose", "q", "color"}, flagNames(flags))
+}
+
+func TestSortFlagsDeclaration(t *testing.T) {
+	flags := []*FlagHelp{{Name: "verbose"}, {Short: "q"}, {Name: "color"}}
+
+	sortFlags(flags, SortDeclaration)
+
+	assert.Equal(t, []string{"verbose", "q", "color"}, flagNames(flags))
+}
+
+func TestSortFlagsAlphabetical(t *testing.T) {
+	flags := []*FlagHelp{{Name: "verbose"}, {Short: "q"}, {Name: "color"}}
+
+	sortFlags(flags, SortAlphabetical)
+
+	assert.Equal(t, []string{"color", "q", "verbose"}, flagNames(flags))
+}
+
+func TestSortFlagsWeight(t *testing.T) {
+	flags := []*FlagHelp{{Name: "verbose"}, {Short: "q", Weight: 1}, {Name: "color"}, {Name: "debug", Weight: -1}}
+
+	sortFlags(flags, SortWeight)
+
+	assert.Equal(t, []string{"debug", "verbose", "color", "q"}, flagNames(flags))
+}
+
+func TestAppHelpSortOrder(t *testing.T) {
+	type opts struct {
+		Verbose bool   `opt:"verbose" help:"Verbose output"`
+		Color   string `opt:"color" help:"Color to use" weight:"1"`
+		Debug   bool   `opt:"debug" help:"Debugging mode" weight:"-1"`
+	}
+	buf := &bytes.Buffer{}
+	obj := &App{}
+	inv := &Invocation{
+		Path: []string{"app", "sub"},
+		Command: &Command{
+			Defaults:     &opts{},
+			CommandOrder: SortWeight,
+			FlagOrder:    SortWeight,
+			Subcommands: map[string]ICommand{
+				"init":   &Command{Summary: "Initialize", Weight: -1},
+				"deploy": &Command{Summary: "Deploy"},
+				"clean":  &Command{Summary: "Clean up", Weight: 1},
+			},
+		},
+	}
+
+	err := obj.help(buf, inv)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Usage: app sub [flags] COMMAND [ARGS...]
+
+Available commands:
+  init    Initialize
+  deploy  Deploy
+  clean   Clean up
+
+Flags:
+      --debug    Debugging mode
+      --verbose  Verbose output
+      --color    Color to use
+`, buf.String())
+}
@@ -0,0 +1,430 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OutputFlag is the global flag that selects the format in which the
+// data returned by commands is rendered, if the application allows
+// it; see App.AllowOutput.  The format may be given as the following
+// argument or assigned, as in "--output=json".  OutputShortFlag is
+// equivalent.  Neither is recognized where the command declares a
+// flag of the same name.
+const (
+	OutputFlag      = "--output"
+	OutputShortFlag = "-o"
+)
+
+// Names of the built-in output formats.
+const (
+	OutputJSON     = "json"     // Indented JSON
+	OutputYAML     = "yaml"     // YAML
+	OutputTable    = "table"    // A table with a row for each element and a column for each field
+	OutputWide     = "wide"     // A table whose cells are never truncated
+	OutputName     = "name"     // The name of each element, one per line
+	OutputTemplate = "template" // A Go template, given as "template=TEMPLATE"
+)
+
+// DefaultOutput is the output format used if none is selected; see
+// App.WithDefaultOutput.
+const DefaultOutput = OutputTable
+
+// ErrUnknownOutput indicates that the selected output format is not
+// known.
+var ErrUnknownOutput = errors.New("unknown output format")
+
+// OutputFormatter renders the data returned by a command.
+type OutputFormatter interface {
+	// Format renders the data to the stream.
+	Format(w io.Writer, value interface{}) error
+}
+
+// OutputFormatterFunc is an implementation of OutputFormatter that
+// uses a function.
+type OutputFormatterFunc func(w io.Writer, value interface{}) error
+
+// Format renders the data to the stream.
+func (f OutputFormatterFunc) Format(w io.Writer, value interface{}) error {
+	return f(w, value)
+}
+
+// OutputFormat constructs the OutputFormatter for an output format.
+// Formats are selected by name, optionally followed by "=" and an
+// argument, which is passed to the function, as for the template
+// format, "template={{.Name}}"; the argument is "" if not given.
+type OutputFormat func(arg string) (OutputFormatter, error)
+
+// OutputFormats contains the output formats that may be selected,
+// by name.  Applications may add their own formats before any
+// commands are dispatched, or register them with a single
+// application; see App.WithOutputFormat.
+var OutputFormats = map[string]OutputFormat{
+	OutputJSON:     simpleOutput(formatJSON),
+	OutputYAML:     simpleOutput(formatYAML),
+	OutputTable:    simpleOutput(formatTable),
+	OutputWide:     simpleOutput(formatWide),
+	OutputName:     simpleOutput(formatName),
+	OutputTemplate: templateOutput,
+	OutputNDJSON:   formatNDJSON,
+}
+
+// Output renders the data returned by commands in the selected
+// output format.  It is available from the injector, so that
+// commands may also render data themselves.  Commands whose
+// RunMethod returns a result before its error, as in "func (c *List)
+// Run() ([]Item, error)", have the result rendered automatically,
+// unless it is nil.
+type Output struct {
+	Format    string          // Name of the selected format, e.g., "json"
+	Formatter OutputFormatter // Renders the data
+	Writer    io.Writer       // The stream to which the data is rendered
+}
+
+// Render renders the data.
+func (o *Output) Render(value interface{}) error {
+	return o.Formatter.Format(o.Writer, value)
+}
+
+// WithOutput sets whether the global OutputFlag is recognized.
+// Returns the App, to allow chaining.
+func (a *App) WithOutput(allow bool) *App {
+	a.AllowOutput = allow
+	return a
+}
+
+// WithDefaultOutput sets the output format used if none is selected
+// with the OutputFlag; see DefaultOutput.  Returns the App, to allow
+// chaining.
+func (a *App) WithDefaultOutput(format string) *App {
+	a.DefaultOutput = format
+	return a
+}
+
+// WithOutputFormat registers an output format with the application,
+// overriding any format of the same name in OutputFormats.  Returns
+// the App, to allow chaining.
+func (a *App) WithOutputFormat(name string, format OutputFormat) *App {
+	if a.OutputFormats == nil {
+		a.OutputFormats = map[string]OutputFormat{}
+	}
+	a.OutputFormats[name] = format
+	return a
+}
+
+// newOutput constructs the Output for the output format selected by
+// the text, as "name" or "name=argument"; if the text is empty, the
+// default format is selected.
+func (a *App) newOutput(text string) (*Output, error) {
+	if text == "" {
+		text = a.DefaultOutput
+	}
+	if text == "" {
+		text = DefaultOutput
+	}
+	name, arg := text, ""
+	if i := strings.Index(text, "="); i >= 0 {
+		name, arg = text[:i], text[i+1:]
+	}
+
+	format, ok := a.OutputFormats[name]
+	if !ok {
+		format, ok = OutputFormats[name]
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownOutput, name)
+	}
+	formatter, err := format(arg)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %s", ErrInvalidValue, text, err)
+	}
+	if f, ok := formatter.(*ndjsonFormatter); ok {
+		f.clock = a.clock()
+	}
+
+	return &Output{
+		Format:    name,
+		Formatter: formatter,
+		Writer:    a.stdout(),
+	}, nil
+}
+
+// extractOutput is a helper that removes the OutputFlag, or the
+// OutputShortFlag, and the format it selects, from the arguments
+// preceding any "--", returning the format--or "" if the flag was
+// not present--and the remaining arguments.
+func extractOutput(args []string) (string, []string, error) {
+	format := ""
+	result := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+
+		// Check for the flag
+		switch {
+		case arg == OutputFlag || arg == OutputShortFlag:
+			if i+1 >= len(args) {
+				return "", args, fmt.Errorf("%w %s", ErrMissingValue, arg)
+			}
+			i++
+			format = args[i]
+		case strings.HasPrefix(arg, OutputFlag+"="):
+			format = arg[len(OutputFlag)+1:]
+		case strings.HasPrefix(arg, OutputShortFlag+"="):
+			format = arg[len(OutputShortFlag)+1:]
+		default:
+			result = append(result, arg)
+		}
+	}
+
+	return format, result, nil
+}
+
+// simpleOutput is a helper that constructs an OutputFormat for a
+// format that takes no argument.
+func simpleOutput(fn func(w io.Writer, value interface{}) error) OutputFormat {
+	return func(arg string) (OutputFormatter, error) {
+		if arg != "" {
+			return nil, errors.New("format takes no argument")
+		}
+		return OutputFormatterFunc(fn), nil
+	}
+}
+
+// formatJSON renders data as indented JSON.
+func formatJSON(w io.Writer, value interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(value)
+}
+
+// formatYAML renders data as YAML.
+func formatYAML(w io.Writer, value interface{}) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(value); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// formatTable renders data as a table, truncated to the width of
+// the terminal; see outputTable and TableWriter.
+func formatTable(w io.Writer, value interface{}) error {
+	return writeTable(NewTableWriter(w), value)
+}
+
+// formatWide renders data as a table, like formatTable, but without
+// truncation.
+func formatWide(w io.Writer, value interface{}) error {
+	tw := NewTableWriter(w)
+	tw.Wide = true
+	return writeTable(tw, value)
+}
+
+// writeTable is a helper that writes data to a TableWriter; see
+// outputTable.
+func writeTable(tw *TableWriter, value interface{}) error {
+	header, rows := outputTable(value)
+	tw.Header = header
+	for _, row := range rows {
+		tw.Append(row...)
+	}
+
+	return tw.Flush()
+}
+
+// formatName renders the name of each element of the data, one per
+// line; see outputName.
+func formatName(w io.Writer, value interface{}) error {
+	for _, elem := range outputElements(reflect.ValueOf(value)) {
+		if _, err := fmt.Fprintln(w, outputName(elem)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// templateOutput constructs an OutputFormatter that renders data by
+// executing the Go template given as the argument.
+func templateOutput(arg string) (OutputFormatter, error) {
+	if arg == "" {
+		return nil, errors.New("template is required, as in template={{.Name}}")
+	}
+	tmpl, err := template.New(OutputTemplate).Parse(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	return OutputFormatterFunc(func(w io.Writer, value interface{}) error {
+		return tmpl.Execute(w, value)
+	}), nil
+}
+
+// outputElements is a helper that returns the elements of data:
+// those of a slice or array, or the data itself.  Pointers and
+// interfaces are dereferenced.
+func outputElements(v reflect.Value) []reflect.Value {
+	v = outputIndirect(v)
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []reflect.Value{v}
+	}
+
+	elems := make([]reflect.Value, v.Len())
+	for i := range elems {
+		elems[i] = outputIndirect(v.Index(i))
+	}
+
+	return elems
+}
+
+// outputIndirect is a helper that dereferences pointers and
+// interfaces.
+func outputIndirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// outputTable is a helper that converts data to a table, with a row
+// for each element; see outputElements.  If the elements are
+// structs, the columns are their exported fields, headed by their
+// names--or the names given by their "json" tags--in upper case;
+// fields tagged `json:"-"` are omitted.  If the elements are maps,
+// the columns are their keys, in sorted order.  Otherwise, the table
+// has a single column and no header.
+func outputTable(value interface{}) ([]string, [][]string) {
+	elems := outputElements(reflect.ValueOf(value))
+	if len(elems) == 0 {
+		return nil, nil
+	}
+
+	var header []string
+	var cell func(elem reflect.Value, i int) reflect.Value
+	switch first := elems[0]; first.Kind() {
+	case reflect.Struct:
+		var index [][]int
+		for i := 0; i < first.NumField(); i++ {
+			field := first.Type().Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if field.PkgPath != "" || name == "-" {
+				continue
+			} else if name == "" {
+				name = field.Name
+			}
+			header = append(header, strings.ToUpper(name))
+			index = append(index, field.Index)
+		}
+		cell = func(elem reflect.Value, i int) reflect.Value {
+			if elem.Kind() != reflect.Struct || elem.Type() != first.Type() {
+				return reflect.Value{}
+			}
+			return elem.FieldByIndex(index[i])
+		}
+
+	case reflect.Map:
+		keys := map[string]reflect.Value{}
+		for _, elem := range elems {
+			if elem.Kind() != reflect.Map {
+				continue
+			}
+			for _, key := range elem.MapKeys() {
+				keys[fmt.Sprint(key.Interface())] = key
+			}
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			header = append(header, strings.ToUpper(name))
+		}
+		cell = func(elem reflect.Value, i int) reflect.Value {
+			key := keys[names[i]]
+			if elem.Kind() != reflect.Map || !key.Type().AssignableTo(elem.Type().Key()) {
+				return reflect.Value{}
+			}
+			return elem.MapIndex(key)
+		}
+
+	default:
+		rows := make([][]string, len(elems))
+		for i, elem := range elems {
+			rows[i] = []string{outputText(elem)}
+		}
+		return nil, rows
+	}
+
+	rows := make([][]string, len(elems))
+	for i, elem := range elems {
+		rows[i] = make([]string, len(header))
+		for j := range header {
+			rows[i][j] = outputText(cell(elem, j))
+		}
+	}
+
+	return header, rows
+}
+
+// outputText is a helper that renders a single value as text.  Nil
+// values are rendered as "".
+func outputText(v reflect.Value) string {
+	v = outputIndirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+
+	return fmt.Sprint(v.Interface())
+}
+
+// outputName is a helper that returns the name of an element of the
+// data: its "Name" field, for structs, or its "name" key, for maps
+// with string keys; otherwise, the element is rendered as text.
+func outputName(elem reflect.Value) string {
+	switch elem.Kind() {
+	case reflect.Struct:
+		if field := elem.FieldByName("Name"); field.IsValid() {
+			return outputText(field)
+		}
+	case reflect.Map:
+		if elem.Type().Key().Kind() == reflect.String {
+			if name := elem.MapIndex(reflect.ValueOf("name").Convert(elem.Type().Key())); name.IsValid() {
+				return outputText(name)
+			}
+		}
+	}
+
+	return outputText(elem)
+}
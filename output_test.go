@@ -0,0 +1,420 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type outputItem struct {
+	Name    string
+	Size    int `json:"size"`
+	Note    *string
+	Secret  string `json:"-"`
+	private string
+}
+
+type outputCommand struct {
+	Command
+	result interface{}
+	err    error
+}
+
+func (c *outputCommand) Run() (interface{}, error) {
+	return c.result, c.err
+}
+
+// outputApp is a helper that constructs an application allowing the
+// OutputFlag, whose root command returns the specified result.
+func outputApp(result interface{}) (*App, *bytes.Buffer) {
+	stdout := &bytes.Buffer{}
+	obj := (&App{
+		Name:   "tool",
+		Root:   &outputCommand{result: result},
+		Stdout: stdout,
+	}).WithNoConfigSearch(true).WithOutput(true)
+
+	return obj, stdout
+}
+
+func outputItems() []outputItem {
+	note := "first"
+	return []outputItem{
+		{Name: "a", Size: 1, Note: &note, Secret: "x"},
+		{Name: "bb", Size: 22},
+	}
+}
+
+func TestExtractOutput(t *testing.T) {
+	format, args, err := extractOutput([]string{"sub", "--output", "json", "arg", "--", "-o", "yaml"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "json", format)
+	assert.Equal(t, []string{"sub", "arg", "--", "-o", "yaml"}, args)
+}
+
+func TestExtractOutputForms(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"Assigned", []string{"--output=json", "sub"}},
+		{"Short", []string{"-o", "json", "sub"}},
+		{"ShortAssigned", []string{"-o=json", "sub"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, args, err := extractOutput(test.args)
+
+			assert.NoError(t, err)
+			assert.Equal(t, "json", format)
+			assert.Equal(t, []string{"sub"}, args)
+		})
+	}
+}
+
+func TestExtractOutputIgnored(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"ShortAttached", []string{"-ojson", "sub"}},
+		{"Combined", []string{"-ov", "sub"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, args, err := extractOutput(test.args)
+
+			assert.NoError(t, err)
+			assert.Equal(t, "", format)
+			assert.Equal(t, test.args, args)
+		})
+	}
+}
+
+func TestExtractOutputMissingValue(t *testing.T) {
+	_, _, err := extractOutput([]string{"sub", "-o"})
+
+	assert.ErrorIs(t, err, ErrMissingValue)
+	assert.EqualError(t, err, "missing value for flag -o")
+}
+
+type outputFileOptions struct {
+	File string `opt:"file,o" help:"File to write"`
+}
+
+type outputFileCommand struct {
+	Command
+	file string
+}
+
+func (c *outputFileCommand) Run(opts *outputFileOptions) (interface{}, error) {
+	c.file = opts.File
+	return outputItems(), nil
+}
+
+type outputNameOptions struct {
+	Name string `opt:"name" help:"Name to use"`
+}
+
+type outputNameCommand struct {
+	Command
+	name string
+}
+
+func (c *outputNameCommand) Run(opts *outputNameOptions) (interface{}, error) {
+	c.name = opts.Name
+	return outputItems(), nil
+}
+
+func TestAppDispatchOutputDeclaredValue(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	named := &outputNameCommand{Command: Command{Defaults: &outputNameOptions{}}}
+	obj := (&App{
+		Name:   "tool",
+		Root:   &Command{Subcommands: map[string]ICommand{"named": named}},
+		Stdout: stdout,
+	}).WithNoConfigSearch(true).WithOutput(true)
+
+	err := obj.Dispatch(context.Background(), []string{"named", "--name", "-o", "--output", "json"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "-o", named.name)
+	assert.Contains(t, stdout.String(), `"size": 22`)
+}
+
+func TestAppDispatchOutputDeclared(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	save := &outputFileCommand{Command: Command{Defaults: &outputFileOptions{}}}
+	obj := (&App{
+		Name:   "tool",
+		Root:   &Command{Subcommands: map[string]ICommand{"save": save}},
+		Stdout: stdout,
+	}).WithNoConfigSearch(true).WithOutput(true)
+
+	err := obj.Dispatch(context.Background(), []string{"-o", "json", "save", "-o", "out.txt"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "out.txt", save.file)
+	assert.Contains(t, stdout.String(), `"size": 22`)
+}
+
+func TestAppWithOutput(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithOutput(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.AllowOutput)
+}
+
+func TestAppWithDefaultOutput(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithDefaultOutput(OutputJSON)
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, OutputJSON, obj.DefaultOutput)
+}
+
+func TestAppWithOutputFormat(t *testing.T) {
+	format := func(arg string) (OutputFormatter, error) { return nil, nil }
+	obj := &App{}
+
+	result := obj.WithOutputFormat("custom", format)
+
+	assert.Same(t, obj, result)
+	assert.Contains(t, obj.OutputFormats, "custom")
+}
+
+func TestAppNewOutputDefault(t *testing.T) {
+	obj := &App{}
+
+	result, err := obj.newOutput("")
+
+	assert.NoError(t, err)
+	assert.Equal(t, OutputTable, result.Format)
+}
+
+func TestAppNewOutputUnknown(t *testing.T) {
+	obj := &App{}
+
+	result, err := obj.newOutput("xml")
+
+	assert.ErrorIs(t, err, ErrUnknownOutput)
+	assert.EqualError(t, err, `unknown output format "xml"`)
+	assert.Nil(t, result)
+}
+
+func TestAppNewOutputBadArgument(t *testing.T) {
+	obj := &App{}
+
+	result, err := obj.newOutput("json=pretty")
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.EqualError(t, err, `invalid value "json=pretty": format takes no argument`)
+	assert.Nil(t, result)
+}
+
+func TestAppDispatchOutputTable(t *testing.T) {
+	obj, stdout := outputApp(outputItems())
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `NAME  SIZE  NOTE
+a     1     first
+bb    22
+`, stdout.String())
+}
+
+func TestAppDispatchOutputJSON(t *testing.T) {
+	obj, stdout := outputApp(outputItems()[1:])
+
+	err := obj.Dispatch(context.Background(), []string{"-o", "json"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `[
+  {
+    "Name": "bb",
+    "size": 22,
+    "Note": null
+  }
+]
+`, stdout.String())
+}
+
+func TestAppDispatchOutputYAML(t *testing.T) {
+	obj, stdout := outputApp(map[string]interface{}{"name": "a", "tags": []string{"x"}})
+
+	err := obj.Dispatch(context.Background(), []string{"--output=yaml"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "name: a\ntags:\n- x\n", stdout.String())
+}
+
+func TestAppDispatchOutputName(t *testing.T) {
+	obj, stdout := outputApp([]interface{}{
+		outputItems()[0],
+		map[string]int{"name": 5},
+		"text",
+		nil,
+	})
+
+	err := obj.Dispatch(context.Background(), []string{"-o", "name"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "a\n5\ntext\n\n", stdout.String())
+}
+
+func TestAppDispatchOutputTemplate(t *testing.T) {
+	obj, stdout := outputApp(outputItems())
+
+	err := obj.Dispatch(context.Background(), []string{"-o", "template={{range .}}{{.Name}}={{.Size}};{{end}}"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "a=1;bb=22;", stdout.String())
+}
+
+func TestAppDispatchOutputTemplateMissing(t *testing.T) {
+	obj, _ := outputApp(outputItems())
+
+	err := obj.Dispatch(context.Background(), []string{"-o", "template"})
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	var cmdErr *CommandError
+	assert.ErrorAs(t, err, &cmdErr)
+	assert.True(t, cmdErr.Usage)
+}
+
+func TestAppDispatchOutputCustom(t *testing.T) {
+	obj, stdout := outputApp("data")
+	obj.WithOutputFormat("upper", func(arg string) (OutputFormatter, error) {
+		return OutputFormatterFunc(func(w io.Writer, value interface{}) error {
+			_, err := io.WriteString(w, arg+":"+value.(string)+"\n")
+			return err
+		}), nil
+	})
+
+	err := obj.Dispatch(context.Background(), []string{"-o", "upper=prefix"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "prefix:data\n", stdout.String())
+}
+
+func TestAppDispatchOutputWide(t *testing.T) {
+	obj, stdout := outputApp([]map[string]string{{"name": "a", "description": "text"}})
+
+	err := obj.Dispatch(context.Background(), []string{"-o", "wide"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "DESCRIPTION  NAME\ntext         a\n", stdout.String())
+}
+
+func TestAppDispatchOutputDefault(t *testing.T) {
+	obj, stdout := outputApp([]string{"a", "b"})
+	obj.WithDefaultOutput(OutputJSON)
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "[\n  \"a\",\n  \"b\"\n]\n", stdout.String())
+}
+
+func TestAppDispatchOutputNil(t *testing.T) {
+	obj, stdout := outputApp((*outputItem)(nil))
+
+	err := obj.Dispatch(context.Background(), []string{"-o", "json"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", stdout.String())
+}
+
+func TestAppDispatchOutputError(t *testing.T) {
+	obj, stdout := outputApp(outputItems())
+	obj.Root.(*outputCommand).err = assert.AnError
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.Same(t, assert.AnError, err)
+	assert.Equal(t, "", stdout.String())
+}
+
+func TestAppDispatchOutputNotAllowed(t *testing.T) {
+	obj, stdout := outputApp([]string{"a"})
+	obj.WithOutput(false)
+
+	err := obj.Dispatch(context.Background(), []string{"-o", "json"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "a\n", stdout.String())
+}
+
+type renderCommand struct {
+	Command
+	output *Output
+}
+
+func (c *renderCommand) Run(output *Output) error {
+	c.output = output
+	return output.Render("text")
+}
+
+func TestAppDispatchOutputInjected(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	root := &renderCommand{}
+	obj := (&App{
+		Root:   root,
+		Stdout: stdout,
+	}).WithNoConfigSearch(true).WithDefaultOutput(OutputName)
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, OutputName, root.output.Format)
+	assert.Equal(t, "text\n", stdout.String())
+}
+
+func TestOutputTableMaps(t *testing.T) {
+	header, rows := outputTable([]map[string]interface{}{
+		{"name": "a", "size": 1},
+		{"name": "b", "extra": true},
+	})
+
+	assert.Equal(t, []string{"EXTRA", "NAME", "SIZE"}, header)
+	assert.Equal(t, [][]string{
+		{"", "a", "1"},
+		{"true", "b", ""},
+	}, rows)
+}
+
+func TestOutputTableScalar(t *testing.T) {
+	header, rows := outputTable(5)
+
+	assert.Nil(t, header)
+	assert.Equal(t, [][]string{{"5"}}, rows)
+}
+
+func TestOutputTableEmpty(t *testing.T) {
+	header, rows := outputTable([]outputItem{})
+
+	assert.Nil(t, header)
+	assert.Nil(t, rows)
+}
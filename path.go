@@ -0,0 +1,160 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// KindPath is the flag kind for filesystem paths, selected with
+// KindTag; the flag's field must be a string, a *string, or a
+// []string.  A leading "~" or "~user" is expanded to the home
+// directory of the current or named user, "/" separators are
+// converted to those of the platform, and relative paths are
+// resolved against the base selected with BaseTag, so that the field
+// holds an absolute, cleaned path.  This applies to values from the
+// command line, the configuration, and the environment alike, and
+// unless CompleteTag says otherwise, values are completed as files.
+// To verify that the path exists or may be accessed, name validators
+// such as "exists", "file", "dir", "readable", or "writable" with
+// ValidateTag, as in:
+//
+//	Cache string `opt:"cache" kind:"path" base:"config" validate:"dir,writable"`
+const KindPath = "path"
+
+// BaseTag selects the directory against which the relative paths
+// given to a KindPath flag are resolved: BaseCwd, the default, or
+// BaseConfig.
+const BaseTag = "base"
+
+// Bases of relative paths, selected with BaseTag.
+const (
+	BaseCwd    = "cwd"    // The current working directory
+	BaseConfig = "config" // The directory of the configuration file; see App.pathBase
+)
+
+// pathTypes lists the field types allowed for KindPath.
+var pathTypes = []reflect.Type{
+	reflect.TypeOf(""),
+	reflect.TypeOf((*string)(nil)),
+	reflect.TypeOf([]string(nil)),
+}
+
+// Hooks for testing.
+var (
+	userHomeDir = os.UserHomeDir
+	lookupUser  = user.Lookup
+)
+
+// checkPathKind checks that a field has a type allowed for
+// KindPath.
+func checkPathKind(typ reflect.Type) bool {
+	for _, allowed := range pathTypes {
+		if typ == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkBase checks the BaseTag of a flag, which is only allowed for
+// KindPath.
+func checkBase(opt *option) error {
+	switch {
+	case opt.Base == "":
+	case opt.Kind != KindPath:
+		return fmt.Errorf("%s tag requires a %s flag", BaseTag, KindPath)
+	case opt.Base != BaseCwd && opt.Base != BaseConfig:
+		return fmt.Errorf("bad %s tag %q", BaseTag, opt.Base)
+	}
+
+	return nil
+}
+
+// ExpandPath expands a leading "~" or "~user" in a path to the home
+// directory of the current or named user, converts "/" separators to
+// those of the platform, and, if the path is relative, resolves it
+// against the base directory.  The result is an absolute, cleaned
+// path; if the base is empty, the current working directory is used.
+// An empty path is returned unchanged.
+func ExpandPath(path, base string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	path = filepath.FromSlash(path)
+	if strings.HasPrefix(path, "~") {
+		name := path[1:]
+		rest := ""
+		if i := strings.IndexRune(name, filepath.Separator); i >= 0 {
+			name, rest = name[:i], name[i:]
+		}
+
+		var home string
+		if name == "" {
+			var err error
+			if home, err = userHomeDir(); err != nil {
+				return "", err
+			}
+		} else {
+			u, err := lookupUser(name)
+			if err != nil {
+				return "", err
+			}
+			home = u.HomeDir
+		}
+		path = home + rest
+	}
+
+	if !filepath.IsAbs(path) && base != "" {
+		path = filepath.Join(base, path)
+	}
+
+	return filepath.Abs(path)
+}
+
+// expandPath expands the value of a KindPath flag, resolving it
+// against the base selected by the flag's BaseTag.
+func (o *options) expandPath(opt *option, text string) (string, error) {
+	base := ""
+	if opt.Base == BaseConfig {
+		base = o.configDir
+	}
+
+	return ExpandPath(text, base)
+}
+
+// pathBase returns the directory against which relative paths are
+// resolved for KindPath flags with the BaseConfig base: the
+// directory of the last configuration file loaded, which is the one
+// taking precedence.  If there is no configuration file, the empty
+// string is returned, and paths are resolved against the current
+// working directory.
+func (a *App) pathBase(inv *Invocation) string {
+	paths := a.configPaths(inv)
+	for i := len(paths) - 1; i >= 0; i-- {
+		if _, err := os.Stat(paths[i]); err == nil {
+			return filepath.Dir(paths[i])
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,260 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeHome is a helper that makes the home directory hooks report
+// the specified directory for the current user and for the user
+// "alice", restoring them when the test completes.
+func fakeHome(t *testing.T, home string) {
+	t.Helper()
+
+	oldHomeDir, oldLookupUser := userHomeDir, lookupUser
+	userHomeDir = func() (string, error) { return home, nil }
+	lookupUser = func(name string) (*user.User, error) {
+		if name != "alice" {
+			return nil, user.UnknownUserError(name)
+		}
+		return &user.User{Username: name, HomeDir: filepath.Join(home, "alice")}, nil
+	}
+	t.Cleanup(func() {
+		userHomeDir, lookupUser = oldHomeDir, oldLookupUser
+	})
+}
+
+func TestExpandPathEmpty(t *testing.T) {
+	result, err := ExpandPath("", "/base")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", result)
+}
+
+func TestExpandPathAbsolute(t *testing.T) {
+	base := t.TempDir()
+	path := filepath.Join(base, "dir", "..", "file")
+
+	result, err := ExpandPath(path, "/elsewhere")
+
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(base, "file"), result)
+}
+
+func TestExpandPathRelativeBase(t *testing.T) {
+	base := t.TempDir()
+
+	result, err := ExpandPath("dir/./file", base)
+
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(base, "dir", "file"), result)
+}
+
+func TestExpandPathRelativeCwd(t *testing.T) {
+	cwd, _ := os.Getwd()
+
+	result, err := ExpandPath("dir/file", "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(cwd, "dir", "file"), result)
+}
+
+func TestExpandPathHome(t *testing.T) {
+	home := t.TempDir()
+	fakeHome(t, home)
+
+	result, err := ExpandPath("~/dir/file", "/base")
+
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "dir", "file"), result)
+}
+
+func TestExpandPathHomeAlone(t *testing.T) {
+	home := t.TempDir()
+	fakeHome(t, home)
+
+	result, err := ExpandPath("~", "/base")
+
+	assert.NoError(t, err)
+	assert.Equal(t, home, result)
+}
+
+func TestExpandPathHomeError(t *testing.T) {
+	fakeHome(t, "")
+	userHomeDir = func() (string, error) { return "", assert.AnError }
+
+	_, err := ExpandPath("~/file", "")
+
+	assert.Same(t, assert.AnError, err)
+}
+
+func TestExpandPathUser(t *testing.T) {
+	home := t.TempDir()
+	fakeHome(t, home)
+
+	result, err := ExpandPath("~alice/file", "/base")
+
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "alice", "file"), result)
+}
+
+func TestExpandPathUnknownUser(t *testing.T) {
+	fakeHome(t, t.TempDir())
+
+	_, err := ExpandPath("~bob/file", "")
+
+	assert.Equal(t, user.UnknownUserError("bob"), err)
+}
+
+func TestCheckPathKind(t *testing.T) {
+	assert.True(t, checkPathKind(reflect.TypeOf("")))
+	assert.True(t, checkPathKind(reflect.TypeOf((*string)(nil))))
+	assert.True(t, checkPathKind(reflect.TypeOf([]string{})))
+	assert.False(t, checkPathKind(reflect.TypeOf(0)))
+}
+
+func TestCheckBase(t *testing.T) {
+	assert.NoError(t, checkBase(&option{}))
+	assert.NoError(t, checkBase(&option{Kind: KindPath}))
+	assert.NoError(t, checkBase(&option{Kind: KindPath, Base: BaseCwd}))
+	assert.NoError(t, checkBase(&option{Kind: KindPath, Base: BaseConfig}))
+	assert.EqualError(t, checkBase(&option{Kind: KindPath, Base: "home"}), `bad base tag "home"`)
+	assert.EqualError(t, checkBase(&option{Base: BaseCwd}), "base tag requires a path flag")
+}
+
+func TestNewOptionSetPath(t *testing.T) {
+	type opts struct {
+		Dir  string  `opt:"dir" kind:"path"`
+		Data *string `opt:"data" kind:"path" complete:"dir"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Completion{Kind: CompleteFile}, result.long["dir"].Complete)
+	assert.Equal(t, &Completion{Kind: CompleteDir}, result.long["data"].Complete)
+}
+
+func TestNewOptionSetPathBadType(t *testing.T) {
+	type opts struct {
+		Dir int `opt:"dir" kind:"path"`
+	}
+
+	_, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.EqualError(t, err, "invalid options declaration: field Dir: path flag cannot be a int")
+}
+
+func TestNewOptionSetPathBadBase(t *testing.T) {
+	type opts struct {
+		Dir string `opt:"dir" base:"config"`
+	}
+
+	_, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.EqualError(t, err, "invalid options declaration: field Dir: base tag requires a path flag")
+}
+
+func TestAppPathBase(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.yaml")
+	second := filepath.Join(dir, "sub", "second.yaml")
+	if err := ioutil.WriteFile(first, []byte{}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	obj := &App{
+		NoConfigSearch: true,
+		ConfigFiles:    []string{first},
+		ConfigFile:     second,
+	}
+
+	result := obj.pathBase(&Invocation{})
+
+	assert.Equal(t, dir, result)
+}
+
+func TestAppPathBaseNone(t *testing.T) {
+	obj := &App{NoConfigSearch: true}
+
+	result := obj.pathBase(&Invocation{})
+
+	assert.Equal(t, "", result)
+}
+
+type pathOptions struct {
+	Cache string   `opt:"cache" kind:"path" base:"config"`
+	Out   *string  `opt:"out" kind:"path"`
+	Incl  []string `opt:"include" kind:"path"`
+	Data  string   `opt:"data" kind:"path" validate:"exists"`
+}
+
+func TestAppDispatchPath(t *testing.T) {
+	dir := t.TempDir()
+	home := t.TempDir()
+	fakeHome(t, home)
+	cwd, _ := os.Getwd()
+	config := filepath.Join(dir, "tool.yaml")
+	if err := ioutil.WriteFile(config, []byte("cache: cache\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	root := newRunCommand("Root", nil)
+	root.Defaults = &pathOptions{}
+	var opts *pathOptions
+	root.On("Run", Args{}, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		opts = args.Get(1).(*Invocation).Options.(*pathOptions)
+	})
+	obj := &App{
+		Root:           root,
+		NoConfigSearch: true,
+		ConfigFile:     config,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--out", "~/out", "--include", "a/../b", "--include", "/c//d"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "cache"), opts.Cache)
+	assert.Equal(t, filepath.Join(home, "out"), *opts.Out)
+	assert.Equal(t, []string{filepath.Join(cwd, "b"), filepath.FromSlash("/c/d")}, opts.Incl)
+	assert.Equal(t, "", opts.Data)
+	root.AssertExpectations(t)
+}
+
+func TestAppDispatchPathMissing(t *testing.T) {
+	dir := t.TempDir()
+	root := newRunCommand("Root", nil)
+	root.Defaults = &pathOptions{}
+	obj := &App{
+		Root:           root,
+		NoConfigSearch: true,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--data", filepath.Join(dir, "missing")})
+
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Contains(t, err.Error(), "--data: does not exist")
+	root.AssertExpectations(t)
+}
@@ -0,0 +1,373 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Errors related to external plugins.
+var (
+	ErrPluginFailed    = errors.New("plugin failed")
+	ErrManifestVersion = errors.New("unsupported manifest version")
+)
+
+// ManifestSuffix is the suffix appended to the path of a plugin
+// executable to locate its optional manifest file.
+const ManifestSuffix = ".manifest.json"
+
+// ManifestFlag is the flag passed to a plugin executable to request
+// that it emit its manifest, as JSON, on standard output.  Plugins
+// are only queried if the PluginLoader's Query field is set and no
+// manifest file is present.
+const ManifestFlag = "--nelson-manifest"
+
+// ManifestVersion is the version of the manifest protocol.
+const ManifestVersion = 1
+
+// DefaultManifestTimeout is the time a plugin queried with
+// ManifestFlag is allowed to emit its manifest, unless the
+// PluginLoader selects another.
+const DefaultManifestTimeout = 5 * time.Second
+
+// PluginManifest describes an external plugin.  A manifest is
+// optional; if present, it supplies the summary and help text for
+// the plugin, and may advertise the plugin's subcommands and flags
+// so that they may be merged into the host command tree and
+// completion output.  A manifest may be provided as a file next to
+// the plugin executable, with the name of the executable plus
+// ManifestSuffix, or emitted by the plugin when invoked with
+// ManifestFlag.
+type PluginManifest struct {
+	Version     int                        `json:"version,omitempty"`     // Manifest protocol version
+	Summary     string                     `json:"summary,omitempty"`     // The summary of the plugin
+	Description string                     `json:"description,omitempty"` // The full description of the plugin
+	Group       string                     `json:"group,omitempty"`       // Optional group name for the plugin
+	Flags       []*PluginFlag              `json:"flags,omitempty"`       // Flags accepted by the plugin
+	Subcommands map[string]*PluginManifest `json:"subcommands,omitempty"` // Subcommands of the plugin
+}
+
+// PluginFlag describes a flag accepted by an external plugin.
+type PluginFlag struct {
+	Name     string   `json:"name,omitempty"`     // Long name of the flag, without dashes
+	Short    string   `json:"short,omitempty"`    // Short name of the flag, without dash
+	Help     string   `json:"help,omitempty"`     // Help text for the flag
+	Value    string   `json:"value,omitempty"`    // Name of the flag's value; empty for boolean flags
+	Complete string   `json:"complete,omitempty"` // Completion hint, e.g., "file" or "dir"
+	Choices  []string `json:"choices,omitempty"`  // Allowed values of the flag
+}
+
+// ReadManifest reads a plugin manifest from a stream.  Manifests
+// using a newer version of the manifest protocol than ManifestVersion
+// are rejected with ErrManifestVersion.
+func ReadManifest(r io.Reader) (*PluginManifest, error) {
+	manifest := &PluginManifest{}
+	if err := json.NewDecoder(r).Decode(manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Version > ManifestVersion {
+		return nil, fmt.Errorf("%w %d", ErrManifestVersion, manifest.Version)
+	}
+
+	return manifest, nil
+}
+
+// Write writes the plugin manifest, as JSON, to a stream.  This is
+// intended for use by plugins implementing ManifestFlag.
+func (m *PluginManifest) Write(w io.Writer) error {
+	if m.Version == 0 {
+		m.Version = ManifestVersion
+	}
+
+	return json.NewEncoder(w).Encode(m)
+}
+
+// PluginCommand is a command implemented by an external executable.
+// When executed, the arguments are forwarded to the executable, which
+// shares the standard input, output, and error streams of the
+// application; the executable's exit code becomes the exit code of
+// the application.
+type PluginCommand struct {
+	Command
+	Path     string          // Path to the plugin executable
+	Prefix   []string        // Arguments to pass before the user's arguments
+	Manifest *PluginManifest // The plugin's manifest, if any
+}
+
+// Exec executes the plugin with the specified arguments and streams.
+// If the context is canceled, the plugin is killed.  If the plugin
+// exits with a non-zero exit code, a CommandError wrapping
+// ErrPluginFailed and carrying that exit code is returned.
+func (c *PluginCommand) Exec(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	args = append(append([]string{}, c.Prefix...), args...)
+	cmd := exec.CommandContext(ctx, c.Path, args...) //nolint:gosec
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &CommandError{
+			Err:  fmt.Errorf("%w: %s exited with code %d", ErrPluginFailed, c.Path, exitErr.ExitCode()),
+			Code: exitErr.ExitCode(),
+		}
+	}
+
+	return err
+}
+
+// Run runs the plugin.  The plugin shares the standard streams of
+// the application.
+func (c *PluginCommand) Run(ctx context.Context, app *App, args Args) error {
+	return c.Exec(ctx, args, app.stdin(), app.stdout(), app.stderr())
+}
+
+// PluginLoader discovers external plugins.  A plugin is an
+// executable on the search path whose name begins with the prefix;
+// the remainder of the name becomes the name of the subcommand.  For
+// instance, with the prefix "app-", the executable "app-frob"
+// provides the "frob" subcommand, in the manner of git or kubectl.
+// Empty entries in the search path are skipped, rather than
+// searching the current directory.
+type PluginLoader struct {
+	Prefix  string        // Executable name prefix, e.g., "app-"
+	Path    string        // Search path; if empty, $PATH is used
+	Query   bool          // If true, query plugins for manifests
+	Timeout time.Duration // Time allowed to query a plugin; defaults to DefaultManifestTimeout
+}
+
+// NewPluginLoader constructs a PluginLoader that discovers plugins
+// named "<app>-<subcommand>" on $PATH.
+func NewPluginLoader(app string) *PluginLoader {
+	return &PluginLoader{
+		Prefix: app + "-",
+	}
+}
+
+// Load discovers the plugins, returning a map of subcommand names to
+// PluginCommand objects.  If a plugin name is found in more than one
+// directory on the search path, the first one found is used.
+func (l *PluginLoader) Load() map[string]ICommand {
+	return l.load(nil)
+}
+
+// load implements Load, reporting the plugins discovered to the
+// logger, if any.
+func (l *PluginLoader) load(log *Logger) map[string]ICommand {
+	path := l.Path
+	if path == "" {
+		path = os.Getenv("PATH")
+	}
+
+	result := map[string]ICommand{}
+	for _, dir := range filepath.SplitList(path) {
+		// Skip empty entries, rather than searching the current
+		// directory
+		if dir == "" {
+			continue
+		}
+
+		// Read the directory; unreadable directories are skipped
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name, ok := l.pluginName(dir, entry)
+			if !ok {
+				continue
+			}
+			cmd := &PluginCommand{
+				Path: filepath.Join(dir, entry.Name()),
+			}
+			if found, ok := result[name]; ok {
+				log.Debugf("ignoring plugin %s, shadowed by %s", cmd.Path, found.(*PluginCommand).Path)
+				continue
+			}
+			log.Debugf("found plugin %q at %s", name, cmd.Path)
+			if manifest := l.manifest(cmd.Path); manifest != nil {
+				cmd.apply(manifest)
+			}
+			result[name] = cmd
+		}
+	}
+
+	return result
+}
+
+// Names returns the sorted names of the discovered plugins.
+func (l *PluginLoader) Names() []string {
+	plugins := l.Load()
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// DynamicSubcommands adds the discovered plugins to the subcommands,
+// reporting them to the application's logger.  It is suitable for
+// use as the DynamicSubcommands function of a Command.
+func (l *PluginLoader) DynamicSubcommands(subs Subcommands, log *Logger) {
+	for name, cmd := range l.load(log) {
+		subs[name] = cmd
+	}
+}
+
+// pluginName determines whether an entry of the directory is a
+// plugin, and returns the corresponding subcommand name.  Symbolic
+// links, as package managers often install plugins, are followed.
+func (l *PluginLoader) pluginName(dir string, entry os.FileInfo) (string, bool) {
+	name := entry.Name()
+	if !strings.HasPrefix(name, l.Prefix) {
+		return "", false
+	}
+	if entry.Mode()&os.ModeSymlink != 0 {
+		var err error
+		if entry, err = os.Stat(filepath.Join(dir, name)); err != nil {
+			return "", false
+		}
+	}
+	if !entry.Mode().IsRegular() {
+		return "", false
+	}
+
+	// Check that it's executable
+	if runtime.GOOS == "windows" {
+		if !strings.EqualFold(filepath.Ext(name), ".exe") {
+			return "", false
+		}
+		name = name[:len(name)-len(".exe")]
+	} else if entry.Mode().Perm()&0o111 == 0 {
+		return "", false
+	}
+
+	name = name[len(l.Prefix):]
+	return name, name != ""
+}
+
+// manifest reads the manifest for the plugin, if one exists.  The
+// manifest file is tried first; if it does not exist and Query is
+// set, the plugin is invoked with ManifestFlag, and is killed if it
+// does not exit within the loader's Timeout.  Errors reading the
+// manifest are ignored, and result in a nil return.
+func (l *PluginLoader) manifest(path string) *PluginManifest {
+	// Try the manifest file first
+	f, err := os.Open(path + ManifestSuffix)
+	if err == nil {
+		defer f.Close()
+		manifest, _ := ReadManifest(f)
+		return manifest
+	}
+
+	// Query the plugin if allowed
+	if !l.Query {
+		return nil
+	}
+	timeout := l.Timeout
+	if timeout <= 0 {
+		timeout = DefaultManifestTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, ManifestFlag).Output() //nolint:gosec
+	if err != nil {
+		return nil
+	}
+	manifest, _ := ReadManifest(bytes.NewReader(out))
+	return manifest
+}
+
+// apply applies a manifest to the plugin command, constructing
+// plugin commands for any advertised subcommands.
+func (c *PluginCommand) apply(manifest *PluginManifest) {
+	c.Manifest = manifest
+	c.Summary = manifest.Summary
+	c.Description = manifest.Description
+	c.Group = manifest.Group
+
+	if len(manifest.Subcommands) == 0 {
+		return
+	}
+	c.Subcommands = map[string]ICommand{}
+	for name, subManifest := range manifest.Subcommands {
+		sub := &PluginCommand{
+			Path:   c.Path,
+			Prefix: append(append([]string{}, c.Prefix...), name),
+		}
+		sub.apply(subManifest)
+		c.Subcommands[name] = sub
+	}
+}
+
+// describeFlags describes the flags advertised by the plugin's
+// manifest, for help, specifications, and completion.  The flags are
+// not parsed by the application, but are passed to the plugin along
+// with its other arguments.
+func (c *PluginCommand) describeFlags() (*options, error) {
+	if c.Manifest == nil || len(c.Manifest.Flags) == 0 {
+		return nil, nil
+	}
+
+	// Construct a defaults struct declaring the flags
+	fields := make([]reflect.StructField, 0, len(c.Manifest.Flags))
+	for i, flag := range c.Manifest.Flags {
+		typ := reflect.TypeOf(false)
+		if flag.Value != "" {
+			typ = reflect.TypeOf("")
+		}
+		names := flag.Name
+		if flag.Short != "" {
+			names += "," + flag.Short
+		}
+		tags := []string{
+			fmt.Sprintf("%s:%q", OptTag, names),
+			fmt.Sprintf("%s:%q", HelpTag, flag.Help),
+			fmt.Sprintf("%s:%q", EnvTag, "-"),
+			fmt.Sprintf("%s:%q", ConfigTag, "-"),
+		}
+		if len(flag.Choices) > 0 {
+			tags = append(tags, fmt.Sprintf("%s:%q", ChoicesTag, strings.Join(flag.Choices, ",")))
+		}
+		if flag.Complete != "" {
+			tags = append(tags, fmt.Sprintf("%s:%q", CompleteTag, flag.Complete))
+		}
+		fields = append(fields, reflect.StructField{
+			Name: fmt.Sprintf("Flag%d", i),
+			Type: typ,
+			Tag:  reflect.StructTag(strings.Join(tags, " ")),
+		})
+	}
+
+	return optionsFor(reflect.New(reflect.StructOf(fields)).Interface(), false)
+}
@@ -0,0 +1,569 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func skipPluginTests(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin tests require a POSIX shell")
+	}
+}
+
+func makePlugin(t *testing.T, dir, name, script string, mode os.FileMode) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), mode))
+	return path
+}
+
+func makePluginDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "nelson")
+	require.NoError(t, err)
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestReadManifestBase(t *testing.T) {
+	result, err := ReadManifest(strings.NewReader(`{"version":1,"summary":"Summary"}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &PluginManifest{
+		Version: 1,
+		Summary: "Summary",
+	}, result)
+}
+
+func TestReadManifestError(t *testing.T) {
+	result, err := ReadManifest(strings.NewReader(`not json`))
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestReadManifestNewer(t *testing.T) {
+	result, err := ReadManifest(strings.NewReader(`{"version":2,"summary":"Summary"}`))
+
+	assert.ErrorIs(t, err, ErrManifestVersion)
+	assert.EqualError(t, err, "unsupported manifest version 2")
+	assert.Nil(t, result)
+}
+
+func TestPluginManifestWrite(t *testing.T) {
+	obj := &PluginManifest{
+		Summary: "Summary",
+	}
+	buf := &bytes.Buffer{}
+
+	err := obj.Write(buf)
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"version":1,"summary":"Summary"}`, buf.String())
+}
+
+func TestPluginCommandImplementsICommand(t *testing.T) {
+	assert.Implements(t, (*ICommand)(nil), &PluginCommand{})
+}
+
+func TestPluginCommandExecBase(t *testing.T) {
+	skipPluginTests(t)
+	dir, cleanup := makePluginDir(t)
+	defer cleanup()
+	obj := &PluginCommand{
+		Path: makePlugin(t, dir, "app-test", `cat; echo "out $@"; echo "err $1" >&2`, 0o755),
+	}
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	err := obj.Exec(context.Background(), []string{"a1", "a2"}, strings.NewReader("input\n"), stdout, stderr)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "input\nout a1 a2\n", stdout.String())
+	assert.Equal(t, "err a1\n", stderr.String())
+}
+
+func TestPluginCommandExecPrefix(t *testing.T) {
+	skipPluginTests(t)
+	dir, cleanup := makePluginDir(t)
+	defer cleanup()
+	obj := &PluginCommand{
+		Path:   makePlugin(t, dir, "app-test", `echo "$@"`, 0o755),
+		Prefix: []string{"sub", "deep"},
+	}
+	stdout := &bytes.Buffer{}
+
+	err := obj.Exec(context.Background(), []string{"a1"}, nil, stdout, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "sub deep a1\n", stdout.String())
+	assert.Equal(t, []string{"sub", "deep"}, obj.Prefix)
+}
+
+func TestPluginCommandExecExitCode(t *testing.T) {
+	skipPluginTests(t)
+	dir, cleanup := makePluginDir(t)
+	defer cleanup()
+	obj := &PluginCommand{
+		Path: makePlugin(t, dir, "app-test", "exit 3", 0o755),
+	}
+
+	err := obj.Exec(context.Background(), nil, nil, nil, nil)
+
+	assert.ErrorIs(t, err, ErrPluginFailed)
+	code, usage := ExitControl(err)
+	assert.Equal(t, 3, code)
+	assert.False(t, usage)
+}
+
+func TestPluginCommandExecCanceled(t *testing.T) {
+	skipPluginTests(t)
+	dir, cleanup := makePluginDir(t)
+	defer cleanup()
+	obj := &PluginCommand{
+		Path: makePlugin(t, dir, "app-test", "exec sleep 10", 0o755),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := obj.Exec(ctx, nil, nil, nil, nil)
+
+	assert.Error(t, err)
+}
+
+func TestPluginCommandExecMissing(t *testing.T) {
+	obj := &PluginCommand{
+		Path: "/no/such/plugin",
+	}
+
+	err := obj.Exec(context.Background(), nil, nil, nil, nil)
+
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrPluginFailed)
+}
+
+func TestPluginCommandRun(t *testing.T) {
+	skipPluginTests(t)
+	dir, cleanup := makePluginDir(t)
+	defer cleanup()
+	obj := &PluginCommand{
+		Path: makePlugin(t, dir, "app-test", `cat; echo "out $@"; echo "err $1" >&2`, 0o755),
+	}
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	app := &App{
+		Root: &Command{
+			Subcommands: map[string]ICommand{
+				"test": obj,
+			},
+		},
+		Stdin:  strings.NewReader("input\n"),
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+
+	err := app.Dispatch(context.Background(), []string{"test", "a1", "a2"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "input\nout a1 a2\n", stdout.String())
+	assert.Equal(t, "err a1\n", stderr.String())
+}
+
+func TestNewPluginLoader(t *testing.T) {
+	result := NewPluginLoader("app")
+
+	assert.Equal(t, &PluginLoader{
+		Prefix: "app-",
+	}, result)
+}
+
+func TestPluginLoaderLoad(t *testing.T) {
+	skipPluginTests(t)
+	dir1, cleanup1 := makePluginDir(t)
+	defer cleanup1()
+	dir2, cleanup2 := makePluginDir(t)
+	defer cleanup2()
+	frob := makePlugin(t, dir1, "app-frob", "exit 0", 0o755)
+	makePlugin(t, dir1, "app-noexec", "exit 0", 0o644)
+	makePlugin(t, dir1, "app-", "exit 0", 0o755)
+	makePlugin(t, dir1, "other-frob", "exit 0", 0o755)
+	require.NoError(t, os.Mkdir(filepath.Join(dir1, "app-dir"), 0o755))
+	makePlugin(t, dir2, "app-frob", "exit 0", 0o755)
+	bar := makePlugin(t, dir2, "app-bar", "exit 0", 0o755)
+	require.NoError(t, ioutil.WriteFile(bar+ManifestSuffix, []byte(`{"summary":"Bar the thing","description":"Bars things.","group":"things"}`), 0o644))
+	obj := &PluginLoader{
+		Prefix: "app-",
+		Path:   strings.Join([]string{dir1, "/no/such/dir", dir2}, string(filepath.ListSeparator)),
+	}
+
+	result := obj.Load()
+
+	assert.Equal(t, map[string]ICommand{
+		"frob": &PluginCommand{
+			Path: frob,
+		},
+		"bar": &PluginCommand{
+			Command: Command{
+				Summary:     "Bar the thing",
+				Description: "Bars things.",
+				Group:       "things",
+			},
+			Path: bar,
+			Manifest: &PluginManifest{
+				Summary:     "Bar the thing",
+				Description: "Bars things.",
+				Group:       "things",
+			},
+		},
+	}, result)
+}
+
+func TestPluginLoaderLoadSymlink(t *testing.T) {
+	skipPluginTests(t)
+	dir1, cleanup1 := makePluginDir(t)
+	defer cleanup1()
+	dir2, cleanup2 := makePluginDir(t)
+	defer cleanup2()
+	target := makePlugin(t, dir1, "frob", "exit 0", 0o755)
+	frob := filepath.Join(dir2, "app-frob")
+	require.NoError(t, os.Symlink(target, frob))
+	require.NoError(t, os.Symlink(filepath.Join(dir1, "missing"), filepath.Join(dir2, "app-dangling")))
+	require.NoError(t, os.Symlink(dir1, filepath.Join(dir2, "app-dir")))
+	obj := &PluginLoader{
+		Prefix: "app-",
+		Path:   dir2,
+	}
+
+	result := obj.Load()
+
+	assert.Equal(t, map[string]ICommand{
+		"frob": &PluginCommand{
+			Path: frob,
+		},
+	}, result)
+}
+
+func TestPluginLoaderLoadEmptyPathEntry(t *testing.T) {
+	skipPluginTests(t)
+	dir, cleanup := makePluginDir(t)
+	defer cleanup()
+	makePlugin(t, dir, "app-frob", "exit 0", 0o755)
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(cwd)
+	obj := &PluginLoader{
+		Prefix: "app-",
+		Path:   string(filepath.ListSeparator) + "/no/such/dir",
+	}
+
+	result := obj.Load()
+
+	assert.Empty(t, result)
+}
+
+func TestPluginLoaderLoadEnvPath(t *testing.T) {
+	skipPluginTests(t)
+	dir, cleanup := makePluginDir(t)
+	defer cleanup()
+	frob := makePlugin(t, dir, "app-frob", "exit 0", 0o755)
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir)
+	obj := NewPluginLoader("app")
+
+	result := obj.Load()
+
+	assert.Equal(t, map[string]ICommand{
+		"frob": &PluginCommand{
+			Path: frob,
+		},
+	}, result)
+}
+
+func TestPluginLoaderLoadBadManifest(t *testing.T) {
+	skipPluginTests(t)
+	dir, cleanup := makePluginDir(t)
+	defer cleanup()
+	frob := makePlugin(t, dir, "app-frob", "exit 0", 0o755)
+	require.NoError(t, ioutil.WriteFile(frob+ManifestSuffix, []byte(`not json`), 0o644))
+	obj := &PluginLoader{
+		Prefix: "app-",
+		Path:   dir,
+	}
+
+	result := obj.Load()
+
+	assert.Equal(t, map[string]ICommand{
+		"frob": &PluginCommand{
+			Path: frob,
+		},
+	}, result)
+}
+
+func TestPluginLoaderLoadQuery(t *testing.T) {
+	skipPluginTests(t)
+	dir, cleanup := makePluginDir(t)
+	defer cleanup()
+	frob := makePlugin(t, dir, "app-frob", `[ "$1" = "--nelson-manifest" ] && echo '{"version":1,"summary":"Frob","flags":[{"name":"all","short":"a","help":"Frob all"}],"subcommands":{"sub":{"summary":"Frob sub","subcommands":{"deep":{"summary":"Deep"}}}}}'`, 0o755)
+	bar := makePlugin(t, dir, "app-bar", "exit 1", 0o755)
+	obj := &PluginLoader{
+		Prefix: "app-",
+		Path:   dir,
+		Query:  true,
+	}
+
+	result := obj.Load()
+
+	deepManifest := &PluginManifest{
+		Summary: "Deep",
+	}
+	subManifest := &PluginManifest{
+		Summary: "Frob sub",
+		Subcommands: map[string]*PluginManifest{
+			"deep": deepManifest,
+		},
+	}
+	assert.Equal(t, map[string]ICommand{
+		"frob": &PluginCommand{
+			Command: Command{
+				Summary: "Frob",
+				Subcommands: map[string]ICommand{
+					"sub": &PluginCommand{
+						Command: Command{
+							Summary: "Frob sub",
+							Subcommands: map[string]ICommand{
+								"deep": &PluginCommand{
+									Command: Command{
+										Summary: "Deep",
+									},
+									Path:     frob,
+									Prefix:   []string{"sub", "deep"},
+									Manifest: deepManifest,
+								},
+							},
+						},
+						Path:     frob,
+						Prefix:   []string{"sub"},
+						Manifest: subManifest,
+					},
+				},
+			},
+			Path: frob,
+			Manifest: &PluginManifest{
+				Version: 1,
+				Summary: "Frob",
+				Flags: []*PluginFlag{
+					{
+						Name:  "all",
+						Short: "a",
+						Help:  "Frob all",
+					},
+				},
+				Subcommands: map[string]*PluginManifest{
+					"sub": subManifest,
+				},
+			},
+		},
+		"bar": &PluginCommand{
+			Path: bar,
+		},
+	}, result)
+}
+
+func TestPluginLoaderNames(t *testing.T) {
+	skipPluginTests(t)
+	dir, cleanup := makePluginDir(t)
+	defer cleanup()
+	makePlugin(t, dir, "app-frob", "exit 0", 0o755)
+	makePlugin(t, dir, "app-bar", "exit 0", 0o755)
+	obj := &PluginLoader{
+		Prefix: "app-",
+		Path:   dir,
+	}
+
+	result := obj.Names()
+
+	assert.Equal(t, []string{"bar", "frob"}, result)
+}
+
+func TestPluginLoaderDynamicSubcommands(t *testing.T) {
+	skipPluginTests(t)
+	dir, cleanup := makePluginDir(t)
+	defer cleanup()
+	frob := makePlugin(t, dir, "app-frob", "exit 0", 0o755)
+	obj := &PluginLoader{
+		Prefix: "app-",
+		Path:   dir,
+	}
+	subs := Subcommands{}
+	buf := &bytes.Buffer{}
+
+	obj.DynamicSubcommands(subs, NewLogger(buf, "app", VerbosityDebug))
+
+	assert.Equal(t, Subcommands{
+		"frob": &PluginCommand{
+			Path: frob,
+		},
+	}, subs)
+	assert.Equal(t, fmt.Sprintf("app: debug: found plugin \"frob\" at %s\n", frob), buf.String())
+}
+
+func TestPluginLoaderLoadQueryTimeout(t *testing.T) {
+	skipPluginTests(t)
+	dir, cleanup := makePluginDir(t)
+	defer cleanup()
+	frob := makePlugin(t, dir, "app-frob", "exec sleep 30", 0o755)
+	obj := &PluginLoader{
+		Prefix:  "app-",
+		Path:    dir,
+		Query:   true,
+		Timeout: 50 * time.Millisecond,
+	}
+	start := time.Now()
+
+	result := obj.Load()
+
+	assert.Less(t, int64(time.Since(start)), int64(10*time.Second))
+	assert.Equal(t, map[string]ICommand{
+		"frob": &PluginCommand{
+			Path: frob,
+		},
+	}, result)
+}
+
+// flagPlugin is a helper that constructs a plugin command whose
+// manifest advertises flags.
+func flagPlugin() *PluginCommand {
+	cmd := &PluginCommand{Path: "/bin/app-frob"}
+	cmd.apply(&PluginManifest{
+		Summary: "Frob things",
+		Flags: []*PluginFlag{
+			{Name: "all", Short: "a", Help: "Frob all"},
+			{Name: "format", Value: "FORMAT", Help: "Output format", Choices: []string{"json", "text"}},
+			{Name: "input", Value: "FILE", Help: "Input file", Complete: CompleteFile},
+		},
+	})
+	return cmd
+}
+
+func TestPluginCommandDescribeFlags(t *testing.T) {
+	obj := flagPlugin()
+
+	result, err := obj.describeFlags()
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Set.Options, 3)
+	all, format, input := result.Set.Options[0], result.Set.Options[1], result.Set.Options[2]
+	assert.Equal(t, "all", all.Name)
+	assert.Equal(t, "a", all.Short)
+	assert.Equal(t, "Frob all", all.Help)
+	assert.False(t, all.TakesValue())
+	assert.Equal(t, []string{"json", "text"}, format.Choices)
+	assert.True(t, format.TakesValue())
+	assert.Equal(t, CompleteFile, input.Complete.String())
+}
+
+func TestPluginCommandDescribeFlagsNone(t *testing.T) {
+	obj := &PluginCommand{Path: "/bin/app-frob"}
+
+	result, err := obj.describeFlags()
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestPluginCommandDescribeFlagsBad(t *testing.T) {
+	obj := &PluginCommand{
+		Manifest: &PluginManifest{
+			Flags: []*PluginFlag{{Name: "all", Complete: "bogus"}},
+		},
+	}
+
+	_, err := obj.describeFlags()
+
+	assert.Error(t, err)
+}
+
+// pluginApp is a helper that constructs an application whose "frob"
+// command is a plugin advertising flags.
+func pluginApp(stdout *bytes.Buffer) *App {
+	return &App{
+		Name:   "app",
+		Stdout: stdout,
+		Root: &Command{
+			DynamicSubcommands: func(subs Subcommands) {
+				subs["frob"] = flagPlugin()
+			},
+		},
+	}
+}
+
+func TestPluginFlagsHelp(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := pluginApp(stdout)
+
+	err := obj.Dispatch(context.Background(), []string{"help", "frob"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Usage: app frob [flags]")
+	assert.Contains(t, stdout.String(), "-a, --all")
+	assert.Contains(t, stdout.String(), "Frob all")
+	assert.Contains(t, stdout.String(), "--format")
+}
+
+func TestPluginFlagsSpec(t *testing.T) {
+	obj := pluginApp(&bytes.Buffer{})
+
+	result := obj.Spec()
+
+	require.Len(t, result.Subcommands, 1)
+	frob := result.Subcommands[0]
+	assert.Equal(t, "frob", frob.Name)
+	assert.Equal(t, "Frob things", frob.Summary)
+	require.Len(t, frob.Flags, 3)
+	assert.Equal(t, "all", frob.Flags[0].Name)
+	assert.Equal(t, "", frob.Flags[0].Env)
+	assert.Equal(t, []string{"json", "text"}, frob.Flags[1].Choices)
+	assert.Equal(t, CompleteFile, frob.Flags[2].Complete)
+}
+
+func TestPluginFlagsComplete(t *testing.T) {
+	obj := pluginApp(&bytes.Buffer{})
+
+	result, err := obj.Complete(context.Background(), []string{"frob", "--f"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Candidate{{Value: "--format", Description: "Output format"}}, result)
+}
+
+func TestPluginFlagsCompleteChoices(t *testing.T) {
+	obj := pluginApp(&bytes.Buffer{})
+
+	result, err := obj.Complete(context.Background(), []string{"frob", "--format", ""})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Candidate{{Value: "json"}, {Value: "text"}}, result)
+}
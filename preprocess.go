@@ -0,0 +1,81 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/klmitch/nelson/internal/shlex"
+)
+
+// Preprocessor transforms the command line arguments before they are
+// parsed.  Preprocessors are registered with App.Preprocess.
+type Preprocessor func(args []string) ([]string, error)
+
+// Preprocess adds preprocessors to the application.  Preprocessors
+// are applied to the command line arguments in the order added,
+// before the arguments are parsed.  Returns the App, to allow
+// chaining.
+func (a *App) Preprocess(pp ...Preprocessor) *App {
+	a.preprocessors = append(a.preprocessors, pp...)
+	return a
+}
+
+// preprocess is a helper that applies the preprocessors to the
+// arguments.
+func (a *App) preprocess(args []string) ([]string, error) {
+	for _, pp := range a.preprocessors {
+		var err error
+		if args, err = pp(args); err != nil {
+			return nil, err
+		}
+	}
+
+	return args, nil
+}
+
+// ExpandArgFiles is a Preprocessor that replaces each argument of
+// the form "@path/to/file" with the words contained in that file.
+// Words are separated by whitespace, and may be quoted as in a POSIX
+// shell.  Arguments following "--" are not expanded, nor is a lone
+// "@".
+func ExpandArgFiles(args []string) ([]string, error) {
+	result := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+		if len(arg) < 2 || !strings.HasPrefix(arg, "@") {
+			result = append(result, arg)
+			continue
+		}
+
+		// Read and split the file
+		content, err := ioutil.ReadFile(arg[1:])
+		if err != nil {
+			return nil, err
+		}
+		words, err := shlex.Split(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", arg[1:], err)
+		}
+		result = append(result, words...)
+	}
+
+	return result, nil
+}
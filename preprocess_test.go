@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/klmitch/nelson/internal/shlex"
+)
+
+func makeArgFile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "nelson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "args")
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestAppPreprocess(t *testing.T) {
+	pp := func(args []string) ([]string, error) { return args, nil }
+	obj := &App{}
+
+	result := obj.Preprocess(pp, pp)
+
+	assert.Same(t, obj, result)
+	assert.Len(t, obj.preprocessors, 2)
+}
+
+func TestAppPreprocessApply(t *testing.T) {
+	obj := &App{
+		preprocessors: []Preprocessor{
+			func(args []string) ([]string, error) { return append(args, "first"), nil },
+			func(args []string) ([]string, error) { return append(args, "second"), nil },
+		},
+	}
+
+	result, err := obj.preprocess([]string{"a1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1", "first", "second"}, result)
+}
+
+func TestAppPreprocessApplyError(t *testing.T) {
+	obj := &App{
+		preprocessors: []Preprocessor{
+			func(args []string) ([]string, error) { return nil, assert.AnError },
+			func(args []string) ([]string, error) { return append(args, "second"), nil },
+		},
+	}
+
+	result, err := obj.preprocess([]string{"a1"})
+
+	assert.Same(t, assert.AnError, err)
+	assert.Nil(t, result)
+}
+
+func TestExpandArgFiles(t *testing.T) {
+	path := makeArgFile(t, "one 'two three'\n\"four\"\n")
+
+	result, err := ExpandArgFiles([]string{"a1", "@" + path, "@", "a2", "--", "@" + path})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1", "one", "two three", "four", "@", "a2", "--", "@" + path}, result)
+}
+
+func TestExpandArgFilesMissing(t *testing.T) {
+	path := makeArgFile(t, "")
+
+	result, err := ExpandArgFiles([]string{"@" + path + ".missing"})
+
+	assert.True(t, os.IsNotExist(err))
+	assert.Nil(t, result)
+}
+
+func TestExpandArgFilesBadQuoting(t *testing.T) {
+	path := makeArgFile(t, "one 'two")
+
+	result, err := ExpandArgFiles([]string{"@" + path})
+
+	assert.ErrorIs(t, err, shlex.ErrUnterminated)
+	assert.True(t, strings.HasPrefix(err.Error(), path+": "))
+	assert.Nil(t, result)
+}
+
+func TestAppDispatchPreprocess(t *testing.T) {
+	path := makeArgFile(t, "a2 a3")
+	root := newRunCommand("Root command", nil)
+	root.On("Run", Args{"a1", "a2", "a3"}, mock.Anything).Return(nil)
+	obj := New("app").WithRoot(root).Preprocess(ExpandArgFiles)
+
+	err := obj.Dispatch(context.Background(), []string{"a1", "@" + path})
+
+	assert.NoError(t, err)
+	root.AssertExpectations(t)
+}
+
+func TestAppDispatchPreprocessError(t *testing.T) {
+	root := newRunCommand("Root command", nil)
+	obj := New("app").WithRoot(root).Preprocess(func([]string) ([]string, error) {
+		return nil, assert.AnError
+	})
+
+	err := obj.Dispatch(context.Background(), []string{"a1"})
+
+	assert.Same(t, assert.AnError, err)
+	root.AssertExpectations(t)
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPrivilege indicates that a command requires a privilege that
+// the process does not hold.
+var ErrPrivilege = errors.New("insufficient privileges")
+
+// ErrUnknownCapability indicates that a capability required by a
+// command is not known.
+var ErrUnknownCapability = errors.New("unknown capability")
+
+// PrivilegeExitCode is the exit code used when a command is refused
+// because the process lacks a privilege it requires, following the
+// EX_NOPERM convention of sysexits.h.
+const PrivilegeExitCode = 77
+
+// Privilege describes a privilege that a command may require; see
+// Requires.
+type Privilege struct {
+	Name      string               // Description of the privilege, as in "root privileges"
+	Elevation string               // How to obtain the privilege, as in "run it as root, e.g., with sudo"
+	Held      func() (bool, error) // Reports whether the process holds the privilege
+}
+
+// Admin returns the Privilege of the system administrator: root on
+// Unix-like systems, or an elevated Administrator token on Windows.
+func Admin() Privilege {
+	return adminPrivilege()
+}
+
+// Capability returns the Privilege of holding the Linux capability
+// with the specified name, such as "CAP_NET_ADMIN" or "net_admin", in
+// the effective set of the process.  On other systems, which lack
+// capabilities, it is equivalent to Admin.
+func Capability(name string) Privilege {
+	return capabilityPrivilege(strings.ToUpper(strings.TrimPrefix(strings.ToLower(name), "cap_")))
+}
+
+// IRequires is an optional interface for commands that require
+// privileges to run.  The dispatcher refuses to run a command if the
+// process lacks any privilege required by the command or by any
+// command on the path to it.  Commands may implement it directly, or
+// be wrapped with Requires.
+type IRequires interface {
+	// GetRequirements retrieves the privileges the command
+	// requires.
+	GetRequirements() []Privilege
+}
+
+// RequiresCommand wraps a command, declaring the privileges it
+// requires.
+type RequiresCommand struct {
+	Wrapped    ICommand    // Wrapped command
+	Privileges []Privilege // The privileges the command requires
+}
+
+// Requires wraps a command to declare the privileges it requires, as
+// in:
+//
+//	nelson.Requires(cmd, nelson.Capability("CAP_NET_ADMIN"))
+func Requires(cmd ICommand, privs ...Privilege) *RequiresCommand {
+	return &RequiresCommand{
+		Wrapped:    cmd,
+		Privileges: privs,
+	}
+}
+
+// GetSummary retrieves the command summary.
+func (c *RequiresCommand) GetSummary() string {
+	return c.Wrapped.GetSummary()
+}
+
+// GetDescription retrieves the command's full description.
+func (c *RequiresCommand) GetDescription() string {
+	return c.Wrapped.GetDescription()
+}
+
+// GetGroup retrieves the group name of the command.
+func (c *RequiresCommand) GetGroup() string {
+	return c.Wrapped.GetGroup()
+}
+
+// GetSubcommands retrieves subcommands for this command.
+func (c *RequiresCommand) GetSubcommands() map[string]ICommand {
+	return c.Wrapped.GetSubcommands()
+}
+
+// GetDefaults retrieves the defaults for arguments for this command.
+func (c *RequiresCommand) GetDefaults() interface{} {
+	return c.Wrapped.GetDefaults()
+}
+
+// Unwrap returns the wrapped command.
+func (c *RequiresCommand) Unwrap() ICommand {
+	return c.Wrapped
+}
+
+// GetRequirements retrieves the privileges the command requires.
+func (c *RequiresCommand) GetRequirements() []Privilege {
+	return c.Privileges
+}
+
+// GetRequirements is a helper that retrieves the privileges required
+// by a command.  It examines the command and any commands it wraps,
+// combining their requirements.
+func GetRequirements(cmd ICommand) []Privilege {
+	var privs []Privilege
+	for cmd != nil {
+		if tmp, ok := cmd.(IRequires); ok {
+			privs = append(privs, tmp.GetRequirements()...)
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return privs
+}
+
+// CheckPrivileges checks that the process holds the privileges.  If
+// it lacks one, a CommandError wrapping ErrPrivilege, with the
+// PrivilegeExitCode, is returned, explaining what elevation is
+// needed.  The name describes what requires the privileges, as in
+// `command "tool up"`.
+func CheckPrivileges(name string, privs ...Privilege) error {
+	for _, priv := range privs {
+		held, err := priv.Held()
+		if err != nil {
+			return fmt.Errorf("unable to check for %s: %w", priv.Name, err)
+		}
+		if held {
+			continue
+		}
+
+		msg := fmt.Sprintf("%s requires %s", name, priv.Name)
+		if priv.Elevation != "" {
+			msg += "; " + priv.Elevation
+		}
+		return &CommandError{
+			Err:  fmt.Errorf("%w: %s", ErrPrivilege, msg),
+			Code: PrivilegeExitCode,
+		}
+	}
+
+	return nil
+}
+
+// checkPrivileges is a helper that checks the privileges required by
+// the commands along the path of the invocation.
+func checkPrivileges(inv *Invocation) error {
+	for i, cmd := range inv.Commands {
+		name := fmt.Sprintf("command %q", strings.Join(inv.Path[:i+1], " "))
+		if err := CheckPrivileges(name, GetRequirements(cmd)...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
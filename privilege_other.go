@@ -0,0 +1,133 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package nelson
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// procStatus is the file describing the capabilities of the process
+// on Linux; it is a hook for testing.
+var procStatus = "/proc/self/status"
+
+// capabilities maps the names of Linux capabilities, without the
+// "CAP_" prefix, to their bit numbers.
+var capabilities = map[string]uint{
+	"CHOWN":              0,
+	"DAC_OVERRIDE":       1,
+	"DAC_READ_SEARCH":    2,
+	"FOWNER":             3,
+	"FSETID":             4,
+	"KILL":               5,
+	"SETGID":             6,
+	"SETUID":             7,
+	"SETPCAP":            8,
+	"LINUX_IMMUTABLE":    9,
+	"NET_BIND_SERVICE":   10,
+	"NET_BROADCAST":      11,
+	"NET_ADMIN":          12,
+	"NET_RAW":            13,
+	"IPC_LOCK":           14,
+	"IPC_OWNER":          15,
+	"SYS_MODULE":         16,
+	"SYS_RAWIO":          17,
+	"SYS_CHROOT":         18,
+	"SYS_PTRACE":         19,
+	"SYS_PACCT":          20,
+	"SYS_ADMIN":          21,
+	"SYS_BOOT":           22,
+	"SYS_NICE":           23,
+	"SYS_RESOURCE":       24,
+	"SYS_TIME":           25,
+	"SYS_TTY_CONFIG":     26,
+	"MKNOD":              27,
+	"LEASE":              28,
+	"AUDIT_WRITE":        29,
+	"AUDIT_CONTROL":      30,
+	"SETFCAP":            31,
+	"MAC_OVERRIDE":       32,
+	"MAC_ADMIN":          33,
+	"SYSLOG":             34,
+	"WAKE_ALARM":         35,
+	"BLOCK_SUSPEND":      36,
+	"AUDIT_READ":         37,
+	"PERFMON":            38,
+	"BPF":                39,
+	"CHECKPOINT_RESTORE": 40,
+}
+
+// adminPrivilege returns the Privilege of the superuser.
+func adminPrivilege() Privilege {
+	return Privilege{
+		Name:      "root privileges",
+		Elevation: "run it as root, e.g., with sudo",
+		Held: func() (bool, error) {
+			return os.Geteuid() == 0, nil
+		},
+	}
+}
+
+// capabilityPrivilege returns the Privilege of holding the Linux
+// capability with the specified name, without the "CAP_" prefix.
+// Other systems lack capabilities, so the superuser is required
+// instead.
+func capabilityPrivilege(name string) Privilege {
+	if runtime.GOOS != "linux" {
+		return adminPrivilege()
+	}
+
+	return Privilege{
+		Name:      "the CAP_" + name + " capability",
+		Elevation: "grant it to the program, e.g., with setcap, or run it as root",
+		Held: func() (bool, error) {
+			return hasCapability(name)
+		},
+	}
+}
+
+// hasCapability is a helper that determines whether the Linux
+// capability with the specified name is in the effective set of the
+// process.
+func hasCapability(name string) (bool, error) {
+	bit, ok := capabilities[name]
+	if !ok {
+		return false, fmt.Errorf("%w CAP_%s", ErrUnknownCapability, name)
+	}
+	data, err := ioutil.ReadFile(procStatus)
+	if err != nil {
+		return false, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if text := strings.TrimPrefix(line, "CapEff:"); text != line {
+			mask, err := strconv.ParseUint(strings.TrimSpace(text), 16, 64)
+			if err != nil {
+				return false, err
+			}
+			return mask&(1<<bit) != 0, nil
+		}
+	}
+
+	return false, errors.New("effective capabilities not reported")
+}
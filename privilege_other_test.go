@@ -0,0 +1,115 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package nelson
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProcStatus is a helper that sets the file describing the
+// capabilities of the process for the duration of a test.
+func fakeProcStatus(t *testing.T, content string) {
+	path := filepath.Join(t.TempDir(), "status")
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := procStatus
+	procStatus = path
+	t.Cleanup(func() { procStatus = old })
+}
+
+func TestAdmin(t *testing.T) {
+	obj := Admin()
+
+	result, err := obj.Held()
+
+	assert.NoError(t, err)
+	assert.Equal(t, os.Geteuid() == 0, result)
+	assert.Equal(t, "root privileges", obj.Name)
+}
+
+func TestCapability(t *testing.T) {
+	obj := Capability("CAP_NET_ADMIN")
+
+	if runtime.GOOS == "linux" {
+		assert.Equal(t, "the CAP_NET_ADMIN capability", obj.Name)
+	} else {
+		assert.Equal(t, "root privileges", obj.Name)
+	}
+}
+
+func TestHasCapabilityHeld(t *testing.T) {
+	fakeProcStatus(t, "Name:\ttool\nCapInh:\t0000000000000000\nCapEff:\t0000000000001000\n")
+
+	result, err := hasCapability("NET_ADMIN")
+
+	assert.NoError(t, err)
+	assert.True(t, result)
+}
+
+func TestHasCapabilityNotHeld(t *testing.T) {
+	fakeProcStatus(t, "CapEff:\t0000000000001000\n")
+
+	result, err := hasCapability("NET_RAW")
+
+	assert.NoError(t, err)
+	assert.False(t, result)
+}
+
+func TestHasCapabilityUnknown(t *testing.T) {
+	result, err := hasCapability("MAGIC")
+
+	assert.ErrorIs(t, err, ErrUnknownCapability)
+	assert.EqualError(t, err, "unknown capability CAP_MAGIC")
+	assert.False(t, result)
+}
+
+func TestHasCapabilityMissingFile(t *testing.T) {
+	old := procStatus
+	procStatus = filepath.Join(t.TempDir(), "missing")
+	t.Cleanup(func() { procStatus = old })
+
+	result, err := hasCapability("NET_ADMIN")
+
+	assert.Error(t, err)
+	assert.False(t, result)
+}
+
+func TestHasCapabilityBadMask(t *testing.T) {
+	fakeProcStatus(t, "CapEff:\tbogus\n")
+
+	result, err := hasCapability("NET_ADMIN")
+
+	assert.Error(t, err)
+	assert.False(t, result)
+}
+
+func TestHasCapabilityNotReported(t *testing.T) {
+	fakeProcStatus(t, "Name:\ttool\n")
+
+	result, err := hasCapability("NET_ADMIN")
+
+	assert.EqualError(t, err, "effective capabilities not reported")
+	assert.False(t, result)
+}
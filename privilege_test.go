@@ -0,0 +1,244 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// heldPrivilege is a helper that returns a Privilege that is or is
+// not held.
+func heldPrivilege(name string, held bool) Privilege {
+	return Privilege{
+		Name:      name,
+		Elevation: "ask nicely",
+		Held: func() (bool, error) {
+			return held, nil
+		},
+	}
+}
+
+func TestRequiresCommandImplementsICommand(t *testing.T) {
+	assert.Implements(t, (*ICommand)(nil), &RequiresCommand{})
+}
+
+func TestRequiresCommandImplementsIWrapped(t *testing.T) {
+	assert.Implements(t, (*IWrapped)(nil), &RequiresCommand{})
+}
+
+func TestRequiresCommandImplementsIRequires(t *testing.T) {
+	assert.Implements(t, (*IRequires)(nil), &RequiresCommand{})
+}
+
+func TestRequires(t *testing.T) {
+	cmd := &mockICommand{}
+	priv := heldPrivilege("root", true)
+
+	result := Requires(cmd, priv)
+
+	assert.Same(t, cmd, result.Wrapped)
+	assert.Len(t, result.Privileges, 1)
+	assert.Equal(t, "root", result.Privileges[0].Name)
+}
+
+func TestRequiresCommandGetSummary(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetSummary").Return("some text")
+	obj := &RequiresCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetSummary()
+
+	assert.Equal(t, "some text", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestRequiresCommandGetDescription(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetDescription").Return("some text")
+	obj := &RequiresCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetDescription()
+
+	assert.Equal(t, "some text", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestRequiresCommandGetGroup(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetGroup").Return("some text")
+	obj := &RequiresCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetGroup()
+
+	assert.Equal(t, "some text", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestRequiresCommandGetSubcommands(t *testing.T) {
+	subs := map[string]ICommand{
+		"sub": &mockICommand{},
+	}
+	cmd := &mockICommand{}
+	cmd.On("GetSubcommands").Return(subs)
+	obj := &RequiresCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetSubcommands()
+
+	assert.Equal(t, subs, result)
+	cmd.AssertExpectations(t)
+}
+
+func TestRequiresCommandGetDefaults(t *testing.T) {
+	cmd := &mockICommand{}
+	cmd.On("GetDefaults").Return("defaults")
+	obj := &RequiresCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.GetDefaults()
+
+	assert.Equal(t, "defaults", result)
+	cmd.AssertExpectations(t)
+}
+
+func TestRequiresCommandUnwrap(t *testing.T) {
+	cmd := &mockICommand{}
+	obj := &RequiresCommand{
+		Wrapped: cmd,
+	}
+
+	result := obj.Unwrap()
+
+	assert.Same(t, cmd, result)
+}
+
+func TestGetRequirements(t *testing.T) {
+	cmd := Hidden(Requires(Requires(&mockICommand{}, heldPrivilege("inner", true)), heldPrivilege("outer", true)))
+
+	result := GetRequirements(cmd)
+
+	assert.Len(t, result, 2)
+	assert.Equal(t, "outer", result[0].Name)
+	assert.Equal(t, "inner", result[1].Name)
+}
+
+func TestGetRequirementsNone(t *testing.T) {
+	result := GetRequirements(&mockICommand{})
+
+	assert.Nil(t, result)
+}
+
+func TestCheckPrivilegesHeld(t *testing.T) {
+	err := CheckPrivileges("command", heldPrivilege("a", true), heldPrivilege("b", true))
+
+	assert.NoError(t, err)
+}
+
+func TestCheckPrivilegesMissing(t *testing.T) {
+	err := CheckPrivileges(`command "tool up"`, heldPrivilege("a", true), heldPrivilege("root privileges", false))
+
+	assert.ErrorIs(t, err, ErrPrivilege)
+	assert.EqualError(t, err, `insufficient privileges: command "tool up" requires root privileges; ask nicely`)
+	code, usage := ExitControl(err)
+	assert.Equal(t, PrivilegeExitCode, code)
+	assert.False(t, usage)
+}
+
+func TestCheckPrivilegesNoElevation(t *testing.T) {
+	priv := heldPrivilege("root privileges", false)
+	priv.Elevation = ""
+
+	err := CheckPrivileges("command", priv)
+
+	assert.EqualError(t, err, "insufficient privileges: command requires root privileges")
+}
+
+func TestCheckPrivilegesError(t *testing.T) {
+	priv := Privilege{
+		Name: "magic",
+		Held: func() (bool, error) {
+			return false, assert.AnError
+		},
+	}
+
+	err := CheckPrivileges("command", priv)
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NotErrorIs(t, err, ErrPrivilege)
+}
+
+func TestCapabilityName(t *testing.T) {
+	assert.Equal(t, Capability("CAP_NET_ADMIN").Name, Capability("net_admin").Name)
+}
+
+// privilegeApp is a helper that constructs an application with a
+// command requiring a privilege.
+func privilegeApp(stdout *bytes.Buffer, held bool) (*App, *runCommand) {
+	sub := newRunCommand("Bring the network up", nil)
+	return &App{
+		Name:   "tool",
+		Stdout: stdout,
+		Root: &Command{
+			Subcommands: map[string]ICommand{
+				"net": Requires(&Command{
+					Subcommands: map[string]ICommand{"up": sub},
+				}, heldPrivilege("root privileges", held)),
+			},
+		},
+	}, sub
+}
+
+func TestAppDispatchPrivilegeMissing(t *testing.T) {
+	obj, sub := privilegeApp(nil, false)
+
+	err := obj.Dispatch(context.Background(), []string{"net", "up"})
+
+	assert.EqualError(t, err, `insufficient privileges: command "tool net" requires root privileges; ask nicely`)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchPrivilegeHeld(t *testing.T) {
+	obj, sub := privilegeApp(nil, true)
+	sub.On("Run", Args{}, mock.Anything).Return(nil)
+
+	err := obj.Dispatch(context.Background(), []string{"net", "up"})
+
+	assert.NoError(t, err)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchPrivilegeHelp(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj, sub := privilegeApp(stdout, false)
+
+	err := obj.Dispatch(context.Background(), []string{"net", "up", "--help"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Bring the network up")
+	sub.AssertExpectations(t)
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package nelson
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// tokenElevation is the TokenElevation information class of the
+// GetTokenInformation function of the Windows API.
+const tokenElevation = 20
+
+// adminPrivilege returns the Privilege of an elevated Administrator.
+func adminPrivilege() Privilege {
+	return Privilege{
+		Name:      "Administrator privileges",
+		Elevation: `run it from an elevated prompt, e.g., with "Run as administrator"`,
+		Held:      isElevated,
+	}
+}
+
+// capabilityPrivilege returns the Privilege corresponding to a Linux
+// capability.  Windows lacks capabilities, so an elevated
+// Administrator is required instead.
+func capabilityPrivilege(name string) Privilege {
+	return adminPrivilege()
+}
+
+// isElevated determines whether the process has an elevated token.
+func isElevated() (bool, error) {
+	token, err := syscall.OpenCurrentProcessToken()
+	if err != nil {
+		return false, err
+	}
+	defer token.Close()
+
+	var elevation, size uint32
+	if err := syscall.GetTokenInformation(token, tokenElevation, (*byte)(unsafe.Pointer(&elevation)), uint32(unsafe.Sizeof(elevation)), &size); err != nil {
+		return false, err
+	}
+
+	return elevation != 0, nil
+}
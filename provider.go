@@ -0,0 +1,180 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ErrConfigProvider indicates that a configuration provider failed
+// to load its configuration.
+var ErrConfigProvider = errors.New("configuration provider failed")
+
+// ConfigProvider describes a source of configuration other than the
+// application's configuration files, such as a central configuration
+// service or a secret mounted into a container.  The configuration
+// returned by each provider is merged over the configuration files,
+// in the order in which the providers are given; see
+// WithConfigProviders.  Flags given on the command line and in the
+// environment still take precedence over the providers.
+type ConfigProvider interface {
+	// Load loads the configuration.  Sections of the configuration
+	// may be map[string]interface{} or
+	// map[interface{}]interface{}.  A nil configuration is
+	// ignored.
+	Load(ctx context.Context) (map[string]interface{}, error)
+}
+
+// FileProvider is a ConfigProvider that loads a configuration file,
+// such as a secret mounted into a container.  Unlike the
+// application's configuration files, the file must exist, unless
+// Optional is set.
+type FileProvider struct {
+	Path     string // Path of the file
+	Format   string // Format of the file; see WithConfigFormat
+	Optional bool   // If true, a missing file is ignored
+}
+
+// Load loads the configuration file.
+func (p *FileProvider) Load(ctx context.Context) (map[string]interface{}, error) {
+	if _, err := os.Stat(p.Path); os.IsNotExist(err) {
+		if p.Optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrMissingConfig, p.Path)
+	}
+
+	return loadConfig(p.Path, p.Format)
+}
+
+// HTTPProvider is a ConfigProvider that fetches the configuration
+// from an HTTP endpoint with a GET request.  Responses with a status
+// other than 2xx are errors.  Unless Format is set, the format of the
+// response is selected by its Content-Type--"application/json" and
+// "application/toml" select ConfigJSON and ConfigTOML,
+// respectively--and failing that, by the extension of the URL's path,
+// as for configuration files.
+type HTTPProvider struct {
+	URL    string       // URL of the configuration
+	Format string       // Format of the response; see WithConfigFormat
+	Client *http.Client // Client making the request; defaults to http.DefaultClient
+	Header http.Header  // Optional headers of the request, e.g., for authorization
+}
+
+// Load fetches the configuration.
+func (p *HTTPProvider) Load(ctx context.Context) (map[string]interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, p.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for name, values := range p.Header {
+		req.Header[name] = values
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s: %s", p.URL, resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	format := p.format(resp.Header.Get("Content-Type"))
+	decode, ok := configDecoders[format]
+	if !ok {
+		return nil, fmt.Errorf("%w %s: unknown format %q", ErrBadConfig, p.URL, format)
+	}
+	cfg, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %s", ErrBadConfig, p.URL, err)
+	}
+
+	return cfg, nil
+}
+
+// format is a helper that selects the format of the response, given
+// its content type.
+func (p *HTTPProvider) format(contentType string) string {
+	if p.Format != "" {
+		return p.Format
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch {
+		case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+			return ConfigJSON
+		case mediaType == "application/toml":
+			return ConfigTOML
+		case strings.HasSuffix(mediaType, "yaml"):
+			return ConfigYAML
+		}
+	}
+
+	path := p.URL
+	if u, err := url.Parse(p.URL); err == nil {
+		path = u.Path
+	}
+
+	return configFormat(path, "")
+}
+
+// WithConfigProviders sets the configuration providers of the
+// application.  The configuration each provider loads is merged, in
+// order, over the configuration files, including the file named by
+// the ConfigFlag.
+func (a *App) WithConfigProviders(providers ...ConfigProvider) *App {
+	a.ConfigProviders = providers
+	return a
+}
+
+// loadProviders is a helper that merges the configuration loaded by
+// the application's providers into the configuration, which is
+// returned.  Errors are wrapped in ErrConfigProvider.  The providers
+// are reported to the logger, if any.
+func (a *App) loadProviders(ctx context.Context, cfg map[string]interface{}, log *Logger) (map[string]interface{}, error) {
+	for _, provider := range a.ConfigProviders {
+		log.Debugf("loading configuration from provider %T", provider)
+		loaded, err := provider.Load(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrConfigProvider, err)
+		}
+		if loaded == nil {
+			continue
+		}
+		if cfg == nil {
+			cfg = map[string]interface{}{}
+		}
+		mergeConfig(cfg, normalizeConfig(loaded).(map[string]interface{}))
+	}
+
+	return cfg, nil
+}
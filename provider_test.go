@@ -0,0 +1,303 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type providerFunc func(ctx context.Context) (map[string]interface{}, error)
+
+func (f providerFunc) Load(ctx context.Context) (map[string]interface{}, error) {
+	return f(ctx)
+}
+
+func TestFileProviderLoad(t *testing.T) {
+	path := makeArgFile(t, "name: secret\n")
+	obj := &FileProvider{Path: path}
+
+	result, err := obj.Load(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "secret"}, result)
+}
+
+func TestFileProviderLoadFormat(t *testing.T) {
+	path := makeArgFile(t, "name = \"secret\"\n")
+	obj := &FileProvider{Path: path, Format: ConfigTOML}
+
+	result, err := obj.Load(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "secret"}, result)
+}
+
+func TestFileProviderLoadMissing(t *testing.T) {
+	obj := &FileProvider{Path: filepath.Join(t.TempDir(), "missing")}
+
+	result, err := obj.Load(context.Background())
+
+	assert.ErrorIs(t, err, ErrMissingConfig)
+	assert.Nil(t, result)
+}
+
+func TestFileProviderLoadMissingOptional(t *testing.T) {
+	obj := &FileProvider{Path: filepath.Join(t.TempDir(), "missing"), Optional: true}
+
+	result, err := obj.Load(context.Background())
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestHTTPProviderLoad(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"name": "remote", "port": 8080}`))
+	}))
+	defer srv.Close()
+	obj := &HTTPProvider{
+		URL:    srv.URL + "/config",
+		Header: http.Header{"Authorization": []string{"Bearer token"}},
+	}
+
+	result, err := obj.Load(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name": "remote",
+		"port": json.Number("8080"),
+	}, result)
+}
+
+func TestHTTPProviderLoadExtension(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("name = \"remote\"\n"))
+	}))
+	defer srv.Close()
+	obj := &HTTPProvider{URL: srv.URL + "/config.toml?version=2", Client: srv.Client()}
+
+	result, err := obj.Load(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "remote"}, result)
+}
+
+func TestHTTPProviderLoadStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	obj := &HTTPProvider{URL: srv.URL}
+
+	result, err := obj.Load(context.Background())
+
+	assert.EqualError(t, err, srv.URL+": 404 Not Found")
+	assert.Nil(t, result)
+}
+
+func TestHTTPProviderLoadBadConfig(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("- a\n"))
+	}))
+	defer srv.Close()
+	obj := &HTTPProvider{URL: srv.URL}
+
+	result, err := obj.Load(context.Background())
+
+	assert.ErrorIs(t, err, ErrBadConfig)
+	assert.Nil(t, result)
+}
+
+func TestHTTPProviderLoadUnknownFormat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("name: remote\n"))
+	}))
+	defer srv.Close()
+	obj := &HTTPProvider{URL: srv.URL, Format: "ini"}
+
+	result, err := obj.Load(context.Background())
+
+	assert.ErrorIs(t, err, ErrBadConfig)
+	assert.Nil(t, result)
+}
+
+func TestHTTPProviderLoadCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	obj := &HTTPProvider{URL: srv.URL}
+
+	result, err := obj.Load(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, result)
+}
+
+func TestHTTPProviderFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		url         string
+		contentType string
+		expected    string
+	}{
+		{"Explicit", ConfigTOML, "http://host/config.json", "application/json", ConfigTOML},
+		{"JSON", "", "http://host/config", "application/json", ConfigJSON},
+		{"JSONSuffix", "", "http://host/config", "application/vnd.config+json", ConfigJSON},
+		{"TOML", "", "http://host/config", "application/toml", ConfigTOML},
+		{"YAML", "", "http://host/config.json", "application/yaml", ConfigYAML},
+		{"Extension", "", "http://host/config.json", "text/plain", ConfigJSON},
+		{"Default", "", "http://host/config", "", ConfigYAML},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			obj := &HTTPProvider{URL: test.url, Format: test.format}
+
+			result := obj.format(test.contentType)
+
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestAppWithConfigProviders(t *testing.T) {
+	provider := &FileProvider{Path: "a"}
+	obj := &App{}
+
+	result := obj.WithConfigProviders(provider)
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, []ConfigProvider{provider}, obj.ConfigProviders)
+}
+
+func TestAppConfigProviders(t *testing.T) {
+	path := makeArgFile(t, "verbose: true\nsub:\n  name: file\n  other: file\n")
+	var received context.Context
+	obj := (&App{}).WithConfigFile(path).WithConfigProviders(
+		providerFunc(func(ctx context.Context) (map[string]interface{}, error) {
+			received = ctx
+			return nil, nil
+		}),
+		providerFunc(func(ctx context.Context) (map[string]interface{}, error) {
+			return map[string]interface{}{
+				"sub": map[interface{}]interface{}{"name": "provider"},
+			}, nil
+		}),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result, err := obj.Config(ctx)
+
+	assert.NoError(t, err)
+	assert.Same(t, ctx, received)
+	assert.Equal(t, map[string]interface{}{
+		"verbose": true,
+		"sub": map[string]interface{}{
+			"name":  "provider",
+			"other": "file",
+		},
+	}, result)
+}
+
+func TestAppConfigProvidersOnly(t *testing.T) {
+	obj := (&App{NoConfigSearch: true}).WithConfigProviders(
+		providerFunc(func(ctx context.Context) (map[string]interface{}, error) {
+			return map[string]interface{}{"name": "provider"}, nil
+		}),
+	)
+
+	result, err := obj.Config(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "provider"}, result)
+}
+
+func TestAppConfigProvidersError(t *testing.T) {
+	obj := (&App{NoConfigSearch: true}).WithConfigProviders(
+		providerFunc(func(ctx context.Context) (map[string]interface{}, error) {
+			return nil, errors.New("unavailable")
+		}),
+	)
+
+	result, err := obj.Config(context.Background())
+
+	assert.ErrorIs(t, err, ErrConfigProvider)
+	assert.EqualError(t, err, "configuration provider failed: unavailable")
+	assert.Nil(t, result)
+}
+
+func TestAppDispatchConfigProviders(t *testing.T) {
+	setEnv(t, "TOOL_SUB_NAME", "env")
+	path := makeArgFile(t, "verbose: false\n")
+	sub := newRunCommand("Subcommand", nil)
+	sub.Defaults = &subOptions{}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	obj := (&App{
+		Name:       "tool",
+		Root:       root,
+		ConfigFile: path,
+		EnvPrefix:  "tool",
+	}).WithConfigProviders(providerFunc(func(ctx context.Context) (map[string]interface{}, error) {
+		return map[string]interface{}{
+			"verbose": true,
+			"sub":     map[string]interface{}{"name": "provider"},
+		}, nil
+	}))
+	var inv *Invocation
+	sub.On("Run", Args{"file"}, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		inv = args.Get(1).(*Invocation)
+	})
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "file"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &subOptions{
+		RootOptions: RootOptions{Verbose: true},
+		Name:        "env",
+		File:        "file",
+	}, inv.Options)
+	assert.Equal(t, SourceConfig, inv.Source("--verbose"))
+	assert.Equal(t, SourceEnv, inv.Source("--name"))
+}
+
+func TestAppDispatchConfigProvidersError(t *testing.T) {
+	obj := (&App{Root: &Command{}, NoConfigSearch: true}).WithConfigProviders(
+		providerFunc(func(ctx context.Context) (map[string]interface{}, error) {
+			return nil, errors.New("unavailable")
+		}),
+	)
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.ErrorIs(t, err, ErrConfigProvider)
+}
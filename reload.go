@@ -0,0 +1,178 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultReloadInterval is the interval at which a ConfigReloader
+// checks the configuration files for changes if no interval is set.
+const DefaultReloadInterval = time.Second
+
+// ConfigUpdate describes the result of reloading the configuration.
+type ConfigUpdate struct {
+	Options interface{} // The re-resolved defaults of the command, if the reload succeeded
+	Err     error       // The error that prevented the reload, if any
+}
+
+// ConfigReloader reloads the configuration for long-running
+// commands, such as servers.  It is available from the injector, and
+// reloading is opt-in: a command that wishes to follow changes to the
+// configuration calls Watch.
+type ConfigReloader struct {
+	Interval time.Duration // Interval at which files are checked; defaults to DefaultReloadInterval
+
+	app        *App        // The application
+	inj        *Injector   // Injector used to compute dynamic subcommands
+	args       []string    // The arguments from which the command was resolved
+	configPath string      // Configuration file named by the ConfigFlag, if any
+	lock       sync.Mutex  // Serializes reloads
+	closers    []closeFunc // Closes files opened by the last reload
+}
+
+// closeFunc closes the files opened while resolving a command.
+type closeFunc func() error
+
+// Reload re-reads the configuration and resolves the command again
+// from the same arguments, returning the command's re-validated
+// defaults.  Values are read afresh from the configuration files and
+// the environment, while the command line takes precedence as
+// before.  Standard input is not read again, so flags read from
+// standard input, and secrets that would be prompted for, are left
+// empty; warnings are not repeated.  Files named by flags are opened
+// again, and those opened by the previous reload are closed.  The
+// command is resolved into a clone of the injector, so the running
+// command's injector is left untouched.
+func (r *ConfigReloader) Reload() (interface{}, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	tmp := *r.app
+	tmp.Stdin = strings.NewReader("")
+	tmp.WarningHandler = func(string) {}
+
+	inv, err := tmp.resolve(r.inj.Clone(), r.args, r.configPath)
+	if err != nil {
+		if inv != nil {
+			_ = inv.closeFiles()
+		}
+		return nil, err
+	}
+
+	for _, closer := range r.closers {
+		_ = closer()
+	}
+	r.closers = []closeFunc{inv.closeFiles}
+
+	return inv.Options, nil
+}
+
+// Watch reloads the configuration whenever one of the configuration
+// files changes, as determined by checking its modification time and
+// size at the reloader's interval, or when the process receives
+// SIGHUP, delivering each result on the returned channel.  Watching
+// stops, and the channel is closed, when the context is done.  The
+// channel is unbuffered, so the command should receive from it
+// promptly.
+func (r *ConfigReloader) Watch(ctx context.Context) <-chan ConfigUpdate {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	updates := make(chan ConfigUpdate)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	state := r.fileState()
+
+	go func() {
+		defer close(updates)
+		defer signal.Stop(sigs)
+		defer r.close()
+
+		ticker := r.app.clock().NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigs:
+			case <-ticker.C():
+				if r.fileState() == state {
+					continue
+				}
+			}
+			state = r.fileState()
+
+			// Reload and deliver the update
+			opts, err := r.Reload()
+			select {
+			case updates <- ConfigUpdate{Options: opts, Err: err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return updates
+}
+
+// paths returns the paths of the configuration files.
+func (r *ConfigReloader) paths() []string {
+	if r.configPath != "" {
+		return []string{r.configPath}
+	}
+	if r.app.AllowConfigFlag {
+		if path := os.Getenv(r.app.ConfigEnv()); path != "" {
+			return []string{path}
+		}
+	}
+
+	return r.app.configFiles()
+}
+
+// fileState returns a summary of the modification times and sizes of
+// the configuration files, which changes if any of the files is
+// modified, created, or removed.
+func (r *ConfigReloader) fileState() string {
+	var sb strings.Builder
+	for _, path := range r.paths() {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&sb, "%s %s %d\x00", path, info.ModTime(), info.Size())
+		} else {
+			fmt.Fprintf(&sb, "%s\x00", path)
+		}
+	}
+
+	return sb.String()
+}
+
+// close closes the files opened by the last reload.
+func (r *ConfigReloader) close() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, closer := range r.closers {
+		_ = closer()
+	}
+	r.closers = nil
+}
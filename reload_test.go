@@ -0,0 +1,162 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type reloadCommand struct {
+	Command
+	reloader *ConfigReloader
+}
+
+func (c *reloadCommand) Run(reloader *ConfigReloader) {
+	c.reloader = reloader
+}
+
+// reloadApp is a helper that constructs an application with a
+// command capturing the ConfigReloader, dispatches it, and returns
+// the reloader and the path of the configuration file.
+func reloadApp(t *testing.T, args ...string) (*ConfigReloader, string) {
+	path := filepath.Join(t.TempDir(), "tool.yaml")
+	writeReloadConfig(t, path, "sub:\n  name: first\n")
+	sub := &reloadCommand{
+		Command: Command{Defaults: &subOptions{Name: "default"}},
+	}
+	root := &Command{
+		Defaults:    &RootOptions{},
+		Subcommands: map[string]ICommand{"sub": sub},
+	}
+	obj := (&App{Name: "tool", Root: root}).WithNoConfigSearch(true).WithConfigFile(path).WithGlobals(&globalOptions{})
+
+	err := obj.Dispatch(context.Background(), append([]string{"sub"}, args...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sub.reloader, path
+}
+
+// writeReloadConfig is a helper that writes a configuration file,
+// advancing its modification time so that the change is detected.
+func writeReloadConfig(t *testing.T, path, content string) {
+	if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(time.Duration(len(content)) * time.Second)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigReloaderReload(t *testing.T) {
+	reloader, path := reloadApp(t, "file")
+	writeReloadConfig(t, path, "verbose: true\nsub:\n  name: second\n")
+
+	result, err := reloader.Reload()
+
+	assert.NoError(t, err)
+	assert.Equal(t, &subOptions{
+		RootOptions: RootOptions{Verbose: true},
+		Name:        "second",
+		File:        "file",
+	}, result)
+}
+
+func TestConfigReloaderReloadInjector(t *testing.T) {
+	reloader, _ := reloadApp(t, "file")
+	var before, after *globalOptions
+	reloader.inj.Get(&before)
+
+	_, err := reloader.Reload()
+
+	assert.NoError(t, err)
+	assert.True(t, reloader.inj.Get(&after))
+	assert.Same(t, before, after)
+}
+
+func TestConfigReloaderReloadCommandLine(t *testing.T) {
+	reloader, path := reloadApp(t, "--name", "flag", "file")
+	writeReloadConfig(t, path, "sub:\n  name: second\n")
+
+	result, err := reloader.Reload()
+
+	assert.NoError(t, err)
+	assert.Equal(t, &subOptions{Name: "flag", File: "file"}, result)
+}
+
+func TestConfigReloaderReloadError(t *testing.T) {
+	reloader, path := reloadApp(t, "file")
+	writeReloadConfig(t, path, "- bad\n")
+
+	result, err := reloader.Reload()
+
+	assert.ErrorIs(t, err, ErrBadConfig)
+	assert.Nil(t, result)
+}
+
+func TestConfigReloaderWatchChange(t *testing.T) {
+	reloader, path := reloadApp(t, "file")
+	reloader.Interval = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates := reloader.Watch(ctx)
+	writeReloadConfig(t, path, "sub:\n  name: second\n")
+
+	select {
+	case update := <-updates:
+		assert.NoError(t, update.Err)
+		assert.Equal(t, &subOptions{Name: "second", File: "file"}, update.Options)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update received")
+	}
+	cancel()
+	for range updates {
+	}
+}
+
+func TestConfigReloaderWatchCancel(t *testing.T) {
+	reloader, _ := reloadApp(t, "file")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	updates := reloader.Watch(ctx)
+	cancel()
+
+	select {
+	case _, ok := <-updates:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel not closed")
+	}
+}
+
+func TestConfigReloaderPaths(t *testing.T) {
+	setEnv(t, "TOOL_CONFIG", "env.yaml")
+	obj := &App{Name: "tool", ConfigFile: "file.yaml", NoConfigSearch: true}
+
+	assert.Equal(t, []string{"flag.yaml"}, (&ConfigReloader{app: obj, configPath: "flag.yaml"}).paths())
+	assert.Equal(t, []string{"file.yaml"}, (&ConfigReloader{app: obj}).paths())
+	obj.AllowConfigFlag = true
+	assert.Equal(t, []string{"env.yaml"}, (&ConfigReloader{app: obj}).paths())
+}
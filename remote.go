@@ -0,0 +1,486 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Paths of the endpoints served by RemoteHandler.  Commands are
+// invoked by POSTing a RemoteRequest to RemoteInvokePath, which
+// replies with a RemoteResponse; a GET of RemoteCommandsPath returns
+// the CommandEntry describing the command tree, as JSON.
+const (
+	RemoteInvokePath   = "/invoke"
+	RemoteCommandsPath = "/commands"
+)
+
+// DefaultRemoteAddress is the address on which the command
+// constructed by NewRemoteCommand listens by default: a port chosen
+// by the system on the loopback interface.
+const DefaultRemoteAddress = "127.0.0.1:0"
+
+// RemoteUnixPrefix is the prefix of remote addresses naming a unix
+// domain socket, as in "unix:/run/tool.sock".
+const RemoteUnixPrefix = "unix:"
+
+// remoteOutput is the name of the output format that captures the
+// results of remotely invoked commands.
+const remoteOutput = "remote"
+
+// maxRemoteRequest is the largest request body accepted by
+// RemoteHandler.
+const maxRemoteRequest = 1 << 20
+
+// ErrBadRemoteRequest indicates that a remote request could not be
+// converted into the arguments of a command.
+var ErrBadRemoteRequest = errors.New("bad remote request")
+
+// WithRemoteToken sets the bearer token that RemoteHandler requires
+// of every request, in an "Authorization: Bearer TOKEN" header.  If
+// no token is set, any process able to connect to the handler may
+// run every command with the privileges of the server.  Returns the
+// App, to allow chaining.
+func (a *App) WithRemoteToken(token string) *App {
+	a.RemoteToken = token
+	return a
+}
+
+// RemoteRequest describes a command to be invoked remotely.  The
+// command is invoked with the arguments that would be given on the
+// command line: the command path, followed by the options, then the
+// positional arguments.  If the command parses flags, the positional
+// arguments follow "--", so that they are never taken for flags.
+type RemoteRequest struct {
+	Command []string               `json:"command"`           // Names of the commands along the path, excluding the application
+	Options map[string]interface{} `json:"options,omitempty"` // Flag values, by long flag name
+	Args    []string               `json:"args,omitempty"`    // Positional arguments
+	Stdin   string                 `json:"stdin,omitempty"`   // Standard input of the command
+}
+
+// RemoteResponse describes the outcome of a remotely invoked
+// command.  The output streams are captured, except for the results
+// the command returns, which are rendered as JSON in Result instead;
+// see Output.
+type RemoteResponse struct {
+	ExitCode int             `json:"exit_code"`        // Exit code of the command; see ExitControl
+	Result   json.RawMessage `json:"result,omitempty"` // Result rendered by the command, if any
+	Stdout   string          `json:"stdout,omitempty"` // Standard output of the command
+	Stderr   string          `json:"stderr,omitempty"` // Standard error of the command, including any usage
+	Error    *RemoteError    `json:"error,omitempty"`  // The error returned by the command, if any
+}
+
+// RemoteError describes the error returned by a remotely invoked
+// command.
+type RemoteError struct {
+	Message string `json:"message"`         // The error message
+	Usage   bool   `json:"usage,omitempty"` // True if the error is a usage error
+}
+
+// argv returns the arguments given to the application for the
+// request.  Options are given in sorted order, as "--name=value";
+// boolean options that are true are given as just "--name", and
+// lists give the flag once for each element.  If separate is true,
+// the positional arguments follow "--".
+func (r *RemoteRequest) argv(separate bool) ([]string, error) {
+	for _, name := range r.Command {
+		if strings.HasPrefix(name, "-") {
+			return nil, fmt.Errorf("%w: %q is not a command name", ErrBadRemoteRequest, name)
+		}
+	}
+	args := append([]string(nil), r.Command...)
+
+	names := make([]string, 0, len(r.Options))
+	for name := range r.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		flag := name
+		if !strings.HasPrefix(flag, "-") {
+			flag = "--" + flag
+		}
+		values, ok := r.Options[name].([]interface{})
+		if !ok {
+			values = []interface{}{r.Options[name]}
+		}
+		for _, value := range values {
+			switch v := value.(type) {
+			case bool:
+				if v {
+					args = append(args, flag)
+				} else {
+					args = append(args, flag+"=false")
+				}
+			case string, json.Number, float64:
+				args = append(args, fmt.Sprintf("%s=%v", flag, v))
+			default:
+				return nil, fmt.Errorf("%w: option %q must be a string, number, boolean, or list of them", ErrBadRemoteRequest, name)
+			}
+		}
+	}
+
+	if separate && len(r.Args) > 0 {
+		args = append(args, "--")
+	}
+
+	return append(args, r.Args...), nil
+}
+
+// remoteTarget resolves the command at the path, refusing the command
+// constructed by NewRemoteCommand, and determines whether it parses
+// flags, in which case its positional arguments may follow "--".
+// Commands accepting passthrough arguments are excluded, since they
+// would receive the arguments as passthrough arguments instead.  A
+// path that cannot be resolved is left for dispatch to report.
+func (a *App) remoteTarget(ctx context.Context, path []string) (bool, error) {
+	inv, err := a.helpPath(a.newInjector(ctx), path)
+	if err != nil {
+		return false, nil
+	}
+	for cmd := inv.Command; cmd != nil; {
+		if _, ok := cmd.(*remoteCommand); ok {
+			return false, fmt.Errorf("%w: %q cannot be invoked remotely", ErrBadRemoteRequest, strings.Join(inv.Path, " "))
+		}
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+	if IsPassthrough(inv.Command) {
+		return false, nil
+	}
+	opts, err := newOptions(inv.Command)
+
+	return err == nil && opts != nil, nil
+}
+
+// remoteFormatter is an OutputFormatter that captures the results of
+// remotely invoked commands.
+type remoteFormatter struct {
+	result json.RawMessage // The last result rendered
+}
+
+// Format captures the data, as JSON.
+func (f *remoteFormatter) Format(w io.Writer, value interface{}) error {
+	result, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	f.result = result
+	return nil
+}
+
+// Invoke invokes a command as described by the request, as Execute
+// would with the corresponding arguments, and returns the outcome.
+// The command runs through the same injector, hooks, and middleware,
+// but with the output streams captured and standard input taken from
+// the request.  Global flags that cannot be honored remotely, such as
+// the WatchFlag, the DetachFlag, and the diagnostic flags, are not
+// recognized, nor are the ConfigFlag and the confirmation flags, and
+// the command constructed by NewRemoteCommand is refused.  Invoke is
+// the basis of RemoteHandler, and may also be used by applications
+// embedding the command tree directly.
+func (a *App) Invoke(ctx context.Context, req *RemoteRequest) *RemoteResponse {
+	separate, err := a.remoteTarget(ctx, req.Command)
+	var args []string
+	if err == nil {
+		args, err = req.argv(separate)
+	}
+	if err != nil {
+		return &RemoteResponse{
+			ExitCode: UsageExitCode,
+			Error:    &RemoteError{Message: err.Error(), Usage: true},
+		}
+	}
+
+	// Construct a copy of the application for the invocation
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	formatter := &remoteFormatter{}
+	app := *a
+	app.Stdin = strings.NewReader(req.Stdin)
+	app.Stdout = stdout
+	app.Stderr = stderr
+	app.HelpOut = nil
+	app.AllowWatch = false
+	app.AllowDetach = false
+	app.AllowConfigFlag = false
+	app.AllowConfirmFlags = false
+	app.NoDebugFlags = true
+	app.AllowOutput = false
+	app.DefaultOutput = remoteOutput
+	app.OutputFormats = map[string]OutputFormat{}
+	for name, format := range a.OutputFormats {
+		app.OutputFormats[name] = format
+	}
+	app.OutputFormats[remoteOutput] = func(string) (OutputFormatter, error) {
+		return formatter, nil
+	}
+
+	// Run the command
+	inv, err := app.dispatch(ctx, args)
+	resp := &RemoteResponse{
+		ExitCode: app.report(inv, err),
+		Result:   formatter.result,
+	}
+	if err != nil {
+		_, usage := ExitControl(err)
+		resp.Error = &RemoteError{Message: err.Error(), Usage: usage}
+	}
+	resp.Stdout = stdout.String()
+	resp.Stderr = stderr.String()
+
+	return resp
+}
+
+// remoteHandler implements RemoteHandler.
+type remoteHandler struct {
+	app *App       // The application
+	mu  sync.Mutex // Serializes invocations
+}
+
+// RemoteHandler returns an http.Handler exposing the command tree
+// over HTTP, so that other programs may drive the application
+// without running it as a separate process; see RemoteInvokePath.
+// Commands are invoked one at a time, with the context of the HTTP
+// request, since commands generally assume that they have the
+// process to themselves.  Since any command may be invoked, the
+// handler should only be served on a local endpoint, such as the
+// loopback interface or a unix domain socket; see ServeRemote.  To
+// keep web pages from invoking commands, requests must name a
+// loopback host, and requests to invoke commands must have the
+// content type "application/json".  If a token is set, requests must
+// also carry it; see WithRemoteToken.
+func (a *App) RemoteHandler() http.Handler {
+	h := &remoteHandler{app: a}
+	mux := http.NewServeMux()
+	mux.HandleFunc(RemoteInvokePath, h.invoke)
+	mux.HandleFunc(RemoteCommandsPath, h.commands)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !localRequest(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		if a.RemoteToken != "" && !validToken(r, a.RemoteToken) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// localRequest determines whether a request was made over a unix
+// domain socket or names a loopback host, such as "localhost".  This
+// defends against web pages that reach the loopback interface
+// through DNS rebinding, which name a host of their own.
+func localRequest(r *http.Request) bool {
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && addr.Network() == "unix" {
+		return true
+	}
+	host := r.Host
+	if tmp, _, err := net.SplitHostPort(host); err == nil {
+		host = tmp
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+
+	return ip != nil && ip.IsLoopback()
+}
+
+// validToken determines whether a request carries the bearer token.
+func validToken(r *http.Request, token string) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(token)) == 1
+}
+
+// newRemoteToken generates a random bearer token.
+func newRemoteToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// invoke handles requests to invoke commands.
+func (h *remoteHandler) invoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Require JSON, which web pages cannot send across origins
+	// without a preflight request
+	if typ, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || typ != "application/json" {
+		http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Decode the request
+	req := &RemoteRequest{}
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxRemoteRequest))
+	dec.UseNumber()
+	if err := dec.Decode(req); err != nil {
+		writeRemote(w, http.StatusBadRequest, &RemoteResponse{
+			ExitCode: UsageExitCode,
+			Error:    &RemoteError{Message: fmt.Sprintf("%s: %s", ErrBadRemoteRequest, err), Usage: true},
+		})
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeRemote(w, http.StatusOK, h.app.Invoke(r.Context(), req))
+}
+
+// commands handles requests describing the command tree.
+func (h *remoteHandler) commands(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	root, err := h.app.Commands(false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeRemote(w, http.StatusOK, root)
+}
+
+// writeRemote is a helper that writes a response as JSON.
+func writeRemote(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// ListenRemote listens on the address for serving the command tree;
+// see ServeRemote.  Addresses beginning with RemoteUnixPrefix name a
+// unix domain socket; others are TCP addresses, as "host:port".
+func ListenRemote(addr string) (net.Listener, error) {
+	if strings.HasPrefix(addr, RemoteUnixPrefix) {
+		return net.Listen("unix", strings.TrimPrefix(addr, RemoteUnixPrefix))
+	}
+
+	return net.Listen("tcp", addr)
+}
+
+// ServeRemote serves the RemoteHandler on the listener until the
+// context is done, then shuts down the server, waiting for commands
+// that are running to complete.
+func (a *App) ServeRemote(ctx context.Context, l net.Listener) error {
+	srv := &http.Server{
+		Handler:     a.RemoteHandler(),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(l)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	if err := srv.Shutdown(context.Background()); err != nil {
+		return err
+	}
+	if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// remoteOptions are the flags of the command constructed by
+// NewRemoteCommand.
+type remoteOptions struct {
+	Listen string `opt:"listen,l" help:"Address on which to listen, as host:port or unix:PATH"`
+}
+
+// remoteCommand implements the command constructed by
+// NewRemoteCommand.
+type remoteCommand struct {
+	Command
+}
+
+// NewRemoteCommand constructs a command that serves the
+// application's command tree until interrupted; see ServeRemote.  It
+// reports the address on which it listens to standard error, as
+// "listening on ADDRESS", and listens on DefaultRemoteAddress unless
+// another is selected with its "--listen" flag.  Unless the App sets
+// a token, TCP listeners require a random token, reported as "token
+// TOKEN"; access to a unix domain socket is governed by its file
+// permissions, and any user able to connect to it may run every
+// command with the privileges of the server.
+func NewRemoteCommand() ICommand {
+	return &remoteCommand{
+		Command: Command{
+			Summary:  "Serve the commands over HTTP",
+			Defaults: &remoteOptions{Listen: DefaultRemoteAddress},
+		},
+	}
+}
+
+// Run serves the command tree.
+func (c *remoteCommand) Run(ctx context.Context, app *App, opts *remoteOptions, streams *IOStreams) error {
+	l, err := ListenRemote(opts.Listen)
+	if err != nil {
+		return err
+	}
+
+	addr := l.Addr().String()
+	if l.Addr().Network() == "unix" {
+		addr = RemoteUnixPrefix + addr
+	}
+	fmt.Fprintf(streams.ErrOut, "listening on %s\n", addr)
+	srv := *app
+	if l.Addr().Network() != "unix" && srv.RemoteToken == "" {
+		if srv.RemoteToken, err = newRemoteToken(); err != nil {
+			l.Close()
+			return err
+		}
+		fmt.Fprintf(streams.ErrOut, "token %s\n", srv.RemoteToken)
+	}
+	return srv.ServeRemote(ctx, l)
+}
@@ -0,0 +1,594 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type remoteTestOptions struct {
+	Name    string   `opt:"name"`
+	Tags    []string `opt:"tag"`
+	Verbose bool     `opt:"verbose"`
+	Count   int      `opt:"count"`
+}
+
+type remoteTestCommand struct {
+	Command
+	err error
+}
+
+func (c *remoteTestCommand) Run(opts *remoteTestOptions, args Args, streams *IOStreams) (interface{}, error) {
+	data, _ := ioutil.ReadAll(streams.In)
+	streams.Out.Write(data)
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return map[string]interface{}{
+		"name":    opts.Name,
+		"tags":    opts.Tags,
+		"verbose": opts.Verbose,
+		"count":   opts.Count,
+		"args":    []string(args),
+	}, nil
+}
+
+// remoteApp is a helper that constructs an application for testing
+// remote invocation, whose "echo" command returns its options and
+// arguments and whose "fail" command returns the specified error.
+func remoteApp(err error) (*App, *bytes.Buffer) {
+	stdout := &bytes.Buffer{}
+	return (&App{
+		Name:   "tool",
+		Stdout: stdout,
+		Stderr: stdout,
+		Root: &Command{
+			Subcommands: map[string]ICommand{
+				"echo": &remoteTestCommand{
+					Command: Command{
+						Summary:  "Echo the options",
+						Defaults: &remoteTestOptions{},
+					},
+				},
+				"fail": &remoteTestCommand{
+					Command: Command{
+						Summary:  "Fail",
+						Defaults: &remoteTestOptions{},
+					},
+					err: err,
+				},
+			},
+		},
+	}).WithNoConfigSearch(true), stdout
+}
+
+func TestRemoteRequestArgv(t *testing.T) {
+	obj := &RemoteRequest{
+		Command: []string{"remote", "add"},
+		Options: map[string]interface{}{
+			"name":    "origin",
+			"tag":     []interface{}{"a", "b"},
+			"verbose": true,
+			"quiet":   false,
+			"count":   json.Number("12345678901234567890"),
+			"ratio":   0.5,
+			"-x":      "y",
+		},
+		Args: []string{"a1", "a2"},
+	}
+
+	result, err := obj.argv(false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"remote", "add",
+		"-x=y",
+		"--count=12345678901234567890",
+		"--name=origin",
+		"--quiet=false",
+		"--ratio=0.5",
+		"--tag=a", "--tag=b",
+		"--verbose",
+		"a1", "a2",
+	}, result)
+}
+
+func TestRemoteRequestArgvSeparate(t *testing.T) {
+	obj := &RemoteRequest{
+		Command: []string{"echo"},
+		Options: map[string]interface{}{"name": "n"},
+		Args:    []string{"--force", "-v"},
+	}
+
+	result, err := obj.argv(true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"echo", "--name=n", "--", "--force", "-v"}, result)
+}
+
+func TestRemoteRequestArgvSeparateNoArgs(t *testing.T) {
+	obj := &RemoteRequest{Command: []string{"echo"}}
+
+	result, err := obj.argv(true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"echo"}, result)
+}
+
+func TestRemoteRequestArgvBadCommand(t *testing.T) {
+	obj := &RemoteRequest{Command: []string{"echo", "--config=/x"}}
+
+	_, err := obj.argv(false)
+
+	assert.ErrorIs(t, err, ErrBadRemoteRequest)
+	assert.EqualError(t, err, `bad remote request: "--config=/x" is not a command name`)
+}
+
+func TestRemoteRequestArgvBadOption(t *testing.T) {
+	for _, value := range []interface{}{nil, map[string]interface{}{}, []interface{}{[]interface{}{}}} {
+		obj := &RemoteRequest{
+			Options: map[string]interface{}{"name": value},
+		}
+
+		_, err := obj.argv(false)
+
+		assert.ErrorIs(t, err, ErrBadRemoteRequest)
+		assert.EqualError(t, err, `bad remote request: option "name" must be a string, number, boolean, or list of them`)
+	}
+}
+
+func TestAppInvoke(t *testing.T) {
+	obj, stdout := remoteApp(nil)
+
+	result := obj.Invoke(context.Background(), &RemoteRequest{
+		Command: []string{"echo"},
+		Options: map[string]interface{}{
+			"name":    "n",
+			"tag":     []interface{}{"a", "b"},
+			"verbose": true,
+			"count":   json.Number("3"),
+		},
+		Args:  []string{"a1"},
+		Stdin: "input",
+	})
+
+	assert.Equal(t, &RemoteResponse{
+		Result: json.RawMessage(`{"args":["a1"],"count":3,"name":"n","tags":["a","b"],"verbose":true}`),
+		Stdout: "input",
+	}, result)
+	assert.Equal(t, "", stdout.String())
+}
+
+func TestAppInvokeError(t *testing.T) {
+	obj, _ := remoteApp(&CommandError{Err: assert.AnError, Code: 3})
+
+	result := obj.Invoke(context.Background(), &RemoteRequest{
+		Command: []string{"fail"},
+	})
+
+	assert.Equal(t, &RemoteResponse{
+		ExitCode: 3,
+		Stderr:   "tool: " + assert.AnError.Error() + "\n",
+		Error:    &RemoteError{Message: assert.AnError.Error()},
+	}, result)
+}
+
+func TestAppInvokeUsage(t *testing.T) {
+	obj, _ := remoteApp(nil)
+
+	result := obj.Invoke(context.Background(), &RemoteRequest{
+		Command: []string{"bogus"},
+	})
+
+	assert.Equal(t, UsageExitCode, result.ExitCode)
+	assert.Equal(t, &RemoteError{Message: `unknown command "bogus"`, Usage: true}, result.Error)
+	assert.Contains(t, result.Stderr, "tool: unknown command \"bogus\"\n")
+	assert.Contains(t, result.Stderr, "Usage:")
+	assert.Nil(t, result.Result)
+}
+
+func TestAppInvokeBadRequest(t *testing.T) {
+	obj, _ := remoteApp(nil)
+
+	result := obj.Invoke(context.Background(), &RemoteRequest{
+		Command: []string{"echo"},
+		Options: map[string]interface{}{"name": nil},
+	})
+
+	assert.Equal(t, &RemoteResponse{
+		ExitCode: UsageExitCode,
+		Error: &RemoteError{
+			Message: `bad remote request: option "name" must be a string, number, boolean, or list of them`,
+			Usage:   true,
+		},
+	}, result)
+}
+
+func TestAppInvokeDashArgs(t *testing.T) {
+	obj, _ := remoteApp(nil)
+	obj.AllowVerbosity = true
+	obj.AllowOutput = true
+
+	result := obj.Invoke(context.Background(), &RemoteRequest{
+		Command: []string{"echo"},
+		Args:    []string{"--name=x", "-v", "-o"},
+	})
+
+	assert.Equal(t, 0, result.ExitCode)
+	assert.JSONEq(t, `{"args":["--name=x","-v","-o"],"count":0,"name":"","tags":null,"verbose":false}`, string(result.Result))
+}
+
+func TestAppInvokeRemoteCommand(t *testing.T) {
+	obj, _ := remoteApp(nil)
+	obj.Root.(*Command).Subcommands["serve"] = Hidden(NewRemoteCommand())
+
+	result := obj.Invoke(context.Background(), &RemoteRequest{
+		Command: []string{"serve"},
+		Options: map[string]interface{}{"listen": "0.0.0.0:8080"},
+	})
+
+	assert.Equal(t, &RemoteResponse{
+		ExitCode: UsageExitCode,
+		Error: &RemoteError{
+			Message: `bad remote request: "tool serve" cannot be invoked remotely`,
+			Usage:   true,
+		},
+	}, result)
+}
+
+func TestAppInvokeNoConfigOrConfirm(t *testing.T) {
+	for option, value := range map[string]interface{}{"config": "x", "yes": true} {
+		obj, _ := remoteApp(nil)
+		obj.AllowConfigFlag = true
+		obj.AllowConfirmFlags = true
+
+		result := obj.Invoke(context.Background(), &RemoteRequest{
+			Command: []string{"echo"},
+			Options: map[string]interface{}{option: value},
+		})
+
+		assert.Equal(t, UsageExitCode, result.ExitCode, option)
+	}
+}
+
+func TestAppInvokeNoWatch(t *testing.T) {
+	obj, _ := remoteApp(nil)
+	obj.AllowWatch = true
+
+	result := obj.Invoke(context.Background(), &RemoteRequest{
+		Command: []string{"echo"},
+		Options: map[string]interface{}{"watch": true},
+	})
+
+	assert.Equal(t, UsageExitCode, result.ExitCode)
+	assert.True(t, obj.AllowWatch)
+}
+
+// remoteDo is a helper that makes a request of the RemoteHandler of
+// the application, returning the recorded response.
+func remoteDo(obj *App, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Host = "localhost"
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	obj.RemoteHandler().ServeHTTP(w, req)
+	return w
+}
+
+func TestAppRemoteHandlerInvoke(t *testing.T) {
+	obj, _ := remoteApp(nil)
+
+	result := remoteDo(obj, http.MethodPost, RemoteInvokePath, `{"command":["echo"],"options":{"count":7},"args":["a1"]}`)
+
+	assert.Equal(t, http.StatusOK, result.Code)
+	assert.Equal(t, "application/json", result.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"exit_code": 0,
+		"result": {"args":["a1"],"count":7,"name":"","tags":null,"verbose":false}
+	}`, result.Body.String())
+}
+
+func TestAppRemoteHandlerInvokeFailed(t *testing.T) {
+	obj, _ := remoteApp(errors.New("failed"))
+
+	result := remoteDo(obj, http.MethodPost, RemoteInvokePath, `{"command":["fail"]}`)
+
+	assert.Equal(t, http.StatusOK, result.Code)
+	assert.JSONEq(t, `{
+		"exit_code": 1,
+		"stderr": "tool: failed\n",
+		"error": {"message": "failed"}
+	}`, result.Body.String())
+}
+
+func TestAppRemoteHandlerInvokeBadJSON(t *testing.T) {
+	obj, _ := remoteApp(nil)
+
+	result := remoteDo(obj, http.MethodPost, RemoteInvokePath, `{"command":`)
+
+	assert.Equal(t, http.StatusBadRequest, result.Code)
+	resp := &RemoteResponse{}
+	assert.NoError(t, json.Unmarshal(result.Body.Bytes(), resp))
+	assert.Equal(t, UsageExitCode, resp.ExitCode)
+	assert.True(t, strings.HasPrefix(resp.Error.Message, "bad remote request: "))
+}
+
+func TestAppRemoteHandlerInvokeMethod(t *testing.T) {
+	obj, _ := remoteApp(nil)
+
+	result := remoteDo(obj, http.MethodGet, RemoteInvokePath, "")
+
+	assert.Equal(t, http.StatusMethodNotAllowed, result.Code)
+	assert.Equal(t, http.MethodPost, result.Header().Get("Allow"))
+}
+
+func TestAppRemoteHandlerInvokeContentType(t *testing.T) {
+	obj, _ := remoteApp(nil)
+	for _, typ := range []string{"", "text/plain", "application/x-www-form-urlencoded"} {
+		req := httptest.NewRequest(http.MethodPost, RemoteInvokePath, strings.NewReader(`{"command":["echo"]}`))
+		req.Host = "localhost"
+		req.Header.Set("Content-Type", typ)
+		w := httptest.NewRecorder()
+
+		obj.RemoteHandler().ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code, typ)
+	}
+}
+
+func TestAppRemoteHandlerInvokeCharset(t *testing.T) {
+	obj, _ := remoteApp(nil)
+	req := httptest.NewRequest(http.MethodPost, RemoteInvokePath, strings.NewReader(`{"command":["echo"]}`))
+	req.Host = "127.0.0.1:8080"
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	w := httptest.NewRecorder()
+
+	obj.RemoteHandler().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAppWithRemoteToken(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithRemoteToken("secret")
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, "secret", obj.RemoteToken)
+}
+
+func TestAppRemoteHandlerToken(t *testing.T) {
+	tests := []struct {
+		auth string
+		code int
+	}{
+		{"Bearer secret", http.StatusOK},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"secret", http.StatusUnauthorized},
+		{"", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		obj, _ := remoteApp(nil)
+		obj.RemoteToken = "secret"
+		req := httptest.NewRequest(http.MethodGet, RemoteCommandsPath, nil)
+		req.Host = "localhost"
+		req.Header.Set("Authorization", test.auth)
+		w := httptest.NewRecorder()
+
+		obj.RemoteHandler().ServeHTTP(w, req)
+
+		assert.Equal(t, test.code, w.Code, test.auth)
+	}
+}
+
+func TestAppRemoteHandlerForeignHost(t *testing.T) {
+	obj, _ := remoteApp(nil)
+	req := httptest.NewRequest(http.MethodPost, RemoteInvokePath, strings.NewReader(`{"command":["echo"]}`))
+	req.Host = "attacker.example.com:8080"
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	obj.RemoteHandler().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestLocalRequest(t *testing.T) {
+	tests := []struct {
+		host  string
+		local bool
+	}{
+		{"localhost", true},
+		{"LocalHost:8080", true},
+		{"127.0.0.1:8080", true},
+		{"127.1.2.3", true},
+		{"[::1]:8080", true},
+		{"::1", true},
+		{"example.com", false},
+		{"10.0.0.1:8080", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, RemoteCommandsPath, nil)
+		req.Host = test.host
+
+		assert.Equal(t, test.local, localRequest(req), test.host)
+	}
+}
+
+func TestLocalRequestUnix(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, RemoteCommandsPath, nil)
+	req.Host = "example.com"
+	addr := &net.UnixAddr{Name: "/run/tool.sock", Net: "unix"}
+	req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, addr))
+
+	assert.True(t, localRequest(req))
+}
+
+func TestAppRemoteHandlerCommands(t *testing.T) {
+	obj, _ := remoteApp(nil)
+
+	result := remoteDo(obj, http.MethodGet, RemoteCommandsPath, "")
+
+	assert.Equal(t, http.StatusOK, result.Code)
+	entry := &CommandEntry{}
+	assert.NoError(t, json.Unmarshal(result.Body.Bytes(), entry))
+	assert.Equal(t, "tool", entry.Path)
+	assert.Equal(t, "tool echo", entry.Subcommands[0].Path)
+}
+
+func TestAppRemoteHandlerCommandsMethod(t *testing.T) {
+	obj, _ := remoteApp(nil)
+
+	result := remoteDo(obj, http.MethodPost, RemoteCommandsPath, "")
+
+	assert.Equal(t, http.StatusMethodNotAllowed, result.Code)
+	assert.Equal(t, http.MethodGet, result.Header().Get("Allow"))
+}
+
+func TestAppRemoteHandlerNotFound(t *testing.T) {
+	obj, _ := remoteApp(nil)
+
+	result := remoteDo(obj, http.MethodGet, "/bogus", "")
+
+	assert.Equal(t, http.StatusNotFound, result.Code)
+}
+
+func TestListenRemoteTCP(t *testing.T) {
+	l, err := ListenRemote("127.0.0.1:0")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp", l.Addr().Network())
+	l.Close()
+}
+
+func TestListenRemoteUnix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix domain sockets are not reliably available")
+	}
+	path := filepath.Join(t.TempDir(), "tool.sock")
+
+	l, err := ListenRemote(RemoteUnixPrefix + path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "unix", l.Addr().Network())
+	assert.Equal(t, path, l.Addr().String())
+	l.Close()
+}
+
+func TestAppServeRemote(t *testing.T) {
+	obj, _ := remoteApp(nil)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- obj.ServeRemote(ctx, l)
+	}()
+
+	resp, err := http.Post("http://"+l.Addr().String()+RemoteInvokePath, "application/json", strings.NewReader(`{"command":["echo"]}`))
+	cancel()
+
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NoError(t, <-done)
+}
+
+func TestAppServeRemoteClosed(t *testing.T) {
+	obj, _ := remoteApp(nil)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	err = obj.ServeRemote(context.Background(), l)
+
+	assert.Error(t, err)
+}
+
+func TestNewRemoteCommand(t *testing.T) {
+	result := NewRemoteCommand()
+
+	assert.Equal(t, "Serve the commands over HTTP", result.GetSummary())
+	assert.Equal(t, &remoteOptions{Listen: DefaultRemoteAddress}, result.GetDefaults())
+}
+
+func TestRemoteCommandRun(t *testing.T) {
+	obj, _ := remoteApp(nil)
+	streams, _, _, errOut := NewTestIOStreams()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := NewRemoteCommand().(*remoteCommand).Run(ctx, obj, &remoteOptions{Listen: "127.0.0.1:0"}, streams)
+
+	assert.NoError(t, err)
+	assert.Regexp(t, `^listening on 127\.0\.0\.1:[0-9]+\ntoken [0-9a-f]{32}\n$`, errOut.String())
+}
+
+func TestRemoteCommandRunToken(t *testing.T) {
+	obj, _ := remoteApp(nil)
+	obj.RemoteToken = "secret"
+	streams, _, _, errOut := NewTestIOStreams()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := NewRemoteCommand().(*remoteCommand).Run(ctx, obj, &remoteOptions{Listen: "127.0.0.1:0"}, streams)
+
+	assert.NoError(t, err)
+	assert.Regexp(t, `^listening on 127\.0\.0\.1:[0-9]+\n$`, errOut.String())
+}
+
+func TestRemoteCommandRunUnix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix domain sockets are not reliably available")
+	}
+	obj, _ := remoteApp(nil)
+	streams, _, _, errOut := NewTestIOStreams()
+	path := filepath.Join(t.TempDir(), "tool.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := NewRemoteCommand().(*remoteCommand).Run(ctx, obj, &remoteOptions{Listen: RemoteUnixPrefix + path}, streams)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "listening on unix:"+path+"\n", errOut.String())
+}
+
+func TestRemoteCommandRunBadAddress(t *testing.T) {
+	obj, _ := remoteApp(nil)
+	streams, _, _, _ := NewTestIOStreams()
+
+	err := NewRemoteCommand().(*remoteCommand).Run(context.Background(), obj, &remoteOptions{Listen: "bogus"}, streams)
+
+	assert.Error(t, err)
+}
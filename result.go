@@ -0,0 +1,45 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+// ParseResult describes how the command line was parsed, so that
+// commands may implement behavior that depends on whether a value was
+// given explicitly, or produce better error messages.  It is
+// available from the injector.  Flags may be given by any of their
+// names, as "--name" or "-s"; the dashes may be omitted.  Both the
+// resolved command's flags and the global flags are considered.
+type ParseResult struct {
+	inv *Invocation // The resolved invocation
+	raw []string    // The arguments, before any processing
+}
+
+// Changed returns true if the value of a flag was given by the
+// command line, the environment, or the configuration file, rather
+// than coming from the defaults.
+func (r *ParseResult) Changed(flag string) bool {
+	return r.Source(flag) != SourceDefault
+}
+
+// Source returns the source of the value of a flag.  If there is no
+// such flag, SourceDefault is returned.
+func (r *ParseResult) Source(flag string) Source {
+	return r.inv.Source(flag)
+}
+
+// RawArgs returns the arguments exactly as given, before any
+// preprocessing or parsing.
+func (r *ParseResult) RawArgs() []string {
+	return append([]string(nil), r.raw...)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type resultCommand struct {
+	Command
+	result *ParseResult
+}
+
+func (c *resultCommand) Run(result *ParseResult) {
+	c.result = result
+}
+
+func TestParseResultChanged(t *testing.T) {
+	opts, _ := newOptions(&Command{Defaults: &testOptions{}})
+	opts.sources = map[string]Source{"--verbose": SourceEnv}
+	obj := &ParseResult{inv: &Invocation{options: []*options{opts}}}
+
+	assert.True(t, obj.Changed("--verbose"))
+	assert.False(t, obj.Changed("--name"))
+	assert.False(t, obj.Changed("--missing"))
+}
+
+func TestParseResultSource(t *testing.T) {
+	opts, _ := newOptions(&Command{Defaults: &testOptions{}})
+	opts.sources = map[string]Source{"--count": SourceConfig}
+	obj := &ParseResult{inv: &Invocation{options: []*options{opts}}}
+
+	result := obj.Source("c")
+
+	assert.Equal(t, SourceConfig, result)
+}
+
+func TestParseResultRawArgs(t *testing.T) {
+	obj := &ParseResult{raw: []string{"a1", "a2"}}
+
+	result := obj.RawArgs()
+	result[0] = "changed"
+
+	assert.Equal(t, []string{"a1", "a2"}, obj.raw)
+}
+
+func TestAppDispatchParseResult(t *testing.T) {
+	setEnv(t, "TOOL_NAME", "env")
+	root := &resultCommand{
+		Command: Command{Defaults: &testOptions{}},
+	}
+	obj := &App{
+		Root:      root,
+		EnvPrefix: "tool",
+		Globals:   []interface{}{&globalOptions{}},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"-v", "--no-color", "file"})
+
+	assert.NoError(t, err)
+	assert.True(t, root.result.Changed("verbose"))
+	assert.Equal(t, SourceEnv, root.result.Source("--name"))
+	assert.False(t, root.result.Changed("count"))
+	assert.True(t, root.result.Changed("no-color"))
+	assert.Equal(t, []string{"-v", "--no-color", "file"}, root.result.RawArgs())
+}
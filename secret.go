@@ -0,0 +1,180 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// KindSecret is the flag kind for secrets, such as passwords and
+// tokens, selected with KindTag; the flag's field must be a string.
+// The value of a secret flag is never displayed as a default, and is
+// redacted from error messages.  For each secret flag "--name", an
+// additional flag "--name-file" is declared, which reads the secret
+// from the named file.  If a secret flag has no value once the
+// command line has been parsed and standard input is a terminal, the
+// user is prompted for the secret, without echo.
+const KindSecret = "secret"
+
+// kindSecretFile is the kind of the flags declared to read secrets
+// from files.
+const kindSecretFile = "secret-file"
+
+// SecretFileSuffix is the suffix added to the long name of a secret
+// flag to form the name of the flag that reads the secret from a
+// file.
+const SecretFileSuffix = "-file"
+
+// Redacted is displayed in place of the value of a secret.
+const Redacted = "********"
+
+// SecretPrompter is a function that prompts the user for a secret and
+// reads it from the terminal without echo.  The prompt is a
+// description of the secret, such as "--token".
+type SecretPrompter func(prompt string) (string, error)
+
+// Hooks for testing.
+var (
+	isTerminal = termIsTerminal
+	runStty    = termRunStty
+)
+
+// WithSecretPrompter sets the function used to prompt the user for
+// secrets.  Returns the App, to allow chaining.
+func (a *App) WithSecretPrompter(prompter SecretPrompter) *App {
+	a.SecretPrompter = prompter
+	return a
+}
+
+// IsSecret returns true if the value of the flag must not be
+// displayed.
+func (o *option) IsSecret() bool {
+	return o.Kind == KindSecret || o.Kind == kindSecretFile
+}
+
+// Display formats a value of the flag for display, such as in an
+// error message.  The values of secret flags are redacted.
+func (o *option) Display(text string) string {
+	if o.Kind == KindSecret {
+		return Redacted
+	}
+
+	return strconv.Quote(text)
+}
+
+// addSecretFile adds the flag that reads a secret flag from a file.
+// Short-only secret flags have no such flag.
+func (s *optionSet) addSecretFile(opt *option) error {
+	if opt.Name == "" {
+		return nil
+	}
+
+	fileOpt := &option{
+		Name:  opt.Name + SecretFileSuffix,
+		Help:  fmt.Sprintf("Read %s from a file", opt),
+		Kind:  kindSecretFile,
+		Index: opt.Index,
+		Type:  opt.Type,
+	}
+	if _, dup := s.long[fileOpt.Name]; dup {
+		return fmt.Errorf("%w: duplicate flag %s", ErrBadOptions, fileOpt)
+	}
+
+	s.Options = append(s.Options, fileOpt)
+	s.long[fileOpt.Name] = fileOpt
+	return nil
+}
+
+// readSecretFile reads a secret from a file.  A single trailing
+// newline is removed.
+func readSecretFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	text := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(text, "\r"), nil
+}
+
+// promptSecrets prompts the user for the values of secret flags that
+// have not been set, provided standard input is a terminal.
+func (a *App) promptSecrets(o *options) error {
+	if o == nil {
+		return nil
+	}
+
+	for _, opt := range o.Set.Options {
+		field := o.Field(opt.Index)
+		if opt.Kind != KindSecret || field.String() != "" {
+			continue
+		}
+		if !streamIsTerminal(a.stdin()) {
+			return nil
+		}
+
+		prompter := a.SecretPrompter
+		if prompter == nil {
+			prompter = a.readSecret
+		}
+		text, err := prompter(opt.String())
+		if err != nil {
+			return err
+		}
+		field.SetString(text)
+	}
+
+	return nil
+}
+
+// readSecret is the default SecretPrompter.  It prompts on the App's
+// standard error and reads a line from standard input, disabling
+// echo with stty(1) while doing so.
+func (a *App) readSecret(prompt string) (string, error) {
+	f := a.stdin().(*os.File)
+	fmt.Fprintf(a.stderr(), "%s: ", prompt)
+	if err := runStty(f, "-echo"); err == nil {
+		defer func() {
+			_ = runStty(f, "echo")
+			fmt.Fprintln(a.stderr())
+		}()
+	}
+
+	text, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && text == "" {
+		return "", err
+	}
+
+	return strings.TrimRight(text, "\r\n"), nil
+}
+
+// termIsTerminal determines whether a file is a terminal.
+func termIsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// termRunStty runs stty(1) on a terminal with the specified setting.
+func termRunStty(f *os.File, setting string) error {
+	cmd := exec.Command("stty", setting) //nolint:gosec
+	cmd.Stdin = f
+	return cmd.Run()
+}
@@ -0,0 +1,347 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type secretOptions struct {
+	User  string `opt:"user"`
+	Token string `opt:"token" kind:"secret" choices:"good,better"`
+}
+
+// fakeTerminal is a helper that makes the terminal test hooks report
+// that standard input is a terminal, restoring them when the test
+// completes.
+func fakeTerminal(t *testing.T, terminal bool, stty func(f *os.File, setting string) error) {
+	t.Helper()
+
+	oldIsTerminal, oldRunStty := isTerminal, runStty
+	isTerminal = func(f *os.File) bool { return terminal }
+	runStty = stty
+	t.Cleanup(func() {
+		isTerminal, runStty = oldIsTerminal, oldRunStty
+	})
+}
+
+// stdinFile is a helper that constructs a file containing the
+// specified content, opened for reading.
+func stdinFile(t *testing.T, content string) *os.File {
+	t.Helper()
+
+	f, err := os.Open(makeArgFile(t, content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	return f
+}
+
+func TestAppWithSecretPrompter(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithSecretPrompter(func(prompt string) (string, error) {
+		return "", nil
+	})
+
+	assert.Same(t, obj, result)
+	assert.NotNil(t, obj.SecretPrompter)
+}
+
+func TestOptionIsSecret(t *testing.T) {
+	assert.True(t, (&option{Kind: KindSecret}).IsSecret())
+	assert.True(t, (&option{Kind: kindSecretFile}).IsSecret())
+	assert.False(t, (&option{}).IsSecret())
+}
+
+func TestOptionDisplay(t *testing.T) {
+	assert.Equal(t, Redacted, (&option{Kind: KindSecret}).Display("text"))
+	assert.Equal(t, `"text"`, (&option{Kind: kindSecretFile}).Display("text"))
+}
+
+func TestNewOptionSetSecret(t *testing.T) {
+	result, err := newOptionSet(reflect.TypeOf(secretOptions{}))
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Options, 3)
+	assert.Equal(t, &option{
+		Name:  "token-file",
+		Help:  "Read --token from a file",
+		Kind:  kindSecretFile,
+		Index: []int{1},
+		Field: "secretOptions.Token",
+		Type:  reflect.TypeOf(""),
+	}, result.Options[2])
+	assert.Same(t, result.Options[2], result.long["token-file"])
+}
+
+func TestNewOptionSetSecretShortOnly(t *testing.T) {
+	type opts struct {
+		Token string `opt:",t" kind:"secret"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Options, 1)
+	assert.Equal(t, map[string]*option{}, result.long)
+}
+
+func TestNewOptionSetSecretNotString(t *testing.T) {
+	type opts struct {
+		Token []byte `opt:"token" kind:"secret"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetSecretDuplicateFile(t *testing.T) {
+	type opts struct {
+		File  string `opt:"token-file"`
+		Token string `opt:"token" kind:"secret"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.EqualError(t, err, "invalid options declaration: duplicate flag --token-file")
+	assert.Nil(t, result)
+}
+
+func TestOptionsDefaultTextSecret(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &secretOptions{Token: "hunter2"}})
+
+	text, ok := obj.DefaultText(obj.Set.Options[1])
+	fileText, fileOK := obj.DefaultText(obj.Set.Options[2])
+
+	assert.False(t, ok)
+	assert.Equal(t, "", text)
+	assert.False(t, fileOK)
+	assert.Equal(t, "", fileText)
+}
+
+func TestOptionsParseSecretRedacted(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &secretOptions{}})
+
+	_, _, err := obj.parse([]string{"--token", "hunter2"}, nil)
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.EqualError(t, err, "invalid value ******** for --token: must be one of good, better")
+}
+
+func TestOptionsParseSecretFile(t *testing.T) {
+	path := makeArgFile(t, "good\r\n")
+	obj, _ := newOptions(&Command{Defaults: &secretOptions{}})
+
+	_, _, err := obj.parse([]string{"--token-file", path}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &secretOptions{Token: "good"}, obj.Value.Interface())
+}
+
+func TestOptionsParseSecretFileMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing")
+	obj, _ := newOptions(&Command{Defaults: &secretOptions{}})
+
+	_, _, err := obj.parse([]string{"--token-file", path}, nil)
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+}
+
+func TestAppPromptSecretsBase(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	obj := &App{
+		Stdin: os.Stdin,
+		SecretPrompter: func(prompt string) (string, error) {
+			assert.Equal(t, "--token", prompt)
+			return "good", nil
+		},
+	}
+	opts, _ := newOptions(&Command{Defaults: &secretOptions{}})
+
+	err := obj.promptSecrets(opts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &secretOptions{Token: "good"}, opts.Value.Interface())
+}
+
+func TestAppPromptSecretsSet(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	obj := &App{
+		Stdin: os.Stdin,
+		SecretPrompter: func(prompt string) (string, error) {
+			t.Fatal("prompter called")
+			return "", nil
+		},
+	}
+	opts, _ := newOptions(&Command{Defaults: &secretOptions{Token: "set"}})
+
+	err := obj.promptSecrets(opts)
+
+	assert.NoError(t, err)
+}
+
+func TestAppPromptSecretsNotTerminal(t *testing.T) {
+	fakeTerminal(t, false, nil)
+	obj := &App{
+		Stdin: os.Stdin,
+		SecretPrompter: func(prompt string) (string, error) {
+			t.Fatal("prompter called")
+			return "", nil
+		},
+	}
+	opts, _ := newOptions(&Command{Defaults: &secretOptions{}})
+
+	err := obj.promptSecrets(opts)
+
+	assert.NoError(t, err)
+}
+
+func TestAppPromptSecretsNotFile(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	obj := &App{
+		Stdin: &bytes.Buffer{},
+		SecretPrompter: func(prompt string) (string, error) {
+			t.Fatal("prompter called")
+			return "", nil
+		},
+	}
+	opts, _ := newOptions(&Command{Defaults: &secretOptions{}})
+
+	err := obj.promptSecrets(opts)
+
+	assert.NoError(t, err)
+}
+
+func TestAppPromptSecretsError(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	obj := &App{
+		Stdin: os.Stdin,
+		SecretPrompter: func(prompt string) (string, error) {
+			return "", assert.AnError
+		},
+	}
+	opts, _ := newOptions(&Command{Defaults: &secretOptions{}})
+
+	err := obj.promptSecrets(opts)
+
+	assert.Same(t, assert.AnError, err)
+}
+
+func TestAppPromptSecretsNil(t *testing.T) {
+	obj := &App{}
+
+	err := obj.promptSecrets(nil)
+
+	assert.NoError(t, err)
+}
+
+func TestAppPromptSecretsDefault(t *testing.T) {
+	settings := []string{}
+	fakeTerminal(t, true, func(f *os.File, setting string) error {
+		settings = append(settings, setting)
+		return nil
+	})
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Stdin:  stdinFile(t, "better\n"),
+		Stderr: stderr,
+	}
+	opts, _ := newOptions(&Command{Defaults: &secretOptions{}})
+
+	err := obj.promptSecrets(opts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &secretOptions{Token: "better"}, opts.Value.Interface())
+	assert.Equal(t, []string{"-echo", "echo"}, settings)
+	assert.Equal(t, "--token: \n", stderr.String())
+}
+
+func TestAppReadSecretNoStty(t *testing.T) {
+	fakeTerminal(t, true, func(f *os.File, setting string) error {
+		return assert.AnError
+	})
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Stdin:  stdinFile(t, "secret"),
+		Stderr: stderr,
+	}
+
+	result, err := obj.readSecret("--token")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", result)
+	assert.Equal(t, "--token: ", stderr.String())
+}
+
+func TestAppReadSecretEOF(t *testing.T) {
+	fakeTerminal(t, true, func(f *os.File, setting string) error {
+		return nil
+	})
+	obj := &App{
+		Stdin:  stdinFile(t, ""),
+		Stderr: &bytes.Buffer{},
+	}
+
+	result, err := obj.readSecret("--token")
+
+	assert.Error(t, err)
+	assert.Equal(t, "", result)
+}
+
+func TestTermIsTerminal(t *testing.T) {
+	f := stdinFile(t, "")
+
+	result := termIsTerminal(f)
+
+	assert.False(t, result)
+}
+
+func TestTermRunStty(t *testing.T) {
+	f := stdinFile(t, "")
+
+	err := termRunStty(f, "-echo")
+
+	assert.Error(t, err)
+}
+
+func TestAppDispatchSecret(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	root := newRunCommand("Root", nil)
+	root.Defaults = &secretOptions{}
+	obj := &App{
+		Root:  root,
+		Stdin: os.Stdin,
+		SecretPrompter: func(prompt string) (string, error) {
+			return "", assert.AnError
+		},
+	}
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.Same(t, assert.AnError, err)
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrBadSeeAlso indicates that a command refers to a related command
+// that does not exist; see ISeeAlso.
+var ErrBadSeeAlso = errors.New("unknown command in see-also reference")
+
+// seeAlsoNames is a helper for helpData that converts the paths of
+// related commands into command names, as in "tool remote add".
+// Surrounding white space and repeated spaces are removed; empty
+// references are omitted.
+func seeAlsoNames(app string, refs []string) []string {
+	var names []string
+	for _, ref := range refs {
+		if fields := strings.Fields(ref); len(fields) > 0 {
+			names = append(names, strings.Join(append([]string{app}, fields...), " "))
+		}
+	}
+
+	return names
+}
+
+// CheckSeeAlso checks the static command tree for references to
+// related commands that do not exist, returning an error describing
+// the first one found.  References are resolved through the static
+// subcommands, so commands added by DynamicSubcommands cannot be
+// checked; the HelpCommand is available at the root.
+func (a *App) CheckSeeAlso() error {
+	return a.checkSeeAlso(a.Root, nil)
+}
+
+// checkSeeAlso is a helper for CheckSeeAlso that checks a single
+// command and recurses into its subcommands.
+func (a *App) checkSeeAlso(cmd ICommand, path []string) error {
+	for _, ref := range GetSeeAlso(cmd) {
+		if !a.resolvesStatic(strings.Fields(ref)) {
+			name := strings.Join(append([]string{a.name()}, path...), " ")
+			return fmt.Errorf("%w: %q refers to %q", ErrBadSeeAlso, name, ref)
+		}
+	}
+
+	// Recurse into the subcommands in a stable order
+	if IsAlias(cmd) {
+		return nil
+	}
+	subs := cmd.GetSubcommands()
+	subNames := make([]string, 0, len(subs))
+	for name := range subs {
+		subNames = append(subNames, name)
+	}
+	sort.Strings(subNames)
+	for _, name := range subNames {
+		if err := a.checkSeeAlso(subs[name], append(append([]string{}, path...), name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolvesStatic is a helper that determines whether a path of
+// subcommand names below the root names a command in the static
+// command tree.  An empty path does not.
+func (a *App) resolvesStatic(names []string) bool {
+	if len(names) == 0 {
+		return false
+	}
+
+	cmd := a.Root
+	for i, name := range names {
+		subs := cmd.GetSubcommands()
+		if i == 0 {
+			subs = a.withHelp(subs, nil)
+		}
+		sub, ok := subs[name]
+		if !ok {
+			return false
+		}
+		cmd = sub
+	}
+
+	return true
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// seeAlsoApp is a helper that constructs an application for testing
+// see-also references.
+func seeAlsoApp(refs ...string) *App {
+	return &App{
+		Name: "tool",
+		Root: &Command{
+			Subcommands: map[string]ICommand{
+				"remote": &Command{
+					Subcommands: map[string]ICommand{
+						"add":    &Command{SeeAlso: refs},
+						"remove": &Command{},
+					},
+				},
+				"status": &Command{},
+			},
+		},
+	}
+}
+
+func TestSeeAlsoNames(t *testing.T) {
+	result := seeAlsoNames("tool", []string{"remote add", "  status ", "", "remote   remove"})
+
+	assert.Equal(t, []string{"tool remote add", "tool status", "tool remote remove"}, result)
+}
+
+func TestSeeAlsoNamesNone(t *testing.T) {
+	result := seeAlsoNames("tool", nil)
+
+	assert.Nil(t, result)
+}
+
+func TestAppCheckSeeAlsoBase(t *testing.T) {
+	obj := seeAlsoApp("remote remove", "status", "help")
+
+	err := obj.CheckSeeAlso()
+
+	assert.NoError(t, err)
+}
+
+func TestAppCheckSeeAlsoUnknown(t *testing.T) {
+	obj := seeAlsoApp("status", "remote rename")
+
+	err := obj.CheckSeeAlso()
+
+	assert.ErrorIs(t, err, ErrBadSeeAlso)
+	assert.EqualError(t, err, `unknown command in see-also reference: "tool remote add" refers to "remote rename"`)
+}
+
+func TestAppCheckSeeAlsoEmpty(t *testing.T) {
+	obj := seeAlsoApp(" ")
+
+	err := obj.CheckSeeAlso()
+
+	assert.ErrorIs(t, err, ErrBadSeeAlso)
+}
+
+func TestAppCheckSeeAlsoAlias(t *testing.T) {
+	obj := &App{
+		Name: "tool",
+		Root: &Command{
+			Subcommands: map[string]ICommand{
+				"st": Alias(&Command{
+					Subcommands: map[string]ICommand{
+						"bogus": &Command{SeeAlso: []string{"bogus"}},
+					},
+				}),
+			},
+		},
+	}
+
+	err := obj.CheckSeeAlso()
+
+	assert.NoError(t, err)
+}
+
+func TestAppHelpSeeAlso(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{}
+	inv := &Invocation{
+		Path: []string{"tool", "remote", "add"},
+		Command: &Command{
+			Summary: "Add a remote",
+			SeeAlso: []string{"remote remove", "status"},
+		},
+	}
+
+	err := obj.help(buf, inv)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Add a remote
+
+Usage: tool remote add [ARGS...]
+
+See also:
+  tool remote remove
+  tool status
+`, buf.String())
+}
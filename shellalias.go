@@ -0,0 +1,276 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/klmitch/nelson/internal/shlex"
+)
+
+// ShellAliasCommand is the name of the subcommand that writes shell
+// functions for the user's command aliases, as in "tool alias bash".
+// It is added to the root command if the application allows it; see
+// WithShellAliases.
+const ShellAliasCommand = "alias"
+
+// AliasesKey is the configuration key of the user's command aliases.
+// Its value maps the name of each alias to the command line it
+// stands for, following the application name, either as a string,
+// which is split into words as by a POSIX shell, or as a list of
+// words:
+//
+//	aliases:
+//	  k: get pods
+//	  kd: [describe, pod]
+const AliasesKey = "aliases"
+
+// ErrBadAlias indicates that a command alias in the configuration is
+// malformed or does not name a command.
+var ErrBadAlias = errors.New("invalid alias")
+
+// aliasName describes valid alias names; they must be usable as
+// shell function names.
+var aliasName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// ShellAlias is a command alias defined by the user.
+type ShellAlias struct {
+	Name string   // Name of the alias
+	Args []string // The arguments the alias stands for, following the application name
+}
+
+// WithShellAliases sets whether the ShellAliasCommand is added to the
+// root command.  Returns the App, to allow chaining.
+func (a *App) WithShellAliases(allow bool) *App {
+	a.AllowShellAliases = allow
+	return a
+}
+
+// ShellAliases loads the user's command aliases from the
+// configuration, sorted by name; see AliasesKey.  Each alias must
+// begin with the name of a command in the command tree, if the root
+// command has subcommands.
+func (a *App) ShellAliases(ctx context.Context) ([]ShellAlias, error) {
+	cfg, err := a.Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.shellAliases(a.newInjector(ctx), cfg)
+}
+
+// shellAliases is a helper that extracts the command aliases from the
+// configuration, using the injector to compute dynamic subcommands.
+func (a *App) shellAliases(inj *Injector, cfg map[string]interface{}) ([]ShellAlias, error) {
+	raw, ok := cfg[AliasesKey]
+	if !ok {
+		return nil, nil
+	}
+	section, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %q must map alias names to commands", ErrBadAlias, AliasesKey)
+	}
+
+	aliases := []ShellAlias{}
+	for name, value := range section {
+		if !aliasName.MatchString(name) {
+			return nil, fmt.Errorf("%w name %q", ErrBadAlias, name)
+		}
+		args, err := aliasArgs(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %s", ErrBadAlias, name, err)
+		}
+		if err := a.checkAlias(inj, args); err != nil {
+			return nil, fmt.Errorf("%w %q: %s", ErrBadAlias, name, err)
+		}
+		aliases = append(aliases, ShellAlias{Name: name, Args: args})
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		return aliases[i].Name < aliases[j].Name
+	})
+
+	return aliases, nil
+}
+
+// aliasArgs is a helper that converts the value of an alias in the
+// configuration to the arguments it stands for.
+func aliasArgs(value interface{}) ([]string, error) {
+	var args []string
+	switch tmp := value.(type) {
+	case string:
+		words, err := shlex.Split(tmp)
+		if err != nil {
+			return nil, err
+		}
+		args = words
+
+	case []interface{}:
+		for _, item := range tmp {
+			word, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("word %v is not a string", item)
+			}
+			args = append(args, word)
+		}
+
+	default:
+		return nil, errors.New("must be a string or a list of words")
+	}
+	if len(args) == 0 {
+		return nil, errors.New("no command given")
+	}
+
+	return args, nil
+}
+
+// checkAlias is a helper that checks that the arguments of an alias
+// begin with the name of a command, if the root command has
+// subcommands.
+func (a *App) checkAlias(inj *Injector, args []string) error {
+	subs, err := resolveSubcommands(a.Root, inj)
+	if err != nil {
+		return err
+	}
+	if _, ok := subs[args[0]]; !ok && len(subs) > 0 {
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	return nil
+}
+
+// ShellAliasScript produces the shell functions implementing the
+// aliases for the application in the specified shell, one of
+// ShellBash, ShellZsh, or ShellFish.  The app is the name of the
+// application.  Completion of each alias is provided by the
+// application's CompleteCommand, so it follows the real command
+// tree.
+func ShellAliasScript(shell, app string, aliases []ShellAlias) (string, error) {
+	if shell != ShellBash && shell != ShellZsh && shell != ShellFish {
+		return "", fmt.Errorf("%w %q", ErrUnknownShell, shell)
+	}
+
+	buf := &strings.Builder{}
+	for _, alias := range aliases {
+		fn := "_" + shellIdent(app) + "_alias_" + shellIdent(alias.Name)
+		args := make([]string, len(alias.Args))
+		for i, arg := range alias.Args {
+			args[i] = shellQuote(shell, arg)
+		}
+		prog := "command " + shellQuote(shell, app)
+		run := prog + " " + strings.Join(args, " ")
+		complete := prog + " " + CompleteCommand + " -- " + strings.Join(args, " ")
+
+		switch shell {
+		case ShellBash:
+			fmt.Fprintf(buf, "%s() { %s \"$@\"; }\n", alias.Name, run)
+			fmt.Fprintf(buf, "%s() {\n\tlocal IFS=$'\\n'\n\tCOMPREPLY=($(%s \"${COMP_WORDS[@]:1:COMP_CWORD}\" 2>/dev/null | cut -f1))\n}\n", fn, complete)
+			fmt.Fprintf(buf, "complete -o default -F %s %s\n", fn, alias.Name)
+
+		case ShellZsh:
+			fmt.Fprintf(buf, "%s() { %s \"$@\"; }\n", alias.Name, run)
+			fmt.Fprintf(buf, "%s() {\n\tlocal -a candidates\n\tcandidates=(\"${(@f)$(%s \"${(@)words[2,CURRENT]}\" 2>/dev/null)}\")\n\tcompadd -- \"${(@)candidates%%%%$'\\t'*}\"\n}\n", fn, complete)
+			fmt.Fprintf(buf, "compdef %s %s\n", fn, alias.Name)
+
+		case ShellFish:
+			fmt.Fprintf(buf, "function %s\n\t%s $argv\nend\n", alias.Name, run)
+			fmt.Fprintf(buf, "function %s\n\t%s (commandline -opc)[2..-1] (commandline -ct)\nend\n", fn, complete)
+			fmt.Fprintf(buf, "complete -c %s -f -a '(%s)'\n", alias.Name, fn)
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// shellIdent is a helper that converts a name to a form usable in
+// the names of shell functions.
+func shellIdent(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// shellQuote is a helper that quotes a word for the shell, if
+// necessary, using single quotes.
+func shellQuote(shell, word string) string {
+	if word != "" && strings.Trim(word, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./:=,@%+") == "" {
+		return word
+	}
+
+	// Fish interprets backslashes within single quotes
+	if shell == ShellFish {
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(word) + "'"
+	}
+
+	return "'" + strings.ReplaceAll(word, "'", `'"'"'`) + "'"
+}
+
+// shellAliasOptions are the arguments of the ShellAliasCommand.
+type shellAliasOptions struct {
+	Shell string `arg:"SHELL" arity:"[0,1]" choices:"bash,zsh,fish" help:"Shell to write the aliases for; defaults to the shell in $SHELL"`
+}
+
+// shellAliasCommand implements the ShellAliasCommand.
+type shellAliasCommand struct {
+	Command
+	app *App      // The application
+	inj *Injector // Injector used to compute dynamic subcommands
+}
+
+// newShellAliasCommand constructs the ShellAliasCommand for the
+// application.
+func (a *App) newShellAliasCommand(inj *Injector) *shellAliasCommand {
+	return &shellAliasCommand{
+		Command: Command{
+			Summary:     "Write shell functions for your command aliases",
+			Description: fmt.Sprintf("Write shell functions, with completion, for the command aliases in the %q section of the configuration.  Load them in the shell's startup file, e.g., with:\n\n  eval \"$(%s %s bash)\"", AliasesKey, a.name(), ShellAliasCommand),
+			Defaults:    &shellAliasOptions{},
+		},
+		app: a,
+		inj: inj,
+	}
+}
+
+// Run writes the shell functions for the aliases.
+func (c *shellAliasCommand) Run(ctx context.Context, opts *shellAliasOptions, inv *Invocation, log *Logger, streams *IOStreams) error {
+	shell := opts.Shell
+	if shell == "" {
+		shell = filepath.Base(os.Getenv("SHELL"))
+	}
+	cfg, err := c.app.config(ctx, inv.config, log)
+	if err != nil {
+		return err
+	}
+	aliases, err := c.app.shellAliases(c.inj, cfg)
+	if err != nil {
+		return err
+	}
+	script, err := ShellAliasScript(shell, inv.Path[0], aliases)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(streams.Out, script)
+
+	return err
+}
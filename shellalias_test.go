@@ -0,0 +1,190 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// aliasApp is a helper that constructs an application for testing
+// the command aliases.
+func aliasApp(config string, stdout *bytes.Buffer) *App {
+	return (&App{
+		Name:   "tool",
+		Stdout: stdout,
+		Root: &Command{
+			Subcommands: map[string]ICommand{
+				"get": &Command{
+					Subcommands: map[string]ICommand{
+						"pods": &Command{Summary: "Get pods"},
+					},
+				},
+			},
+		},
+	}).WithConfigFiles(config).WithShellAliases(true)
+}
+
+func TestAppWithShellAliases(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithShellAliases(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.AllowShellAliases)
+}
+
+func TestAppShellAliases(t *testing.T) {
+	config := makeArgFile(t, "aliases:\n  kp: get pods --name \"a b\"\n  k: [get]\n")
+	obj := aliasApp(config, nil)
+
+	result, err := obj.ShellAliases(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []ShellAlias{
+		{Name: "k", Args: []string{"get"}},
+		{Name: "kp", Args: []string{"get", "pods", "--name", "a b"}},
+	}, result)
+}
+
+func TestAppShellAliasesNone(t *testing.T) {
+	config := makeArgFile(t, "verbose: true\n")
+	obj := aliasApp(config, nil)
+
+	result, err := obj.ShellAliases(context.Background())
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestAppShellAliasesInvalid(t *testing.T) {
+	for _, config := range []string{
+		"aliases: [k]\n",
+		"aliases:\n  k!: get\n",
+		"aliases:\n  k: \"\"\n",
+		"aliases:\n  k: get 'pods\n",
+		"aliases:\n  k: [get, 5]\n",
+		"aliases:\n  k: 5\n",
+		"aliases:\n  k: delete pods\n",
+	} {
+		obj := aliasApp(makeArgFile(t, config), nil)
+
+		result, err := obj.ShellAliases(context.Background())
+
+		assert.ErrorIs(t, err, ErrBadAlias, config)
+		assert.Nil(t, result, config)
+	}
+}
+
+func TestAppShellAliasesUnknownCommand(t *testing.T) {
+	obj := aliasApp(makeArgFile(t, "aliases:\n  k: delete pods\n"), nil)
+
+	_, err := obj.ShellAliases(context.Background())
+
+	assert.EqualError(t, err, `invalid alias "k": unknown command "delete"`)
+}
+
+func TestShellAliasScriptBash(t *testing.T) {
+	result, err := ShellAliasScript(ShellBash, "tool", []ShellAlias{{Name: "kp", Args: []string{"get", "it's"}}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `kp() { command tool get 'it'"'"'s' "$@"; }
+_tool_alias_kp() {
+	local IFS=$'\n'
+	COMPREPLY=($(command tool __complete -- get 'it'"'"'s' "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null | cut -f1))
+}
+complete -o default -F _tool_alias_kp kp
+`, result)
+}
+
+func TestShellAliasScriptZsh(t *testing.T) {
+	result, err := ShellAliasScript(ShellZsh, "my-tool", []ShellAlias{{Name: "k", Args: []string{"get"}}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `k() { command my-tool get "$@"; }
+_my_tool_alias_k() {
+	local -a candidates
+	candidates=("${(@f)$(command my-tool __complete -- get "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	compadd -- "${(@)candidates%%$'\t'*}"
+}
+compdef _my_tool_alias_k k
+`, result)
+}
+
+func TestShellAliasScriptFish(t *testing.T) {
+	result, err := ShellAliasScript(ShellFish, "tool", []ShellAlias{{Name: "k", Args: []string{"get", `it's\`}}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `function k
+	command tool get 'it\'s\\' $argv
+end
+function _tool_alias_k
+	command tool __complete -- get 'it\'s\\' (commandline -opc)[2..-1] (commandline -ct)
+end
+complete -c k -f -a '(_tool_alias_k)'
+`, result)
+}
+
+func TestShellAliasScriptUnknownShell(t *testing.T) {
+	result, err := ShellAliasScript("csh", "tool", nil)
+
+	assert.ErrorIs(t, err, ErrUnknownShell)
+	assert.Equal(t, "", result)
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "get", shellQuote(ShellBash, "get"))
+	assert.Equal(t, "--name=a.b", shellQuote(ShellBash, "--name=a.b"))
+	assert.Equal(t, "''", shellQuote(ShellBash, ""))
+	assert.Equal(t, "'a b'", shellQuote(ShellZsh, "a b"))
+	assert.Equal(t, `'$HOME'`, shellQuote(ShellFish, "$HOME"))
+}
+
+func TestAppDispatchShellAlias(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := aliasApp(makeArgFile(t, "aliases:\n  k: get\n"), stdout)
+
+	err := obj.Dispatch(context.Background(), []string{"alias", "fish"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `function k
+	command tool get $argv
+end
+function _tool_alias_k
+	command tool __complete -- get (commandline -opc)[2..-1] (commandline -ct)
+end
+complete -c k -f -a '(_tool_alias_k)'
+`, stdout.String())
+}
+
+func TestAppDispatchShellAliasDefaultShell(t *testing.T) {
+	setEnv(t, "SHELL", "/bin/csh")
+	obj := aliasApp(makeArgFile(t, "aliases:\n  k: get\n"), &bytes.Buffer{})
+
+	err := obj.Dispatch(context.Background(), []string{"alias"})
+
+	assert.ErrorIs(t, err, ErrUnknownShell)
+}
+
+func TestAppDispatchShellAliasBadConfig(t *testing.T) {
+	obj := aliasApp(makeArgFile(t, "aliases: 5\n"), &bytes.Buffer{})
+
+	err := obj.Dispatch(context.Background(), []string{"alias", "bash"})
+
+	assert.ErrorIs(t, err, ErrBadAlias)
+}
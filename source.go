@@ -0,0 +1,184 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Source identifies where the value of a flag came from.  Values are
+// resolved in order of increasing precedence: the defaults struct,
+// the configuration file, the environment, and the command line.
+type Source int
+
+// Recognized sources of flag values.
+const (
+	SourceDefault Source = iota // Value is from the command's defaults
+	SourceConfig                // Value is from the configuration file
+	SourceEnv                   // Value is from an environment variable
+	SourceFlag                  // Value is from the command line
+)
+
+// String returns a description of the source.
+func (s Source) String() string {
+	switch s {
+	case SourceConfig:
+		return "config"
+	case SourceEnv:
+		return "env"
+	case SourceFlag:
+		return "flag"
+	default:
+		return "default"
+	}
+}
+
+// setSource records the source of a flag's value.
+func (o *options) setSource(opt *option, src Source) {
+	if o.sources == nil {
+		o.sources = map[string]Source{}
+	}
+	o.sources[opt.String()] = src
+}
+
+// source returns the source of a flag's value.  The flag may be
+// given by any of its names, as "--name" or "-s".
+func (o *options) source(flag string) (Source, bool) {
+	for _, opt := range o.Set.Options {
+		if opt.Matches(flag) {
+			return o.sources[opt.String()], true
+		}
+	}
+
+	return SourceDefault, false
+}
+
+// flagName is a helper that adds the dashes to a flag name given
+// without them: a single character is taken to be a short flag, and
+// anything longer a long flag.
+func flagName(name string) string {
+	switch {
+	case strings.HasPrefix(name, "-"):
+		return name
+	case utf8.RuneCountInString(name) == 1:
+		return "-" + name
+	default:
+		return "--" + name
+	}
+}
+
+// inheritSources copies the sources of flag values from the parent
+// options, for flags the options share with the parent.  This
+// complements inheritDefaults.
+func (o *options) inheritSources(parent *options) {
+	if parent == nil {
+		return
+	}
+
+	for flag, src := range parent.sources {
+		if _, ok := o.source(flag); ok {
+			if o.sources == nil {
+				o.sources = map[string]Source{}
+			}
+			o.sources[flag] = src
+		}
+	}
+}
+
+// Source reports where the value of a flag of the resolved command,
+// or of a global flag, came from, for debugging configuration
+// problems.  The flag may be given by any of its names, as "--name"
+// or "-s"; the dashes may be omitted.  If there is no such flag,
+// SourceDefault is returned.
+func (inv *Invocation) Source(flag string) Source {
+	flag = flagName(flag)
+	for i := len(inv.options) - 1; i >= 0; i-- {
+		if src, ok := inv.options[i].source(flag); ok {
+			return src
+		}
+	}
+	for _, opts := range inv.globals {
+		if src, ok := opts.source(flag); ok {
+			return src
+		}
+	}
+
+	return SourceDefault
+}
+
+// FlagsGiven returns the flags of the resolved command, and the
+// global flags, whose values were given on the command line, sorted
+// by name.  Flags are named by their long names, as "--name", or by
+// their short names, as "-s", if they have no long names.  The
+// values of the flags are not included, so the result is safe to
+// report, e.g., for telemetry.
+func (inv *Invocation) FlagsGiven() []string {
+	seen := map[string]bool{}
+	flags := []string{}
+	for _, opts := range append(append([]*options{}, inv.options...), inv.globals...) {
+		for flag, src := range opts.sources {
+			if src == SourceFlag && !seen[flag] {
+				seen[flag] = true
+				flags = append(flags, flag)
+			}
+		}
+	}
+	sort.Strings(flags)
+
+	return flags
+}
+
+// FlagValues returns the values of the flags named by FlagsGiven, by
+// flag name, formatted as text.  The values of secret flags are
+// Redacted, and flags that open files give the paths named, so the
+// result is suitable for recording, e.g., in an audit log.
+func (inv *Invocation) FlagValues() map[string]string {
+	values := map[string]string{}
+	all := append(append([]*options{}, inv.options...), inv.globals...)
+	for i := len(all) - 1; i >= 0; i-- {
+		for _, opt := range all[i].Set.Options {
+			flag := opt.String()
+			if _, seen := values[flag]; !seen && all[i].sources[flag] == SourceFlag {
+				values[flag] = all[i].valueText(opt)
+			}
+		}
+	}
+
+	return values
+}
+
+// valueText returns the value of a flag, formatted as text for
+// FlagValues.
+func (o *options) valueText(opt *option) string {
+	if opt.IsSecret() {
+		return Redacted
+	} else if path, ok := o.paths[opt]; ok {
+		return path
+	}
+
+	v := o.Field(opt.Index)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceString(t *testing.T) {
+	assert.Equal(t, "default", SourceDefault.String())
+	assert.Equal(t, "config", SourceConfig.String())
+	assert.Equal(t, "env", SourceEnv.String())
+	assert.Equal(t, "flag", SourceFlag.String())
+}
+
+func TestOptionsSetSource(t *testing.T) {
+	opts, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	opts.setSource(opts.Set.Options[0], SourceEnv)
+
+	assert.Equal(t, map[string]Source{"--verbose": SourceEnv}, opts.sources)
+}
+
+func TestOptionsSourceBase(t *testing.T) {
+	opts, _ := newOptions(&Command{Defaults: &testOptions{}})
+	opts.sources = map[string]Source{"--verbose": SourceFlag}
+
+	result, ok := opts.source("--verbose")
+
+	assert.True(t, ok)
+	assert.Equal(t, SourceFlag, result)
+}
+
+func TestOptionsSourceShort(t *testing.T) {
+	opts, _ := newOptions(&Command{Defaults: &testOptions{}})
+	opts.sources = map[string]Source{"--verbose": SourceFlag}
+
+	result, ok := opts.source("-v")
+
+	assert.True(t, ok)
+	assert.Equal(t, SourceFlag, result)
+}
+
+func TestOptionsSourceDefault(t *testing.T) {
+	opts, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	result, ok := opts.source("--name")
+
+	assert.True(t, ok)
+	assert.Equal(t, SourceDefault, result)
+}
+
+func TestOptionsSourceMissing(t *testing.T) {
+	opts, _ := newOptions(&Command{Defaults: &testOptions{}})
+
+	result, ok := opts.source("--missing")
+
+	assert.False(t, ok)
+	assert.Equal(t, SourceDefault, result)
+}
+
+func TestOptionsInheritSourcesBase(t *testing.T) {
+	parent, _ := newOptions(&Command{Defaults: &RootOptions{}})
+	parent.sources = map[string]Source{"--verbose": SourceEnv}
+	opts, _ := newOptions(&Command{Defaults: &subOptions{}})
+
+	opts.inheritSources(parent)
+
+	assert.Equal(t, map[string]Source{"--verbose": SourceEnv}, opts.sources)
+}
+
+func TestOptionsInheritSourcesUnshared(t *testing.T) {
+	parent, _ := newOptions(&Command{Defaults: &testOptions{}})
+	parent.sources = map[string]Source{"--tag": SourceEnv}
+	opts, _ := newOptions(&Command{Defaults: &subOptions{}})
+
+	opts.inheritSources(parent)
+
+	assert.Nil(t, opts.sources)
+}
+
+func TestOptionsInheritSourcesNoParent(t *testing.T) {
+	opts, _ := newOptions(&Command{Defaults: &subOptions{}})
+
+	opts.inheritSources(nil)
+
+	assert.Nil(t, opts.sources)
+}
+
+func TestInvocationSourceBase(t *testing.T) {
+	root, _ := newOptions(&Command{Defaults: &RootOptions{}})
+	root.sources = map[string]Source{"--verbose": SourceEnv}
+	sub, _ := newOptions(&Command{Defaults: &subOptions{}})
+	sub.sources = map[string]Source{"--verbose": SourceFlag}
+	obj := &Invocation{options: []*options{root, sub}}
+
+	result := obj.Source("--verbose")
+
+	assert.Equal(t, SourceFlag, result)
+}
+
+func TestInvocationSourceAncestor(t *testing.T) {
+	root, _ := newOptions(&Command{Defaults: &testOptions{}})
+	root.sources = map[string]Source{"--tag": SourceConfig}
+	sub, _ := newOptions(&Command{Defaults: &subOptions{}})
+	obj := &Invocation{options: []*options{root, sub}}
+
+	result := obj.Source("--tag")
+
+	assert.Equal(t, SourceConfig, result)
+}
+
+func TestInvocationSourceBare(t *testing.T) {
+	root, _ := newOptions(&Command{Defaults: &testOptions{}})
+	root.sources = map[string]Source{"--verbose": SourceEnv, "--count": SourceFlag}
+	obj := &Invocation{options: []*options{root}}
+
+	assert.Equal(t, SourceEnv, obj.Source("verbose"))
+	assert.Equal(t, SourceFlag, obj.Source("c"))
+}
+
+func TestFlagName(t *testing.T) {
+	assert.Equal(t, "--name", flagName("--name"))
+	assert.Equal(t, "-n", flagName("-n"))
+	assert.Equal(t, "--name", flagName("name"))
+	assert.Equal(t, "-n", flagName("n"))
+}
+
+func TestInvocationSourceMissing(t *testing.T) {
+	obj := &Invocation{}
+
+	result := obj.Source("--verbose")
+
+	assert.Equal(t, SourceDefault, result)
+}
+
+func TestInvocationFlagsGiven(t *testing.T) {
+	root, _ := newOptions(&Command{Defaults: &RootOptions{}})
+	root.sources = map[string]Source{"--verbose": SourceFlag}
+	sub, _ := newOptions(&Command{Defaults: &subOptions{}})
+	sub.sources = map[string]Source{"--verbose": SourceFlag, "--name": SourceFlag}
+	globals, _ := newOptions(&Command{Defaults: &testOptions{}})
+	globals.sources = map[string]Source{"--tag": SourceEnv, "--count": SourceFlag}
+	obj := &Invocation{options: []*options{root, sub}, globals: []*options{globals}}
+
+	result := obj.FlagsGiven()
+
+	assert.Equal(t, []string{"--count", "--name", "--verbose"}, result)
+}
+
+func TestInvocationFlagsGivenNone(t *testing.T) {
+	obj := &Invocation{}
+
+	result := obj.FlagsGiven()
+
+	assert.Equal(t, []string{}, result)
+}
+
+type flagValueOptions struct {
+	Name  string    `opt:"name"`
+	Token string    `opt:"token" kind:"secret"`
+	Limit *int      `opt:"limit"`
+	Unset *int      `opt:"unset"`
+	In    io.Reader `opt:"in" kind:"input"`
+}
+
+func TestInvocationFlagValues(t *testing.T) {
+	limit := 5
+	root, _ := newOptions(&Command{Defaults: &flagValueOptions{Name: "root"}})
+	root.sources = map[string]Source{"--name": SourceFlag}
+	sub, _ := newOptions(&Command{Defaults: &flagValueOptions{
+		Name:  "sub",
+		Token: "hunter2",
+		Limit: &limit,
+	}})
+	sub.sources = map[string]Source{
+		"--name":       SourceFlag,
+		"--token-file": SourceFlag,
+		"--limit":      SourceFlag,
+		"--unset":      SourceFlag,
+		"--in":         SourceFlag,
+	}
+	sub.paths = map[*option]string{sub.Set.long["in"]: "input.txt"}
+	globals, _ := newOptions(&Command{Defaults: &testOptions{Count: 3, Tags: []string{"a", "b"}}})
+	globals.sources = map[string]Source{"--count": SourceFlag, "--tag": SourceEnv}
+	obj := &Invocation{options: []*options{root, sub}, globals: []*options{globals}}
+
+	result := obj.FlagValues()
+
+	assert.Equal(t, map[string]string{
+		"--name":       "sub",
+		"--token-file": Redacted,
+		"--limit":      "5",
+		"--unset":      "",
+		"--in":         "input.txt",
+		"--count":      "3",
+	}, result)
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+// SpecCommand is the name of the hidden subcommand that writes the
+// machine-readable specification of the application to standard
+// output as JSON, as in "tool __spec".  It is added to the root
+// command alongside the HelpCommand, unless the root declares its own
+// SpecCommand.
+const SpecCommand = "__spec"
+
+// SpecSchemaID identifies the schema that the specifications produced
+// by App.Spec conform to.  It is recorded in the "$schema" property
+// of the specification.
+const SpecSchemaID = "urn:nelson:spec:v1"
+
+// SpecSchema is the JSON Schema describing the specifications
+// produced by App.Spec and Export.
+const SpecSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "urn:nelson:spec:v1",
+  "title": "Command line interface specification",
+  "$ref": "#/definitions/command",
+  "definitions": {
+    "command": {
+      "type": "object",
+      "required": ["name"],
+      "additionalProperties": false,
+      "properties": {
+        "$schema": {"type": "string"},
+        "name": {"type": "string"},
+        "summary": {"type": "string"},
+        "description": {"type": "string"},
+        "group": {"type": "string"},
+        "hidden": {"type": "boolean"},
+        "alias": {"type": "boolean"},
+        "deprecated": {"$ref": "#/definitions/deprecation"},
+        "flags": {"type": "array", "items": {"$ref": "#/definitions/flag"}},
+        "globals": {"type": "array", "items": {"$ref": "#/definitions/flag"}},
+        "args": {"type": "array", "items": {"$ref": "#/definitions/arg"}},
+        "subcommands": {"type": "array", "items": {"$ref": "#/definitions/command"}}
+      }
+    },
+    "flag": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "name": {"type": "string"},
+        "aliases": {"type": "array", "items": {"type": "string"}},
+        "short": {"type": "string"},
+        "type": {"type": "string"},
+        "help": {"type": "string"},
+        "hint": {"type": "string"},
+        "choices": {"type": "array", "items": {"type": "string"}},
+        "default": {"type": "string"},
+        "optional": {"type": "string"},
+        "hidden": {"type": "boolean"},
+        "deprecated": {"type": "string"},
+        "complete": {"type": "string"},
+        "env": {"type": "string"},
+        "config": {"type": "string"}
+      }
+    },
+    "arg": {
+      "type": "object",
+      "required": ["name"],
+      "additionalProperties": false,
+      "properties": {
+        "name": {"type": "string"},
+        "type": {"type": "string"},
+        "arity": {"type": "string"},
+        "help": {"type": "string"},
+        "hint": {"type": "string"},
+        "choices": {"type": "array", "items": {"type": "string"}},
+        "complete": {"type": "string"}
+      }
+    },
+    "deprecation": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "alternative": {"type": "string"},
+        "since": {"type": "string", "format": "date"},
+        "removeBy": {"type": "string", "format": "date"}
+      }
+    }
+  }
+}
+`
+
+// Spec produces the machine-readable specification of the
+// application, for use by GUI wrappers and compatibility checkers.
+// Unlike Export, the specification includes the application-global
+// flags, and the environment variables and configuration keys bound
+// to each flag; configuration keys are only included if the
+// application sets its configuration files or search paths
+// explicitly.  Dynamic subcommands, such as plugins, are included,
+// along with the flags advertised by plugin manifests.  The root
+// command is named for the application.
+func (a *App) Spec() *CommandSpec {
+	e := &exporter{
+		env:       true,
+		envPrefix: a.EnvPrefix,
+		config:    a.hasConfig(),
+		inj:       a.newInjector(nil),
+	}
+	spec := e.command(a.name(), a.Root, nil)
+	spec.Schema = SpecSchemaID
+
+	// Describe the global flags
+	for _, obj := range a.Globals {
+		if opts, err := optionsFor(obj, true); err == nil && opts != nil {
+			spec.Globals = append(spec.Globals, e.flags(opts, nil)...)
+		}
+	}
+
+	return spec
+}
+
+// specCommand implements the SpecCommand.
+type specCommand struct {
+	Command
+	app *App // The application
+}
+
+// IsHidden returns true, since the SpecCommand is not intended for
+// users.
+func (c *specCommand) IsHidden() bool {
+	return true
+}
+
+// Run writes the specification of the application.
+func (c *specCommand) Run(streams *IOStreams) error {
+	text, err := c.app.Spec().JSON()
+	if err != nil {
+		return err
+	}
+	_, err = streams.Out.Write(append(text, '\n'))
+
+	return err
+}
+
+// withSpec adds the SpecCommand to the subcommands of the root
+// command, unless the root has no subcommands or declares its own.
+// The subcommands are copied rather than modified.
+func (a *App) withSpec(subs map[string]ICommand) map[string]ICommand {
+	if _, ok := subs[SpecCommand]; ok || len(subs) == 0 {
+		return subs
+	}
+
+	return withBuiltin(subs, map[string]ICommand{SpecCommand: a.newSpecCommand()})
+}
+
+// newSpecCommand constructs the SpecCommand for the application.
+func (a *App) newSpecCommand() *specCommand {
+	return &specCommand{
+		Command: Command{
+			Summary: "Write the specification of the command line interface",
+		},
+		app: a,
+	}
+}
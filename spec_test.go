@@ -0,0 +1,170 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type specOptions struct {
+	Region string `opt:"region" help:"Region to use" config:"cloud.region"`
+	Token  string `opt:"token" env:"API_TOKEN" config:"-"`
+	Quiet  bool   `opt:"quiet,q" env:"-"`
+}
+
+// schemaProperties is a helper that returns the names of the
+// properties of a definition in the SpecSchema.
+func schemaProperties(t *testing.T, def string) []string {
+	schema := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(SpecSchema), &schema))
+	props := schema["definitions"].(map[string]interface{})[def].(map[string]interface{})["properties"].(map[string]interface{})
+
+	names := []string{}
+	for name := range props {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// jsonNames is a helper that returns the JSON property names of the
+// fields of a struct.
+func jsonNames(obj interface{}) []string {
+	typ := reflect.TypeOf(obj)
+	names := []string{}
+	for i := 0; i < typ.NumField(); i++ {
+		names = append(names, strings.Split(typ.Field(i).Tag.Get("json"), ",")[0])
+	}
+
+	return names
+}
+
+func TestSpecSchema(t *testing.T) {
+	assert.ElementsMatch(t, jsonNames(CommandSpec{}), schemaProperties(t, "command"))
+	assert.ElementsMatch(t, jsonNames(FlagSpec{}), schemaProperties(t, "flag"))
+	assert.ElementsMatch(t, jsonNames(ArgSpec{}), schemaProperties(t, "arg"))
+	assert.ElementsMatch(t, jsonNames(DeprecationSpec{}), schemaProperties(t, "deprecation"))
+}
+
+func TestAppSpecBase(t *testing.T) {
+	obj := &App{
+		Name: "app",
+		Root: &Command{
+			Subcommands: map[string]ICommand{
+				"sub": &Command{Defaults: &specOptions{}},
+			},
+		},
+		EnvPrefix:  "APP",
+		ConfigFile: "app.yaml",
+		Globals:    []interface{}{&globalOptions{}},
+	}
+
+	result := obj.Spec()
+
+	assert.Equal(t, SpecSchemaID, result.Schema)
+	assert.Equal(t, "app", result.Name)
+	require.Len(t, result.Globals, 2)
+	assert.Equal(t, "log-level", result.Globals[0].Name)
+	assert.Equal(t, "APP_LOG_LEVEL", result.Globals[0].Env)
+	assert.Equal(t, "log-level", result.Globals[0].Config)
+	require.Len(t, result.Subcommands, 1)
+	flags := result.Subcommands[0].Flags
+	require.Len(t, flags, 3)
+	assert.Equal(t, "APP_SUB_REGION", flags[0].Env)
+	assert.Equal(t, "cloud.region", flags[0].Config)
+	assert.Equal(t, "API_TOKEN", flags[1].Env)
+	assert.Equal(t, "", flags[1].Config)
+	assert.Equal(t, "", flags[2].Env)
+	assert.Equal(t, "sub.quiet", flags[2].Config)
+}
+
+func TestAppSpecNoConfig(t *testing.T) {
+	obj := &App{
+		Root: &Command{Defaults: &specOptions{}},
+	}
+
+	result := obj.Spec()
+
+	require.Len(t, result.Flags, 3)
+	assert.Equal(t, "", result.Flags[0].Env)
+	assert.Equal(t, "API_TOKEN", result.Flags[1].Env)
+	assert.Equal(t, "", result.Flags[0].Config)
+	assert.Nil(t, result.Globals)
+}
+
+func TestExportNoBindings(t *testing.T) {
+	result := Export(&Command{Defaults: &specOptions{}})
+
+	require.Len(t, result.Flags, 3)
+	assert.Equal(t, "", result.Flags[0].Env)
+	assert.Equal(t, "", result.Flags[1].Env)
+	assert.Equal(t, "", result.Flags[0].Config)
+	assert.Equal(t, "", result.Schema)
+}
+
+func TestAppDispatchSpec(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout)
+
+	err := obj.Dispatch(context.Background(), []string{SpecCommand})
+
+	assert.NoError(t, err)
+	result, err := ReadSpec(stdout)
+	require.NoError(t, err)
+	assert.Equal(t, SpecSchemaID, result.Schema)
+	assert.Equal(t, "app", result.Name)
+	require.Len(t, result.Subcommands, 2)
+	assert.Equal(t, "host", result.Subcommands[0].Name)
+	assert.Equal(t, "sub", result.Subcommands[1].Name)
+}
+
+func TestAppWithSpecBase(t *testing.T) {
+	sub := &Command{}
+	subs := map[string]ICommand{"sub": sub}
+	obj := &App{}
+
+	result := obj.withSpec(subs)
+
+	assert.Len(t, result, 2)
+	assert.Same(t, sub, result["sub"])
+	assert.True(t, IsHidden(result[SpecCommand]))
+	assert.Len(t, subs, 1)
+}
+
+func TestAppWithSpecNoSubcommands(t *testing.T) {
+	obj := &App{}
+
+	result := obj.withSpec(nil)
+
+	assert.Nil(t, result)
+}
+
+func TestAppWithSpecDeclared(t *testing.T) {
+	spec := &Command{}
+	subs := map[string]ICommand{SpecCommand: spec}
+	obj := &App{}
+
+	result := obj.withSpec(subs)
+
+	assert.Same(t, spec, result[SpecCommand])
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// StdinTag is the struct tag that allows the value of a flag or
+// argument to be read from standard input by giving "-" as the value
+// on the command line.  The tag's value selects what is read: StdinAll
+// reads the entire content, as for documents; StdinLine reads a
+// single line, without its line ending, as for secrets.  Standard
+// input may be read for only one flag or argument per invocation.
+const StdinTag = "stdin"
+
+// Values for StdinTag.
+const (
+	StdinAll  = "all"  // Read the entire content of standard input
+	StdinLine = "line" // Read a single line from standard input
+)
+
+// ErrStdinUsed indicates that standard input was requested for more
+// than one flag or argument.
+var ErrStdinUsed = errors.New("standard input already read for")
+
+// stdinTag is a helper that retrieves the value of StdinTag for a
+// field, checking that it is valid.
+func stdinTag(tag string) (string, error) {
+	switch tag {
+	case "", StdinAll, StdinLine:
+		return tag, nil
+	}
+
+	return "", fmt.Errorf("bad %s tag %q", StdinTag, tag)
+}
+
+// stdinSource provides standard input for values given as "-",
+// ensuring that it is read only once.
+type stdinSource struct {
+	r    io.Reader // Standard input
+	used string    // Name of the flag or argument that read it
+}
+
+// read reads a value from standard input for the named flag or
+// argument.  The mode is StdinAll or StdinLine.
+func (s *stdinSource) read(name, mode string) (string, error) {
+	if s.used != "" {
+		return "", fmt.Errorf("%w %s", ErrStdinUsed, s.used)
+	}
+	s.used = name
+
+	if mode == StdinLine {
+		text, err := bufio.NewReader(s.r).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		return strings.TrimRight(text, "\r\n"), nil
+	}
+
+	data, err := ioutil.ReadAll(s.r)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// fromStdin is a helper that reads the value of a flag or argument
+// from standard input if the value is "-" and the mode permits.
+// Otherwise, the value is returned unchanged.
+func (o *options) fromStdin(name, mode, text string) (string, error) {
+	if text != "-" || mode == "" || o.stdin == nil {
+		return text, nil
+	}
+
+	return o.stdin.read(name, mode)
+}
@@ -0,0 +1,260 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stdinOptions struct {
+	Token    string   `opt:"token" stdin:"line"`
+	Name     string   `opt:"name"`
+	Document string   `arg:"DOC" stdin:"all"`
+	Rest     []string `arg:"REST" arity:"[0,)"`
+}
+
+type stdinCommand struct {
+	Command
+	opts *stdinOptions
+}
+
+func (c *stdinCommand) Run(opts *stdinOptions) {
+	c.opts = opts
+}
+
+// stdinOptionsFor is a helper that constructs options for
+// stdinOptions reading standard input from the specified content.
+func stdinOptionsFor(content string) *options {
+	obj, _ := newOptions(&Command{Defaults: &stdinOptions{}})
+	obj.stdin = &stdinSource{r: bytes.NewBufferString(content)}
+
+	return obj
+}
+
+func TestStdinTagValid(t *testing.T) {
+	for _, tag := range []string{"", StdinAll, StdinLine} {
+		result, err := stdinTag(tag)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tag, result)
+	}
+}
+
+func TestStdinTagInvalid(t *testing.T) {
+	result, err := stdinTag("bogus")
+
+	assert.EqualError(t, err, `bad stdin tag "bogus"`)
+	assert.Equal(t, "", result)
+}
+
+func TestNewOptionSetStdin(t *testing.T) {
+	result, err := newOptionSet(reflect.TypeOf(stdinOptions{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, StdinLine, result.Options[0].Stdin)
+	assert.Equal(t, "", result.Options[1].Stdin)
+	assert.Equal(t, StdinAll, result.Args[0].Stdin)
+}
+
+func TestNewOptionSetBadStdin(t *testing.T) {
+	type opts struct {
+		Name string `opt:"name" stdin:"bogus"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetBadArgStdin(t *testing.T) {
+	type opts struct {
+		Name string `arg:"NAME" stdin:"bogus"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestStdinSourceReadAll(t *testing.T) {
+	obj := &stdinSource{r: bytes.NewBufferString("line 1\nline 2\n")}
+
+	result, err := obj.read("DOC", StdinAll)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "line 1\nline 2\n", result)
+	assert.Equal(t, "DOC", obj.used)
+}
+
+func TestStdinSourceReadAllError(t *testing.T) {
+	obj := &stdinSource{r: iotest.ErrReader(assert.AnError)}
+
+	result, err := obj.read("DOC", StdinAll)
+
+	assert.Same(t, assert.AnError, err)
+	assert.Equal(t, "", result)
+}
+
+func TestStdinSourceReadLine(t *testing.T) {
+	obj := &stdinSource{r: bytes.NewBufferString("hunter2\r\nmore\n")}
+
+	result, err := obj.read("--token", StdinLine)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", result)
+	assert.Equal(t, "--token", obj.used)
+}
+
+func TestStdinSourceReadLineEOF(t *testing.T) {
+	obj := &stdinSource{r: bytes.NewBufferString("hunter2")}
+
+	result, err := obj.read("--token", StdinLine)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", result)
+}
+
+func TestStdinSourceReadLineError(t *testing.T) {
+	obj := &stdinSource{r: iotest.ErrReader(assert.AnError)}
+
+	result, err := obj.read("--token", StdinLine)
+
+	assert.Same(t, assert.AnError, err)
+	assert.Equal(t, "", result)
+}
+
+func TestStdinSourceReadUsed(t *testing.T) {
+	obj := &stdinSource{r: bytes.NewBufferString("data"), used: "--token"}
+
+	result, err := obj.read("DOC", StdinAll)
+
+	assert.ErrorIs(t, err, ErrStdinUsed)
+	assert.EqualError(t, err, "standard input already read for --token")
+	assert.Equal(t, "", result)
+}
+
+func TestOptionsFromStdinNotDash(t *testing.T) {
+	obj := stdinOptionsFor("data")
+
+	result, err := obj.fromStdin("DOC", StdinAll, "value")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "value", result)
+	assert.Equal(t, "", obj.stdin.used)
+}
+
+func TestOptionsFromStdinNoMode(t *testing.T) {
+	obj := stdinOptionsFor("data")
+
+	result, err := obj.fromStdin("--name", "", "-")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "-", result)
+	assert.Equal(t, "", obj.stdin.used)
+}
+
+func TestOptionsFromStdinNoSource(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &stdinOptions{}})
+
+	result, err := obj.fromStdin("DOC", StdinAll, "-")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "-", result)
+}
+
+func TestOptionsFromStdin(t *testing.T) {
+	obj := stdinOptionsFor("data")
+
+	result, err := obj.fromStdin("DOC", StdinAll, "-")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "data", result)
+}
+
+func TestOptionsParseStdin(t *testing.T) {
+	obj := stdinOptionsFor("hunter2\n")
+
+	_, _, err := obj.parse([]string{"--token", "-", "--name", "-"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &stdinOptions{Token: "hunter2", Name: "-"}, obj.Value.Interface())
+}
+
+func TestOptionsParseStdinUsed(t *testing.T) {
+	obj := stdinOptionsFor("hunter2\n")
+
+	_, _, err := obj.parse([]string{"--token", "-", "--token=-"}, nil)
+
+	assert.ErrorIs(t, err, ErrStdinUsed)
+}
+
+func TestOptionsBindStdin(t *testing.T) {
+	obj := stdinOptionsFor("document\n")
+
+	err := obj.bind([]string{"-", "-"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &stdinOptions{Document: "document\n", Rest: []string{"-"}}, obj.Value.Interface())
+}
+
+func TestOptionsBindStdinUsed(t *testing.T) {
+	obj := stdinOptionsFor("hunter2\n")
+	obj.stdin.used = "--token"
+
+	err := obj.bind([]string{"-"})
+
+	assert.ErrorIs(t, err, ErrStdinUsed)
+}
+
+func TestAppDispatchStdin(t *testing.T) {
+	root := &stdinCommand{
+		Command: Command{Defaults: &stdinOptions{}},
+	}
+	obj := &App{
+		Root:  root,
+		Stdin: bytes.NewBufferString("document"),
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--token", "tok", "-"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &stdinOptions{Token: "tok", Document: "document"}, root.opts)
+}
+
+func TestAppDispatchStdinUsed(t *testing.T) {
+	root := &stdinCommand{
+		Command: Command{Defaults: &stdinOptions{}},
+	}
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Root:   root,
+		Stdin:  bytes.NewBufferString("document"),
+		Stderr: stderr,
+		Exiter: ExiterFunc(func(int) {}),
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--token", "-", "-"})
+
+	assert.ErrorIs(t, err, ErrStdinUsed)
+	assert.Nil(t, root.opts)
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+// FlagStorer is an optional interface for defaults structs that
+// store the values of their flags and arguments without reflection.
+// Implementations are typically generated by the nelson-gen tool
+// (see cmd/nelson-gen), for applications that care about start-up
+// latency; reflection remains the default.  Allowed choices are
+// checked before StoreFlag is called, and validation is applied
+// afterward as usual.
+type FlagStorer interface {
+	// StoreFlag converts text and stores it in the named field.
+	// The name is a dotted path beginning with the name of the
+	// struct type and passing through any embedded structs, e.g.,
+	// "Options.Common.Verbose", so that a method promoted from an
+	// embedded struct does not handle the fields of the outer
+	// struct.  Values of slice fields are appended.
+	// Conversions should use ParseBool, ParseInt, and similar
+	// functions, so that errors are reported consistently.
+	// Returns false if the field is not handled, in which case it
+	// is set through reflection.
+	StoreFlag(field, text string) (bool, error)
+}
+
+// StaticRunner is an optional interface for commands that call their
+// RunMethod, or ExecuteMethod, without reflection.  Implementations
+// are typically generated by the nelson-gen tool, alongside
+// FlagStorer; commands that do not implement it are called through
+// the injector as usual.
+type StaticRunner interface {
+	// RunStatic calls the run method of the command, supplying its
+	// arguments from the injector with Injector.Inject.  Returns
+	// the result of the method, if it returns one before its
+	// error, and its error.
+	RunStatic(inj *Injector) (interface{}, error)
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type storerOptions struct {
+	Name   string   `opt:"name"`
+	Format string   `opt:"format" choices:"json,yaml"`
+	Count  int      `opt:"count"`
+	Other  string   `opt:"other"`
+	Files  []string `arg:"FILE" arity:"[0,)"`
+
+	stored []string
+}
+
+func (o *storerOptions) StoreFlag(field, text string) (bool, error) {
+	o.stored = append(o.stored, field)
+	switch field {
+	case "storerOptions.Name":
+		o.Name = "stored " + text
+	case "storerOptions.Format":
+		o.Format = text
+	case "storerOptions.Count":
+		v, err := ParseInt(text, 0)
+		if err != nil {
+			return true, err
+		}
+		o.Count = int(v)
+	case "storerOptions.Files":
+		o.Files = append(o.Files, text)
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+type storerCommand struct {
+	Command
+	result *storerOptions
+}
+
+func (c *storerCommand) Run(opts *storerOptions) {
+	c.result = opts
+}
+
+func TestAppDispatchFlagStorer(t *testing.T) {
+	cmd := &storerCommand{Command: Command{Defaults: &storerOptions{}}}
+	obj := (&App{Name: "tool", Root: cmd}).WithNoConfigSearch(true)
+
+	err := obj.Dispatch(context.Background(), []string{"--name", "n", "--format=yaml", "--count", "0x10", "--other", "o", "f1", "f2"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "stored n", cmd.result.Name)
+	assert.Equal(t, "yaml", cmd.result.Format)
+	assert.Equal(t, 16, cmd.result.Count)
+	assert.Equal(t, "o", cmd.result.Other)
+	assert.Equal(t, []string{"f1", "f2"}, cmd.result.Files)
+	assert.Equal(t, []string{
+		"storerOptions.Name",
+		"storerOptions.Format",
+		"storerOptions.Count",
+		"storerOptions.Other",
+		"storerOptions.Files",
+		"storerOptions.Files",
+	}, cmd.result.stored)
+}
+
+func TestAppDispatchFlagStorerChoices(t *testing.T) {
+	cmd := &storerCommand{Command: Command{Defaults: &storerOptions{}}}
+	obj := (&App{Name: "tool", Root: cmd}).WithNoConfigSearch(true)
+
+	err := obj.Dispatch(context.Background(), []string{"--format", "xml"})
+
+	assert.EqualError(t, err, `invalid value "xml" for --format: must be one of json, yaml`)
+	assert.Nil(t, cmd.result)
+}
+
+func TestAppDispatchFlagStorerError(t *testing.T) {
+	cmd := &storerCommand{Command: Command{Defaults: &storerOptions{}}}
+	obj := (&App{Name: "tool", Root: cmd}).WithNoConfigSearch(true)
+
+	err := obj.Dispatch(context.Background(), []string{"--count", "many"})
+
+	assert.EqualError(t, err, `invalid value "many" for --count: not a number`)
+	assert.Nil(t, cmd.result)
+}
+
+func TestAppDispatchFlagStorerLocaleValues(t *testing.T) {
+	cmd := &storerCommand{Command: Command{Defaults: &storerOptions{}}}
+	obj := (&App{Name: "tool", Root: cmd, Locale: "de_DE"}).WithLocaleValues(true).WithNoConfigSearch(true)
+
+	err := obj.Dispatch(context.Background(), []string{"--count", "1.234"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1234, cmd.result.Count)
+	assert.Equal(t, []string{"storerOptions.Count"}, cmd.result.stored)
+}
+
+type staticCommand struct {
+	Command
+	calls []string
+}
+
+func (c *staticCommand) Run(opts *storerOptions) (string, error) {
+	c.calls = append(c.calls, "Run")
+	return opts.Name, nil
+}
+
+func (c *staticCommand) RunStatic(inj *Injector) (interface{}, error) {
+	var opts *storerOptions
+	if err := inj.Inject("Run", &opts); err != nil {
+		return nil, err
+	}
+	c.calls = append(c.calls, "RunStatic")
+	return c.Run(opts)
+}
+
+func TestAppDispatchStaticRunner(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	cmd := &staticCommand{Command: Command{Defaults: &storerOptions{}}}
+	obj := (&App{Name: "tool", Root: cmd, Stdout: stdout}).WithNoConfigSearch(true)
+
+	err := obj.Dispatch(context.Background(), []string{"--name", "n"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"RunStatic", "Run"}, cmd.calls)
+	assert.Equal(t, "stored n\n", stdout.String())
+}
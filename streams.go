@@ -0,0 +1,70 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"os"
+)
+
+// Streams returns the application's streams, as provided to
+// commands by the injector; see WithStreams.  Streams that are not
+// set default to the standard streams of the process.
+func (a *App) Streams() *IOStreams {
+	return &IOStreams{
+		In:     a.stdin(),
+		Out:    a.stdout(),
+		ErrOut: a.stderr(),
+		Help:   a.helpOut(),
+		theme:  a.Theme,
+	}
+}
+
+// NewTestIOStreams constructs IOStreams for testing commands, whose
+// streams are buffers, returning the streams and the buffers for
+// standard input, output, and error, respectively.  Help is written
+// to the output buffer.
+func NewTestIOStreams() (*IOStreams, *bytes.Buffer, *bytes.Buffer, *bytes.Buffer) {
+	in, out, errOut := &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}
+	return &IOStreams{
+		In:     in,
+		Out:    out,
+		ErrOut: errOut,
+	}, in, out, errOut
+}
+
+// IsInTerminal returns true if standard input is a terminal, such
+// that the user may be prompted.
+func (s *IOStreams) IsInTerminal() bool {
+	return streamIsTerminal(s.In)
+}
+
+// IsOutTerminal returns true if standard output is a terminal, rather
+// than being piped to another program or redirected to a file.
+func (s *IOStreams) IsOutTerminal() bool {
+	return streamIsTerminal(s.Out)
+}
+
+// IsErrOutTerminal returns true if standard error is a terminal.
+func (s *IOStreams) IsErrOutTerminal() bool {
+	return streamIsTerminal(s.ErrOut)
+}
+
+// streamIsTerminal is a helper that determines whether a stream is a
+// terminal.  Only files may be terminals.
+func streamIsTerminal(stream interface{}) bool {
+	f, ok := stream.(*os.File)
+	return ok && isTerminal(f)
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppStreamsExported(t *testing.T) {
+	in, out, errOut, help := &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}
+	obj := (&App{}).WithStreams(&IOStreams{In: in, Out: out, ErrOut: errOut, Help: help})
+
+	result := obj.Streams()
+
+	assert.Equal(t, &IOStreams{In: in, Out: out, ErrOut: errOut, Help: help}, result)
+}
+
+func TestAppStreamsExportedDefault(t *testing.T) {
+	obj := &App{}
+
+	result := obj.Streams()
+
+	assert.Equal(t, &IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr, Help: os.Stdout}, result)
+}
+
+func TestNewTestIOStreams(t *testing.T) {
+	streams, in, out, errOut := NewTestIOStreams()
+
+	assert.Same(t, in, streams.In)
+	assert.Same(t, out, streams.Out)
+	assert.Same(t, errOut, streams.ErrOut)
+	assert.Nil(t, streams.Help)
+}
+
+func TestIOStreamsIsTerminal(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	obj := &IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: &bytes.Buffer{}}
+
+	assert.True(t, obj.IsInTerminal())
+	assert.True(t, obj.IsOutTerminal())
+	assert.False(t, obj.IsErrOutTerminal())
+}
+
+func TestIOStreamsIsTerminalNotTerminal(t *testing.T) {
+	fakeTerminal(t, false, nil)
+	obj := &IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
+
+	assert.False(t, obj.IsInTerminal())
+	assert.False(t, obj.IsOutTerminal())
+	assert.False(t, obj.IsErrOutTerminal())
+}
+
+type streamsCommand struct {
+	Command
+}
+
+func (c *streamsCommand) Run(streams *IOStreams) error {
+	_, err := streams.Out.Write([]byte("output\n"))
+	return err
+}
+
+func TestAppDispatchTestIOStreams(t *testing.T) {
+	streams, _, out, _ := NewTestIOStreams()
+	obj := (&App{Root: &streamsCommand{}}).WithNoConfigSearch(true).WithStreams(streams)
+
+	err := obj.Dispatch(context.Background(), []string{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "output\n", out.String())
+}
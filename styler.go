@@ -0,0 +1,76 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+// Styler styles text written to one of the IOStreams, using the
+// application's theme, so that commands need not emit ANSI escape
+// sequences themselves.  Text is left unstyled unless the
+// application has a theme and the stream is a terminal, and coloring
+// is not disabled by the NoColorFlag or the NoColorEnv environment
+// variable; see WithTheme.  On Windows, the console's processing of
+// escape sequences is enabled as needed.
+type Styler struct {
+	theme *Theme // The theme; nil if text is not styled
+}
+
+// OutStyler returns a Styler for text written to Out.
+func (s *IOStreams) OutStyler() *Styler {
+	return &Styler{theme: colorTheme(s.theme, s.Out, s.noColor)}
+}
+
+// ErrStyler returns a Styler for text written to ErrOut.
+func (s *IOStreams) ErrStyler() *Styler {
+	return &Styler{theme: colorTheme(s.theme, s.ErrOut, s.noColor)}
+}
+
+// Enabled returns true if text is styled.
+func (s *Styler) Enabled() bool {
+	return s.theme != nil
+}
+
+// style is a helper that styles text with the style selected from
+// the theme.
+func (s *Styler) style(text string, style func(t *Theme) string) string {
+	if s.theme == nil {
+		return text
+	}
+
+	return s.theme.paint(style(s.theme), text)
+}
+
+// Success styles a message reporting success.
+func (s *Styler) Success(text string) string {
+	return s.style(text, func(t *Theme) string { return t.Success })
+}
+
+// Warn styles a warning.
+func (s *Styler) Warn(text string) string {
+	return s.style(text, func(t *Theme) string { return t.Warning })
+}
+
+// Error styles an error.
+func (s *Styler) Error(text string) string {
+	return s.style(text, func(t *Theme) string { return t.Error })
+}
+
+// Bold styles emphasized text.
+func (s *Styler) Bold(text string) string {
+	return s.style(text, func(t *Theme) string { return t.Bold })
+}
+
+// Dim styles deemphasized text.
+func (s *Styler) Dim(text string) string {
+	return s.style(text, func(t *Theme) string { return t.Dim })
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIOStreamsOutStylerTerminal(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	setEnv(t, NoColorEnv, "")
+	obj := &IOStreams{Out: os.Stdout, theme: DefaultTheme}
+
+	result := obj.OutStyler()
+
+	assert.True(t, result.Enabled())
+	assert.Equal(t, "\x1b[32mok\x1b[0m", result.Success("ok"))
+}
+
+func TestIOStreamsOutStylerNotTerminal(t *testing.T) {
+	fakeTerminal(t, false, nil)
+	setEnv(t, NoColorEnv, "")
+	obj := &IOStreams{Out: os.Stdout, theme: DefaultTheme}
+
+	result := obj.OutStyler()
+
+	assert.False(t, result.Enabled())
+	assert.Equal(t, "ok", result.Success("ok"))
+}
+
+func TestIOStreamsOutStylerNoColor(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	setEnv(t, NoColorEnv, "")
+	obj := &IOStreams{Out: os.Stdout, theme: DefaultTheme, noColor: true}
+
+	result := obj.OutStyler()
+
+	assert.False(t, result.Enabled())
+}
+
+func TestIOStreamsOutStylerNoColorEnv(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	setEnv(t, NoColorEnv, "1")
+	obj := &IOStreams{Out: os.Stdout, theme: DefaultTheme}
+
+	result := obj.OutStyler()
+
+	assert.False(t, result.Enabled())
+}
+
+func TestIOStreamsOutStylerNoTheme(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	setEnv(t, NoColorEnv, "")
+	obj := &IOStreams{Out: os.Stdout}
+
+	result := obj.OutStyler()
+
+	assert.False(t, result.Enabled())
+}
+
+func TestIOStreamsErrStyler(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	setEnv(t, NoColorEnv, "")
+	obj := &IOStreams{Out: os.Stdout, ErrOut: os.Stderr, theme: DefaultTheme}
+
+	result := obj.ErrStyler()
+
+	assert.True(t, result.Enabled())
+	assert.Equal(t, "\x1b[1;31mbad\x1b[0m", result.Error("bad"))
+}
+
+func TestIOStreamsErrStylerNotFile(t *testing.T) {
+	fakeTerminal(t, true, nil)
+	setEnv(t, NoColorEnv, "")
+	obj, _, _, _ := NewTestIOStreams()
+	obj.theme = DefaultTheme
+
+	result := obj.ErrStyler()
+
+	assert.False(t, result.Enabled())
+}
+
+func TestStylerStyles(t *testing.T) {
+	obj := &Styler{theme: DefaultTheme}
+
+	assert.Equal(t, "\x1b[32mtext\x1b[0m", obj.Success("text"))
+	assert.Equal(t, "\x1b[33mtext\x1b[0m", obj.Warn("text"))
+	assert.Equal(t, "\x1b[1mtext\x1b[0m", obj.Bold("text"))
+	assert.Equal(t, "\x1b[2mtext\x1b[0m", obj.Dim("text"))
+}
+
+func TestStylerDisabled(t *testing.T) {
+	obj := &Styler{}
+
+	assert.Equal(t, "text", obj.Success("text"))
+	assert.Equal(t, "text", obj.Warn("text"))
+	assert.Equal(t, "text", obj.Error("text"))
+	assert.Equal(t, "text", obj.Bold("text"))
+	assert.Equal(t, "text", obj.Dim("text"))
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+// Subcommands is a collection of subcommands, keyed by name.  A
+// Subcommands is passed to the function returned by
+// IDynamicSubcommands.GetDynamicSubcommands, which should add any
+// dynamically computed subcommands to it.
+type Subcommands map[string]ICommand
+
+// IDynamicSubcommands is an optional interface for commands which
+// have subcommands that cannot be determined statically, e.g., one
+// subcommand per configured environment.  The function returned by
+// GetDynamicSubcommands is called through the injector at dispatch or
+// completion time; it may declare any arguments available from the
+// injector, and should declare a Subcommands argument to which it
+// adds the additional subcommands.  It may optionally return an
+// error.
+type IDynamicSubcommands interface {
+	// GetDynamicSubcommands retrieves the function used to
+	// compute additional subcommands.
+	GetDynamicSubcommands() interface{}
+}
+
+// resolveSubcommands computes the full set of subcommands for a
+// command, combining the static subcommands with any dynamic
+// subcommands.  The command and any commands it wraps are examined
+// for IDynamicSubcommands implementations.  Static subcommands take
+// precedence over dynamic subcommands with the same name.
+func resolveSubcommands(cmd ICommand, inj *Injector) (map[string]ICommand, error) {
+	// Begin with the dynamic subcommands
+	dynamic := Subcommands{}
+	for tmp := cmd; tmp != nil; {
+		if dyn, ok := tmp.(IDynamicSubcommands); ok {
+			if fn := dyn.GetDynamicSubcommands(); fn != nil {
+				tmpInj := inj.Clone()
+				tmpInj.Provide(dynamic)
+				if err := tmpInj.call("DynamicSubcommands", fn); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// Unwrap the command
+		wrapped, ok := tmp.(IWrapped)
+		if !ok {
+			break
+		}
+		tmp = wrapped.Unwrap()
+	}
+
+	// Short-circuit if there are no dynamic subcommands
+	static := cmd.GetSubcommands()
+	if len(dynamic) == 0 {
+		return static, nil
+	}
+
+	// Overlay the static subcommands
+	for name, sub := range static {
+		dynamic[name] = sub
+	}
+
+	return dynamic, nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSubcommandsStatic(t *testing.T) {
+	sub := &Command{}
+	cmd := &Command{
+		Subcommands: map[string]ICommand{
+			"sub": sub,
+		},
+	}
+
+	result, err := resolveSubcommands(cmd, NewInjector())
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]ICommand{
+		"sub": sub,
+	}, result)
+}
+
+func TestResolveSubcommandsDynamic(t *testing.T) {
+	sub1 := &Command{Summary: "static"}
+	sub2 := &Command{Summary: "dynamic"}
+	sub3 := &Command{Summary: "shadowed"}
+	inj := NewInjector()
+	inj.Provide("env")
+	cmd := &Command{
+		Subcommands: map[string]ICommand{
+			"sub1": sub1,
+		},
+		DynamicSubcommands: func(name string, subs Subcommands) {
+			subs[name] = sub2
+			subs["sub1"] = sub3
+		},
+	}
+
+	result, err := resolveSubcommands(cmd, inj)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]ICommand{
+		"sub1": sub1,
+		"env":  sub2,
+	}, result)
+	assert.Len(t, inj.deps, 1)
+}
+
+func TestResolveSubcommandsWrapped(t *testing.T) {
+	sub := &Command{}
+	cmd := Hidden(&Command{
+		DynamicSubcommands: func(subs Subcommands) {
+			subs["sub"] = sub
+		},
+	})
+
+	result, err := resolveSubcommands(cmd, NewInjector())
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]ICommand{
+		"sub": sub,
+	}, result)
+}
+
+func TestResolveSubcommandsNoFunc(t *testing.T) {
+	cmd := &Command{}
+
+	result, err := resolveSubcommands(cmd, NewInjector())
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestResolveSubcommandsError(t *testing.T) {
+	cmd := &Command{
+		DynamicSubcommands: func(subs Subcommands) error {
+			return assert.AnError
+		},
+	}
+
+	result, err := resolveSubcommands(cmd, NewInjector())
+
+	assert.Same(t, assert.AnError, err)
+	assert.Nil(t, result)
+}
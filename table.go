@@ -0,0 +1,183 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ellipsis marks the cells of a table that were truncated to fit the
+// width of the terminal.
+const Ellipsis = "…"
+
+// Defaults for TableWriter.
+const (
+	DefaultTablePadding  = 2 // Spaces separating the columns
+	DefaultTableMinWidth = 4 // Narrowest width to which a column is truncated
+)
+
+// TableWriter writes a table, aligning its columns.  Rows are
+// buffered until Flush is called, so that the width of each column
+// is known.  If the table is wider than MaxWidth, the widest columns
+// are narrowed, and the cells that no longer fit are truncated and
+// marked with Ellipsis, unless Wide is set.  Cells are measured in
+// runes, and newlines within cells are replaced by spaces.
+type TableWriter struct {
+	Header   []string // Optional headers of the columns
+	MaxWidth int      // Width of the table, in columns; 0 for no limit
+	MinWidth int      // Narrowest width to which a column is truncated
+	Padding  int      // Spaces separating the columns
+	Wide     bool     // If true, cells are never truncated
+
+	w    io.Writer  // The stream to write to
+	rows [][]string // The buffered rows
+}
+
+// NewTableWriter constructs a TableWriter that writes to the
+// specified stream, with the optional headers.  If the stream is a
+// terminal, MaxWidth is the width of the terminal, as described by
+// the ColumnsEnv environment variable or by the terminal itself;
+// otherwise, the table is not limited, so that output consumed by
+// other programs is never truncated.
+func NewTableWriter(w io.Writer, header ...string) *TableWriter {
+	return &TableWriter{
+		Header:   header,
+		MaxWidth: terminalWidth(w),
+		MinWidth: DefaultTableMinWidth,
+		Padding:  DefaultTablePadding,
+		w:        w,
+	}
+}
+
+// terminalWidth is a helper that determines the width, in columns,
+// of the terminal to which the stream writes.  Returns 0 if the
+// stream is not a terminal, or if its width cannot be determined.
+func terminalWidth(w io.Writer) int {
+	if !streamIsTerminal(w) {
+		return 0
+	}
+	if cols, err := strconv.Atoi(os.Getenv(ColumnsEnv)); err == nil && cols > 0 {
+		return cols
+	}
+
+	return termWidth(w.(*os.File))
+}
+
+// Append adds a row to the table.  Rows may have differing numbers
+// of cells; missing cells are empty.
+func (t *TableWriter) Append(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// Flush writes the table, headers first, and discards the buffered
+// rows.  Rows are written without trailing spaces.
+func (t *TableWriter) Flush() error {
+	rows := t.rows
+	t.rows = nil
+	if t.Header != nil {
+		rows = append([][]string{t.Header}, rows...)
+	}
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			cells[j] = strings.ReplaceAll(cell, "\n", " ")
+		}
+		rows[i] = cells
+	}
+
+	widths := t.widths(rows)
+	for _, row := range rows {
+		var buf strings.Builder
+		pending := 0
+		for i, width := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = truncate(row[i], width)
+			}
+			if cell == "" {
+				pending += width + t.Padding
+				continue
+			}
+			buf.WriteString(strings.Repeat(" ", pending))
+			buf.WriteString(cell)
+			pending = width - utf8.RuneCountInString(cell) + t.Padding
+		}
+		buf.WriteString("\n")
+		if _, err := io.WriteString(t.w, buf.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// widths is a helper that computes the width of each column: the
+// width of its widest cell, narrowed to fit MaxWidth unless Wide is
+// set.  The widest columns are narrowed first, but no column is
+// narrowed below MinWidth.
+func (t *TableWriter) widths(rows [][]string) []int {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if size := utf8.RuneCountInString(cell); size > widths[i] {
+				widths[i] = size
+			}
+		}
+	}
+	if t.Wide || t.MaxWidth <= 0 || len(widths) == 0 {
+		return widths
+	}
+
+	total := t.Padding * (len(widths) - 1)
+	for _, width := range widths {
+		total += width
+	}
+	for total > t.MaxWidth {
+		widest := 0
+		for i, width := range widths {
+			if width > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= t.MinWidth {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+
+	return widths
+}
+
+// truncate is a helper that truncates text to the specified width,
+// in runes, marking truncated text with Ellipsis.
+func truncate(text string, width int) string {
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	if width <= 0 {
+		return ""
+	}
+
+	runes := []rune(text)
+	return string(runes[:width-1]) + Ellipsis
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTableWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	result := NewTableWriter(buf, "A", "B")
+
+	assert.Equal(t, &TableWriter{
+		Header:   []string{"A", "B"},
+		MinWidth: DefaultTableMinWidth,
+		Padding:  DefaultTablePadding,
+		w:        buf,
+	}, result)
+}
+
+func TestTerminalWidth(t *testing.T) {
+	setEnv(t, ColumnsEnv, "")
+	fakeTerminal(t, true, nil)
+	fakeTermWidth(t, 120)
+
+	result := terminalWidth(os.Stdout)
+
+	assert.Equal(t, 120, result)
+}
+
+func TestTerminalWidthColumns(t *testing.T) {
+	setEnv(t, ColumnsEnv, "60")
+	fakeTerminal(t, true, nil)
+	fakeTermWidth(t, 120)
+
+	result := terminalWidth(os.Stdout)
+
+	assert.Equal(t, 60, result)
+}
+
+func TestTerminalWidthNotTerminal(t *testing.T) {
+	setEnv(t, ColumnsEnv, "60")
+	fakeTerminal(t, false, nil)
+	fakeTermWidth(t, 120)
+
+	result := terminalWidth(os.Stdout)
+
+	assert.Equal(t, 0, result)
+}
+
+func TestTerminalWidthNotFile(t *testing.T) {
+	result := terminalWidth(&bytes.Buffer{})
+
+	assert.Equal(t, 0, result)
+}
+
+func TestTableWriterFlush(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := NewTableWriter(buf, "NAME", "SIZE", "NOTE")
+	obj.Append("alpha", "1", "first\nline")
+	obj.Append("b", "", "second")
+	obj.Append("gamma", "333")
+
+	err := obj.Flush()
+
+	assert.NoError(t, err)
+	assert.Equal(t, `NAME   SIZE  NOTE
+alpha  1     first line
+b            second
+gamma  333
+`, buf.String())
+	assert.Nil(t, obj.rows)
+}
+
+func TestTableWriterFlushNoHeader(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := NewTableWriter(buf)
+	obj.Padding = 1
+	obj.Append("a", "b")
+	obj.Append("ccc", "d", "e")
+
+	err := obj.Flush()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "a   b\nccc d e\n", buf.String())
+}
+
+func TestTableWriterFlushTruncated(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := NewTableWriter(buf, "NAME", "DESCRIPTION")
+	obj.MaxWidth = 20
+	obj.Append("short", "a rather long description")
+	obj.Append("much-longer-name", "text")
+
+	err := obj.Flush()
+
+	assert.NoError(t, err)
+	assert.Equal(t, `NAME       DESCRIPT…
+short      a rather…
+much-lon…  text
+`, buf.String())
+}
+
+func TestTableWriterFlushMinWidth(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := NewTableWriter(buf)
+	obj.MaxWidth = 5
+	obj.Append("abcdefgh", "ijklmnop")
+
+	err := obj.Flush()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "abc…  ijk…\n", buf.String())
+}
+
+func TestTableWriterFlushWide(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := NewTableWriter(buf)
+	obj.MaxWidth = 5
+	obj.Wide = true
+	obj.Append("abcdefgh", "ijklmnop")
+
+	err := obj.Flush()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "abcdefgh  ijklmnop\n", buf.String())
+}
+
+func TestTableWriterFlushEmpty(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := NewTableWriter(buf)
+	obj.MaxWidth = 5
+
+	err := obj.Flush()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "", buf.String())
+}
+
+type failWriter struct{}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	return 0, assert.AnError
+}
+
+func TestTableWriterFlushError(t *testing.T) {
+	obj := NewTableWriter(&failWriter{})
+	obj.Append("a")
+
+	err := obj.Flush()
+
+	assert.Same(t, assert.AnError, err)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 3))
+	assert.Equal(t, "ab…", truncate("abcd", 3))
+	assert.Equal(t, "hé…", truncate("héllo", 3))
+	assert.Equal(t, "", truncate("abc", 0))
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"strings"
+)
+
+// Attributes set on the span of each command invocation by the
+// Telemetry middleware.
+const (
+	AttrCommand  = "cli.command"     // Path of the command, as "tool sub"
+	AttrFlags    = "cli.flags"       // Names of the flags given on the command line, as a []string
+	AttrExitCode = "cli.exit_code"   // Exit code of the command, as an int
+	AttrDuration = "cli.duration_ms" // Duration of the command in milliseconds, as an int64
+)
+
+// Tracer starts the spans describing command invocations.  It is the
+// hook through which the Telemetry middleware reports to an
+// exporter; for example, an OpenTelemetry tracer may be adapted to
+// it in a few lines.
+type Tracer interface {
+	// Start starts a span with the specified name, returning the
+	// span and a context carrying it.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// SetAttribute sets an attribute of the span.
+	SetAttribute(key string, value interface{})
+
+	// RecordError records the error that ended the command.
+	RecordError(err error)
+
+	// End ends the span.
+	End()
+}
+
+// TracerFunc is an adapter allowing an ordinary function to be used
+// as a Tracer.
+type TracerFunc func(ctx context.Context, name string) (context.Context, Span)
+
+// Start starts a span with the specified name.
+func (f TracerFunc) Start(ctx context.Context, name string) (context.Context, Span) {
+	return f(ctx, name)
+}
+
+// Telemetry returns middleware that starts a span for each command
+// invocation, named for the command path.  The span's attributes
+// describe the command, the flags given on the command line--but
+// not their values, which may be sensitive--the exit code, and the
+// duration; see AttrCommand and the other attributes.  The context
+// carrying the span is available to the command through the
+// injector, so that its own spans are children of the invocation's.
+// Use it with App.Use.
+func Telemetry(tracer Tracer) Middleware {
+	return func(next Runner) Runner {
+		return RunnerFunc(func(ctx context.Context, inv *Invocation, inj *Injector) error {
+			var clock Clock
+			if !inj.Get(&clock) {
+				clock = defaultClock
+			}
+
+			name := strings.Join(inv.Path, " ")
+			ctx, span := tracer.Start(ctx, name)
+			defer span.End()
+			span.SetAttribute(AttrCommand, name)
+			span.SetAttribute(AttrFlags, inv.FlagsGiven())
+
+			start := clock.Now()
+			err := next.Run(ctx, inv, inj)
+			code := 0
+			if err != nil {
+				code, _ = ExitControl(err)
+				span.RecordError(err)
+			}
+			span.SetAttribute(AttrExitCode, code)
+			span.SetAttribute(AttrDuration, clock.Now().Sub(start).Milliseconds())
+
+			return err
+		})
+	}
+}
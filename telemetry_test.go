@@ -0,0 +1,148 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSpan is a Span that records its attributes.
+type fakeSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *fakeSpan) SetAttribute(key string, value interface{}) {
+	s.attrs[key] = value
+}
+
+func (s *fakeSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *fakeSpan) End() {
+	s.ended = true
+}
+
+type spanKey struct{}
+
+// fakeTracer is a helper that returns a Tracer recording its spans.
+func fakeTracer(spans *[]*fakeSpan) Tracer {
+	return TracerFunc(func(ctx context.Context, name string) (context.Context, Span) {
+		span := &fakeSpan{name: name, attrs: map[string]interface{}{}}
+		*spans = append(*spans, span)
+		return context.WithValue(ctx, spanKey{}, span), span
+	})
+}
+
+// manualClock is a Clock whose time is set by tests.
+type manualClock struct {
+	RealClock
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	return c.now
+}
+
+type telemetryOptions struct {
+	Name   string `opt:"name,n"`
+	Force  bool   `opt:",f"`
+	Secret string `opt:"secret"`
+}
+
+type telemetryCommand struct {
+	Command
+	clock *manualClock
+	span  interface{}
+	err   error
+}
+
+func (c *telemetryCommand) Run(ctx context.Context) error {
+	c.span = ctx.Value(spanKey{})
+	c.clock.now = c.clock.now.Add(1500 * time.Millisecond)
+	return c.err
+}
+
+// telemetryApp is a helper that constructs an application for
+// testing the Telemetry middleware.
+func telemetryApp(spans *[]*fakeSpan, err error) (*App, *telemetryCommand) {
+	clock := &manualClock{now: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)}
+	sub := &telemetryCommand{
+		Command: Command{Defaults: &telemetryOptions{}},
+		clock:   clock,
+		err:     err,
+	}
+	app := (&App{
+		Name: "tool",
+		Root: &Command{
+			Subcommands: map[string]ICommand{"sub": sub},
+		},
+	}).WithNoConfigSearch(true).WithClock(clock).Use(Telemetry(fakeTracer(spans)))
+
+	return app, sub
+}
+
+func TestTracerFunc(t *testing.T) {
+	span := &fakeSpan{}
+	obj := TracerFunc(func(ctx context.Context, name string) (context.Context, Span) {
+		assert.Equal(t, "tool", name)
+		return ctx, span
+	})
+
+	_, result := obj.Start(context.Background(), "tool")
+
+	assert.Same(t, span, result)
+}
+
+func TestTelemetry(t *testing.T) {
+	spans := []*fakeSpan{}
+	obj, sub := telemetryApp(&spans, nil)
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "--secret", "hunter2", "-f", "--name=x"})
+
+	assert.NoError(t, err)
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "tool sub", spans[0].name)
+	assert.Equal(t, map[string]interface{}{
+		AttrCommand:  "tool sub",
+		AttrFlags:    []string{"--name", "--secret", "-f"},
+		AttrExitCode: 0,
+		AttrDuration: int64(1500),
+	}, spans[0].attrs)
+	assert.NoError(t, spans[0].err)
+	assert.True(t, spans[0].ended)
+	assert.Same(t, spans[0], sub.span)
+}
+
+func TestTelemetryError(t *testing.T) {
+	spans := []*fakeSpan{}
+	obj, _ := telemetryApp(&spans, &CommandError{Err: assert.AnError, Code: 3})
+
+	err := obj.Dispatch(context.Background(), []string{"sub"})
+
+	assert.Error(t, err)
+	assert.Len(t, spans, 1)
+	assert.Equal(t, 3, spans[0].attrs[AttrExitCode])
+	assert.Equal(t, []string{}, spans[0].attrs[AttrFlags])
+	assert.Same(t, err, spans[0].err)
+	assert.True(t, spans[0].ended)
+}
@@ -0,0 +1,595 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/klmitch/nelson/internal/interval"
+)
+
+// DefaultUsageTemplate is the template used to render the brief
+// usage message for a command, displayed for usage errors: the usage
+// line, followed by a pointer to the full help.  The template is
+// executed with a HelpData.
+const DefaultUsageTemplate = `{{heading (tr "Usage:")}} {{.Usage}}
+{{printf (tr "See '%s --help' for more information.") .Name}}
+`
+
+// DefaultHelpTemplate is the template used to render the full help
+// for a command, as requested with HelpFlag or the HelpCommand.  The
+// template is executed with a HelpData, and may invoke the usage
+// template as {{template "usage" .}}.
+const DefaultHelpTemplate = `{{with .Description}}{{wrap $.Width .}}
+
+{{else}}{{with .Summary}}{{wrap $.Width .}}
+
+{{end}}{{end}}{{with .Deprecated}}({{.}})
+
+{{end}}{{heading (tr "Usage:")}} {{.Usage}}
+{{range .Groups}}
+{{heading (printf "%s:" .Title)}}
+{{range .Commands}}  {{command (pad $.CommandWidth .Name)}}  {{hang (add $.CommandWidth 4) $.Width .Text}}
+{{end}}{{end}}{{if .Topics}}
+{{heading (tr "Topics:")}}
+{{range .Topics}}  {{command (pad $.TopicWidth .Name)}}  {{hang (add $.TopicWidth 4) $.Width .Summary}}
+{{end}}{{end}}{{if .Flags}}
+{{heading (tr "Flags:")}}
+{{range .Flags}}  {{flag (pad $.FlagWidth .Label)}}  {{hang (add $.FlagWidth 4) $.Width .Text}}
+{{end}}{{end}}{{if .GlobalFlags}}
+{{heading (tr "Global flags:")}}
+{{range .GlobalFlags}}  {{flag (pad $.GlobalFlagWidth .Label)}}  {{hang (add $.GlobalFlagWidth 4) $.Width .Text}}
+{{end}}{{end}}{{with .Examples}}
+{{heading (tr "Examples:")}}
+{{indent 2 (wrap (add $.Width -2) .)}}
+{{end}}{{with .SeeAlso}}
+{{heading (tr "See also:")}}
+{{range .}}  {{command .}}
+{{end}}{{end}}`
+
+// HelpData is the data model passed to the help and usage templates.
+// Text fields have surrounding white space removed.
+type HelpData struct {
+	App         string            // Name of the application
+	Path        []string          // Names of the commands, beginning with the application
+	Name        string            // The command path, as in "tool remote add"
+	Usage       string            // The usage line, as in "tool remote add [flags] NAME URL"; see IUsageLine
+	Summary     string            // Command summary
+	Description string            // Full description of the command
+	Examples    string            // Examples of using the command; see IExamples
+	SeeAlso     []string          // Names of related commands, as in "tool remote add"; see ISeeAlso
+	Deprecated  string            // Deprecation note, if the command is deprecated; see DeprecatedCommand.Note
+	All         bool              // True if hidden commands and flags are included; see HelpAllFlag
+	Commands    []*SubcommandHelp // Visible subcommands, sorted; see ISortOrder
+	Groups      []*GroupHelp      // Visible subcommands, by group; see IGroups
+	Topics      []*TopicHelp      // Help topics, sorted by name, for the root command only; see WithTopic
+	Flags       []*FlagHelp       // Visible flags of the command, sorted; see ISortOrder
+	GlobalFlags []*FlagHelp       // Visible application-global flags, for the root command or if All is true; see WithGlobals
+	Args        []*ArgHelp        // Positional arguments of the command, in order
+	Width       int               // Width, in columns, to which help should be wrapped; see WithWidth
+
+	CommandWidth    int // Width of the longest subcommand name
+	TopicWidth      int // Width of the longest topic name
+	FlagWidth       int // Width of the longest label in Flags
+	GlobalFlagWidth int // Width of the longest label in GlobalFlags
+
+	globals bool // True if the application has visible global flags, even if not listed
+}
+
+// SubcommandHelp describes a subcommand in a HelpData.
+type SubcommandHelp struct {
+	Name       string // Name of the subcommand
+	Summary    string // Summary of the subcommand
+	Group      string // Group name of the subcommand
+	Hidden     bool   // True if the subcommand is hidden
+	Weight     int    // Weight of the subcommand; see IWeight
+	Deprecated string // Deprecation note, if the subcommand is deprecated
+	Text       string // Summary, followed by the deprecation note
+}
+
+// DefaultGroupTitle is the heading of the group of ungrouped
+// subcommands in help, unless the parent command describes it; see
+// IGroups.
+const DefaultGroupTitle = "Available commands"
+
+// GroupHelp describes a group of subcommands in a HelpData.
+type GroupHelp struct {
+	Name     string            // Group name; empty for ungrouped commands
+	Title    string            // Heading of the group
+	Commands []*SubcommandHelp // Subcommands in the group, sorted; see ISortOrder
+}
+
+// FlagHelp describes a flag in a HelpData.
+type FlagHelp struct {
+	Name       string   // Long name of the flag
+	Aliases    []string // Alternate long names of the flag
+	Short      string   // Short name of the flag
+	Label      string   // Names of the flag and its value, as in "--name, -n NAME"
+	Help       string   // Help text of the flag
+	Default    *string  // Default value, if any
+	Optional   *string  // Value used if the flag is given without one, if the value is optional
+	Hidden     bool     // True if the flag is hidden
+	Weight     int      // Weight of the flag; see WeightTag
+	Deprecated string   // Deprecation message, if the flag is deprecated
+	Env        string   // Environment variable bound to the flag, if any; see WithEnvPrefix
+	Config     string   // Configuration key bound to the flag, if the application has a configuration file
+	Text       string   // Help text, followed by notes about the default and optional values, deprecation, and bindings
+}
+
+// ArgHelp describes a positional argument in a HelpData.
+type ArgHelp struct {
+	Name  string // Name of the argument
+	Help  string // Help text of the argument
+	Arity string // Number of values, in interval notation
+
+	arity interval.Interval // Number of values
+}
+
+// usage describes the argument for the usage line: "NAME" for a
+// required argument, "[NAME]" for an optional argument, and
+// "NAME..." or "[NAME...]" for an argument accepting several values.
+// An argument requiring an exact number of values is repeated that
+// number of times.
+func (a *ArgHelp) usage() string {
+	switch {
+	case a.arity.End == a.arity.Start+1:
+		return strings.TrimSpace(strings.Repeat(a.Name+" ", int(a.arity.Start)))
+	case a.arity.Start == 0 && a.arity.End == 2:
+		return "[" + a.Name + "]"
+	case a.arity.Start == 0:
+		return "[" + a.Name + "...]"
+	}
+
+	return a.Name + "..."
+}
+
+// helpFuncs are the functions available to the help and usage
+// templates, in addition to the text/template builtins.
+var helpFuncs = template.FuncMap{
+	"pad":    helpPad,
+	"indent": helpIndent,
+	"join":   strings.Join,
+	"trim":   strings.TrimSpace,
+	"add":    helpAdd,
+	"wrap":   helpWrap,
+	"hang":   helpHang,
+}
+
+// themeFuncs returns the template functions that color text
+// according to a theme, which may be nil.
+func themeFuncs(theme *Theme) template.FuncMap {
+	var styles Theme
+	if theme != nil {
+		styles = *theme
+	}
+
+	return template.FuncMap{
+		"heading": func(text string) string { return theme.paint(styles.Heading, text) },
+		"command": func(text string) string { return theme.paint(styles.Command, text) },
+		"flag":    func(text string) string { return theme.paint(styles.Flag, text) },
+	}
+}
+
+// helpPad is a template function that pads text with spaces to the
+// specified width.
+func helpPad(width int, text string) string {
+	return fmt.Sprintf("%-*s", width, text)
+}
+
+// helpAdd is a template function that adds integers.
+func helpAdd(a, b int) int {
+	return a + b
+}
+
+// helpIndent is a template function that indents each non-empty line
+// of text by the specified number of spaces.
+func helpIndent(n int, text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = strings.Repeat(" ", n) + line
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// WithTemplates replaces the templates used to render the usage
+// message and the full help of every command, allowing applications
+// to customize branding and layout.  The templates are executed
+// with a HelpData; the functions "pad", "indent", "join", "trim",
+// "add", "wrap", and "hang" are available in addition to the
+// text/template builtins, along with "heading", "command", and
+// "flag", which color text according to the theme, and "tr", which
+// translates text with the application's catalogs; see WithTheme and
+// WithCatalog.  An empty
+// template selects the default, either DefaultUsageTemplate or
+// DefaultHelpTemplate.  Commands may override the templates; see
+// IHelpTemplates.  Returns the App, to allow chaining.
+func (a *App) WithTemplates(usage, help string) *App {
+	a.UsageTemplate = usage
+	a.HelpTemplate = help
+	return a
+}
+
+// IHelpTemplates is an optional interface for commands that replace
+// the templates used to render their usage message and full help.
+// An empty template selects the application's template; see
+// WithTemplates.
+type IHelpTemplates interface {
+	// GetUsageTemplate retrieves the template used to render the
+	// usage message for this command.
+	GetUsageTemplate() string
+
+	// GetHelpTemplate retrieves the template used to render the
+	// full help for this command.
+	GetHelpTemplate() string
+}
+
+// getHelpTemplates is a helper that retrieves the IHelpTemplates
+// implementation for a command, if any.  It examines the command and
+// any commands it wraps.
+func getHelpTemplates(cmd ICommand) IHelpTemplates {
+	for cmd != nil {
+		if tmp, ok := cmd.(IHelpTemplates); ok {
+			return tmp
+		}
+
+		// Unwrap the command
+		wrapped, ok := cmd.(IWrapped)
+		if !ok {
+			break
+		}
+		cmd = wrapped.Unwrap()
+	}
+
+	return nil
+}
+
+// templates constructs the templates used to render the help for a
+// command, colored with the specified theme, which may be nil, and
+// translated with the specified translator.  The returned template
+// renders the full help, and has an associated template named
+// "usage" that renders the usage message.
+func (a *App) templates(cmd ICommand, theme *Theme, tr translator) (*template.Template, error) {
+	usage, help := DefaultUsageTemplate, DefaultHelpTemplate
+	if a.UsageTemplate != "" {
+		usage = a.UsageTemplate
+	}
+	if a.HelpTemplate != "" {
+		help = a.HelpTemplate
+	}
+	if tmp := getHelpTemplates(cmd); tmp != nil {
+		if text := tmp.GetUsageTemplate(); text != "" {
+			usage = text
+		}
+		if text := tmp.GetHelpTemplate(); text != "" {
+			help = text
+		}
+	}
+
+	tmpl, err := template.New("help").Funcs(helpFuncs).Funcs(themeFuncs(theme)).Funcs(template.FuncMap{
+		"tr": tr.translate,
+	}).Parse(help)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmpl.New("usage").Parse(usage); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// render renders the help for the invocation with the named
+// template, either "usage" or "help".
+func (a *App) render(w io.Writer, name string, inv *Invocation) error {
+	return a.renderWidth(w, name, inv, a.width(w))
+}
+
+// renderWidth renders the help for the invocation with the named
+// template, wrapped to the specified width.
+func (a *App) renderWidth(w io.Writer, name string, inv *Invocation, width int) error {
+	tmpl, err := a.templates(inv.Command, a.theme(w, inv.noColor), a.translator(a.languages()))
+	if err != nil {
+		return err
+	}
+
+	data := a.helpData(inv)
+	data.Width = width
+	return tmpl.ExecuteTemplate(w, name, data)
+}
+
+// usage emits a brief usage message for the invocation, as for usage
+// errors; see DefaultUsageTemplate.
+func (a *App) usage(w io.Writer, inv *Invocation) error {
+	return a.render(w, "usage", inv)
+}
+
+// helpData constructs the data model describing the invocation for
+// the help templates, in the language selected by the locale.
+func (a *App) helpData(inv *Invocation) *HelpData {
+	langs := a.languages()
+	tr := a.translator(langs)
+	summary, description := translateCommand(inv.Command, langs)
+	tmplData := NewTemplateData(inv.Path[0], a.Version).ForCommand(inv.Path)
+	summary, description = tmplData.TryExpand(summary), tmplData.TryExpand(description)
+	data := &HelpData{
+		App:         inv.Path[0],
+		Path:        inv.Path,
+		Name:        strings.Join(inv.Path, " "),
+		Summary:     strings.TrimSpace(summary),
+		Description: strings.TrimSpace(description),
+		Examples:    strings.TrimSpace(GetExamples(inv.Command)),
+		SeeAlso:     seeAlsoNames(inv.Path[0], GetSeeAlso(inv.Command)),
+		All:         inv.helpAll,
+	}
+	if dep := GetDeprecation(inv.Command); dep != nil {
+		data.Deprecated = dep.note(tr)
+	}
+
+	// Describe the visible subcommands, including any dynamic
+	// subcommands
+	subs := inv.Command.GetSubcommands()
+	if inv.inj != nil {
+		if tmp, err := resolveSubcommands(inv.Command, inv.inj); err == nil {
+			subs = tmp
+		}
+	}
+	if len(inv.Path) == 1 {
+		subs = a.withBuiltins(subs, inv.inj)
+	}
+	for name, sub := range subs {
+		if IsHidden(sub) && !data.All {
+			continue
+		}
+		summary, _ := translateCommand(sub, langs)
+		summary = tmplData.ForCommand(append(inv.Path[:len(inv.Path):len(inv.Path)], name)).TryExpand(summary)
+		subHelp := &SubcommandHelp{
+			Name:    name,
+			Summary: summary,
+			Group:   sub.GetGroup(),
+			Hidden:  IsHidden(sub),
+			Weight:  GetWeight(sub),
+			Text:    summary,
+		}
+		if dep := GetDeprecation(sub); dep != nil {
+			subHelp.Deprecated = dep.note(tr)
+			subHelp.Text = helpNote(subHelp.Summary, []string{subHelp.Deprecated})
+		}
+		data.Commands = append(data.Commands, subHelp)
+		if len(name) > data.CommandWidth {
+			data.CommandWidth = len(name)
+		}
+	}
+	commandOrder, flagOrder := SortDefault, SortDefault
+	if tmp := getSortOrder(inv.Command); tmp != nil {
+		commandOrder, flagOrder = tmp.GetCommandOrder(), tmp.GetFlagOrder()
+	}
+	sortCommands(data.Commands, commandOrder)
+	data.Groups = helpGroups(data.Commands, GetGroups(inv.Command), tr)
+	if _, ok := subs[HelpCommand].(*helpCommand); ok {
+		data.Topics, data.TopicWidth = a.helpTopics(tr)
+	}
+
+	// Describe the flags and arguments
+	if opts, err := describeOptions(inv.Command); err == nil && opts != nil {
+		data.Flags, data.FlagWidth = a.helpFlags([]*options{opts}, inv.Path[1:], data.All, tr)
+		sortFlags(data.Flags, flagOrder)
+		for _, arg := range opts.Set.Args {
+			data.Args = append(data.Args, &ArgHelp{
+				Name:  arg.Name,
+				Help:  arg.Help,
+				Arity: arg.Arity.String(),
+				arity: arg.Arity,
+			})
+		}
+	}
+	globals := []*options{}
+	for _, obj := range a.Globals {
+		if opts, err := optionsFor(obj, false); err == nil && opts != nil {
+			globals = append(globals, opts)
+		}
+	}
+	if flags, width := a.helpFlags(globals, nil, data.All, tr); len(flags) > 0 {
+		data.globals = true
+		if len(inv.Path) == 1 || data.All {
+			data.GlobalFlags, data.GlobalFlagWidth = flags, width
+			sortFlags(data.GlobalFlags, flagOrder)
+		}
+	}
+
+	// Compose the usage line
+	usage := GetUsageLine(inv.Command)
+	if usage == "" {
+		usage = data.usageLine(tr)
+	}
+	data.Usage = strings.TrimSpace(data.Name + " " + usage)
+
+	return data
+}
+
+// usageLine is a helper for helpData that composes the usage line,
+// following the command path, from the flags, subcommands, and
+// positional arguments.  Commands that declare no arguments accept
+// any.
+func (d *HelpData) usageLine(tr translator) string {
+	parts := []string{}
+	if len(d.Flags) > 0 || d.globals {
+		parts = append(parts, tr.translate("[flags]"))
+	}
+	switch {
+	case len(d.Commands) > 0:
+		parts = append(parts, tr.translate("COMMAND"), tr.translate("[ARGS...]"))
+	case len(d.Args) == 0:
+		parts = append(parts, tr.translate("[ARGS...]"))
+	default:
+		for _, arg := range d.Args {
+			if text := arg.usage(); text != "" {
+				parts = append(parts, text)
+			}
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// helpGroups is a helper for helpData that organizes the sorted
+// subcommands by group.  The described groups come first, in the
+// order given, followed by the others in alphabetical order; the
+// group of ungrouped commands comes first unless it is described.
+// Empty groups are omitted.  The titles are translated with the
+// specified translator.
+func helpGroups(subs []*SubcommandHelp, described []Group, tr translator) []*GroupHelp {
+	groups := []*GroupHelp{}
+	index := map[string]*GroupHelp{}
+	addGroup := func(name, title string) *GroupHelp {
+		if title == "" {
+			title = name
+		}
+		if title == "" {
+			title = DefaultGroupTitle
+		}
+		group := &GroupHelp{Name: name, Title: tr.translate(title)}
+		index[name] = group
+		groups = append(groups, group)
+		return group
+	}
+
+	// Set up the described groups
+	for _, desc := range described {
+		if _, ok := index[desc.Name]; !ok {
+			addGroup(desc.Name, desc.Title)
+		}
+	}
+	if _, ok := index[""]; !ok {
+		group := &GroupHelp{Title: tr.translate(DefaultGroupTitle)}
+		index[""] = group
+		groups = append([]*GroupHelp{group}, groups...)
+	}
+	fixed := len(groups)
+
+	// Place the subcommands into their groups
+	for _, sub := range subs {
+		group, ok := index[sub.Group]
+		if !ok {
+			group = addGroup(sub.Group, "")
+		}
+		group.Commands = append(group.Commands, sub)
+	}
+	undescribed := groups[fixed:]
+	sort.Slice(undescribed, func(i, j int) bool {
+		return undescribed[i].Name < undescribed[j].Name
+	})
+
+	// Omit the empty groups
+	result := []*GroupHelp{}
+	for _, group := range groups {
+		if len(group.Commands) > 0 {
+			result = append(result, group)
+		}
+	}
+
+	return result
+}
+
+// helpFlags is a helper for helpData that describes the flags of the
+// specified options that are not hidden or deprecated, unless all is
+// true.  The path is the names of the commands below the root, which
+// determine the environment variables and configuration keys bound
+// to the flags.  The notes are translated with the specified
+// translator.  It also returns the width of the longest label.
+func (a *App) helpFlags(sets []*options, path []string, all bool, tr translator) ([]*FlagHelp, int) {
+	var flags []*FlagHelp
+	width := 0
+	for _, opts := range sets {
+		envNames, _ := opts.Set.envNames(a.EnvPrefix, path)
+		for _, opt := range opts.Set.Options {
+			if (opt.Hidden || opt.Deprecated != "") && !all {
+				continue
+			}
+			flag := &FlagHelp{
+				Name:       opt.Name,
+				Aliases:    opt.Aliases,
+				Short:      opt.Short,
+				Label:      opt.Label(),
+				Help:       opt.Help,
+				Optional:   opt.Optional,
+				Hidden:     opt.Hidden,
+				Weight:     opt.Weight,
+				Deprecated: opt.Deprecated,
+				Env:        envNames[opt],
+			}
+			if keys := opt.configKeys(path); a.hasConfig() && keys != nil {
+				flag.Config = strings.Join(keys, ".")
+			}
+			if len(flag.Label) > width {
+				width = len(flag.Label)
+			}
+
+			// Note the default and optional values, the
+			// deprecation, and the bindings
+			notes := []string{}
+			if text, ok := opts.DefaultText(opt); ok {
+				flag.Default = &text
+				notes = append(notes, fmt.Sprintf(tr.translate("default %s"), text))
+			}
+			if opt.Optional != nil {
+				notes = append(notes, fmt.Sprintf(tr.translate("%s if no value"), strconv.Quote(*opt.Optional)))
+			}
+			if opt.Deprecated != "" {
+				notes = append(notes, fmt.Sprintf(tr.translate("deprecated: %s"), opt.Deprecated))
+			}
+			if bindings := flag.bindings(tr); bindings != "" {
+				notes = append(notes, bindings)
+			}
+			flag.Text = helpNote(flag.Help, notes)
+			flags = append(flags, flag)
+		}
+	}
+
+	return flags, width
+}
+
+// bindings describes the environment variable and configuration key
+// the flag is bound to, as in "env: TOOL_OUTPUT, config:
+// output.format", translated with the specified translator.  Returns
+// the empty string if the flag is not bound to either.
+func (f *FlagHelp) bindings(tr translator) string {
+	parts := []string{}
+	if f.Env != "" {
+		parts = append(parts, fmt.Sprintf(tr.translate("env: %s"), f.Env))
+	}
+	if f.Config != "" {
+		parts = append(parts, fmt.Sprintf(tr.translate("config: %s"), f.Config))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// helpNote is a helper that appends notes, in parentheses, to help
+// text.
+func helpNote(text string, notes []string) string {
+	if len(notes) == 0 {
+		return text
+	}
+
+	return strings.TrimSpace(fmt.Sprintf("%s (%s)", text, strings.Join(notes, "; ")))
+}
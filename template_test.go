@@ -0,0 +1,605 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/klmitch/nelson/internal/interval"
+)
+
+type templateOptions struct {
+	Verbose bool     `opt:"verbose,v" help:"Verbose output"`
+	Color   string   `opt:"color" help:"Colorize output" optional:"auto"`
+	Old     string   `opt:"old" deprecated:"gone"`
+	Files   []string `arg:"FILES" help:"Files to process"`
+}
+
+type templateGlobals struct {
+	Debug bool `opt:"debug" help:"Enable debugging"`
+}
+
+func TestHelpPad(t *testing.T) {
+	result := helpPad(5, "ab")
+
+	assert.Equal(t, "ab   ", result)
+}
+
+func TestHelpIndent(t *testing.T) {
+	result := helpIndent(2, "one\n\ntwo")
+
+	assert.Equal(t, "  one\n\n  two", result)
+}
+
+func TestAppWithTemplates(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithTemplates("usage", "help")
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, "usage", obj.UsageTemplate)
+	assert.Equal(t, "help", obj.HelpTemplate)
+}
+
+func TestGetHelpTemplatesBase(t *testing.T) {
+	cmd := &mockICommand{}
+
+	result := getHelpTemplates(cmd)
+
+	assert.Nil(t, result)
+}
+
+func TestGetHelpTemplatesWrapped(t *testing.T) {
+	inner := &Command{HelpTemplate: "help"}
+	cmd := Hidden(inner)
+
+	result := getHelpTemplates(cmd)
+
+	assert.Same(t, inner, result)
+}
+
+func TestAppHelpDataBase(t *testing.T) {
+	obj := &App{Globals: []interface{}{&templateGlobals{}}}
+	inv := &Invocation{
+		Path: []string{"app", "sub"},
+		Command: &Command{
+			Summary:     " Summary ",
+			Description: "Description.\n",
+			Examples:    "\n  app sub -v\n",
+			Defaults:    &templateOptions{Color: "never"},
+			Subcommands: map[string]ICommand{
+				"beta":   &Command{Summary: "Beta", Group: "Other"},
+				"alpha":  &Command{Summary: "Alpha"},
+				"gamma":  &Command{Summary: "Gamma", Group: "Other"},
+				"hidden": Hidden(&Command{}),
+			},
+		},
+	}
+
+	result := obj.helpData(inv)
+
+	never, auto := `"never"`, "auto"
+	alpha := &SubcommandHelp{Name: "alpha", Summary: "Alpha", Text: "Alpha"}
+	beta := &SubcommandHelp{Name: "beta", Summary: "Beta", Group: "Other", Text: "Beta"}
+	gamma := &SubcommandHelp{Name: "gamma", Summary: "Gamma", Group: "Other", Text: "Gamma"}
+	assert.Equal(t, &HelpData{
+		App:         "app",
+		Path:        []string{"app", "sub"},
+		Name:        "app sub",
+		Usage:       "app sub [flags] COMMAND [ARGS...]",
+		Summary:     "Summary",
+		Description: "Description.",
+		Examples:    "app sub -v",
+		Commands:    []*SubcommandHelp{alpha, beta, gamma},
+		Groups: []*GroupHelp{
+			{Title: "Available commands", Commands: []*SubcommandHelp{alpha}},
+			{Name: "Other", Title: "Other", Commands: []*SubcommandHelp{beta, gamma}},
+		},
+		Flags: []*FlagHelp{
+			{
+				Name:  "verbose",
+				Short: "v",
+				Label: "-v, --verbose",
+				Help:  "Verbose output",
+				Text:  "Verbose output",
+			},
+			{
+				Name:     "color",
+				Label:    "    --color",
+				Help:     "Colorize output",
+				Default:  &never,
+				Optional: &auto,
+				Text:     `Colorize output (default "never"; "auto" if no value)`,
+			},
+		},
+		Args: []*ArgHelp{
+			{Name: "FILES", Help: "Files to process", Arity: "[1]", arity: interval.Interval{Start: 1, End: 2}},
+		},
+		CommandWidth: 5,
+		FlagWidth:    13,
+		globals:      true,
+	}, result)
+}
+
+func TestAppHelpDataRoot(t *testing.T) {
+	obj := &App{}
+	inv := &Invocation{
+		Path: []string{"app"},
+		Command: &Command{
+			Subcommands: map[string]ICommand{
+				"sub": &Command{Summary: "A subcommand"},
+			},
+		},
+	}
+
+	result := obj.helpData(inv)
+
+	assert.Equal(t, []*SubcommandHelp{
+		{Name: "help", Summary: "Show help for a command", Text: "Show help for a command"},
+		{Name: "sub", Summary: "A subcommand", Text: "A subcommand"},
+	}, result.Commands)
+}
+
+func TestAppHelpDataHiddenDeprecated(t *testing.T) {
+	obj := &App{}
+	inv := &Invocation{
+		Path: []string{"app", "sub"},
+		Command: &Command{
+			Subcommands: map[string]ICommand{
+				"old":    Deprecated(&Command{Summary: "Old"}, "new"),
+				"older":  Deprecated(&Command{}, ""),
+				"hidden": Hidden(&Command{Summary: "Hidden"}),
+			},
+		},
+	}
+
+	result := obj.helpData(inv)
+
+	assert.Equal(t, []*SubcommandHelp{
+		{Name: "old", Summary: "Old", Deprecated: "deprecated: use new", Text: "Old (deprecated: use new)"},
+		{Name: "older", Deprecated: "deprecated", Text: "(deprecated)"},
+	}, result.Commands)
+}
+
+func TestAppHelpDataHelpAll(t *testing.T) {
+	obj := &App{}
+	inv := &Invocation{
+		Path: []string{"app", "sub"},
+		Command: &Command{
+			Subcommands: map[string]ICommand{
+				"hidden": Hidden(&Command{Summary: "Hidden"}),
+			},
+		},
+		helpAll: true,
+	}
+
+	result := obj.helpData(inv)
+
+	assert.True(t, result.All)
+	assert.Equal(t, []*SubcommandHelp{
+		{Name: "hidden", Summary: "Hidden", Hidden: true, Text: "Hidden"},
+	}, result.Commands)
+}
+
+func TestAppHelpDeprecated(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{Width: 80}
+	inv := &Invocation{
+		Path:    []string{"app", "old"},
+		Command: Deprecated(&Command{Summary: "Old command"}, "new"),
+	}
+
+	err := obj.help(buf, inv)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Old command\n\n(deprecated: use new)\n\nUsage: app old [ARGS...]\n", buf.String())
+}
+
+func TestArgHelpUsage(t *testing.T) {
+	tests := []struct {
+		name   string
+		arity  interval.Interval
+		result string
+	}{
+		{"required", interval.Interval{Start: 1, End: 2}, "NAME"},
+		{"exact", interval.Interval{Start: 2, End: 3}, "NAME NAME"},
+		{"none", interval.Interval{Start: 0, End: 1}, ""},
+		{"optional", interval.Interval{Start: 0, End: 2}, "[NAME]"},
+		{"any", interval.Interval{Start: 0, End: math.MaxInt64}, "[NAME...]"},
+		{"some", interval.Interval{Start: 0, End: 4}, "[NAME...]"},
+		{"many", interval.Interval{Start: 1, End: math.MaxInt64}, "NAME..."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			obj := &ArgHelp{Name: "NAME", arity: test.arity}
+
+			result := obj.usage()
+
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func TestAppHelpDataUsageArgs(t *testing.T) {
+	type opts struct {
+		Force bool     `opt:"force"`
+		Name  string   `arg:"NAME"`
+		None  []string `arg:"NONE" arity:"[0]"`
+		Files []string `arg:"FILES" arity:"[0,)"`
+	}
+	obj := &App{}
+	inv := &Invocation{
+		Path:    []string{"app", "sub"},
+		Command: &Command{Defaults: &opts{}},
+	}
+
+	result := obj.helpData(inv)
+
+	assert.Equal(t, "app sub [flags] NAME [FILES...]", result.Usage)
+}
+
+func TestAppHelpDataUsageNoFlags(t *testing.T) {
+	type opts struct {
+		Name string `arg:"NAME"`
+	}
+	obj := &App{}
+	inv := &Invocation{
+		Path:    []string{"app", "sub"},
+		Command: &Command{Defaults: &opts{}},
+	}
+
+	result := obj.helpData(inv)
+
+	assert.Equal(t, "app sub NAME", result.Usage)
+}
+
+func TestAppHelpDataUsageGlobalFlags(t *testing.T) {
+	obj := &App{Globals: []interface{}{&templateGlobals{}}}
+	inv := &Invocation{
+		Path:    []string{"app", "sub"},
+		Command: &Command{},
+	}
+
+	result := obj.helpData(inv)
+
+	assert.Equal(t, "app sub [flags] [ARGS...]", result.Usage)
+}
+
+func TestAppHelpDataGlobalFlagsRoot(t *testing.T) {
+	obj := &App{Globals: []interface{}{&templateGlobals{}}}
+	inv := &Invocation{
+		Path:    []string{"app"},
+		Command: &Command{},
+	}
+
+	result := obj.helpData(inv)
+
+	assert.Equal(t, []*FlagHelp{
+		{
+			Name:  "debug",
+			Label: "    --debug",
+			Help:  "Enable debugging",
+			Text:  "Enable debugging",
+		},
+	}, result.GlobalFlags)
+	assert.Equal(t, 11, result.GlobalFlagWidth)
+}
+
+func TestAppHelpDataGlobalFlagsHelpAll(t *testing.T) {
+	obj := &App{Globals: []interface{}{&templateGlobals{}}}
+	inv := &Invocation{
+		Path:    []string{"app", "sub"},
+		Command: &Command{},
+		helpAll: true,
+	}
+
+	result := obj.helpData(inv)
+
+	assert.Len(t, result.GlobalFlags, 1)
+	assert.Equal(t, 11, result.GlobalFlagWidth)
+}
+
+func TestAppHelpDataUsageOverride(t *testing.T) {
+	obj := &App{}
+	inv := &Invocation{
+		Path:    []string{"app", "cp"},
+		Command: &Command{UsageLine: "[flags] SRC... DEST"},
+	}
+
+	result := obj.helpData(inv)
+
+	assert.Equal(t, "app cp [flags] SRC... DEST", result.Usage)
+}
+
+func TestHelpGroupsUndescribed(t *testing.T) {
+	alpha := &SubcommandHelp{Name: "alpha", Group: "Zeta"}
+	beta := &SubcommandHelp{Name: "beta"}
+	gamma := &SubcommandHelp{Name: "gamma", Group: "Eta"}
+
+	result := helpGroups([]*SubcommandHelp{alpha, beta, gamma}, nil, nil)
+
+	assert.Equal(t, []*GroupHelp{
+		{Title: "Available commands", Commands: []*SubcommandHelp{beta}},
+		{Name: "Eta", Title: "Eta", Commands: []*SubcommandHelp{gamma}},
+		{Name: "Zeta", Title: "Zeta", Commands: []*SubcommandHelp{alpha}},
+	}, result)
+}
+
+func TestHelpGroupsDescribed(t *testing.T) {
+	alpha := &SubcommandHelp{Name: "alpha", Group: "zeta"}
+	beta := &SubcommandHelp{Name: "beta"}
+	gamma := &SubcommandHelp{Name: "gamma", Group: "eta"}
+	delta := &SubcommandHelp{Name: "delta", Group: "other"}
+
+	result := helpGroups([]*SubcommandHelp{alpha, beta, delta, gamma}, []Group{
+		{Name: "zeta", Title: "Zeta commands"},
+		{Name: "", Title: "Other commands"},
+		{Name: "empty"},
+		{Name: "eta"},
+		{Name: "zeta", Title: "Ignored"},
+	}, nil)
+
+	assert.Equal(t, []*GroupHelp{
+		{Name: "zeta", Title: "Zeta commands", Commands: []*SubcommandHelp{alpha}},
+		{Title: "Other commands", Commands: []*SubcommandHelp{beta}},
+		{Name: "eta", Title: "eta", Commands: []*SubcommandHelp{gamma}},
+		{Name: "other", Title: "other", Commands: []*SubcommandHelp{delta}},
+	}, result)
+}
+
+func TestHelpGroupsDescribedUngroupedDefault(t *testing.T) {
+	alpha := &SubcommandHelp{Name: "alpha", Group: "main"}
+	beta := &SubcommandHelp{Name: "beta"}
+
+	result := helpGroups([]*SubcommandHelp{alpha, beta}, []Group{
+		{Name: "main", Title: "Main commands"},
+		{Name: ""},
+	}, nil)
+
+	assert.Equal(t, []*GroupHelp{
+		{Name: "main", Title: "Main commands", Commands: []*SubcommandHelp{alpha}},
+		{Title: "Available commands", Commands: []*SubcommandHelp{beta}},
+	}, result)
+}
+
+func TestAppHelpFlagBindings(t *testing.T) {
+	type opts struct {
+		Output string `opt:"output" help:"Output format" config:"output.format"`
+		Token  string `opt:"token" help:"API token" env:"-" config:"-"`
+	}
+	buf := &bytes.Buffer{}
+	obj := &App{
+		EnvPrefix:  "tool",
+		ConfigFile: "tool.yaml",
+		Globals:    []interface{}{&templateGlobals{}},
+		Width:      100,
+	}
+	inv := &Invocation{
+		Path:    []string{"tool", "sub"},
+		Command: &Command{Defaults: &opts{Output: "json"}},
+		helpAll: true,
+	}
+
+	err := obj.help(buf, inv)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Usage: tool sub [flags] [ARGS...]
+
+Flags:
+      --output  Output format (default "json"; env: TOOL_SUB_OUTPUT, config: output.format)
+      --token   API token
+
+Global flags:
+      --debug  Enable debugging (env: TOOL_DEBUG, config: debug)
+`, buf.String())
+}
+
+func TestFlagHelpBindings(t *testing.T) {
+	obj := &FlagHelp{Env: "TOOL_OUTPUT", Config: "output.format"}
+
+	result := obj.bindings(nil)
+
+	assert.Equal(t, "env: TOOL_OUTPUT, config: output.format", result)
+}
+
+func TestFlagHelpBindingsEnv(t *testing.T) {
+	obj := &FlagHelp{Env: "TOOL_OUTPUT"}
+
+	result := obj.bindings(nil)
+
+	assert.Equal(t, "env: TOOL_OUTPUT", result)
+}
+
+func TestFlagHelpBindingsNone(t *testing.T) {
+	obj := &FlagHelp{}
+
+	result := obj.bindings(nil)
+
+	assert.Equal(t, "", result)
+}
+
+func TestAppUsageBrief(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{Globals: []interface{}{&templateGlobals{}}}
+	inv := &Invocation{
+		Path: []string{"app", "sub"},
+		Command: &Command{
+			Defaults: &templateOptions{},
+			Subcommands: map[string]ICommand{
+				"push": &Command{Summary: "Push changes"},
+			},
+		},
+	}
+
+	err := obj.usage(buf, inv)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Usage: app sub [flags] COMMAND [ARGS...]\nSee 'app sub --help' for more information.\n", buf.String())
+}
+
+func TestAppHelpGroups(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{}
+	inv := &Invocation{
+		Path: []string{"app", "sub"},
+		Command: &Command{
+			Groups: []Group{{Name: "remote", Title: "Remote commands"}},
+			Subcommands: map[string]ICommand{
+				"push":   &Command{Summary: "Push changes", Group: "remote"},
+				"status": &Command{Summary: "Show status"},
+				"pull":   &Command{Summary: "Pull changes", Group: "remote"},
+			},
+		},
+	}
+
+	err := obj.help(buf, inv)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Usage: app sub COMMAND [ARGS...]
+
+Available commands:
+  status  Show status
+
+Remote commands:
+  pull    Pull changes
+  push    Push changes
+`, buf.String())
+}
+
+func TestAppRenderAppTemplates(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{}
+	obj.WithTemplates("{{.Name}} usage\n", "{{.Summary}}: {{template \"usage\" .}}")
+	inv := &Invocation{
+		Path:    []string{"app", "sub"},
+		Command: &Command{Summary: "Summary"},
+	}
+
+	err := obj.render(buf, "help", inv)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Summary: app sub usage\n", buf.String())
+}
+
+func TestAppRenderCommandTemplates(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{}
+	obj.WithTemplates("app usage\n", "app help\n")
+	inv := &Invocation{
+		Path: []string{"app", "sub"},
+		Command: &Command{
+			UsageTemplate: "{{join .Path \"/\"}} usage\n",
+		},
+	}
+
+	err := obj.render(buf, "help", inv)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "app help\n", buf.String())
+	buf.Reset()
+	err = obj.render(buf, "usage", inv)
+	assert.NoError(t, err)
+	assert.Equal(t, "app/sub usage\n", buf.String())
+}
+
+func TestAppRenderCommandHelpTemplate(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{}
+	inv := &Invocation{
+		Path: []string{"app", "sub"},
+		Command: &Command{
+			HelpTemplate: "Custom {{trim \" help \"}}\n",
+		},
+	}
+
+	err := obj.render(buf, "help", inv)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Custom help\n", buf.String())
+}
+
+func TestAppRenderBadHelpTemplate(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{HelpTemplate: "{{"}
+	inv := &Invocation{
+		Path:    []string{"app"},
+		Command: &Command{},
+	}
+
+	err := obj.render(buf, "help", inv)
+
+	assert.Error(t, err)
+	assert.Equal(t, "", buf.String())
+}
+
+func TestAppRenderBadUsageTemplate(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{UsageTemplate: "{{end}}"}
+	inv := &Invocation{
+		Path:    []string{"app"},
+		Command: &Command{},
+	}
+
+	err := obj.render(buf, "usage", inv)
+
+	assert.Error(t, err)
+	assert.Equal(t, "", buf.String())
+}
+
+func TestAppHelpExamples(t *testing.T) {
+	buf := &bytes.Buffer{}
+	obj := &App{}
+	inv := &Invocation{
+		Path: []string{"app", "sub"},
+		Command: &Command{
+			Summary:  "Summary",
+			Examples: "app sub one\napp sub two",
+		},
+	}
+
+	err := obj.help(buf, inv)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `Summary
+
+Usage: app sub [ARGS...]
+
+Examples:
+  app sub one
+  app sub two
+`, buf.String())
+}
+
+func TestAppExecuteUsageTemplateError(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Name:          "app",
+		Root:          &Command{Subcommands: map[string]ICommand{"sub": &Command{}}},
+		UsageTemplate: "{{.Missing}}",
+		Stderr:        stderr,
+	}
+
+	result := obj.Execute(context.Background(), []string{"bogus"})
+
+	assert.Equal(t, 2, result)
+	assert.Contains(t, stderr.String(), `app: unknown command "bogus"`+"\n")
+	assert.Contains(t, stderr.String(), "app: template: usage:1:2: executing \"usage\"")
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Topic is a standalone help topic, such as a description of the
+// environment variables or configuration file format of the
+// application.  Topics are not runnable; they are displayed by the
+// HelpCommand, as in "tool help environment", and listed in the help
+// of the root command.
+type Topic struct {
+	Summary string // Summary of the topic, listed in the help of the root command
+	Text    string // Full text of the topic
+}
+
+// TopicHelp describes a help topic in a HelpData.
+type TopicHelp struct {
+	Name    string // Name of the topic
+	Summary string // Summary of the topic
+}
+
+// WithTopic registers a standalone help topic with the specified
+// name, which is displayed by "tool help NAME".  Since the topics are
+// only available through the HelpCommand, they are not available
+// unless the root command has subcommands; a subcommand of the root
+// takes precedence over a topic of the same name.  The summary and
+// text of the topic are translated with the application's catalogs;
+// see WithCatalog.  Returns the App, to allow chaining.
+func (a *App) WithTopic(name string, topic *Topic) *App {
+	if a.Topics == nil {
+		a.Topics = map[string]*Topic{}
+	}
+	a.Topics[name] = topic
+	return a
+}
+
+// helpTopics is a helper for helpData that describes the help topics
+// of the application, sorted by name, translated with the specified
+// translator.  It also returns the width of the longest name.
+func (a *App) helpTopics(tr translator) ([]*TopicHelp, int) {
+	var topics []*TopicHelp
+	width := 0
+	for name, topic := range a.Topics {
+		topics = append(topics, &TopicHelp{
+			Name:    name,
+			Summary: strings.TrimSpace(tr.translate(topic.Summary)),
+		})
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	sort.Slice(topics, func(i, j int) bool {
+		return topics[i].Name < topics[j].Name
+	})
+
+	return topics, width
+}
+
+// topic emits the text of a help topic, wrapped to the width of the
+// output; if the topic has no text, its summary is emitted instead.
+func (a *App) topic(w io.Writer, topic *Topic) error {
+	tr := a.translator(a.languages())
+	text := strings.TrimSpace(tr.translate(topic.Text))
+	if text == "" {
+		text = strings.TrimSpace(tr.translate(topic.Summary))
+	}
+
+	_, err := fmt.Fprintln(w, wrapText(a.width(w), text))
+	return err
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppWithTopic(t *testing.T) {
+	topic := &Topic{Summary: "Environment variables"}
+	obj := &App{}
+
+	result := obj.WithTopic("environment", topic)
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, map[string]*Topic{"environment": topic}, obj.Topics)
+}
+
+func TestAppHelpTopics(t *testing.T) {
+	obj := &App{
+		Topics: map[string]*Topic{
+			"environment":   {Summary: "Environment variables"},
+			"config-format": {Summary: " Format of the configuration file\n"},
+		},
+	}
+
+	result, width := obj.helpTopics(nil)
+
+	assert.Equal(t, []*TopicHelp{
+		{Name: "config-format", Summary: "Format of the configuration file"},
+		{Name: "environment", Summary: "Environment variables"},
+	}, result)
+	assert.Equal(t, 13, width)
+}
+
+func TestAppHelpTopicsTranslated(t *testing.T) {
+	obj := &App{
+		Topics: map[string]*Topic{
+			"environment": {Summary: "Environment variables"},
+		},
+	}
+	tr := translator{{"Environment variables": "Variables d'environnement"}}
+
+	result, _ := obj.helpTopics(tr)
+
+	assert.Equal(t, "Variables d'environnement", result[0].Summary)
+}
+
+func TestAppDispatchHelpTopics(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout).WithTopic("environment", &Topic{Summary: "Environment variables"})
+
+	err := obj.Dispatch(context.Background(), []string{"--help"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `The application
+
+Usage: app COMMAND [ARGS...]
+
+Available commands:
+  help  Show help for a command
+  host  Uses -h
+  sub   A subcommand
+
+Topics:
+  environment  Environment variables
+`, stdout.String())
+}
+
+func TestAppDispatchHelpTopicsSubcommand(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout).WithTopic("environment", &Topic{Summary: "Environment variables"})
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "--help"})
+
+	assert.NoError(t, err)
+	assert.NotContains(t, stdout.String(), "Topics:")
+}
+
+func TestAppDispatchHelpTopic(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout).WithTopic("environment", &Topic{
+		Summary: "Environment variables",
+		Text:    "\nThe application reads APP_HOME.\n",
+	})
+
+	err := obj.Dispatch(context.Background(), []string{"help", "environment"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "The application reads APP_HOME.\n", stdout.String())
+}
+
+func TestAppDispatchHelpTopicNoText(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout).WithTopic("environment", &Topic{Summary: "Environment variables"})
+
+	err := obj.Dispatch(context.Background(), []string{"help", "environment"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Environment variables\n", stdout.String())
+}
+
+func TestAppDispatchHelpTopicShadowed(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout).WithTopic("sub", &Topic{Text: "A topic"})
+
+	err := obj.Dispatch(context.Background(), []string{"help", "sub"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "Usage: app sub [flags] [ARGS...]\n")
+}
+
+func TestAppDispatchHelpTopicExtraArgs(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := helpApp(stdout).WithTopic("environment", &Topic{Text: "A topic"})
+
+	err := obj.Dispatch(context.Background(), []string{"help", "environment", "more"})
+
+	assert.ErrorIs(t, err, ErrUnknownCommand)
+	assert.Equal(t, "", stdout.String())
+}
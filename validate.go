@@ -0,0 +1,380 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/klmitch/nelson/internal/interval"
+)
+
+// Struct tags used to validate the values of flags and arguments.
+const (
+	MatchTag    = "match"    // Regular expression the value must match: `match:"^[a-z]+$"`
+	RangeTag    = "range"    // Interval a numeric value must fall in: `range:"[1,65535]"`
+	ValidateTag = "validate" // Named validators, separated by ",": `validate:"file,readable"`
+)
+
+// ErrValidation indicates that the values of one or more flags or
+// arguments failed validation.  Errors describing validation
+// failures are instances of ValidationError.
+var ErrValidation = errors.New("validation failed")
+
+// Errors produced by the built-in validators.
+var (
+	ErrNoMatch     = errors.New("does not match")
+	ErrNotExist    = errors.New("does not exist")
+	ErrNotInRange  = errors.New("must be in")
+	ErrNotFile     = errors.New("not a file")
+	ErrNotDir      = errors.New("not a directory")
+	ErrNotURL      = errors.New("not a URL")
+	ErrNotHostname = errors.New("not a hostname")
+	ErrNotPort     = errors.New("not a port")
+)
+
+// Validators contains the validators that may be named with
+// ValidateTag.  Each validator receives the value of a flag or
+// argument--or of each element, for a list--as text, and returns an
+// error describing why it is invalid.  Applications may add their own
+// validators before any commands are resolved.
+var Validators = map[string]func(text string) error{
+	"exists":   validateExists,
+	"file":     validateFile,
+	"dir":      validateDir,
+	"readable": validateReadable,
+	"writable": validateWritable,
+	"url":      validateURL,
+	"hostname": validateHostname,
+	"port":     validatePort,
+}
+
+// ValidationError is the error returned when the values of flags or
+// arguments fail validation.  All failures are reported together.
+type ValidationError struct {
+	Failures []error // Failures, in declaration order
+}
+
+// Error returns the error message.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Failures))
+	for i, err := range e.Failures {
+		msgs[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%s: %s", ErrValidation, strings.Join(msgs, "; "))
+}
+
+// Is allows ValidationError to match ErrValidation with errors.Is.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation //nolint:goerr113
+}
+
+// stringerType is the type of fmt.Stringer.
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// check validates a single value of a flag or argument.
+type check func(v reflect.Value) error
+
+// checks constructs the checks for a field from its MatchTag,
+// RangeTag, and ValidateTag.
+func checks(field reflect.StructField) ([]check, error) {
+	result := []check{}
+
+	if tag, ok := field.Tag.Lookup(MatchTag); ok {
+		re, err := regexp.Compile(tag)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, textCheck(func(text string) error {
+			if !re.MatchString(text) {
+				return fmt.Errorf("%w %q", ErrNoMatch, tag)
+			}
+			return nil
+		}))
+	}
+
+	if tag, ok := field.Tag.Lookup(RangeTag); ok {
+		ival, err := interval.Parse(tag)
+		if err != nil {
+			return nil, err
+		}
+		chk, err := rangeCheck(field.Type, ival, tag)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, chk)
+	}
+
+	if tag := field.Tag.Get(ValidateTag); tag != "" {
+		for _, name := range strings.Split(tag, ",") {
+			fn, ok := Validators[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown validator %q", name)
+			}
+			result = append(result, textCheck(fn))
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// textCheck is a helper that constructs a check from a function that
+// validates text.
+func textCheck(fn func(text string) error) check {
+	return func(v reflect.Value) error {
+		if v.Kind() == reflect.String {
+			return fn(v.String())
+		}
+		return fn(fmt.Sprint(v.Interface()))
+	}
+}
+
+// rangeCheck is a helper that constructs a check that a numeric value
+// falls within an interval.  The text is the interval as given in
+// the tag.
+func rangeCheck(typ reflect.Type, ival interval.Interval, text string) (check, error) {
+	for typ.Kind() == reflect.Ptr || isList(typ) {
+		typ = typ.Elem()
+	}
+
+	fail := fmt.Errorf("%w %s", ErrNotInRange, text)
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(v reflect.Value) error {
+			if !ival.Includes(v.Int()) {
+				return fail
+			}
+			return nil
+		}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(v reflect.Value) error {
+			if v.Uint() > math.MaxInt64 || !ival.Includes(int64(v.Uint())) {
+				return fail
+			}
+			return nil
+		}, nil
+
+	case reflect.Float32, reflect.Float64:
+		return func(v reflect.Value) error {
+			if f := v.Float(); f < float64(ival.Start) || f >= float64(ival.End) {
+				return fail
+			}
+			return nil
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w for range %s", ErrUnsupportedType, typ)
+}
+
+// runChecks applies checks to a value, which is skipped if it is the
+// zero value.  Each element of a list is checked separately, and
+// pointers are dereferenced unless they implement fmt.Stringer.
+// Failures are appended to the list of errors, labeled with the name
+// of the flag or argument.
+func runChecks(errs []error, label string, v reflect.Value, chks []check) []error {
+	if len(chks) == 0 || v.IsZero() {
+		return errs
+	}
+
+	values := []reflect.Value{v}
+	if isList(v.Type()) {
+		values = make([]reflect.Value, v.Len())
+		for i := range values {
+			values[i] = v.Index(i)
+		}
+	}
+	for _, val := range values {
+		if val.Kind() == reflect.Ptr && !val.Type().Implements(stringerType) {
+			if val.IsNil() {
+				continue
+			}
+			val = val.Elem()
+		}
+		for _, chk := range chks {
+			if err := chk(val); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", label, err))
+			}
+		}
+	}
+
+	return errs
+}
+
+// validate checks the values of the flags and arguments of the
+// options, returning the failures.  Zero values are not checked.
+func (o *options) validate(errs []error) []error {
+	if o == nil {
+		return errs
+	}
+
+	for _, opt := range o.Set.Options {
+		errs = runChecks(errs, opt.String(), o.Field(opt.Index), opt.Checks)
+	}
+	for _, arg := range o.Set.Args {
+		errs = runChecks(errs, arg.Name, o.Field(arg.Index), arg.Checks)
+	}
+
+	return errs
+}
+
+// validateAll validates the options along the path to a command,
+// after all sources of values have been applied.  Failures for flags
+// inherited by descendant commands are reported once.  Returns a
+// ValidationError if there are any failures.
+func validateAll(opts []*options) error {
+	var errs []error
+	for _, o := range opts {
+		errs = o.validate(errs)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	// Drop duplicate failures
+	seen := map[string]bool{}
+	failures := make([]error, 0, len(errs))
+	for _, err := range errs {
+		if !seen[err.Error()] {
+			seen[err.Error()] = true
+			failures = append(failures, err)
+		}
+	}
+
+	return &ValidationError{Failures: failures}
+}
+
+// validateExists checks that a path names an existing file or
+// directory.
+func validateExists(text string) error {
+	if _, err := os.Stat(text); err != nil {
+		return fmt.Errorf("%w: %s", ErrNotExist, err)
+	}
+
+	return nil
+}
+
+// validateFile checks that a path names an existing file that is not
+// a directory.
+func validateFile(text string) error {
+	info, err := os.Stat(text)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrNotFile, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%w: %s is a directory", ErrNotFile, text)
+	}
+
+	return nil
+}
+
+// validateDir checks that a path names an existing directory.
+func validateDir(text string) error {
+	info, err := os.Stat(text)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrNotDir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s", ErrNotDir, text)
+	}
+
+	return nil
+}
+
+// validateReadable checks that a path names a file or directory that
+// can be opened for reading.
+func validateReadable(text string) error {
+	f, err := os.Open(text)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// validateWritable checks that a path names a file that can be
+// opened for writing, or that does not exist but could be created.
+func validateWritable(text string) error {
+	f, err := os.OpenFile(text, os.O_WRONLY, 0)
+	if os.IsNotExist(err) {
+		// Try creating a file in the same directory
+		f, err = ioutil.TempFile(filepath.Dir(text), ".nelson")
+		if err == nil {
+			defer os.Remove(f.Name())
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// validateURL checks that text is an absolute URL with a scheme and
+// a host.
+func validateURL(text string) error {
+	u, err := url.Parse(text)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%w: %q", ErrNotURL, text)
+	}
+
+	return nil
+}
+
+// validateHostname checks that text is a valid hostname, as described
+// by RFC 1123.  A trailing dot is permitted.
+func validateHostname(text string) error {
+	name := strings.TrimSuffix(text, ".")
+	if name == "" || len(name) > 253 {
+		return fmt.Errorf("%w: %q", ErrNotHostname, text)
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("%w: %q", ErrNotHostname, text)
+		}
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' {
+				return fmt.Errorf("%w: %q", ErrNotHostname, text)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validatePort checks that text is a TCP or UDP port number between 1
+// and 65535.
+func validatePort(text string) error {
+	port, err := strconv.ParseUint(text, 10, 16)
+	if err != nil || port == 0 {
+		return fmt.Errorf("%w: %q", ErrNotPort, text)
+	}
+
+	return nil
+}
@@ -0,0 +1,336 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validateOptions struct {
+	Name  string   `opt:"name" match:"^[a-z]+$"`
+	Port  int      `opt:"port" range:"[1,65535]"`
+	Ratio float64  `opt:"ratio" range:"[0,1)"`
+	Size  uint     `opt:"size" range:"[,100]"`
+	Limit *int     `opt:"limit" range:"[1,10]"`
+	Hosts []string `opt:"host" validate:"hostname"`
+	Big   *big.Int `opt:"big" match:"^[0-9]+$"`
+	File  string   `arg:"FILE" validate:"file,readable"`
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestChecksNone(t *testing.T) {
+	field, _ := reflect.TypeOf(testOptions{}).FieldByName("Name")
+
+	result, err := checks(field)
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestChecksBadMatch(t *testing.T) {
+	type opts struct {
+		Name string `opt:"name" match:"("`
+	}
+	field, _ := reflect.TypeOf(opts{}).FieldByName("Name")
+
+	result, err := checks(field)
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestChecksBadRange(t *testing.T) {
+	type opts struct {
+		Port int `opt:"port" range:"[1,"`
+	}
+	field, _ := reflect.TypeOf(opts{}).FieldByName("Port")
+
+	result, err := checks(field)
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestChecksRangeNotNumeric(t *testing.T) {
+	type opts struct {
+		Name string `opt:"name" range:"[1,5]"`
+	}
+	field, _ := reflect.TypeOf(opts{}).FieldByName("Name")
+
+	result, err := checks(field)
+
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+	assert.Nil(t, result)
+}
+
+func TestChecksUnknownValidator(t *testing.T) {
+	type opts struct {
+		Name string `opt:"name" validate:"bogus"`
+	}
+	field, _ := reflect.TypeOf(opts{}).FieldByName("Name")
+
+	result, err := checks(field)
+
+	assert.EqualError(t, err, `unknown validator "bogus"`)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetBadChecks(t *testing.T) {
+	type opts struct {
+		Name string `opt:"name" validate:"bogus"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestNewOptionSetBadArgChecks(t *testing.T) {
+	type opts struct {
+		Name string `arg:"NAME" validate:"bogus"`
+	}
+
+	result, err := newOptionSet(reflect.TypeOf(opts{}))
+
+	assert.ErrorIs(t, err, ErrBadOptions)
+	assert.Nil(t, result)
+}
+
+func TestOptionsValidatePass(t *testing.T) {
+	opts, _ := newOptions(&Command{Defaults: &validateOptions{
+		Name:  "name",
+		Port:  65535,
+		Ratio: 0.5,
+		Size:  100,
+		Limit: intPtr(10),
+		Hosts: []string{"example.com", "localhost."},
+		Big:   big.NewInt(42),
+		File:  makeArgFile(t, ""),
+	}})
+
+	result := opts.validate(nil)
+
+	assert.Nil(t, result)
+}
+
+func TestOptionsValidateZero(t *testing.T) {
+	opts, _ := newOptions(&Command{Defaults: &validateOptions{}})
+
+	result := opts.validate(nil)
+
+	assert.Nil(t, result)
+}
+
+func TestOptionsValidateNil(t *testing.T) {
+	var opts *options
+
+	result := opts.validate([]error{assert.AnError})
+
+	assert.Equal(t, []error{assert.AnError}, result)
+}
+
+func TestOptionsValidateFail(t *testing.T) {
+	opts, _ := newOptions(&Command{Defaults: &validateOptions{
+		Name:  "Name",
+		Port:  65536,
+		Ratio: 1,
+		Size:  101,
+		Limit: intPtr(0),
+		Hosts: []string{"example.com", "-bad"},
+		Big:   big.NewInt(-1),
+	}})
+
+	result := opts.validate(nil)
+
+	assert.Len(t, result, 7)
+	assert.EqualError(t, result[0], `--name: does not match "^[a-z]+$"`)
+	assert.ErrorIs(t, result[0], ErrNoMatch)
+	assert.EqualError(t, result[1], "--port: must be in [1,65535]")
+	assert.ErrorIs(t, result[1], ErrNotInRange)
+	assert.EqualError(t, result[2], "--ratio: must be in [0,1)")
+	assert.EqualError(t, result[3], "--size: must be in [,100]")
+	assert.EqualError(t, result[4], "--limit: must be in [1,10]")
+	assert.EqualError(t, result[5], `--host: not a hostname: "-bad"`)
+	assert.EqualError(t, result[6], `--big: does not match "^[0-9]+$"`)
+}
+
+func TestOptionsValidateHugeUint(t *testing.T) {
+	type opts struct {
+		Size uint64 `opt:"size" range:"[0,)"`
+	}
+	obj, _ := newOptions(&Command{Defaults: &opts{Size: 1 << 63}})
+
+	result := obj.validate(nil)
+
+	assert.Len(t, result, 1)
+}
+
+func TestOptionsValidateNilElement(t *testing.T) {
+	type opts struct {
+		Limits []*int `opt:"limit" range:"[1,10]"`
+	}
+	obj, _ := newOptions(&Command{Defaults: &opts{Limits: []*int{nil, intPtr(11)}}})
+
+	result := obj.validate(nil)
+
+	assert.Len(t, result, 1)
+}
+
+func TestOptionsValidateArgument(t *testing.T) {
+	opts, _ := newOptions(&Command{Defaults: &validateOptions{
+		File: filepath.Join(t.TempDir(), "missing"),
+	}})
+
+	result := opts.validate(nil)
+
+	assert.Len(t, result, 2)
+	assert.ErrorIs(t, result[0], ErrNotFile)
+}
+
+func TestValidateAllPass(t *testing.T) {
+	opts, _ := newOptions(&Command{Defaults: &validateOptions{}})
+
+	err := validateAll([]*options{opts})
+
+	assert.NoError(t, err)
+}
+
+func TestValidateAllFail(t *testing.T) {
+	parent, _ := newOptions(&Command{Defaults: &validateOptions{Port: 0x10000}})
+	opts, _ := newOptions(&Command{Defaults: &validateOptions{Port: 0x10000, Name: "A"}})
+
+	err := validateAll([]*options{parent, opts})
+
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.EqualError(t, err, `validation failed: --port: must be in [1,65535]; --name: does not match "^[a-z]+$"`)
+}
+
+func TestValidationErrorIs(t *testing.T) {
+	obj := &ValidationError{}
+
+	assert.True(t, obj.Is(ErrValidation))
+	assert.False(t, obj.Is(assert.AnError))
+}
+
+func TestAppDispatchValidate(t *testing.T) {
+	root := &passthroughCommand{
+		Command: Command{Defaults: &validateOptions{}},
+	}
+	obj := &App{
+		Root:   root,
+		Stderr: ioutil.Discard,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"--port", "0", "--name", "A", "--host", "a..b", "/"})
+
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Len(t, err.(*CommandError).Err.(*ValidationError).Failures, 3)
+}
+
+func TestValidateExists(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.NoError(t, validateExists(makeArgFile(t, "")))
+	assert.NoError(t, validateExists(dir))
+	assert.ErrorIs(t, validateExists(filepath.Join(dir, "missing")), ErrNotExist)
+}
+
+func TestValidateFile(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.NoError(t, validateFile(makeArgFile(t, "")))
+	assert.ErrorIs(t, validateFile(dir), ErrNotFile)
+	assert.ErrorIs(t, validateFile(filepath.Join(dir, "missing")), ErrNotFile)
+}
+
+func TestValidateDir(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.NoError(t, validateDir(dir))
+	assert.ErrorIs(t, validateDir(makeArgFile(t, "")), ErrNotDir)
+	assert.ErrorIs(t, validateDir(filepath.Join(dir, "missing")), ErrNotDir)
+}
+
+func TestValidateReadable(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.NoError(t, validateReadable(makeArgFile(t, "")))
+	assert.Error(t, validateReadable(filepath.Join(dir, "missing")))
+}
+
+func TestValidateWritable(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.NoError(t, validateWritable(makeArgFile(t, "")))
+	assert.NoError(t, validateWritable(filepath.Join(dir, "new")))
+	assert.Error(t, validateWritable(dir))
+	assert.Error(t, validateWritable(filepath.Join(dir, "missing", "new")))
+	files, _ := ioutil.ReadDir(dir)
+	assert.Empty(t, files)
+}
+
+func TestValidateURL(t *testing.T) {
+	assert.NoError(t, validateURL("https://example.com/path"))
+	assert.ErrorIs(t, validateURL("example.com"), ErrNotURL)
+	assert.ErrorIs(t, validateURL("file:///path"), ErrNotURL)
+	assert.ErrorIs(t, validateURL("http://[::1"), ErrNotURL)
+}
+
+func TestValidateHostname(t *testing.T) {
+	assert.NoError(t, validateHostname("example.com"))
+	assert.NoError(t, validateHostname("a-1.example.com."))
+	assert.ErrorIs(t, validateHostname(""), ErrNotHostname)
+	assert.ErrorIs(t, validateHostname("a..b"), ErrNotHostname)
+	assert.ErrorIs(t, validateHostname("a-.b"), ErrNotHostname)
+	assert.ErrorIs(t, validateHostname("a_b"), ErrNotHostname)
+	assert.ErrorIs(t, validateHostname(string(make([]byte, 254))), ErrNotHostname)
+}
+
+func TestValidatePort(t *testing.T) {
+	assert.NoError(t, validatePort("443"))
+	assert.ErrorIs(t, validatePort("0"), ErrNotPort)
+	assert.ErrorIs(t, validatePort("65536"), ErrNotPort)
+	assert.ErrorIs(t, validatePort("http"), ErrNotPort)
+}
+
+func TestValidatorsCustom(t *testing.T) {
+	Validators["even"] = func(text string) error {
+		if len(text)%2 != 0 {
+			return assert.AnError
+		}
+		return nil
+	}
+	defer delete(Validators, "even")
+	type opts struct {
+		Name string `opt:"name" validate:"even"`
+	}
+	obj, _ := newOptions(&Command{Defaults: &opts{Name: "odd"}})
+
+	result := obj.validate(nil)
+
+	assert.Len(t, result, 1)
+	assert.ErrorIs(t, result[0], assert.AnError)
+}
@@ -0,0 +1,327 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedType indicates that a flag or argument has a type
+// that cannot be set from the command line.
+var ErrUnsupportedType = errors.New("unsupported type")
+
+// ErrBadTime indicates that a time did not match any of the expected
+// layouts.
+var ErrBadTime = errors.New("time does not match layout")
+
+// ErrBadChoice indicates that a value is not one of the allowed
+// choices.
+var ErrBadChoice = errors.New("must be one of")
+
+// Errors describing values that could not be converted.
+var (
+	ErrNotNumber   = errors.New("not a number")
+	ErrOutOfRange  = errors.New("out of range")
+	ErrNotBoolean  = errors.New("not a boolean")
+	ErrNotDuration = errors.New("not a duration")
+)
+
+// Value is an optional interface for the types of flags and
+// arguments that convert text themselves.  It is compatible with
+// flag.Value from the standard library.  The Set method is called
+// on a pointer to the field; if the field is itself a pointer, a new
+// value is allocated.
+type Value interface {
+	// String returns the value as text.
+	String() string
+
+	// Set converts text and stores it in the value.
+	Set(text string) error
+}
+
+// IChoices is an optional interface for the types of flags and
+// arguments that may only take one of a fixed set of values.  The
+// method is called on the zero value of the type.
+type IChoices interface {
+	// Choices returns the allowed values.
+	Choices() []string
+}
+
+// IChoiceDescriptions is an optional interface for types implementing
+// IChoices that describe their allowed values, for display next to
+// completion candidates.  The method is called on the zero value of
+// the type.
+type IChoiceDescriptions interface {
+	// ChoiceDescriptions returns the descriptions of the allowed
+	// values, by value.
+	ChoiceDescriptions() map[string]string
+}
+
+// DefaultLayouts are the layouts used to parse time.Time values if
+// none are specified with LayoutTag.
+var DefaultLayouts = []string{time.RFC3339, DateLayout}
+
+// Types with special handling.
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	valueType           = reflect.TypeOf((*Value)(nil)).Elem()
+)
+
+// storeValue checks that the text is one of the choices, if any are
+// given, then converts it and stores it in the specified value using
+// setValue.
+func storeValue(v reflect.Value, text string, layouts, choices []string) error {
+	if err := checkChoice(text, choices); err != nil {
+		return err
+	}
+
+	return setValue(v, text, layouts)
+}
+
+// checkChoice checks that the text is one of the choices, if any are
+// given.
+func checkChoice(text string, choices []string) error {
+	if len(choices) == 0 {
+		return nil
+	}
+
+	for _, choice := range choices {
+		if text == choice {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w %s", ErrBadChoice, strings.Join(choices, ", "))
+}
+
+// setValue converts text and stores it in the specified value.  If
+// the value is a slice, the converted text is appended to it.  The
+// layouts are used to parse time.Time values; if empty,
+// DefaultLayouts is used.  Values whose types implement Value or
+// encoding.TextUnmarshaler--either directly or through a pointer--are
+// converted with Set or UnmarshalText, respectively; network types are
+// handled by setNetValue.  For other pointer types, a new value is
+// allocated and set, so a pointer field remains nil unless a value is
+// given; this allows commands to distinguish a flag explicitly set to
+// the zero value from one that was not set.
+func setValue(v reflect.Value, text string, layouts []string) error {
+	if ok, err := setNetValue(v, text); ok {
+		return err
+	}
+
+	switch v.Type() {
+	case durationType:
+		d, err := ParseDuration(text)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+
+	case timeType:
+		t, err := parseTime(text, layouts)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	// Handle types implementing Value
+	if reflect.PtrTo(v.Type()).Implements(valueType) {
+		return v.Addr().Interface().(Value).Set(text)
+	}
+	if v.Kind() == reflect.Ptr && v.Type().Implements(valueType) {
+		tmp := reflect.New(v.Type().Elem())
+		if err := tmp.Interface().(Value).Set(text); err != nil {
+			return err
+		}
+		v.Set(tmp)
+		return nil
+	}
+
+	// Handle types implementing encoding.TextUnmarshaler
+	if reflect.PtrTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+	}
+	if v.Kind() == reflect.Ptr && v.Type().Implements(textUnmarshalerType) {
+		tmp := reflect.New(v.Type().Elem())
+		if err := tmp.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+			return err
+		}
+		v.Set(tmp)
+		return nil
+	}
+
+	// Handle pointers, allocating a new value so that the default is
+	// not modified
+	if v.Kind() == reflect.Ptr {
+		tmp := reflect.New(v.Type().Elem())
+		if !v.IsNil() {
+			tmp.Elem().Set(v.Elem())
+		}
+		if err := setValue(tmp.Elem(), text, layouts); err != nil {
+			return err
+		}
+		v.Set(tmp)
+		return nil
+	}
+
+	if v.Kind() == reflect.Slice {
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := setValue(elem, text, layouts); err != nil {
+			return err
+		}
+		v.Set(reflect.Append(v, elem))
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+
+	case reflect.Bool:
+		b, err := ParseBool(text)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := ParseInt(text, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := ParseUint(text, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+
+	default:
+		return fmt.Errorf("%w %s", ErrUnsupportedType, v.Type())
+	}
+
+	return nil
+}
+
+// isList is a helper that determines whether values of a type hold
+// a list of values, each set separately, as opposed to a slice type
+// that is converted from a single value, such as net.IP.
+func isList(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Slice || typ == hardwareAddrType {
+		return false
+	}
+	ptr := reflect.PtrTo(typ)
+	return !ptr.Implements(valueType) && !ptr.Implements(textUnmarshalerType)
+}
+
+// ParseBool converts text to a boolean, as for flag values.
+func ParseBool(text string) (bool, error) {
+	b, err := strconv.ParseBool(text)
+	if err != nil {
+		return false, ErrNotBoolean
+	}
+
+	return b, nil
+}
+
+// ParseInt converts text to an integer of the specified bit size, as
+// for flag values.  Prefixes such as "0x" select the base.
+func ParseInt(text string, bits int) (int64, error) {
+	i, err := strconv.ParseInt(text, 0, bits)
+	if err != nil {
+		return 0, numError(err)
+	}
+
+	return i, nil
+}
+
+// ParseUint converts text to an unsigned integer of the specified bit
+// size, as for flag values.  Prefixes such as "0x" select the base.
+func ParseUint(text string, bits int) (uint64, error) {
+	u, err := strconv.ParseUint(text, 0, bits)
+	if err != nil {
+		return 0, numError(err)
+	}
+
+	return u, nil
+}
+
+// ParseFloat converts text to a floating point number of the
+// specified bit size, as for flag values.
+func ParseFloat(text string, bits int) (float64, error) {
+	f, err := strconv.ParseFloat(text, bits)
+	if err != nil {
+		return 0, numError(err)
+	}
+
+	return f, nil
+}
+
+// ParseDuration converts text to a time.Duration, as for flag values.
+func ParseDuration(text string) (time.Duration, error) {
+	d, err := time.ParseDuration(text)
+	if err != nil {
+		return 0, ErrNotDuration
+	}
+
+	return d, nil
+}
+
+// numError is a helper that converts an error from the strconv
+// package into a more readable error.
+func numError(err error) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return ErrOutOfRange
+	}
+
+	return ErrNotNumber
+}
+
+// parseTime parses a time using the first of the layouts that
+// matches.  If no layouts are given, DefaultLayouts is used.
+func parseTime(text string, layouts []string) (time.Time, error) {
+	if len(layouts) == 0 {
+		layouts = DefaultLayouts
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%w: expected %s", ErrBadTime, strings.Join(layouts, " or "))
+}
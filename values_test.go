@@ -0,0 +1,468 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"math/big"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetValueString(t *testing.T) {
+	var target string
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "value", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "value", target)
+}
+
+func TestSetValueBool(t *testing.T) {
+	var target bool
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "true", nil)
+
+	assert.NoError(t, err)
+	assert.True(t, target)
+}
+
+func TestSetValueBoolError(t *testing.T) {
+	var target bool
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "bogus", nil)
+
+	assert.ErrorIs(t, err, ErrNotBoolean)
+	assert.False(t, target)
+}
+
+func TestSetValueInt(t *testing.T) {
+	var target int16
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "0x10", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int16(16), target)
+}
+
+func TestSetValueIntError(t *testing.T) {
+	var target int8
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "1000", nil)
+
+	assert.ErrorIs(t, err, ErrOutOfRange)
+	assert.Equal(t, int8(0), target)
+}
+
+func TestSetValueUint(t *testing.T) {
+	var target uint
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "42", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(42), target)
+}
+
+func TestSetValueUintError(t *testing.T) {
+	var target uint
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "-1", nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, uint(0), target)
+}
+
+func TestSetValueFloat(t *testing.T) {
+	var target float64
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "1.5", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, target)
+}
+
+func TestSetValueFloatError(t *testing.T) {
+	var target float32
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "bogus", nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, float32(0), target)
+}
+
+func TestSetValueSlice(t *testing.T) {
+	target := []int{1}
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "2", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, target)
+}
+
+func TestSetValueSliceError(t *testing.T) {
+	target := []int{1}
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "bogus", nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, []int{1}, target)
+}
+
+func TestSetValuePtr(t *testing.T) {
+	var target *int
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "0", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, *target)
+}
+
+func TestSetValuePtrDefault(t *testing.T) {
+	def := 5
+	target := &def
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "3", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, *target)
+	assert.Equal(t, 5, def)
+}
+
+func TestSetValuePtrSlice(t *testing.T) {
+	target := &[]string{"a"}
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "b", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &[]string{"a", "b"}, target)
+}
+
+func TestSetValuePtrDuration(t *testing.T) {
+	var target *time.Duration
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "5s", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, *target)
+}
+
+func TestSetValuePtrError(t *testing.T) {
+	var target *int
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "bogus", nil)
+
+	assert.ErrorIs(t, err, ErrNotNumber)
+	assert.Nil(t, target)
+}
+
+func TestSetValueUnsupported(t *testing.T) {
+	var target map[string]string
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "value", nil)
+
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
+
+func TestSetValueDuration(t *testing.T) {
+	var target time.Duration
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "1m30s", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Second, target)
+}
+
+func TestSetValueDurationError(t *testing.T) {
+	var target time.Duration
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "30", nil)
+
+	assert.ErrorIs(t, err, ErrNotDuration)
+	assert.Equal(t, time.Duration(0), target)
+}
+
+func TestSetValueDurationSlice(t *testing.T) {
+	var target []time.Duration
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "1s", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{time.Second}, target)
+}
+
+func TestSetValueTimeRFC3339(t *testing.T) {
+	var target time.Time
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "2021-03-04T05:06:07Z", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC), target)
+}
+
+func TestSetValueTimeDate(t *testing.T) {
+	var target time.Time
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "2021-03-04", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC), target)
+}
+
+func TestSetValueTimeLayouts(t *testing.T) {
+	var target time.Time
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "04/03/2021", []string{"2006", "02/01/2006"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC), target)
+}
+
+func TestSetValueTimeError(t *testing.T) {
+	var target time.Time
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "bogus", nil)
+
+	assert.ErrorIs(t, err, ErrBadTime)
+	assert.EqualError(t, err, "time does not match layout: expected 2006-01-02T15:04:05Z07:00 or 2006-01-02")
+	assert.True(t, target.IsZero())
+}
+
+func TestStoreValue(t *testing.T) {
+	var target string
+
+	err := storeValue(reflect.ValueOf(&target).Elem(), "yaml", nil, []string{"json", "yaml"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "yaml", target)
+}
+
+func TestStoreValueNoChoices(t *testing.T) {
+	var target string
+
+	err := storeValue(reflect.ValueOf(&target).Elem(), "xml", nil, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "xml", target)
+}
+
+func TestStoreValueBadChoice(t *testing.T) {
+	var target string
+
+	err := storeValue(reflect.ValueOf(&target).Elem(), "xml", nil, []string{"json", "yaml"})
+
+	assert.ErrorIs(t, err, ErrBadChoice)
+	assert.EqualError(t, err, "must be one of json, yaml")
+	assert.Equal(t, "", target)
+}
+
+func TestSetValueTextUnmarshaler(t *testing.T) {
+	var target net.IP
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "192.0.2.1", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, net.ParseIP("192.0.2.1"), target)
+}
+
+func TestSetValueTextUnmarshalerError(t *testing.T) {
+	var target net.IP
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "bogus", nil)
+
+	assert.Error(t, err)
+	assert.Nil(t, target)
+}
+
+func TestSetValueTextUnmarshalerSlice(t *testing.T) {
+	var target []net.IP
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "192.0.2.1", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("192.0.2.1")}, target)
+}
+
+func TestSetValueTextUnmarshalerPtr(t *testing.T) {
+	var target *big.Int
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "12345678901234567890", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "12345678901234567890", target.String())
+}
+
+func TestSetValueTextUnmarshalerPtrError(t *testing.T) {
+	var target *big.Int
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "bogus", nil)
+
+	assert.Error(t, err)
+	assert.Nil(t, target)
+}
+
+type testValue struct {
+	text string
+}
+
+func (v *testValue) String() string {
+	return v.text
+}
+
+func (v *testValue) Set(text string) error {
+	if text == "bogus" {
+		return assert.AnError
+	}
+	v.text = strings.ToUpper(text)
+	return nil
+}
+
+type testListValue []string
+
+func (v *testListValue) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *testListValue) Set(text string) error {
+	*v = strings.Split(text, ",")
+	return nil
+}
+
+func TestSetValueValue(t *testing.T) {
+	var target testValue
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "text", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, testValue{text: "TEXT"}, target)
+}
+
+func TestSetValueValueError(t *testing.T) {
+	var target testValue
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "bogus", nil)
+
+	assert.Same(t, assert.AnError, err)
+}
+
+func TestSetValueValuePtr(t *testing.T) {
+	var target *testValue
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "text", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &testValue{text: "TEXT"}, target)
+}
+
+func TestSetValueValuePtrError(t *testing.T) {
+	var target *testValue
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "bogus", nil)
+
+	assert.Same(t, assert.AnError, err)
+	assert.Nil(t, target)
+}
+
+func TestSetValueValueList(t *testing.T) {
+	var target testListValue
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "a,b", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, testListValue{"a", "b"}, target)
+}
+
+func TestSetValueNotNumber(t *testing.T) {
+	var target int
+
+	err := setValue(reflect.ValueOf(&target).Elem(), "bogus", nil)
+
+	assert.ErrorIs(t, err, ErrNotNumber)
+	assert.EqualError(t, err, "not a number")
+}
+
+func TestIsList(t *testing.T) {
+	assert.True(t, isList(reflect.TypeOf([]string{})))
+	assert.False(t, isList(reflect.TypeOf("")))
+	assert.False(t, isList(reflect.TypeOf(net.IP{})))
+	assert.False(t, isList(reflect.TypeOf(testListValue{})))
+}
+
+func TestNumErrorRange(t *testing.T) {
+	_, err := strconv.ParseInt("1000", 10, 8)
+
+	result := numError(err)
+
+	assert.Same(t, ErrOutOfRange, result)
+}
+
+func TestNumErrorSyntax(t *testing.T) {
+	_, err := strconv.ParseInt("bogus", 10, 8)
+
+	result := numError(err)
+
+	assert.Same(t, ErrNotNumber, result)
+}
+
+func TestParseBool(t *testing.T) {
+	result, err := ParseBool("true")
+	assert.NoError(t, err)
+	assert.True(t, result)
+
+	_, err = ParseBool("maybe")
+	assert.Same(t, ErrNotBoolean, err)
+}
+
+func TestParseInt(t *testing.T) {
+	result, err := ParseInt("0x10", 8)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(16), result)
+
+	_, err = ParseInt("300", 8)
+	assert.Same(t, ErrOutOfRange, err)
+	_, err = ParseInt("many", 0)
+	assert.Same(t, ErrNotNumber, err)
+}
+
+func TestParseUint(t *testing.T) {
+	result, err := ParseUint("42", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(42), result)
+
+	_, err = ParseUint("-1", 0)
+	assert.Same(t, ErrNotNumber, err)
+}
+
+func TestParseFloat(t *testing.T) {
+	result, err := ParseFloat("1.5", 64)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, result)
+
+	_, err = ParseFloat("1e400", 64)
+	assert.Same(t, ErrOutOfRange, err)
+}
+
+func TestParseDuration(t *testing.T) {
+	result, err := ParseDuration("5s")
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, result)
+
+	_, err = ParseDuration("soon")
+	assert.Same(t, ErrNotDuration, err)
+}
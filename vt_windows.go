@@ -0,0 +1,47 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+//go:build windows
+// +build windows
+
+package nelson
+
+import (
+	"os"
+	"syscall"
+)
+
+// enableVirtualTerminalProcessing is the console mode that enables
+// the processing of ANSI escape sequences.
+const enableVirtualTerminalProcessing = 0x0004
+
+// setConsoleMode is the SetConsoleMode function of the Windows API.
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// enableVT enables the processing of ANSI escape sequences by a
+// console, returning false if they cannot be processed, as on
+// versions of Windows before Windows 10.
+func enableVT(f *os.File) bool {
+	var mode uint32
+	handle := syscall.Handle(f.Fd())
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+
+	ok, _, _ := setConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing))
+	return ok != 0
+}
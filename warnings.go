@@ -0,0 +1,44 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import "fmt"
+
+// WarningHandler is a function that reports a warning to the user.
+// The message does not include the application name or a trailing
+// newline.
+type WarningHandler func(msg string)
+
+// WithWarningHandler sets the function used to report warnings.  By
+// default, warnings are written to the application's standard error,
+// prefixed with the application name.  Returns the App, to allow
+// chaining.
+func (a *App) WithWarningHandler(handler WarningHandler) *App {
+	a.WarningHandler = handler
+	return a
+}
+
+// Warn reports a warning to the user, such as the use of a
+// deprecated command or flag.  The arguments are interpreted as for
+// fmt.Sprintf.
+func (a *App) Warn(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if a.WarningHandler != nil {
+		a.WarningHandler(msg)
+		return
+	}
+
+	fmt.Fprintf(a.stderr(), "%s: warning: %s\n", a.name(), msg)
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppWithWarningHandler(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithWarningHandler(func(string) {})
+
+	assert.Same(t, obj, result)
+	assert.NotNil(t, obj.WarningHandler)
+}
+
+func TestAppWarn(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	obj := &App{
+		Name:   "app",
+		Stderr: stderr,
+	}
+
+	obj.Warn("something %s", "odd")
+
+	assert.Equal(t, "app: warning: something odd\n", stderr.String())
+}
+
+func TestAppWarnHandler(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	var msgs []string
+	obj := &App{
+		Name:   "app",
+		Stderr: stderr,
+		WarningHandler: func(msg string) {
+			msgs = append(msgs, msg)
+		},
+	}
+
+	obj.Warn("something %s", "odd")
+
+	assert.Equal(t, []string{"something odd"}, msgs)
+	assert.Equal(t, "", stderr.String())
+}
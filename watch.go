@@ -0,0 +1,106 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// WatchFlag is the global flag that requests that a command be run
+// repeatedly, if the application allows it; see App.AllowWatch.  The
+// flag may be given an interval, as in "--watch=5s"; otherwise,
+// DefaultWatchInterval is used.
+const WatchFlag = "--watch"
+
+// DefaultWatchInterval is the interval at which a watched command is
+// re-run if no interval is given.
+const DefaultWatchInterval = 2 * time.Second
+
+// clearScreen is the terminal escape sequence that clears the screen
+// and homes the cursor.
+const clearScreen = "\x1b[H\x1b[2J"
+
+// extractWatch is a helper that removes the WatchFlag from the
+// arguments preceding any "--", returning the requested interval--or
+// 0 if the flag was not present--and the remaining arguments.
+func extractWatch(args []string) (time.Duration, []string, error) {
+	interval := time.Duration(0)
+	result := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			result = append(result, args[i:]...)
+			break
+		}
+
+		// Check for the flag
+		switch {
+		case arg == WatchFlag:
+			interval = DefaultWatchInterval
+		case strings.HasPrefix(arg, WatchFlag+"="):
+			value := arg[len(WatchFlag)+1:]
+			tmp, err := time.ParseDuration(value)
+			if err != nil || tmp <= 0 {
+				return 0, args, fmt.Errorf("%w %q for %s", ErrInvalidValue, value, WatchFlag)
+			}
+			interval = tmp
+		default:
+			result = append(result, arg)
+		}
+	}
+
+	return interval, result, nil
+}
+
+// watch runs a function repeatedly at the specified interval,
+// clearing the screen before each run if standard output is a
+// terminal that interprets ANSI escape sequences.  It stops when the
+// function returns an error, which is returned; when an interrupt
+// signal is received, in which case nil is returned; or when the
+// context is done, in which case the context's error is returned.
+func (a *App) watch(ctx context.Context, interval time.Duration, run func() error) error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	out := a.stdout()
+	clearing := streamIsTerminal(out) && enableVT(out.(*os.File))
+	for {
+		if clearing {
+			fmt.Fprint(out, clearScreen)
+		}
+		if err := run(); err != nil {
+			return err
+		}
+
+		// Wait for the next run
+		timer := a.clock().NewTimer(interval)
+		select {
+		case <-sigs:
+			timer.Stop()
+			return nil
+
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+
+		case <-timer.C():
+		}
+	}
+}
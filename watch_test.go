@@ -0,0 +1,139 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractWatch(t *testing.T) {
+	interval, args, err := extractWatch([]string{"a1", "--watch", "a2", "--", "--watch"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultWatchInterval, interval)
+	assert.Equal(t, []string{"a1", "a2", "--", "--watch"}, args)
+}
+
+func TestExtractWatchInterval(t *testing.T) {
+	interval, args, err := extractWatch([]string{"a1", "--watch=5s"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, interval)
+	assert.Equal(t, []string{"a1"}, args)
+}
+
+func TestExtractWatchAbsent(t *testing.T) {
+	interval, args, err := extractWatch([]string{"a1", "a2"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), interval)
+	assert.Equal(t, []string{"a1", "a2"}, args)
+}
+
+func TestExtractWatchBadInterval(t *testing.T) {
+	_, _, err := extractWatch([]string{"--watch=bogus"})
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+}
+
+func TestExtractWatchNonPositive(t *testing.T) {
+	_, _, err := extractWatch([]string{"--watch=0s"})
+
+	assert.ErrorIs(t, err, ErrInvalidValue)
+}
+
+func TestAppWatchError(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	obj := &App{Stdout: stdout}
+	count := 0
+
+	err := obj.watch(context.Background(), time.Millisecond, func() error {
+		count++
+		if count >= 3 {
+			return assert.AnError
+		}
+		return nil
+	})
+
+	assert.Same(t, assert.AnError, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, "", stdout.String())
+}
+
+func TestAppWatchTerminal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("console modes cannot be set on files")
+	}
+	fakeTerminal(t, true, nil)
+	stdout, err := ioutil.TempFile(t.TempDir(), "stdout")
+	require.NoError(t, err)
+	defer stdout.Close()
+	obj := &App{Stdout: stdout}
+	count := 0
+
+	err = obj.watch(context.Background(), time.Millisecond, func() error {
+		count++
+		if count >= 2 {
+			return assert.AnError
+		}
+		return nil
+	})
+
+	assert.Same(t, assert.AnError, err)
+	data, err := ioutil.ReadFile(stdout.Name())
+	require.NoError(t, err)
+	assert.Equal(t, clearScreen+clearScreen, string(data))
+}
+
+func TestAppWatchContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	obj := &App{Stdout: &bytes.Buffer{}}
+	count := 0
+
+	err := obj.watch(ctx, time.Hour, func() error {
+		count++
+		cancel()
+		return nil
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, count)
+}
+
+func TestAppWatchSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals cannot be sent on Windows")
+	}
+	obj := &App{Stdout: &bytes.Buffer{}}
+	count := 0
+
+	err := obj.watch(context.Background(), time.Hour, func() error {
+		count++
+		p, _ := os.FindProcess(os.Getpid())
+		return p.Signal(os.Interrupt)
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
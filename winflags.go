@@ -0,0 +1,70 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"strings"
+)
+
+// WindowsFlagPrefix is the prefix of Windows-style flags, which are
+// recognized in addition to the usual forms if the application
+// allows it; see WithWindowsFlags.
+const WindowsFlagPrefix = "/"
+
+// WithWindowsFlags sets whether Windows-style flags are recognized,
+// for the benefit of users of tools migrated from Windows.  Such
+// flags are given as "/name", or as "/name:value" for a flag taking
+// a value; the name may be the long or the short name of the flag,
+// and the value may also be assigned with "=".  Only names of the
+// command's flags are recognized, so that other arguments beginning
+// with a slash, such as absolute paths, remain positional arguments.
+// Returns the App, to allow chaining.
+func (a *App) WithWindowsFlags(allow bool) *App {
+	a.WindowsFlags = allow
+	return a
+}
+
+// windowsFlag translates an argument that is a Windows-style flag
+// into the equivalent flag in the usual form, which is returned
+// along with true.  If Windows-style flags are not recognized, or if
+// the argument does not name a flag of the options, it returns false.
+func (o *options) windowsFlag(arg string) (string, bool) {
+	if o == nil || !o.windows || !strings.HasPrefix(arg, WindowsFlagPrefix) {
+		return "", false
+	}
+
+	// Split off the value
+	name, value := arg[len(WindowsFlagPrefix):], ""
+	assigned := false
+	if i := strings.IndexAny(name, ":="); i >= 0 {
+		name, value = name[:i], name[i+1:]
+		assigned = true
+	}
+
+	// Look up the flag; short flags are translated to their long
+	// names, so that values may be assigned
+	if _, ok := o.lookupLong(name); !ok {
+		opt, ok := o.Set.short[name]
+		if !ok {
+			return "", false
+		}
+		name = opt.Name
+	}
+
+	if assigned {
+		return "--" + name + "=" + value, true
+	}
+	return "--" + name, true
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type windowsOptions struct {
+	Verbose bool   `opt:"verbose,v"`
+	Output  string `opt:"output,o"`
+	Name    string `opt:"name,n"`
+}
+
+func TestAppWithWindowsFlags(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithWindowsFlags(true)
+
+	assert.Same(t, obj, result)
+	assert.True(t, obj.WindowsFlags)
+}
+
+func TestOptionsWindowsFlag(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &windowsOptions{}})
+	obj.windows = true
+	tests := []struct {
+		arg    string
+		result string
+		ok     bool
+	}{
+		{"/verbose", "--verbose", true},
+		{"/verbose:false", "--verbose=false", true},
+		{"/name:n", "--name=n", true},
+		{"/name=n", "--name=n", true},
+		{"/name:a:b", "--name=a:b", true},
+		{"/n:n", "--name=n", true},
+		{"/v", "--verbose", true},
+		{"/o:", "--output=", true},
+		{"/Verbose", "", false},
+		{"/x", "", false},
+		{"/tmp", "", false},
+		{"/tmp/file", "", false},
+		{"/", "", false},
+		{"--name", "", false},
+		{"name", "", false},
+	}
+
+	for _, test := range tests {
+		result, ok := obj.windowsFlag(test.arg)
+
+		assert.Equal(t, test.result, result, test.arg)
+		assert.Equal(t, test.ok, ok, test.arg)
+	}
+}
+
+func TestOptionsWindowsFlagDisabled(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &windowsOptions{}})
+
+	_, ok := obj.windowsFlag("/verbose")
+
+	assert.False(t, ok)
+}
+
+func TestOptionsWindowsFlagInsensitive(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &windowsOptions{}})
+	obj.windows = true
+	obj.insensitive = true
+
+	result, ok := obj.windowsFlag("/Verbose")
+
+	assert.True(t, ok)
+	assert.Equal(t, "--Verbose", result)
+}
+
+func TestOptionsWindowsFlagNil(t *testing.T) {
+	var obj *options
+
+	_, ok := obj.windowsFlag("/verbose")
+
+	assert.False(t, ok)
+}
+
+func TestOptionsParseWindows(t *testing.T) {
+	obj, _ := newOptions(&Command{Defaults: &windowsOptions{}})
+	obj.windows = true
+	args := []string{"/v", "/tmp/a1", "/name", "/tmp/n", "/o:out", "--", "/v"}
+
+	positional, next, err := obj.parse(args, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/tmp/a1", "/v"}, positional)
+	assert.Equal(t, -1, next)
+	assert.Equal(t, &windowsOptions{
+		Verbose: true,
+		Name:    "/tmp/n",
+		Output:  "out",
+	}, obj.Value.Interface())
+	assert.Equal(t, []string{"/v", "/tmp/a1", "/name", "/tmp/n", "/o:out", "--", "/v"}, args)
+}
+
+func TestAppDispatchWindowsFlags(t *testing.T) {
+	sub := newRunCommand("Sub command", nil)
+	sub.Defaults = &windowsOptions{}
+	sub.On("Run", Args{"/tmp/a1"}, mock.Anything).Return(nil)
+	root := &Command{
+		Defaults: &testOptions{},
+		Subcommands: map[string]ICommand{
+			"sub": sub,
+		},
+	}
+	var opts *windowsOptions
+	sub.ExpectedCalls[0].Run(func(args mock.Arguments) {
+		opts = args.Get(1).(*Invocation).Options.(*windowsOptions)
+	})
+	obj := (&App{
+		Root: root,
+	}).WithWindowsFlags(true)
+
+	err := obj.Dispatch(context.Background(), []string{"/count:3", "sub", "/v", "/tmp/a1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &windowsOptions{Verbose: true}, opts)
+	sub.AssertExpectations(t)
+}
+
+func TestAppDispatchWindowsFlagsDisabled(t *testing.T) {
+	sub := newRunCommand("Sub command", nil)
+	sub.Defaults = &windowsOptions{}
+	sub.On("Run", Args{"/v"}, mock.Anything).Return(nil)
+	root := &Command{
+		Subcommands: map[string]ICommand{
+			"sub": sub,
+		},
+	}
+	obj := &App{
+		Root: root,
+	}
+
+	err := obj.Dispatch(context.Background(), []string{"sub", "/v"})
+
+	assert.NoError(t, err)
+	sub.AssertExpectations(t)
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultWidth is the width, in columns, to which help is wrapped if
+// the width of the terminal cannot be determined.
+const DefaultWidth = 80
+
+// ColumnsEnv is the environment variable that overrides the width of
+// the terminal, as set by many shells.
+const ColumnsEnv = "COLUMNS"
+
+// minWrapWidth is the narrowest column to which text is wrapped, so
+// that help remains legible on very narrow terminals.
+const minWrapWidth = 20
+
+// termWidth is a hook for testing.
+var termWidth = termSttyWidth
+
+// WithWidth sets the width, in columns, to which help is wrapped,
+// overriding the width of the terminal.  A width of 0 selects the
+// width of the terminal, as described by the ColumnsEnv environment
+// variable or by the terminal itself, or DefaultWidth if it cannot be
+// determined.  Returns the App, to allow chaining.
+func (a *App) WithWidth(width int) *App {
+	a.Width = width
+	return a
+}
+
+// width determines the width, in columns, to which help written to
+// the specified stream is wrapped.
+func (a *App) width(w io.Writer) int {
+	if a.Width > 0 {
+		return a.Width
+	}
+	if cols, err := strconv.Atoi(os.Getenv(ColumnsEnv)); err == nil && cols > 0 {
+		return cols
+	}
+	if f, ok := w.(*os.File); ok && isTerminal(f) {
+		if cols := termWidth(f); cols > 0 {
+			return cols
+		}
+	}
+
+	return DefaultWidth
+}
+
+// termSttyWidth determines the width of a terminal using stty(1).
+// Returns 0 if the width cannot be determined.
+func termSttyWidth(f *os.File) int {
+	cmd := exec.Command("stty", "size") //nolint:gosec
+	cmd.Stdin = f
+	out, err := cmd.Output()
+	if err != nil {
+		return 0
+	}
+
+	// Output is "rows columns"
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return 0
+	}
+	cols, _ := strconv.Atoi(fields[1])
+	return cols
+}
+
+// wrapText wraps each line of text to the specified width, breaking
+// lines between words.  Blank lines and lines beginning with white
+// space, such as indented examples, are preserved as written.  Words
+// longer than the width are not broken.
+func wrapText(width int, text string) string {
+	if width < minWrapWidth {
+		width = minWrapWidth
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" || strings.IndexAny(line[:1], " \t") == 0 {
+			lines = append(lines, line)
+			continue
+		}
+
+		current, length := "", 0
+		for _, word := range strings.Fields(line) {
+			size := utf8.RuneCountInString(word)
+			switch {
+			case length == 0:
+				current, length = word, size
+			case length+1+size > width:
+				lines = append(lines, current)
+				current, length = word, size
+			default:
+				current += " " + word
+				length += 1 + size
+			}
+		}
+		lines = append(lines, current)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// helpWrap is a template function that wraps text to the specified
+// width; see wrapText.
+func helpWrap(width int, text string) string {
+	return wrapText(width, text)
+}
+
+// helpHang is a template function that wraps text that begins in the
+// specified column of a line of the specified width, such as the
+// help text of a flag; continuation lines are indented to the same
+// column.
+func helpHang(column, width int, text string) string {
+	return strings.ReplaceAll(wrapText(width-column, text), "\n", "\n"+strings.Repeat(" ", column))
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2021 Kevin L. Mitchell
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you
+// may not use this file except in compliance with the License.  You
+// may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nelson
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTermWidth is a helper that makes the terminal width test hook
+// report the specified width, restoring it when the test completes.
+func fakeTermWidth(t *testing.T, width int) {
+	t.Helper()
+
+	old := termWidth
+	termWidth = func(f *os.File) int { return width }
+	t.Cleanup(func() {
+		termWidth = old
+	})
+}
+
+func TestAppWithWidth(t *testing.T) {
+	obj := &App{}
+
+	result := obj.WithWidth(100)
+
+	assert.Same(t, obj, result)
+	assert.Equal(t, 100, obj.Width)
+}
+
+func TestAppWidthOverride(t *testing.T) {
+	setEnv(t, ColumnsEnv, "60")
+	obj := &App{Width: 100}
+
+	result := obj.width(&bytes.Buffer{})
+
+	assert.Equal(t, 100, result)
+}
+
+func TestAppWidthColumns(t *testing.T) {
+	setEnv(t, ColumnsEnv, "60")
+	obj := &App{}
+
+	result := obj.width(&bytes.Buffer{})
+
+	assert.Equal(t, 60, result)
+}
+
+func TestAppWidthColumnsInvalid(t *testing.T) {
+	setEnv(t, ColumnsEnv, "wide")
+	obj := &App{}
+
+	result := obj.width(&bytes.Buffer{})
+
+	assert.Equal(t, DefaultWidth, result)
+}
+
+func TestAppWidthTerminal(t *testing.T) {
+	setEnv(t, ColumnsEnv, "")
+	fakeTerminal(t, true, nil)
+	fakeTermWidth(t, 120)
+	obj := &App{}
+
+	result := obj.width(os.Stdout)
+
+	assert.Equal(t, 120, result)
+}
+
+func TestAppWidthTerminalUnknown(t *testing.T) {
+	setEnv(t, ColumnsEnv, "")
+	fakeTerminal(t, true, nil)
+	fakeTermWidth(t, 0)
+	obj := &App{}
+
+	result := obj.width(os.Stdout)
+
+	assert.Equal(t, DefaultWidth, result)
+}
+
+func TestAppWidthNotTerminal(t *testing.T) {
+	setEnv(t, ColumnsEnv, "")
+	fakeTerminal(t, false, nil)
+	fakeTermWidth(t, 120)
+	obj := &App{}
+
+	result := obj.width(os.Stdout)
+
+	assert.Equal(t, DefaultWidth, result)
+}
+
+func TestTermSttyWidthNotTerminal(t *testing.T) {
+	f := stdinFile(t, "")
+
+	result := termSttyWidth(f)
+
+	assert.Equal(t, 0, result)
+}
+
+func TestWrapTextBase(t *testing.T) {
+	result := wrapText(20, "The quick brown fox jumps over the lazy dog.")
+
+	assert.Equal(t, "The quick brown fox\njumps over the lazy\ndog.", result)
+}
+
+func TestWrapTextPreserved(t *testing.T) {
+	result := wrapText(20, "First paragraph is wrapped.\n\n  indented lines are not wrapped\n\tnor are tabbed lines")
+
+	assert.Equal(t, "First paragraph is\nwrapped.\n\n  indented lines are not wrapped\n\tnor are tabbed lines", result)
+}
+
+func TestWrapTextLongWord(t *testing.T) {
+	result := wrapText(20, "see https://example.com/a/very/long/path for details")
+
+	assert.Equal(t, "see\nhttps://example.com/a/very/long/path\nfor details", result)
+}
+
+func TestWrapTextMinimum(t *testing.T) {
+	result := wrapText(5, "The quick brown fox jumps")
+
+	assert.Equal(t, "The quick brown fox\njumps", result)
+}
+
+func TestHelpWrap(t *testing.T) {
+	result := helpWrap(20, "The quick brown fox jumps")
+
+	assert.Equal(t, "The quick brown fox\njumps", result)
+}
+
+func TestHelpHang(t *testing.T) {
+	result := helpHang(4, 24, "The quick brown fox jumps")
+
+	assert.Equal(t, "The quick brown fox\n    jumps", result)
+}
+
+func TestAppHelpWrapped(t *testing.T) {
+	type opts struct {
+		Verbose bool `opt:"verbose,v" help:"Emit verbose output describing each step as it is performed"`
+	}
+	buf := &bytes.Buffer{}
+	obj := &App{Width: 40}
+	inv := &Invocation{
+		Path: []string{"app", "sub"},
+		Command: &Command{
+			Description: "A subcommand with a long description that must be wrapped.",
+			Examples:    "app sub --verbose first-file second-file third-file",
+			Defaults:    &opts{},
+		},
+	}
+
+	err := obj.help(buf, inv)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `A subcommand with a long description
+that must be wrapped.
+
+Usage: app sub [flags] [ARGS...]
+
+Flags:
+  -v, --verbose  Emit verbose output
+                 describing each step as
+                 it is performed
+
+Examples:
+  app sub --verbose first-file
+  second-file third-file
+`, buf.String())
+}